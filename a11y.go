@@ -0,0 +1,142 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// screenReaderCheckInterval is how long the Notifier trusts that a screen
+// reader is running or not before asking again.
+const screenReaderCheckInterval = time.Minute
+
+// WithMinimumTimeout makes the Notifier raise the timeouts shorter than min
+// to min, so that notifications stay long enough to be read. Notifications
+// that never expire, and those with DefaultTimeout, are left alone.
+func WithMinimumTimeout(min time.Duration) Option {
+	return func(nf *Notifier) error {
+		if min < 0 {
+			return fmt.Errorf("notify: negative minimum timeout %v", min)
+		}
+		nf.minTimeout = min
+		return nil
+	}
+}
+
+// WithScreenReaderTimeout makes the Notifier raise the minimum timeout to
+// min while a screen reader is running, so that it has the time to announce
+// the notifications, see WithMinimumTimeout and ScreenReaderRunning.
+//
+// The answer of ScreenReaderRunning is kept for a minute, and asked again in
+// the background, so that it never delays a send: the notifications sent
+// before the first answer get the usual minimum.
+func WithScreenReaderTimeout(min time.Duration) Option {
+	return func(nf *Notifier) error {
+		if min < 0 {
+			return fmt.Errorf("notify: negative screen reader timeout %v", min)
+		}
+		nf.screenReaderMin = min
+		return nil
+	}
+}
+
+// WithScreenReaderDetector makes the Notifier call running to find out
+// whether a screen reader is running, instead of asking the accessibility
+// bus, see ScreenReaderRunning.
+func WithScreenReaderDetector(running func() (bool, error)) Option {
+	return func(nf *Notifier) error {
+		if running == nil {
+			return errors.New("notify: nil screen reader detector")
+		}
+		nf.screenReaderDetector = running
+		return nil
+	}
+}
+
+// ScreenReaderRunning returns true if a screen reader like Orca is running,
+// as reported by the ScreenReaderEnabled property of the org.a11y.Bus
+// service of the session bus, or the detector set by
+// WithScreenReaderDetector. Without the service, there is no screen reader.
+func (nf *Notifier) ScreenReaderRunning() (bool, error) {
+	if nf.screenReaderDetector != nil {
+		return nf.screenReaderDetector()
+	}
+	conn, err := nf.connection()
+	if err != nil {
+		return false, err
+	}
+	var enabled dbus.Variant
+	err = conn.Object("org.a11y.Bus", "/org/a11y/bus").Call("org.freedesktop.DBus.Properties.Get", dbus.FlagNoAutoStart, "org.a11y.Status", "ScreenReaderEnabled").Store(&enabled)
+	if errors.Is(noDaemon(err), ErrNoDaemon) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	running, ok := enabled.Value().(bool)
+	if !ok {
+		return false, errUnrecognizedResponse
+	}
+	return running, nil
+}
+
+// screenReader holds the last answer of ScreenReaderRunning, see
+// WithScreenReaderTimeout.
+type screenReader struct {
+	running  bool
+	checked  time.Time
+	checking bool
+}
+
+// screenReaderCached returns the last known answer of ScreenReaderRunning,
+// asking again in the background if it is too old.
+func (nf *Notifier) screenReaderCached() bool {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	sr := &nf.screenReader
+	if !nf.shutdown && !sr.checking && (sr.checked.IsZero() || nf.clock.Now().Sub(sr.checked) >= screenReaderCheckInterval) {
+		sr.checking = true
+		go nf.checkScreenReader()
+	}
+	return sr.running
+}
+
+// checkScreenReader updates the answer of screenReaderCached.
+func (nf *Notifier) checkScreenReader() {
+	running, err := nf.ScreenReaderRunning()
+	if err != nil {
+		nf.log(LevelWarn, "finding out whether a screen reader is running failed", err)
+	}
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	nf.screenReader = screenReader{running: running, checked: nf.clock.Now()}
+}
+
+// minimumTimeout returns the timeout below which the timeouts of the
+// notifications are raised.
+func (nf *Notifier) minimumTimeout() time.Duration {
+	if nf.screenReaderMin > nf.minTimeout && nf.screenReaderCached() {
+		return nf.screenReaderMin
+	}
+	return nf.minTimeout
+}
+
+// expireTimeout returns the timeout of n in milliseconds, raised to the
+// minimum timeout, or 0 for the critical notifications that must not expire.
+func (nf *Notifier) expireTimeout(n *Notification) int32 {
+	if nf.dropsCriticalTimeout(n) {
+		return 0
+	}
+	if n.Timeout <= 0 {
+		return n.timeoutInMS()
+	}
+	if min := nf.minimumTimeout(); n.TimeoutDuration() < min {
+		return int32(min / time.Millisecond)
+	}
+	return n.timeoutInMS()
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestMinimumTimeout(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithMinimumTimeout(5*time.Second))
+
+	for _, tt := range []struct {
+		timeout notify.Duration
+		want    int32
+	}{
+		{notify.Duration(time.Second), 5000},
+		{notify.Duration(5 * time.Second), 5000},
+		{notify.Duration(8 * time.Second), 8000},
+		{0, 0},
+		{notify.DefaultTimeout, -1},
+	} {
+		n := notify.New("test", "short", "", "", 0, notify.NormalUrgency)
+		n.Timeout = tt.timeout
+		c, err := n.DryRun(nf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.ExpireTimeout != tt.want {
+			t.Errorf("timeout %v: ExpireTimeout = %d, want %d", tt.timeout, c.ExpireTimeout, tt.want)
+		}
+	}
+
+	if _, err := notify.NewNotifier(notify.WithMinimumTimeout(-time.Second)); err == nil {
+		t.Error("negative minimum timeout accepted")
+	}
+}
+
+func TestScreenReaderTimeout(t *testing.T) {
+	newFakeServer(t)
+	clock := newFakeClock()
+	var running atomic.Bool
+	var checks atomic.Int32
+	running.Store(true)
+	nf := newTestNotifier(t,
+		notify.WithClock(clock),
+		notify.WithMinimumTimeout(2*time.Second),
+		notify.WithScreenReaderTimeout(10*time.Second),
+		notify.WithScreenReaderDetector(func() (bool, error) {
+			defer checks.Add(1)
+			return running.Load(), nil
+		}))
+
+	timeout := func() int32 {
+		t.Helper()
+		n := notify.New("test", "announced", "", "", time.Second, notify.NormalUrgency)
+		c, err := n.DryRun(nf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.ExpireTimeout
+	}
+
+	// The first send does not wait for the detection.
+	if got := timeout(); got != 2000 {
+		t.Errorf("before detection: ExpireTimeout = %d, want 2000", got)
+	}
+	waitFor(t, "the detection", func() bool { return checks.Load() == 1 })
+	waitFor(t, "the screen reader timeout", func() bool { return timeout() == 10000 })
+	if n := checks.Load(); n != 1 {
+		t.Errorf("detector called %d times, want 1", n)
+	}
+
+	running.Store(false)
+	clock.Advance(time.Minute)
+	timeout()
+	waitFor(t, "the new detection", func() bool { return checks.Load() == 2 })
+	waitFor(t, "the usual minimum", func() bool { return timeout() == 2000 })
+}
+
+func TestScreenReaderRunningWithoutBus(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	running, err := nf.ScreenReaderRunning()
+	if err != nil || running {
+		t.Errorf("ScreenReaderRunning = %v, %v, want false, nil", running, err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoAction is returned by SetActionLabel for a key that is not one of
+// the actions of the notification.
+var ErrNoAction = errors.New("notify: no such action")
+
+// DefaultAction is the key of the action invoked by most daemons when the
+// notification itself is clicked.
+const DefaultAction = "default"
+
+// Action is a button, or another way for the user to respond, shown along
+// with the notification by daemons with the "actions" capability.
+type Action struct {
+	// Key identifies the action when it is invoked.
+	Key string
+	// Label is the text shown to the user.
+	Label string
+}
+
+// AddAction appends an action with the given key and label to n. Actions
+// are shown in the order they were added.
+func (n *Notification) AddAction(key, label string) {
+	n.Actions = append(n.Actions, Action{key, label})
+}
+
+// SetActions replaces all the actions of n by actions, shown in this order.
+func (n *Notification) SetActions(actions ...Action) {
+	n.Actions = append([]Action(nil), actions...)
+}
+
+// RemoveAction removes the actions with the given key from n, keeping the
+// others in order. Once n is sent again, invoking a removed action has no
+// effect.
+func (n *Notification) RemoveAction(key string) {
+	actions := make([]Action, 0, len(n.Actions))
+	for _, a := range n.Actions {
+		if a.Key != key {
+			actions = append(actions, a)
+		}
+	}
+	n.Actions = actions
+}
+
+// SetActionLabel changes the label of the actions with the given key, so
+// that n shows label once sent again, for example by Patch. The key, and
+// so the OnAction callback, stay the same, which makes toggle buttons:
+//
+//	n.SetActionLabel("toggle", "Resume")
+//	n.Patch(func(*notify.Notification) {})
+func (n *Notification) SetActionLabel(key, label string) error {
+	actions := append([]Action(nil), n.Actions...)
+	found := false
+	for i := range actions {
+		if actions[i].Key == key {
+			actions[i].Label = label
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %q", ErrNoAction, key)
+	}
+	n.Actions = actions
+	return nil
+}
+
+// actionsArray returns actions as the flat key, label list of the
+// specification.
+func actionsArray(actions []Action) []string {
+	if len(actions) == 0 {
+		return nil
+	}
+	array := make([]string, 0, 2*len(actions))
+	for _, a := range actions {
+		array = append(array, a.Key, a.Label)
+	}
+	return array
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestActionsOrder(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	n := notify.New("test", "five actions", "", "", 0, notify.NormalUrgency)
+	n.SetActions(
+		notify.Action{Key: "e", Label: "Echo"},
+		notify.Action{Key: "d", Label: "Delta"},
+		notify.Action{Key: "c", Label: "Charlie"},
+		notify.Action{Key: "b", Label: "Bravo"},
+	)
+	n.AddAction("a", "Alpha")
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"e", "Echo", "d", "Delta", "c", "Charlie", "b", "Bravo", "a", "Alpha"}
+	if got := s.last(t).Actions; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("actions = %q, want %q", got, want)
+	}
+
+	n.SetActions(notify.Action{Key: "x", Label: "X"})
+	if len(n.Actions) != 1 || n.Actions[0].Key != "x" {
+		t.Errorf("SetActions left %v", n.Actions)
+	}
+}
+
+func TestRemoveAction(t *testing.T) {
+	s := newFakeServer(t)
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithLogger(logs.log))
+
+	var cb callbacks
+	n := notify.New("test", "stale", "", "", 0, notify.NormalUrgency)
+	n.AddAction("keep", "Keep")
+	n.AddAction("drop", "Drop")
+	n.AddAction("last", "Last")
+	cb.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+
+	n.RemoveAction("drop")
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Actions; strings.Join(got, ",") != "keep,Keep,last,Last" {
+		t.Errorf("actions = %q after RemoveAction", got)
+	}
+
+	s.emitAction(n.Id, "drop")
+	s.emitAction(n.Id, "keep")
+	waitFor(t, "action", func() bool { return len(cb.invoked()) == 1 })
+	if keys := cb.invoked(); keys[0] != "keep" {
+		t.Errorf("OnAction called with %q, want only the action still shown", keys)
+	}
+	if logs.len() != 1 {
+		t.Errorf("logged %q, want a warning about the removed action", logs.msgs)
+	}
+}
+
+func TestSetActionLabel(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	var cb callbacks
+	n := notify.New("test", "player", "", "", 0, notify.NormalUrgency)
+	n.AddAction("toggle", "Pause")
+	n.AddAction("skip", "Skip")
+	cb.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.SetActionLabel("missing", "Missing"); !errors.Is(err, notify.ErrNoAction) {
+		t.Errorf("SetActionLabel of a missing key = %v, want ErrNoAction", err)
+	}
+
+	labels := []string{"Resume", "Pause"}
+	for i, label := range labels {
+		s.emitAction(n.Id, "toggle")
+		waitFor(t, "action", func() bool { return len(cb.invoked()) == i+1 })
+		if err := n.SetActionLabel("toggle", label); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nf.Patch(n, func(*notify.Notification) {}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := strings.Join(s.last(t).Actions, ","), "toggle,"+label+",skip,Skip"; got != want {
+			t.Errorf("actions = %q, want %q", got, want)
+		}
+	}
+	if keys := cb.invoked(); strings.Join(keys, ",") != "toggle,toggle" {
+		t.Errorf("OnAction called with %q", keys)
+	}
+}
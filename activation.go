@@ -0,0 +1,72 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// ErrNoDaemon is returned when no notification daemon is running, and none
+// was started for the call.
+var ErrNoDaemon = errors.New("notify: no notification daemon running")
+
+// WithAutoActivation sets whether the calls of the Notifier may start the
+// notification daemon through D-Bus activation, which they do by default.
+// On some minimal setups, activation starts an unwanted fallback daemon:
+// without it, sending while no daemon runs fails right away with
+// ErrNoDaemon, and Available returns false. Use Activate to start the
+// daemon on purpose.
+func WithAutoActivation(activate bool) Option {
+	return func(nf *Notifier) error {
+		nf.noAutoStart = !activate
+		return nil
+	}
+}
+
+// callFlags returns the flags of the method calls of nf to the daemon.
+func (nf *Notifier) callFlags() dbus.Flags {
+	if nf.noAutoStart {
+		return dbus.FlagNoAutoStart
+	}
+	return 0
+}
+
+// noDaemon wraps err with ErrNoDaemon if the bus reports that no daemon
+// owns the name of the daemon, and that none was started.
+func noDaemon(err error) error {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return err
+	}
+	switch dbusErr.Name {
+	case "org.freedesktop.DBus.Error.ServiceUnknown", "org.freedesktop.DBus.Error.NameHasNoOwner":
+		return fmt.Errorf("%w: %w", ErrNoDaemon, err)
+	}
+	return err
+}
+
+// Activate starts the notification daemon of nf through D-Bus activation if
+// it is not running, even with WithAutoActivation(false), and returns
+// ErrNoDaemon if none can be started.
+func (nf *Notifier) Activate(ctx context.Context) error {
+	conn, err := nf.connection()
+	if err != nil {
+		return err
+	}
+	// The bus only starts daemons with a service file, even if one is
+	// already running, so check for that first.
+	var running bool
+	err = conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.NameHasOwner", 0, nf.destination).Store(&running)
+	if err != nil || running {
+		return err
+	}
+	var reply uint32
+	err = conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.StartServiceByName", 0, nf.destination, uint32(0)).Store(&reply)
+	return noDaemon(err)
+}
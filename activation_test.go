@@ -0,0 +1,64 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestAutoActivationFlag(t *testing.T) {
+	s := newFakeServer(t)
+	for _, c := range []struct {
+		activate bool
+		want     dbus.Flags
+	}{
+		{true, 0},
+		{false, dbus.FlagNoAutoStart},
+	} {
+		nf := newTestNotifier(t, notify.WithAutoActivation(c.activate))
+		if _, err := nf.Notify(notify.New("test", "flags", "", "", time.Second, notify.NormalUrgency)); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.last(t).Flags & dbus.FlagNoAutoStart; got != c.want {
+			t.Errorf("activate %v: flags = %v, want %v", c.activate, got, c.want)
+		}
+	}
+}
+
+func TestNoDaemon(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithAutoActivation(false))
+	s.conn.Close()
+
+	start := time.Now()
+	_, err := nf.Notify(notify.New("test", "nobody", "", "", time.Second, notify.NormalUrgency))
+	if !errors.Is(err, notify.ErrNoDaemon) {
+		t.Errorf("Notify returned %v, want ErrNoDaemon", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Notify took %v", d)
+	}
+	if nf.Available() {
+		t.Error("Available with no daemon")
+	}
+	if err := nf.Activate(context.Background()); !errors.Is(err, notify.ErrNoDaemon) {
+		t.Errorf("Activate returned %v, want ErrNoDaemon", err)
+	}
+
+	newFakeServer(t)
+	if err := nf.Activate(context.Background()); err != nil {
+		t.Errorf("Activate with a running daemon: %v", err)
+	}
+	if !nf.Available() {
+		t.Error("daemon not available once running")
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// EnvActivationToken is the environment variable passing an XDG activation
+// token to a process, so that its window is raised when it opens.
+const EnvActivationToken = "XDG_ACTIVATION_TOKEN"
+
+// ActivationToken returns the XDG activation token that the daemon sent with
+// the last action invoked on n, or the empty string if it sent none. Wayland
+// compositors only let an application raise its window with such a token:
+// pass it to the toolkit, or with SetActivationEnv to the process started
+// by OnAction. It is set before OnAction is called.
+func (n *Notification) ActivationToken() string {
+	return n.activationToken
+}
+
+// activationTokenReceived records the token of the next action invoked on
+// id.
+func (nf *Notifier) activationTokenReceived(id uint32, token string) {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if nf.activationTokens == nil {
+		nf.activationTokens = make(map[uint32]string)
+	}
+	nf.activationTokens[id] = token
+}
+
+// SetActivationEnv sets XDG_ACTIVATION_TOKEN to token in the environment of
+// cmd, which inherits the environment of the process if cmd.Env is nil. It
+// does nothing if token is empty.
+func SetActivationEnv(cmd *exec.Cmd, token string) {
+	if token == "" {
+		return
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	out := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, EnvActivationToken+"=") {
+			out = append(out, kv)
+		}
+	}
+	cmd.Env = append(out, EnvActivationToken+"="+token)
+}
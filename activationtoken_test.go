@@ -0,0 +1,78 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestActivationToken(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	events := nf.Events()
+
+	tokens := make(chan string, 2)
+	send := func(summary string) *notify.Notification {
+		n := notify.New("test", summary, "", "", 0, notify.NormalUrgency)
+		n.AddAction(notify.DefaultAction, "Open")
+		n.OnAction = func(string) { tokens <- n.ActivationToken() }
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		waitEvent(t, events, notify.EventSent)
+		return n
+	}
+	first, second := send("first"), send("second")
+
+	// The tokens are paired with the actions by ID, whatever their order.
+	s.emitActivationToken(second.Id, "token-2")
+	s.emitActivationToken(first.Id, "token-1")
+	s.emitAction(first.Id, notify.DefaultAction)
+	if e := waitEvent(t, events, notify.EventAction); e.ID != first.Id || e.ActivationToken != "token-1" {
+		t.Errorf("first action: ID %d, token %q", e.ID, e.ActivationToken)
+	}
+	if got := <-tokens; got != "token-1" {
+		t.Errorf("first ActivationToken() = %q, want token-1", got)
+	}
+	s.emitAction(second.Id, notify.DefaultAction)
+	if e := waitEvent(t, events, notify.EventAction); e.ID != second.Id || e.ActivationToken != "token-2" {
+		t.Errorf("second action: ID %d, token %q", e.ID, e.ActivationToken)
+	}
+	if got := <-tokens; got != "token-2" {
+		t.Errorf("second ActivationToken() = %q, want token-2", got)
+	}
+
+	// A token is only used once.
+	s.emitAction(first.Id, notify.DefaultAction)
+	if e := waitEvent(t, events, notify.EventAction); e.ActivationToken != "" {
+		t.Errorf("action without a token got %q", e.ActivationToken)
+	}
+	if got := <-tokens; got != "" {
+		t.Errorf("ActivationToken() without a token = %q", got)
+	}
+}
+
+func TestSetActivationEnv(t *testing.T) {
+	cmd := exec.Command("true")
+	cmd.Env = []string{"HOME=/home/me", "XDG_ACTIVATION_TOKEN=stale"}
+	notify.SetActivationEnv(cmd, "fresh")
+	if want := []string{"HOME=/home/me", "XDG_ACTIVATION_TOKEN=fresh"}; !reflect.DeepEqual(cmd.Env, want) {
+		t.Errorf("Env = %q, want %q", cmd.Env, want)
+	}
+
+	cmd = exec.Command("true")
+	notify.SetActivationEnv(cmd, "")
+	if cmd.Env != nil {
+		t.Errorf("empty token set Env to %q", cmd.Env)
+	}
+	notify.SetActivationEnv(cmd, "fresh")
+	if n := len(cmd.Env); n == 0 || cmd.Env[n-1] != "XDG_ACTIVATION_TOKEN=fresh" {
+		t.Errorf("inherited Env = %q", cmd.Env)
+	}
+}
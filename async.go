@@ -0,0 +1,167 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// asyncOp is a queued asynchronous operation on a notification.
+type asyncOp struct {
+	n *Notification
+	// apply modifies n right before it is sent. It may be nil.
+	apply func(n *Notification)
+	done  chan error
+	// seq orders the operations of all the lanes.
+	seq uint64
+	// queuedAt is when the operation was queued, see WithQueueMetrics.
+	queuedAt time.Time
+}
+
+// lane holds the pending operations on one notification, or on all the
+// notifications with the same tag. They are executed in order by a single
+// goroutine.
+type lane struct {
+	ops []*asyncOp
+}
+
+// SendAsync queues n to be sent in the background, and returns a channel
+// receiving the result once n was sent. If the queue is full, see WithQueue,
+// the result may be ErrDropped or ErrQueueFull; with QueueBlock,
+// SendAsync waits for room in the queue.
+//
+// Asynchronous operations on the same *Notification, or on notifications
+// with the same Tag, are executed in the order they were queued: in
+// particular, a replace waits for the ID of a pending send, so that a quick
+// SendAsync followed by ReplaceAsync shows only one notification. Operations
+// on different notifications may interleave.
+//
+// Until the returned channel receives, n belongs to nf and must not be read
+// or modified by the caller, except for queueing more operations on it.
+func (nf *Notifier) SendAsync(n *Notification) <-chan error {
+	return nf.enqueue(context.Background(), n, nil)
+}
+
+// SendAsyncContext is like SendAsync, but with QueueBlock it stops waiting
+// for room in the queue once ctx is done, and the result is the error of
+// ctx.
+func (nf *Notifier) SendAsyncContext(ctx context.Context, n *Notification) <-chan error {
+	return nf.enqueue(ctx, n, nil)
+}
+
+// ReplaceAsync is the asynchronous version of Notification.ReplaceMsg: it
+// queues replacing the summary and body of n and sending it again. See
+// SendAsync for the ordering guarantees.
+func (nf *Notifier) ReplaceAsync(n *Notification, summary, body string) <-chan error {
+	return nf.enqueue(context.Background(), n, func(n *Notification) {
+		n.Summary, n.Body = summary, body
+	})
+}
+
+// enqueue adds an operation on n to its lane, starting the lane if needed.
+func (nf *Notifier) enqueue(ctx context.Context, n *Notification, apply func(*Notification)) <-chan error {
+	correlate(n)
+	op := &asyncOp{n: n, apply: apply, done: make(chan error, 1)}
+	var key any = n
+	if n.Tag != "" {
+		key = n.Tag
+	}
+
+	nf.mu.Lock()
+	var dropped *asyncOp
+	for !nf.shutdown && nf.queued >= nf.queueCap {
+		switch nf.queuePolicy {
+		case QueueDropOldest:
+			if dropped = nf.dropOldest(); dropped != nil {
+				continue
+			}
+			fallthrough
+		case QueueDropNewest:
+			nf.stats.Dropped++
+			nf.mu.Unlock()
+			nf.dropped(op)
+			return op.done
+		case QueueError:
+			nf.mu.Unlock()
+			return failOp(op, ErrQueueFull)
+		}
+
+		if nf.queueFreed == nil {
+			nf.queueFreed = make(chan struct{})
+		}
+		freed := nf.queueFreed
+		nf.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return failOp(op, ctx.Err())
+		case <-nf.stopped:
+		}
+		nf.mu.Lock()
+	}
+	if nf.shutdown {
+		nf.mu.Unlock()
+		return failOp(op, ErrShutdown)
+	}
+	defer func() {
+		nf.mu.Unlock()
+		if dropped != nil {
+			nf.dropped(dropped)
+		}
+	}()
+
+	nf.seq++
+	op.seq = nf.seq
+	nf.queued++
+	nf.queuedOp(op)
+	if l, ok := nf.lanes[key]; ok {
+		l.ops = append(l.ops, op)
+		return op.done
+	}
+	if nf.lanes == nil {
+		nf.lanes = make(map[any]*lane)
+	}
+	l := &lane{ops: []*asyncOp{op}}
+	nf.lanes[key] = l
+	nf.lanesRunning.Add(1)
+	go nf.runLane(key, l)
+	return op.done
+}
+
+// failOp completes op with err without executing it.
+func failOp(op *asyncOp, err error) <-chan error {
+	op.done <- err
+	close(op.done)
+	return op.done
+}
+
+// runLane executes the operations of l until there are none left.
+func (nf *Notifier) runLane(key any, l *lane) {
+	for {
+		nf.mu.Lock()
+		if len(l.ops) == 0 {
+			delete(nf.lanes, key)
+			nf.mu.Unlock()
+			nf.lanesRunning.Done()
+			return
+		}
+		op := l.ops[0]
+		l.ops = l.ops[1:]
+		nf.mu.Unlock()
+
+		if op.apply != nil {
+			op.apply(op.n)
+		}
+		_, err := nf.Notify(op.n)
+		nf.doneOp(op)
+		nf.mu.Lock()
+		nf.queued--
+		nf.dequeued()
+		nf.mu.Unlock()
+		op.done <- err
+		close(op.done)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestSendThenReplaceAsync(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	const pairs = 50
+	var wg sync.WaitGroup
+	for i := 0; i < pairs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n := notify.New("test", fmt.Sprintf("send %d", i), "", "", 0, notify.NormalUrgency)
+			sent := nf.SendAsync(n)
+			replaced := nf.ReplaceAsync(n, fmt.Sprintf("replace %d", i), "")
+			if err := <-sent; err != nil {
+				t.Error(err)
+			}
+			if err := <-replaced; err != nil {
+				t.Error(err)
+			}
+			if n.Summary != fmt.Sprintf("replace %d", i) {
+				t.Errorf("notification %d has summary %q after replace", i, n.Summary)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	popups := make(map[uint32]int)
+	for _, sn := range s.notifications() {
+		if sn.ReplacesID == 0 {
+			popups[sn.ID]++
+		}
+	}
+	if len(popups) != pairs {
+		t.Errorf("fake server shows %d notifications, want %d", len(popups), pairs)
+	}
+}
+
+func TestTagReplaces(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	first := notify.New("test", "network down", "", "", 0, notify.NormalUrgency)
+	first.Tag = "network"
+	second := notify.New("test", "network up", "", "", 0, notify.NormalUrgency)
+	second.Tag = "network"
+	done1, done2 := nf.SendAsync(first), nf.SendAsync(second)
+	if err := <-done1; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done2; err != nil {
+		t.Fatal(err)
+	}
+
+	if second.Id != first.Id {
+		t.Errorf("tagged notifications got IDs %d and %d", first.Id, second.Id)
+	}
+	got := s.last(t)
+	if got.ReplacesID != first.Id {
+		t.Errorf("second tagged notification replaces %d, want %d", got.ReplacesID, first.Id)
+	}
+	if v := got.Hints["x-dunst-stack-tag"].Value(); v != "network" {
+		t.Errorf("x-dunst-stack-tag hint = %v", v)
+	}
+}
+
+func TestOnReplaced(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	var replacedBy []uint32
+	first := notify.New("test", "downloading", "", "", 0, notify.NormalUrgency)
+	first.Tag = "download"
+	first.OnReplaced = func(id uint32) { replacedBy = append(replacedBy, id) }
+	if _, err := nf.Notify(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.Notify(first); err != nil {
+		t.Fatal(err)
+	}
+	if len(replacedBy) != 0 {
+		t.Fatalf("OnReplaced called when sending the same notification again")
+	}
+
+	second := notify.New("test", "download done", "", "", 0, notify.NormalUrgency)
+	second.Tag = "download"
+	if _, err := nf.Notify(second); err != nil {
+		t.Fatal(err)
+	}
+	if len(replacedBy) != 1 || replacedBy[0] != second.Id {
+		t.Errorf("OnReplaced called with %v, want [%d]", replacedBy, second.Id)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AutoConfigure returns a Notifier configured by opts, with defaults picked
+// for the running desktop and notification daemon:
+//
+//   - the desktop-entry hint is set to appID, or to the name of the
+//     executable if appID is empty;
+//   - the quirks of the daemon, or of the daemon of the desktop named by
+//     XDG_CURRENT_DESKTOP if the daemon cannot be asked, are worked around.
+//
+// Options in opts take precedence over these defaults: WithQuirks replaces
+// the detected quirks and a DesktopEntryHint in WithDefaultHints replaces
+// appID. The decisions are logged at LevelInfo, so pass WithLogger to see
+// them.
+func AutoConfigure(appID string, opts ...Option) (*Notifier, error) {
+	nf, err := NewNotifier(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := nf.defaultHints["desktop-entry"]; !ok {
+		if appID == "" {
+			appID = executableID()
+		}
+		if appID != "" {
+			WithDefaultHints(DesktopEntryHint(strings.TrimSuffix(appID, ".desktop")))(nf)
+		}
+	}
+
+	desktop := os.Getenv("XDG_CURRENT_DESKTOP")
+	info, err := nf.ServerInfo()
+	daemon := "unknown"
+	if err == nil {
+		daemon = info.Name
+	} else {
+		nf.log(LevelInfo, "auto-configuration could not ask the notification daemon", err)
+		for _, d := range strings.Split(desktop, ":") {
+			if name, ok := desktopDaemons[d]; ok {
+				daemon = name
+				info = ServerInfo{Name: name}
+				break
+			}
+		}
+	}
+	if nf.quirks == nil {
+		if q, ok := LookupQuirks(info); ok {
+			nf.quirks = &q
+			nf.quirksAuto = true
+		}
+	}
+
+	quirks := "none"
+	if nf.quirks != nil {
+		quirks = fmt.Sprintf("%+v", *nf.quirks)
+	}
+	nf.log(LevelInfo, fmt.Sprintf("auto-configured for desktop %q, daemon %q: desktop-entry %v, quirks %s",
+		desktop, daemon, nf.defaultHints["desktop-entry"].Value(), quirks), nil)
+	return nf, nil
+}
+
+// executableID returns the name of the running executable, which is the
+// name of the desktop file of most applications.
+func executableID() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(exe)
+}
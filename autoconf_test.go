@@ -0,0 +1,90 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func autoConfigure(t *testing.T, appID string, opts ...notify.Option) *notify.Notifier {
+	t.Helper()
+	nf, err := notify.AutoConfigure(appID, append([]notify.Option{notify.WithBusAddress(busAddress)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nf.Close() })
+	return nf
+}
+
+func TestAutoConfigureDaemonQuirks(t *testing.T) {
+	s := newFakeServer(t)
+	s.setServerName("dunst")
+	var logs logRecorder
+	nf := autoConfigure(t, "org.example.App.desktop", notify.WithLogger(logs.log))
+
+	n := notify.New("test", "tagged", "", "", time.Second, notify.NormalUrgency)
+	n.Tag = "volume"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	hints := s.last(t).Hints
+	if v := hints["desktop-entry"].Value(); v != "org.example.App" {
+		t.Errorf("desktop-entry = %v", v)
+	}
+	if _, ok := hints["x-dunst-stack-tag"]; !ok {
+		t.Error("no dunst stack tag sent to dunst")
+	}
+	if _, ok := hints["x-canonical-private-synchronous"]; ok {
+		t.Error("Canonical stack tag sent to dunst")
+	}
+
+	if logs.len() != 1 || !strings.Contains(logs.msgs[0], `daemon "dunst"`) {
+		t.Errorf("logged %q", logs.msgs)
+	}
+}
+
+func TestAutoConfigureOverrides(t *testing.T) {
+	s := newFakeServer(t)
+	s.setServerName("dunst")
+	nf := autoConfigure(t, "org.example.App",
+		notify.WithQuirks(notify.Quirks{}),
+		notify.WithDefaultHints(notify.DesktopEntryHint("org.example.Other")))
+
+	n := notify.New("test", "tagged", "", "", time.Second, notify.NormalUrgency)
+	n.Tag = "volume"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	hints := s.last(t).Hints
+	if v := hints["desktop-entry"].Value(); v != "org.example.Other" {
+		t.Errorf("desktop-entry = %v", v)
+	}
+	if _, ok := hints["x-canonical-private-synchronous"]; !ok {
+		t.Error("quirks not overridden")
+	}
+}
+
+func TestAutoConfigureDesktopFallback(t *testing.T) {
+	requireBus(t)
+	t.Setenv("XDG_CURRENT_DESKTOP", "KDE")
+	nf := autoConfigure(t, "")
+
+	n := notify.New("test", "persistent", "", "", 0, notify.NormalUrgency)
+	n.Persistent = true
+	c, err := n.DryRun(nf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Actions) != 0 {
+		t.Errorf("actions %q for a persistent notification on KDE", c.Actions)
+	}
+	if v, ok := c.Hints["desktop-entry"].Value().(string); !ok || v == "" {
+		t.Errorf("desktop-entry = %v, want the executable name", c.Hints["desktop-entry"])
+	}
+}
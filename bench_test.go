@@ -0,0 +1,228 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/Schnouki/notify"
+)
+
+// benchNotifications are the notifications used by the send benchmarks.
+var benchNotifications = []struct {
+	name string
+	make func() *notify.Notification
+}{
+	{"Text", func() *notify.Notification {
+		return notify.New("bench", "summary", "body", "", time.Second, notify.NormalUrgency)
+	}},
+	{"Hints", func() *notify.Notification {
+		n := notify.New("bench", "summary", "body", "", time.Second, notify.CriticalUrgency)
+		n.Tag = "bench"
+		n.AddAction("ok", "OK")
+		n.AddAction("cancel", "Cancel")
+		return n
+	}},
+	{"Image", func() *notify.Notification {
+		n := notify.New("bench", "summary", "body", "", time.Second, notify.NormalUrgency)
+		n.SetImage(image.NewRGBA(image.Rect(0, 0, 64, 64)))
+		return n
+	}},
+}
+
+func BenchmarkNotify(b *testing.B) {
+	for _, bn := range benchNotifications {
+		b.Run(bn.name, func(b *testing.B) {
+			newFakeServer(b)
+			nf := newTestNotifier(b)
+			n := bn.make()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := nf.Notify(n); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDryRun(b *testing.B) {
+	for _, bn := range benchNotifications {
+		b.Run(bn.name, func(b *testing.B) {
+			newFakeServer(b)
+			nf := newTestNotifier(b)
+			n := bn.make()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := n.DryRun(nf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkUrgencyHints builds the hints of a notification with only an
+// urgency, which should not allocate them.
+func BenchmarkUrgencyHints(b *testing.B) {
+	newFakeServer(b)
+	nf := newTestNotifier(b)
+	n := notify.New("bench", "summary", "body", "", time.Second, notify.NormalUrgency)
+	urgencies := []notify.NotificationUrgency{notify.LowUrgency, notify.NormalUrgency, notify.CriticalUrgency}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n.Urgency = urgencies[i%len(urgencies)]
+		if _, err := n.DryRun(nf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSetImage(b *testing.B) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1200, 1200))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i)
+	}
+	for _, bq := range []struct {
+		name    string
+		quality notify.ImageQuality
+	}{
+		{"Nearest", notify.ImageNearest},
+		{"Bilinear", notify.ImageBilinear},
+	} {
+		b.Run(bq.name, func(b *testing.B) {
+			n := notify.New("bench", "summary", "body", "", time.Second, notify.NormalUrgency)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				n.SetImage(img, notify.ImageResampling(bq.quality))
+			}
+		})
+	}
+}
+
+// BenchmarkNotifyInFlight sends from many goroutines to a daemon slow to
+// reply, reporting the highest number of calls in flight.
+func BenchmarkNotifyInFlight(b *testing.B) {
+	for _, max := range []int{4, notify.DefaultMaxInFlight, 256} {
+		b.Run(fmt.Sprint(max), func(b *testing.B) {
+			s := newFakeServer(b)
+			s.setReplyDelay(time.Millisecond)
+			nf := newTestNotifier(b, notify.WithMaxInFlight(max))
+			b.ReportAllocs()
+			b.SetParallelism(64)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				n := notify.New("bench", "summary", "body", "", time.Second, notify.NormalUrgency)
+				for pb.Next() {
+					n.Id = 0
+					if _, err := nf.Notify(n); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(nf.Stats().PeakInFlight), "peak-in-flight")
+		})
+	}
+}
+
+// BenchmarkSingleSend compares the ways a program sending one notification
+// and exiting can send it: a Notifier of its own, and QuickSend.
+func BenchmarkSingleSend(b *testing.B) {
+	newFakeServer(b)
+	b.Run("Notifier", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			nf, err := notify.NewNotifier(notify.WithBusAddress(busAddress))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := nf.Notify(notify.New("bench", "summary", "body", "", time.Second, notify.NormalUrgency)); err != nil {
+				b.Fatal(err)
+			}
+			nf.Close()
+		}
+	})
+	b.Run("QuickSend", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := notify.QuickSend("summary", "body", notify.NormalUrgency); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// callBytes returns the size of the Notify call message of sn.
+func callBytes(b *testing.B, sn sentNotification) int {
+	args := []interface{}{sn.AppName, sn.ReplacesID, sn.AppIcon, sn.Summary, sn.Body, sn.Actions, sn.Hints, sn.ExpireTimeout}
+	msg := &dbus.Message{
+		Type: dbus.TypeMethodCall,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldPath:        dbus.MakeVariant(dbus.ObjectPath("/org/freedesktop/Notifications")),
+			dbus.FieldInterface:   dbus.MakeVariant("org.freedesktop.Notifications"),
+			dbus.FieldMember:      dbus.MakeVariant("Notify"),
+			dbus.FieldDestination: dbus.MakeVariant("org.freedesktop.Notifications"),
+			dbus.FieldSignature:   dbus.MakeVariant(dbus.SignatureOf(args...)),
+		},
+		Body: args,
+	}
+	var buf bytes.Buffer
+	if err := msg.EncodeTo(&buf, binary.LittleEndian); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Len()
+}
+
+// BenchmarkRepeatedAvatar sends the notifications of a chat bridge, whose
+// avatars repeat, reporting the bytes sent on the bus per notification with
+// and without the image cache.
+func BenchmarkRepeatedAvatar(b *testing.B) {
+	avatars := make([]image.Image, 4)
+	for i := range avatars {
+		img := image.NewNRGBA(image.Rect(0, 0, notify.DefaultImageMaxDimension, notify.DefaultImageMaxDimension))
+		for j := range img.Pix {
+			img.Pix[j] = byte(i * j)
+		}
+		avatars[i] = img
+	}
+	for _, bc := range []struct {
+		name string
+		opts []notify.Option
+	}{
+		{"Embedded", nil},
+		{"Cached", []notify.Option{notify.WithImageCache(0)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			s := newFakeServer(b)
+			nf := newTestNotifier(b, bc.opts...)
+			b.ReportAllocs()
+			b.ResetTimer()
+			sent := 0
+			for i := 0; i < b.N; i++ {
+				n := notify.New("chat", "new message", "hello", "", time.Second, notify.NormalUrgency)
+				n.SetImage(avatars[i%len(avatars)])
+				if _, err := nf.Notify(n); err != nil {
+					b.Fatal(err)
+				}
+				b.StopTimer()
+				all := s.notifications()
+				sent += callBytes(b, all[len(all)-1])
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(sent)/float64(b.N), "bus-B/op")
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// BodyImage returns the markup embedding the image at path, a file path or a
+// file:// URI, in the body of a notification, with alt as its alternative
+// text. If path does not name an image file, only the escaped alt text is
+// returned, so that daemons do not show a broken image instead.
+//
+// Images are only shown by daemons with the "body-images" capability; for
+// the others, Notify replaces them by their alternative text.
+func BodyImage(path, alt string) string {
+	if !isImageFile(path) {
+		return html.EscapeString(alt)
+	}
+	return `<img src="` + html.EscapeString(path) + `" alt="` + html.EscapeString(alt) + `"/>`
+}
+
+// isImageFile returns true if path, a file path or a file:// URI, names a
+// file that looks like an image.
+func isImageFile(path string) bool {
+	name := path
+	if strings.HasPrefix(path, "file://") {
+		u, err := url.Parse(path)
+		if err != nil {
+			return false
+		}
+		name = u.Path
+	}
+	if !filepath.IsAbs(name) || checkImagePath(path) != nil {
+		return false
+	}
+	if strings.EqualFold(filepath.Ext(name), ".svg") {
+		return true
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false
+	}
+	return strings.HasPrefix(http.DetectContentType(buf[:n]), "image/")
+}
+
+var (
+	imgTag = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+	imgAlt = regexp.MustCompile(`(?i)\balt\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	brTag  = regexp.MustCompile(`(?i)<br\s*/?>`)
+	// markupTag matches the tags of the markup of the specification, and
+	// the <br/> tags of WithPreserveNewlines.
+	markupTag = regexp.MustCompile(`(?i)</?(?:a|b|br|i|img|u)\b[^>]*>`)
+)
+
+// WithPreserveNewlines makes the Notifier turn the newlines of bodies into
+// <br/> tags if the daemon has the "body-markup" capability: such daemons
+// collapse plain newlines like HTML does. Bodies already holding a <br> tag
+// are left as they are, as they already break their lines, and so are the
+// bodies sent to plain text daemons.
+//
+// Bodies are converted as they are sent, so after any escaping done by the
+// application or by NewHTTPHandler.
+func WithPreserveNewlines(preserve bool) Option {
+	return func(nf *Notifier) error {
+		nf.preserveNewlines = preserve
+		return nil
+	}
+}
+
+// newlines returns body with its newlines turned into line breaks, if nf
+// preserves them and the daemon reads markup.
+func (nf *Notifier) newlines(body string) string {
+	if !nf.preserveNewlines || !nf.onBus() || !strings.Contains(body, "\n") || brTag.MatchString(body) {
+		return body
+	}
+	if f, err := nf.features(); err != nil || !f.BodyMarkup {
+		return body
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "<br/>")
+}
+
+// adaptBody returns body with its images replaced by their alternative text
+// if the daemon does not support them, and true if it replaced any.
+func (nf *Notifier) adaptBody(body string) (string, bool) {
+	if !nf.onBus() || !imgTag.MatchString(body) {
+		return body, false
+	}
+	if f, err := nf.features(); err != nil || f.BodyImages {
+		return body, false
+	}
+	return stripImages(body), true
+}
+
+// stripImages replaces the img tags of body by their alternative text.
+func stripImages(body string) string {
+	return imgTag.ReplaceAllStringFunc(body, func(tag string) string {
+		m := imgAlt.FindStringSubmatch(tag)
+		if m == nil {
+			return ""
+		}
+		return m[1] + m[2]
+	})
+}
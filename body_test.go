@@ -0,0 +1,124 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"html"
+	"image"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func writePNG(t *testing.T, name string) string {
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBodyImage(t *testing.T) {
+	img := writePNG(t, `a&"b.png`)
+	text := filepath.Join(t.TempDir(), "notes.png")
+	if err := os.WriteFile(text, []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path, alt, want string
+	}{
+		{img, "<ok>", `<img src="` + filepath.Dir(img) + `/a&amp;&#34;b.png" alt="&lt;ok&gt;"/>`},
+		{"file://" + img, "ok", `<img src="file://` + filepath.Dir(img) + `/a&amp;&#34;b.png" alt="ok"/>`},
+		{text, "not <an> image", "not &lt;an&gt; image"},
+		{"/does/not/exist.png", "missing", "missing"},
+		{"relative.png", "relative", "relative"},
+		{filepath.Dir(img), "dir", "dir"},
+	}
+	for _, tt := range tests {
+		if got := notify.BodyImage(tt.path, tt.alt); got != tt.want {
+			t.Errorf("BodyImage(%q, %q) = %q, want %q", tt.path, tt.alt, got, tt.want)
+		}
+	}
+}
+
+func TestBodyImageStripped(t *testing.T) {
+	img := writePNG(t, "smile.png")
+	body := "hi " + notify.BodyImage(img, ":-)") + " <b>there</b> <IMG src='x.png'>"
+
+	tests := []struct {
+		name string
+		caps []string
+		want string
+	}{
+		{"supported", []string{"body", "body-markup", "body-images"}, body},
+		{"unsupported", []string{"body", "body-markup"}, "hi :-) <b>there</b> "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			s.setCapabilities(tt.caps...)
+			nf := newTestNotifier(t)
+			if _, err := nf.Notify(&notify.Notification{Summary: "chat", Body: body}); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.last(t).Body; got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreserveNewlines(t *testing.T) {
+	markup := []string{"body", "body-markup"}
+	tests := []struct {
+		name string
+		caps []string
+		body string
+		want string
+	}{
+		{"markup", markup, "line 1\nline 2\n", "line 1<br/>line 2<br/>"},
+		{"crlf", markup, "line 1\r\nline 2", "line 1<br/>line 2"},
+		{"escaped", markup, html.EscapeString("a < b\n\"c\""), "a &lt; b<br/>&#34;c&#34;"},
+		{"already broken", markup, "line 1<BR />line 2\nline 3", "line 1<BR />line 2\nline 3"},
+		{"plain text", []string{"body"}, "line 1\nline 2", "line 1\nline 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			s.setCapabilities(tt.caps...)
+			nf := newTestNotifier(t, notify.WithPreserveNewlines(true))
+			if _, err := nf.Notify(&notify.Notification{Summary: "log", Body: tt.body}); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.last(t).Body; got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPreserveNewlinesHTTP checks that posted bodies are escaped before their
+// newlines are converted, so that the line breaks are not escaped.
+func TestPreserveNewlinesHTTP(t *testing.T) {
+	s := newFakeServer(t)
+	s.setCapabilities("body", "body-markup")
+	h := notify.NewHTTPHandler(newTestNotifier(t, notify.WithPreserveNewlines(true)), notify.HandlerOptions{})
+	if w := post(h, "", `{"summary": "build", "body": "<b>1</b> failed\nsee log"}`); w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if got, want := s.last(t).Body, "&lt;b&gt;1&lt;/b&gt; failed<br/>see log"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
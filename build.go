@@ -0,0 +1,99 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// BuildActions returns the actions array of a Call for actions, or a
+// *ValidationError if an action has an empty key, the key of another one,
+// or invalid UTF-8 text.
+func BuildActions(actions ...Action) ([]string, error) {
+	if errs := checkActions(actions); len(errs) > 0 {
+		return nil, &ValidationError{errs}
+	}
+	return actionsArray(actions), nil
+}
+
+// BuildHints returns the hints map of a Call holding the hints hs, or a
+// *ValidationError if a hint is nil, cannot be sent over D-Bus, or has the
+// wrong type for its key. Later hints replace the earlier ones with the same
+// key.
+func BuildHints(hs ...Hint) (map[string]dbus.Variant, error) {
+	hints := make(map[string]dbus.Variant, len(hs))
+	var errs []error
+	for i, h := range hs {
+		if err := encodeHint(hints, h); err != nil {
+			errs = append(errs, &FieldError{fmt.Sprintf("hint %d", i), err})
+		}
+	}
+	errs = append(errs, checkHints(hints)...)
+	if len(errs) > 0 {
+		return nil, &ValidationError{errs}
+	}
+	return hints, nil
+}
+
+// encodeHint stores h in hints, returning an error wrapping ErrHintType if
+// h is nil or its encoding panics, as MakeVariant does for the values that
+// cannot be sent.
+func encodeHint(hints map[string]dbus.Variant, h Hint) (err error) {
+	if h == nil {
+		return fmt.Errorf("%w: nil hint", ErrHintType)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: cannot encode %T: %v", ErrHintType, h, r)
+		}
+	}()
+	h.EncodeHint(hints)
+	return nil
+}
+
+// Validate returns a *ValidationError listing everything that makes the
+// daemon reject c as a whole: an actions array with a key without a label,
+// empty or duplicate action keys, invalid UTF-8 text, or hints that cannot
+// be sent or have the wrong type for their key. Use BuildActions and
+// BuildHints to build valid calls.
+func (c Call) Validate() error {
+	var errs []error
+	for _, f := range []struct{ name, s string }{
+		{"app name", c.AppName},
+		{"app icon", c.AppIcon},
+		{"summary", c.Summary},
+		{"body", c.Body},
+	} {
+		if !utf8.ValidString(f.s) {
+			errs = append(errs, &FieldError{f.name, ErrInvalidUTF8})
+		}
+	}
+	if len(c.Actions)%2 != 0 {
+		errs = append(errs, &FieldError{"actions", fmt.Errorf("%w: %q", ErrOddActions, c.Actions[len(c.Actions)-1])})
+	}
+	actions := make([]Action, 0, len(c.Actions)/2)
+	for i := 0; i+1 < len(c.Actions); i += 2 {
+		actions = append(actions, Action{c.Actions[i], c.Actions[i+1]})
+	}
+	errs = append(errs, checkActions(actions)...)
+	errs = append(errs, checkHints(c.Hints)...)
+	if len(errs) > 0 {
+		return &ValidationError{errs}
+	}
+	return nil
+}
+
+// RawNotifyChecked is like RawNotify, but returns the error of c.Validate
+// without making the call if c is not valid.
+func (nf *Notifier) RawNotifyChecked(ctx context.Context, c Call) (id uint32, err error) {
+	if err := c.Validate(); err != nil {
+		return 0, err
+	}
+	return nf.RawNotify(ctx, c)
+}
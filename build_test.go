@@ -0,0 +1,95 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/godbus/dbus/v5"
+)
+
+// chanHint is a hint that cannot be sent over D-Bus.
+type chanHint struct{}
+
+func (chanHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["x-chan"] = dbus.MakeVariant(make(chan int))
+}
+
+func TestBuildActions(t *testing.T) {
+	got, err := notify.BuildActions(notify.Action{"open", "Open"}, notify.Action{"later", "Later"})
+	if want := []string{"open", "Open", "later", "Later"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildActions = %q, %v, want %q", got, err, want)
+	}
+	for _, tt := range []struct {
+		actions []notify.Action
+		want    error
+	}{
+		{[]notify.Action{{"", "Open"}}, notify.ErrEmptyActionKey},
+		{[]notify.Action{{"open", "Open"}, {"open", "Again"}}, notify.ErrDuplicateAction},
+		{[]notify.Action{{"open", "\xff"}}, notify.ErrInvalidUTF8},
+	} {
+		if _, err := notify.BuildActions(tt.actions...); !errors.Is(err, tt.want) {
+			t.Errorf("BuildActions(%q) = %v, want %v", tt.actions, err, tt.want)
+		}
+	}
+}
+
+func TestBuildHints(t *testing.T) {
+	hints, err := notify.BuildHints(notify.CategoryHint("email"), notify.TransientHint(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hints) != 2 || hints["category"].Value() != "email" || hints["transient"].Value() != true {
+		t.Errorf("BuildHints = %v", hints)
+	}
+	for _, hs := range [][]notify.Hint{{nil}, {chanHint{}}} {
+		if _, err := notify.BuildHints(hs...); !errors.Is(err, notify.ErrHintType) {
+			t.Errorf("BuildHints(%v) = %v, want %v", hs, err, notify.ErrHintType)
+		}
+	}
+	if _, err := notify.NewNotifier(notify.WithDefaultHints(chanHint{})); !errors.Is(err, notify.ErrHintType) {
+		t.Errorf("WithDefaultHints of an invalid hint: %v", err)
+	}
+}
+
+func TestRawNotifyChecked(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	for _, tt := range []struct {
+		call notify.Call
+		want error
+	}{
+		{notify.Call{Summary: "odd", Actions: []string{"open", "Open", "later"}}, notify.ErrOddActions},
+		{notify.Call{Summary: "empty key", Actions: []string{"", "Open"}}, notify.ErrEmptyActionKey},
+		{notify.Call{Summary: "bad hint", Hints: map[string]dbus.Variant{"urgency": dbus.MakeVariant("high")}}, notify.ErrHintType},
+	} {
+		if _, err := nf.RawNotifyChecked(context.Background(), tt.call); !errors.Is(err, tt.want) {
+			t.Errorf("%s: RawNotifyChecked = %v, want %v", tt.call.Summary, err, tt.want)
+		}
+	}
+	if n := s.received(); n != 0 {
+		t.Fatalf("%d invalid calls reached the daemon", n)
+	}
+
+	actions, err := notify.BuildActions(notify.Action{"open", "Open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hints, err := notify.BuildHints(notify.UrgencyHint(notify.CriticalUrgency))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.RawNotifyChecked(context.Background(), notify.Call{Summary: "valid", Actions: actions, Hints: hints, ExpireTimeout: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); !reflect.DeepEqual(got.Actions, actions) {
+		t.Errorf("sent actions %q, want %q", got.Actions, actions)
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build !(linux || freebsd || netbsd || openbsd || dragonfly)
+
+package notify
+
+import "github.com/godbus/dbus/v5"
+
+var newBusTransport func(nf *Notifier) Transport
+
+// dial fails: notification daemons are only reached over D-Bus on the
+// platforms that have it.
+func (nf *Notifier) dial() (*dbus.Conn, error) {
+	return nil, ErrNoTransport
+}
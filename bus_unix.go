@@ -0,0 +1,25 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build linux || freebsd || netbsd || openbsd || dragonfly
+
+package notify
+
+import "github.com/godbus/dbus/v5"
+
+// newBusTransport returns the D-Bus transport of nf, the default one. It is
+// nil on platforms without D-Bus.
+var newBusTransport = func(nf *Notifier) Transport { return busTransport{nf} }
+
+// dial connects to the bus configured for nf.
+func (nf *Notifier) dial() (*dbus.Conn, error) {
+	switch {
+	case nf.address != "":
+		return dbus.Connect(nf.address, nf.connOpts...)
+	case len(nf.connOpts) > 0:
+		return dbus.ConnectSessionBus(nf.connOpts...)
+	default:
+		return dbus.SessionBus()
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+// UserBus is the session bus of a user, see ListUserBuses.
+type UserBus struct {
+	// UID is the user owning the socket of the bus.
+	UID uint32
+	// Path is the path of the socket, and Address the D-Bus address to
+	// give to NotifierForBusAddress.
+	Path    string
+	Address string
+}
+
+// NotifierForBusAddress returns a new Notifier connected to the bus at addr,
+// like the Address of a UserBus, and configured by opts. Each Notifier for
+// an address has its own connection and state, see WithBusAddress, so
+// Notifiers for several buses, such as those of the users of a multi-seat
+// machine, are independent.
+func NotifierForBusAddress(addr string, opts ...Option) (*Notifier, error) {
+	return NewNotifier(append([]Option{WithBusAddress(addr)}, opts...)...)
+}
+
+// busKey identifies the bus of nf, for the IDs of the notifications sent on
+// several buses: the address of its bus, or the empty string for the
+// session bus.
+func (nf *Notifier) busKey() string {
+	for nf.parent != nil {
+		nf = nf.parent
+	}
+	return nf.address
+}
+
+// NotificationID returns the ID of n on the bus of nf, 0 if it was not sent
+// there. The ID field of n is the one of the bus n was last sent on: sending
+// n with Notifiers for different buses, see NotifierForBusAddress, replaces
+// the notification shown on each bus.
+func (nf *Notifier) NotificationID(n *Notification) uint32 {
+	key := nf.busKey()
+	// An ID set by hand is sent as it is.
+	if key == n.bus || n.Id != n.busIDs[n.bus] {
+		return n.Id
+	}
+	return n.busIDs[key]
+}
+
+// setID records that the daemon on the bus of nf gave n the ID id.
+func (nf *Notifier) setID(n *Notification, id uint32) {
+	n.Id = id
+	key := nf.busKey()
+	// The IDs of the session bus are recorded too, or the ID n has there
+	// would pass for one set by hand on the other buses.
+	if n.bus == key && n.busIDs != nil && n.busIDs[key] == id {
+		return
+	}
+	// Copy on write, so that copies of n do not share their IDs.
+	ids := make(map[string]uint32, len(n.busIDs)+1)
+	for k, v := range n.busIDs {
+		ids[k] = v
+	}
+	ids[key] = id
+	n.bus, n.busIDs = key, ids
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build linux || freebsd || netbsd || openbsd || dragonfly
+
+package notify_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/internal/testbus"
+)
+
+// newUserBus starts a bus at root/<uid>/bus, like the session bus of a
+// user, and returns its address.
+func newUserBus(t *testing.T, root, uid string) string {
+	t.Helper()
+	dir := filepath.Join(root, uid)
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return testbus.Start(t, filepath.Join(dir, "bus"))
+}
+
+func TestListUserBuses(t *testing.T) {
+	root := t.TempDir()
+	newUserBus(t, root, "1000")
+	newUserBus(t, root, "1001")
+	// Neither a socket nor in a directory of its own.
+	if err := os.WriteFile(filepath.Join(root, "bus"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "1002"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "1002", "bus"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	buses, err := notify.ListUserBusesIn(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buses) != 2 {
+		t.Fatalf("ListUserBusesIn() = %+v, want the two buses", buses)
+	}
+	for i, uid := range []string{"1000", "1001"} {
+		path := filepath.Join(root, uid, "bus")
+		if b := buses[i]; b.Path != path || b.Address != "unix:path="+path || b.UID != uint32(os.Getuid()) {
+			t.Errorf("bus %d = %+v", i, b)
+		}
+	}
+
+	if _, err := notify.ListUserBusesIn(filepath.Join(root, "missing")); err == nil {
+		t.Error("ListUserBusesIn() of a missing directory succeeded")
+	}
+}
+
+func TestNotifierForBusAddress(t *testing.T) {
+	root := t.TempDir()
+	addrs := []string{newUserBus(t, root, "1000"), newUserBus(t, root, "1001")}
+	servers := []*fakeServer{newFakeServerOnBus(t, addrs[0]), newFakeServerOnBus(t, addrs[1])}
+	// The second daemon counts its IDs from elsewhere.
+	servers[1].mu.Lock()
+	servers[1].lastID = 100
+	servers[1].mu.Unlock()
+	var nfs []*notify.Notifier
+	for _, addr := range addrs {
+		nf, err := notify.NotifierForBusAddress(addr, notify.WithAppName("agent"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { nf.Close() })
+		nfs = append(nfs, nf)
+	}
+
+	n := notify.New("", "backup done", "", "", 0, notify.NormalUrgency)
+	var ids []uint32
+	for _, nf := range nfs {
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, n.Id)
+	}
+	if ids[0] == ids[1] {
+		t.Fatalf("both daemons gave the ID %d", ids[0])
+	}
+	// Sending n again replaces the notification on each bus.
+	n.Summary = "backup verified"
+	for i, nf := range nfs {
+		if got := nf.NotificationID(n); got != ids[i] {
+			t.Errorf("NotificationID() on bus %d = %d, want %d", i, got, ids[i])
+		}
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		sent := servers[i].notifications()
+		if len(sent) != 2 || sent[0].ReplacesID != 0 || sent[1].ReplacesID != ids[i] || sent[1].AppName != "agent" {
+			t.Errorf("bus %d got %+v", i, sent)
+		}
+	}
+
+	// An ID set by hand is sent as it is.
+	n.Id = 3
+	if got := nfs[0].NotificationID(n); got != 3 {
+		t.Errorf("NotificationID() = %d, want the ID set by hand", got)
+	}
+}
+
+func TestNotifierForBusAddressAfterSessionBus(t *testing.T) {
+	session := newFakeServer(t)
+	addr := newUserBus(t, t.TempDir(), "1000")
+	other := newFakeServerOnBus(t, addr)
+	nf, err := notify.NewNotifier(notify.WithAppName("agent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nf.Close() })
+	user, err := notify.NotifierForBusAddress(addr, notify.WithAppName("agent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { user.Close() })
+
+	n := notify.New("", "backup done", "", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := user.NotificationID(n); got != 0 {
+		t.Errorf("NotificationID() on the other bus = %d, want 0 before sending there", got)
+	}
+	if _, err := user.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if sent := other.last(t); sent.ReplacesID != 0 {
+		t.Errorf("the other bus got a replacement of %d, the ID on the session bus", sent.ReplacesID)
+	}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	sent := session.notifications()
+	if len(sent) != 2 || sent[1].ReplacesID != sent[0].ID {
+		t.Errorf("the session bus got %+v, want the first notification replaced", sent)
+	}
+}
@@ -0,0 +1,482 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Call holds the arguments of a Notify call exactly as they are sent to the
+// notification daemon, after the Notifier has applied its defaults, tags and
+// the hints required by the daemon.
+//
+// Actions and Hints may be shared with later calls for the same
+// notification, so they must not be modified. The hints are sent sorted by
+// key, see SortedHints, so that identical calls are identical on the wire.
+type Call struct {
+	AppName       string
+	ReplacesID    uint32
+	AppIcon       string
+	Summary       string
+	Body          string
+	Actions       []string
+	Hints         map[string]dbus.Variant
+	ExpireTimeout int32
+
+	// Urgency is the urgency of the notification. It is not sent to the
+	// daemon, which gets the urgency hint instead: that hint holds the byte
+	// Urgency is mapped to by WithUrgencyMap, if any.
+	Urgency NotificationUrgency
+
+	// CorrelationID is the correlation ID of the notification, see
+	// Notification.CorrelationID. It is not sent to the daemon, but lets
+	// transports and mirrors trace the call.
+	CorrelationID string
+
+	// Sanitized are the characters removed or escaped from the summary and
+	// body, see WithControlSanitizer. They are not sent to the daemon.
+	Sanitized []SanitizedChar
+
+	// strippedImages is true if the images of the body were replaced by
+	// their alternative text, see Preview.
+	strippedImages bool
+	// imageFile is the cached file sent in place of the embedded image,
+	// and imageOpaque is true if the image is sent without its alpha
+	// channel, see WithImageCache.
+	imageFile   string
+	imageOpaque bool
+	// stringID is the ID of the notification for a StringIDTransport.
+	stringID string
+}
+
+// HintEntry is a hint of a Call.
+type HintEntry struct {
+	Key   string
+	Value dbus.Variant
+}
+
+// SortedHints returns the hints of c sorted by key, in the order they are
+// sent to the daemon.
+func (c Call) SortedHints() []HintEntry {
+	hints := make([]HintEntry, 0, len(c.Hints))
+	for k, v := range c.Hints {
+		hints = append(hints, HintEntry{k, v})
+	}
+	sort.Slice(hints, func(i, j int) bool { return hints[i].Key < hints[j].Key })
+	return hints
+}
+
+// notifySignature is the signature of the Notify method.
+var notifySignature = dbus.ParseSignatureMust("susssasa{sv}i")
+
+// notifyMessage returns the Notify call c to the daemon at path on the bus
+// name dest. godbus sends maps in a random order, so the hints are given as
+// the array of their entries, which is encoded the same as the dictionary
+// of the signature.
+func notifyMessage(dest string, path dbus.ObjectPath, flags dbus.Flags, c Call) *dbus.Message {
+	return &dbus.Message{
+		Type:  dbus.TypeMethodCall,
+		Flags: flags,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldDestination: dbus.MakeVariant(dest),
+			dbus.FieldPath:        dbus.MakeVariant(path),
+			dbus.FieldInterface:   dbus.MakeVariant(dbusInterface),
+			dbus.FieldMember:      dbus.MakeVariant("Notify"),
+			dbus.FieldSignature:   dbus.MakeVariant(notifySignature),
+		},
+		Body: []interface{}{c.AppName, c.ReplacesID, c.AppIcon, c.Summary, c.Body, c.Actions, c.SortedHints(), c.ExpireTimeout},
+	}
+}
+
+// ImageSize returns the size in bytes of the pixels of the raw image
+// embedded in c, or 0 if there is none.
+func (c Call) ImageSize() int {
+	if v, ok := c.Hints[c.ImageHintKey()]; ok {
+		if d, ok := v.Value().(imageData); ok {
+			return len(d.Data)
+		}
+	}
+	return 0
+}
+
+// ImageHintKey returns the name of the hint holding the raw image embedded
+// in c, or the empty string if there is none. See WithImageHintKey.
+func (c Call) ImageHintKey() string {
+	for _, key := range imageHintKeys {
+		if _, ok := c.Hints[key]; ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// DryRun returns the call that notifier would make to send n, without
+// sending anything. If notifier is nil, the default Notifier used by the
+// package-level functions is used.
+//
+// DryRun may still talk to the daemon to find out which hints it expects.
+func (n *Notification) DryRun(notifier *Notifier) (Call, error) {
+	if notifier == nil {
+		notifier = Default()
+	}
+	return notifier.prepare(n)
+}
+
+// prepare returns the call sending n. It is shared by DryRun and Notify, so
+// that DryRun shows exactly what Notify sends.
+func (nf *Notifier) prepare(n *Notification) (Call, error) {
+	id := nf.NotificationID(n)
+	if id == 0 && n.Tag != "" {
+		id = nf.taggedID(n.Tag)
+	}
+	icon := n.IconPath
+	if icon == "" && n.icon == nil {
+		icon = nf.categoryIcon(n)
+	}
+	if icon == "" {
+		icon = nf.appIcon
+	}
+	if n.icon != nil {
+		if path, err := nf.tempFiles().write(n.icon, n.iconExt); err != nil {
+			nf.log(LevelWarn, "writing the icon to a temporary file failed, sending the icon path instead", err)
+		} else {
+			icon = path
+		}
+	}
+	if n.image.empty() {
+		return Call{}, fmt.Errorf("notify: %w", &FieldError{"image", ErrEmptyImage})
+	}
+	if err := checkHintValues(n.hints); err != nil {
+		return Call{}, fmt.Errorf("notify: %w", err)
+	}
+	if errs := nf.sizeLimits.check(n); len(errs) > 0 {
+		return Call{}, &ValidationError{errs}
+	}
+	if err := checkCategoryHint(n); err != nil && !nf.looseCategories {
+		return Call{}, fmt.Errorf("notify: %w", err)
+	}
+	if nf.checkImagePath && n.ImagePath != "" && n.image == nil {
+		if err := checkImagePath(n.ImagePath); err != nil {
+			return Call{}, err
+		}
+	}
+	actions, err := nf.pageActions(n, nf.actions(n))
+	if err != nil {
+		return Call{}, err
+	}
+	summary, body := nf.policySummaryBody(n)
+	name, summary, body, errs := nf.applyFieldPolicies(nf.appNameFor(n.Name), summary, body)
+	if len(errs) > 0 {
+		return Call{}, &ValidationError{errs}
+	}
+	summary, body, sanitized := nf.sanitize(summary, body)
+	if nf.hideBody(n) {
+		body = nf.sensitivePlaceholder
+	}
+	body, stripped := nf.adaptBody(nf.newlines(body))
+	hints := nf.cachedHints(n)
+	return Call{
+		AppName:        name,
+		ReplacesID:     id,
+		AppIcon:        icon,
+		Summary:        summary,
+		Body:           body,
+		Actions:        n.cachedActions(actions),
+		Hints:          hints,
+		ExpireTimeout:  nf.expireTimeout(n),
+		Urgency:        n.urgency(),
+		CorrelationID:  n.CorrelationID,
+		stringID:       nf.stringID(n, id),
+		Sanitized:      sanitized,
+		strippedImages: stripped,
+		imageFile:      n.cache.hintsKey.imageFile,
+		imageOpaque:    n.cache.hintsKey.opaque,
+	}, nil
+}
+
+// sendCache holds the actions and hints computed for the last send of a
+// notification, so that sending it again unchanged, like a progress
+// notification, does not allocate them again.
+type sendCache struct {
+	actions      []Action
+	actionsArray []string
+
+	hintsKey hintsKey
+	hints    map[string]dbus.Variant
+}
+
+// hintsKey holds everything the hints of a notification are computed from.
+type hintsKey struct {
+	urgency      NotificationUrgency
+	tag          string
+	resident     bool
+	image        *imageData
+	imagePath    string
+	major, minor int
+	monitor      string
+	windowID     uint32
+	opaque       bool
+	// imageFile is the cached file of image, see WithImageCache.
+	imageFile string
+	// level is the revision of the specification of the daemon, only set
+	// for the hints depending on it, see SpecLevel.
+	level SpecLevel
+
+	// customGen is the version of the hints set with SetHint.
+	customGen uint64
+}
+
+// actions returns the actions to send with n: its own, translated if n is
+// Localized, and the AckAction if it has to be acknowledged.
+func (nf *Notifier) actions(n *Notification) []Action {
+	actions := n.Actions
+	if n.Localized {
+		actions = nf.localizeActions(actions)
+	}
+	if nf.needsRepost(n) && !hasAction(actions, AckAction) {
+		actions = append(actions[:len(actions):len(actions)], Action{AckAction, nf.localize(MsgAck)})
+	}
+	return actions
+}
+
+// cachedActions returns the actions array for actions, reusing the one of the
+// last send of n if the actions did not change.
+func (n *Notification) cachedActions(actions []Action) []string {
+	c := &n.cache
+	if c.actionsArray != nil && actionsEqual(c.actions, actions) {
+		return c.actionsArray
+	}
+	c.actions = append(c.actions[:0], actions...)
+	c.actionsArray = actionsArray(actions)
+	return c.actionsArray
+}
+
+func actionsEqual(a, b []Action) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// cachedHints returns the hints of n, reusing the ones of the last send if
+// nothing they depend on changed.
+func (nf *Notifier) cachedHints(n *Notification) map[string]dbus.Variant {
+	key := hintsKey{
+		urgency:   n.baseUrgency(),
+		tag:       n.Tag,
+		resident:  (len(n.Actions) > 0 || nf.needsRepost(n)) && !n.CloseOnAction,
+		image:     n.image,
+		imagePath: n.ImagePath,
+		monitor:   n.monitor,
+		windowID:  n.windowID,
+		customGen: n.hintsGen,
+	}
+	if key.image != nil {
+		key.opaque = nf.onBus() && nf.daemonQuirks().OpaqueImages
+		key.imageFile = nf.imageFile(key.image, key.opaque)
+	}
+	if spec := nf.imageSpec; spec != nil {
+		key.major, key.minor = spec[0], spec[1]
+	} else if key.image != nil || key.imagePath != "" {
+		var err error
+		key.major, key.minor, err = nf.SpecVersion()
+		if err != nil {
+			key.major, key.minor = latestSpecMajor, latestSpecMinor
+		}
+	}
+
+	_, soundFile := n.hints["sound-file"]
+	if key.windowID != 0 && waylandSession() || nf.senderPID || soundFile {
+		key.level, _ = nf.SpecLevel()
+	}
+
+	c := &n.cache
+	if c.hints != nil && c.hintsKey == key {
+		return c.hints
+	}
+	c.hintsKey = key
+	c.hints = nf.hints(key, n.hints)
+	return c.hints
+}
+
+// hints returns the hints described by key, and the custom ones. Without
+// any, it is the shared map of the urgency; otherwise that map is copied
+// before the others are merged in.
+func (nf *Notifier) hints(key hintsKey, custom map[string]interface{}) map[string]dbus.Variant {
+	shared := nf.urgencyHint(key.urgency)
+	if len(nf.defaultHints) == 0 && key.tag == "" && !key.resident && key.image == nil &&
+		key.imagePath == "" && key.monitor == "" && key.windowID == 0 && len(custom) == 0 && !nf.senderPID {
+		return shared
+	}
+	hints := make(map[string]dbus.Variant, len(shared)+len(nf.defaultHints)+len(custom)+2)
+	for k, v := range shared {
+		hints[k] = v
+	}
+	for k, v := range nf.defaultHints {
+		if _, ok := hints[k]; !ok {
+			hints[k] = v
+		}
+	}
+	if key.tag != "" {
+		tag := dbus.MakeVariant(key.tag)
+		for _, k := range nf.stackTagHints() {
+			hints[k] = tag
+		}
+	}
+	if key.resident {
+		hints["resident"] = dbus.MakeVariant(true)
+	}
+	switch {
+	case key.image != nil:
+		if key.imagePath != "" {
+			nf.log(LevelWarn, fmt.Sprintf("dropping image path %q in favor of the embedded image", key.imagePath), nil)
+		}
+		if key.imageFile != "" {
+			hints[imagePathHintKey(key.major, key.minor)] = dbus.MakeVariant("file://" + key.imageFile)
+			break
+		}
+		image := key.image
+		if key.opaque {
+			image = image.opaque()
+		}
+		hints[imageHintKey(key.major, key.minor)] = dbus.MakeVariant(*image)
+	case key.imagePath != "":
+		hints[imagePathHintKey(key.major, key.minor)] = dbus.MakeVariant(key.imagePath)
+	}
+	if key.monitor != "" {
+		nf.monitorHint(hints, key.monitor)
+	}
+	if key.windowID != 0 {
+		nf.windowIDHints(hints, key.windowID, key.level)
+	}
+	if nf.senderPID {
+		hints["sender-pid"] = senderPIDHint(key.level)
+	}
+	encodeHints(hints, custom)
+	if key.level.StrictSoundHints {
+		nf.absSoundFile(hints)
+	}
+	nf.mapUrgencyHint(hints, custom)
+	return hints
+}
+
+// RawNotifyAsync makes the Notify call c without waiting for the reply, and
+// returns the pending call. Like with godbus, ch receives the call once it
+// is complete; if ch is nil, a new channel is created, available as the
+// Done field of the call. Use NotifyReply to get the ID from the reply.
+//
+// c is sent as it is: unlike Notify, no defaults, tags or daemon-specific
+// hints are applied, and no signals will be dispatched for the notification.
+// RawNotifyAsync uses the connection of nf and may be called concurrently.
+func (nf *Notifier) RawNotifyAsync(ctx context.Context, c Call, ch chan *dbus.Call) *dbus.Call {
+	method := dbusInterface + ".Notify"
+	conn, err := nf.connection()
+	if err != nil {
+		if ch == nil {
+			ch = make(chan *dbus.Call, 1)
+		}
+		call := &dbus.Call{Destination: nf.destination, Path: nf.path, Method: method, Err: err, Done: ch}
+		select {
+		case ch <- call:
+		default:
+		}
+		return call
+	}
+	return conn.SendWithContext(ctx, notifyMessage(nf.destination, nf.path, nf.callFlags(), c), ch)
+}
+
+// RawNotify makes the Notify call c and returns the ID assigned by the
+// daemon. See RawNotifyAsync.
+func (nf *Notifier) RawNotify(ctx context.Context, c Call) (id uint32, err error) {
+	id, _, err = nf.rawNotifyExtra(ctx, c)
+	return id, err
+}
+
+// rawNotifyExtra is RawNotify, also returning the extra out-arguments of the
+// reply.
+func (nf *Notifier) rawNotifyExtra(ctx context.Context, c Call) (uint32, []interface{}, error) {
+	call := <-nf.RawNotifyAsync(ctx, c, make(chan *dbus.Call, 1)).Done
+	return NotifyReplyExtra(call)
+}
+
+// NotifyReply returns the notification ID from a complete Notify call, see
+// NotifyReplyExtra.
+func NotifyReply(call *dbus.Call) (id uint32, err error) {
+	id, _, err = NotifyReplyExtra(call)
+	return id, err
+}
+
+// NotifyReplyExtra returns the notification ID from a complete Notify call,
+// and the out-arguments following it, which some daemons add, like an
+// activation token. The ID of the daemons replying with an int32 instead of
+// a uint32 is accepted as well.
+func NotifyReplyExtra(call *dbus.Call) (id uint32, extra []interface{}, err error) {
+	if call.Err != nil {
+		return 0, nil, call.Err
+	}
+	id, extra, ok := replyID(call.Body)
+	if !ok {
+		return 0, nil, errUnrecognizedResponse
+	}
+	return id, extra, nil
+}
+
+// replyID returns the ID at the start of the body of a Notify reply and the
+// values following it, or false if the body does not start with an ID.
+func replyID(body []interface{}) (uint32, []interface{}, bool) {
+	if len(body) == 0 {
+		return 0, nil, false
+	}
+	var id uint32
+	switch v := body[0].(type) {
+	case uint32:
+		id = v
+	case int32:
+		if v < 0 {
+			return 0, nil, false
+		}
+		id = uint32(v)
+	default:
+		return 0, nil, false
+	}
+	if len(body) == 1 {
+		return id, nil, true
+	}
+	return id, body[1:], true
+}
+
+// send delivers the Notify call c via the transport of nf with ctx, and
+// returns the ID assigned by the daemon.
+func (nf *Notifier) send(ctx context.Context, c Call) (id uint32, err error) {
+	id, _, err = nf.sendExtra(ctx, c)
+	return id, err
+}
+
+// sendExtra is send, also returning the extra out-arguments of the reply
+// if the transport reports them.
+func (nf *Notifier) sendExtra(ctx context.Context, c Call) (uint32, []interface{}, error) {
+	if nf.transport == nil {
+		return 0, nil, ErrNoTransport
+	}
+	ctx = nf.execContext(ctx)
+	if t, ok := nf.stringIDTransport(); ok && c.stringID != "" {
+		id, err := nf.sendStringID(ctx, t, c)
+		return id, nil, err
+	}
+	if t, ok := nf.transport.(extraReplier); ok {
+		return t.notifyExtra(ctx, c)
+	}
+	id, err := nf.transport.Notify(ctx, c)
+	return id, nil, err
+}
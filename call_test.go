@@ -0,0 +1,58 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestNotifyReplyShapes(t *testing.T) {
+	tests := []struct {
+		name   string
+		daemon func(*fakeServer) interface{}
+		extra  []interface{}
+	}{
+		{"token", func(s *fakeServer) interface{} { return tokenReplyDaemon{fakeDaemon{s}} }, []interface{}{"token"}},
+		{"int32", func(s *fakeServer) interface{} { return int32ReplyDaemon{fakeDaemon{s}} }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			s.setReplyShape(t, tt.daemon(s))
+			nf := newTestNotifier(t)
+
+			n := notify.New("test", "hello", "", "", 0, notify.NormalUrgency)
+			res, err := nf.Notify(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := s.last(t).ID; res.Id != want || n.Id != want {
+				t.Errorf("ID = %d (notification %d), want %d", res.Id, n.Id, want)
+			}
+			if !reflect.DeepEqual(res.Extra, tt.extra) {
+				t.Errorf("Extra = %#v, want %#v", res.Extra, tt.extra)
+			}
+
+			// Replacing it works as well.
+			n.Summary = "hello again"
+			if res, err = nf.Notify(n); err != nil {
+				t.Fatal(err)
+			} else if !res.ReusedID {
+				t.Errorf("replacement = %+v, want the ID reused", res)
+			}
+		})
+	}
+}
+
+func TestNotifyReplyWithoutID(t *testing.T) {
+	s := newFakeServer(t)
+	s.setReplyShape(t, stringReplyDaemon{fakeDaemon{s}})
+	if _, err := newTestNotifier(t).Notify(notify.New("test", "hello", "", "", 0, notify.NormalUrgency)); err == nil {
+		t.Error("Notify() succeeded with a reply without an ID")
+	}
+}
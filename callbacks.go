@@ -0,0 +1,107 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"sync"
+)
+
+// callbackQueue is the capacity of the queue of each worker of a Pool.
+const callbackQueue = 64
+
+// Execution is how the Notifier runs the OnAction, OnReply and OnClose
+// callbacks, see WithCallbackExecution.
+type Execution struct {
+	concurrent bool
+	workers    int
+}
+
+var (
+	// Serial runs the callbacks one at a time, in the order of the signals,
+	// on the goroutine dispatching the signals: a slow callback delays all
+	// the others, and the events of the Notifier.
+	Serial = Execution{}
+	// Concurrent runs each callback on its own goroutine, in no particular
+	// order.
+	Concurrent = Execution{concurrent: true}
+)
+
+// Pool returns the Execution running the callbacks on n workers, or Serial
+// if n is 0. The
+// callbacks of a notification always run on the same worker, in the order
+// of the signals, and a slow callback only delays those sharing its worker.
+// When the queue of a worker is full, the dispatch of the signals waits.
+func Pool(n int) Execution {
+	return Execution{workers: n}
+}
+
+// WithCallbackExecution sets how the callbacks of the notifications are run.
+// The default is Serial.
+func WithCallbackExecution(e Execution) Option {
+	return func(nf *Notifier) error {
+		if e.workers < 0 || e.concurrent && e.workers > 0 {
+			return fmt.Errorf("notify: invalid callback pool of %d workers", e.workers)
+		}
+		nf.execution = e
+		return nil
+	}
+}
+
+// callbackPool is the set of workers of a Pool.
+type callbackPool struct {
+	mu     sync.Mutex
+	queues []chan func()
+}
+
+// run queues fn on the worker of id, starting the workers if needed.
+func (p *callbackPool) run(workers int, id uint32, fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.queues == nil {
+		p.queues = make([]chan func(), workers)
+		for i := range p.queues {
+			q := make(chan func(), callbackQueue)
+			p.queues[i] = q
+			go func() {
+				for fn := range q {
+					fn()
+				}
+			}()
+		}
+	}
+	p.queues[id%uint32(len(p.queues))] <- fn
+}
+
+// stop stops the workers once their queues are drained.
+func (p *callbackPool) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, q := range p.queues {
+		close(q)
+	}
+	p.queues = nil
+}
+
+// runCallback runs the callback fn for the notification id as set by
+// WithCallbackExecution, see callback.
+func (nf *Notifier) runCallback(what string, id uint32, fn func()) {
+	switch e := nf.execution; {
+	case e.concurrent:
+		nf.callbacksRunning.Add(1)
+		go func() {
+			defer nf.callbacksRunning.Done()
+			nf.callback(what, id, fn)
+		}()
+	case e.workers > 0:
+		nf.callbacksRunning.Add(1)
+		nf.pool.run(e.workers, id, func() {
+			defer nf.callbacksRunning.Done()
+			nf.callback(what, id, fn)
+		})
+	default:
+		nf.callback(what, id, fn)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// TestSlowCallback checks that with Concurrent and Pool, a callback blocked
+// on one notification does not delay the callbacks of another.
+func TestSlowCallback(t *testing.T) {
+	for _, e := range []struct {
+		name string
+		exec notify.Execution
+	}{
+		{"Concurrent", notify.Concurrent},
+		{"Pool", notify.Pool(4)},
+	} {
+		t.Run(e.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			nf := newTestNotifier(t, notify.WithCallbackExecution(e.exec))
+			events := nf.Events()
+
+			release := make(chan struct{})
+			defer close(release)
+			fast := make(chan string, 16)
+			send := func(summary string, onAction func(string)) *notify.Notification {
+				n := notify.New("test", summary, "", "", 0, notify.NormalUrgency)
+				n.AddAction(notify.DefaultAction, "Open")
+				n.OnAction = onAction
+				if _, err := nf.Notify(n); err != nil {
+					t.Fatal(err)
+				}
+				waitEvent(t, events, notify.EventSent)
+				return n
+			}
+			slow := send("slow", func(string) { <-release })
+			var others []*notify.Notification
+			for i := 0; i < 3; i++ {
+				others = append(others, send("fast", func(key string) { fast <- key }))
+			}
+
+			s.emitAction(slow.Id, notify.DefaultAction)
+			for _, n := range others {
+				for i := 0; i < 10; i++ {
+					s.emitAction(n.Id, notify.DefaultAction)
+				}
+			}
+			start := time.Now()
+			for i := 0; i < 30; i++ {
+				select {
+				case <-fast:
+				case <-waitTimeout():
+					t.Fatalf("only %d fast callbacks while the slow one runs", i)
+				}
+			}
+			if d := time.Since(start); d > time.Second {
+				t.Errorf("the fast callbacks took %v", d)
+			}
+		})
+	}
+}
+
+// TestCallbackOrder checks that Serial and Pool call the callbacks of a
+// notification in the order of the signals, even when they are slow.
+func TestCallbackOrder(t *testing.T) {
+	for _, e := range []struct {
+		name string
+		exec notify.Execution
+	}{
+		{"Serial", notify.Serial},
+		{"Pool", notify.Pool(2)},
+	} {
+		t.Run(e.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			nf := newTestNotifier(t, notify.WithCallbackExecution(e.exec))
+			events := nf.Events()
+
+			var mu sync.Mutex
+			var got []string
+			record := func(s string) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, s)
+			}
+			n := notify.New("test", "ordered", "", "", 0, notify.NormalUrgency)
+			n.AddAction("a", "A")
+			n.AddAction("b", "B")
+			n.OnAction = func(key string) {
+				time.Sleep(10 * time.Millisecond)
+				record(key)
+			}
+			n.OnClose = func(notify.CloseReason) { record("closed") }
+			if _, err := nf.Notify(n); err != nil {
+				t.Fatal(err)
+			}
+			waitEvent(t, events, notify.EventSent)
+
+			s.emitAction(n.Id, "a")
+			s.emitAction(n.Id, "b")
+			s.emitAction(n.Id, "a")
+			s.emitClosed(n.Id, uint32(notify.ReasonDismissed))
+			want := []string{"a", "b", "a", "closed"}
+			waitFor(t, "the callbacks", func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(got) == len(want)
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("callbacks in order %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCallbackExecutionInvalid(t *testing.T) {
+	if _, err := notify.NewNotifier(notify.WithCallbackExecution(notify.Pool(-1))); err == nil {
+		t.Error("a pool of -1 workers was accepted")
+	}
+}
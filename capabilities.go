@@ -0,0 +1,150 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// The latest version of the specification known to this package. Daemons
+// reporting an unknown or unparsable version are assumed to follow it.
+const (
+	latestSpecMajor = 1
+	latestSpecMinor = 2
+)
+
+// ServerInfo describes the notification daemon, as returned by the
+// GetServerInformation method of the specification.
+type ServerInfo struct {
+	// Name is the product name of the daemon, for example "dunst".
+	Name string
+	// Vendor is the vendor name, for example "KDE" or "GNOME".
+	Vendor string
+	// Version is the version of the daemon itself.
+	Version string
+	// SpecVersion is the version of the specification the daemon claims
+	// to be compliant with, for example "1.2".
+	SpecVersion string
+}
+
+// ServerInfo returns information about the notification daemon.
+//
+// The information is cached after the first successful call, by the Core
+// of nf if it has one, see Snapshot.
+func (nf *Notifier) ServerInfo() (ServerInfo, error) {
+	if nf.core != nil {
+		return nf.core.root.ServerInfo()
+	}
+	p := nf.probeDaemon(context.Background(), true, false)
+	if p.infoErr != nil {
+		return ServerInfo{}, p.infoErr
+	}
+	return *p.info, nil
+}
+
+// Capabilities returns the optional capabilities of the notification daemon,
+// such as "actions" or "body-markup".
+//
+// The capabilities are cached after the first successful call, by the Core
+// of nf if it has one, see Snapshot.
+func (nf *Notifier) Capabilities() ([]string, error) {
+	if nf.core != nil {
+		return nf.core.root.Capabilities()
+	}
+	p := nf.probeDaemon(context.Background(), false, true)
+	if p.capsErr != nil {
+		return nil, p.capsErr
+	}
+	return append([]string(nil), p.caps...), nil
+}
+
+// InvalidateCaches drops the cached server information, capabilities,
+// features and interfaces of the daemon, and the quirks picked by AutoConfigure, so that
+// they are asked again when next needed. Unchanged notifications are sent
+// again too, see WithSkipUnchanged.
+//
+// This is done automatically when another daemon takes over the bus name,
+// for example when the user switches from one daemon to another.
+func (nf *Notifier) InvalidateCaches() {
+	if nf.core != nil {
+		nf.core.root.InvalidateCaches()
+	}
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	nf.info = nil
+	nf.caps = nil
+	nf.feats = nil
+	nf.ifaces = nil
+	nf.probe.taken = time.Time{}
+	nf.probe.gen++
+	nf.lastSent = nil
+	if nf.images != nil {
+		nf.images.newDaemon()
+	}
+	if nf.quirksAuto {
+		nf.quirks = nil
+		nf.quirksAuto = false
+	}
+}
+
+// watchOwner starts listening to the signals of the bus, so that the caches
+// are invalidated when the daemon changes.
+func (nf *Notifier) watchOwner(conn *dbus.Conn) {
+	if err := nf.listen(conn); err != nil {
+		nf.log(LevelWarn, "watching for daemon changes failed", err)
+	}
+}
+
+// Capabilities returns the optional capabilities of the notification daemon,
+// see Notifier.Capabilities.
+func Capabilities() ([]string, error) {
+	return Default().Capabilities()
+}
+
+// SpecVersion returns the version of the specification implemented by the
+// notification daemon. If the daemon reports a version that cannot be
+// parsed, the latest version known to this package is assumed.
+func (nf *Notifier) SpecVersion() (major, minor int, err error) {
+	info, err := nf.ServerInfo()
+	if err != nil {
+		return 0, 0, err
+	}
+	major, minor = parseSpecVersion(info.SpecVersion)
+	return major, minor, nil
+}
+
+// SpecVersion returns the version of the specification implemented by the
+// notification daemon, see Notifier.SpecVersion.
+func SpecVersion() (major, minor int, err error) {
+	return Default().SpecVersion()
+}
+
+// parseSpecVersion parses a "major.minor" version, falling back to the
+// latest known version if v cannot be parsed.
+func parseSpecVersion(v string) (major, minor int) {
+	parts := strings.SplitN(strings.TrimSpace(v), ".", 3)
+	if len(parts) < 2 {
+		return latestSpecMajor, latestSpecMinor
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || major < 0 {
+		return latestSpecMajor, latestSpecMinor
+	}
+	minor, err = strconv.Atoi(parts[1])
+	if err != nil || minor < 0 {
+		return latestSpecMajor, latestSpecMinor
+	}
+	return major, minor
+}
+
+// specAtLeast returns true if major.minor is at least wantMajor.wantMinor.
+func specAtLeast(major, minor, wantMajor, wantMinor int) bool {
+	return major > wantMajor || (major == wantMajor && minor >= wantMinor)
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// catchUpGroups is the number of groups listed in the body of a catch-up
+// notification.
+const catchUpGroups = 5
+
+// ResumePolicy decides what happens to the notifications held back while a
+// Notifier was paused, see ResumeWith.
+type ResumePolicy int
+
+const (
+	// ResumeFlushAll sends the notifications held back, like Resume(true).
+	ResumeFlushAll ResumePolicy = iota
+	// ResumeCollapse sends a single catch-up notification summarizing the
+	// notifications held back, see ResumeWith.
+	ResumeCollapse
+	// ResumeDrop discards the notifications held back, like Resume(false).
+	ResumeDrop
+)
+
+// ResumeWith stops holding back notifications, and handles those held back
+// according to p.
+//
+// With ResumeCollapse, a single catch-up notification is sent instead of
+// them: its body counts them by category, or by application if they have no
+// "category" hint, with the summary of the last one of each. Invoking its
+// DefaultAction sends them individually, like ResumeFlushAll.
+func (nf *Notifier) ResumeWith(p ResumePolicy) error {
+	if p < ResumeFlushAll || p > ResumeDrop {
+		return fmt.Errorf("notify: invalid resume policy %d", p)
+	}
+	nf.mu.Lock()
+	deferred := nf.deferred
+	nf.deferred = nil
+	nf.stats.Paused = false
+	nf.stats.Buffered = 0
+	nf.mu.Unlock()
+
+	switch p {
+	case ResumeCollapse:
+		return nf.sendCatchUp(deferred)
+	case ResumeFlushAll:
+		return nf.flushAll(deferred)
+	}
+	return nil
+}
+
+// flushAll sends held, only the last one of those with the same Tag or
+// content, see collapse. The result is the join of the errors of the sends.
+func (nf *Notifier) flushAll(held []*Notification) error {
+	var errs []error
+	for _, n := range collapse(nf, held) {
+		if _, err := nf.Notify(n); err != nil {
+			summary, _ := nf.redact(n.Summary, "")
+			errs = append(errs, fmt.Errorf("notify: sending %q: %w", summary, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendCatchUp sends the catch-up notification summarizing held, if any.
+func (nf *Notifier) sendCatchUp(held []*Notification) error {
+	if len(held) == 0 {
+		return nil
+	}
+	_, err := nf.Notify(nf.catchUpNotification(held))
+	return err
+}
+
+// catchUpNotification returns the notification summarizing held, which
+// sends them individually when its DefaultAction is invoked.
+func (nf *Notifier) catchUpNotification(held []*Notification) *Notification {
+	type group struct {
+		key   string
+		count int
+		last  string
+	}
+	var groups []*group
+	index := make(map[string]*group)
+	urgency := LowUrgency
+	for _, n := range held {
+		key := nf.appNameFor(n.Name)
+		if v, err := hintValue(n.Hint("category")); err == nil {
+			if category := Render(v.Value()); category != "" {
+				key = category
+			}
+		}
+		g, ok := index[key]
+		if !ok {
+			g = &group{key: key}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.count++
+		g.last, _ = nf.redact(n.Summary, "")
+		if u := n.baseUrgency(); u > urgency {
+			urgency = u
+		}
+	}
+
+	lines := make([]string, len(groups))
+	for i, g := range groups {
+		lines[i] = nf.localize(MsgCatchUpItem, g.count, g.key, g.last)
+	}
+	n := &Notification{
+		Summary: nf.localize(MsgCatchUp),
+		Body:    joinLines(lines, catchUpGroups),
+		Urgency: urgency,
+		Actions: []Action{{DefaultAction, nf.localize(MsgShowAll)}},
+
+		urgencySet: true,
+	}
+	n.OnAction = func(key string) {
+		if key != DefaultAction {
+			return
+		}
+		if err := nf.flushAll(held); err != nil {
+			nf.log(LevelWarn, "sending the held back notifications failed", err)
+		}
+	}
+	return n
+}
+
+// holdCatchUp holds back a copy of n until the end of the quiet hours at
+// until, when the catch-up notification summarizing the notifications held
+// back is sent.
+func (nf *Notifier) holdCatchUp(n *Notification, until time.Time) {
+	cp := *n
+	cp.cache = sendCache{}
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	nf.catchUp.held = append(nf.catchUp.held, &cp)
+	if nf.catchUp.timer == nil {
+		nf.catchUp.timer = nf.afterWall(until.Sub(nf.clock.Now()), nf.fireCatchUp)
+	}
+}
+
+// fireCatchUp sends the catch-up notification of the quiet hours.
+func (nf *Notifier) fireCatchUp() {
+	nf.mu.Lock()
+	held := nf.catchUp.held
+	nf.catchUp = catchUpState{}
+	nf.mu.Unlock()
+
+	if err := nf.sendCatchUp(held); err != nil {
+		nf.log(LevelWarn, "catch-up notification failed", err)
+	}
+}
+
+// catchUpState holds the notifications held back by quiet hours with
+// Collapse set.
+type catchUpState struct {
+	held  []*Notification
+	timer *wallTimer
+}
+
+// stopCatchUp drops the notifications held back for the catch-up
+// notification. It must be called with nf.mu held.
+func (nf *Notifier) stopCatchUp() {
+	if nf.catchUp.timer != nil {
+		nf.catchUp.timer.Stop()
+	}
+	nf.catchUp = catchUpState{}
+}
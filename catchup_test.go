@@ -0,0 +1,119 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/notifytest"
+)
+
+// holdBack sends three failed builds and two mails to nf while it is
+// paused.
+func holdBack(t *testing.T, nf *notify.Notifier) {
+	t.Helper()
+	nf.Pause()
+	for i := 1; i <= 3; i++ {
+		n := notify.New("ci", fmt.Sprintf("build %d failed", i), "", "", 0, notify.NormalUrgency)
+		n.AddHints(notify.CategoryHint("build"))
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= 2; i++ {
+		if _, err := nf.Notify(notify.New("mail", fmt.Sprintf("mail %d", i), "", "", 0, notify.LowUrgency)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResumeWith(t *testing.T) {
+	for _, tt := range []struct {
+		policy notify.ResumePolicy
+		want   []string
+	}{
+		{notify.ResumeFlushAll, []string{"build 1 failed", "build 2 failed", "build 3 failed", "mail 1", "mail 2"}},
+		{notify.ResumeCollapse, []string{"While you were away"}},
+		{notify.ResumeDrop, nil},
+	} {
+		s := newFakeServer(t)
+		nf := newTestNotifier(t)
+		holdBack(t, nf)
+		if err := nf.ResumeWith(tt.policy); err != nil {
+			t.Fatal(err)
+		}
+		if got := summaries(s); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("ResumeWith(%d) sent %q, want %q", tt.policy, got, tt.want)
+		}
+		if nf.Stats().Paused {
+			t.Errorf("ResumeWith(%d) left nf paused", tt.policy)
+		}
+		s.conn.ReleaseName("org.freedesktop.Notifications")
+	}
+	if err := newTestNotifier(t).ResumeWith(notify.ResumePolicy(7)); err == nil {
+		t.Error("invalid resume policy accepted")
+	}
+}
+
+func TestResumeCollapse(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	holdBack(t, nf)
+	if err := nf.ResumeWith(notify.ResumeCollapse); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if want := "build (3): build 3 failed\nmail (2): mail 2"; got.Body != want {
+		t.Errorf("catch-up body %q, want %q", got.Body, want)
+	}
+	if len(got.Actions) != 2 || got.Actions[0] != notify.DefaultAction || got.Actions[1] != "Show all" {
+		t.Errorf("catch-up actions %q", got.Actions)
+	}
+	if u := got.Hints["urgency"].Value(); u != byte(notify.NormalUrgency) {
+		t.Errorf("catch-up urgency %v", u)
+	}
+
+	// Expanding it sends the notifications held back.
+	s.emitAction(got.ID, notify.DefaultAction)
+	waitFor(t, "the held back notifications", func() bool { return len(summaries(s)) == 6 })
+	if got := summaries(s); got[1] != "build 1 failed" || got[5] != "mail 2" {
+		t.Errorf("expanded to %q", got[1:])
+	}
+
+	// An empty pause sends nothing.
+	nf.Pause()
+	if err := nf.ResumeWith(notify.ResumeCollapse); err != nil || s.received() != 6 {
+		t.Errorf("ResumeWith(ResumeCollapse) = %v, sent %d", err, s.received())
+	}
+}
+
+func TestQuietHoursCollapse(t *testing.T) {
+	s := newFakeServer(t)
+	clock := notifytest.NewClock(time.Date(2013, 1, 1, 23, 0, 0, 0, time.UTC))
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithQuietHours(notify.QuietHours{
+		Windows:  []notify.QuietWindow{{22 * time.Hour, 7 * time.Hour}},
+		Location: time.UTC,
+		Collapse: true,
+	}))
+	for i := 0; i < 7; i++ {
+		n := notify.New(fmt.Sprint("app", i), "news", "", "", 0, notify.NormalUrgency)
+		if res, err := nf.Notify(n); err != nil || !res.Quiet {
+			t.Fatalf("Notify() = %+v, %v, want it held back", res, err)
+		}
+	}
+	if p := nf.PendingScheduled(); len(p) != 0 || s.received() != 0 {
+		t.Errorf("pending %+v, sent %d during quiet hours", p, s.received())
+	}
+
+	clock.Advance(8 * time.Hour)
+	waitFor(t, "the catch-up notification", func() bool { return s.received() == 1 })
+	want := "app0 (1): news\napp1 (1): news\napp2 (1): news\napp3 (1): news\napp4 (1): news\n…"
+	if got := s.last(t); got.Summary != "While you were away" || got.Body != want {
+		t.Errorf("catch-up sent as %q, %q", got.Summary, got.Body)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Category returns the category of the notifications of class, such as
+// "email", and specific, such as "arrived": "email.arrived", or class alone
+// if specific is empty. See CheckCategory for the valid ones.
+func Category(class, specific string) string {
+	if specific == "" {
+		return class
+	}
+	return class + "." + specific
+}
+
+// ParseCategory splits the category s into its class and the rest, such as
+// "email" and "arrived" for "email.arrived". vendor is true for the vendor
+// extensions, whose class is the vendor, such as "x-myapp" and
+// "sync.finished" for "x-myapp.sync.finished".
+func ParseCategory(s string) (class, specific string, vendor bool) {
+	class, specific, _ = strings.Cut(s, ".")
+	return class, specific, strings.HasPrefix(class, "x-")
+}
+
+// InClass returns true if the category s is class, or one of its more
+// specific categories: "im.received" is in the class "im", and
+// "x-myapp.sync.finished" is in "x-myapp" and "x-myapp.sync".
+func InClass(s, class string) bool {
+	return s == class || strings.HasPrefix(s, class+".") && class != ""
+}
+
+// CheckCategory returns an error wrapping ErrInvalidCategory if s is not a
+// category by the conventions of the specification: parts separated by
+// dots, none empty, in lower case and without spaces.
+func CheckCategory(s string) error {
+	if !utf8.ValidString(s) {
+		return ErrInvalidUTF8
+	}
+	for _, part := range strings.Split(s, ".") {
+		if part == "" {
+			return fmt.Errorf("%w %q: empty part", ErrInvalidCategory, s)
+		}
+		for _, r := range part {
+			if unicode.IsSpace(r) {
+				return fmt.Errorf("%w %q: space", ErrInvalidCategory, s)
+			} else if unicode.IsUpper(r) {
+				return fmt.Errorf("%w %q: upper case", ErrInvalidCategory, s)
+			}
+		}
+	}
+	return nil
+}
+
+// WithCategoryCheck sets whether the Notifier refuses to send the
+// notifications whose "category" hint is not valid, see CheckCategory. It
+// does by default; WithCategoryCheck(false) is for daemons expecting other
+// categories.
+func WithCategoryCheck(on bool) Option {
+	return func(nf *Notifier) error {
+		nf.looseCategories = !on
+		return nil
+	}
+}
+
+// category returns the "category" hint of n, or the empty string.
+func (n *Notification) category() string {
+	v, err := hintValue(n.Hint("category"))
+	if err != nil {
+		return ""
+	}
+	category, _ := v.Value().(string)
+	return category
+}
+
+// checkCategoryHint returns the FieldError of the "category" hint of n if
+// it is set and not valid.
+func checkCategoryHint(n *Notification) error {
+	h, ok := n.hints["category"]
+	if !ok {
+		return nil
+	}
+	v, err := hintValue(h)
+	if err != nil {
+		// Reported by checkHint.
+		return nil
+	}
+	category, ok := v.Value().(string)
+	if !ok {
+		return nil
+	}
+	if err := CheckCategory(category); err != nil {
+		return &FieldError{"hints[category]", err}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/notifytest"
+)
+
+func TestParseCategory(t *testing.T) {
+	tests := []struct {
+		s, class, specific string
+		vendor             bool
+	}{
+		{"email.arrived", "email", "arrived", false},
+		{"email", "email", "", false},
+		{"x-myapp.sync.finished", "x-myapp", "sync.finished", true},
+		{"x-myapp", "x-myapp", "", true},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		class, specific, vendor := notify.ParseCategory(tt.s)
+		if class != tt.class || specific != tt.specific || vendor != tt.vendor {
+			t.Errorf("ParseCategory(%q) = %q, %q, %v, want %q, %q, %v", tt.s, class, specific, vendor, tt.class, tt.specific, tt.vendor)
+		}
+		if tt.s != "" {
+			if got := notify.Category(class, specific); got != tt.s {
+				t.Errorf("Category(%q, %q) = %q, want %q", class, specific, got, tt.s)
+			}
+		}
+	}
+}
+
+func TestInClass(t *testing.T) {
+	tests := []struct {
+		s, class string
+		want     bool
+	}{
+		{"im.received", "im", true},
+		{"im", "im", true},
+		{"image.saved", "im", false},
+		{"email.arrived", "im", false},
+		{"x-myapp.sync.finished", "x-myapp", true},
+		{"x-myapp.sync.finished", "x-myapp.sync", true},
+		{"x-myapp.sync.finished", "x-my", false},
+		{"im.received", "", false},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		if got := notify.InClass(tt.s, tt.class); got != tt.want {
+			t.Errorf("InClass(%q, %q) = %v, want %v", tt.s, tt.class, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCategory(t *testing.T) {
+	for _, s := range []string{"email", "email.arrived", "x-myapp.sync.finished", "x-kde.foo_bar-2"} {
+		if err := notify.CheckCategory(s); err != nil {
+			t.Errorf("CheckCategory(%q) = %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "Email.arrived", "email .arrived", "email..arrived", "email.", ".email", "im\treceived"} {
+		if err := notify.CheckCategory(s); !errors.Is(err, notify.ErrInvalidCategory) {
+			t.Errorf("CheckCategory(%q) = %v, want ErrInvalidCategory", s, err)
+		}
+	}
+	if err := notify.CheckCategory("\xff"); !errors.Is(err, notify.ErrInvalidUTF8) {
+		t.Errorf("CheckCategory(invalid UTF-8) = %v, want ErrInvalidUTF8", err)
+	}
+}
+
+func TestCategoryCheckOnSend(t *testing.T) {
+	s := newFakeServer(t)
+	n := notify.New("test", "shouting", "", "", 0, notify.NormalUrgency)
+	n.SetHint("category", "Email.Arrived")
+	var fe *notify.FieldError
+	if err := n.Validate(); !errors.As(err, &fe) || fe.Field != "hints[category]" || !errors.Is(err, notify.ErrInvalidCategory) {
+		t.Errorf("Validate() = %v, want an ErrInvalidCategory of hints[category]", err)
+	}
+
+	nf := newTestNotifier(t)
+	if _, err := nf.Notify(n); !errors.Is(err, notify.ErrInvalidCategory) {
+		t.Errorf("Notify() = %v, want ErrInvalidCategory", err)
+	}
+	if got := len(s.notifications()); got != 0 {
+		t.Errorf("the daemon got %d notifications", got)
+	}
+
+	loose := newTestNotifier(t, notify.WithCategoryCheck(false))
+	if _, err := loose.Notify(n); err != nil {
+		t.Fatalf("Notify() with WithCategoryCheck(false) = %v", err)
+	}
+	if got := s.last(t).Hints["category"].Value(); got != "Email.Arrived" {
+		t.Errorf("the daemon got the category %v", got)
+	}
+}
+
+func TestQuietHoursClasses(t *testing.T) {
+	s := newFakeServer(t)
+	clock := notifytest.NewClock(time.Date(2013, 1, 1, 23, 0, 0, 0, time.UTC))
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithQuietHours(notify.QuietHours{
+		Windows:  []notify.QuietWindow{{22 * time.Hour, 7 * time.Hour}},
+		Location: time.UTC,
+		Classes:  []string{"im"},
+	}))
+
+	send := func(summary, category string, u notify.NotificationUrgency) notify.SendResult {
+		t.Helper()
+		n := notify.New("test", summary, "", "", 0, u)
+		if category != "" {
+			n.SetHint("category", category)
+		}
+		res, err := nf.Notify(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	if res := send("hi", notify.Category("im", "received"), notify.NormalUrgency); !res.Quiet {
+		t.Errorf("an instant message was not held back: %+v", res)
+	}
+	if res := send("urgent hi", "im.received", notify.CriticalUrgency); res.Quiet {
+		t.Errorf("a critical instant message was held back: %+v", res)
+	}
+	if res := send("mail", "email.arrived", notify.NormalUrgency); res.Quiet {
+		t.Errorf("an email was held back: %+v", res)
+	}
+	if res := send("plain", "", notify.NormalUrgency); res.Quiet {
+		t.Errorf("a notification without category was held back: %+v", res)
+	}
+	if got := summaries(s); len(got) != 3 {
+		t.Errorf("sent %q, want all but the instant message", got)
+	}
+}
+
+func TestRouteClass(t *testing.T) {
+	var desktop, chat recordingTransport
+	router, err := notify.RouteByUrgency(nil, &desktop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := router.RouteClass("im", &chat); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.RouteClass("email", nil); err == nil {
+		t.Error("RouteClass with a nil transport succeeded")
+	}
+	nf, err := notify.NewNotifier(notify.WithTransport(router), notify.WithBusAddress("unix:path=/nonexistent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	for _, category := range []string{"im.received", "email.arrived", ""} {
+		n := notify.New("test", category, "", "", 0, notify.CriticalUrgency)
+		if category != "" {
+			n.SetHint("category", category)
+		}
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(chat.calls) != 1 || chat.calls[0].Summary != "im.received" {
+		t.Errorf("chat transport got %+v", chat.calls)
+	}
+	if len(desktop.calls) != 2 {
+		t.Errorf("desktop transport got %+v", desktop.calls)
+	}
+}
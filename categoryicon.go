@@ -0,0 +1,78 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import "errors"
+
+// CategoryIcons holds the themed icons of the categories of the
+// specification, and of their classes, such as "email" for
+// "email.arrived". It is used by the Notifiers with WithCategoryIcons, at
+// each send: change it before sending, not while notifications are sent.
+var CategoryIcons = map[string]string{
+	"device":               "drive-removable-media",
+	"device.added":         "drive-removable-media",
+	"device.error":         "dialog-error",
+	"device.removed":       "media-eject",
+	"email":                "mail-unread",
+	"email.arrived":        "mail-unread",
+	"email.bounced":        "dialog-warning",
+	"im":                   "user-available",
+	"im.error":             "dialog-error",
+	"im.received":          "mail-message-new",
+	"network":              "network-wired",
+	"network.connected":    "network-transmit-receive",
+	"network.disconnected": "network-offline",
+	"network.error":        "network-error",
+	"presence":             "user-available",
+	"presence.offline":     "user-offline",
+	"presence.online":      "user-available",
+	"transfer":             "document-save",
+	"transfer.complete":    "document-save",
+	"transfer.error":       "dialog-error",
+}
+
+// WithCategoryIcons makes the Notifier send the notifications with a
+// "category" hint and no icon with the icon of their category in
+// CategoryIcons, or else of its class. The IconPath and icon of a
+// notification, set with SetIcon, always win; the icon of the category
+// wins over the one of WithAppIcon.
+func WithCategoryIcons(on bool) Option {
+	return func(nf *Notifier) error {
+		nf.categoryIcons, nf.categoryIconsOn = nil, on
+		return nil
+	}
+}
+
+// WithCategoryIconMap is like WithCategoryIcons(true), with the icons of
+// icons instead of CategoryIcons.
+func WithCategoryIconMap(icons map[string]string) Option {
+	return func(nf *Notifier) error {
+		if icons == nil {
+			return errors.New("notify: nil category icon map")
+		}
+		nf.categoryIcons, nf.categoryIconsOn = icons, true
+		return nil
+	}
+}
+
+// categoryIcon returns the icon of the category of n, or the empty string.
+func (nf *Notifier) categoryIcon(n *Notification) string {
+	if !nf.categoryIconsOn {
+		return ""
+	}
+	category := n.category()
+	if category == "" {
+		return ""
+	}
+	icons := nf.categoryIcons
+	if icons == nil {
+		icons = CategoryIcons
+	}
+	if icon, ok := icons[category]; ok {
+		return icon
+	}
+	class, _, _ := ParseCategory(category)
+	return icons[class]
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestCategoryIcons(t *testing.T) {
+	newFakeServer(t)
+	tests := []struct {
+		name     string
+		opts     []notify.Option
+		category interface{}
+		icon     string
+		want     string
+	}{
+		{"off", nil, "email.arrived", "", ""},
+		{"category", []notify.Option{notify.WithCategoryIcons(true)}, "email.arrived", "", "mail-unread"},
+		{"typed hint", []notify.Option{notify.WithCategoryIcons(true)}, notify.CategoryHint("device.error"), "", "dialog-error"},
+		{"class", []notify.Option{notify.WithCategoryIcons(true)}, "network.x-vpn", "", "network-wired"},
+		{"unknown", []notify.Option{notify.WithCategoryIcons(true), notify.WithAppIcon("myapp")}, "x-myapp.sync", "", "myapp"},
+		{"over the app icon", []notify.Option{notify.WithCategoryIcons(true), notify.WithAppIcon("myapp")}, "transfer.error", "", "dialog-error"},
+		{"explicit icon", []notify.Option{notify.WithCategoryIcons(true)}, "email.arrived", "mail-read", "mail-read"},
+		{"no category", []notify.Option{notify.WithCategoryIcons(true)}, nil, "", ""},
+		{"custom map", []notify.Option{notify.WithCategoryIconMap(map[string]string{"email.arrived": "myapp-mail"})}, "email.arrived", "", "myapp-mail"},
+		{"custom map only", []notify.Option{notify.WithCategoryIconMap(map[string]string{"email.arrived": "myapp-mail"})}, "device.error", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nf := newTestNotifier(t, tt.opts...)
+			n := notify.New("test", "New mail", "", tt.icon, 0, notify.NormalUrgency)
+			if tt.category != nil {
+				n.SetHint("category", tt.category)
+			}
+			c, err := n.DryRun(nf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.AppIcon != tt.want {
+				t.Errorf("DryRun() has the icon %q, want %q", c.AppIcon, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryIconsSent(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithCategoryIcons(true))
+	n := notify.New("test", "Connected", "", "", 0, notify.NormalUrgency)
+	n.SetHint("category", "network.connected")
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).AppIcon; got != "network-transmit-receive" {
+		t.Errorf("sent the icon %q", got)
+	}
+	if _, err := notify.NewNotifier(notify.WithCategoryIconMap(nil)); err == nil {
+		t.Error("WithCategoryIconMap accepted a nil map")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// HistoryQuery selects records of a History, see History.Filter. The zero
+// fields select all the records.
+type HistoryQuery struct {
+	AppName string
+	// Category selects the records of the category, or of the class of
+	// categories, see InClass.
+	Category string
+	// Since and Until select the records last sent at or after Since and
+	// before Until.
+	Since time.Time
+	Until time.Time
+	// Urgencies selects the records with one of the urgencies.
+	Urgencies []NotificationUrgency
+	// Open selects the records of the notifications not closed yet.
+	Open bool
+}
+
+// Match returns true if q selects r.
+func (q HistoryQuery) Match(r Record) bool {
+	switch {
+	case q.AppName != "" && r.AppName != q.AppName:
+		return false
+	case q.Category != "" && !InClass(r.Category, q.Category):
+		return false
+	case !q.Since.IsZero() && r.UpdatedAt.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !r.UpdatedAt.Before(q.Until):
+		return false
+	case q.Open && !r.ClosedAt.IsZero():
+		return false
+	}
+	if len(q.Urgencies) == 0 {
+		return true
+	}
+	for _, u := range q.Urgencies {
+		if r.Urgency == u {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the records of h selected by q, the oldest first.
+func (h History) Filter(q HistoryQuery) History {
+	var selected History
+	for _, r := range h {
+		if q.Match(r) {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
+
+// Notification returns a notification sending the same call as r.Call,
+// with the same correlation ID, or an error if r has no call. It replaces
+// the notification of r if it is still open.
+//
+// The texts are posted as recorded, so the Redactor and the sanitizer of
+// the Notifier are expected to leave them as they are. The callbacks are not
+// recorded, and a Persistent notification is not posted again until
+// acknowledged.
+func (r Record) Notification() (*Notification, error) {
+	c := r.Call
+	if c.AppName == "" {
+		return nil, errors.New("notify: the record has no call")
+	}
+	n := New(c.AppName, c.Summary, c.Body, c.AppIcon, time.Duration(c.ExpireTimeout)*time.Millisecond, c.Urgency)
+	n.Tag, n.CorrelationID = r.Tag, r.CorrelationID
+	if r.ClosedAt.IsZero() {
+		n.Id = r.ID
+	}
+	for i := 0; i+1 < len(c.Actions); i += 2 {
+		n.AddAction(c.Actions[i], c.Actions[i+1])
+	}
+	for k, v := range c.Hints {
+		// The urgency hint is sent from Urgency, mapped by the Notifier.
+		if k != "urgency" {
+			n.SetHint(k, v)
+		}
+	}
+	// Without the resident hint, the actions closed the notification.
+	if _, ok := c.Hints["resident"]; !ok && len(n.Actions) > 0 {
+		n.CloseOnAction = true
+	}
+	return n, nil
+}
+
+// Repost posts the notification of r again with nf, see Notification.
+// Its record in the history of nf, if any, is updated.
+func (r Record) Repost(nf *Notifier) error {
+	if nf == nil {
+		return errors.New("notify: nil Notifier")
+	}
+	n, err := r.Notification()
+	if err != nil {
+		return err
+	}
+	_, err = nf.Notify(n)
+	return err
+}
+
+// CloseCorrelated asks the daemon to close the open notification with the
+// correlation ID id, see Notification.CorrelationID. The notification is
+// only known if it has callbacks or nf keeps a history.
+func (nf *Notifier) CloseCorrelated(id string) error {
+	if id == "" {
+		return errors.New("notify: empty correlation ID")
+	}
+	nf.mu.Lock()
+	var daemonID uint32
+	if h := nf.history; h != nil {
+		if r, ok := h.byCorrelation[id]; ok && h.open[r.ID] == r {
+			daemonID = r.ID
+		}
+	}
+	if daemonID == 0 {
+		for tid, t := range nf.tracked {
+			if t.n.CorrelationID == id {
+				daemonID = tid
+				break
+			}
+		}
+	}
+	nf.mu.Unlock()
+	if daemonID == 0 {
+		return fmt.Errorf("notify: no open notification with the correlation ID %q", id)
+	}
+	return nf.CloseNotification(daemonID)
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"image"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestRecordRepost(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		close bool
+		build func(n *notify.Notification)
+	}{
+		{"actions and hints", false, func(n *notify.Notification) {
+			n.AddAction(notify.DefaultAction, "Open")
+			n.AddAction("later", "Remind me later")
+			n.AddHints(notify.CategoryHint("im.received"), notify.TransientHint(true), notify.XYHint{X: 10, Y: 20})
+			n.SetHint("x-vendor-count", int32(3))
+			n.SetImage(image.NewRGBA(image.Rect(0, 0, 2, 2)))
+			n.Tag = "chat"
+		}},
+		{"close on action", true, func(n *notify.Notification) {
+			n.AddAction("ok", "OK")
+			n.CloseOnAction = true
+		}},
+		{"critical", true, func(n *notify.Notification) {
+			n.Urgency = notify.CriticalUrgency
+			n.Timeout = notify.Duration(-time.Millisecond)
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			nf := newTestNotifier(t, notify.WithHistory(4), notify.WithAppIcon("app"),
+				notify.WithUrgencyMap(map[notify.NotificationUrgency]byte{notify.CriticalUrgency: 1}))
+			n := notify.New("chat", "New message", "from <b>Bob</b>", "", 5*time.Second, notify.NormalUrgency)
+			tt.build(n)
+			if _, err := nf.Notify(n); err != nil {
+				t.Fatal(err)
+			}
+			if tt.close {
+				if err := nf.CloseNotification(n.Id); err != nil {
+					t.Fatal(err)
+				}
+				s.emitClosed(n.Id, uint32(notify.ReasonClosed))
+				waitFor(t, "the closed record", func() bool { return !nf.History()[0].ClosedAt.IsZero() })
+			}
+			original := s.last(t)
+
+			h := nf.History()
+			if err := h[0].Repost(nf); err != nil {
+				t.Fatal(err)
+			}
+			reposted := s.last(t)
+			wantReplaces := original.ID
+			if tt.close {
+				wantReplaces = 0
+			}
+			if reposted.ReplacesID != wantReplaces {
+				t.Errorf("reposted replacing %d, want %d", reposted.ReplacesID, wantReplaces)
+			}
+			a, b := original, reposted
+			a.ReplacesID, b.ReplacesID = 0, 0
+			a.ID, b.ID = 0, 0
+			if !reflect.DeepEqual(a, b) {
+				t.Errorf("reposted\n%+v\nwant\n%+v", b, a)
+			}
+			if h := nf.History(); len(h) != 1 || h[0].ID != reposted.ID || !h[0].ClosedAt.IsZero() {
+				t.Errorf("History() = %+v, want the record updated", h)
+			}
+		})
+	}
+}
+
+func TestRecordWithoutCall(t *testing.T) {
+	var rt recordingTransport
+	nf := newRecordingNotifier(t, &rt)
+	if err := (notify.Record{Summary: "read from an inbox"}).Repost(nf); err == nil {
+		t.Error("Repost() of a record without a call succeeded")
+	}
+}
+
+func TestHistoryFilter(t *testing.T) {
+	newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithHistory(8), notify.WithClock(clock))
+	start := clock.Now()
+	for _, sc := range []struct {
+		app, category string
+		urgency       notify.NotificationUrgency
+	}{
+		{"mail", "email.arrived", notify.NormalUrgency},
+		{"chat", "im.received", notify.LowUrgency},
+		{"chat", "im", notify.CriticalUrgency},
+		{"backup", "", notify.NormalUrgency},
+	} {
+		n := notify.New(sc.app, sc.app+" "+sc.category, "", "", 0, sc.urgency)
+		if sc.category != "" {
+			n.AddHints(notify.CategoryHint(sc.category))
+		}
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Minute)
+	}
+	h := nf.History()
+	if err := nf.CloseCorrelated(h[0].CorrelationID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		q    notify.HistoryQuery
+		want []string
+	}{
+		{"all", notify.HistoryQuery{}, []string{"mail email.arrived", "chat im.received", "chat im", "backup "}},
+		{"app", notify.HistoryQuery{AppName: "chat"}, []string{"chat im.received", "chat im"}},
+		{"class", notify.HistoryQuery{Category: "im"}, []string{"chat im.received", "chat im"}},
+		{"category", notify.HistoryQuery{Category: "im.received"}, []string{"chat im.received"}},
+		{"time range", notify.HistoryQuery{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, []string{"chat im.received", "chat im"}},
+		{"urgencies", notify.HistoryQuery{Urgencies: []notify.NotificationUrgency{notify.LowUrgency, notify.CriticalUrgency}}, []string{"chat im.received", "chat im"}},
+		{"none", notify.HistoryQuery{AppName: "mail", Urgencies: []notify.NotificationUrgency{notify.LowUrgency}}, nil},
+	} {
+		var got []string
+		for _, r := range h.Filter(tt.q) {
+			got = append(got, r.Summary)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Filter() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if h[1].Category != "im.received" {
+		t.Errorf("Category = %q", h[1].Category)
+	}
+}
+
+func TestCloseCorrelated(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithHistory(2))
+	n := notify.New("test", "busy", "", "", 0, notify.NormalUrgency)
+	n.CorrelationID = "job-42"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if err := nf.CloseCorrelated("job-42"); err != nil {
+		t.Fatal(err)
+	}
+	if ids := s.closedIDs(); len(ids) != 1 || ids[0] != n.Id {
+		t.Errorf("closed %v, want %d", ids, n.Id)
+	}
+	if err := nf.CloseCorrelated("unknown"); err == nil {
+		t.Error("CloseCorrelated() of an unknown ID succeeded")
+	}
+
+	// Without a history, the notifications with callbacks are known.
+	nf = newTestNotifier(t)
+	tracked := notify.New("test", "tracked", "", "", 0, notify.NormalUrgency)
+	tracked.OnClose = func(notify.CloseReason) {}
+	if _, err := nf.Notify(tracked); err != nil {
+		t.Fatal(err)
+	}
+	if err := nf.CloseCorrelated(tracked.CorrelationID); err != nil {
+		t.Fatal(err)
+	}
+	if ids := s.closedIDs(); len(ids) != 2 || ids[1] != tracked.Id {
+		t.Errorf("closed %v, want %d", ids, tracked.Id)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import "time"
+
+// Clock tells the time to a Notifier and runs its timers: repost intervals,
+// scheduled sends, dedup windows and the like. Tests can replace the real
+// clock with WithClock to control time, see the notifytest package.
+//
+// Now tells the wall clock time, while the timers measure their durations on
+// a monotonic clock, like those of the time package, which may stop while
+// the system is suspended: the timers due at a wall clock time fire late
+// after a resume, unless HandleResume is called.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a timer sending the time on its channel after d.
+	NewTimer(d time.Duration) Timer
+	// After returns a channel receiving the time after d.
+	After(d time.Duration) <-chan time.Time
+	// AfterFunc calls f in its own goroutine after d, unless the returned
+	// timer is stopped first.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a timer started by a Clock.
+type Timer interface {
+	// C returns the channel receiving the time when the timer fires, or nil
+	// for the timers of AfterFunc.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, and returns false if it already
+	// fired or was stopped.
+	Stop() bool
+	// Reset makes the timer fire after d, and returns false if it already
+	// fired or was stopped.
+	Reset(d time.Duration) bool
+}
+
+// realClock is the Clock of the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return realTimer{time.AfterFunc(d, f)} }
+
+// realTimer is a Timer of the time package.
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// WithClock makes the Notifier use c instead of the real clock.
+func WithClock(c Clock) Option {
+	return func(nf *Notifier) error {
+		nf.clock = c
+		return nil
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Command notify sends a desktop notification. It accepts the flags of
+// notify-send:
+//
+//	notify [flags] SUMMARY [BODY]
+//
+// With actions or -wait, it waits until the notification is closed, and
+// prints the key of the action invoked, if any. With -max-wait, it stops
+// waiting after that time, and closes the notification.
+//
+// The exit code tells how the notification ended, see notify.ExitCodeFor:
+//
+//	0  an action was invoked, or the notification was sent without waiting
+//	1  the user dismissed the notification
+//	2  the notification expired
+//	3  the notification was closed by a program, or by notify on an interrupt
+//	4  no notification daemon is running
+//	5  the wait timed out, see -max-wait, or the daemon did not reply
+//	6  invalid arguments
+//	7  any other error
+//
+// With -self-test, it checks the notification setup instead, sending a test
+// notification, and prints a report.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// listFlag is a flag that may be given several times.
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(s string) error { *l = append(*l, s); return nil }
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: notify [flags] SUMMARY [BODY]\n       notify -self-test")
+		fs.PrintDefaults()
+	}
+
+	var (
+		urgency   = "normal"
+		expire    = -1
+		icon      string
+		category  string
+		appName   string
+		replaceID uint
+		printID   bool
+		wait      bool
+		maxWait   time.Duration
+		transient bool
+		selfTest  bool
+		hints     listFlag
+		actions   listFlag
+	)
+	for _, name := range []string{"u", "urgency"} {
+		fs.StringVar(&urgency, name, urgency, "urgency: low, normal or critical")
+	}
+	for _, name := range []string{"t", "expire-time"} {
+		fs.IntVar(&expire, name, expire, "timeout in milliseconds, 0 for none, -1 for the daemon default")
+	}
+	for _, name := range []string{"i", "icon"} {
+		fs.StringVar(&icon, name, "", "icon name or path")
+	}
+	for _, name := range []string{"c", "category"} {
+		fs.StringVar(&category, name, "", "category")
+	}
+	for _, name := range []string{"a", "app-name"} {
+		fs.StringVar(&appName, name, "", "application name")
+	}
+	for _, name := range []string{"r", "replace-id"} {
+		fs.UintVar(&replaceID, name, 0, "ID of the notification to replace")
+	}
+	for _, name := range []string{"p", "print-id"} {
+		fs.BoolVar(&printID, name, false, "print the ID of the notification")
+	}
+	for _, name := range []string{"w", "wait"} {
+		fs.BoolVar(&wait, name, false, "wait until the notification is closed")
+	}
+	fs.DurationVar(&maxWait, "max-wait", 0, "stop waiting after this `duration`, like 30s, and close the notification")
+	for _, name := range []string{"e", "transient"} {
+		fs.BoolVar(&transient, name, false, "do not keep the notification in the history")
+	}
+	fs.BoolVar(&selfTest, "self-test", false, "check the notification setup with a test notification, and print a report")
+	for _, name := range []string{"h", "hint"} {
+		fs.Var(&hints, name, "hint as `TYPE:NAME:VALUE`, with TYPE one of boolean, int, double, string or byte")
+	}
+	for _, name := range []string{"A", "action"} {
+		fs.Var(&actions, name, "action as `[KEY=]LABEL`")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return notify.ExitInvalid
+	}
+	if selfTest {
+		return runSelfTest(stdout, stderr)
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return notify.ExitInvalid
+	}
+
+	n := &notify.Notification{
+		Name:     appName,
+		Summary:  fs.Arg(0),
+		Body:     fs.Arg(1),
+		IconPath: icon,
+		Id:       uint32(replaceID),
+	}
+	// A negative timeout of -1 ms lets the daemon choose.
+	n.Timeout = notify.Duration(time.Duration(expire) * time.Millisecond)
+	var u notify.NotificationUrgency
+	if err := u.UnmarshalText([]byte(urgency)); err != nil {
+		return invalid(stderr, err)
+	}
+	n.SetUrgency(u)
+	if category != "" {
+		n.AddHints(notify.CategoryHint(category))
+	}
+	if transient {
+		n.AddHints(notify.TransientHint(true))
+	}
+	for _, h := range hints {
+		if err := setHint(n, h); err != nil {
+			return invalid(stderr, err)
+		}
+	}
+	for i, a := range actions {
+		key, label, ok := strings.Cut(a, "=")
+		if !ok {
+			key, label = strconv.Itoa(i), a
+		}
+		n.AddAction(key, label)
+	}
+
+	answers := make(chan notify.WaitResult, 1)
+	answer := func(r notify.WaitResult) {
+		select {
+		case answers <- r:
+		default:
+		}
+	}
+	waiting := wait || len(n.Actions) > 0 || maxWait > 0
+	if waiting {
+		n.OnAction = func(key string) { answer(notify.WaitResult{Key: key, Action: true}) }
+		n.OnClose = func(reason notify.CloseReason) { answer(notify.WaitResult{Reason: reason, Closed: true}) }
+	}
+
+	nf, err := notify.NewNotifier()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer nf.Close()
+	res, err := nf.Notify(n)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if printID {
+		fmt.Fprintln(stdout, res.Id)
+	}
+	if !waiting {
+		return notify.ExitAction
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var timeout <-chan time.Time
+	if maxWait > 0 {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var r notify.WaitResult
+	select {
+	case r = <-answers:
+		if r.Action {
+			fmt.Fprintln(stdout, r.Key)
+		}
+	case <-timeout:
+		nf.CloseNotification(n.Id)
+		r.Err = notify.ErrNoResponse
+		fmt.Fprintln(stderr, "notify: no response within", maxWait)
+	case <-ctx.Done():
+		nf.CloseNotification(n.Id)
+		r = notify.WaitResult{Reason: notify.ReasonClosed, Closed: true}
+	}
+	return notify.ExitCodeFor(r)
+}
+
+// runSelfTest runs the interactive self-test, and prints its report.
+func runSelfTest(stdout, stderr io.Writer) int {
+	nf, err := notify.NewNotifier()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer nf.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Fprintln(stderr, "sending a test notification: click it or dismiss it")
+	report, err := nf.SelfTest(ctx, true)
+	fmt.Fprint(stdout, report)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
+
+// setHint sets the hint given as TYPE:NAME:VALUE on n.
+func setHint(n *notify.Notification, spec string) error {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid hint %q, want TYPE:NAME:VALUE", spec)
+	}
+	typ, name, value := parts[0], parts[1], parts[2]
+
+	var v interface{}
+	var err error
+	switch typ {
+	case "boolean":
+		v, err = strconv.ParseBool(value)
+	case "int":
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 32)
+		v = int32(i)
+	case "double":
+		v, err = strconv.ParseFloat(value, 64)
+	case "string":
+		v = value
+	case "byte":
+		var b uint64
+		b, err = strconv.ParseUint(value, 10, 8)
+		v = byte(b)
+	default:
+		return fmt.Errorf("invalid hint type %q", typ)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s value %q for hint %s", typ, value, name)
+	}
+	n.SetHint(name, v)
+	return nil
+}
+
+// fail prints err, and returns the exit code of its class.
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintln(stderr, "notify:", strings.TrimPrefix(err.Error(), "notify: "))
+	return notify.ExitCodeFor(notify.WaitResult{Err: err})
+}
+
+// invalid prints err, an invalid argument, and returns ExitInvalid.
+func invalid(stderr io.Writer, err error) int {
+	fail(stderr, err)
+	return notify.ExitInvalid
+}
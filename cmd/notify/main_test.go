@@ -0,0 +1,284 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/internal/testbus"
+	"github.com/Schnouki/notify/server"
+	"github.com/godbus/dbus/v5"
+)
+
+var busAddress string
+
+// TestMain runs the command instead of the tests when the test binary is
+// run by notifyCmd, and otherwise starts a private bus for the tests.
+func TestMain(m *testing.M) {
+	if os.Getenv("NOTIFY_TEST_RUN_MAIN") == "1" {
+		main()
+	}
+	os.Exit(testbus.Run(m, &busAddress))
+}
+
+// daemon is a server.Handler recording notifications. If action is set, it
+// keeps invoking it on the last notification until stopped, and if close is
+// set, it keeps closing it with close.
+type daemon struct {
+	action string
+	close  func(r *server.Responder, id uint32) error
+	stop   chan struct{}
+
+	mu       sync.Mutex
+	received []server.ReceivedNotification
+}
+
+func (d *daemon) Notify(r *server.Responder, n server.ReceivedNotification) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.received = append(d.received, n)
+	if d.action != "" || d.close != nil {
+		go func() {
+			for {
+				select {
+				case <-d.stop:
+					return
+				case <-time.After(20 * time.Millisecond):
+					if d.action != "" {
+						r.ActionInvoked(n.ID, d.action)
+					} else {
+						d.close(r, n.ID)
+					}
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+func (d *daemon) CloseNotification(*server.Responder, uint32) error { return nil }
+
+func (d *daemon) last(t *testing.T) server.ReceivedNotification {
+	t.Helper()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.received) == 0 {
+		t.Fatal("no notification received")
+	}
+	return d.received[len(d.received)-1]
+}
+
+// serve starts a notification daemon handled by d on the private bus.
+func serve(t *testing.T, d *daemon) {
+	t.Helper()
+	if busAddress == "" {
+		t.Skip("no private D-Bus session bus available")
+	}
+	d.stop = make(chan struct{})
+	t.Cleanup(func() { close(d.stop) })
+	conn, err := dbus.Connect(busAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s, err := server.New(conn, d, server.WithCapabilities("body", "actions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+}
+
+// notifyCmd runs the command with args, and returns its output.
+func notifyCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "NOTIFY_TEST_RUN_MAIN=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			cmd.Process.Kill()
+		}
+	}()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Logf("stderr: %s", stderr.String())
+	}
+	return strings.TrimSpace(string(out)), err
+}
+
+// exitCode returns the exit code of the command from the error of
+// notifyCmd.
+func exitCode(t *testing.T, err error) int {
+	t.Helper()
+	if err == nil {
+		return 0
+	}
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) {
+		t.Fatal(err)
+	}
+	return exit.ExitCode()
+}
+
+func TestSend(t *testing.T) {
+	var d daemon
+	serve(t, &d)
+
+	out, err := notifyCmd(t, "-u", "critical", "--expire-time", "1500", "-i", "dialog-information",
+		"-c", "email.arrived", "-a", "mail", "-r", "7", "--transient", "--print-id",
+		"-h", "int:value:42", "--hint", "string:x-vendor:yes", "-h", "byte:x-level:3",
+		"-h", "boolean:x-flag:true", "-h", "double:x-ratio:0.5",
+		"New mail", "From: someone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := d.last(t)
+	if out != strconv.Itoa(int(n.ID)) {
+		t.Errorf("printed ID %q, want %d", out, n.ID)
+	}
+	if n.AppName != "mail" || n.Summary != "New mail" || n.Body != "From: someone" || n.AppIcon != "dialog-information" ||
+		n.ReplacesID != 7 || n.ExpireTimeout != 1500 {
+		t.Errorf("received %+v", n)
+	}
+	want := map[string]interface{}{
+		"urgency":   byte(notify.CriticalUrgency),
+		"category":  "email.arrived",
+		"transient": true,
+		"value":     int32(42),
+		"x-vendor":  "yes",
+		"x-level":   byte(3),
+		"x-flag":    true,
+		"x-ratio":   0.5,
+	}
+	for k, v := range want {
+		if got, ok := n.Hints[k]; !ok || got.Value() != v {
+			t.Errorf("hint %s = %v, want %v", k, got, v)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	var d daemon
+	serve(t, &d)
+
+	if _, err := notifyCmd(t, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	n := d.last(t)
+	if n.ExpireTimeout != -1 || n.Hints["urgency"].Value() != byte(notify.NormalUrgency) || n.Body != "" {
+		t.Errorf("received %+v", n)
+	}
+}
+
+func TestLowUrgency(t *testing.T) {
+	var d daemon
+	serve(t, &d)
+
+	if _, err := notifyCmd(t, "-u", "low", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.last(t).Hints["urgency"].Value(); got != byte(notify.LowUrgency) {
+		t.Errorf("urgency = %v, want low", got)
+	}
+}
+
+func TestAction(t *testing.T) {
+	d := daemon{action: "reply"}
+	serve(t, &d)
+
+	out, err := notifyCmd(t, "--action", "reply=Reply", "-A", "Ignore", "ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "reply" {
+		t.Errorf("printed %q, want the invoked action", out)
+	}
+	if got := d.last(t).Actions; len(got) != 2 || got[0] != (notify.Action{Key: "reply", Label: "Reply"}) ||
+		got[1] != (notify.Action{Key: "1", Label: "Ignore"}) {
+		t.Errorf("actions %v", got)
+	}
+}
+
+func TestInvalidArguments(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-u", "urgent", "summary"},
+		{"-h", "int:value:many", "summary"},
+		{"-h", "variant:x:1", "summary"},
+		{"-no-such-flag", "summary"},
+	} {
+		if _, err := notifyCmd(t, args...); exitCode(t, err) != notify.ExitInvalid {
+			t.Errorf("notify %q: %v, want the exit code %d", args, err, notify.ExitInvalid)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		close  func(r *server.Responder, id uint32) error
+		args   []string
+		out    string
+		code   int
+	}{
+		{"action", "yes", nil, []string{"-A", "yes=Yes", "Deploy?"}, "yes", notify.ExitAction},
+		{"dismissed", "", (*server.Responder).CloseDismissed, []string{"-w", "Deploy?"}, "", notify.ExitDismissed},
+		{"expired", "", (*server.Responder).CloseExpired, []string{"-A", "yes=Yes", "Deploy?"}, "", notify.ExitExpired},
+		{"closed", "", (*server.Responder).CloseRequested, []string{"-w", "Deploy?"}, "", notify.ExitClosed},
+		{"timeout", "", nil, []string{"-max-wait", "100ms", "Deploy?"}, "", notify.ExitTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := daemon{action: tt.action, close: tt.close}
+			serve(t, &d)
+			out, err := notifyCmd(t, tt.args...)
+			if code := exitCode(t, err); code != tt.code || out != tt.out {
+				t.Errorf("exit code %d, output %q, want %d and %q", code, out, tt.code, tt.out)
+			}
+		})
+	}
+}
+
+func TestExitCodeNoDaemon(t *testing.T) {
+	if busAddress == "" {
+		t.Skip("no private D-Bus session bus available")
+	}
+	if _, err := notifyCmd(t, "-w", "nobody listens"); exitCode(t, err) != notify.ExitNoDaemon {
+		t.Errorf("notify without a daemon: %v, want the exit code %d", err, notify.ExitNoDaemon)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	d := daemon{action: notify.DefaultAction}
+	serve(t, &d)
+
+	out, err := notifyCmd(t, "--self-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"capabilities: actions, body", "ok      notification", "with an action", `action "default" invoked`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report does not contain %q:\n%s", want, out)
+		}
+	}
+	if n := d.last(t); n.Summary != "Notification self-test" {
+		t.Errorf("received %+v", n)
+	}
+}
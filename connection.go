@@ -6,68 +6,52 @@ package notify
 
 import (
 	"errors"
+	"sync/atomic"
+)
 
-	"github.com/godbus/dbus"
+const (
+	dbusDestination = "org.freedesktop.Notifications"
+	dbusObjectPath  = "/org/freedesktop/Notifications"
+	dbusInterface   = "org.freedesktop.Notifications"
 )
 
-// connection is a global D-Bus connection. TODO: I do not know if this
-// can be used concurrently!
-var connection *dbus.Conn
+var errUnrecognizedResponse = errors.New("unrecognized response from notify daemon")
 
-// ServiceAvailable returns true if notifications via DBus are available.
-//
-// First, it initiates a connection via DBus to find out whether DBus is
-// available, then it contacts the notification service to find out if it is
-// available. If one or the other is not available, it returns false.
-//
-// Before using notify, it is a good idea (though not necessary) to test
-// if this service is available. If it's not available, this does not
-// tell you why though. Maybe another day.
-func ServiceAvailable() bool {
-	if connection == nil {
-		var err error
-		connection, err = dbus.SessionBus()
-		if err != nil {
-			return false
-		}
-	}
+// defaultCore holds the connection of defaultNotifier, the shared session bus
+// connection. Its transport is set by NOTIFY_TRANSPORT, see TransportFromEnv,
+// unless it is invalid, which is logged. It uses the built-in quirks if
+// NOTIFY_QUIRKS_FILE is invalid.
+var defaultCore, _ = newCore(envOptions()...)
 
-	obj := connection.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
-	call := obj.Call("org.freedesktop.Notifications.GetCapabilities", 0)
-	if call.Err != nil {
-		return false
-	}
+// defaultNotifier holds the Notifier used by all the package-level
+// functions, see SetDefault. It starts as a Notifier of defaultCore.
+var defaultNotifier atomic.Pointer[Notifier]
 
-	return true
+func init() {
+	nf, _ := defaultCore.Notifier("")
+	defaultNotifier.Store(nf)
 }
 
-// notify does the real work of getting a connection and talking to the
-// notification daemon. It doesn't really talk though.
-//
-// To have some elements use their defaults, the following is accepted:
-//
-//	name = ""
-//	body = ""
-//	replacesID = 0
-//	actions = nil
-//	hints = nil
-//
-// So you see, really only summary and timeout are required for a meaningful
-// notification.
-func notify(name, summary, body, icon string, replacesID uint32, actions []string, hints map[string]dbus.Variant, timeout int32) (id uint32, err error) {
-	if connection == nil {
-		connection, err = dbus.SessionBus()
-		if err != nil {
-			return 0, err
-		}
-	}
+// Default returns the Notifier used by the package-level functions and the
+// methods of Notification sending without a Notifier, such as SendR.
+func Default() *Notifier {
+	return defaultNotifier.Load()
+}
 
-	obj := connection.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
-	call := obj.Call("org.freedesktop.Notifications.Notify", 0, name, replacesID, icon, summary, body, actions, hints, timeout)
-	if call.Err != nil {
-		return 0, call.Err
-	} else if call.Store(&id) != nil {
-		return 0, errors.New("unrecognized response from notify daemon")
+// SetDefault makes nf the Notifier used by the package-level functions, and
+// returns the previous one, for example to Shutdown it. The functions get the
+// default Notifier when they are called: a call already running goes on with
+// the previous one, and the next calls use nf. The implicit notification,
+// see Init, is not part of the Notifier and is kept. nf must not be nil.
+func SetDefault(nf *Notifier) (previous *Notifier) {
+	if nf == nil {
+		panic("notify: SetDefault(nil)")
 	}
-	return
+	return defaultNotifier.Swap(nf)
+}
+
+// CloseNotification asks the daemon to close the notification with the ID
+// id, if it is still shown.
+func CloseNotification(id uint32) error {
+	return Default().CloseNotification(id)
 }
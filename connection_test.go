@@ -0,0 +1,138 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func newRecordingNotifier(t *testing.T, rt *recordingTransport, opts ...notify.Option) *notify.Notifier {
+	t.Helper()
+	nf, err := notify.NewNotifier(append([]notify.Option{notify.WithTransport(rt)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nf.Close() })
+	return nf
+}
+
+func TestSetDefault(t *testing.T) {
+	var rt recordingTransport
+	nf := newRecordingNotifier(t, &rt, notify.WithAppName("swapped"))
+	original := notify.Default()
+	if prev := notify.SetDefault(nf); prev != original {
+		t.Errorf("SetDefault() returned %p, want the original default %p", prev, original)
+	}
+	defer notify.SetDefault(original)
+	if notify.Default() != nf {
+		t.Fatal("Default() is not the Notifier given to SetDefault")
+	}
+
+	if _, err := notify.SendMsg("hello", ""); err != nil {
+		t.Fatal(err)
+	}
+	n := notify.New("", "sent", "", "", 0, notify.NormalUrgency)
+	if _, err := n.SendR(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.calls) != 2 || rt.calls[0].Summary != "hello" || rt.calls[1].AppName != "swapped" {
+		t.Errorf("the new default got %+v", rt.calls)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("SetDefault(nil) did not panic")
+		}
+	}()
+	notify.SetDefault(nil)
+}
+
+// TestSetDefaultImplicitSends checks that the helpers sending the implicit
+// notification get the options of the default Notifier.
+func TestSetDefaultImplicitSends(t *testing.T) {
+	var rt recordingTransport
+	nf := newRecordingNotifier(t, &rt, notify.WithAppIcon("app-icon"), notify.WithMinimumTimeout(10*time.Second),
+		notify.WithControlSanitizer(notify.StripControls), notify.WithCriticalNoExpiry())
+	defer notify.SetDefault(notify.SetDefault(nf))
+
+	sends := []struct {
+		name     string
+		send     func() (uint32, error)
+		critical bool
+	}{
+		{"SendMsg", func() (uint32, error) { return notify.SendMsg("one\u202e", "") }, false},
+		{"SendUrgentMsg", func() (uint32, error) { return notify.SendUrgentMsg("two\u202e", "", notify.CriticalUrgency) }, true},
+		{"ReplaceMsg", func() (uint32, error) { return notify.ReplaceMsg(1, "three\u202e", "") }, false},
+		{"ReplaceUrgentMsg", func() (uint32, error) { return notify.ReplaceUrgentMsg(2, "four\u202e", "", notify.CriticalUrgency) }, true},
+	}
+	for i, tt := range sends {
+		if _, err := tt.send(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		rt.mu.Lock()
+		c := rt.calls[len(rt.calls)-1]
+		rt.mu.Unlock()
+		want := int32(10000)
+		if tt.critical {
+			want = 0
+		}
+		if c.AppIcon != "app-icon" || c.ExpireTimeout != want || strings.ContainsRune(c.Summary, '\u202e') {
+			t.Errorf("%s sent %+v", tt.name, c)
+		}
+		if i >= 2 && c.ReplacesID != uint32(i-1) {
+			t.Errorf("%s sent replaces_id %d, want %d", tt.name, c.ReplacesID, i-1)
+		}
+	}
+
+	// Only the critical notifications are sent while paused.
+	nf.Pause()
+	for _, tt := range sends {
+		if _, err := tt.send(); err != nil {
+			t.Fatalf("%s while paused: %v", tt.name, err)
+		}
+	}
+	if got := len(rt.calls); got != len(sends)+2 {
+		t.Errorf("%d calls made while paused, want the 2 critical ones", got-len(sends))
+	}
+}
+
+func TestSetDefaultConcurrent(t *testing.T) {
+	var a, b recordingTransport
+	nfs := [2]*notify.Notifier{newRecordingNotifier(t, &a), newRecordingNotifier(t, &b)}
+	original := notify.SetDefault(nfs[0])
+	defer notify.SetDefault(original)
+
+	const senders, sends = 8, 100
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < sends; j++ {
+				if _, err := notify.SendMsg("flood", ""); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		notify.SetDefault(nfs[i%2])
+	}
+	wg.Wait()
+
+	a.mu.Lock()
+	b.mu.Lock()
+	defer a.mu.Unlock()
+	defer b.mu.Unlock()
+	if got := len(a.calls) + len(b.calls); got != senders*sends {
+		t.Errorf("the defaults got %d calls, want %d", got, senders*sends)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+)
+
+// notifierKey is the context key of the Notifier, see NewContext.
+type notifierKey struct{}
+
+// NewContext returns a copy of ctx holding nf, for request-scoped code that
+// sends notifications with a Notifier of its own, like a view with the app
+// name of a client. FromContext returns it, and the package-level functions
+// ending in Context, like SendMsgContext, send with it.
+func NewContext(ctx context.Context, nf *Notifier) context.Context {
+	if nf == nil {
+		panic("notify: NewContext with a nil Notifier")
+	}
+	return context.WithValue(ctx, notifierKey{}, nf)
+}
+
+// FromContext returns the Notifier held by ctx, see NewContext, or the
+// default Notifier if it holds none.
+func FromContext(ctx context.Context) *Notifier {
+	if nf, ok := ctx.Value(notifierKey{}).(*Notifier); ok {
+		return nf
+	}
+	return Default()
+}
+
+// SendMsgContext is like SendMsg, with the Notifier of ctx, see FromContext.
+// The call is made with ctx.
+func SendMsgContext(ctx context.Context, summary, body string) (id uint32, err error) {
+	return SendUrgentMsgContext(ctx, summary, body, note.Urgency)
+}
+
+// SendUrgentMsgContext is like SendUrgentMsg, with the Notifier of ctx, see
+// SendMsgContext.
+func SendUrgentMsgContext(ctx context.Context, summary, body string, urgency NotificationUrgency) (id uint32, err error) {
+	return sendImplicit(ctx, FromContext(ctx), 0, summary, body, urgency)
+}
+
+// ReplaceMsgContext is like ReplaceMsg, with the Notifier of ctx, see
+// SendMsgContext.
+func ReplaceMsgContext(ctx context.Context, id uint32, summary, body string) (newID uint32, err error) {
+	return ReplaceUrgentMsgContext(ctx, id, summary, body, note.Urgency)
+}
+
+// ReplaceUrgentMsgContext is like ReplaceUrgentMsg, with the Notifier of
+// ctx, see SendMsgContext.
+func ReplaceUrgentMsgContext(ctx context.Context, id uint32, summary, body string, urgency NotificationUrgency) (newID uint32, err error) {
+	return sendImplicit(ctx, FromContext(ctx), id, summary, body, urgency)
+}
+
+// NotifyValueContext is like NotifyValue, with the Notifier of ctx, see
+// SendMsgContext.
+func NotifyValueContext(ctx context.Context, summary string, body any) (id uint32, err error) {
+	return SendMsgContext(ctx, summary, Render(body))
+}
+
+// NotifyfContext is like Notifyf, with the Notifier of ctx, see
+// SendMsgContext.
+func NotifyfContext(ctx context.Context, summary, format string, args ...any) (id uint32, err error) {
+	return SendMsgContext(ctx, summary, fmt.Sprintf(format, renderArgs(args)...))
+}
+
+// CloseNotificationContext is like CloseNotification, with the Notifier of
+// ctx, see FromContext.
+func CloseNotificationContext(ctx context.Context, id uint32) error {
+	return FromContext(ctx).CloseNotification(id)
+}
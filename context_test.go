@@ -0,0 +1,116 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestFromContextFallback(t *testing.T) {
+	var rt recordingTransport
+	nf := newRecordingNotifier(t, &rt, notify.WithAppName("default"))
+	original := notify.SetDefault(nf)
+	defer notify.SetDefault(original)
+
+	ctx := context.Background()
+	if got := notify.FromContext(ctx); got != nf {
+		t.Errorf("FromContext() = %p, want the default Notifier %p", got, nf)
+	}
+	if _, err := notify.SendMsgContext(ctx, "hello", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.calls) != 1 || rt.calls[0].AppName != "default" {
+		t.Errorf("the default Notifier got %+v", rt.calls)
+	}
+
+	var other recordingTransport
+	scoped := newRecordingNotifier(t, &other)
+	ctx = notify.NewContext(ctx, scoped)
+	if got := notify.FromContext(ctx); got != scoped {
+		t.Errorf("FromContext() = %p, want the Notifier of the context %p", got, scoped)
+	}
+	if got := notify.FromContext(context.Background()); got != nf {
+		t.Errorf("FromContext() of another context = %p, want the default %p", got, nf)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("NewContext(nil) did not panic")
+		}
+	}()
+	notify.NewContext(ctx, nil)
+}
+
+func TestContextHelpers(t *testing.T) {
+	var rt recordingTransport
+	nf := newRecordingNotifier(t, &rt, notify.WithAppName("client"))
+	ctx := notify.NewContext(context.Background(), nf)
+
+	if _, err := notify.SendMsgContext(ctx, "send", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notify.ReplaceUrgentMsgContext(ctx, 7, "replace", "", notify.CriticalUrgency); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notify.NotifyfContext(ctx, "format", "%d files, priority %d", 3, priority(2)); err != nil {
+		t.Fatal(err)
+	}
+	rt.mu.Lock()
+	calls := rt.calls
+	rt.mu.Unlock()
+	if len(calls) != 3 {
+		t.Fatalf("the Notifier of the context got %d calls, want 3", len(calls))
+	}
+	for _, c := range calls {
+		if c.AppName != "client" {
+			t.Errorf("call %q sent as %q, want the app name of the Notifier of the context", c.Summary, c.AppName)
+		}
+	}
+	if c := calls[1]; c.ReplacesID != 7 || c.Urgency != notify.CriticalUrgency {
+		t.Errorf("replacement = %+v, want ID 7 and critical urgency", c)
+	}
+	if c, want := calls[2], "3 files, priority 2"; c.Body != want {
+		t.Errorf("formatted body = %q, want %q", c.Body, want)
+	}
+}
+
+func TestContextNotifiersConcurrent(t *testing.T) {
+	var rt recordingTransport
+	nf := newRecordingNotifier(t, &rt)
+
+	const clients, sends = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprint("client-", i)
+			ctx := notify.NewContext(context.Background(), nf.As(name))
+			for j := 0; j < sends; j++ {
+				if _, err := notify.SendMsgContext(ctx, name, ""); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if len(rt.calls) != clients*sends {
+		t.Fatalf("got %d calls, want %d", len(rt.calls), clients*sends)
+	}
+	for _, c := range rt.calls {
+		if c.AppName != c.Summary {
+			t.Fatalf("call of %s sent as %s", c.Summary, c.AppName)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCoordinatorClosed is returned by the Notify method of a closed
+// Coordinator.
+var ErrCoordinatorClosed = errors.New("notify: coordinator closed")
+
+const (
+	// coordRetry is how long a node waits before trying to lead or follow
+	// again.
+	coordRetry = 20 * time.Millisecond
+	// coordWriteTimeout is how long a node waits for another one to read a
+	// message before dropping their connection.
+	coordWriteTimeout = time.Second
+)
+
+// Coordinator is a node of a group of processes sharing the signals of the
+// daemon, see Notifier.Coordinate. Its methods may be called concurrently.
+type Coordinator struct {
+	nf     *Notifier
+	addr   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	// done is closed when the node left the group.
+	done chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	events chan Event
+	// subs are the tags the node receives the events of, and sent the tags
+	// of the notifications sent through it, by ID, announced to every new
+	// leader.
+	subs map[string]bool
+	sent map[uint32]string
+	// up is the connection to the leader, if the node follows one.
+	up *coordPeer
+	// leader is true if the node leads the group, with the followers in
+	// peers and the notifications announced by the group in tags.
+	leader bool
+	peers  map[*coordPeer]bool
+	tags   map[uint32]coordEntry
+	// tokens holds the activation tokens received for the next
+	// ActionInvoked signal, by ID.
+	tokens map[uint32]string
+}
+
+// coordPeer is the connection to another node.
+type coordPeer struct {
+	conn net.Conn
+	enc  *json.Encoder
+	// subs are the tags of the follower, for the leader.
+	subs map[string]bool
+}
+
+// coordEntry is a notification announced to the leader, by the follower
+// owner, or by the leader itself if owner is nil.
+type coordEntry struct {
+	tag   string
+	owner *coordPeer
+}
+
+// coordMessage is a line of the protocol between the nodes, in JSON.
+type coordMessage struct {
+	// Subscribe are tags a follower receives the events of.
+	Subscribe []string `json:"subscribe,omitempty"`
+	// Sent are the tags of notifications sent by a follower, by ID.
+	Sent map[uint32]string `json:"sent,omitempty"`
+	// Event is an event forwarded by the leader to a subscribed follower.
+	Event *Event `json:"event,omitempty"`
+	// Closed are the IDs of the notifications of a follower that were
+	// closed, which it no longer announces.
+	Closed []uint32 `json:"closed,omitempty"`
+}
+
+// send writes m to p. On failure, the connection is closed so that the
+// node reading it notices.
+func (p *coordPeer) send(m coordMessage) {
+	p.conn.SetWriteDeadline(time.Now().Add(coordWriteTimeout))
+	if err := p.enc.Encode(m); err != nil {
+		p.conn.Close()
+	}
+}
+
+// Coordinate makes nf a node of the group of processes sharing addr, so
+// that a single one of them, the leader, watches every signal of the daemon
+// (see WatchAll) and forwards the events of the tagged notifications to the
+// nodes subscribed to their tag. The others are not woken up by the signals
+// they receive no events of.
+//
+// addr is an abstract unix socket if it starts with "@", on Linux, or the
+// path of a unix socket, next to which a ".lock" file is locked by the
+// leader. The first node listening on it leads, the others connect to it;
+// when the leader leaves, the others elect a new one among themselves.
+//
+// Only the notifications sent with Coordinator.Notify are announced, and
+// their action, close and reply events forwarded, which can be missed
+// while a new leader is elected. The callbacks of the notifications are
+// unaffected. The Coordinator is closed with nf.
+func (nf *Notifier) Coordinate(addr string) (*Coordinator, error) {
+	if addr == "" {
+		return nil, errors.New("notify: empty coordination address")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Coordinator{
+		nf:     nf,
+		addr:   addr,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		events: make(chan Event, eventsBuffer),
+		subs:   make(map[string]bool),
+		sent:   make(map[uint32]string),
+	}
+	nf.mu.Lock()
+	if nf.coordinators == nil {
+		nf.coordinators = make(map[*Coordinator]struct{})
+	}
+	nf.coordinators[c] = struct{}{}
+	nf.mu.Unlock()
+	go c.run()
+	return c, nil
+}
+
+// Events returns the channel receiving the events of the notifications
+// whose tags the node subscribed to, closed by Close. Like with
+// Notifier.Events, events are dropped if it is full.
+func (c *Coordinator) Events() <-chan Event {
+	return c.events
+}
+
+// Subscribe makes the node receive the events of the notifications tagged
+// with one of tags, whichever node sent them.
+func (c *Coordinator) Subscribe(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		c.subs[tag] = true
+	}
+	if c.up != nil {
+		c.up.send(coordMessage{Subscribe: tags})
+	}
+}
+
+// Notify sends n with the Notifier of c like Notifier.Notify, and
+// announces it to the group if it has a tag.
+func (c *Coordinator) Notify(n *Notification) (SendResult, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return SendResult{}, ErrCoordinatorClosed
+	}
+	res, err := c.nf.Notify(n)
+	if err != nil || n.Tag == "" || res.Id == 0 {
+		return res, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent[res.Id] = n.Tag
+	switch {
+	case c.leader:
+		c.tags[res.Id] = coordEntry{tag: n.Tag}
+	case c.up != nil:
+		c.up.send(coordMessage{Sent: map[uint32]string{res.Id: n.Tag}})
+	}
+	return res, nil
+}
+
+// Leader returns true if the node leads the group.
+func (c *Coordinator) Leader() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.leader
+}
+
+// Close makes the node leave the group, which elects another leader if it
+// was the one, and closes the channel of Events.
+func (c *Coordinator) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	c.cancel()
+	<-c.done
+	c.nf.mu.Lock()
+	delete(c.nf.coordinators, c)
+	c.nf.mu.Unlock()
+	c.mu.Lock()
+	close(c.events)
+	c.mu.Unlock()
+	return nil
+}
+
+// closeCoordinators closes the coordinators of nf.
+func (nf *Notifier) closeCoordinators() {
+	nf.mu.Lock()
+	coordinators := make([]*Coordinator, 0, len(nf.coordinators))
+	for c := range nf.coordinators {
+		coordinators = append(coordinators, c)
+	}
+	nf.mu.Unlock()
+	for _, c := range coordinators {
+		c.Close()
+	}
+}
+
+// run leads the group, or follows its leader, until c is closed.
+func (c *Coordinator) run() {
+	defer close(c.done)
+	for c.ctx.Err() == nil {
+		if ln, release, err := coordListen(c.addr); err == nil {
+			c.lead(ln, release)
+			continue
+		}
+		if conn, err := net.Dial("unix", c.addr); err == nil {
+			c.follow(conn)
+			continue
+		}
+		select {
+		case <-c.ctx.Done():
+		case <-c.nf.clock.After(coordRetry):
+		}
+	}
+}
+
+// coordListen listens on addr if no other node does, and returns the
+// function releasing it once the listener is closed.
+func coordListen(addr string) (net.Listener, func(), error) {
+	if strings.HasPrefix(addr, "@") {
+		ln, err := net.Listen("unix", addr)
+		return ln, func() {}, err
+	}
+	lock, err := os.OpenFile(addr+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tryLockFile(lock); err != nil {
+		lock.Close()
+		return nil, nil, err
+	}
+	// The socket of a leader that exited without closing it is stale.
+	os.Remove(addr)
+	ln, err := net.Listen("unix", addr)
+	if err != nil {
+		lock.Close()
+		return nil, nil, err
+	}
+	return ln, func() { lock.Close() }, nil
+}
+
+// lead watches the signals and forwards the events to the followers
+// connecting to ln, until c is closed.
+func (c *Coordinator) lead(ln net.Listener, release func()) {
+	defer release()
+	defer ln.Close()
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	signals, err := c.nf.WatchAll(ctx)
+	if err != nil {
+		c.nf.log(LevelWarn, "the coordination leader cannot watch the signals", err)
+		select {
+		case <-ctx.Done():
+		case <-c.nf.clock.After(coordRetry):
+		}
+		return
+	}
+
+	c.mu.Lock()
+	c.leader = true
+	c.peers = make(map[*coordPeer]bool)
+	c.tags = make(map[uint32]coordEntry, len(c.sent))
+	for id, tag := range c.sent {
+		c.tags[id] = coordEntry{tag: tag}
+	}
+	c.tokens = make(map[uint32]string)
+	c.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	go c.accept(ln)
+	for sig := range signals {
+		c.dispatch(sig)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for p := range c.peers {
+		p.conn.Close()
+	}
+	c.leader = false
+	c.peers, c.tags, c.tokens = nil, nil, nil
+}
+
+// accept serves the followers connecting to ln until it is closed.
+func (c *Coordinator) accept(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		p := &coordPeer{conn: conn, enc: json.NewEncoder(conn), subs: make(map[string]bool)}
+		c.mu.Lock()
+		if !c.leader {
+			c.mu.Unlock()
+			conn.Close()
+			return
+		}
+		c.peers[p] = true
+		c.mu.Unlock()
+		go c.serve(p)
+	}
+}
+
+// serve reads the messages of the follower p until it leaves.
+func (c *Coordinator) serve(p *coordPeer) {
+	defer p.conn.Close()
+	dec := json.NewDecoder(p.conn)
+	for {
+		var m coordMessage
+		if err := dec.Decode(&m); err != nil {
+			break
+		}
+		c.mu.Lock()
+		for _, tag := range m.Subscribe {
+			p.subs[tag] = true
+		}
+		if c.tags != nil {
+			for id, tag := range m.Sent {
+				c.tags[id] = coordEntry{tag: tag, owner: p}
+			}
+		}
+		c.mu.Unlock()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.peers, p)
+	for id, e := range c.tags {
+		if e.owner == p {
+			delete(c.tags, id)
+		}
+	}
+}
+
+// dispatch forwards the event of sig, if it concerns a notification
+// announced to the leader, to the nodes subscribed to its tag.
+func (c *Coordinator) dispatch(sig RawSignal) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sig.Member == "ActivationToken" {
+		if len(sig.Body) == 2 {
+			if token, ok := sig.Body[1].(string); ok {
+				c.tokens[sig.ID] = token
+			}
+		}
+		return
+	}
+	entry, ok := c.tags[sig.ID]
+	if !ok {
+		return
+	}
+	e := Event{Time: c.nf.clock.Now(), ID: sig.ID, Tag: entry.tag}
+	switch sig.Member {
+	case "ActionInvoked":
+		e.Kind, e.Key, e.ActivationToken = EventAction, sig.Key, c.tokens[sig.ID]
+		delete(c.tokens, sig.ID)
+	case "NotificationReplied":
+		e.Kind = EventReplied
+	case "NotificationClosed":
+		e.Kind, e.Reason = EventClosed, sig.Reason
+		delete(c.tags, sig.ID)
+		delete(c.tokens, sig.ID)
+		if entry.owner == nil {
+			delete(c.sent, sig.ID)
+		} else {
+			entry.owner.send(coordMessage{Closed: []uint32{sig.ID}})
+		}
+	default:
+		return
+	}
+	if c.subs[e.Tag] {
+		c.deliver(e)
+	}
+	for p := range c.peers {
+		if p.subs[e.Tag] {
+			p.send(coordMessage{Event: &e})
+		}
+	}
+}
+
+// follow receives the events forwarded by the leader connected with conn,
+// until it leaves or c is closed.
+func (c *Coordinator) follow(conn net.Conn) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+	defer conn.Close()
+
+	p := &coordPeer{conn: conn, enc: json.NewEncoder(conn)}
+	c.mu.Lock()
+	hello := coordMessage{Sent: make(map[uint32]string, len(c.sent))}
+	for tag := range c.subs {
+		hello.Subscribe = append(hello.Subscribe, tag)
+	}
+	for id, tag := range c.sent {
+		hello.Sent[id] = tag
+	}
+	p.send(hello)
+	c.up = p
+	c.mu.Unlock()
+
+	dec := json.NewDecoder(conn)
+	for {
+		var m coordMessage
+		if err := dec.Decode(&m); err != nil {
+			break
+		}
+		c.mu.Lock()
+		if m.Event != nil {
+			c.deliver(*m.Event)
+		}
+		for _, id := range m.Closed {
+			delete(c.sent, id)
+		}
+		c.mu.Unlock()
+	}
+	c.mu.Lock()
+	c.up = nil
+	c.mu.Unlock()
+}
+
+// deliver sends e to the channel of Events, unless it is full. c.mu must be
+// held.
+func (c *Coordinator) deliver(e Event) {
+	select {
+	case c.events <- e:
+	default:
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// coordNodes returns n nodes of a group sharing addr, each with its own
+// Notifier.
+func coordNodes(t *testing.T, addr string, n int) []*notify.Coordinator {
+	t.Helper()
+	nodes := make([]*notify.Coordinator, n)
+	for i := range nodes {
+		c, err := newTestNotifier(t).Coordinate(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		nodes[i] = c
+	}
+	return nodes
+}
+
+// abstractAddr returns an abstract socket address for the test.
+func abstractAddr(t *testing.T) string {
+	if runtime.GOOS != "linux" {
+		t.Skip("no abstract unix sockets")
+	}
+	return fmt.Sprintf("@notify-test-%d-%s", os.Getpid(), strings.ReplaceAll(t.Name(), "/", "-"))
+}
+
+// waitLeader waits until a single one of nodes leads, and returns its index.
+func waitLeader(t *testing.T, nodes []*notify.Coordinator) int {
+	t.Helper()
+	leader := -1
+	waitFor(t, "a leader", func() bool {
+		leader = -1
+		for i, c := range nodes {
+			if c.Leader() {
+				if leader >= 0 {
+					return false
+				}
+				leader = i
+			}
+		}
+		return leader >= 0
+	})
+	return leader
+}
+
+// awaitAction invokes the action key of id until c receives its event,
+// since the announcements and subscriptions reach the leader
+// asynchronously.
+func awaitAction(t *testing.T, s *fakeServer, c *notify.Coordinator, id uint32, key string) notify.Event {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		s.emitAction(id, key)
+		select {
+		case e := <-c.Events():
+			return e
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("no event for the action %s of notification %d", key, id)
+		}
+	}
+}
+
+// drain returns the events received by c so far.
+func drain(c *notify.Coordinator) []notify.Event {
+	var events []notify.Event
+	for {
+		select {
+		case e := <-c.Events():
+			events = append(events, e)
+		case <-time.After(50 * time.Millisecond):
+			return events
+		}
+	}
+}
+
+func TestCoordinateForwardsTaggedEvents(t *testing.T) {
+	s := newFakeServer(t)
+	nodes := coordNodes(t, abstractAddr(t), 3)
+	leader := waitLeader(t, nodes)
+	// The sender and the subscriber follow the leader.
+	sender, subscriber := nodes[(leader+1)%3], nodes[(leader+2)%3]
+	subscriber.Subscribe("build")
+
+	n := notify.New("ci", "build done", "", "", 0, notify.NormalUrgency)
+	n.Tag = "build"
+	if _, err := sender.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	e := awaitAction(t, s, subscriber, n.Id, "open")
+	if e.Kind != notify.EventAction || e.ID != n.Id || e.Tag != "build" || e.Key != "open" {
+		t.Errorf("received %+v", e)
+	}
+	drain(subscriber)
+
+	s.emitClosed(n.Id, uint32(notify.ReasonDismissed))
+	select {
+	case e := <-subscriber.Events():
+		if e.Kind != notify.EventClosed || e.ID != n.Id || e.Reason != notify.ReasonDismissed {
+			t.Errorf("received %+v", e)
+		}
+	case <-waitTimeout():
+		t.Fatal("no closed event")
+	}
+
+	// The events of other tags are not forwarded.
+	other := notify.New("ci", "deploy done", "", "", 0, notify.NormalUrgency)
+	other.Tag = "deploy"
+	if _, err := nodes[leader].Notify(other); err != nil {
+		t.Fatal(err)
+	}
+	s.emitAction(other.Id, "open")
+	for _, c := range nodes {
+		if got := drain(c); len(got) != 0 {
+			t.Errorf("node received %v", got)
+		}
+	}
+}
+
+func TestCoordinatePromotesFollower(t *testing.T) {
+	s := newFakeServer(t)
+	nodes := coordNodes(t, abstractAddr(t), 3)
+	leader := waitLeader(t, nodes)
+	sender, subscriber := nodes[(leader+1)%3], nodes[(leader+2)%3]
+	subscriber.Subscribe("chat")
+
+	n := notify.New("chat", "new message", "", "", 0, notify.NormalUrgency)
+	n.Tag = "chat"
+	if _, err := sender.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	awaitAction(t, s, subscriber, n.Id, "reply")
+	drain(subscriber)
+
+	// The notification sent before is announced to the new leader.
+	if err := nodes[leader].Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitLeader(t, []*notify.Coordinator{sender, subscriber})
+	if e := awaitAction(t, s, subscriber, n.Id, "open"); e.Key != "open" || e.Tag != "chat" {
+		t.Errorf("received %+v from the new leader", e)
+	}
+}
+
+func TestCoordinateSocketPath(t *testing.T) {
+	s := newFakeServer(t)
+	addr := filepath.Join(t.TempDir(), "coord.sock")
+	nodes := coordNodes(t, addr, 2)
+	leader := waitLeader(t, nodes)
+	follower := nodes[1-leader]
+	follower.Subscribe("mail")
+
+	if err := nodes[leader].Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the follower to lead", follower.Leader)
+	n := notify.New("mail", "new mail", "", "", 0, notify.NormalUrgency)
+	n.Tag = "mail"
+	if _, err := follower.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	// The leader receives the events of its own subscriptions.
+	if e := awaitAction(t, s, follower, n.Id, "read"); e.Key != "read" {
+		t.Errorf("received %+v", e)
+	}
+
+	if err := follower.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-follower.Events():
+			closed = !ok
+		case <-waitTimeout():
+			t.Fatal("Events() is not closed by Close()")
+		}
+	}
+	if _, err := follower.Notify(n); err != notify.ErrCoordinatorClosed {
+		t.Errorf("Notify() after Close() = %v", err)
+	}
+}
+
+func TestCoordinateRetriesOnClock(t *testing.T) {
+	newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+	// Nobody can lead or follow in a directory that does not exist.
+	c, err := nf.Coordinate(filepath.Join(t.TempDir(), "missing", "coord.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	waitFor(t, "the retry timer", func() bool { return clock.Timers() == 1 })
+	clock.Advance(time.Second)
+	waitFor(t, "the next retry timer", func() bool { return clock.Timers() == 1 })
+	if c.Leader() {
+		t.Error("the node leads without a socket")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Core is a connection to the notification daemon shared by several
+// Notifiers, each with its own defaults, tags, callbacks and events. The
+// Notifiers of a Core share a single bus connection, a single subscription
+// to the signals of the daemon, and the cached information and capabilities
+// of the daemon.
+//
+// The connection is closed once the Core and all its Notifiers are closed.
+// The package-level functions use a default Core.
+type Core struct {
+	// root owns the connection and the subscription to the signals.
+	root *Notifier
+
+	mu sync.Mutex
+	// members are the Notifiers of the Core that are not closed, and
+	// closed is true once the Core itself is.
+	members map[*Notifier]struct{}
+	closed  bool
+	// subs are the members listening to the signals.
+	subs map[*Notifier]struct{}
+}
+
+// NewCore returns a new Core configured by opts, like NewNotifier. Its
+// Notifiers use the connection, destination, transport, clock and logger
+// configured by opts.
+func NewCore(opts ...Option) (*Core, error) {
+	if envQuirksErr != nil {
+		return nil, envQuirksErr
+	}
+	return newCore(opts...)
+}
+
+// newCore is NewCore, without the checks of the environment.
+func newCore(opts ...Option) (*Core, error) {
+	root, err := newNotifier(opts...)
+	if err != nil {
+		return nil, err
+	}
+	c := &Core{
+		root:    root,
+		members: make(map[*Notifier]struct{}),
+		subs:    make(map[*Notifier]struct{}),
+	}
+	root.fanout = c
+	return c, nil
+}
+
+// Notifier returns a new Notifier using the connection of c, sending its
+// notifications with the app name appName, or the name of the executable if
+// it is empty, and configured by opts. Options about the connection and the
+// destination are ignored: those of c apply.
+func (c *Core) Notifier(appName string, opts ...Option) (*Notifier, error) {
+	r := c.root
+	nf := baseNotifier()
+	nf.clock, nf.logf = r.clock, r.logf
+	if appName != "" {
+		nf.appName, nf.appNameSet = appName, true
+	}
+	for _, opt := range opts {
+		if err := opt(nf); err != nil {
+			return nil, err
+		}
+	}
+	r.mu.Lock()
+	pending := r.pendingLogs
+	r.mu.Unlock()
+	for _, p := range pending {
+		nf.logLater(p.level, p.msg, p.err)
+	}
+	nf.parent, nf.core = r, c
+	nf.address, nf.connOpts = r.address, r.connOpts
+	nf.destination, nf.path = r.destination, r.path
+	if nf.transport == nil {
+		nf.transport = r.transport
+	}
+	nf.transport = nf.bindBus(nf.transport)
+	nf.mirror()
+	if nf.resumeWatch {
+		nf.watchSleep()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.members[nf] = struct{}{}
+	return nf, nil
+}
+
+// Close releases the reference of c to the connection: it is closed once
+// the Notifiers of c are closed too. Notifiers can still be created
+// afterwards, and connect again.
+func (c *Core) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	last := len(c.members) == 0
+	c.mu.Unlock()
+
+	if last {
+		return c.root.Close()
+	}
+	return nil
+}
+
+// release forgets nf, closing the connection if it was the last user.
+func (c *Core) release(nf *Notifier) error {
+	c.mu.Lock()
+	_, ok := c.members[nf]
+	delete(c.members, nf)
+	delete(c.subs, nf)
+	last := ok && c.closed && len(c.members) == 0
+	c.mu.Unlock()
+
+	if last {
+		return c.root.Close()
+	}
+	return nil
+}
+
+// subscribe makes nf receive the signals of the daemon, listening to them
+// on conn if the Core does not yet.
+func (c *Core) subscribe(conn *dbus.Conn, nf *Notifier) error {
+	if err := c.root.listen(conn); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.members[nf]; ok {
+		c.subs[nf] = struct{}{}
+	}
+	return nil
+}
+
+// unsubscribe stops delivering the signals of the daemon to nf.
+func (c *Core) unsubscribe(nf *Notifier) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subs, nf)
+}
+
+// listeners returns the Notifiers receiving the signals.
+func (c *Core) listeners() []*Notifier {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	subs := make([]*Notifier, 0, len(c.subs))
+	for nf := range c.subs {
+		subs = append(subs, nf)
+	}
+	return subs
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/Schnouki/notify"
+)
+
+// matchRules returns the number of match rules of the connection name, or
+// skips the test if the bus does not tell.
+func matchRules(t *testing.T, conn *dbus.Conn, name string) uint32 {
+	t.Helper()
+	var stats map[string]dbus.Variant
+	err := conn.BusObject().Call("org.freedesktop.DBus.Debug.Stats.GetConnectionStats", 0, name).Store(&stats)
+	if err != nil {
+		t.Skipf("bus statistics unavailable: %v", err)
+	}
+	n, _ := stats["MatchRules"].Value().(uint32)
+	return n
+}
+
+func TestCoreSharesConnection(t *testing.T) {
+	s := newFakeServer(t)
+	core, err := notify.NewCore(notify.WithBusAddress(busAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer core.Close()
+
+	apps := []string{"updater", "sync", "alerts"}
+	var nfs []*notify.Notifier
+	for _, app := range apps {
+		nf, err := core.Notifier(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nfs = append(nfs, nf)
+	}
+
+	for i, nf := range nfs {
+		events := nf.Events()
+		n := notify.New("", "hello", "", "", 0, notify.NormalUrgency)
+		n.AddAction(notify.DefaultAction, "Open")
+		clicked := make(chan struct{}, 1)
+		n.OnAction = func(string) { clicked <- struct{}{} }
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		waitEvent(t, events, notify.EventSent)
+		s.emitAction(n.Id, notify.DefaultAction)
+		select {
+		case <-clicked:
+		case <-waitTimeout():
+			t.Fatalf("OnAction of %s not called", apps[i])
+		}
+	}
+
+	sent := s.notifications()
+	for _, n := range sent[1:] {
+		if n.Sender != sent[0].Sender {
+			t.Fatalf("notifications sent from %s and %s, want a single connection", sent[0].Sender, n.Sender)
+		}
+	}
+	for i, app := range apps {
+		if sent[i].AppName != app {
+			t.Errorf("notification %d sent as %q, want %q", i, sent[i].AppName, app)
+		}
+	}
+	if n := matchRules(t, s.conn, sent[0].Sender); n != 2 {
+		t.Errorf("the connection has %d match rules, want a single set of 2", n)
+	}
+
+	// Closing one Notifier leaves the others working.
+	nfs[0].Close()
+	if _, err := nfs[1].Notify(notify.New("", "still here", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Errorf("Notify after closing another Notifier: %v", err)
+	}
+}
+
+func TestCoreNotifierSettleWindow(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	core, err := notify.NewCore(notify.WithBusAddress(busAddress), notify.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer core.Close()
+	nf, err := core.Notifier("chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	var log settleLog
+	n := notify.New("", "Message from Bob", "", "", 0, notify.NormalUrgency)
+	n.AddAction("inline-reply", "Reply")
+	log.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	// The close is held back for the action coming right after it.
+	s.emitClosed(n.Id, uint32(notify.ReasonDismissed))
+	s.emitAction(n.Id, "inline-reply")
+	waitFor(t, "the callbacks", func() bool { return len(log.get()) == 2 })
+	if got, want := log.get(), []string{"action inline-reply", "close dismissed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("callbacks %q, want %q", got, want)
+	}
+}
+
+func TestCoreClosesWithLastNotifier(t *testing.T) {
+	s := newFakeServer(t)
+	core, err := notify.NewCore(notify.WithBusAddress(busAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nf, err := core.Notifier("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.Notify(notify.New("", "hello", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	sender := s.last(t).Sender
+
+	core.Close()
+	if !hasName(t, s.conn, sender) {
+		t.Fatal("connection closed while a Notifier uses it")
+	}
+	nf.Close()
+	waitFor(t, "the connection to close", func() bool { return !hasName(t, s.conn, sender) })
+}
+
+// hasName returns true if name is on the bus.
+func hasName(t *testing.T, conn *dbus.Conn, name string) bool {
+	t.Helper()
+	var has bool
+	if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, name).Store(&has); err != nil {
+		t.Fatal(err)
+	}
+	return has
+}
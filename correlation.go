@@ -0,0 +1,28 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// newCorrelationID returns a random version 4 UUID.
+func newCorrelationID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("notify: reading random bytes failed: " + err.Error())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// correlate gives n a correlation ID if it has none.
+func correlate(n *Notification) {
+	if n.CorrelationID == "" {
+		n.CorrelationID = newCorrelationID()
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+// WithCriticalNoExpiry makes the Notifier send the critical notifications
+// with a timeout as never expiring, as the specification says they should
+// not expire by themselves: this catches notifications built from a
+// template with a timeout, and made critical later. The notifications with
+// DefaultTimeout, which lets the daemon choose, are left alone, and so are
+// those with AllowCriticalExpiry set.
+//
+// The notifications sent without their timeout have
+// SendResult.CriticalNoExpiry set, and are logged.
+func WithCriticalNoExpiry() Option {
+	return func(nf *Notifier) error {
+		nf.criticalNoExpiry = true
+		return nil
+	}
+}
+
+// dropsCriticalTimeout returns true if n is sent as never expiring instead
+// of with its timeout, see WithCriticalNoExpiry.
+func (nf *Notifier) dropsCriticalTimeout(n *Notification) bool {
+	return nf.criticalNoExpiry && n.urgency() == CriticalUrgency && n.Timeout > 0 && !n.AllowCriticalExpiry
+}
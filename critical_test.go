@@ -0,0 +1,99 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestCriticalNoExpiry(t *testing.T) {
+	s := newFakeServer(t)
+	var rec logRecorder
+	nf := newTestNotifier(t, notify.WithCriticalNoExpiry(), notify.WithMinimumTimeout(5*time.Second), notify.WithLogger(rec.log))
+
+	// A template with a timeout, reused for all urgencies.
+	template := notify.New("test", "template", "", "", 2*time.Second, notify.NormalUrgency)
+	for _, tt := range []struct {
+		urgency notify.NotificationUrgency
+		timeout notify.Duration
+		allow   bool
+		want    int32
+		dropped bool
+	}{
+		{notify.NormalUrgency, notify.Duration(2 * time.Second), false, 5000, false},
+		{notify.CriticalUrgency, notify.Duration(2 * time.Second), false, 0, true},
+		{notify.CriticalUrgency, notify.Duration(2 * time.Second), true, 5000, false},
+		{notify.CriticalUrgency, 0, false, 0, false},
+		{notify.CriticalUrgency, notify.DefaultTimeout, false, -1, false},
+	} {
+		n := *template
+		n.Urgency, n.Timeout, n.AllowCriticalExpiry = tt.urgency, tt.timeout, tt.allow
+		res, err := nf.Notify(&n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.last(t).ExpireTimeout; got != tt.want {
+			t.Errorf("%v, timeout %v, allow %v: ExpireTimeout = %d, want %d", tt.urgency, tt.timeout, tt.allow, got, tt.want)
+		}
+		if res.CriticalNoExpiry != tt.dropped {
+			t.Errorf("%v, timeout %v, allow %v: CriticalNoExpiry = %v, want %v", tt.urgency, tt.timeout, tt.allow, res.CriticalNoExpiry, tt.dropped)
+		}
+	}
+	// Made critical through the urgency hint.
+	hinted := notify.New("test", "hinted", "", "", 2*time.Second, notify.NormalUrgency)
+	hinted.AddHints(notify.UrgencyHint(notify.CriticalUrgency))
+	if res, err := nf.Notify(hinted); err != nil || !res.CriticalNoExpiry {
+		t.Fatalf("Notify with the urgency hint = %+v, %v", res, err)
+	}
+	if got := s.last(t).ExpireTimeout; got != 0 {
+		t.Errorf("ExpireTimeout = %d with the urgency hint, want 0", got)
+	}
+	if template.Urgency != notify.NormalUrgency || template.Timeout != notify.Duration(2*time.Second) {
+		t.Errorf("the template changed: %+v", template)
+	}
+	if !rec.logged("never expiring") {
+		t.Error("the dropped timeout was not logged")
+	}
+
+	// Without the option, the timeout is sent as is.
+	nf = newTestNotifier(t)
+	n := notify.New("test", "critical", "", "", 2*time.Second, notify.CriticalUrgency)
+	if res, err := nf.Notify(n); err != nil || res.CriticalNoExpiry {
+		t.Fatalf("Notify = %+v, %v", res, err)
+	}
+	if got := s.last(t).ExpireTimeout; got != 2000 {
+		t.Errorf("ExpireTimeout = %d without WithCriticalNoExpiry, want 2000", got)
+	}
+}
+
+func TestCriticalNoExpiryCloseOnAction(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithCriticalNoExpiry())
+
+	// A critical notification that never expires is still closed by the
+	// Notifier once an action is invoked.
+	var cb callbacks
+	n := notify.New("test", "critical", "", "", 2*time.Second, notify.CriticalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	n.CloseOnAction = true
+	cb.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).ExpireTimeout; got != 0 {
+		t.Errorf("ExpireTimeout = %d, want 0", got)
+	}
+	s.emitAction(n.Id, notify.DefaultAction)
+	waitFor(t, "close timer", func() bool { return clock.Timers() == 1 })
+	clock.Advance(time.Second)
+	waitFor(t, "close after action", func() bool { return len(cb.closed()) == 1 })
+	if ids := s.closedIDs(); len(ids) != 1 || ids[0] != n.Id {
+		t.Errorf("CloseNotification called with %v, want [%d]", ids, n.Id)
+	}
+}
@@ -0,0 +1,390 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// TraceKind is the kind of a TraceEvent.
+type TraceKind int
+
+const (
+	TraceNotify TraceKind = iota // TraceNotify is a Notify call.
+	TraceClose                   // TraceClose is a CloseNotification call.
+	TraceReply                   // TraceReply is the reply to a call.
+	TraceSignal                  // TraceSignal is a signal received.
+)
+
+var traceKindNames = [...]string{
+	TraceNotify: "notify",
+	TraceClose:  "close",
+	TraceReply:  "reply",
+	TraceSignal: "signal",
+}
+
+// String returns "notify", "close", "reply" or "signal".
+func (k TraceKind) String() string {
+	if k >= 0 && int(k) < len(traceKindNames) {
+		return traceKindNames[k]
+	}
+	return fmt.Sprintf("TraceKind(%d)", int(k))
+}
+
+// MarshalText encodes k as its name, see String.
+func (k TraceKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(traceKindNames) {
+		return nil, fmt.Errorf("notify: invalid trace kind %d", int(k))
+	}
+	return []byte(traceKindNames[k]), nil
+}
+
+// UnmarshalText decodes a kind encoded by MarshalText.
+func (k *TraceKind) UnmarshalText(text []byte) error {
+	for i, name := range traceKindNames {
+		if string(text) == name {
+			*k = TraceKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("notify: invalid trace kind %q", text)
+}
+
+// TraceEvent is a record of a debug trace, see EnableDebugTrace.
+type TraceEvent struct {
+	Time time.Time `json:"time"`
+	Kind TraceKind `json:"kind"`
+	// ID is the ID replaced by a Notify call, closed by a CloseNotification
+	// call, given by the reply to a Notify call, or of the notification of
+	// a signal that has one.
+	ID uint32 `json:"id,omitempty"`
+
+	// The arguments of a Notify call, see Call. The hints are formatted as
+	// by dbus.Variant.String, except the images, which are only described.
+	AppName       string            `json:"app_name,omitempty"`
+	AppIcon       string            `json:"app_icon,omitempty"`
+	Summary       string            `json:"summary,omitempty"`
+	Body          string            `json:"body,omitempty"`
+	Actions       []string          `json:"actions,omitempty"`
+	Hints         map[string]string `json:"hints,omitempty"`
+	ExpireTimeout int32             `json:"expire_timeout,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+
+	// Err is the error of a reply, if the call failed.
+	Err string `json:"error,omitempty"`
+
+	// Signal is the name of a signal, such as
+	// "org.freedesktop.Notifications.ActionInvoked", and Args are its
+	// arguments after the ID, formatted with fmt.
+	Signal string   `json:"signal,omitempty"`
+	Args   []string `json:"args,omitempty"`
+}
+
+// debugTrace writes the debug trace of a Notifier.
+type debugTrace struct {
+	mu   sync.Mutex
+	w    io.Writer
+	json bool
+}
+
+// EnableDebugTrace makes nf write to w a line for each Notify and
+// CloseNotification call it makes, each reply to them and each signal it
+// receives, as the calls are made, for bug reports. The calls are written as
+// DryRun returns them, after redaction, see WithRedactor; so are the texts
+// replied by the user. Write errors are ignored.
+//
+// The lines are meant to be read by people, but ReadTrace parses them back.
+// EnableDebugTrace(nil) stops the trace. It may be called at any time.
+func (nf *Notifier) EnableDebugTrace(w io.Writer) {
+	nf.setDebugTrace(w, false)
+}
+
+// EnableDebugTraceJSON is EnableDebugTrace writing each record as a line of
+// JSON, encoding a TraceEvent.
+func (nf *Notifier) EnableDebugTraceJSON(w io.Writer) {
+	nf.setDebugTrace(w, true)
+}
+
+func (nf *Notifier) setDebugTrace(w io.Writer, asJSON bool) {
+	if w == nil {
+		nf.debugTrace.Store(nil)
+		return
+	}
+	nf.debugTrace.Store(&debugTrace{w: w, json: asJSON})
+}
+
+// traceCall writes c to the debug trace of nf, if any.
+func (nf *Notifier) traceCall(c *Call) {
+	t := nf.debugTrace.Load()
+	if t == nil {
+		return
+	}
+	e := TraceEvent{
+		Kind:          TraceNotify,
+		ID:            c.ReplacesID,
+		AppName:       c.AppName,
+		AppIcon:       c.AppIcon,
+		Summary:       c.Summary,
+		Body:          c.Body,
+		Actions:       c.Actions,
+		ExpireTimeout: c.ExpireTimeout,
+		CorrelationID: c.CorrelationID,
+	}
+	if len(c.Hints) > 0 {
+		e.Hints = make(map[string]string, len(c.Hints))
+		for k, v := range c.Hints {
+			e.Hints[k] = traceHint(v)
+		}
+	}
+	nf.writeTrace(t, e)
+}
+
+// traceHint returns the hint v as written to the traces.
+func traceHint(v dbus.Variant) string {
+	if d, ok := v.Value().(imageData); ok {
+		return fmt.Sprintf("image %dx%d, %d bytes", d.Width, d.Height, len(d.Data))
+	}
+	return v.String()
+}
+
+// traceClose writes the closing of the notification id to the debug trace
+// of nf, if any.
+func (nf *Notifier) traceClose(id uint32) {
+	if t := nf.debugTrace.Load(); t != nil {
+		nf.writeTrace(t, TraceEvent{Kind: TraceClose, ID: id})
+	}
+}
+
+// traceReply writes the reply to a call to the debug trace of nf, if any.
+func (nf *Notifier) traceReply(id uint32, correlationID string, err error) {
+	t := nf.debugTrace.Load()
+	if t == nil {
+		return
+	}
+	e := TraceEvent{Kind: TraceReply, ID: id, CorrelationID: correlationID}
+	if err != nil {
+		e.Err = err.Error()
+	}
+	nf.writeTrace(t, e)
+}
+
+// traceSignal writes sig to the debug trace of nf, if any.
+func (nf *Notifier) traceSignal(sig *dbus.Signal) {
+	t := nf.debugTrace.Load()
+	if t == nil {
+		return
+	}
+	e := TraceEvent{Kind: TraceSignal, Signal: sig.Name}
+	args := sig.Body
+	if len(args) > 0 {
+		if id, ok := args[0].(uint32); ok {
+			e.ID, args = id, args[1:]
+		}
+	}
+	for _, arg := range args {
+		e.Args = append(e.Args, fmt.Sprint(arg))
+	}
+	if sig.Name == dbusInterface+".NotificationReplied" && len(e.Args) > 0 {
+		_, e.Args[0] = nf.redact("", e.Args[0])
+	}
+	nf.writeTrace(t, e)
+}
+
+func (nf *Notifier) writeTrace(t *debugTrace, e TraceEvent) {
+	e.Time = nf.clock.Now()
+	var line []byte
+	if t.json {
+		var err error
+		if line, err = json.Marshal(e); err != nil {
+			return
+		}
+		line = append(line, '\n')
+	} else {
+		line = e.appendText(nil)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.w.Write(line)
+}
+
+// appendText appends the line of e in the text format to b. Each field is
+// written as key=value, the strings quoted as in Go, and the hints as
+// hint="key":"value".
+func (e TraceEvent) appendText(b []byte) []byte {
+	b = e.Time.AppendFormat(b, time.RFC3339Nano)
+	b = append(b, ' ')
+	b = append(b, e.Kind.String()...)
+	str := func(key, v string) {
+		if v != "" {
+			b = append(b, ' ')
+			b = append(b, key...)
+			b = append(b, '=')
+			b = strconv.AppendQuote(b, v)
+		}
+	}
+	if e.ID != 0 {
+		b = append(b, " id="...)
+		b = strconv.AppendUint(b, uint64(e.ID), 10)
+	}
+	str("signal", e.Signal)
+	str("app", e.AppName)
+	str("icon", e.AppIcon)
+	str("summary", e.Summary)
+	str("body", e.Body)
+	for _, a := range e.Actions {
+		b = append(b, " action="...)
+		b = strconv.AppendQuote(b, a)
+	}
+	keys := make([]string, 0, len(e.Hints))
+	for k := range e.Hints {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b = append(b, " hint="...)
+		b = strconv.AppendQuote(b, k)
+		b = append(b, ':')
+		b = strconv.AppendQuote(b, e.Hints[k])
+	}
+	if e.ExpireTimeout != 0 {
+		b = append(b, " timeout="...)
+		b = strconv.AppendInt(b, int64(e.ExpireTimeout), 10)
+	}
+	str("correlation", e.CorrelationID)
+	str("error", e.Err)
+	for _, a := range e.Args {
+		b = append(b, " arg="...)
+		b = strconv.AppendQuote(b, a)
+	}
+	return append(b, '\n')
+}
+
+// ReadTrace parses a debug trace written by EnableDebugTrace or
+// EnableDebugTraceJSON. Blank lines are skipped.
+func ReadTrace(r io.Reader) ([]TraceEvent, error) {
+	var events []TraceEvent
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return events, err
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			var e TraceEvent
+			var perr error
+			if line[0] == '{' {
+				perr = json.Unmarshal([]byte(line), &e)
+			} else {
+				perr = e.parseText(line)
+			}
+			if perr != nil {
+				return events, fmt.Errorf("notify: trace line %d: %w", n, perr)
+			}
+			events = append(events, e)
+		}
+		if err == io.EOF {
+			return events, nil
+		}
+	}
+}
+
+// parseText parses a line written by appendText into e.
+func (e *TraceEvent) parseText(line string) error {
+	stamp, rest, _ := strings.Cut(line, " ")
+	t, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return err
+	}
+	e.Time = t
+	kind, rest, _ := strings.Cut(rest, " ")
+	if err := e.Kind.UnmarshalText([]byte(kind)); err != nil {
+		return err
+	}
+	for rest != "" {
+		key, v, ok := strings.Cut(rest, "=")
+		if !ok {
+			return fmt.Errorf("field without value %q", rest)
+		}
+		if key == "id" || key == "timeout" {
+			v, rest, _ = strings.Cut(v, " ")
+			if key == "id" {
+				id, err := strconv.ParseUint(v, 10, 32)
+				if err != nil {
+					return err
+				}
+				e.ID = uint32(id)
+			} else {
+				timeout, err := strconv.ParseInt(v, 10, 32)
+				if err != nil {
+					return err
+				}
+				e.ExpireTimeout = int32(timeout)
+			}
+			continue
+		}
+		s, v, err := unquotePrefix(v)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", key, err)
+		}
+		switch key {
+		case "signal":
+			e.Signal = s
+		case "app":
+			e.AppName = s
+		case "icon":
+			e.AppIcon = s
+		case "summary":
+			e.Summary = s
+		case "body":
+			e.Body = s
+		case "action":
+			e.Actions = append(e.Actions, s)
+		case "hint":
+			if !strings.HasPrefix(v, ":") {
+				return fmt.Errorf("hint %q without value", s)
+			}
+			var value string
+			if value, v, err = unquotePrefix(v[1:]); err != nil {
+				return fmt.Errorf("hint %q: %w", s, err)
+			}
+			if e.Hints == nil {
+				e.Hints = make(map[string]string)
+			}
+			e.Hints[s] = value
+		case "correlation":
+			e.CorrelationID = s
+		case "error":
+			e.Err = s
+		case "arg":
+			e.Args = append(e.Args, s)
+		default:
+			return fmt.Errorf("unknown field %q", key)
+		}
+		rest = strings.TrimPrefix(v, " ")
+	}
+	return nil
+}
+
+// unquotePrefix unquotes the Go string at the start of s, and returns the
+// rest of s.
+func unquotePrefix(s string) (string, string, error) {
+	q, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", s, errors.New("malformed string")
+	}
+	v, err := strconv.Unquote(q)
+	return v, s[len(q):], err
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// traceBuffer is a bytes.Buffer safe to write from the signal dispatcher.
+type traceBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *traceBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *traceBuffer) events(t *testing.T) []notify.TraceEvent {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events, err := notify.ReadTrace(bytes.NewReader(b.buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadTrace() = %v\n%s", err, b.buf.String())
+	}
+	return events
+}
+
+func kinds(events []notify.TraceEvent) []notify.TraceKind {
+	var ks []notify.TraceKind
+	for _, e := range events {
+		ks = append(ks, e.Kind)
+	}
+	return ks
+}
+
+func TestDebugTrace(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	redactor, err := notify.NewRegexpRedactor([]string{`hunter2`}, "***")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithRedactor(redactor))
+
+	var buf traceBuffer
+	nf.EnableDebugTrace(&buf)
+	n := notify.New("test", "password hunter2", "", "", 5*time.Second, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	n.OnReply = func(string) {}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	s.emitReplied(n.Id, "it is hunter2")
+	waitFor(t, "the traced signal", func() bool { return len(buf.events(t)) == 3 })
+	if err := nf.CloseNotification(n.Id); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the traced close", func() bool { return len(buf.events(t)) == 6 })
+
+	// The daemon may signal the close before the reply is traced.
+	events := buf.events(t)
+	if events[4].Kind == notify.TraceSignal {
+		events[4], events[5] = events[5], events[4]
+	}
+	want := []notify.TraceKind{notify.TraceNotify, notify.TraceReply, notify.TraceSignal, notify.TraceClose, notify.TraceReply, notify.TraceSignal}
+	if got := kinds(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("trace kinds = %v, want %v", got, want)
+	}
+	call := events[0]
+	if call.Summary != "password ***" || call.AppName != "test" || call.ExpireTimeout != 5000 ||
+		!reflect.DeepEqual(call.Actions, []string{notify.DefaultAction, "Open"}) || call.Hints["urgency"] != "@y 0x1" {
+		t.Errorf("traced call %+v", call)
+	}
+	if !call.Time.Equal(clock.Now()) {
+		t.Errorf("traced at %v, want %v", call.Time, clock.Now())
+	}
+	if reply := events[1]; reply.ID != n.Id || reply.Err != "" {
+		t.Errorf("traced reply %+v, want ID %d", reply, n.Id)
+	}
+	if sig := events[2]; sig.Signal != "org.freedesktop.Notifications.NotificationReplied" || sig.ID != n.Id ||
+		!reflect.DeepEqual(sig.Args, []string{"it is ***"}) {
+		t.Errorf("traced signal %+v", sig)
+	}
+	if closed := events[5]; closed.Signal != "org.freedesktop.Notifications.NotificationClosed" || closed.ID != n.Id ||
+		!reflect.DeepEqual(closed.Args, []string{"3"}) {
+		t.Errorf("traced signal %+v", closed)
+	}
+
+	nf.EnableDebugTrace(nil)
+	if _, err := nf.Notify(notify.New("test", "untraced", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(buf.events(t)); got != 6 {
+		t.Errorf("%d events after disabling the trace, want 6", got)
+	}
+}
+
+func TestDebugTraceRoundTrip(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithClock(newFakeClock()))
+
+	var text, ndjson traceBuffer
+	send := func(w *traceBuffer, json bool) {
+		t.Helper()
+		if json {
+			nf.EnableDebugTraceJSON(w)
+		} else {
+			nf.EnableDebugTrace(w)
+		}
+		n := notify.New("test \"quoted\"", "a=b c", "two\nlines\t\u00e9\u2603", "", 0, notify.CriticalUrgency)
+		n.AddAction("x y", "=label")
+		n.SetHint("key with \"quotes\":and=signs ", "value: \"v\"")
+		n.CorrelationID = "corr 1"
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		n.Summary = "replaced"
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send(&text, false)
+	send(&ndjson, true)
+	nf.EnableDebugTrace(nil)
+
+	fromText, fromJSON := text.events(t), ndjson.events(t)
+	if len(fromText) != 4 {
+		t.Fatalf("got %d events from the text trace, want 4:\n%s", len(fromText), text.buf.String())
+	}
+	// Both traces are of the same calls, but for the IDs.
+	for i := range fromText {
+		a, b := fromText[i], fromJSON[i]
+		a.ID, b.ID = 0, 0
+		if !a.Time.Equal(b.Time) {
+			t.Errorf("event %d: times %v and %v", i, a.Time, b.Time)
+		}
+		a.Time, b.Time = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("event %d:\ntext %+v\njson %+v", i, a, b)
+		}
+	}
+	call := fromText[0]
+	if call.AppName != "test \"quoted\"" || call.Summary != "a=b c" || call.Body != "two\nlines\t\u00e9\u2603" ||
+		call.CorrelationID != "corr 1" || call.Hints["key with \"quotes\":and=signs "] != `"value: \"v\""` {
+		t.Errorf("parsed call %+v", call)
+	}
+	if replace := fromText[2]; replace.ID != fromText[1].ID || replace.Summary != "replaced" {
+		t.Errorf("replacing call %+v, want ID %d", replace, fromText[1].ID)
+	}
+}
+
+func TestReadTraceMalformed(t *testing.T) {
+	for _, trace := range []string{
+		"not a time notify\n",
+		"2013-01-01T00:00:00Z unknown\n",
+		"2013-01-01T00:00:00Z notify summary=unquoted\n",
+		"2013-01-01T00:00:00Z notify summary=\"unterminated\n",
+		"2013-01-01T00:00:00Z notify hint=\"key\"\n",
+		"2013-01-01T00:00:00Z notify id=x\n",
+		"2013-01-01T00:00:00Z notify color=\"red\"\n",
+		"{\"kind\":\"nope\"}\n",
+	} {
+		if _, err := notify.ReadTrace(strings.NewReader(trace)); err == nil {
+			t.Errorf("ReadTrace(%q) succeeded", trace)
+		}
+	}
+	events, err := notify.ReadTrace(strings.NewReader("\n2013-01-01T00:00:00Z close id=4\n\n2013-01-01T00:00:01Z reply id=4"))
+	if err != nil || len(events) != 2 || events[0].Kind != notify.TraceClose || events[1].ID != 4 {
+		t.Errorf("ReadTrace() = %+v, %v", events, err)
+	}
+}
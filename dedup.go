@@ -0,0 +1,162 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// dedup remembers the notifications shown recently, so that they are not
+// shown again within ttl, even by another process.
+type dedup struct {
+	mu   sync.Mutex
+	path string
+	ttl  time.Duration
+	// shown holds when each notification was last shown, by content hash.
+	// It is loaded from path when first needed.
+	shown map[string]time.Time
+}
+
+// WithDedup makes the Notifier drop the notifications it already showed
+// within ttl, recorded in the file at path so that they are remembered
+// across restarts. Notifications are the same if they have the same app
+// name, summary, body and "category" hint. Critical notifications are
+// always shown.
+//
+// A dropped notification is not sent, and its SendResult has Deduplicated
+// set. If the file cannot be read or written, notifications are shown as if
+// deduplication was off.
+func WithDedup(path string, ttl time.Duration) Option {
+	return func(nf *Notifier) error {
+		if path == "" {
+			return errors.New("notify: empty deduplication file path")
+		} else if ttl <= 0 {
+			return fmt.Errorf("notify: invalid deduplication TTL %v", ttl)
+		}
+		nf.dedup = &dedup{path: path, ttl: ttl}
+		return nil
+	}
+}
+
+// contentHash returns the hash identifying the content of n.
+func (nf *Notifier) contentHash(n *Notification) string {
+	category := n.Hint("category")
+	if v, ok := category.(dbus.Variant); ok {
+		category = v.Value()
+	}
+	h := sha256.New()
+	for _, s := range []string{nf.appNameFor(n.Name), n.Summary, n.Body, fmt.Sprint(category)} {
+		fmt.Fprintf(h, "%d:%s", len(s), s)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// duplicate returns true if n was shown within the TTL.
+func (nf *Notifier) duplicate(n *Notification) bool {
+	if nf.dedup == nil || n.Urgency == CriticalUrgency {
+		return false
+	}
+	d := nf.dedup
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load(nf)
+	shown, ok := d.shown[nf.contentHash(n)]
+	return ok && nf.clock.Now().Sub(shown) < d.ttl
+}
+
+// shown records that n was shown.
+func (nf *Notifier) shown(n *Notification) {
+	if nf.dedup == nil || n.Urgency == CriticalUrgency {
+		return
+	}
+	d := nf.dedup
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load(nf)
+	d.shown[nf.contentHash(n)] = nf.clock.Now()
+	d.save(nf)
+}
+
+// ForgetHash forgets that n was shown, so that the next identical
+// notification is shown even within the TTL set with WithDedup.
+func (nf *Notifier) ForgetHash(n *Notification) {
+	if nf.dedup == nil {
+		return
+	}
+	d := nf.dedup
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load(nf)
+	hash := nf.contentHash(n)
+	if _, ok := d.shown[hash]; ok {
+		delete(d.shown, hash)
+		d.save(nf)
+	}
+}
+
+// load reads the file of d, once. A missing or corrupted file is treated as
+// empty.
+func (d *dedup) load(nf *Notifier) {
+	if d.shown != nil {
+		return
+	}
+	d.shown = make(map[string]time.Time)
+	data, err := os.ReadFile(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	} else if err == nil {
+		err = json.Unmarshal(data, &d.shown)
+	}
+	if err != nil {
+		nf.log(LevelWarn, "ignoring deduplication file "+d.path, err)
+		d.shown = make(map[string]time.Time)
+	}
+}
+
+// save writes the unexpired entries of d to its file.
+func (d *dedup) save(nf *Notifier) {
+	now := nf.clock.Now()
+	for hash, shown := range d.shown {
+		if now.Sub(shown) >= d.ttl {
+			delete(d.shown, hash)
+		}
+	}
+	data, err := json.Marshal(d.shown)
+	if err == nil {
+		err = writeFileAtomic(d.path, data)
+	}
+	if err != nil {
+		nf.log(LevelWarn, "writing deduplication file "+d.path+" failed", err)
+	}
+}
+
+// writeFileAtomic replaces the file at path with data, so that readers never
+// see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
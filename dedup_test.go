@@ -0,0 +1,92 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func overdue(urgency notify.NotificationUrgency) *notify.Notification {
+	n := notify.New("backup", "Backup overdue", "Last backup: 3 days ago", "", time.Second, urgency)
+	n.AddHints(notify.CategoryHint("device"))
+	return n
+}
+
+func TestDedupAcrossRestarts(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	path := filepath.Join(t.TempDir(), "shown.json")
+
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithDedup(path, 24*time.Hour))
+	if res, err := nf.Notify(overdue(notify.NormalUrgency)); err != nil || res.Deduplicated {
+		t.Fatalf("first send returned %+v, %v", res, err)
+	}
+	nf.Close()
+
+	nf = newTestNotifier(t, notify.WithClock(clock), notify.WithDedup(path, 24*time.Hour))
+	res, err := nf.Notify(overdue(notify.NormalUrgency))
+	if err != nil || !res.Deduplicated || res.Id != 0 {
+		t.Fatalf("send after restart returned %+v, %v", res, err)
+	}
+	other := overdue(notify.NormalUrgency)
+	other.AddHints(notify.CategoryHint("transfer"))
+	if res, _ := nf.Notify(other); res.Deduplicated {
+		t.Error("notification with another category dropped")
+	}
+	if len(s.notifications()) != 2 {
+		t.Errorf("sent %d notifications, want 2", len(s.notifications()))
+	}
+
+	clock.Advance(24 * time.Hour)
+	if res, _ := nf.Notify(overdue(notify.NormalUrgency)); res.Deduplicated {
+		t.Error("notification dropped after the TTL")
+	}
+}
+
+func TestDedupBypassAndForget(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithDedup(filepath.Join(t.TempDir(), "shown.json"), time.Hour))
+
+	for i := 0; i < 2; i++ {
+		if res, _ := nf.Notify(overdue(notify.CriticalUrgency)); res.Deduplicated {
+			t.Error("critical notification dropped")
+		}
+	}
+
+	n := overdue(notify.NormalUrgency)
+	nf.Notify(n)
+	if res, _ := nf.Notify(n); !res.Deduplicated {
+		t.Error("duplicate sent")
+	}
+	nf.ForgetHash(n)
+	if res, _ := nf.Notify(n); res.Deduplicated {
+		t.Error("notification dropped after ForgetHash")
+	}
+	if got := len(s.notifications()); got != 4 {
+		t.Errorf("sent %d notifications, want 4", got)
+	}
+}
+
+func TestDedupCorruptedFile(t *testing.T) {
+	s := newFakeServer(t)
+	path := filepath.Join(t.TempDir(), "shown.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithDedup(path, time.Hour), notify.WithLogger(logs.log))
+
+	if res, err := nf.Notify(overdue(notify.NormalUrgency)); err != nil || res.Deduplicated {
+		t.Fatalf("send with a corrupted file returned %+v, %v", res, err)
+	}
+	if len(s.notifications()) != 1 || logs.len() == 0 {
+		t.Errorf("sent %d notifications, logged %q", len(s.notifications()), logs.msgs)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+// DeliveryContext is what was known of the desktop of the user when a
+// notification was sent, telling whether the user may have seen it. It is
+// gathered from what nf already knows, without asking anyone: the fields
+// that were not found out yet are left unknown.
+type DeliveryContext struct {
+	// Daemon is the name of the notification daemon, as cached by
+	// ServerInfo, or empty if it was not asked yet.
+	Daemon string
+	// Locked is true if the session was locked. With a detector set by
+	// WithLockDetector, it is called at each send; otherwise Locked is the
+	// last answer of SessionLocked. LockKnown is false if there is none.
+	Locked    bool
+	LockKnown bool
+	// DoNotDisturb is true if nf was paused, see Pause, or in quiet hours,
+	// see WithQuietHours: only the notifications let through were sent.
+	DoNotDisturb bool
+	// GraphicalSession is the last answer of GraphicalSessionActive.
+	// SessionKnown is false if there is none.
+	GraphicalSession bool
+	SessionKnown     bool
+}
+
+// probedSession holds the last answers of SessionLocked and
+// GraphicalSessionActive, for DeliveryContext.
+type probedSession struct {
+	locked, lockKnown         bool
+	graphical, graphicalKnown bool
+}
+
+// deliveryContext returns the DeliveryContext of a notification sent now.
+func (nf *Notifier) deliveryContext() DeliveryContext {
+	var dc DeliveryContext
+	if nf.lockDetector != nil {
+		locked, err := nf.lockDetector()
+		dc.Locked, dc.LockKnown = locked, err == nil
+	}
+	_, quiet := nf.InQuietHours()
+
+	nf.mu.Lock()
+	info := nf.info
+	dc.DoNotDisturb = nf.stats.Paused || quiet
+	if !dc.LockKnown {
+		dc.Locked, dc.LockKnown = nf.probed.locked, nf.probed.lockKnown
+	}
+	dc.GraphicalSession, dc.SessionKnown = nf.probed.graphical, nf.probed.graphicalKnown
+	nf.mu.Unlock()
+
+	if nf.core != nil {
+		root := nf.core.root
+		root.mu.Lock()
+		info = root.info
+		root.mu.Unlock()
+	}
+	if info != nil {
+		dc.Daemon = info.Name
+	}
+	return dc
+}
+
+// sessionProbed records the answer of SessionLocked.
+func (nf *Notifier) sessionProbed(locked bool) {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	nf.probed.locked, nf.probed.lockKnown = locked, true
+}
+
+// graphicalProbed records the answer of GraphicalSessionActive.
+func (nf *Notifier) graphicalProbed(active bool) {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	nf.probed.graphical, nf.probed.graphicalKnown = active, true
+}
+
+// json returns the JSON form of dc.
+func (dc DeliveryContext) json() *jsonContext {
+	j := &jsonContext{Daemon: dc.Daemon, DoNotDisturb: dc.DoNotDisturb}
+	if dc.LockKnown {
+		j.Locked = &dc.Locked
+	}
+	if dc.SessionKnown {
+		j.GraphicalSession = &dc.GraphicalSession
+	}
+	return j
+}
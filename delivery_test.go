@@ -0,0 +1,146 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/notifytest"
+)
+
+func TestDeliveryContext(t *testing.T) {
+	s := newFakeServer(t)
+	s.setServerName("fake-daemon")
+	l := newFakeLogind(t)
+	l.addSession(t, "1", "wayland", false)
+	night := notify.QuietHours{Windows: []notify.QuietWindow{{22 * time.Hour, 7 * time.Hour}}, Location: time.UTC}
+	at := func(hour int) notify.Option {
+		return notify.WithClock(notifytest.NewClock(time.Date(2013, 1, 1, hour, 0, 0, 0, time.UTC)))
+	}
+
+	tests := []struct {
+		name    string
+		opts    []notify.Option
+		locked  func() (bool, error)
+		pause   bool
+		probe   bool
+		active  bool
+		urgency notify.NotificationUrgency
+		want    notify.DeliveryContext
+	}{
+		{
+			name: "nothing probed",
+			want: notify.DeliveryContext{},
+		},
+		{
+			name:   "active session",
+			locked: func() (bool, error) { return false, nil },
+			probe:  true,
+			active: true,
+			want:   notify.DeliveryContext{Daemon: "fake-daemon", LockKnown: true, GraphicalSession: true, SessionKnown: true},
+		},
+		{
+			name:   "locked",
+			locked: func() (bool, error) { return true, nil },
+			probe:  true,
+			active: true,
+			want:   notify.DeliveryContext{Daemon: "fake-daemon", Locked: true, LockKnown: true, GraphicalSession: true, SessionKnown: true},
+		},
+		{
+			name:   "no graphical session",
+			locked: func() (bool, error) { return false, errors.New("no screensaver") },
+			probe:  true,
+			want:   notify.DeliveryContext{Daemon: "fake-daemon", SessionKnown: true},
+		},
+		{
+			name:    "paused",
+			pause:   true,
+			urgency: notify.CriticalUrgency,
+			want:    notify.DeliveryContext{DoNotDisturb: true},
+		},
+		{
+			name:    "quiet hours",
+			opts:    []notify.Option{notify.WithQuietHours(night), at(23)},
+			urgency: notify.CriticalUrgency,
+			want:    notify.DeliveryContext{DoNotDisturb: true},
+		},
+		{
+			name: "out of quiet hours",
+			opts: []notify.Option{notify.WithQuietHours(night), at(12)},
+			want: notify.DeliveryContext{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]notify.Option{notify.WithHistory(1), notify.WithLogindConn(l.conn)}, tt.opts...)
+			if tt.locked != nil {
+				opts = append(opts, notify.WithLockDetector(tt.locked))
+			}
+			nf := newTestNotifier(t, opts...)
+			if tt.probe {
+				if tt.active {
+					l.activate("1")
+					t.Cleanup(func() { l.setActive("1", false) })
+				}
+				if _, err := nf.GraphicalSessionActive(); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := nf.ServerInfo(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.pause {
+				nf.Pause()
+			}
+			res, err := nf.Notify(notify.New("test", tt.name, "", "", 0, tt.urgency))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Context != tt.want {
+				t.Errorf("SendResult.Context = %+v, want %+v", res.Context, tt.want)
+			}
+			if h := nf.History(); len(h) != 1 || h[0].Context != tt.want {
+				t.Errorf("History() = %+v, want a record with the Context %+v", h, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliveryContextJSON(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithHistory(1), notify.WithLockDetector(func() (bool, error) { return true, nil }))
+	if _, err := nf.ServerInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.Notify(notify.New("test", "locked", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(nf.History())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Records []struct {
+			Context map[string]interface{} `json:"context"`
+		} `json:"records"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Records) != 1 {
+		t.Fatalf("exported %s", data)
+	}
+	c := doc.Records[0].Context
+	if c["locked"] != true || c["do_not_disturb"] != false || c["daemon"] == nil {
+		t.Errorf("exported context = %v", c)
+	}
+	if _, ok := c["graphical_session"]; ok {
+		t.Errorf("exported context = %v, want no graphical_session", c)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// ErrUnsupported is returned when the notification daemon cannot do what
+// was asked.
+var ErrUnsupported = errors.New("notify: not supported by the notification daemon")
+
+// dunstInterface is the D-Bus interface of dunst's own commands.
+const dunstInterface = "org.dunstproject.cmd0"
+
+// DisplayedInfo describes a notification currently shown by the daemon,
+// whichever application sent it.
+type DisplayedInfo struct {
+	ID      uint32
+	AppName string
+	Summary string
+	Body    string
+	Urgency NotificationUrgency
+}
+
+// Displayed returns the notifications currently shown by the daemon, or
+// ErrUnsupported if it cannot tell. Only daemons with the command interface
+// of dunst can: Displayed looks for it with ProbeInterfaces, or goes by
+// Quirks.Displayed if the daemon cannot be introspected.
+func (nf *Notifier) Displayed() ([]DisplayedInfo, error) {
+	if ifaces, err := nf.ProbeInterfaces(context.Background()); err == nil && len(ifaces) > 0 {
+		if dunst, ok := findInterface(ifaces, dunstInterface); ok && dunst.HasMethod("NotificationListDisplayed") {
+			return nf.dunstDisplayed()
+		}
+		return nil, ErrUnsupported
+	}
+	switch nf.daemonQuirks().Displayed {
+	case "dunst":
+		return nf.dunstDisplayed()
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// dunstDisplayed lists the notifications shown by dunst.
+func (nf *Notifier) dunstDisplayed() ([]DisplayedInfo, error) {
+	conn, err := nf.connection()
+	if err != nil {
+		return nil, err
+	}
+	var list []map[string]dbus.Variant
+	err = nf.object(conn).Call(dunstInterface+".NotificationListDisplayed", nf.callFlags()).Store(&list)
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) && (dbusErr.Name == "org.freedesktop.DBus.Error.UnknownMethod" ||
+		dbusErr.Name == "org.freedesktop.DBus.Error.UnknownInterface") {
+		// Older versions of dunst only list their history.
+		return nil, ErrUnsupported
+	} else if err != nil {
+		return nil, fmt.Errorf("notify: listing the notifications shown by dunst: %w", err)
+	}
+
+	shown := make([]DisplayedInfo, 0, len(list))
+	for _, entry := range list {
+		d := DisplayedInfo{
+			AppName: dunstString(entry, "appname"),
+			Summary: dunstString(entry, "summary"),
+			Body:    dunstString(entry, "body"),
+			Urgency: NormalUrgency,
+		}
+		switch id := entry["id"].Value().(type) {
+		case int32:
+			d.ID = uint32(id)
+		case uint32:
+			d.ID = id
+		}
+		switch strings.ToUpper(dunstString(entry, "urgency")) {
+		case "LOW":
+			d.Urgency = LowUrgency
+		case "CRITICAL":
+			d.Urgency = CriticalUrgency
+		}
+		shown = append(shown, d)
+	}
+	return shown, nil
+}
+
+// dunstString returns the string field key of a dunst notification entry.
+func dunstString(entry map[string]dbus.Variant, key string) string {
+	s, _ := entry[key].Value().(string)
+	return s
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeDunst exports the command interface of dunst.
+type fakeDunst struct {
+	displayed []map[string]dbus.Variant
+}
+
+func (d fakeDunst) NotificationListDisplayed() ([]map[string]dbus.Variant, *dbus.Error) {
+	return d.displayed, nil
+}
+
+func TestDisplayedDunst(t *testing.T) {
+	s := newFakeServer(t)
+	s.setServerName("dunst")
+	dunst := fakeDunst{displayed: []map[string]dbus.Variant{
+		{
+			"id":      dbus.MakeVariant(int32(4)),
+			"appname": dbus.MakeVariant("mail"),
+			"summary": dbus.MakeVariant("New mail"),
+			"body":    dbus.MakeVariant("From: someone"),
+			"urgency": dbus.MakeVariant("CRITICAL"),
+		},
+		{
+			"id":      dbus.MakeVariant(int32(5)),
+			"summary": dbus.MakeVariant("low"),
+			"urgency": dbus.MakeVariant("LOW"),
+		},
+	}}
+	if err := s.conn.Export(dunst, s.path, "org.dunstproject.cmd0"); err != nil {
+		t.Fatal(err)
+	}
+	nf := newTestNotifier(t)
+
+	got, err := nf.Displayed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []notify.DisplayedInfo{
+		{ID: 4, AppName: "mail", Summary: "New mail", Body: "From: someone", Urgency: notify.CriticalUrgency},
+		{ID: 5, Summary: "low", Urgency: notify.LowUrgency},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Displayed() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDisplayedUnsupported(t *testing.T) {
+	for _, name := range []string{"fake", "dunst"} {
+		t.Run(name, func(t *testing.T) {
+			s := newFakeServer(t)
+			s.setServerName(name)
+			nf := newTestNotifier(t)
+			if _, err := nf.Displayed(); !errors.Is(err, notify.ErrUnsupported) {
+				t.Errorf("Displayed() = %v, want ErrUnsupported", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import "fmt"
+
+// WithStrictUrgency makes the Notifier warn when a notification replaces
+// one it sent with a higher urgency, like a ReplaceUrgentMsg passed the zero
+// NotificationUrgency, which is LowUrgency. The replacement is still sent,
+// with SendResult.UrgencyLowered set, and logged; notifications with
+// AllowUrgencyDowngrade set are meant to lower it, and are left alone.
+func WithStrictUrgency() Option {
+	return func(nf *Notifier) error {
+		nf.strictUrgency = true
+		return nil
+	}
+}
+
+// lowersUrgency returns true if c replaces a notification nf sent with a
+// higher urgency, and logs it, unless allow is true.
+func (nf *Notifier) lowersUrgency(c Call, allow bool) bool {
+	if !nf.strictUrgency || c.ReplacesID == 0 || allow {
+		return false
+	}
+	nf.mu.Lock()
+	last, ok := nf.sentUrgency[c.ReplacesID]
+	nf.mu.Unlock()
+	if !ok || c.Urgency >= last {
+		return false
+	}
+	nf.log(LevelWarn, fmt.Sprintf("notification %d replaced with the urgency %v instead of %v; set AllowUrgencyDowngrade if that is meant", c.ReplacesID, c.Urgency, last), nil)
+	return true
+}
+
+// recordUrgency records that id was sent with the urgency of c.
+func (nf *Notifier) recordUrgency(id uint32, c Call) {
+	if !nf.strictUrgency {
+		return
+	}
+	// The closed notifications are forgotten, so listen to the signals to
+	// find out.
+	nf.listenAlways()
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if nf.sentUrgency == nil {
+		nf.sentUrgency = make(map[uint32]NotificationUrgency)
+	}
+	if c.ReplacesID != 0 && c.ReplacesID != id {
+		delete(nf.sentUrgency, c.ReplacesID)
+	}
+	nf.sentUrgency[id] = c.Urgency
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestStrictUrgency(t *testing.T) {
+	s := newFakeServer(t)
+	var rec logRecorder
+	nf := newTestNotifier(t, notify.WithStrictUrgency(), notify.WithLogger(rec.log))
+
+	n := notify.New("test", "disk full", "", "", 0, notify.CriticalUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		urgency notify.NotificationUrgency
+		allow   bool
+		lowered bool
+	}{
+		{notify.CriticalUrgency, false, false},
+		// The zero urgency, from a struct built without it.
+		{0, false, true},
+		{notify.NormalUrgency, false, false},
+		{notify.LowUrgency, true, false},
+		{notify.NormalUrgency, false, false},
+	} {
+		n.Urgency, n.AllowUrgencyDowngrade = tt.urgency, tt.allow
+		res, err := nf.Notify(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.UrgencyLowered != tt.lowered {
+			t.Errorf("%v, allow %v: UrgencyLowered = %v, want %v", tt.urgency, tt.allow, res.UrgencyLowered, tt.lowered)
+		}
+	}
+	if rec.len() != 1 || !rec.logged("replaced with the urgency low instead of critical") {
+		t.Errorf("logged %d messages, want the downgrade", rec.len())
+	}
+	if got := s.notifications(); len(got) != 6 || got[2].Hints["urgency"].Value() != byte(notify.LowUrgency) {
+		t.Errorf("the downgrades were not sent")
+	}
+
+	// A closed notification is forgotten.
+	if err := nf.CloseNotification(n.Id); err != nil {
+		t.Fatal(err)
+	}
+	res, err := nf.Notify(notify.New("test", "new lifecycle", "", "", 0, notify.LowUrgency))
+	if err != nil || res.UrgencyLowered {
+		t.Errorf("Notify() = %+v, %v", res, err)
+	}
+}
+
+func TestStrictUrgencyImplicit(t *testing.T) {
+	s := newFakeServer(t)
+	var rec logRecorder
+	original := notify.SetDefault(newTestNotifier(t, notify.WithStrictUrgency(), notify.WithLogger(rec.log)))
+	defer notify.SetDefault(original)
+
+	id, err := notify.SendUrgentMsg("update ready", "", notify.CriticalUrgency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notify.ReplaceUrgentMsg(id, "update installed", "", 0); err != nil {
+		t.Fatal(err)
+	}
+	if !rec.logged("instead of critical") {
+		t.Error("the lowered urgency was not logged")
+	}
+	if got := s.last(t); got.ReplacesID != id || got.Summary != "update installed" {
+		t.Errorf("sent %+v", got)
+	}
+}
+
+func TestReplaceKeeping(t *testing.T) {
+	s := newFakeServer(t)
+	var rec logRecorder
+	original := notify.SetDefault(newTestNotifier(t, notify.WithStrictUrgency(), notify.WithLogger(rec.log)))
+	defer notify.SetDefault(original)
+
+	n := notify.New("test", "downloading", "0%", "", 0, notify.CriticalUrgency)
+	n.AddAction("cancel", "Cancel")
+	if _, err := n.SendR(); err != nil {
+		t.Fatal(err)
+	}
+	id := n.Id
+	if err := n.ReplaceKeeping("downloaded", "100%"); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if got.ReplacesID != id || got.Summary != "downloaded" || got.Body != "100%" ||
+		got.Hints["urgency"].Value() != byte(notify.CriticalUrgency) || len(got.Actions) != 2 {
+		t.Errorf("replaced with %+v", got)
+	}
+	if rec.len() != 0 {
+		t.Errorf("logged %d messages keeping the urgency", rec.len())
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The environment variables read by TransportFromEnv.
+const (
+	// EnvTransport lists the transports to try, like "dbus,portal,exec,log".
+	EnvTransport = "NOTIFY_TRANSPORT"
+	// EnvExecBin is the program run by the "exec" transport,
+	// DefaultExecBin if unset.
+	EnvExecBin = "NOTIFY_EXEC_BIN"
+	// EnvLogTarget is where the "log" transport writes: "stderr", the
+	// default, "stdout", or the path of a file to append to.
+	EnvLogTarget = "NOTIFY_LOG_TARGET"
+)
+
+var (
+	transportsMu sync.Mutex
+	// transports holds the constructors of the transports by name, see
+	// RegisterTransport.
+	transports = map[string]func() (Transport, error){
+		"dbus":   envBusTransport,
+		"portal": func() (Transport, error) { return &PortalTransport{}, nil },
+		"exec":   func() (Transport, error) { return &ExecTransport{Bin: os.Getenv(EnvExecBin)}, nil },
+		"log":    envLogTransport,
+	}
+)
+
+// RegisterTransport makes TransportFromEnv build the transport name with
+// newTransport, replacing any previous one with that name.
+func RegisterTransport(name string, newTransport func() (Transport, error)) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transports[name] = newTransport
+}
+
+// TransportFromEnv returns the transport described by the NOTIFY_TRANSPORT
+// environment variable, a comma-separated list of transport names: "dbus",
+// "portal" (PortalTransport), "exec" (ExecTransport, running
+// NOTIFY_EXEC_BIN), "log" (LogTransport, writing to NOTIFY_LOG_TARGET), or
+// those added by RegisterTransport. Several names give a FallbackTransport
+// trying them in order.
+//
+// It returns nil and no error if the variable is not set; an unknown name is
+// an error listing the valid ones. The result is meant for WithTransport: it
+// is bound to the Notifier it is given to. The default Notifier uses it when
+// the variable is set and valid.
+func TransportFromEnv() (Transport, error) {
+	spec, ok := os.LookupEnv(EnvTransport)
+	if !ok {
+		return nil, nil
+	}
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	var chain []Transport
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		newTransport, ok := transports[name]
+		if !ok {
+			return nil, fmt.Errorf("notify: unknown transport %q in %s, valid names are %s", name, EnvTransport, transportNames())
+		}
+		t, err := newTransport()
+		if err != nil {
+			return nil, fmt.Errorf("notify: transport %q: %w", name, err)
+		}
+		chain = append(chain, t)
+	}
+
+	switch len(chain) {
+	case 0:
+		return nil, fmt.Errorf("notify: no transport in %s, valid names are %s", EnvTransport, transportNames())
+	case 1:
+		return chain[0], nil
+	}
+	return NewFallbackTransport(chain...), nil
+}
+
+// transportNames returns the sorted names of the transports, comma-separated.
+// It must be called with transportsMu held.
+func transportNames() string {
+	names := make([]string, 0, len(transports))
+	for name := range transports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// envBusTransport returns the D-Bus transport, bound to a Notifier by
+// WithTransport.
+func envBusTransport() (Transport, error) {
+	if newBusTransport == nil {
+		return nil, ErrNoTransport
+	}
+	return busTransport{}, nil
+}
+
+// envLogTransport returns the log transport writing to NOTIFY_LOG_TARGET.
+func envLogTransport() (Transport, error) {
+	switch target := os.Getenv(EnvLogTarget); target {
+	case "", "stderr":
+		return NewLogTransport(os.Stderr), nil
+	case "stdout":
+		return NewLogTransport(os.Stdout), nil
+	default:
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		return NewLogTransport(f), nil
+	}
+}
+
+// envOptions returns the options of the default Notifier from the
+// environment. An invalid NOTIFY_TRANSPORT is logged to the first logger
+// set, as the default Notifier then sends over D-Bus.
+func envOptions() []Option {
+	t, err := TransportFromEnv()
+	if err != nil {
+		return []Option{func(nf *Notifier) error {
+			nf.logLater(LevelWarn, "ignoring "+EnvTransport+", sending over D-Bus", err)
+			return nil
+		}}
+	}
+	if t == nil {
+		return nil
+	}
+	return []Option{WithTransport(t)}
+}
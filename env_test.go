@@ -0,0 +1,228 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// failingTransport is a Transport failing every call.
+type failingTransport struct{ err error }
+
+func (t failingTransport) Notify(ctx context.Context, c notify.Call) (uint32, error) {
+	return 0, t.err
+}
+
+func (t failingTransport) CloseNotification(id uint32) error { return t.err }
+
+// registerStubs registers the transports "stub-down", failing, and
+// "stub-up", recording the calls in the returned transport.
+func registerStubs(t *testing.T) *recordingTransport {
+	up := &recordingTransport{}
+	notify.RegisterTransport("stub-down", func() (notify.Transport, error) {
+		return failingTransport{errors.New("down")}, nil
+	})
+	notify.RegisterTransport("stub-up", func() (notify.Transport, error) { return up, nil })
+	return up
+}
+
+func TestTransportFromEnv(t *testing.T) {
+	registerStubs(t)
+
+	t.Setenv(notify.EnvTransport, "")
+	os.Unsetenv(notify.EnvTransport)
+	if tr, err := notify.TransportFromEnv(); tr != nil || err != nil {
+		t.Errorf("unset: %v, %v", tr, err)
+	}
+
+	t.Setenv(notify.EnvTransport, "stub-up")
+	if tr, err := notify.TransportFromEnv(); err != nil {
+		t.Error(err)
+	} else if _, ok := tr.(*recordingTransport); !ok {
+		t.Errorf("one name gave %T, want the transport itself", tr)
+	}
+
+	t.Setenv(notify.EnvTransport, " stub-down , stub-up,,")
+	tr, err := notify.TransportFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, ok := tr.(*notify.FallbackTransport)
+	if !ok || len(chain.Transports) != 2 {
+		t.Fatalf("got %#v, want a chain of 2", tr)
+	}
+	if _, ok := chain.Transports[0].(failingTransport); !ok {
+		t.Errorf("first transport is %T", chain.Transports[0])
+	}
+
+	for _, spec := range []string{"dbus,dbsu", ""} {
+		t.Setenv(notify.EnvTransport, spec)
+		_, err := notify.TransportFromEnv()
+		if err == nil || !strings.Contains(err.Error(), "dbus, exec, log, portal") {
+			t.Errorf("%q: error %v does not list the valid names", spec, err)
+		}
+	}
+	t.Setenv(notify.EnvTransport, "dbsu")
+	if _, err := notify.TransportFromEnv(); err == nil || !strings.Contains(err.Error(), `"dbsu"`) {
+		t.Errorf("error %v does not name the typo", err)
+	}
+}
+
+func TestFallbackTransportOrder(t *testing.T) {
+	up := registerStubs(t)
+	t.Setenv(notify.EnvTransport, "stub-down,stub-up")
+	tr, err := notify.TransportFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nf, err := notify.NewNotifier(notify.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	n := notify.New("test", "fallback", "", "", time.Second, notify.NormalUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	n.Summary = "replaced"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if len(up.calls) != 2 || up.calls[1].ReplacesID != 1 {
+		t.Errorf("second transport got %+v", up.calls)
+	}
+	if err := nf.CloseNotification(n.Id); err != nil || len(up.closed) != 1 {
+		t.Errorf("close: %v, closed %v", err, up.closed)
+	}
+
+	t.Setenv(notify.EnvTransport, "stub-down,stub-down")
+	tr, _ = notify.TransportFromEnv()
+	if _, err := tr.Notify(context.Background(), notify.Call{Summary: "s"}); err == nil || !strings.Contains(err.Error(), "down") {
+		t.Errorf("all failing: %v", err)
+	}
+}
+
+// switchTransport is a Transport failing the calls while down, and passing
+// them to up otherwise.
+type switchTransport struct {
+	up   *recordingTransport
+	down bool
+}
+
+func (t *switchTransport) Notify(ctx context.Context, c notify.Call) (uint32, error) {
+	if t.down {
+		return 0, errors.New("down")
+	}
+	return t.up.Notify(ctx, c)
+}
+
+func (t *switchTransport) CloseNotification(id uint32) error { return t.up.CloseNotification(id) }
+
+func TestFallbackTransportIDs(t *testing.T) {
+	first := &switchTransport{up: &recordingTransport{}}
+	second := &recordingTransport{}
+	chain := notify.NewFallbackTransport(first, second)
+	ctx := context.Background()
+
+	// Both transports give the ID 1.
+	id1, err := chain.Notify(ctx, notify.Call{Summary: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.down = true
+	id2, err := chain.Notify(ctx, notify.Call{Summary: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id1 == id2 {
+		t.Fatalf("both notifications got the ID %d", id1)
+	}
+	if id, err := chain.Notify(ctx, notify.Call{Summary: "second again", ReplacesID: id2}); err != nil || id != id2 {
+		t.Errorf("replacing: %d, %v, want the ID %d kept", id, err, id2)
+	}
+	if got := second.calls[1].ReplacesID; got != 1 {
+		t.Errorf("the second transport replaced %d, want its own ID", got)
+	}
+	if err := chain.CloseNotification(id1); err != nil {
+		t.Fatal(err)
+	}
+	if len(first.up.closed) != 1 || len(second.closed) != 0 {
+		t.Errorf("closed %v and %v, want the first notification closed by the first transport", first.up.closed, second.closed)
+	}
+}
+
+func TestTransportFromEnvBuiltins(t *testing.T) {
+	s := newFakeServer(t)
+	log := filepath.Join(t.TempDir(), "notify.log")
+	t.Setenv(notify.EnvLogTarget, log)
+	t.Setenv(notify.EnvTransport, "dbus,log")
+	tr, err := notify.TransportFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nf := newTestNotifier(t, notify.WithTransport(tr))
+	if _, err := nf.Notify(notify.New("test", "over D-Bus", "", "", time.Second, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Summary; got != "over D-Bus" {
+		t.Errorf("daemon got %q", got)
+	}
+
+	s.conn.Close()
+	if _, err := nf.Notify(notify.New("test", "logged", "line 1\nline 2", "", time.Second, notify.CriticalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "[critical] test: logged: \"line 1\\nline 2\"\n"; got != want {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+}
+
+func TestExecTransportFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "args")
+	bin := filepath.Join(dir, "notify-send")
+	script := "#!/bin/sh\nfor a in \"$@\"; do echo \"$a\"; done > " + out + "\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	t.Setenv(notify.EnvExecBin, bin)
+	t.Setenv(notify.EnvTransport, "exec")
+	tr, err := notify.TransportFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nf, err := notify.NewNotifier(notify.WithTransport(tr), notify.WithAppName("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+	if _, err := nf.Notify(notify.New("", "-summary", "body", "", 2*time.Second, notify.LowUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--app-name=app\n--urgency=low\n--expire-time=2000\n--\n-summary\nbody\n"
+	if string(data) != want {
+		t.Errorf("args = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ErrorMapper returns the icon and urgency of the notifications about err,
+// or false if it does not know err.
+type ErrorMapper func(err error) (icon string, urgency NotificationUrgency, ok bool)
+
+var (
+	errorMappersMu sync.RWMutex
+	// errorMappers are consulted from the last registered one.
+	errorMappers = []ErrorMapper{netErrorMapper, contextErrorMapper}
+)
+
+// RegisterErrorMapper makes NotifyError consult m, before the mappers
+// registered earlier and the built-in ones for context and net errors.
+// Mappers should use errors.Is and errors.As, so that they recognize
+// wrapped errors.
+func RegisterErrorMapper(m ErrorMapper) {
+	errorMappersMu.Lock()
+	defer errorMappersMu.Unlock()
+	errorMappers = append(errorMappers, m)
+}
+
+// NotifyError sends a notification about err with the given summary via
+// notifier, or the default Notifier if it is nil, and does nothing if err
+// is nil. The body shows err with the %+v verb, so that errors carrying
+// details like stack traces show them, followed by the errors it wraps
+// whose text it does not already show. The icon and urgency come from the
+// registered mappers, and default to "dialog-error" and CriticalUrgency.
+func NotifyError(notifier *Notifier, summary string, err error) error {
+	if notifier == nil {
+		notifier = Default()
+	}
+	return notifier.NotifyError(summary, err)
+}
+
+// errorBody returns the body of the notifications about err: err with the
+// %+v verb, and a line for each error of its chain that it does not show.
+func errorBody(err error) string {
+	var b strings.Builder
+	var walk func(err error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+		if text := fmt.Sprintf("%+v", err); !strings.Contains(b.String(), text) {
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(text)
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, err := range u.Unwrap() {
+				walk(err)
+			}
+		}
+	}
+	walk(err)
+	return b.String()
+}
+
+// mapError returns the icon and urgency of the notifications about err.
+func mapError(err error) (icon string, urgency NotificationUrgency) {
+	errorMappersMu.RLock()
+	defer errorMappersMu.RUnlock()
+	for i := len(errorMappers) - 1; i >= 0; i-- {
+		if icon, urgency, ok := errorMappers[i](err); ok {
+			return icon, urgency
+		}
+	}
+	return "dialog-error", CriticalUrgency
+}
+
+func contextErrorMapper(err error) (string, NotificationUrgency, bool) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "appointment-missed", NormalUrgency, true
+	case errors.Is(err, context.Canceled):
+		return "process-stop", LowUrgency, true
+	}
+	return "", 0, false
+}
+
+func netErrorMapper(err error) (string, NotificationUrgency, bool) {
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return "network-error", NormalUrgency, true
+	}
+	return "", 0, false
+}
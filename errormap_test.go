@@ -0,0 +1,85 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+// quotaError is recognized by the mapper registered in TestNotifyError.
+type quotaError struct{}
+
+func (quotaError) Error() string { return "quota exceeded" }
+
+// stepError wraps an error without showing it.
+type stepError struct {
+	step string
+	err  error
+}
+
+func (e stepError) Error() string { return e.step + " failed" }
+func (e stepError) Unwrap() error { return e.err }
+
+func TestNotifyError(t *testing.T) {
+	notify.RegisterErrorMapper(func(err error) (string, notify.NotificationUrgency, bool) {
+		var q quotaError
+		if errors.As(err, &q) {
+			return "drive-harddisk", notify.LowUrgency, true
+		}
+		return "", 0, false
+	})
+
+	tests := []struct {
+		err     error
+		icon    string
+		urgency notify.NotificationUrgency
+	}{
+		{errors.New("boom"), "dialog-error", notify.CriticalUrgency},
+		{fmt.Errorf("sync: %w", context.DeadlineExceeded), "appointment-missed", notify.NormalUrgency},
+		{fmt.Errorf("sync: %w", context.Canceled), "process-stop", notify.LowUrgency},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, "network-error", notify.NormalUrgency},
+		{fmt.Errorf("upload: %w", quotaError{}), "drive-harddisk", notify.LowUrgency},
+	}
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	for _, tt := range tests {
+		if err := notify.NotifyError(nf, "Sync failed", tt.err); err != nil {
+			t.Fatal(err)
+		}
+		got := s.last(t)
+		if got.AppIcon != tt.icon || got.Hints["urgency"].Value() != byte(tt.urgency) {
+			t.Errorf("%v: sent icon %q, urgency %v, want %q, %v", tt.err, got.AppIcon, got.Hints["urgency"].Value(), tt.icon, tt.urgency)
+		}
+		if got.Summary != "Sync failed" || got.Body != tt.err.Error() {
+			t.Errorf("%v: sent %q, %q", tt.err, got.Summary, got.Body)
+		}
+	}
+}
+
+func TestNotifyErrorChain(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	if err := notify.NotifyError(nf, "Sync failed", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.received(); got != 0 {
+		t.Errorf("sent %d notifications about a nil error", got)
+	}
+
+	err := fmt.Errorf("sync: %w", stepError{"upload", fmt.Errorf("put: %w", quotaError{})})
+	if err := notify.NotifyError(nf, "Sync failed", err); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.last(t).Body, "sync: upload failed\nput: quota exceeded"; got != want {
+		t.Errorf("sent the body %q, want %q", got, want)
+	}
+}
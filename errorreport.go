@@ -0,0 +1,116 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// errorReportEvery is how often an error of the same kind as the previous
+// ones is reported again to the error handler.
+const errorReportEvery = 100
+
+// defaultErrorHandler is the error handler of the Notifiers without their
+// own, see SetErrorHandler.
+var defaultErrorHandler atomic.Pointer[func(n *Notification, err error)]
+
+// SetErrorHandler makes h the error handler of the Notifiers that have none
+// of their own, the default Notifier first, see Notifier.SetErrorHandler. A
+// nil h removes it.
+func SetErrorHandler(h func(n *Notification, err error)) {
+	if h == nil {
+		defaultErrorHandler.Store(nil)
+		return
+	}
+	defaultErrorHandler.Store(&h)
+}
+
+// SetErrorHandler makes nf call h with the notification and the error of
+// its failed sends, on top of returning the error, so that the errors of
+// one-line helpers like SendMsg and Notifyf, which are seldom checked, do
+// not go unnoticed. The background sends, like the ones of SendAsync or of
+// persistent notifications, are reported too. The views of nf share h. A nil
+// h falls back to the handler of the package-level SetErrorHandler.
+//
+// Errors do not flood h: after the first error of a kind, only every 100th
+// error of the same kind is reported, until an error of another kind or a
+// successful send. The kind of an error is its innermost wrapped error,
+// like ErrNoDaemon. Sends cancelled through their context are not
+// reported.
+//
+// h is called from the goroutine of the send, once n can be sent again, so
+// it must not block.
+func (nf *Notifier) SetErrorHandler(h func(n *Notification, err error)) {
+	r := nf.errorReports
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handler = h
+}
+
+// errorReports holds the error handler of a Notifier and its views, and the
+// errors reported lately.
+type errorReports struct {
+	mu      sync.Mutex
+	handler func(n *Notification, err error)
+	// kind is the kind of the last errors, and repeats how many there
+	// were in a row.
+	kind    string
+	repeats int
+}
+
+// reportError reports err, the result of a send of n, to the error handler,
+// or forgets the errors reported so far if err is nil.
+func (nf *Notifier) reportError(n *Notification, err error) {
+	r := nf.errorReports
+	if r == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	r.mu.Lock()
+	h := r.handler
+	if h == nil {
+		if p := defaultErrorHandler.Load(); p != nil {
+			h = *p
+		}
+	}
+	if err == nil || h == nil {
+		r.kind, r.repeats = "", 0
+		r.mu.Unlock()
+		return
+	}
+	if kind := errorKind(err); kind != r.kind {
+		r.kind, r.repeats = kind, 0
+	}
+	report := r.repeats%errorReportEvery == 0
+	r.repeats++
+	r.mu.Unlock()
+
+	if report {
+		h(n, err)
+	}
+}
+
+// errorKind returns the type and message of the innermost error wrapped by
+// err, following the first one of the errors wrapping several.
+func errorKind(err error) string {
+	for {
+		var next error
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			next = e.Unwrap()
+		case interface{ Unwrap() []error }:
+			if errs := e.Unwrap(); len(errs) > 0 {
+				next = errs[0]
+			}
+		}
+		if next == nil {
+			return fmt.Sprintf("%T: %v", err, err)
+		}
+		err = next
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+// errorRecorder records the calls of an error handler.
+type errorRecorder struct {
+	mu    sync.Mutex
+	calls []reportedError
+}
+
+type reportedError struct {
+	n   *notify.Notification
+	err error
+}
+
+func (r *errorRecorder) handle(n *notify.Notification, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, reportedError{n, err})
+}
+
+func (r *errorRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+// badHint returns a notification failing to send for its hint.
+func badHint() *notify.Notification {
+	n := notify.New("app", "bad hint", "", "", 0, notify.NormalUrgency)
+	n.SetHint("x-channel", make(chan int))
+	return n
+}
+
+// badCategory returns a notification failing to send for its category.
+func badCategory() *notify.Notification {
+	n := notify.New("app", "bad category", "", "", 0, notify.NormalUrgency)
+	n.SetHint("category", "not a category!")
+	return n
+}
+
+func TestErrorHandlerGetsSendErrors(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	var r errorRecorder
+	nf.SetErrorHandler(r.handle)
+
+	n := badHint()
+	_, err := nf.Notify(n)
+	if err == nil {
+		t.Fatal("Notify() succeeded")
+	}
+	if r.count() != 1 || r.calls[0].n != n || r.calls[0].err != err {
+		t.Fatalf("handler calls = %v, want %v for %p", r.calls, err, n)
+	}
+
+	// The views share the handler, and Patch reports too.
+	if _, err := nf.As("other").Patch(badCategory(), func(*notify.Notification) {}); err == nil {
+		t.Fatal("Patch() succeeded")
+	}
+	if r.count() != 2 {
+		t.Errorf("handler called %d times, want 2", r.count())
+	}
+
+	// The errors of the queued sends are reported as they are returned.
+	async := badHint()
+	if err := <-nf.SendAsync(async); err == nil {
+		t.Fatal("SendAsync() succeeded")
+	}
+	if r.count() != 3 || r.calls[2].n != async {
+		t.Errorf("handler calls = %v, want the queued send last", r.calls)
+	}
+}
+
+func TestErrorHandlerImplicitSends(t *testing.T) {
+	ft := &failingTransport{errors.New("down")}
+	nf, err := notify.NewNotifier(notify.WithTransport(ft))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+	defer notify.SetDefault(notify.SetDefault(nf))
+	var r errorRecorder
+	nf.SetErrorHandler(r.handle)
+
+	if _, err := notify.SendMsg("sync failed", ""); err == nil {
+		t.Fatal("SendMsg() succeeded")
+	}
+	// Another kind of error, which is not held back as a repeat.
+	ft.err = errors.New("still down")
+	if _, err := notify.Notifyf("sync failed", "%d files", 3); err == nil {
+		t.Fatal("Notifyf() succeeded")
+	}
+	if r.count() != 2 || r.calls[0].n.Summary != "sync failed" || r.calls[1].n.Body != "3 files" || r.calls[1].err != ft.err {
+		t.Errorf("handler calls = %v, want the errors of SendMsg and Notifyf", r.calls)
+	}
+}
+
+func TestErrorHandlerDedup(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	var r errorRecorder
+	nf.SetErrorHandler(r.handle)
+
+	send := func(n *notify.Notification, times int) {
+		t.Helper()
+		for i := 0; i < times; i++ {
+			nf.Notify(n)
+		}
+	}
+	tests := []struct {
+		name  string
+		send  func()
+		calls int
+	}{
+		{"first error", func() { send(badHint(), 1) }, 1},
+		{"repeats", func() { send(badHint(), 99) }, 1},
+		{"every 100th", func() { send(badHint(), 101) }, 3},
+		{"new kind", func() { send(badCategory(), 1) }, 4},
+		{"previous kind again", func() { send(badHint(), 2) }, 5},
+		{"after a success", func() {
+			send(notify.New("app", "fine", "", "", 0, notify.NormalUrgency), 1)
+			send(badHint(), 1)
+		}, 6},
+	}
+	for _, tt := range tests {
+		tt.send()
+		if got := r.count(); got != tt.calls {
+			t.Errorf("%s: handler called %d times, want %d", tt.name, got, tt.calls)
+		}
+	}
+}
+
+func TestDefaultErrorHandler(t *testing.T) {
+	newFakeServer(t)
+	var def, own errorRecorder
+	notify.SetErrorHandler(def.handle)
+	t.Cleanup(func() { notify.SetErrorHandler(nil) })
+
+	nf := newTestNotifier(t)
+	if _, err := nf.Notify(badHint()); err == nil {
+		t.Fatal("Notify() succeeded")
+	}
+	if def.count() != 1 {
+		t.Errorf("default handler called %d times, want 1", def.count())
+	}
+
+	// A handler of the Notifier replaces the default one.
+	nf.SetErrorHandler(own.handle)
+	if _, err := nf.Notify(badCategory()); !errors.Is(err, notify.ErrInvalidCategory) {
+		t.Fatalf("Notify() = %v", err)
+	}
+	if def.count() != 1 || own.count() != 1 {
+		t.Errorf("handlers called %d and %d times, want 1 and 1", def.count(), own.count())
+	}
+}
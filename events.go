@@ -0,0 +1,133 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"time"
+)
+
+// eventsBuffer is the capacity of the channel returned by Events.
+const eventsBuffer = 64
+
+// EventKind is the kind of an Event.
+type EventKind int
+
+const (
+	EventSent        EventKind = iota // EventSent means a notification was sent.
+	EventAction                       // EventAction means an action was invoked.
+	EventClosed                       // EventClosed means a notification was closed.
+	EventFailed                       // EventFailed means something failed in the background.
+	EventReplied                      // EventReplied means the user replied inline; the text is not included.
+	EventDropped                      // EventDropped means an asynchronous operation was dropped from the full queue, or a notification during quiet hours.
+	EventDeferred                     // EventDeferred means a notification was held back by Pause, quiet hours or WithSessionWait.
+	EventPaced                        // EventPaced means a send waits to keep the pace of the daemon.
+	EventReconnected                  // EventReconnected means the connection to the bus was lost and made again; signals may have been missed.
+	EventSlow                         // EventSlow means an asynchronous operation took longer than the threshold of WithQueueMetrics.
+)
+
+// String returns the name of the event kind.
+func (k EventKind) String() string {
+	switch k {
+	case EventSent:
+		return "sent"
+	case EventAction:
+		return "action"
+	case EventClosed:
+		return "closed"
+	case EventFailed:
+		return "failed"
+	case EventReplied:
+		return "replied"
+	case EventDropped:
+		return "dropped"
+	case EventDeferred:
+		return "deferred"
+	case EventPaced:
+		return "paced"
+	case EventReconnected:
+		return "reconnected"
+	case EventSlow:
+		return "slow"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event describes something that happened to the notifications of a
+// Notifier, see Events.
+type Event struct {
+	Kind EventKind
+	// Time is when the event happened.
+	Time time.Time
+	// ID is the ID of the notification concerned, if any.
+	ID uint32
+	// CorrelationID is the correlation ID of the notification concerned,
+	// see Notification.CorrelationID. For the signals of the daemon, it is
+	// only known if the notification has callbacks or nf keeps a history.
+	CorrelationID string
+	// Tag is the tag of the notification concerned, for EventDropped,
+	// EventDeferred, EventPaced and EventSlow.
+	Tag string
+	// Key is the key of the invoked action, for EventAction.
+	Key string
+	// ActivationToken is the token letting the application raise its
+	// window, for EventAction, if the daemon sent one. See
+	// Notification.ActivationToken.
+	ActivationToken string
+	// Reason is the reason of the close, for EventClosed.
+	Reason CloseReason
+	// DisplayedFor is how long the notification was shown, for EventClosed,
+	// as in Record.DisplayedFor. DisplayedKnown is false if the send was
+	// not recorded: only the notifications with callbacks, or sent by a
+	// Notifier keeping a history, are.
+	DisplayedFor   time.Duration
+	DisplayedKnown bool
+	// Err is what failed, for EventFailed.
+	Err error
+	// Delay is how long the send waits, for EventPaced, or how long the
+	// operation took, for EventSlow.
+	Delay time.Duration
+	// ReplacesID is the ID the notification was sent to replace, for
+	// EventSent, and ReusedID is true if the daemon kept it, see
+	// SendResult.ReusedID.
+	ReplacesID uint32
+	ReusedID   bool
+}
+
+// Events returns a channel receiving the events of nf. Calling Events again
+// returns the same channel, which is closed by Close.
+//
+// Events are only recorded once Events has been called. They are dropped if
+// the channel is full, so keep reading from it.
+func (nf *Notifier) Events() <-chan Event {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if nf.events == nil {
+		nf.events = make(chan Event, eventsBuffer)
+	}
+	return nf.events
+}
+
+// emit sends e to the events channel, if there is one.
+func (nf *Notifier) emit(e Event) {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if nf.events == nil {
+		return
+	}
+	e.Time = nf.clock.Now()
+	select {
+	case nf.events <- e:
+	default:
+	}
+}
+
+// closeEvents closes the events channel. It must be called with nf.mu held.
+func (nf *Notifier) closeEvents() {
+	if nf.events != nil {
+		close(nf.events)
+		nf.events = nil
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultExecBin is the program run by ExecTransport if Bin is empty.
+const DefaultExecBin = "notify-send"
+
+// ExecTransport delivers calls by running a program taking the arguments of
+// notify-send:
+//
+//	bin --app-name=APP --urgency=LEVEL [--expire-time=MS] [--icon=ICON] -- SUMMARY [BODY]
+//
+// It assigns its own IDs, and closing notifications does nothing. For a
+// Notifier, bin is run by its ExecRunner with its environment, see
+// WithExecRunner and WithExecEnv.
+type ExecTransport struct {
+	// Bin is the program to run, DefaultExecBin if empty.
+	Bin string
+
+	lastID atomic.Uint32
+}
+
+func (t *ExecTransport) Notify(ctx context.Context, c Call) (uint32, error) {
+	bin := t.Bin
+	if bin == "" {
+		bin = DefaultExecBin
+	}
+	e := execFrom(ctx)
+	var stderr bytes.Buffer
+	cmd := e.command(ctx, bin, execArgs(c)...)
+	cmd.Stderr = &stderr
+	if err := e.run(cmd); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return 0, fmt.Errorf("notify: %s: %w: %s", bin, err, msg)
+		}
+		return 0, fmt.Errorf("notify: %s: %w", bin, err)
+	}
+	if c.ReplacesID != 0 {
+		return c.ReplacesID, nil
+	}
+	return t.lastID.Add(1), nil
+}
+
+// CloseNotification does nothing: notify-send cannot close notifications.
+func (t *ExecTransport) CloseNotification(id uint32) error {
+	return nil
+}
+
+// execArgs returns the notify-send arguments for c.
+func execArgs(c Call) []string {
+	args := []string{"--app-name=" + c.AppName, "--urgency=" + c.Urgency.String()}
+	if c.ExpireTimeout >= 0 {
+		args = append(args, "--expire-time="+strconv.Itoa(int(c.ExpireTimeout)))
+	}
+	if c.AppIcon != "" {
+		args = append(args, "--icon="+c.AppIcon)
+	}
+	args = append(args, "--", c.Summary)
+	if c.Body != "" {
+		args = append(args, c.Body)
+	}
+	return args
+}
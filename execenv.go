@@ -0,0 +1,176 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// ExecRunner runs the processes spawned for a Notifier: by ExecTransport,
+// ExecSoundPlayer, and the actions of NotifyFile and Transfer opening files
+// and copying to the clipboard. Replace it with WithExecRunner, for example
+// to test these features without the programs they run. The commands it
+// gets already have the environment of WithExecEnv.
+type ExecRunner interface {
+	// LookPath looks for the program file, like exec.LookPath.
+	LookPath(file string) (string, error)
+	// Run runs cmd and waits for it to exit, like cmd.Run.
+	Run(cmd *exec.Cmd) error
+	// Start starts cmd without waiting for it, like cmd.Start.
+	Start(cmd *exec.Cmd) error
+}
+
+// osRunner is the default ExecRunner, running the commands with os/exec.
+type osRunner struct{}
+
+func (osRunner) LookPath(file string) (string, error) { return exec.LookPath(file) }
+func (osRunner) Run(cmd *exec.Cmd) error              { return cmd.Run() }
+func (osRunner) Start(cmd *exec.Cmd) error            { return cmd.Start() }
+
+// WithExecRunner makes the Notifier spawn its processes with r, see
+// ExecRunner.
+func WithExecRunner(r ExecRunner) Option {
+	return func(nf *Notifier) error {
+		if r == nil {
+			return errors.New("notify: nil exec runner")
+		}
+		nf.execRunner = r
+		return nil
+	}
+}
+
+// WithExecEnv adds env to the environment of the processes the Notifier
+// spawns, see ExecRunner, replacing the variables of the process with the
+// same names. Services of the systemd user instance, for example, often lack
+// DISPLAY, WAYLAND_DISPLAY or XDG_RUNTIME_DIR. The clipboard of NotifyFile
+// also looks for DISPLAY and WAYLAND_DISPLAY in env first.
+func WithExecEnv(env map[string]string) Option {
+	return func(nf *Notifier) error {
+		cp := make(map[string]string, len(env))
+		for k, v := range env {
+			if k == "" || strings.ContainsAny(k, "=\x00") || strings.ContainsRune(v, 0) {
+				return fmt.Errorf("notify: invalid environment variable %q", k)
+			}
+			cp[k] = v
+		}
+		nf.execEnv = cp
+		return nil
+	}
+}
+
+// execConfig is how a Notifier spawns processes.
+type execConfig struct {
+	runner ExecRunner
+	env    map[string]string
+}
+
+// execConfigKey is the context key of the execConfig of the Notifier
+// delivering a call, for the transports and sound players.
+type execConfigKey struct{}
+
+// execContext returns ctx with the execConfig of nf, if it has one.
+func (nf *Notifier) execContext(ctx context.Context) context.Context {
+	if nf.execRunner == nil && len(nf.execEnv) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, execConfigKey{}, nf.execConfig())
+}
+
+// execConfig returns the execConfig of nf.
+func (nf *Notifier) execConfig() execConfig {
+	return execConfig{nf.execRunner, nf.execEnv}
+}
+
+// execFrom returns the execConfig of ctx, or the default one.
+func execFrom(ctx context.Context) execConfig {
+	e, _ := ctx.Value(execConfigKey{}).(execConfig)
+	return e
+}
+
+func (e execConfig) runnerOrDefault() ExecRunner {
+	if e.runner == nil {
+		return osRunner{}
+	}
+	return e.runner
+}
+
+// command returns the command running name with args, with the environment
+// of e.
+func (e execConfig) command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if len(e.env) > 0 {
+		cmd.Env = mergeEnv(os.Environ(), e.env)
+	}
+	return cmd
+}
+
+func (e execConfig) lookPath(file string) (string, error) { return e.runnerOrDefault().LookPath(file) }
+func (e execConfig) run(cmd *exec.Cmd) error              { return e.runnerOrDefault().Run(cmd) }
+func (e execConfig) start(cmd *exec.Cmd) error            { return e.runnerOrDefault().Start(cmd) }
+
+// getenv returns the variable key of e, or else of the process.
+func (e execConfig) getenv(key string) string {
+	if v, ok := e.env[key]; ok {
+		return v
+	}
+	return os.Getenv(key)
+}
+
+// mergeEnv returns environ, as "KEY=value" strings, with the variables of
+// env, sorted, in place of those with the same names.
+func mergeEnv(environ []string, env map[string]string) []string {
+	out := make([]string, 0, len(environ)+len(env))
+	for _, kv := range environ {
+		k, _, _ := strings.Cut(kv, "=")
+		if _, ok := env[k]; !ok {
+			out = append(out, kv)
+		}
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out = append(out, k+"="+env[k])
+	}
+	return out
+}
+
+// xdgOpen opens path, a file or a folder, with the preferred application.
+func (nf *Notifier) xdgOpen(path string) error {
+	e := nf.execConfig()
+	cmd := e.command(context.Background(), "xdg-open", path)
+	if err := e.start(cmd); err != nil {
+		return err
+	}
+	// The process is reaped once it exits, so that it is not left a zombie.
+	if cmd.Process != nil {
+		go cmd.Wait()
+	}
+	return nil
+}
+
+// copyToClipboard copies text to the clipboard.
+func (nf *Notifier) copyToClipboard(text string) error {
+	e := nf.execConfig()
+	var cmd *exec.Cmd
+	switch {
+	case e.getenv("WAYLAND_DISPLAY") != "":
+		cmd = e.command(context.Background(), "wl-copy")
+	case e.getenv("DISPLAY") != "":
+		cmd = e.command(context.Background(), "xclip", "-selection", "clipboard")
+	default:
+		return errors.New("no clipboard available")
+	}
+	cmd.Stdin = strings.NewReader(text)
+	return e.run(cmd)
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+// recordingRunner is an ExecRunner recording the commands instead of running
+// them.
+type recordingRunner struct {
+	mu   sync.Mutex
+	cmds []ranCmd
+	ran  chan struct{}
+}
+
+type ranCmd struct {
+	args  []string
+	env   []string
+	stdin string
+}
+
+func newRecordingRunner() *recordingRunner {
+	return &recordingRunner{ran: make(chan struct{}, 16)}
+}
+
+func (r *recordingRunner) LookPath(file string) (string, error) { return file, nil }
+func (r *recordingRunner) Start(cmd *exec.Cmd) error            { return r.Run(cmd) }
+
+func (r *recordingRunner) Run(cmd *exec.Cmd) error {
+	c := ranCmd{args: cmd.Args, env: cmd.Env}
+	if cmd.Stdin != nil {
+		data, _ := io.ReadAll(cmd.Stdin)
+		c.stdin = string(data)
+	}
+	r.mu.Lock()
+	r.cmds = append(r.cmds, c)
+	r.mu.Unlock()
+	r.ran <- struct{}{}
+	return nil
+}
+
+// next waits for the next command run.
+func (r *recordingRunner) next(t *testing.T) ranCmd {
+	t.Helper()
+	select {
+	case <-r.ran:
+	case <-waitTimeout():
+		t.Fatal("no command was run")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.cmds[0]
+	r.cmds = r.cmds[1:]
+	return c
+}
+
+// lookupEnv returns the last value of key in env, as the process sees it.
+func lookupEnv(env []string, key string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		if k, v, _ := strings.Cut(env[i], "="); k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func TestExecEnvTransport(t *testing.T) {
+	t.Setenv("NOTIFY_TEST_VAR", "process")
+	r := newRecordingRunner()
+	nf, err := notify.NewNotifier(
+		notify.WithTransport(&notify.ExecTransport{Bin: "notify-send"}),
+		notify.WithAppName("app"),
+		notify.WithExecRunner(r),
+		notify.WithExecEnv(map[string]string{"NOTIFY_TEST_VAR": "exec", "DISPLAY": ":1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+	if _, err := nf.Notify(notify.New("", "Hello", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	c := r.next(t)
+	if c.args[0] != "notify-send" || c.args[len(c.args)-1] != "Hello" {
+		t.Errorf("ran %q", c.args)
+	}
+	for k, want := range map[string]string{"NOTIFY_TEST_VAR": "exec", "DISPLAY": ":1"} {
+		if got, _ := lookupEnv(c.env, k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+	if _, ok := lookupEnv(c.env, "PATH"); !ok {
+		t.Error("the environment of the process was not kept")
+	}
+}
+
+func TestExecEnvSoundAndFile(t *testing.T) {
+	s := newFakeServer(t)
+	r := newRecordingRunner()
+	nf := newTestNotifier(t,
+		notify.WithSoundFallback(notify.ExecSoundPlayer{}),
+		notify.WithExecRunner(r),
+		notify.WithExecEnv(map[string]string{"WAYLAND_DISPLAY": "wayland-1"}))
+
+	sendSound(t, nf, notify.NormalUrgency, notify.SoundNameHint("bell"))
+	c := r.next(t)
+	if strings.Join(c.args, " ") != "canberra-gtk-play -i bell" {
+		t.Errorf("ran %q", c.args)
+	}
+	if got, _ := lookupEnv(c.env, "WAYLAND_DISPLAY"); got != "wayland-1" {
+		t.Errorf("WAYLAND_DISPLAY = %q", got)
+	}
+
+	path := writePNG(t, "shot.png")
+	n, err := notify.NotifyFile(nf, "Saved", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.emitAction(n.Id, notify.DefaultAction)
+	if c := r.next(t); strings.Join(c.args, " ") != "xdg-open "+path {
+		t.Errorf("default action ran %q", c.args)
+	} else if got, _ := lookupEnv(c.env, "WAYLAND_DISPLAY"); got != "wayland-1" {
+		t.Errorf("WAYLAND_DISPLAY = %q", got)
+	}
+	s.emitAction(n.Id, notify.CopyPathAction)
+	if c := r.next(t); c.args[0] != "wl-copy" || c.stdin != path {
+		t.Errorf("copy action ran %q with %q", c.args, c.stdin)
+	}
+}
+
+// startingRunner is an ExecRunner starting the commands, and sending the PIDs
+// of those it starts without waiting for them.
+type startingRunner struct {
+	pids chan int
+}
+
+func (r startingRunner) LookPath(file string) (string, error) { return exec.LookPath(file) }
+func (r startingRunner) Run(cmd *exec.Cmd) error              { return cmd.Run() }
+
+func (r startingRunner) Start(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	r.pids <- cmd.Process.Pid
+	return nil
+}
+
+func TestOpenFileReaped(t *testing.T) {
+	if _, err := os.Stat("/proc/self"); err != nil {
+		t.Skip("no /proc to find the processes in")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "xdg-open"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	s := newFakeServer(t)
+	r := startingRunner{make(chan int, 1)}
+	nf := newTestNotifier(t, notify.WithExecRunner(r))
+
+	n, err := notify.NotifyFile(nf, "Saved", writePNG(t, "shot.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.emitAction(n.Id, notify.DefaultAction)
+	var pid int
+	select {
+	case pid = <-r.pids:
+	case <-waitTimeout():
+		t.Fatal("xdg-open was not started")
+	}
+	waitFor(t, "xdg-open to be reaped", func() bool {
+		_, err := os.Stat(fmt.Sprintf("/proc/%d", pid))
+		return os.IsNotExist(err)
+	})
+}
+
+func TestWithExecEnvInvalid(t *testing.T) {
+	for _, env := range []map[string]string{{"": "x"}, {"A=B": "x"}, {"A": "x\x00"}} {
+		if _, err := notify.NewNotifier(notify.WithExecEnv(env)); err == nil {
+			t.Errorf("WithExecEnv(%q) accepted", env)
+		}
+	}
+	if _, err := notify.NewNotifier(notify.WithExecRunner(nil)); err == nil {
+		t.Error("WithExecRunner(nil) accepted")
+	}
+}
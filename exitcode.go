@@ -0,0 +1,93 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// WaitResult is how the wait for a notification ended, for ExitCodeFor.
+type WaitResult struct {
+	// Key is the key of the action invoked, if Action is true.
+	Key    string
+	Action bool
+	// Reason is why the notification was closed without an action, if
+	// Closed is true.
+	Reason CloseReason
+	Closed bool
+	// Err is the error that ended the wait, or the send, if any. It wins
+	// over the fields above.
+	Err error
+}
+
+// The exit codes returned by ExitCodeFor, as the notify command exits with.
+const (
+	// ExitAction is for an action invoked, or a send without a wait.
+	ExitAction = 0
+	// ExitDismissed is for a notification dismissed by the user.
+	ExitDismissed = 1
+	// ExitExpired is for a notification that expired.
+	ExitExpired = 2
+	// ExitClosed is for a notification closed by CloseNotification, or for
+	// an undefined reason.
+	ExitClosed = 3
+	// ExitNoDaemon is for ErrNoDaemon.
+	ExitNoDaemon = 4
+	// ExitTimeout is for a deadline exceeded, ErrNoResponse and the D-Bus
+	// calls without a reply.
+	ExitTimeout = 5
+	// ExitInvalid is for invalid arguments, like a notification failing
+	// Validate.
+	ExitInvalid = 6
+	// ExitError is for the other errors.
+	ExitError = 7
+)
+
+// ExitCodeFor returns the exit code of a command for result, one of the
+// constants above, so that shell scripts can tell the outcomes apart:
+//
+//	if key=$(notify -A yes=Yes -A no=No "Deploy?"); then
+//		echo "chose $key"
+//	elif [ $? -eq 2 ]; then
+//		echo "no answer"
+//	fi
+func ExitCodeFor(result WaitResult) int {
+	if result.Err != nil {
+		return errorExitCode(result.Err)
+	}
+	if result.Action || !result.Closed {
+		return ExitAction
+	}
+	switch result.Reason {
+	case ReasonDismissed:
+		return ExitDismissed
+	case ReasonExpired:
+		return ExitExpired
+	}
+	return ExitClosed
+}
+
+// errorExitCode returns the exit code of the class of err.
+func errorExitCode(err error) int {
+	var (
+		validation *ValidationError
+		field      *FieldError
+		dbusErr    dbus.Error
+	)
+	switch {
+	case errors.Is(err, ErrNoDaemon):
+		return ExitNoDaemon
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, ErrNoResponse):
+		return ExitTimeout
+	case errors.As(err, &dbusErr) && (dbusErr.Name == "org.freedesktop.DBus.Error.NoReply" || dbusErr.Name == "org.freedesktop.DBus.Error.Timeout"):
+		return ExitTimeout
+	case errors.As(err, &validation), errors.As(err, &field):
+		return ExitInvalid
+	}
+	return ExitError
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/godbus/dbus/v5"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		result notify.WaitResult
+		want   int
+	}{
+		{"sent", notify.WaitResult{}, notify.ExitAction},
+		{"action", notify.WaitResult{Key: "yes", Action: true}, notify.ExitAction},
+		{"dismissed", notify.WaitResult{Reason: notify.ReasonDismissed, Closed: true}, notify.ExitDismissed},
+		{"expired", notify.WaitResult{Reason: notify.ReasonExpired, Closed: true}, notify.ExitExpired},
+		{"closed", notify.WaitResult{Reason: notify.ReasonClosed, Closed: true}, notify.ExitClosed},
+		{"undefined", notify.WaitResult{Reason: notify.ReasonUndefined, Closed: true}, notify.ExitClosed},
+		{"no daemon", notify.WaitResult{Err: fmt.Errorf("%w: gone", notify.ErrNoDaemon)}, notify.ExitNoDaemon},
+		{"deadline", notify.WaitResult{Err: context.DeadlineExceeded}, notify.ExitTimeout},
+		{"no response", notify.WaitResult{Err: notify.ErrNoResponse}, notify.ExitTimeout},
+		{"no reply", notify.WaitResult{Err: dbus.Error{Name: "org.freedesktop.DBus.Error.NoReply"}}, notify.ExitTimeout},
+		{"invalid", notify.WaitResult{Err: (&notify.Notification{}).Validate()}, notify.ExitInvalid},
+		{"field", notify.WaitResult{Err: fmt.Errorf("notify: %w", &notify.FieldError{Field: "image", Err: notify.ErrEmptyImage})}, notify.ExitInvalid},
+		{"other", notify.WaitResult{Err: errors.New("boom"), Action: true}, notify.ExitError},
+	}
+	for _, tt := range tests {
+		if got := notify.ExitCodeFor(tt.result); got != tt.want {
+			t.Errorf("%s: ExitCodeFor(%+v) = %d, want %d", tt.name, tt.result, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownGrace is the time HandleSignals gives Shutdown, unless set
+// with WithShutdownGrace.
+const defaultShutdownGrace = 5 * time.Second
+
+// WithCloseOnShutdown makes Shutdown close the notifications nf sent that
+// are still open, once the queued ones are sent, so that no resident or
+// never expiring popup outlives the program.
+func WithCloseOnShutdown() Option {
+	return func(nf *Notifier) error {
+		nf.closeOnShutdown = true
+		return nil
+	}
+}
+
+// WithShutdownGrace sets how long HandleSignals waits for Shutdown before
+// closing nf anyway, 5 seconds by default.
+func WithShutdownGrace(d time.Duration) Option {
+	return func(nf *Notifier) error {
+		if d <= 0 {
+			return fmt.Errorf("notify: non-positive shutdown grace %v", d)
+		}
+		nf.shutdownGrace = d
+		return nil
+	}
+}
+
+// HandleSignals shuts nf down when the process receives one of sigs, or
+// os.Interrupt or SIGTERM if none is given: it calls Shutdown with the grace
+// period of WithShutdownGrace, then stops catching the signal and raises it
+// again. Without other handlers, the process then ends as the signal would
+// have ended it; the handlers of the program, like the context of
+// signal.NotifyContext, receive the signal as usual, and again once nf is
+// shut down. HandleSignals never exits itself.
+//
+// HandleSignals stops when ctx is done or stop is called. stop waits for a
+// Shutdown in progress, so that a program can exit once it returns:
+//
+//	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+//	defer cancel()
+//	stop := nf.HandleSignals(ctx, syscall.SIGTERM)
+//	defer stop()
+func (nf *Notifier) HandleSignals(ctx context.Context, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer signal.Stop(ch)
+		select {
+		case sig := <-ch:
+			nf.shutdownOn(sig, ch)
+		case <-ctx.Done():
+			// The signal ending ctx, like that of signal.NotifyContext, is
+			// delivered to ch too. Stop waits for the signals being
+			// delivered, so that it is in ch if it is one of sigs.
+			signal.Stop(ch)
+			select {
+			case sig := <-ch:
+				nf.shutdownOn(sig, ch)
+			default:
+			}
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-finished
+	}
+}
+
+// shutdownOn shuts nf down for sig, caught on ch, and raises sig again.
+func (nf *Notifier) shutdownOn(sig os.Signal, ch chan os.Signal) {
+	nf.log(LevelInfo, fmt.Sprintf("received %v, shutting down", sig), nil)
+	grace := nf.shutdownGrace
+	if grace == 0 {
+		grace = defaultShutdownGrace
+	}
+	// The context of the program may be done by the same signal, so the
+	// grace period does not depend on it.
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := nf.Shutdown(ctx); err != nil {
+		nf.log(LevelWarn, "shutting down on "+sig.String()+" failed", err)
+	}
+
+	signal.Stop(ch)
+	p, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = p.Signal(sig)
+	}
+	if err != nil {
+		nf.log(LevelWarn, "raising "+sig.String()+" again failed", err)
+	}
+}
+
+// recordOpen records that id is open, replacing replaced, for
+// WithCloseOnShutdown.
+func (nf *Notifier) recordOpen(replaced, id uint32) {
+	if !nf.closeOnShutdown {
+		return
+	}
+	// The closes by the user are forgotten, so listen to the signals to
+	// find out.
+	nf.listenAlways()
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if replaced != 0 {
+		delete(nf.openIDs, replaced)
+	}
+	if nf.openIDs == nil {
+		nf.openIDs = make(map[uint32]struct{})
+	}
+	nf.openIDs[id] = struct{}{}
+}
+
+// closeOpen closes the notifications still open, for WithCloseOnShutdown.
+func (nf *Notifier) closeOpen() {
+	nf.mu.Lock()
+	ids := make([]uint32, 0, len(nf.openIDs))
+	for id := range nf.openIDs {
+		ids = append(ids, id)
+	}
+	nf.openIDs = nil
+	nf.mu.Unlock()
+
+	for _, id := range ids {
+		if err := nf.CloseNotification(id); err != nil {
+			nf.log(LevelWarn, fmt.Sprintf("closing notification %d on shutdown failed", id), err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build unix
+
+package notify_test
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// catch makes the test process catch sig, like a program with its own
+// handler would, so that the signals raised again do not end it.
+func catch(t *testing.T, sig os.Signal) <-chan os.Signal {
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, sig)
+	t.Cleanup(func() { signal.Stop(ch) })
+	return ch
+}
+
+func TestHandleSignalsShutsDown(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithCloseOnShutdown())
+	caught := catch(t, syscall.SIGUSR1)
+
+	sticky := notify.New("agent", "Working", "", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(sticky); err != nil {
+		t.Fatal(err)
+	}
+	queued := notify.New("agent", "Stopping", "", "", 0, notify.NormalUrgency)
+	errc := nf.SendAsync(queued)
+
+	stop := nf.HandleSignals(context.Background(), syscall.SIGUSR1)
+	defer stop()
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+
+	// The program gets the signal, then again once nf is shut down.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-caught:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the program received the signal %d times, want 2", i)
+		}
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("queued send: %v", err)
+	}
+	closed := make(map[uint32]bool)
+	for _, id := range s.closedIDs() {
+		closed[id] = true
+	}
+	if !closed[sticky.Id] || !closed[queued.Id] {
+		t.Errorf("closed %v before the end of the shutdown, want %d and %d", s.closedIDs(), sticky.Id, queued.Id)
+	}
+}
+
+func TestHandleSignalsNotifyContext(t *testing.T) {
+	newFakeServer(t)
+	caught := catch(t, syscall.SIGUSR1)
+	// The signal ends the context and is caught by HandleSignals at once:
+	// it must shut nf down whichever it sees first.
+	for i := 0; i < 10; i++ {
+		nf := newTestNotifier(t)
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGUSR1)
+		stop := nf.HandleSignals(ctx, syscall.SIGUSR1)
+		syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+		<-ctx.Done()
+		stop()
+		cancel()
+		// The signal is raised again once nf is shut down.
+		for j := 0; j < 2; j++ {
+			select {
+			case <-caught:
+			case <-waitTimeout():
+				t.Fatalf("round %d: the program received the signal %d times, want 2", i, j)
+			}
+		}
+	}
+}
+
+func TestHandleSignalsStop(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	caught := catch(t, syscall.SIGUSR2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := nf.HandleSignals(ctx, syscall.SIGUSR2)
+	cancel()
+	stop()
+	stop()
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	select {
+	case <-caught:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the program did not receive the signal")
+	}
+	if _, err := nf.Notify(notify.New("agent", "still running", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Errorf("Notify() after stop: %v", err)
+	}
+}
+
+func TestShutdownKeepsClosedNotifications(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithCloseOnShutdown())
+	events := nf.Events()
+	n := notify.New("agent", "Done", "", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	// A notification closed by the user is not closed again.
+	s.emitClosed(n.Id, uint32(notify.ReasonDismissed))
+	for e := range events {
+		if e.Kind == notify.EventClosed {
+			break
+		}
+	}
+	if err := nf.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range s.closedIDs() {
+		if id == n.Id {
+			t.Errorf("closed %d again on shutdown", id)
+		}
+	}
+}
@@ -0,0 +1,466 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/notifytest"
+	"github.com/godbus/dbus/v5"
+)
+
+// sentNotification records the arguments of one Notify call received by
+// the fake server.
+type sentNotification struct {
+	AppName       string
+	ReplacesID    uint32
+	AppIcon       string
+	Summary       string
+	Body          string
+	Actions       []string
+	Hints         map[string]dbus.Variant
+	ExpireTimeout int32
+
+	// ID is the ID the fake server returned for the call.
+	ID uint32
+	// Member, Signature, Sender and Flags are those of the method call
+	// message.
+	Member    string
+	Signature dbus.Signature
+	Sender    string
+	Flags     dbus.Flags
+}
+
+// fakeDaemon is exported on the private bus as the notification daemon.
+// Only the methods of the specification are exported, everything else lives
+// on fakeServer.
+type fakeDaemon struct {
+	s *fakeServer
+}
+
+// fakeServer is a notification daemon for the tests. It records all the
+// notifications it receives.
+type fakeServer struct {
+	conn *dbus.Conn
+
+	path dbus.ObjectPath
+
+	mu           sync.Mutex
+	sent         []sentNotification
+	open         map[uint32]bool
+	closed       []uint32
+	lastID       uint32
+	capabilities []string
+	info         [4]string
+	// gate, if not nil, holds the Notify calls until it is closed, and
+	// arrived counts the Notify calls received.
+	gate    chan struct{}
+	arrived int
+	// replyDelay delays the replies to the Notify calls.
+	replyDelay time.Duration
+	// maxHints, if not 0, is the number of hints above which Notify fails
+	// with LimitsExceeded.
+	maxHints int
+	// probes counts the GetCapabilities and GetServerInformation calls.
+	probes int
+	// freshIDs makes Notify assign a new ID when asked to replace a
+	// notification that is not shown, instead of reusing its ID.
+	freshIDs bool
+	// sizes holds the sizes of the Notify messages, as encoded by the
+	// server. They are kept out of sent, as the padding of the hints
+	// depends on their order.
+	sizes []int
+}
+
+// newFakeServer starts a fake notification daemon on the private bus. It is
+// stopped at the end of the test.
+func newFakeServer(t testing.TB) *fakeServer {
+	t.Helper()
+	return newFakeServerAt(t, "org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+}
+
+// newFakeServerAt is like newFakeServer, but the fake daemon owns name and
+// is exported at path.
+func newFakeServerAt(t testing.TB, name string, path dbus.ObjectPath) *fakeServer {
+	t.Helper()
+	requireBus(t)
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exportFakeServer(t, conn, name, path)
+}
+
+// newFakeServerOnBus is like newFakeServer, but on the bus at addr.
+func newFakeServerOnBus(t testing.TB, addr string) *fakeServer {
+	t.Helper()
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exportFakeServer(t, conn, "org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+}
+
+// exportFakeServer exports a fake daemon owning name at path on conn, which
+// it closes at the end of the test.
+func exportFakeServer(t testing.TB, conn *dbus.Conn, name string, path dbus.ObjectPath) *fakeServer {
+	t.Helper()
+	t.Cleanup(func() { conn.Close() })
+
+	s := &fakeServer{
+		conn:         conn,
+		path:         path,
+		open:         make(map[uint32]bool),
+		capabilities: []string{"body", "actions"},
+		info:         [4]string{"fake", "notify", "1.0", "1.2"},
+	}
+	if err := conn.Export(fakeDaemon{s}, path, "org.freedesktop.Notifications"); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := conn.RequestName(name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		t.Fatal(err)
+	} else if reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("fake server could not own %s", name)
+	}
+	return s
+}
+
+// newTestNotifier returns a Notifier with a private connection to the test
+// bus, closed at the end of the test.
+func newTestNotifier(t testing.TB, opts ...notify.Option) *notify.Notifier {
+	t.Helper()
+	nf, err := notify.NewNotifier(append([]notify.Option{notify.WithBusAddress(busAddress)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nf.Close() })
+	return nf
+}
+
+// setMaxHints makes Notify fail with LimitsExceeded for calls with more than
+// max hints.
+func (s *fakeServer) setMaxHints(max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxHints = max
+}
+
+// setCapabilities changes the capabilities advertised by the fake server.
+func (s *fakeServer) setCapabilities(caps ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capabilities = append([]string(nil), caps...)
+}
+
+// stall makes the fake server hold the Notify calls until release is
+// called, or until the end of the test.
+func (s *fakeServer) stall(t testing.TB) (release func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gate := make(chan struct{})
+	s.gate = gate
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.gate = nil
+			s.mu.Unlock()
+			close(gate)
+		})
+	}
+	t.Cleanup(release)
+	return release
+}
+
+// setReplyDelay delays the replies to the Notify calls by d.
+func (s *fakeServer) setReplyDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replyDelay = d
+}
+
+// received returns the number of Notify calls received, including the ones
+// being held by stall.
+func (s *fakeServer) received() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.arrived
+}
+
+// setServerName changes the daemon name reported by the fake server.
+func (s *fakeServer) setServerName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info[0] = name
+}
+
+// setServerInfo changes the daemon name, vendor and version reported by the
+// fake server.
+func (s *fakeServer) setServerInfo(name, vendor, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info[0], s.info[1], s.info[2] = name, vendor, version
+}
+
+// setSpecVersion changes the specification version reported by the fake
+// server.
+func (s *fakeServer) setSpecVersion(v string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info[3] = v
+}
+
+// daemonFixture is the server information of a daemon the fake server can
+// impersonate, see impersonate.
+type daemonFixture struct {
+	name, vendor, version, spec string
+}
+
+// daemonFixtures are the daemons of the spec revisions: the 1.2 era ones,
+// reporting the revision they implement, and the current GNOME and KDE
+// ones, which implement parts of 1.3 while still reporting 1.2.
+var daemonFixtures = map[string]daemonFixture{
+	"spec-1.1": {"fake", "notify", "1.0", "1.1"},
+	"spec-1.2": {"fake", "notify", "1.0", "1.2"},
+	"spec-1.3": {"fake", "notify", "1.0", "1.3"},
+	"gnome-45": {"gnome-shell", "GNOME", "45.5", "1.2"},
+	"gnome-46": {"gnome-shell", "GNOME", "46.2", "1.2"},
+	"plasma-5": {"Plasma", "KDE", "5.27.11", "1.2"},
+	"plasma-6": {"Plasma", "KDE", "6.0.5", "1.2"},
+}
+
+// impersonate makes the fake server report the server information of the
+// fixture called name.
+func (s *fakeServer) impersonate(t testing.TB, name string) {
+	t.Helper()
+	f, ok := daemonFixtures[name]
+	if !ok {
+		t.Fatalf("no daemon fixture %q", name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info = [4]string{f.name, f.vendor, f.version, f.spec}
+}
+
+// setFreshIDs sets whether Notify assigns a new ID when asked to replace a
+// notification that is not shown.
+func (s *fakeServer) setFreshIDs(fresh bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.freshIDs = fresh
+}
+
+// probeCount returns the number of GetCapabilities and GetServerInformation
+// calls received.
+func (s *fakeServer) probeCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.probes
+}
+
+// emitAction emits the ActionInvoked signal for id and key.
+func (s *fakeServer) emitAction(id uint32, key string) {
+	s.conn.Emit(s.path, "org.freedesktop.Notifications.ActionInvoked", id, key)
+}
+
+// emitActivationToken emits the ActivationToken signal for id and token.
+func (s *fakeServer) emitActivationToken(id uint32, token string) {
+	s.conn.Emit(s.path, "org.freedesktop.Notifications.ActivationToken", id, token)
+}
+
+// emitReplied emits the NotificationReplied signal for id and text.
+func (s *fakeServer) emitReplied(id uint32, text string) {
+	s.conn.Emit(s.path, "org.freedesktop.Notifications.NotificationReplied", id, text)
+}
+
+// emitClosed closes id and emits the NotificationClosed signal for it.
+func (s *fakeServer) emitClosed(id uint32, reason uint32) {
+	s.mu.Lock()
+	delete(s.open, id)
+	s.mu.Unlock()
+	s.conn.Emit(s.path, "org.freedesktop.Notifications.NotificationClosed", id, reason)
+}
+
+// closedIDs returns the IDs passed to CloseNotification so far.
+func (s *fakeServer) closedIDs() []uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uint32(nil), s.closed...)
+}
+
+// notifications returns a copy of all the notifications received so far.
+func (s *fakeServer) notifications() []sentNotification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sentNotification(nil), s.sent...)
+}
+
+// last returns the last notification received, failing the test if there
+// is none.
+func (s *fakeServer) last(t *testing.T) sentNotification {
+	t.Helper()
+	sent := s.notifications()
+	if len(sent) == 0 {
+		t.Fatal("fake server received no notification")
+	}
+	return sent[len(sent)-1]
+}
+
+// lastSize returns the size of the message of the last notification
+// received.
+func (s *fakeServer) lastSize(t *testing.T) int {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sizes) == 0 {
+		t.Fatal("fake server received no notification")
+	}
+	return s.sizes[len(s.sizes)-1]
+}
+
+func (d fakeDaemon) Notify(msg dbus.Message, appName string, replacesID uint32, appIcon, summary, body string, actions []string, hints map[string]dbus.Variant, expireTimeout int32) (uint32, *dbus.Error) {
+	// godbus loses the signature of structs in variants when storing the
+	// arguments, so take the hints straight from the message.
+	if raw, ok := msg.Body[6].(map[string]dbus.Variant); ok {
+		hints = raw
+	}
+
+	s := d.s
+	s.mu.Lock()
+	s.arrived++
+	gate, delay := s.gate, s.replyDelay
+	s.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	time.Sleep(delay)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxHints != 0 && len(hints) > s.maxHints {
+		return 0, &dbus.Error{Name: "org.freedesktop.Notifications.Error.LimitsExceeded", Body: []interface{}{"too many hints"}}
+	}
+	id := replacesID
+	if id == 0 || s.freshIDs && !s.open[id] {
+		s.lastID++
+		id = s.lastID
+	}
+	member, _ := msg.Headers[dbus.FieldMember].Value().(string)
+	sig, _ := msg.Headers[dbus.FieldSignature].Value().(dbus.Signature)
+	sender, _ := msg.Headers[dbus.FieldSender].Value().(string)
+	var encoded bytes.Buffer
+	if err := msg.EncodeTo(&encoded, binary.LittleEndian); err != nil {
+		return 0, dbus.MakeFailedError(err)
+	}
+	s.sent = append(s.sent, sentNotification{appName, replacesID, appIcon, summary, body, actions, hints, expireTimeout, id, member, sig, sender, msg.Flags})
+	s.sizes = append(s.sizes, encoded.Len())
+	s.open[id] = true
+	return id, nil
+}
+
+// tokenReplyDaemon replies to Notify with a token after the ID, like some
+// vendor daemons.
+type tokenReplyDaemon struct {
+	fakeDaemon
+}
+
+func (d tokenReplyDaemon) Notify(msg dbus.Message, appName string, replacesID uint32, appIcon, summary, body string, actions []string, hints map[string]dbus.Variant, expireTimeout int32) (uint32, string, *dbus.Error) {
+	id, err := d.fakeDaemon.Notify(msg, appName, replacesID, appIcon, summary, body, actions, hints, expireTimeout)
+	return id, "token", err
+}
+
+// int32ReplyDaemon replies to Notify with an int32 ID, against the
+// specification.
+type int32ReplyDaemon struct {
+	fakeDaemon
+}
+
+func (d int32ReplyDaemon) Notify(msg dbus.Message, appName string, replacesID uint32, appIcon, summary, body string, actions []string, hints map[string]dbus.Variant, expireTimeout int32) (int32, *dbus.Error) {
+	id, err := d.fakeDaemon.Notify(msg, appName, replacesID, appIcon, summary, body, actions, hints, expireTimeout)
+	return int32(id), err
+}
+
+// stringReplyDaemon replies to Notify without any ID.
+type stringReplyDaemon struct {
+	fakeDaemon
+}
+
+func (d stringReplyDaemon) Notify(msg dbus.Message, appName string, replacesID uint32, appIcon, summary, body string, actions []string, hints map[string]dbus.Variant, expireTimeout int32) (string, *dbus.Error) {
+	_, err := d.fakeDaemon.Notify(msg, appName, replacesID, appIcon, summary, body, actions, hints, expireTimeout)
+	return "shown", err
+}
+
+// setReplyShape makes s reply to Notify as daemon does, one of the daemons
+// above wrapping fakeDaemon.
+func (s *fakeServer) setReplyShape(t testing.TB, daemon interface{}) {
+	t.Helper()
+	if err := s.conn.Export(daemon, s.path, "org.freedesktop.Notifications"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// CloseNotification fails for notifications that are not shown, like some
+// real daemons do.
+func (d fakeDaemon) CloseNotification(id uint32) *dbus.Error {
+	s := d.s
+	s.mu.Lock()
+	if !s.open[id] {
+		s.mu.Unlock()
+		return dbus.MakeFailedError(fmt.Errorf("notification %d is not shown", id))
+	}
+	s.closed = append(s.closed, id)
+	s.mu.Unlock()
+
+	s.emitClosed(id, uint32(notify.ReasonClosed))
+	return nil
+}
+
+func (d fakeDaemon) GetCapabilities() ([]string, *dbus.Error) {
+	s := d.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.probes++
+	return append([]string(nil), s.capabilities...), nil
+}
+
+func (d fakeDaemon) GetServerInformation() (string, string, string, string, *dbus.Error) {
+	s := d.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.probes++
+	return s.info[0], s.info[1], s.info[2], s.info[3], nil
+}
+
+// waitFor waits until cond returns true, failing the test after a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitTimeout returns a channel receiving when a test waited for too long.
+func waitTimeout() <-chan time.Time {
+	return time.After(time.Second)
+}
+
+// newFakeClock returns a clock for the tests, whose time only moves with
+// Advance.
+func newFakeClock() *notifytest.Clock {
+	return notifytest.NewClock(time.Time{})
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// FallbackTransport is a Transport delivering each call to the first of its
+// transports that accepts it, in order: for example D-Bus, and a log when no
+// daemon is running. Notifications are replaced and closed by the transport
+// that delivered them. As the IDs of the transports may collide, the chain
+// gives the notifications IDs of its own, and remembers the transports of
+// the last 4096, like UrgencyRouter.
+//
+// The chain has string IDs, see StringIDTransport, if one of its
+// transports has. The notifications sent by their string IDs are then
+// delivered to the others as notifications of their own.
+//
+// The D-Bus transport of a chain does not deliver signals: the callbacks of
+// notifications need the D-Bus transport alone.
+type FallbackTransport struct {
+	Transports []Transport
+
+	// table holds the transport that delivered each notification, and
+	// named those of the notifications with string IDs, by their hash.
+	table, named routeTable
+}
+
+// NewFallbackTransport returns a FallbackTransport trying transports in
+// order.
+func NewFallbackTransport(transports ...Transport) *FallbackTransport {
+	return &FallbackTransport{Transports: transports}
+}
+
+func (t *FallbackTransport) Notify(ctx context.Context, c Call) (uint32, error) {
+	owner, _ := t.table.lookup(c.ReplacesID)
+
+	var errs []error
+	for _, tr := range t.Transports {
+		call := c
+		// The ID means nothing to the other transports.
+		call.ReplacesID = 0
+		if tr == owner.t {
+			call.ReplacesID = owner.id
+		}
+		id, err := tr.Notify(ctx, call)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return t.table.add(c.ReplacesID, route{tr, id}), nil
+	}
+	return 0, allFailed(errs)
+}
+
+// allFailed returns the error of a chain whose transports failed with errs.
+func allFailed(errs []error) error {
+	if len(errs) == 0 {
+		return ErrNoTransport
+	}
+	return fmt.Errorf("notify: all transports failed: %w", errors.Join(errs...))
+}
+
+func (t *FallbackTransport) CloseNotification(id uint32) error {
+	owner, ok := t.table.remove(id)
+	if !ok {
+		return fmt.Errorf("notify: notification %d was not delivered by this transport", id)
+	}
+	return owner.t.CloseNotification(owner.id)
+}
+
+// NotifyID delivers c as the notification id to the first transport that
+// accepts it: by its string ID to those having string IDs, and as a
+// notification of their own to the others.
+func (t *FallbackTransport) NotifyID(ctx context.Context, id string, c Call) error {
+	key := stringIDHash(id)
+	owner, _ := t.named.lookup(key)
+
+	var errs []error
+	for _, tr := range t.Transports {
+		var (
+			inner uint32
+			err   error
+		)
+		if st, ok := stringIDOf(tr); ok {
+			err = st.NotifyID(ctx, id, c)
+		} else {
+			call := c
+			call.ReplacesID = 0
+			if tr == owner.t {
+				call.ReplacesID = owner.id
+			}
+			inner, err = tr.Notify(ctx, call)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		t.named.set(key, route{tr, inner})
+		return nil
+	}
+	return allFailed(errs)
+}
+
+// CloseID closes the notification id with the transport that delivered it.
+func (t *FallbackTransport) CloseID(id string) error {
+	owner, ok := t.named.remove(stringIDHash(id))
+	if !ok {
+		return fmt.Errorf("notify: notification %q was not delivered by this transport", id)
+	}
+	if st, ok := stringIDOf(owner.t); ok {
+		return st.CloseID(id)
+	}
+	return owner.t.CloseNotification(owner.id)
+}
+
+// hasStringIDs returns true if a transport of t has string IDs.
+func (t *FallbackTransport) hasStringIDs() bool {
+	for _, tr := range t.Transports {
+		if _, ok := stringIDOf(tr); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// bindBus returns t with the unbound D-Bus transports of TransportFromEnv
+// bound to nf.
+func (nf *Notifier) bindBus(t Transport) Transport {
+	switch t := t.(type) {
+	case busTransport:
+		if t.nf == nil {
+			return busTransport{nf}
+		}
+	case *FallbackTransport:
+		bound := make([]Transport, len(t.Transports))
+		changed := false
+		for i, tr := range t.Transports {
+			bound[i] = nf.bindBus(tr)
+			changed = changed || bound[i] != tr
+		}
+		if changed {
+			return NewFallbackTransport(bound...)
+		}
+	}
+	return t
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import "sort"
+
+// Features are the capabilities of a notification daemon, as returned by
+// Notifier.Features.
+type Features struct {
+	Body           bool // "body": the body is shown.
+	BodyMarkup     bool // "body-markup": the body may contain markup.
+	BodyHyperlinks bool // "body-hyperlinks": the body may contain links.
+	BodyImages     bool // "body-images": the body may contain images.
+	Actions        bool // "actions": actions are shown.
+	ActionIcons    bool // "action-icons": actions may be shown as icons.
+	IconStatic     bool // "icon-static": images are shown as a single frame.
+	IconMulti      bool // "icon-multi": images may be animated.
+	Sound          bool // "sound": sounds are played.
+	Persistence    bool // "persistence": notifications stay until dismissed.
+
+	// Other holds the capabilities that are not part of the specification,
+	// such as "inline-reply" or vendor extensions starting with "x-".
+	Other map[string]bool
+}
+
+// featureNames are the capabilities of the specification, in the order of
+// the specification.
+var featureNames = []string{
+	"action-icons", "actions", "body", "body-hyperlinks", "body-images",
+	"body-markup", "icon-multi", "icon-static", "persistence", "sound",
+}
+
+// field returns the field of f for the capability name of the
+// specification, or nil.
+func (f *Features) field(name string) *bool {
+	switch name {
+	case "action-icons":
+		return &f.ActionIcons
+	case "actions":
+		return &f.Actions
+	case "body":
+		return &f.Body
+	case "body-hyperlinks":
+		return &f.BodyHyperlinks
+	case "body-images":
+		return &f.BodyImages
+	case "body-markup":
+		return &f.BodyMarkup
+	case "icon-multi":
+		return &f.IconMulti
+	case "icon-static":
+		return &f.IconStatic
+	case "persistence":
+		return &f.Persistence
+	case "sound":
+		return &f.Sound
+	}
+	return nil
+}
+
+// parseFeatures returns the features advertised by caps.
+func parseFeatures(caps []string) Features {
+	var f Features
+	for _, c := range caps {
+		if p := f.field(c); p != nil {
+			*p = true
+			continue
+		}
+		if f.Other == nil {
+			f.Other = make(map[string]bool)
+		}
+		f.Other[c] = true
+	}
+	return f
+}
+
+// Has returns true if capability is one of f, whether it is part of the
+// specification or not.
+func (f Features) Has(capability string) bool {
+	if p := f.field(capability); p != nil {
+		return *p
+	}
+	return f.Other[capability]
+}
+
+// Capabilities returns the capabilities of f, as advertised by daemons: those
+// of the specification, then the others in alphabetical order.
+func (f Features) Capabilities() []string {
+	var caps []string
+	for _, name := range featureNames {
+		if *f.field(name) {
+			caps = append(caps, name)
+		}
+	}
+	other := make([]string, 0, len(f.Other))
+	for c, ok := range f.Other {
+		if ok {
+			other = append(other, c)
+		}
+	}
+	sort.Strings(other)
+	return append(caps, other...)
+}
+
+// Features returns the capabilities of the notification daemon, see
+// Capabilities.
+func (nf *Notifier) Features() (Features, error) {
+	f, err := nf.features()
+	if err != nil {
+		return Features{}, err
+	}
+	cp := *f
+	if f.Other != nil {
+		cp.Other = make(map[string]bool, len(f.Other))
+		for c, ok := range f.Other {
+			cp.Other[c] = ok
+		}
+	}
+	return cp, nil
+}
+
+// features returns the cached features of the daemon, which must not be
+// modified.
+func (nf *Notifier) features() (*Features, error) {
+	nf.mu.Lock()
+	nf.expireSnapshot()
+	f := nf.feats
+	nf.mu.Unlock()
+	if f != nil {
+		return f, nil
+	}
+
+	caps, err := nf.Capabilities()
+	if err != nil {
+		return nil, err
+	}
+	parsed := parseFeatures(caps)
+	nf.mu.Lock()
+	nf.feats = &parsed
+	nf.mu.Unlock()
+	return &parsed, nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestFeatures(t *testing.T) {
+	s := newFakeServer(t)
+	caps := []string{"x-vendor-thing", "body", "body-markup", "actions", "inline-reply", "persistence", "icon-static"}
+	s.setCapabilities(caps...)
+	nf := newTestNotifier(t)
+
+	f, err := nf.Features()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := notify.Features{
+		Body:        true,
+		BodyMarkup:  true,
+		Actions:     true,
+		IconStatic:  true,
+		Persistence: true,
+		Other:       map[string]bool{"x-vendor-thing": true, "inline-reply": true},
+	}
+	if f.Body != want.Body || f.BodyMarkup != want.BodyMarkup || f.BodyHyperlinks || f.BodyImages ||
+		f.Actions != want.Actions || f.ActionIcons || f.IconStatic != want.IconStatic || f.IconMulti ||
+		f.Sound || f.Persistence != want.Persistence || len(f.Other) != len(want.Other) {
+		t.Errorf("Features() = %+v, want %+v", f, want)
+	}
+	for _, c := range caps {
+		if !f.Has(c) {
+			t.Errorf("Has(%q) = false", c)
+		}
+	}
+	if f.Has("sound") || f.Has("x-other") {
+		t.Error("Has reports a capability that is not advertised")
+	}
+
+	got := f.Capabilities()
+	sort.Strings(got)
+	sort.Strings(caps)
+	if len(got) != len(caps) {
+		t.Fatalf("round trip gave %q, want %q", got, caps)
+	}
+	for i := range got {
+		if got[i] != caps[i] {
+			t.Fatalf("round trip gave %q, want %q", got, caps)
+		}
+	}
+
+	f.Other["x-mutated"] = true
+	if again, _ := nf.Features(); again.Has("x-mutated") {
+		t.Error("modifying the result of Features changed the cached features")
+	}
+}
+
+func TestCachesDroppedOnDaemonChange(t *testing.T) {
+	img := writePNG(t, "smile.png")
+	n := &notify.Notification{Summary: "chat", Body: notify.BodyImage(img, ":-)")}
+
+	s := newFakeServer(t)
+	s.setCapabilities("body", "body-markup")
+	nf := newTestNotifier(t)
+	c, err := n.DryRun(nf)
+	if err != nil {
+		t.Fatal(err)
+	} else if c.Body != ":-)" {
+		t.Fatalf("body = %q, want the image stripped", c.Body)
+	}
+
+	s.conn.Close()
+	restarted := newFakeServer(t)
+	restarted.setCapabilities("body", "body-markup", "body-images")
+	waitFor(t, "the capabilities to be asked again", func() bool {
+		c, err := n.DryRun(nf)
+		return err == nil && c.Body == n.Body
+	})
+
+	nf.InvalidateCaches()
+	restarted.setCapabilities("body")
+	if f, err := nf.Features(); err != nil || f.BodyMarkup {
+		t.Errorf("Features() = %+v, %v after InvalidateCaches", f, err)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// PolicyAction decides what happens to a field breaking its FieldPolicy.
+type PolicyAction int
+
+const (
+	// PolicyError fails Validate and the send with a FieldError.
+	PolicyError PolicyAction = iota
+	// PolicyTruncate cuts the field: before its first invalid byte, or
+	// after MaxRunes runes.
+	PolicyTruncate
+	// PolicySanitize replaces the invalid bytes of the field by U+FFFD, or
+	// cuts it to MaxRunes runes ending with "…", so that the loss shows.
+	PolicySanitize
+)
+
+func (a PolicyAction) String() string {
+	switch a {
+	case PolicyError:
+		return "error"
+	case PolicyTruncate:
+		return "truncate"
+	case PolicySanitize:
+		return "sanitize"
+	}
+	return fmt.Sprintf("PolicyAction(%d)", int(a))
+}
+
+// FieldPolicy is the rules of one field of the notifications, see
+// WithFieldPolicies. Its zero value only rejects invalid UTF-8, as Validate
+// does.
+type FieldPolicy struct {
+	// MaxRunes is the maximum length of the field in runes, or 0 for no
+	// limit.
+	MaxRunes int
+	// OnInvalidUTF8 is the action for a field that is not valid UTF-8.
+	OnInvalidUTF8 PolicyAction
+	// OnTooLong is the action for a field longer than MaxRunes.
+	OnTooLong PolicyAction
+	// Pattern, if not nil, must match the field, once the actions above
+	// are applied. A mismatch is always an error.
+	Pattern *regexp.Regexp
+}
+
+// policyFields are the fields a FieldPolicy can be given for.
+var policyFields = []string{"name", "summary", "body"}
+
+// WithFieldPolicies applies policies, keyed by "name", "summary" or "body",
+// to the notifications sent, in place of the single rule of Validate for
+// these fields. The policy of "name" applies to the app name sent, which is
+// the one of the Notifier for a notification without a Name. The summary
+// and body are checked as the notification has them, or once translated for
+// a Localized one, before any redaction or sanitizing.
+//
+// A notification breaking a policy with PolicyError fails to send with a
+// *ValidationError listing all the problems, as Notifier.Validate reports
+// them. Views of the Notifier, and so its scopes, share the policies; they
+// apply alike to the copies sent by Fire and to the decoded presets.
+//
+//	notify.WithFieldPolicies(map[string]notify.FieldPolicy{
+//		"summary": {MaxRunes: 120},
+//		"body":    {OnInvalidUTF8: notify.PolicySanitize},
+//		"name":    {Pattern: regexp.MustCompile(`^[a-z][a-z0-9-]*$`)},
+//	})
+func WithFieldPolicies(policies map[string]FieldPolicy) Option {
+	return func(nf *Notifier) error {
+		if policies == nil {
+			return fmt.Errorf("notify: nil field policies")
+		}
+		cp := make(map[string]FieldPolicy, len(policies))
+		for field, p := range policies {
+			known := false
+			for _, f := range policyFields {
+				known = known || f == field
+			}
+			if !known {
+				return fmt.Errorf("notify: field policy for unknown field %q", field)
+			}
+			if p.MaxRunes < 0 {
+				return fmt.Errorf("notify: negative MaxRunes %d for %s", p.MaxRunes, field)
+			}
+			for _, a := range []PolicyAction{p.OnInvalidUTF8, p.OnTooLong} {
+				if a < PolicyError || a > PolicySanitize {
+					return fmt.Errorf("notify: invalid policy action %d for %s", a, field)
+				}
+			}
+			if p.OnTooLong == PolicySanitize && p.MaxRunes == 1 {
+				return fmt.Errorf("notify: MaxRunes 1 leaves no room for an ellipsis for %s", field)
+			}
+			cp[field] = p
+		}
+		nf.fieldPolicies = cp
+		return nil
+	}
+}
+
+// Validate is like Notification.Validate, with the size limits and field
+// policies of nf: the fields breaking a policy with PolicyError are
+// reported, with ErrTooLong or ErrPatternMismatch, and the invalid UTF-8 a
+// policy truncates or sanitizes is not.
+func (nf *Notifier) Validate(n *Notification) error {
+	err := n.validate(nf.sizeLimits)
+	if len(nf.fieldPolicies) == 0 {
+		return err
+	}
+	var errs []error
+	if v, ok := err.(*ValidationError); ok {
+		for _, e := range v.Errs {
+			// The policies report the invalid UTF-8 of their fields.
+			if fe, ok := e.(*FieldError); ok && fe.Err == ErrInvalidUTF8 {
+				if _, ok := nf.fieldPolicies[fe.Field]; ok {
+					continue
+				}
+			}
+			errs = append(errs, e)
+		}
+	}
+	summary, body := nf.policySummaryBody(n)
+	_, _, _, perrs := nf.applyFieldPolicies(nf.appNameFor(n.Name), summary, body)
+	errs = append(errs, perrs...)
+	if len(errs) > 0 {
+		return &ValidationError{errs}
+	}
+	return nil
+}
+
+// policySummaryBody returns the summary and body of n the policies apply
+// to: the translated ones for a Localized notification, redacted, so that
+// truncating does not leave part of a secret the Redactor would not match.
+func (nf *Notifier) policySummaryBody(n *Notification) (summary, body string) {
+	summary, body = n.Summary, n.Body
+	if n.Localized {
+		summary = nf.localize(summary)
+		if body != "" {
+			body = nf.localize(body)
+		}
+	}
+	return nf.redact(summary, body)
+}
+
+// applyFieldPolicies applies the policies of nf to the fields, and returns
+// them with the FieldErrors of the policies with PolicyError.
+func (nf *Notifier) applyFieldPolicies(name, summary, body string) (_, _, _ string, errs []error) {
+	if len(nf.fieldPolicies) == 0 {
+		return name, summary, body, nil
+	}
+	name, errs = nf.applyFieldPolicy("name", name, errs)
+	summary, errs = nf.applyFieldPolicy("summary", summary, errs)
+	body, errs = nf.applyFieldPolicy("body", body, errs)
+	return name, summary, body, errs
+}
+
+// applyFieldPolicy applies the policy of nf for field to s, if any, and
+// appends its FieldErrors to errs.
+func (nf *Notifier) applyFieldPolicy(field, s string, errs []error) (string, []error) {
+	p, ok := nf.fieldPolicies[field]
+	if !ok {
+		return s, errs
+	}
+	s, ferrs := p.apply(field, s)
+	return s, append(errs, ferrs...)
+}
+
+// apply returns s with the actions of p applied, and the FieldErrors of
+// field for the rules s breaks with PolicyError.
+func (p FieldPolicy) apply(field, s string) (string, []error) {
+	var errs []error
+	if !utf8.ValidString(s) {
+		switch p.OnInvalidUTF8 {
+		case PolicyTruncate:
+			s = s[:firstInvalid(s)]
+		case PolicySanitize:
+			s = strings.ToValidUTF8(s, "\uFFFD")
+		default:
+			errs = append(errs, &FieldError{field, ErrInvalidUTF8})
+		}
+	}
+	if n := utf8.RuneCountInString(s); p.MaxRunes > 0 && n > p.MaxRunes {
+		switch p.OnTooLong {
+		case PolicyTruncate:
+			s = truncateRunes(s, p.MaxRunes)
+		case PolicySanitize:
+			s = truncateRunes(s, p.MaxRunes-1) + "…"
+		default:
+			errs = append(errs, &FieldError{field, fmt.Errorf("%w: %d runes, at most %d", ErrTooLong, n, p.MaxRunes)})
+		}
+	}
+	if p.Pattern != nil && !p.Pattern.MatchString(s) {
+		errs = append(errs, &FieldError{field, fmt.Errorf("%w %s", ErrPatternMismatch, p.Pattern)})
+	}
+	return s, errs
+}
+
+// firstInvalid returns the index of the first invalid byte of s, or len(s).
+func firstInvalid(s string) int {
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// truncateRunes returns the first n runes of s.
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
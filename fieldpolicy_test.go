@@ -0,0 +1,147 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestFieldPolicyActions(t *testing.T) {
+	s := newFakeServer(t)
+	tests := []struct {
+		name   string
+		policy notify.FieldPolicy
+		value  string
+		want   string
+		err    error
+	}{
+		{"invalid error", notify.FieldPolicy{}, "ab\xffcd", "", notify.ErrInvalidUTF8},
+		{"invalid truncate", notify.FieldPolicy{OnInvalidUTF8: notify.PolicyTruncate}, "ab\xffcd", "ab", nil},
+		{"invalid sanitize", notify.FieldPolicy{OnInvalidUTF8: notify.PolicySanitize}, "ab\xffcd", "ab\uFFFDcd", nil},
+		{"long error", notify.FieldPolicy{MaxRunes: 4}, "ééééé", "", notify.ErrTooLong},
+		{"long truncate", notify.FieldPolicy{MaxRunes: 4, OnTooLong: notify.PolicyTruncate}, "ééééé", "éééé", nil},
+		{"long sanitize", notify.FieldPolicy{MaxRunes: 4, OnTooLong: notify.PolicySanitize}, "ééééé", "ééé…", nil},
+		{"short", notify.FieldPolicy{MaxRunes: 4}, "éééé", "éééé", nil},
+	}
+	fields := []struct {
+		name string
+		set  func(n *notify.Notification, v string)
+		sent func(sentNotification) string
+	}{
+		{"name", func(n *notify.Notification, v string) { n.Name = v }, func(s sentNotification) string { return s.AppName }},
+		{"summary", func(n *notify.Notification, v string) { n.Summary = v }, func(s sentNotification) string { return s.Summary }},
+		{"body", func(n *notify.Notification, v string) { n.Body = v }, func(s sentNotification) string { return s.Body }},
+	}
+	for _, f := range fields {
+		for _, tt := range tests {
+			t.Run(f.name+"/"+tt.name, func(t *testing.T) {
+				nf := newTestNotifier(t, notify.WithFieldPolicies(map[string]notify.FieldPolicy{f.name: tt.policy}))
+				n := notify.New("app", "summary", "body", "", 0, notify.NormalUrgency)
+				f.set(n, tt.value)
+
+				verr := nf.Validate(n)
+				_, err := nf.Notify(n)
+				if tt.err != nil {
+					var fe *notify.FieldError
+					if !errors.Is(err, tt.err) || !errors.As(err, &fe) || fe.Field != f.name {
+						t.Fatalf("Notify() = %v, want a %s error for %s", err, tt.err, f.name)
+					}
+					if !errors.Is(verr, tt.err) {
+						t.Errorf("Validate() = %v, want %v", verr, tt.err)
+					}
+					return
+				}
+				if err != nil || verr != nil {
+					t.Fatalf("Notify() = %v, Validate() = %v", err, verr)
+				}
+				if got := f.sent(s.last(t)); got != tt.want {
+					t.Errorf("sent %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestFieldPolicyAggregatesErrors(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithAppName("My App"), notify.WithFieldPolicies(map[string]notify.FieldPolicy{
+		"summary": {MaxRunes: 10},
+		"name":    {Pattern: regexp.MustCompile(`^[a-z-]+$`)},
+	}))
+	n := &notify.Notification{Summary: "a summary far too long", Body: "\xff", Timeout: -5}
+	err := nf.Validate(n)
+	var verr *notify.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() = %v", err)
+	}
+	// The body has no policy, so its invalid UTF-8 is still reported.
+	for _, want := range []error{notify.ErrTooLong, notify.ErrPatternMismatch, notify.ErrInvalidUTF8, notify.ErrNegativeTimeout} {
+		if !errors.Is(err, want) {
+			t.Errorf("Validate() = %v, want %v", err, want)
+		}
+	}
+	if len(verr.Errs) != 4 {
+		t.Errorf("Validate() reported %d errors, want 4: %v", len(verr.Errs), err)
+	}
+
+	// The name checked is the one sent, so a Name can fix it.
+	n = &notify.Notification{Name: "my-app", Summary: "short"}
+	if err := nf.Validate(n); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestFieldPolicyInherited(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithFieldPolicies(map[string]notify.FieldPolicy{
+		"summary": {MaxRunes: 5, OnTooLong: notify.PolicyTruncate},
+		"body":    {OnInvalidUTF8: notify.PolicySanitize},
+	}))
+
+	template := notify.New("app", "", "", "", 0, notify.CriticalUrgency)
+	if _, err := nf.Fire(template, "Disk full", "no space \xff"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.Summary != "Disk " || got.Body != "no space \uFFFD" {
+		t.Errorf("Fire sent %q %q", got.Summary, got.Body)
+	}
+
+	if _, err := nf.As("other.desktop").Notify(notify.New("", "Backup done", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.Summary != "Backu" {
+		t.Errorf("a view sent %q", got.Summary)
+	}
+
+	var preset notify.Notification
+	if err := json.Unmarshal([]byte(`{"summary":"Meeting soon","body":"room 4"}`), &preset); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.Notify(&preset); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.Summary != "Meeti" {
+		t.Errorf("a preset sent %q", got.Summary)
+	}
+}
+
+func TestWithFieldPoliciesInvalid(t *testing.T) {
+	for name, policies := range map[string]map[string]notify.FieldPolicy{
+		"nil":           nil,
+		"unknown field": {"icon": {}},
+		"negative":      {"body": {MaxRunes: -1}},
+		"action":        {"body": {OnTooLong: notify.PolicyAction(7)}},
+		"no ellipsis":   {"body": {MaxRunes: 1, OnTooLong: notify.PolicySanitize}},
+	} {
+		if _, err := notify.NewNotifier(notify.WithFieldPolicies(policies)); err == nil {
+			t.Errorf("%s: NewNotifier() succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// Body builds the body of a notification from sections: key/value fields,
+// shown as aligned lines, and free text. For example:
+//
+//	b := notify.NewBody().Field("Host", "web-3").Field("Load", "7.2").Text("Disk almost full")
+//	n.Body = notifier.RenderBody(b.Limit(200))
+type Body struct {
+	sections []bodySection
+	max      int
+}
+
+// bodySection is a field, or free text if key is empty.
+type bodySection struct {
+	key, text string
+}
+
+// NewBody returns an empty Body.
+func NewBody() *Body {
+	return &Body{}
+}
+
+// Field appends the line key: value to b, and returns b.
+func (b *Body) Field(key, value string) *Body {
+	b.sections = append(b.sections, bodySection{key, value})
+	return b
+}
+
+// Text appends text to b, on its own lines, and returns b.
+func (b *Body) Text(text string) *Body {
+	b.sections = append(b.sections, bodySection{"", text})
+	return b
+}
+
+// Limit makes b render to at most max characters of plain text, and
+// returns b. The sections that do not fit are dropped whole, never cut in
+// the middle, and replaced by an ellipsis line. 0 means no limit.
+func (b *Body) Limit(max int) *Body {
+	b.max = max
+	return b
+}
+
+// String returns b as plain text, see Plain.
+func (b *Body) String() string {
+	return b.Plain()
+}
+
+// Plain returns b as plain text, one section after the other, with the
+// values of the fields aligned.
+func (b *Body) Plain() string {
+	return b.render(false)
+}
+
+// Markup returns b as markup, with the keys of the fields in bold and the
+// rest escaped.
+func (b *Body) Markup() string {
+	return b.render(true)
+}
+
+// render returns the sections of b that fit in its limit, followed by an
+// ellipsis if some do not.
+func (b *Body) render(markup bool) string {
+	kept := len(b.sections)
+	if b.max > 0 {
+		kept = b.fit()
+	}
+	width := 0
+	for _, s := range b.sections[:kept] {
+		if n := utf8.RuneCountInString(s.key); n > width {
+			width = n
+		}
+	}
+	// The sections left out only count for the ellipsis.
+	lines := make([]string, len(b.sections))
+	for i, s := range b.sections[:kept] {
+		switch {
+		case s.key == "" && markup:
+			lines[i] = html.EscapeString(s.text)
+		case s.key == "":
+			lines[i] = s.text
+		case markup:
+			lines[i] = "<b>" + html.EscapeString(s.key) + ":</b> " + html.EscapeString(s.text)
+		default:
+			lines[i] = s.key + ":" + strings.Repeat(" ", width-utf8.RuneCountInString(s.key)+1) + s.text
+		}
+	}
+	return joinLines(lines, kept)
+}
+
+// fit returns the number of first sections of b whose plain text, with
+// the ellipsis line if some are left out, is at most b.max characters long.
+func (b *Body) fit() int {
+	for n := len(b.sections); n > 0; n-- {
+		size := utf8.RuneCountInString((&Body{sections: b.sections[:n]}).Plain())
+		if n < len(b.sections) {
+			size += 2 // "\n…"
+		}
+		if size <= b.max {
+			return n
+		}
+	}
+	return 0
+}
+
+// RenderBody returns b as markup if the daemon of nf has the "body-markup"
+// capability, and as plain text otherwise.
+func (nf *Notifier) RenderBody(b *Body) string {
+	if !nf.onBus() {
+		return b.Plain()
+	}
+	if f, err := nf.features(); err != nil || !f.BodyMarkup {
+		return b.Plain()
+	}
+	return b.Markup()
+}
+
+// TemplateFuncs returns the functions building notification bodies in
+// templates:
+//
+//	body "Host" .Host "Load" .Load
+//
+// returns a Body with those fields, their values rendered by Render, which
+// prints as plain text.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"body": func(pairs ...any) (*Body, error) {
+			if len(pairs)%2 != 0 {
+				return nil, errors.New("notify: body needs key/value pairs")
+			}
+			b := NewBody()
+			for i := 0; i < len(pairs); i += 2 {
+				b.Field(Render(pairs[i]), Render(pairs[i+1]))
+			}
+			return b, nil
+		},
+	}
+}
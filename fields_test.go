@@ -0,0 +1,90 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestBody(t *testing.T) {
+	b := notify.NewBody().Field("Host", "web-3").Field("Uptime", "3 days").Text("Disk <almost> full")
+	if got, want := b.Plain(), "Host:   web-3\nUptime: 3 days\nDisk <almost> full"; got != want {
+		t.Errorf("Plain() = %q, want %q", got, want)
+	}
+	if got, want := b.Markup(), "<b>Host:</b> web-3\n<b>Uptime:</b> 3 days\nDisk &lt;almost&gt; full"; got != want {
+		t.Errorf("Markup() = %q, want %q", got, want)
+	}
+	if b.String() != b.Plain() {
+		t.Errorf("String() = %q", b.String())
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	b := func() *notify.Body {
+		return notify.NewBody().Field("Host", "web-3").Field("Load", "7.2").Field("Disk", "91%")
+	}
+	tests := []struct {
+		max  int
+		want string
+	}{
+		{0, "Host: web-3\nLoad: 7.2\nDisk: 91%"},
+		{31, "Host: web-3\nLoad: 7.2\nDisk: 91%"},
+		// "Disk: 91%" does not fit whole: it is dropped, not cut.
+		{30, "Host: web-3\nLoad: 7.2\n…"},
+		{23, "Host: web-3\nLoad: 7.2\n…"},
+		{22, "Host: web-3\n…"},
+		{5, "…"},
+	}
+	for _, tt := range tests {
+		b := b().Limit(tt.max)
+		if got := b.Plain(); got != tt.want {
+			t.Errorf("Limit(%d).Plain() = %q, want %q", tt.max, got, tt.want)
+		} else if tt.max > 0 && len([]rune(got)) > tt.max {
+			t.Errorf("Limit(%d).Plain() is %d characters long", tt.max, len([]rune(got)))
+		}
+	}
+	// Markup drops the same fields, and the alignment follows the fields
+	// shown.
+	b2 := notify.NewBody().Field("Host", "web-3").Field("Longer key", "x").Limit(15)
+	if got := b2.Markup(); got != "<b>Host:</b> web-3\n…" {
+		t.Errorf("Markup() = %q", got)
+	}
+	if got := b2.Plain(); got != "Host: web-3\n…" {
+		t.Errorf("Plain() = %q", got)
+	}
+}
+
+func TestRenderBody(t *testing.T) {
+	s := newFakeServer(t)
+	b := notify.NewBody().Field("Load", "7 > 5")
+	if got := newTestNotifier(t).RenderBody(b); got != "Load: 7 > 5" {
+		t.Errorf("RenderBody() = %q without body-markup", got)
+	}
+	s.setCapabilities("body", "body-markup")
+	if got := newTestNotifier(t).RenderBody(b); got != "<b>Load:</b> 7 &gt; 5" {
+		t.Errorf("RenderBody() = %q with body-markup", got)
+	}
+}
+
+func TestBodyTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("alert").Funcs(notify.TemplateFuncs()).Parse(`{{body "Host" .Host "Load" .Load}}`))
+	var out strings.Builder
+	if err := tmpl.Execute(&out, struct {
+		Host string
+		Load float64
+	}{"web-3", 7.2}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "Host: web-3\nLoad: 7.2" {
+		t.Errorf("template output %q", got)
+	}
+	if err := template.Must(template.New("odd").Funcs(notify.TemplateFuncs()).Parse(`{{body "Host"}}`)).Execute(&out, nil); err == nil {
+		t.Error("odd number of arguments accepted")
+	}
+}
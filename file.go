@@ -0,0 +1,100 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+)
+
+// CopyPathAction is the key of the action copying the path of the file of a
+// notification sent with NotifyFile.
+const CopyPathAction = "copy-path"
+
+// fileOptions holds the settings of NotifyFile.
+type fileOptions struct {
+	body      string
+	open      func(path string) error
+	clipboard func(text string) error
+}
+
+// FileOption configures a notification sent with NotifyFile.
+type FileOption func(*fileOptions)
+
+// FileBody sets the body of the notification.
+func FileBody(body string) FileOption {
+	return func(o *fileOptions) { o.body = body }
+}
+
+// FileOpener sets the function opening the file when the notification is
+// clicked. The default runs xdg-open.
+func FileOpener(open func(path string) error) FileOption {
+	return func(o *fileOptions) { o.open = open }
+}
+
+// FileClipboard sets the function copying the path of the file to the
+// clipboard. The default runs wl-copy on Wayland, and xclip otherwise.
+func FileClipboard(copyText func(text string) error) FileOption {
+	return func(o *fileOptions) { o.clipboard = copyText }
+}
+
+// NotifyFile sends a notification about the file at path, like a saved
+// screenshot, via notifier, or the default Notifier if it is nil. The file
+// is shown as the image of the notification if it is an image, and given as
+// a URL to daemons that support it. Clicking the notification opens the
+// file, and a "Copy path" action copies its path to the clipboard.
+//
+// The returned notification is the one that was sent; the errors of the
+// opener and of the clipboard are logged by notifier.
+func NotifyFile(notifier *Notifier, summary, path string, opts ...FileOption) (*Notification, error) {
+	if notifier == nil {
+		notifier = Default()
+	}
+	o := fileOptions{open: notifier.xdgOpen, clipboard: notifier.copyToClipboard}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("notify: %w", err)
+	}
+
+	n := &Notification{Summary: summary, Body: o.body, Urgency: NormalUrgency}
+	if isImageFile(abs) {
+		n.ImagePath = abs
+	}
+	if hint := notifier.fileURLsHint(); hint != "" {
+		n.SetHint(hint, []string{(&url.URL{Scheme: "file", Path: abs}).String()})
+	}
+	n.SetActions(Action{DefaultAction, notifier.localize(MsgOpen)}, Action{CopyPathAction, notifier.localize(MsgCopyPath)})
+	n.OnAction = func(key string) {
+		var err error
+		switch key {
+		case DefaultAction:
+			err = o.open(abs)
+		case CopyPathAction:
+			err = o.clipboard(abs)
+		default:
+			return
+		}
+		if err != nil {
+			notifier.log(LevelWarn, fmt.Sprintf("action %q on %s failed", key, abs), err)
+		}
+	}
+	if _, err := notifier.Notify(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// fileURLsHint returns the hint listing the URLs of the files of a
+// notification, or the empty string if the daemon has none.
+func (nf *Notifier) fileURLsHint() string {
+	if !nf.onBus() {
+		return ""
+	}
+	return nf.daemonQuirks().FileURLsHint
+}
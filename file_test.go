@@ -0,0 +1,82 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestNotifyFile(t *testing.T) {
+	s := newFakeServer(t)
+	s.setServerName("Plasma")
+	nf := newTestNotifier(t)
+	path := writePNG(t, "screenshot.png")
+
+	opened := make(chan string, 1)
+	copied := make(chan string, 1)
+	n, err := notify.NotifyFile(nf, "Screenshot saved", path,
+		notify.FileBody("screenshot.png"),
+		notify.FileOpener(func(path string) error { opened <- path; return nil }),
+		notify.FileClipboard(func(text string) error { copied <- text; return nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := s.last(t)
+	if sent.Summary != "Screenshot saved" || sent.Body != "screenshot.png" {
+		t.Errorf("sent %+v", sent)
+	}
+	if got := sent.Hints["image-path"].Value(); got != path {
+		t.Errorf("image-path hint = %v, want %q", got, path)
+	}
+	if urls, ok := sent.Hints["x-kde-urls"].Value().([]string); !ok || len(urls) != 1 || urls[0] != "file://"+path {
+		t.Errorf("x-kde-urls hint = %v", sent.Hints["x-kde-urls"])
+	}
+	want := []string{notify.DefaultAction, "Open", notify.CopyPathAction, "Copy path"}
+	if len(sent.Actions) != len(want) {
+		t.Fatalf("actions %q, want %q", sent.Actions, want)
+	}
+	for i := range want {
+		if sent.Actions[i] != want[i] {
+			t.Errorf("actions %q, want %q", sent.Actions, want)
+		}
+	}
+
+	for key, ch := range map[string]chan string{notify.DefaultAction: opened, notify.CopyPathAction: copied} {
+		s.emitAction(n.Id, key)
+		select {
+		case got := <-ch:
+			if got != path {
+				t.Errorf("action %q got %q, want %q", key, got, path)
+			}
+		case <-waitTimeout():
+			t.Fatalf("action %q did nothing", key)
+		}
+	}
+}
+
+func TestNotifyFileNotImage(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := notify.NotifyFile(nf, "Saved", path); err != nil {
+		t.Fatal(err)
+	}
+	hints := s.last(t).Hints
+	if _, ok := hints["image-path"]; ok {
+		t.Error("image-path hint sent for a text file")
+	}
+	if _, ok := hints["x-kde-urls"]; ok {
+		t.Error("x-kde-urls hint sent to a daemon without it")
+	}
+}
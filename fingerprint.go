@@ -0,0 +1,69 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"sort"
+)
+
+// FingerprintScope selects the fields of a notification that make its
+// fingerprint, see Notification.Fingerprint.
+type FingerprintScope int
+
+const (
+	// FingerprintContentOnly covers the summary, the body and the
+	// "category" hint.
+	FingerprintContentOnly FingerprintScope = iota
+	// FingerprintFull covers the content, plus the icon, the image, the
+	// urgency, the hints and the actions.
+	FingerprintFull
+)
+
+// Fingerprint returns a hash of the fields of n selected by scope. Two
+// notifications with the same fingerprint show the same thing, as far as
+// scope goes. The hints are hashed in the order of their keys, and the
+// fingerprint of a notification stays the same across runs and versions of
+// the package.
+//
+// A fingerprint is for recognizing notifications, such as duplicates, not a
+// security boundary: it is easy to build notifications with a given
+// fingerprint for anyone who chooses their content.
+func (n *Notification) Fingerprint(scope FingerprintScope) [32]byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d;", scope)
+	category, _ := hintValue(n.Hint("category"))
+	writeFields(h, n.Summary, n.Body, fmt.Sprint(category.Value()))
+	if scope != FingerprintContentOnly {
+		writeFields(h, n.IconPath, n.ImagePath)
+		fmt.Fprintf(h, "%d;", n.baseUrgency())
+		keys := make([]string, 0, len(n.hints))
+		for k := range n.hints {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if v, err := hintValue(n.hints[k]); err == nil {
+				fmt.Fprintf(h, "%q=%s:%v;", k, v.Signature(), v.Value())
+			}
+		}
+		fmt.Fprintf(h, "%d;", len(n.Actions))
+		for _, a := range n.Actions {
+			writeFields(h, a.Key, a.Label)
+		}
+	}
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// writeFields writes each of fields to h, prefixed by its length.
+func writeFields(h hash.Hash, fields ...string) {
+	for _, s := range fields {
+		fmt.Fprintf(h, "%d:%s", len(s), s)
+	}
+}
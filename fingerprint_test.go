@@ -0,0 +1,90 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func fingerprintNotification() *notify.Notification {
+	n := notify.New("test", "Backup done", "42 files", "drive-harddisk", 0, notify.NormalUrgency)
+	n.SetHint("category", "transfer.complete")
+	n.SetHint("x-test-count", int32(3))
+	n.SetHint("resident", true)
+	n.AddAction("open", "Open folder")
+	return n
+}
+
+func TestFingerprint(t *testing.T) {
+	n := fingerprintNotification()
+	// The values are pinned: changing them forgets the fingerprints stored
+	// by users of the package.
+	tests := []struct {
+		scope notify.FingerprintScope
+		want  string
+	}{
+		{notify.FingerprintContentOnly, "13a5ea748d97462945a46465606563bbabf0fa02b29ae65018153d71a8fa0903"},
+		{notify.FingerprintFull, "e0ca3dcf91efd292d5743cdf444606460532b25b052999343d76807c002b7ff3"},
+	}
+	for _, tt := range tests {
+		sum := n.Fingerprint(tt.scope)
+		if got := hex.EncodeToString(sum[:]); got != tt.want {
+			t.Errorf("Fingerprint(%d) = %s, want %s", tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestFingerprintScope(t *testing.T) {
+	n := fingerprintNotification()
+	content, full := n.Fingerprint(notify.FingerprintContentOnly), n.Fingerprint(notify.FingerprintFull)
+	if content == full {
+		t.Error("the scopes have the same fingerprint")
+	}
+
+	// The app name, ID and callbacks are never part of it.
+	other := fingerprintNotification()
+	other.Name, other.Id = "other", 7
+	other.OnAction = func(string) {}
+	if other.Fingerprint(notify.FingerprintFull) != full {
+		t.Error("the fingerprint depends on the app name, ID or callbacks")
+	}
+
+	// Only the full fingerprint covers the icon, hints and actions.
+	other.IconPath = "drive-removable-media"
+	other.SetHint("x-test-count", int32(4))
+	other.AddAction("eject", "Eject")
+	if other.Fingerprint(notify.FingerprintContentOnly) != content {
+		t.Error("the content fingerprint depends on the icon, hints or actions")
+	}
+	if other.Fingerprint(notify.FingerprintFull) == full {
+		t.Error("the full fingerprint does not depend on the icon, hints or actions")
+	}
+
+	other = fingerprintNotification()
+	other.SetHint("category", "transfer.error")
+	if other.Fingerprint(notify.FingerprintContentOnly) == content {
+		t.Error("the content fingerprint does not depend on the category")
+	}
+}
+
+func TestFingerprintHintOrder(t *testing.T) {
+	// Many hints make map iteration order show.
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	n1 := notify.New("test", "s", "", "", 0, notify.NormalUrgency)
+	n2 := notify.New("test", "s", "", "", 0, notify.NormalUrgency)
+	for i := range keys {
+		n1.SetHint("x-"+keys[i], i)
+		n2.SetHint("x-"+keys[len(keys)-1-i], len(keys)-1-i)
+	}
+	want := n1.Fingerprint(notify.FingerprintFull)
+	for i := 0; i < 20; i++ {
+		if n1.Fingerprint(notify.FingerprintFull) != want || n2.Fingerprint(notify.FingerprintFull) != want {
+			t.Fatal("the fingerprint depends on the order of the hints")
+		}
+	}
+}
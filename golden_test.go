@@ -0,0 +1,129 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// formatCall returns the Notify call received by the fake server as text,
+// with the hints sorted and their D-Bus types.
+func formatCall(sent sentNotification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "method: %s\n", sent.Member)
+	fmt.Fprintf(&b, "signature: %s\n", sent.Signature)
+	fmt.Fprintf(&b, "app_name: %q\n", sent.AppName)
+	fmt.Fprintf(&b, "replaces_id: %d\n", sent.ReplacesID)
+	fmt.Fprintf(&b, "app_icon: %q\n", sent.AppIcon)
+	fmt.Fprintf(&b, "summary: %q\n", sent.Summary)
+	fmt.Fprintf(&b, "body: %q\n", sent.Body)
+	fmt.Fprintf(&b, "actions: %q\n", sent.Actions)
+	keys := make([]string, 0, len(sent.Hints))
+	for k := range sent.Hints {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b.WriteString("hints:\n")
+	for _, k := range keys {
+		v := sent.Hints[k]
+		fmt.Fprintf(&b, "  %s: %s %s\n", k, v.Signature(), v)
+	}
+	fmt.Fprintf(&b, "expire_timeout: %d\n", sent.ExpireTimeout)
+	return b.String()
+}
+
+func TestGoldenCalls(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.NRGBA{0, 0, 255, 128})
+
+	tests := []struct {
+		name string
+		n    func() *notify.Notification
+	}{
+		{"low", func() *notify.Notification {
+			return notify.New("golden", "low", "", "", 0, notify.LowUrgency)
+		}},
+		{"normal", func() *notify.Notification {
+			return notify.New("golden", "normal", "body <b>text</b>", "dialog-information", 5*time.Second, notify.NormalUrgency)
+		}},
+		{"critical", func() *notify.Notification {
+			return notify.New("golden", "critical", "", "", 1500*time.Millisecond, notify.CriticalUrgency)
+		}},
+		{"hints", func() *notify.Notification {
+			n := notify.New("golden", "hints", "", "", 0, notify.NormalUrgency)
+			n.AddHints(
+				notify.CategoryHint("email.arrived"),
+				notify.DesktopEntryHint("org.example.Mail"),
+				notify.SoundNameHint("message-new-email"),
+				notify.SuppressSoundHint(true),
+				notify.TransientHint(true),
+				notify.ValueHint(42),
+				notify.XYHint{X: 10, Y: 20},
+			)
+			n.SetHint("x-vendor-string", "yes")
+			n.SetHint("x-vendor-int", int32(-3))
+			return n
+		}},
+		{"actions", func() *notify.Notification {
+			n := notify.New("golden", "actions", "", "", 0, notify.NormalUrgency)
+			n.AddAction(notify.DefaultAction, "Open")
+			n.AddAction("later", "Remind me later")
+			n.AddHints(notify.ActionIconsHint(true))
+			return n
+		}},
+		{"image", func() *notify.Notification {
+			n := notify.New("golden", "image", "", "", 0, notify.NormalUrgency)
+			n.SetImage(img)
+			return n
+		}},
+		{"image-path", func() *notify.Notification {
+			n := notify.New("golden", "image path", "", "", 0, notify.NormalUrgency)
+			n.ImagePath = "file:///usr/share/pixmaps/album.png"
+			return n
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			nf := newTestNotifier(t)
+			if _, err := nf.Notify(tt.n()); err != nil {
+				t.Fatal(err)
+			}
+			got := formatCall(s.last(t))
+
+			path := filepath.Join("testdata", "golden", tt.name+".txt")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run the tests with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("call differs from %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
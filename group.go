@@ -0,0 +1,144 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"strings"
+	"sync"
+)
+
+// rollupSummaries is the number of summaries listed in the body of a rollup
+// notification.
+const rollupSummaries = 3
+
+// Group collects related notifications, so that only a limited number of
+// them is shown individually and the rest is summarized in a single rollup
+// notification. Create one with Notifier.Group.
+//
+// For example, to show at most three new mails:
+//
+//	g := notifier.Group("mail")
+//	for _, m := range mails {
+//		g.Add(notify.New("mail", m.From, m.Subject, "mail-unread", 0, notify.NormalUrgency))
+//	}
+//	g.Flush(3)
+type Group struct {
+	nf   *Notifier
+	name string
+
+	mu      sync.Mutex
+	pending []*Notification
+	rollup  *Notification
+}
+
+// Group returns the notification group called name, creating it if it does
+// not exist yet. Calling Group again with the same name returns the same
+// group.
+func (nf *Notifier) Group(name string) *Group {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+
+	if g, ok := nf.groups[name]; ok {
+		return g
+	}
+	if nf.groups == nil {
+		nf.groups = make(map[string]*Group)
+	}
+	g := &Group{nf: nf, name: name}
+	nf.groups[name] = g
+	return g
+}
+
+// Name returns the name of the group.
+func (g *Group) Name() string { return g.name }
+
+// Add adds n to the notifications sent by the next Flush.
+func (g *Group) Add(n *Notification) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pending = append(g.pending, n)
+}
+
+// Len returns the number of notifications waiting for the next Flush.
+func (g *Group) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.pending)
+}
+
+// Flush sends up to max of the collected notifications individually, in the
+// order they were added, and a single rollup notification with the count and
+// the first few summaries of the rest. The rollup of a previous Flush is
+// replaced rather than shown a second time.
+//
+// Flush stops at the first error; the notifications that were not sent are
+// dropped either way.
+func (g *Group) Flush(max int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	pending := g.pending
+	g.pending = nil
+	if max < 0 {
+		max = 0
+	}
+	if max > len(pending) {
+		max = len(pending)
+	}
+
+	for _, n := range pending[:max] {
+		if _, err := g.nf.Notify(n); err != nil {
+			return err
+		}
+	}
+
+	rest := pending[max:]
+	if len(rest) == 0 {
+		return nil
+	}
+	rollup := g.makeRollup(rest)
+	if _, err := g.nf.Notify(rollup); err != nil {
+		return err
+	}
+	g.rollup = rollup
+	return nil
+}
+
+// makeRollup returns the rollup notification summarizing rest, reusing the
+// ID of the previous rollup so that it gets replaced.
+func (g *Group) makeRollup(rest []*Notification) *Notification {
+	first := rest[0]
+	rollup := &Notification{
+		Name:     first.Name,
+		IconPath: first.IconPath,
+		Timeout:  first.Timeout,
+		Urgency:  first.baseUrgency(),
+		Summary:  g.nf.localize(MsgMore, len(rest)),
+
+		urgencySet: true,
+	}
+	if g.rollup != nil {
+		rollup.Id = g.rollup.Id
+	}
+
+	lines := make([]string, len(rest))
+	for i, n := range rest {
+		if u := n.baseUrgency(); u > rollup.Urgency {
+			rollup.Urgency = u
+		}
+		lines[i] = n.Summary
+	}
+	rollup.Body = joinLines(lines, rollupSummaries)
+	return rollup
+}
+
+// joinLines returns the first max lines, one per line, followed by an
+// ellipsis if some were left out.
+func joinLines(lines []string, max int) string {
+	if len(lines) > max {
+		lines = append(lines[:max:max], "…")
+	}
+	return strings.Join(lines, "\n")
+}
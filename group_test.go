@@ -0,0 +1,56 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestGroupFlush(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	g := nf.Group("mail")
+	if nf.Group("mail") != g {
+		t.Fatal("Group returned a new group for the same name")
+	}
+	for i := 1; i <= 6; i++ {
+		g.Add(notify.New("mail", fmt.Sprintf("mail %d", i), "", "", 0, notify.NormalUrgency))
+	}
+	if err := g.Flush(2); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := s.notifications()
+	if len(sent) != 3 {
+		t.Fatalf("sent %d notifications, want 3", len(sent))
+	}
+	if sent[0].Summary != "mail 1" || sent[1].Summary != "mail 2" {
+		t.Errorf("individual notifications sent out of order: %q, %q", sent[0].Summary, sent[1].Summary)
+	}
+	rollup := sent[2]
+	if rollup.Summary != "and 4 more" || rollup.Body != "mail 3\nmail 4\nmail 5\n…" || rollup.ReplacesID != 0 {
+		t.Errorf("unexpected rollup %+v", rollup)
+	}
+	if g.Len() != 0 {
+		t.Errorf("Len() = %d after Flush", g.Len())
+	}
+
+	g.Add(notify.New("mail", "mail 7", "", "", 0, notify.NormalUrgency))
+	g.Add(notify.New("mail", "mail 8", "", "", 0, notify.CriticalUrgency))
+	if err := g.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+	again := s.last(t)
+	if again.ReplacesID != rollup.ID || again.Summary != "and 2 more" || again.Body != "mail 7\nmail 8" {
+		t.Errorf("second rollup %+v does not replace %d", again, rollup.ID)
+	}
+	if u := again.Hints["urgency"].Value(); u != byte(notify.CriticalUrgency) {
+		t.Errorf("rollup urgency = %v, want the highest of its notifications", u)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// HealthLayer is the part of the notification pipeline a HealthError is
+// about.
+type HealthLayer int
+
+const (
+	HealthBus       HealthLayer = iota // HealthBus means the bus connection is down.
+	HealthOwner                        // HealthOwner means no daemon owns the notification service name.
+	HealthDaemon                       // HealthDaemon means the daemon does not answer.
+	HealthRoundTrip                    // HealthRoundTrip means the probe notification could not be sent and closed.
+)
+
+// String returns the name of the layer.
+func (l HealthLayer) String() string {
+	switch l {
+	case HealthBus:
+		return "bus"
+	case HealthOwner:
+		return "owner"
+	case HealthDaemon:
+		return "daemon"
+	case HealthRoundTrip:
+		return "round trip"
+	}
+	return fmt.Sprintf("HealthLayer(%d)", int(l))
+}
+
+// HealthError is returned by HealthCheck when a layer of the notification
+// pipeline fails.
+type HealthError struct {
+	Layer HealthLayer
+	Err   error
+}
+
+func (e *HealthError) Error() string {
+	return fmt.Sprintf("notify: health check failed at the %s layer: %v", e.Layer, e.Err)
+}
+
+func (e *HealthError) Unwrap() error {
+	return e.Err
+}
+
+// WithHealthProbe makes HealthCheck also send a probe notification and close
+// it right away, to check the whole round trip. The probe is transient, so
+// that it does not stay in the history of the daemon, but users may still
+// see it flash.
+func WithHealthProbe(probe bool) Option {
+	return func(nf *Notifier) error {
+		nf.healthProbe = probe
+		return nil
+	}
+}
+
+// HealthCheck checks that notifications can be delivered: that the bus
+// connection is up, that a daemon owns the notification service name, and
+// that it answers. With WithHealthProbe, a probe notification is also sent
+// and closed. A failure is reported as a *HealthError telling which layer
+// failed.
+//
+// HealthCheck is cheap enough to be called periodically. With a Transport
+// other than D-Bus, there is nothing to check and it returns nil.
+func (nf *Notifier) HealthCheck(ctx context.Context) error {
+	if nf.transport == nil {
+		return &HealthError{HealthBus, ErrNoTransport}
+	} else if !nf.onBus() {
+		return nil
+	}
+
+	conn, err := nf.connection()
+	if err != nil {
+		return &HealthError{HealthBus, err}
+	} else if !conn.Connected() {
+		return &HealthError{HealthBus, errors.New("connection closed")}
+	}
+
+	var owner string
+	err = conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.GetNameOwner", 0, nf.destination).Store(&owner)
+	if err != nil {
+		return &HealthError{HealthOwner, err}
+	}
+
+	obj := nf.object(conn)
+	call := obj.CallWithContext(ctx, dbusInterface+".GetServerInformation", nf.callFlags())
+	if call.Err != nil {
+		return &HealthError{HealthDaemon, call.Err}
+	}
+	if !nf.healthProbe {
+		return nil
+	}
+
+	id, err := nf.RawNotify(ctx, Call{
+		AppName: nf.appNameFor(""),
+		Summary: "Notification health check",
+		Actions: []string{},
+		Urgency: LowUrgency,
+		Hints: map[string]dbus.Variant{
+			"urgency":   nf.urgencyHint(LowUrgency)["urgency"],
+			"transient": dbus.MakeVariant(true),
+		},
+	})
+	if err != nil {
+		return &HealthError{HealthRoundTrip, err}
+	}
+	if err := obj.CallWithContext(ctx, dbusInterface+".CloseNotification", nf.callFlags(), id).Err; err != nil {
+		return &HealthError{HealthRoundTrip, fmt.Errorf("closing probe %d: %w", id, err)}
+	}
+	return nil
+}
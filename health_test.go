@@ -0,0 +1,74 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/godbus/dbus/v5"
+)
+
+// healthLayer returns the layer of the HealthError err, or -1.
+func healthLayer(err error) notify.HealthLayer {
+	var he *notify.HealthError
+	if errors.As(err, &he) {
+		return he.Layer
+	}
+	return -1
+}
+
+func TestHealthCheck(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	if err := nf.HealthCheck(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.notifications()) != 0 {
+		t.Error("health check without probe sent a notification")
+	}
+
+	probing := newTestNotifier(t, notify.WithHealthProbe(true))
+	for i := 0; i < 3; i++ {
+		if err := probing.HealthCheck(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent := s.notifications()
+	if len(sent) != 3 || sent[0].Hints["transient"].Value() != true {
+		t.Fatalf("probes sent: %+v", sent)
+	}
+	if closed := s.closedIDs(); len(closed) != 3 {
+		t.Errorf("closed %v, want every probe", closed)
+	}
+}
+
+func TestHealthCheckLayers(t *testing.T) {
+	requireBus(t)
+	nf := newTestNotifier(t)
+	if err := nf.HealthCheck(context.Background()); healthLayer(err) != notify.HealthOwner {
+		t.Errorf("without a daemon: %v", err)
+	}
+
+	// A daemon owning the name without exporting anything does not answer.
+	conn, err := dbus.Connect(busAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.RequestName("org.freedesktop.Notifications", dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatal(err)
+	}
+	if err := nf.HealthCheck(context.Background()); healthLayer(err) != notify.HealthDaemon {
+		t.Errorf("with a broken daemon: %v", err)
+	}
+
+	broken := newTestNotifier(t, notify.WithBusAddress("unix:path=/nonexistent"))
+	if err := broken.HealthCheck(context.Background()); healthLayer(err) != notify.HealthBus {
+		t.Errorf("without a bus: %v", err)
+	}
+}
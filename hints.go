@@ -0,0 +1,395 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// hintsGen numbers the versions of the hints of all notifications, so that
+// the send cache can tell whether they changed.
+var hintsGen atomic.Uint64
+
+// SetHint sets the hint key to value, which is sent as a D-Bus variant. A
+// nil value removes the hint. Values are converted as follows:
+//
+//   - a dbus.Variant, or a non-nil pointer to one, is sent as it is, not
+//     wrapped in another variant;
+//   - a dbus.ObjectPath or a dbus.Signature is sent as an object path or a
+//     signature, and a []dbus.ObjectPath as an array of object paths;
+//   - a HintValuer is sent as the variant it returns;
+//   - a time.Duration or a Duration is sent as int32 milliseconds, like the
+//     timeout of the notification;
+//   - bool, string, byte, int16, uint16, int32, uint32, int64, uint64 and
+//     float64 values are sent with their D-Bus type, int and uint values as
+//     int32 and uint32, int8 as int16 and float32 as float64;
+//   - []string and []byte values are sent as arrays of strings and bytes;
+//   - the values of named types, such as CategoryHint, are sent as their
+//     underlying type.
+//
+// Notify and Validate fail for the other values, and for int and uint values
+// out of the range of 32 bits.
+//
+// Hints set this way are sent as they are, after the hints the package sets
+// by itself, such as "urgency": setting those here overrides them.
+func (n *Notification) SetHint(key string, value interface{}) {
+	// Copy on write, so that copies of n do not share their hints.
+	hints := make(map[string]interface{}, len(n.hints)+1)
+	for k, v := range n.hints {
+		hints[k] = v
+	}
+	if value == nil {
+		delete(hints, key)
+	} else {
+		hints[key] = value
+	}
+	n.hints = hints
+	n.hintsGen = hintsGen.Add(1)
+}
+
+// Hint returns the value of the hint key set with SetHint, or nil.
+func (n *Notification) Hint(key string) interface{} {
+	return n.hints[key]
+}
+
+// HintValuer is implemented by the values of hints set with SetHint that
+// choose their D-Bus encoding.
+type HintValuer interface {
+	HintValue() dbus.Variant
+}
+
+// encodeHints adds the hints set with SetHint to hints, converted by
+// hintValue. The hints that cannot be converted are left out, see
+// checkHintValues.
+func encodeHints(hints map[string]dbus.Variant, custom map[string]interface{}) {
+	for k, v := range custom {
+		if variant, err := hintValue(v); err == nil {
+			hints[k] = variant
+		}
+	}
+}
+
+// checkHintValues returns the FieldError of the first hint, in the order of
+// their keys, that hintValue cannot convert.
+func checkHintValues(custom map[string]interface{}) error {
+	var bad []string
+	for k, v := range custom {
+		if _, err := hintValue(v); err != nil {
+			bad = append(bad, k)
+		}
+	}
+	if len(bad) == 0 {
+		return nil
+	}
+	sort.Strings(bad)
+	_, err := hintValue(custom[bad[0]])
+	return &FieldError{"hints[" + bad[0] + "]", err}
+}
+
+// hintValue converts the value of a hint set with SetHint to a variant, see
+// SetHint for the rules.
+func hintValue(v interface{}) (dbus.Variant, error) {
+	switch v := v.(type) {
+	case dbus.Variant:
+		return v, nil
+	case *dbus.Variant:
+		if v != nil {
+			return *v, nil
+		}
+	case dbus.ObjectPath, dbus.Signature, []dbus.ObjectPath:
+		// Not their underlying types, string and a struct.
+		return dbus.MakeVariant(v), nil
+	case HintValuer:
+		return v.HintValue(), nil
+	case time.Duration:
+		return dbus.MakeVariant(durationMillis(v)), nil
+	case Duration:
+		return dbus.MakeVariant(durationMillis(time.Duration(v))), nil
+	case bool, string, byte, int16, uint16, int32, uint32, int64, uint64, float64, []string, []byte:
+		return dbus.MakeVariant(v), nil
+	case int:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return dbus.Variant{}, fmt.Errorf("%w: int %d out of the range of int32", ErrHintType, v)
+		}
+		return dbus.MakeVariant(int32(v)), nil
+	case uint:
+		if v > math.MaxUint32 {
+			return dbus.Variant{}, fmt.Errorf("%w: uint %d out of the range of uint32", ErrHintType, v)
+		}
+		return dbus.MakeVariant(uint32(v)), nil
+	case int8:
+		return dbus.MakeVariant(int16(v)), nil
+	case float32:
+		return dbus.MakeVariant(float64(v)), nil
+	}
+	// Named types, such as CategoryHint, are sent as their underlying type.
+	if rv := reflect.ValueOf(v); rv.IsValid() {
+		if base, ok := basicTypes[rv.Kind()]; ok && rv.Type() != base {
+			return hintValue(rv.Convert(base).Interface())
+		}
+	}
+	return dbus.Variant{}, fmt.Errorf("%w: cannot send %T, use a dbus.Variant or a HintValuer", ErrHintType, v)
+}
+
+// basicTypes are the types the named types of their kind are converted to.
+var basicTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.String:  reflect.TypeOf(""),
+	reflect.Int:     reflect.TypeOf(0),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(byte(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+}
+
+// durationMillis returns d in milliseconds, clamped to the range of int32.
+func durationMillis(d time.Duration) int32 {
+	ms := d.Milliseconds()
+	switch {
+	case ms > math.MaxInt32:
+		return math.MaxInt32
+	case ms < math.MinInt32:
+		return math.MinInt32
+	}
+	return int32(ms)
+}
+
+// Hint is a hint with a known D-Bus encoding. Use the concrete types below
+// with AddHints rather than SetHint, which cannot tell the type a daemon
+// expects: a hint of the wrong type is silently ignored by most daemons.
+//
+// Implement Hint for vendor-specific hints with a fixed type.
+type Hint interface {
+	// EncodeHint stores the hint in hints, with the type required by the
+	// specification.
+	EncodeHint(hints map[string]dbus.Variant)
+}
+
+// WithDefaultHints sends the hints hs with every notification, unless the
+// notification sets them itself. It fails if a hint is invalid, see
+// BuildHints.
+func WithDefaultHints(hs ...Hint) Option {
+	return func(nf *Notifier) error {
+		hints, err := BuildHints(hs...)
+		if err != nil {
+			return err
+		}
+		if nf.defaultHints == nil {
+			nf.defaultHints = make(map[string]dbus.Variant, len(hints))
+		}
+		for k, v := range hints {
+			nf.defaultHints[k] = v
+		}
+		return nil
+	}
+}
+
+// AddHints sets the hints hs on n, replacing any previous value.
+func (n *Notification) AddHints(hs ...Hint) {
+	encoded := make(map[string]dbus.Variant, len(hs))
+	for _, h := range hs {
+		h.EncodeHint(encoded)
+	}
+	for k, v := range encoded {
+		n.SetHint(k, v)
+	}
+}
+
+type (
+	// UrgencyHint is the "urgency" hint. The Urgency field of the
+	// notification sets it too.
+	UrgencyHint NotificationUrgency
+	// CategoryHint is the "category" hint, such as "email.arrived".
+	CategoryHint string
+	// DesktopEntryHint is the "desktop-entry" hint, the name of the desktop
+	// file of the application without the ".desktop" suffix.
+	DesktopEntryHint string
+	// ImagePathHint is the "image-path" hint. The ImagePath field of the
+	// notification sets it too, with the key expected by the daemon.
+	ImagePathHint string
+	// SoundFileHint is the "sound-file" hint, the path of a sound to play.
+	SoundFileHint string
+	// SoundNameHint is the "sound-name" hint, a themed sound name such as
+	// "message-new-instant".
+	SoundNameHint string
+	// SuppressSoundHint is the "suppress-sound" hint.
+	SuppressSoundHint bool
+	// TransientHint is the "transient" hint: the notification bypasses the
+	// persistence of the daemon.
+	TransientHint bool
+	// ResidentHint is the "resident" hint: the notification is not removed
+	// when an action is invoked. CloseOnAction sets it too.
+	ResidentHint bool
+	// ActionIconsHint is the "action-icons" hint: the action keys are
+	// themed icon names.
+	ActionIconsHint bool
+	// ValueHint is the "value" hint, a progress percentage from 0 to 100,
+	// supported by many daemons although it is not part of the
+	// specification.
+	ValueHint int32
+)
+
+// XYHint is the "x" and "y" hints, the position on the screen the
+// notification should point to.
+type XYHint struct {
+	X, Y int32
+}
+
+func (h UrgencyHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["urgency"] = dbus.MakeVariant(byte(h))
+}
+
+func (h CategoryHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["category"] = dbus.MakeVariant(string(h))
+}
+
+func (h DesktopEntryHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["desktop-entry"] = dbus.MakeVariant(string(h))
+}
+
+func (h ImagePathHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["image-path"] = dbus.MakeVariant(string(h))
+}
+
+func (h SoundFileHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["sound-file"] = dbus.MakeVariant(string(h))
+}
+
+func (h SoundNameHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["sound-name"] = dbus.MakeVariant(string(h))
+}
+
+func (h SuppressSoundHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["suppress-sound"] = dbus.MakeVariant(bool(h))
+}
+
+func (h TransientHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["transient"] = dbus.MakeVariant(bool(h))
+}
+
+func (h ResidentHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["resident"] = dbus.MakeVariant(bool(h))
+}
+
+func (h ActionIconsHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["action-icons"] = dbus.MakeVariant(bool(h))
+}
+
+func (h ValueHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["value"] = dbus.MakeVariant(int32(h))
+}
+
+func (h XYHint) EncodeHint(hints map[string]dbus.Variant) {
+	hints["x"] = dbus.MakeVariant(h.X)
+	hints["y"] = dbus.MakeVariant(h.Y)
+}
+
+// HintDecoder reads a hint back from the hints of a received notification,
+// for notification daemons. The pointers to the hint types above implement
+// it, with the same keys and types they encode.
+type HintDecoder interface {
+	// DecodeHint sets the receiver from hints, and returns false if the
+	// hint is missing or does not have the type required by the
+	// specification.
+	DecodeHint(hints map[string]dbus.Variant) bool
+}
+
+// decodeHint returns the value of the hint key if it has the type T.
+func decodeHint[T any](hints map[string]dbus.Variant, key string) (T, bool) {
+	v, ok := hints[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	t, ok := v.Value().(T)
+	return t, ok
+}
+
+func (h *UrgencyHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[byte](hints, "urgency")
+	*h = UrgencyHint(v)
+	return ok
+}
+
+func (h *CategoryHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[string](hints, "category")
+	*h = CategoryHint(v)
+	return ok
+}
+
+func (h *DesktopEntryHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[string](hints, "desktop-entry")
+	*h = DesktopEntryHint(v)
+	return ok
+}
+
+func (h *ImagePathHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[string](hints, "image-path")
+	*h = ImagePathHint(v)
+	return ok
+}
+
+func (h *SoundFileHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[string](hints, "sound-file")
+	*h = SoundFileHint(v)
+	return ok
+}
+
+func (h *SoundNameHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[string](hints, "sound-name")
+	*h = SoundNameHint(v)
+	return ok
+}
+
+func (h *SuppressSoundHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[bool](hints, "suppress-sound")
+	*h = SuppressSoundHint(v)
+	return ok
+}
+
+func (h *TransientHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[bool](hints, "transient")
+	*h = TransientHint(v)
+	return ok
+}
+
+func (h *ResidentHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[bool](hints, "resident")
+	*h = ResidentHint(v)
+	return ok
+}
+
+func (h *ActionIconsHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[bool](hints, "action-icons")
+	*h = ActionIconsHint(v)
+	return ok
+}
+
+func (h *ValueHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	v, ok := decodeHint[int32](hints, "value")
+	*h = ValueHint(v)
+	return ok
+}
+
+func (h *XYHint) DecodeHint(hints map[string]dbus.Variant) bool {
+	x, okX := decodeHint[int32](hints, "x")
+	y, okY := decodeHint[int32](hints, "y")
+	*h = XYHint{x, y}
+	return okX && okY
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestTypedHintSignatures(t *testing.T) {
+	tests := []struct {
+		hint notify.Hint
+		want map[string]string
+	}{
+		{notify.UrgencyHint(notify.CriticalUrgency), map[string]string{"urgency": "y"}},
+		{notify.CategoryHint("email.arrived"), map[string]string{"category": "s"}},
+		{notify.DesktopEntryHint("org.example.Mail"), map[string]string{"desktop-entry": "s"}},
+		{notify.ImagePathHint("/tmp/cover.png"), map[string]string{"image-path": "s"}},
+		{notify.SoundFileHint("/tmp/ding.oga"), map[string]string{"sound-file": "s"}},
+		{notify.SoundNameHint("message-new-instant"), map[string]string{"sound-name": "s"}},
+		{notify.SuppressSoundHint(true), map[string]string{"suppress-sound": "b"}},
+		{notify.TransientHint(true), map[string]string{"transient": "b"}},
+		{notify.ResidentHint(true), map[string]string{"resident": "b"}},
+		{notify.ActionIconsHint(true), map[string]string{"action-icons": "b"}},
+		{notify.ValueHint(42), map[string]string{"value": "i"}},
+		{notify.XYHint{X: 10, Y: 20}, map[string]string{"x": "i", "y": "i"}},
+	}
+
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	for _, tt := range tests {
+		n := notify.New("test", "hints", "", "", time.Second, notify.LowUrgency)
+		n.AddHints(tt.hint)
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		hints := s.last(t).Hints
+		for key, sig := range tt.want {
+			v, ok := hints[key]
+			if !ok {
+				t.Errorf("%T: no %q hint sent", tt.hint, key)
+			} else if got := v.Signature().String(); got != sig {
+				t.Errorf("%T: %q hint has signature %s, want %s", tt.hint, key, got, sig)
+			}
+		}
+	}
+}
+
+// rgb is a vendor hint value choosing its encoding.
+type rgb struct{ r, g, b byte }
+
+func (c rgb) HintValue() dbus.Variant { return dbus.MakeVariant([]byte{c.r, c.g, c.b}) }
+
+func TestSetHintConversions(t *testing.T) {
+	type level int
+	variant := dbus.MakeVariant("pointer")
+	tests := []struct {
+		value interface{}
+		sig   string
+		want  interface{}
+	}{
+		{dbus.MakeVariant(uint16(7)), "q", uint16(7)},
+		{&variant, "s", "pointer"},
+		{dbus.ObjectPath("/org/example/Player"), "o", dbus.ObjectPath("/org/example/Player")},
+		{dbus.SignatureOf(""), "g", dbus.SignatureOf("")},
+		{[]dbus.ObjectPath{"/a", "/b"}, "ao", []dbus.ObjectPath{"/a", "/b"}},
+		{rgb{1, 2, 3}, "ay", []byte{1, 2, 3}},
+		{5 * time.Second, "i", int32(5000)},
+		{notify.Duration(1500 * time.Millisecond), "i", int32(1500)},
+		{time.Duration(math.MaxInt64), "i", int32(math.MaxInt32)},
+		{true, "b", true},
+		{"text", "s", "text"},
+		{byte(1), "y", byte(1)},
+		{int8(-2), "n", int16(-2)},
+		{int16(-3), "n", int16(-3)},
+		{uint16(4), "q", uint16(4)},
+		{int32(-5), "i", int32(-5)},
+		{uint32(6), "u", uint32(6)},
+		{int64(-7), "x", int64(-7)},
+		{uint64(8), "t", uint64(8)},
+		{-9, "i", int32(-9)},
+		{uint(10), "u", uint32(10)},
+		{float32(0.5), "d", 0.5},
+		{0.25, "d", 0.25},
+		{[]string{"a", "b"}, "as", []string{"a", "b"}},
+		{[]byte{1, 2}, "ay", []byte{1, 2}},
+		{notify.CategoryHint("im"), "s", "im"},
+		{level(3), "i", int32(3)},
+	}
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	for _, tt := range tests {
+		n := notify.New("test", "hints", "", "", 0, notify.NormalUrgency)
+		n.SetHint("x-vendor", tt.value)
+		if err := n.Validate(); err != nil {
+			t.Errorf("%T: Validate() = %v", tt.value, err)
+		}
+		c, err := n.DryRun(nf)
+		if err != nil {
+			t.Errorf("%T: DryRun = %v", tt.value, err)
+			continue
+		}
+		v := c.Hints["x-vendor"]
+		if got := v.Signature().String(); got != tt.sig {
+			t.Errorf("%T: sent with signature %s, want %s", tt.value, got, tt.sig)
+		}
+		if !reflect.DeepEqual(v.Value(), tt.want) {
+			t.Errorf("%T: sent %#v, want %#v", tt.value, v.Value(), tt.want)
+		}
+	}
+
+	for _, bad := range []interface{}{
+		math.MaxInt32 + 1,
+		uint(math.MaxUint32 + 1),
+		[]int{1},
+		map[string]string{"a": "b"},
+		struct{ A int }{1},
+		func() {},
+		new(int),
+		(*dbus.Variant)(nil),
+	} {
+		n := notify.New("test", "hints", "", "", 0, notify.NormalUrgency)
+		n.SetHint("x-vendor", bad)
+		if err := n.Validate(); !errors.Is(err, notify.ErrHintType) {
+			t.Errorf("%T: Validate() = %v, want ErrHintType", bad, err)
+		}
+		if _, err := n.DryRun(nf); !errors.Is(err, notify.ErrHintType) {
+			t.Errorf("%T: DryRun = %v, want ErrHintType", bad, err)
+		}
+	}
+}
+
+func TestAddHintsOverridesUrgency(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	n := notify.New("test", "hints", "", "", time.Second, notify.LowUrgency)
+	n.AddHints(notify.UrgencyHint(notify.CriticalUrgency), notify.CategoryHint("device"))
+	n.SetHint("x-vendor", "custom")
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	hints := s.last(t).Hints
+	if v := hints["urgency"].Value(); v != byte(notify.CriticalUrgency) {
+		t.Errorf("urgency = %v, want the typed hint", v)
+	}
+	if v := hints["x-vendor"].Value(); v != "custom" {
+		t.Errorf("x-vendor = %v", v)
+	}
+}
+
+// TestSharedUrgencyHints checks that the hints shared by the calls with only
+// an urgency are never written to, even when other hints are merged in
+// concurrently; run it with -race.
+func TestSharedUrgencyHints(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				n := notify.New("test", "shared", "", "", time.Second, notify.NormalUrgency)
+				if i%2 == 1 {
+					n.Tag = "shared"
+					n.SetHint("x-vendor", j)
+				}
+				c, err := n.DryRun(nf)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if i%2 == 0 && len(c.Hints) != 1 {
+					t.Errorf("hints = %v, want only the urgency", c.Hints)
+					return
+				}
+				_ = c.Hints["urgency"].Value()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	c, err := notify.New("test", "shared", "", "", time.Second, notify.NormalUrgency).DryRun(nf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Hints) != 1 || c.Hints["urgency"].Value() != byte(notify.NormalUrgency) {
+		t.Errorf("hints = %v, want only the normal urgency", c.Hints)
+	}
+}
+
+// TestHintsRoundTrip checks that every hint type decodes what it encodes, so
+// that daemons read hints the way clients send them.
+func TestHintsRoundTrip(t *testing.T) {
+	tests := []struct {
+		hint notify.Hint
+		dec  notify.HintDecoder
+	}{
+		{notify.UrgencyHint(notify.CriticalUrgency), new(notify.UrgencyHint)},
+		{notify.CategoryHint("email.arrived"), new(notify.CategoryHint)},
+		{notify.DesktopEntryHint("org.example.Mail"), new(notify.DesktopEntryHint)},
+		{notify.ImagePathHint("/tmp/cover.png"), new(notify.ImagePathHint)},
+		{notify.SoundFileHint("/tmp/ding.oga"), new(notify.SoundFileHint)},
+		{notify.SoundNameHint("message-new-instant"), new(notify.SoundNameHint)},
+		{notify.SuppressSoundHint(true), new(notify.SuppressSoundHint)},
+		{notify.TransientHint(true), new(notify.TransientHint)},
+		{notify.ResidentHint(true), new(notify.ResidentHint)},
+		{notify.ActionIconsHint(true), new(notify.ActionIconsHint)},
+		{notify.ValueHint(42), new(notify.ValueHint)},
+		{notify.XYHint{X: 10, Y: 20}, new(notify.XYHint)},
+	}
+
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	for _, tt := range tests {
+		n := notify.New("test", "hints", "", "", time.Second, notify.LowUrgency)
+		n.AddHints(tt.hint)
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		if !tt.dec.DecodeHint(s.last(t).Hints) {
+			t.Errorf("%T: decoding failed", tt.hint)
+			continue
+		}
+		if got := reflect.ValueOf(tt.dec).Elem().Interface(); got != tt.hint {
+			t.Errorf("%T: decoded %v, want %v", tt.hint, got, tt.hint)
+		}
+	}
+}
+
+// TestHintPassthroughOnTheWire checks that the daemon gets the hints set
+// with D-Bus values with their own signature, not in a variant of their own.
+func TestHintPassthroughOnTheWire(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	n := notify.New("test", "hints", "", "", 0, notify.NormalUrgency)
+	n.SetHint("x-variant", dbus.MakeVariant(int32(3)))
+	n.SetHint("x-path", dbus.ObjectPath("/org/example/Player"))
+	n.SetHint("x-signature", dbus.SignatureOf(uint32(0)))
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	hints := s.last(t).Hints
+	for key, sig := range map[string]string{"x-variant": "i", "x-path": "o", "x-signature": "g"} {
+		if got := hints[key].Signature().String(); got != sig {
+			t.Errorf("%s received with signature %s, want %s", key, got, sig)
+		}
+	}
+	if v, ok := hints["x-variant"].Value().(int32); !ok || v != 3 {
+		t.Errorf("x-variant received as %#v, want int32(3)", hints["x-variant"].Value())
+	}
+}
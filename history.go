@@ -0,0 +1,266 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Record is what happened to a notification sent by a Notifier, see
+// WithHistory. Sending the same Notification again, to replace it, updates
+// its record.
+type Record struct {
+	// CorrelationID identifies the notification, see
+	// Notification.CorrelationID.
+	CorrelationID string
+	// ID is the last ID the daemon assigned to the notification.
+	ID uint32
+
+	AppName string
+	Summary string
+	Body    string
+	Urgency NotificationUrgency
+	Tag     string
+	// Category is the "category" hint of the notification, if any.
+	Category string
+
+	// SentAt is when the notification was first sent, and UpdatedAt when
+	// it was last sent.
+	SentAt    time.Time
+	UpdatedAt time.Time
+	// Action is the key of the last action invoked, at ActionAt.
+	Action   string
+	ActionAt time.Time
+	// ClosedAt is when the notification was closed, for Reason, or zero if
+	// it was not.
+	ClosedAt time.Time
+	Reason   CloseReason
+	// DisplayedFor is how long the notification was shown before it was
+	// closed: from SentAt, or from when it was sent again after a close, to
+	// ClosedAt. It is measured on the monotonic clock, so changes of the
+	// wall clock do not skew it. DisplayedKnown is false while it is not
+	// known: until the notification is closed, and forever if the daemon
+	// never signals the close, as some do for the notifications they drop
+	// or move to their history.
+	DisplayedFor   time.Duration
+	DisplayedKnown bool
+	// Context is what was known of the desktop of the user when the
+	// notification was last sent.
+	Context DeliveryContext
+	// Call is the last call sending the notification, see Repost. For
+	// Sensitive notifications, its body is the placeholder of the sensitive
+	// policy. It is zero in the records read by ReadInbox.
+	Call Call
+
+	// shownAt is the time, with its monotonic clock reading, since which
+	// the notification is shown.
+	shownAt time.Time
+}
+
+// History is the records of the last notifications sent, the oldest first.
+type History []Record
+
+// history holds the records of a Notifier.
+type history struct {
+	size    int
+	trusted bool
+	records []*Record
+	// byCorrelation holds the records by correlation ID, and open the
+	// records of the notifications not closed yet by daemon ID.
+	byCorrelation map[string]*Record
+	open          map[uint32]*Record
+}
+
+// WithHistory makes the Notifier keep the records of the last size
+// notifications it sent, see Notifier.History. The summary and body are
+// recorded as sent, after the Redactor. The body of Sensitive notifications
+// is recorded as the placeholder of the sensitive policy, even when it was
+// shown.
+func WithHistory(size int) Option {
+	return withHistory(size, false)
+}
+
+// WithTrustedHistory is like WithHistory, but records the body of Sensitive
+// notifications, after the Redactor, even when it was hidden.
+func WithTrustedHistory(size int) Option {
+	return withHistory(size, true)
+}
+
+func withHistory(size int, trusted bool) Option {
+	return func(nf *Notifier) error {
+		if size <= 0 {
+			return errors.New("notify: history size must be positive")
+		}
+		nf.history = &history{
+			size:          size,
+			trusted:       trusted,
+			byCorrelation: make(map[string]*Record),
+			open:          make(map[uint32]*Record),
+		}
+		return nil
+	}
+}
+
+// History returns a copy of the records of nf, or nil if it keeps none.
+func (nf *Notifier) History() History {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if nf.history == nil {
+		return nil
+	}
+	h := make(History, len(nf.history.records))
+	for i, r := range nf.history.records {
+		h[i] = *r
+	}
+	return h
+}
+
+// recordSent records that n was sent with the call c, in the context dc.
+func (nf *Notifier) recordSent(n *Notification, c Call, dc DeliveryContext) {
+	nf.mu.Lock()
+	h := nf.history
+	if h == nil {
+		nf.mu.Unlock()
+		return
+	}
+	now := nf.clock.Now()
+	r, ok := h.byCorrelation[n.CorrelationID]
+	if !ok {
+		if len(h.records) == h.size {
+			old := h.records[0]
+			h.records = h.records[1:]
+			delete(h.byCorrelation, old.CorrelationID)
+			if h.open[old.ID] == old {
+				delete(h.open, old.ID)
+			}
+		}
+		r = &Record{CorrelationID: n.CorrelationID, SentAt: now}
+		h.records = append(h.records, r)
+		h.byCorrelation[n.CorrelationID] = r
+	}
+	if h.open[r.ID] == r {
+		delete(h.open, r.ID)
+	} else {
+		r.shownAt = now
+	}
+	r.ID = n.Id
+	r.AppName, r.Summary, r.Body = c.AppName, c.Summary, c.Body
+	if n.Sensitive {
+		r.Body = nf.sensitivePlaceholder
+		if h.trusted {
+			_, r.Body = nf.redact(n.Summary, n.Body)
+		}
+	}
+	r.Urgency, r.Tag = n.baseUrgency(), n.Tag
+	r.Category, _ = c.Hints["category"].Value().(string)
+	r.Call = c
+	r.Call.Hints = make(map[string]dbus.Variant, len(c.Hints))
+	for k, v := range c.Hints {
+		r.Call.Hints[k] = v
+	}
+	r.Call.Actions = append([]string(nil), c.Actions...)
+	if n.Sensitive {
+		r.Call.Body = nf.sensitivePlaceholder
+	}
+	r.UpdatedAt, r.Context = now, dc
+	r.ClosedAt, r.Reason = time.Time{}, 0
+	r.DisplayedFor, r.DisplayedKnown = 0, false
+	h.open[n.Id] = r
+	nf.mu.Unlock()
+
+	// The records are updated from the signals of the daemon.
+	nf.listenAlways()
+}
+
+// recordSignal updates the record of the open notification id with update,
+// and returns its correlation ID. It must be called with nf.mu held.
+func (nf *Notifier) recordSignal(id uint32, update func(r *Record)) string {
+	if nf.history == nil {
+		return ""
+	}
+	r, ok := nf.history.open[id]
+	if !ok {
+		return ""
+	}
+	update(r)
+	return r.CorrelationID
+}
+
+// jsonRecord is the JSON encoding of a Record.
+type jsonRecord struct {
+	CorrelationID string              `json:"correlation_id"`
+	ID            uint32              `json:"id"`
+	AppName       string              `json:"app_name"`
+	Summary       string              `json:"summary"`
+	Body          string              `json:"body,omitempty"`
+	Urgency       NotificationUrgency `json:"urgency"`
+	Tag           string              `json:"tag,omitempty"`
+	Category      string              `json:"category,omitempty"`
+	SentAt        time.Time           `json:"sent_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+	Action        string              `json:"action,omitempty"`
+	ActionAt      *time.Time          `json:"action_at,omitempty"`
+	ClosedAt      *time.Time          `json:"closed_at,omitempty"`
+	Reason        string              `json:"close_reason,omitempty"`
+	DisplayedMs   *int64              `json:"displayed_ms,omitempty"`
+	Context       *jsonContext        `json:"context,omitempty"`
+}
+
+// jsonContext is the JSON form of a DeliveryContext, without the fields
+// that are unknown.
+type jsonContext struct {
+	Daemon           string `json:"daemon,omitempty"`
+	Locked           *bool  `json:"locked,omitempty"`
+	DoNotDisturb     bool   `json:"do_not_disturb"`
+	GraphicalSession *bool  `json:"graphical_session,omitempty"`
+}
+
+// MarshalJSON encodes h as {"version": 1, "records": [...]}, with the
+// fields of the records in snake case, the times in RFC 3339 format, and
+// the urgency and close reason as their names, and DisplayedFor in
+// milliseconds as "displayed_ms". The fields of the events
+// that did not happen are omitted. New fields may be added, but the
+// existing ones keep their meaning while the version stays the same.
+func (h History) MarshalJSON() ([]byte, error) {
+	records := make([]jsonRecord, len(h))
+	for i, r := range h {
+		j := jsonRecord{
+			CorrelationID: r.CorrelationID,
+			ID:            r.ID,
+			AppName:       r.AppName,
+			Summary:       r.Summary,
+			Body:          r.Body,
+			Urgency:       r.Urgency,
+			Tag:           r.Tag,
+			Category:      r.Category,
+			SentAt:        r.SentAt,
+			UpdatedAt:     r.UpdatedAt,
+			Action:        r.Action,
+		}
+		if !r.ActionAt.IsZero() {
+			j.ActionAt = &h[i].ActionAt
+		}
+		if !r.ClosedAt.IsZero() {
+			j.ClosedAt = &h[i].ClosedAt
+			j.Reason = r.Reason.String()
+		}
+		if r.DisplayedKnown {
+			ms := r.DisplayedFor.Milliseconds()
+			j.DisplayedMs = &ms
+		}
+		if r.Context != (DeliveryContext{}) {
+			j.Context = r.Context.json()
+		}
+		records[i] = j
+	}
+	return json.Marshal(struct {
+		Version int          `json:"version"`
+		Records []jsonRecord `json:"records"`
+	}{1, records})
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestCorrelationID(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	events := nf.Events()
+
+	n := notify.New("test", "hello", "", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if !uuidPattern.MatchString(n.CorrelationID) {
+		t.Errorf("CorrelationID = %q, want a UUID", n.CorrelationID)
+	}
+	if e := waitEvent(t, events, notify.EventSent); e.CorrelationID != n.CorrelationID {
+		t.Errorf("EventSent.CorrelationID = %q, want %q", e.CorrelationID, n.CorrelationID)
+	}
+
+	first := n.CorrelationID
+	n.Body = "again"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if n.CorrelationID != first {
+		t.Errorf("CorrelationID changed from %q to %q on replace", first, n.CorrelationID)
+	}
+	waitEvent(t, events, notify.EventSent)
+
+	m := notify.New("test", "mine", "", "", 0, notify.NormalUrgency)
+	m.CorrelationID = "job-42"
+	if _, err := nf.Notify(m); err != nil {
+		t.Fatal(err)
+	}
+	if e := waitEvent(t, events, notify.EventSent); e.CorrelationID != "job-42" {
+		t.Errorf("EventSent.CorrelationID = %q, want the one set by the caller", e.CorrelationID)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithHistory(2), notify.WithClock(clock))
+	events := nf.Events()
+
+	send := func(summary string) *notify.Notification {
+		t.Helper()
+		n := notify.New("test", summary, "body", "", 0, notify.LowUrgency)
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		waitEvent(t, events, notify.EventSent)
+		return n
+	}
+
+	send("dropped")
+	a := send("acted")
+	b := send("closed")
+	s.emitAction(a.Id, "default")
+	if e := waitEvent(t, events, notify.EventAction); e.CorrelationID != a.CorrelationID {
+		t.Errorf("EventAction.CorrelationID = %q, want %q", e.CorrelationID, a.CorrelationID)
+	}
+	s.emitClosed(b.Id, uint32(notify.ReasonDismissed))
+	if e := waitEvent(t, events, notify.EventClosed); e.CorrelationID != b.CorrelationID {
+		t.Errorf("EventClosed.CorrelationID = %q, want %q", e.CorrelationID, b.CorrelationID)
+	}
+
+	h := nf.History()
+	if len(h) != 2 {
+		t.Fatalf("History() has %d records, want 2", len(h))
+	}
+	if r := h[0]; r.CorrelationID != a.CorrelationID || r.Summary != "acted" || r.Action != "default" || r.ActionAt.IsZero() || !r.ClosedAt.IsZero() {
+		t.Errorf("first record = %+v", r)
+	}
+	if r := h[1]; r.CorrelationID != b.CorrelationID || r.Reason != notify.ReasonDismissed || r.ClosedAt.IsZero() || r.Urgency != notify.LowUrgency {
+		t.Errorf("second record = %+v", r)
+	}
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Version int                      `json:"version"`
+		Records []map[string]interface{} `json:"records"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != 1 || len(doc.Records) != 2 {
+		t.Fatalf("exported %s", data)
+	}
+	if r := doc.Records[0]; r["action"] != "default" || r["action_at"] == nil || r["closed_at"] != nil || r["urgency"] != "low" {
+		t.Errorf("first exported record = %v", r)
+	}
+	if r := doc.Records[1]; r["close_reason"] != "dismissed" || r["correlation_id"] != b.CorrelationID {
+		t.Errorf("second exported record = %v", r)
+	}
+}
+
+func TestHistoryDisplayedFor(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithHistory(4), notify.WithClock(clock))
+	events := nf.Events()
+
+	send := func(n *notify.Notification) *notify.Notification {
+		t.Helper()
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		waitEvent(t, events, notify.EventSent)
+		return n
+	}
+	closeAfter := func(n *notify.Notification, d time.Duration, reason notify.CloseReason) {
+		t.Helper()
+		clock.Advance(d)
+		s.emitClosed(n.Id, uint32(reason))
+		if e := waitEvent(t, events, notify.EventClosed); !e.DisplayedKnown || e.DisplayedFor != 3*time.Second {
+			t.Errorf("EventClosed displayed for %v (known %v), want 3s", e.DisplayedFor, e.DisplayedKnown)
+		}
+	}
+
+	dismissed := send(notify.New("test", "dismissed", "", "", 0, notify.NormalUrgency))
+	closeAfter(dismissed, 3*time.Second, notify.ReasonDismissed)
+
+	// Replacing a notification does not restart its display.
+	expired := send(notify.New("test", "expired", "", "", 0, notify.NormalUrgency))
+	clock.Advance(time.Second)
+	expired.Summary = "expired again"
+	send(expired)
+	closeAfter(expired, 2*time.Second, notify.ReasonExpired)
+
+	// Sending it again after the close does.
+	clock.Advance(time.Minute)
+	send(dismissed)
+	closeAfter(dismissed, 3*time.Second, notify.ReasonDismissed)
+
+	send(notify.New("test", "never closed", "", "", 0, notify.NormalUrgency))
+	clock.Advance(time.Hour)
+
+	h := nf.History()
+	if len(h) != 3 {
+		t.Fatalf("History() has %d records, want 3", len(h))
+	}
+	for _, r := range h[:2] {
+		if !r.DisplayedKnown || r.DisplayedFor != 3*time.Second {
+			t.Errorf("%q displayed for %v (known %v), want 3s", r.Summary, r.DisplayedFor, r.DisplayedKnown)
+		}
+	}
+	if r := h[2]; r.DisplayedKnown || r.DisplayedFor != 0 {
+		t.Errorf("%q never closed, displayed for %v (known %v)", r.Summary, r.DisplayedFor, r.DisplayedKnown)
+	}
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Records []map[string]interface{} `json:"records"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.Records[0]["displayed_ms"]; got != 3000.0 {
+		t.Errorf("displayed_ms = %v, want 3000", got)
+	}
+	if got, ok := doc.Records[2]["displayed_ms"]; ok {
+		t.Errorf("displayed_ms = %v for the notification never closed", got)
+	}
+}
+
+func TestEventDisplayedForTracked(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+	events := nf.Events()
+
+	closed := make(chan notify.CloseReason, 1)
+	n := notify.New("test", "tracked", "", "", 0, notify.NormalUrgency)
+	n.OnClose = func(reason notify.CloseReason) { closed <- reason }
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(1500 * time.Millisecond)
+	s.emitClosed(n.Id, uint32(notify.ReasonExpired))
+	if e := waitEvent(t, events, notify.EventClosed); !e.DisplayedKnown || e.DisplayedFor != 1500*time.Millisecond {
+		t.Errorf("EventClosed displayed for %v (known %v), want 1.5s", e.DisplayedFor, e.DisplayedKnown)
+	}
+
+	// Without callbacks nor history, the send is not recorded.
+	untracked := notify.New("test", "untracked", "", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(untracked); err != nil {
+		t.Fatal(err)
+	}
+	s.emitClosed(untracked.Id, uint32(notify.ReasonExpired))
+	if e := waitEvent(t, events, notify.EventClosed); e.DisplayedKnown {
+		t.Errorf("EventClosed displayed for %v of an untracked notification", e.DisplayedFor)
+	}
+}
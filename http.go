@@ -0,0 +1,217 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBodyBytes is the default size limit of the requests of the HTTP
+// handler, see HandlerOptions.
+const DefaultMaxBodyBytes = 64 << 10
+
+// DefaultSecretHeader is the default header holding the shared secret, see
+// HandlerOptions.
+const DefaultSecretHeader = "X-Notify-Secret"
+
+// HandlerOptions configures the handler returned by NewHTTPHandler. The
+// zero value accepts any request up to DefaultMaxBodyBytes.
+type HandlerOptions struct {
+	// MaxBodyBytes is the size limit of the requests, DefaultMaxBodyBytes
+	// if 0.
+	MaxBodyBytes int64
+	// Urgencies are the urgencies accepted, all of them if empty.
+	Urgencies []NotificationUrgency
+	// Rate is the number of notifications accepted per second, unlimited if
+	// 0, with bursts of up to Burst notifications (at least 1).
+	Rate  float64
+	Burst int
+
+	// Secret, if set, must be given in the SecretHeader of every request.
+	Secret string
+	// SecretHeader is the header holding Secret, DefaultSecretHeader if
+	// empty.
+	SecretHeader string
+
+	// AllowMarkup lets the body through as it is. Otherwise it is escaped
+	// for the daemons with the "body-markup" capability, so that it is
+	// shown as plain text. The summary is never markup.
+	AllowMarkup bool
+	// AllowName lets the posted notifications choose the app name they are
+	// sent with, instead of the one of the Notifier.
+	AllowName bool
+	// AllowIcons lets the posted notifications give an icon and an image
+	// path, which name local files the daemon reads.
+	AllowIcons bool
+	// AllowTag lets the posted notifications give a tag, and so replace the
+	// notifications of the application with the same tag.
+	AllowTag bool
+}
+
+// httpHandler shows the notifications posted to it.
+type httpHandler struct {
+	nf   *Notifier
+	opts HandlerOptions
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewHTTPHandler returns a handler showing the notifications posted to it,
+// in their JSON encoding (see Notification.MarshalJSON), via notifier. It
+// replies with the ID of the notification as {"id": 42}.
+//
+// Notifications are validated, and their IDs, actions and persistence are
+// ignored. Unless allowed by opts, so are their name, icon, image path and
+// tag: posted notifications are sent as the application, show no local
+// files, and cannot replace other notifications.
+func NewHTTPHandler(notifier *Notifier, opts HandlerOptions) http.Handler {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.SecretHeader == "" {
+		opts.SecretHeader = DefaultSecretHeader
+	}
+	if opts.Burst < 1 {
+		opts.Burst = 1
+	}
+	return &httpHandler{nf: notifier, opts: opts, tokens: float64(opts.Burst)}
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		httpError(w, http.StatusMethodNotAllowed, errors.New("only POST is allowed"))
+		return
+	}
+	if h.opts.Secret != "" {
+		got := r.Header.Get(h.opts.SecretHeader)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(h.opts.Secret)) != 1 {
+			httpError(w, http.StatusUnauthorized, errors.New("invalid secret"))
+			return
+		}
+	}
+
+	var n Notification
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.opts.MaxBodyBytes))
+	if err := dec.Decode(&n); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpError(w, http.StatusRequestEntityTooLarge, errors.New("request too large"))
+		} else {
+			httpError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+	if !h.sanitize(&n) {
+		httpError(w, http.StatusForbidden, errors.New("urgency not allowed"))
+		return
+	}
+	if err := h.nf.Validate(&n); err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !h.allow() {
+		httpError(w, http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+		return
+	}
+
+	res, err := h.nf.SendContext(r.Context(), &n)
+	if err != nil {
+		h.nf.log(LevelWarn, "sending a posted notification failed", err)
+		httpError(w, http.StatusBadGateway, errors.New("sending the notification failed"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Id uint32 `json:"id"`
+	}{res.Id})
+}
+
+// sanitize drops what a posted notification may not do, and returns false if
+// its urgency is not allowed.
+func (h *httpHandler) sanitize(n *Notification) bool {
+	n.Id = 0
+	n.Actions = nil
+	n.Persistent = false
+	n.CloseOnAction = false
+	if !h.opts.AllowName {
+		n.Name = ""
+	}
+	if !h.opts.AllowIcons {
+		n.IconPath, n.ImagePath = "", ""
+	}
+	if !h.opts.AllowTag {
+		n.Tag = ""
+	}
+	if !h.opts.AllowMarkup && h.markup() {
+		n.Body = html.EscapeString(n.Body)
+	}
+	if len(h.opts.Urgencies) == 0 {
+		return true
+	}
+	for _, u := range h.opts.Urgencies {
+		if n.baseUrgency() == u {
+			return true
+		}
+	}
+	return false
+}
+
+// markup returns true if the daemon shows the markup of bodies. Without
+// its capabilities, bodies are taken as plain text, as the other transports
+// do.
+func (h *httpHandler) markup() bool {
+	f, err := h.nf.features()
+	return err == nil && f.BodyMarkup
+}
+
+// allow returns true if a notification may be sent now without exceeding
+// the rate limit.
+func (h *httpHandler) allow() bool {
+	if h.opts.Rate <= 0 {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.nf.clock.Now()
+	if !h.last.IsZero() {
+		h.tokens += now.Sub(h.last).Seconds() * h.opts.Rate
+		if burst := float64(h.opts.Burst); h.tokens > burst {
+			h.tokens = burst
+		}
+	}
+	h.last = now
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+// httpError replies with err, and the list of the problems if it is a
+// *ValidationError, as {"error": "...", "problems": ["summary: ..."]}.
+func httpError(w http.ResponseWriter, code int, err error) {
+	var problems []string
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		for _, e := range invalid.Errs {
+			problems = append(problems, e.Error())
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error    string   `json:"error"`
+		Problems []string `json:"problems,omitempty"`
+	}{err.Error(), problems})
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// post posts body to h with the given secret, and returns the response.
+func post(h http.Handler, secret, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if secret != "" {
+		req.Header.Set(notify.DefaultSecretHeader, secret)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestHTTPHandler(t *testing.T) {
+	s := newFakeServer(t)
+	s.setCapabilities("body", "body-markup")
+	nf := newTestNotifier(t, notify.WithAppName("app"))
+	h := notify.NewHTTPHandler(nf, notify.HandlerOptions{Secret: "s3cret"})
+
+	w := post(h, "s3cret", `{"summary": "build <b>failed</b>", "body": "on ci & co", "urgency": "critical", "timeout": "5s",
+		"actions": [{"key": "default", "label": "Open"}],
+		"name": "bank", "icon": "/home/user/.ssh/id_rsa", "image_path": "/etc/shadow", "tag": "app-status"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var reply struct{ Id uint32 }
+	if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if reply.Id != got.ID {
+		t.Errorf("replied with ID %d, want %d", reply.Id, got.ID)
+	}
+	if got.Summary != "build <b>failed</b>" || got.Body != "on ci &amp; co" {
+		t.Errorf("sent %q, %q, want only the body escaped", got.Summary, got.Body)
+	}
+	if got.AppName != "app" || got.AppIcon != "" || got.ReplacesID != 0 {
+		t.Errorf("sent as %q with the icon %q replacing %d, want the name of the Notifier and no icon", got.AppName, got.AppIcon, got.ReplacesID)
+	}
+	for _, key := range []string{"image-path", "x-dunst-stack-tag", "x-canonical-private-synchronous"} {
+		if _, ok := got.Hints[key]; ok {
+			t.Errorf("posted hint %s sent", key)
+		}
+	}
+	if got.Hints["urgency"].Value() != byte(notify.CriticalUrgency) || got.ExpireTimeout != 5000 || len(got.Actions) != 0 {
+		t.Errorf("sent %+v", got)
+	}
+}
+
+func TestHTTPHandlerAllows(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithAppName("app"))
+	h := notify.NewHTTPHandler(nf, notify.HandlerOptions{AllowName: true, AllowIcons: true, AllowTag: true})
+
+	w := post(h, "", `{"summary": "status", "body": "ci & co", "name": "ci", "icon": "dialog-information", "tag": "ci-status"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	got := s.last(t)
+	if got.AppName != "ci" || got.AppIcon != "dialog-information" || got.Hints["x-dunst-stack-tag"].Value() != "ci-status" {
+		t.Errorf("sent %+v, want the posted name, icon and tag", got)
+	}
+	if got.Body != "ci & co" {
+		t.Errorf("body %q escaped for a daemon without body-markup", got.Body)
+	}
+}
+
+func TestHTTPHandlerRejects(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	h := notify.NewHTTPHandler(nf, notify.HandlerOptions{
+		Secret:       "s3cret",
+		MaxBodyBytes: 100,
+		Urgencies:    []notify.NotificationUrgency{notify.LowUrgency, notify.NormalUrgency},
+	})
+
+	tests := []struct {
+		name, secret, body string
+		code               int
+	}{
+		{"no secret", "", `{"summary": "hi"}`, http.StatusUnauthorized},
+		{"bad secret", "guess", `{"summary": "hi"}`, http.StatusUnauthorized},
+		{"too large", "s3cret", `{"summary": "` + strings.Repeat("x", 100) + `"}`, http.StatusRequestEntityTooLarge},
+		{"bad JSON", "s3cret", `{"summary": `, http.StatusBadRequest},
+		{"bad urgency", "s3cret", `{"summary": "hi", "urgency": "urgent"}`, http.StatusBadRequest},
+		{"urgency not allowed", "s3cret", `{"summary": "hi", "urgency": "critical"}`, http.StatusForbidden},
+		{"invalid", "s3cret", `{"summary": ""}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := post(h, tt.secret, tt.body); w.Code != tt.code {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, w.Code, tt.code, w.Body)
+		}
+	}
+	if n := len(s.notifications()); n != 0 {
+		t.Errorf("%d notifications sent", n)
+	}
+
+	var reply struct {
+		Problems []string `json:"problems"`
+	}
+	w := post(h, "s3cret", `{"summary": "", "timeout": "-1s"}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil || len(reply.Problems) != 2 {
+		t.Errorf("invalid notification: reply %s, want both problems", w.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d", w.Code)
+	}
+}
+
+func TestHTTPHandlerRate(t *testing.T) {
+	newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+	h := notify.NewHTTPHandler(nf, notify.HandlerOptions{Rate: 1, Burst: 2, AllowMarkup: true})
+
+	var codes []int
+	for i := 0; i < 3; i++ {
+		codes = append(codes, post(h, "", `{"summary": "<b>hi</b>"}`).Code)
+	}
+	clock.Advance(time.Second)
+	codes = append(codes, post(h, "", `{"summary": "<b>hi</b>"}`).Code)
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("status codes %v, want %v", codes, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// IDStore records the ID of the last notification sent with each tag in a
+// directory, so that other processes sending with the same tag, such as
+// other runs of a status script, replace it rather than show another one.
+type IDStore struct {
+	dir     string
+	locking bool
+}
+
+// NewIDStore returns an IDStore keeping its files in dir, which is created
+// if needed.
+//
+// If locking is true, sends with the same tag are serialized across
+// processes with an advisory lock on the file of the tag, held from reading
+// the ID to writing the new one. Otherwise two processes sending at the same
+// time may both show a notification. Locking is not available on all
+// platforms; where it is not, it is ignored.
+func NewIDStore(dir string, locking bool) (*IDStore, error) {
+	if dir == "" {
+		return nil, errors.New("notify: empty ID store directory")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("notify: %w", err)
+	}
+	return &IDStore{dir: dir, locking: locking}, nil
+}
+
+// WithIDStore makes the Notifier read and record the IDs of tagged
+// notifications in s.
+func WithIDStore(s *IDStore) Option {
+	return func(nf *Notifier) error {
+		if s == nil {
+			return errors.New("notify: nil ID store")
+		}
+		nf.idStore = s
+		return nil
+	}
+}
+
+// path returns the file of tag. Tags are hex-encoded, so that any tag is a
+// valid file name.
+func (s *IDStore) path(tag string) string {
+	return filepath.Join(s.dir, "tag-"+hex.EncodeToString([]byte(tag)))
+}
+
+// acquire opens the file of tag, locking it if needed, and returns the ID it
+// holds, or 0. release records id, if not 0, and unlocks the file.
+func (s *IDStore) acquire(tag string) (id uint32, release func(id uint32) error, err error) {
+	f, err := os.OpenFile(s.path(tag), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return 0, nil, err
+	}
+	if s.locking {
+		if err := lockFile(f); err != nil {
+			f.Close()
+			return 0, nil, err
+		}
+	}
+
+	var buf [16]byte
+	n, _ := f.ReadAt(buf[:], 0)
+	if v, err := strconv.ParseUint(strings.TrimSpace(string(buf[:n])), 10, 32); err == nil {
+		id = uint32(v)
+	}
+	return id, func(id uint32) error {
+		var err error
+		if id != 0 {
+			if err = f.Truncate(0); err == nil {
+				_, err = f.WriteAt([]byte(strconv.FormatUint(uint64(id), 10)+"\n"), 0)
+			}
+		}
+		// Closing the file releases the lock.
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
+
+// storedTag reads the ID of the tag of n from the ID store of nf, so that n
+// replaces it, and returns the function recording the ID n was sent with.
+// It returns nil if n does not use the store.
+func (nf *Notifier) storedTag(n *Notification) (record func()) {
+	if nf.idStore == nil || n.Tag == "" || n.Id != 0 {
+		return nil
+	}
+	id, release, err := nf.idStore.acquire(n.Tag)
+	if err != nil {
+		nf.log(LevelWarn, fmt.Sprintf("reading the ID of tag %q failed", n.Tag), err)
+		return nil
+	}
+	if id != 0 {
+		nf.mu.Lock()
+		if nf.tags == nil {
+			nf.tags = make(map[string]tagged)
+		}
+		t := nf.tags[n.Tag]
+		t.id = id
+		nf.tags[n.Tag] = t
+		nf.mu.Unlock()
+	}
+	return func() {
+		if err := release(n.Id); err != nil {
+			nf.log(LevelWarn, fmt.Sprintf("recording the ID of tag %q failed", n.Tag), err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build !unix
+
+package notify
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile does nothing: advisory locks are not available.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// tryLockFile fails: without advisory locks, another process may hold the
+// lock.
+func tryLockFile(f *os.File) error {
+	return errors.New("notify: file locks are not available")
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+// storeNotifier returns a Notifier with its own handle on the ID store in
+// dir, like another process would have.
+func storeNotifier(t *testing.T, dir string) *notify.Notifier {
+	t.Helper()
+	store, err := notify.NewIDStore(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTestNotifier(t, notify.WithIDStore(store))
+}
+
+func TestIDStoreReplacesAcrossNotifiers(t *testing.T) {
+	s := newFakeServer(t)
+	dir := t.TempDir()
+
+	first := notify.New("test", "up", "", "", 0, notify.NormalUrgency)
+	first.Tag = "network"
+	if _, err := storeNotifier(t, dir).Notify(first); err != nil {
+		t.Fatal(err)
+	}
+	second := notify.New("test", "down", "", "", 0, notify.NormalUrgency)
+	second.Tag = "network"
+	if _, err := storeNotifier(t, dir).Notify(second); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).ReplacesID; got != first.Id {
+		t.Errorf("second send replaces %d, want %d", got, first.Id)
+	}
+}
+
+func TestIDStoreViews(t *testing.T) {
+	s := newFakeServer(t)
+	dir := t.TempDir()
+
+	first := notify.New("", "up", "", "", 0, notify.NormalUrgency)
+	first.Tag = "network"
+	view := storeNotifier(t, dir).As("nm-applet")
+	defer view.Close()
+	if _, err := view.Notify(first); err != nil {
+		t.Fatal(err)
+	}
+	second := notify.New("", "down", "", "", 0, notify.NormalUrgency)
+	second.Tag = "network"
+	if _, err := storeNotifier(t, dir).As("nm-applet").Notify(second); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).ReplacesID; got != first.Id {
+		t.Errorf("the send of the other view replaces %d, want %d", got, first.Id)
+	}
+}
+
+func TestIDStoreLockingConcurrentSends(t *testing.T) {
+	s := newFakeServer(t)
+	dir := t.TempDir()
+	notifiers := []*notify.Notifier{storeNotifier(t, dir), storeNotifier(t, dir)}
+
+	var wg sync.WaitGroup
+	for _, nf := range notifiers {
+		wg.Add(1)
+		go func(nf *notify.Notifier) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				n := notify.New("test", "network", "", "", 0, notify.NormalUrgency)
+				n.Tag = "network"
+				if _, err := nf.Notify(n); err != nil {
+					t.Error(err)
+				}
+			}
+		}(nf)
+	}
+	wg.Wait()
+
+	ids := make(map[uint32]bool)
+	for _, n := range s.notifications() {
+		ids[n.ID] = true
+	}
+	if len(ids) != 1 {
+		t.Errorf("sends showed %d notifications, want 1", len(ids))
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// imageDataSignature is the D-Bus signature of imageData.
+const imageDataSignature = "(iiibiiay)"
+
+// imageData is the raw image format of the specification, with the D-Bus
+// signature imageDataSignature.
+type imageData struct {
+	Width         int32
+	Height        int32
+	Rowstride     int32
+	HasAlpha      bool
+	BitsPerSample int32
+	Channels      int32
+	Data          []byte
+}
+
+// SetImage embeds img in the notification, to be shown in place of the icon
+// by daemons that support it. A nil img removes the image. Any image.Image
+// is accepted, whatever its bounds, but sending one without pixels fails with
+// ErrEmptyImage.
+//
+// The image is converted to the raw format of the specification right away,
+// so img may be modified afterwards. It is downscaled to at most
+// DefaultImageMaxDimension pixels on each side, unless opts say otherwise:
+// raw images are large, and daemons may reject big calls.
+func (n *Notification) SetImage(img image.Image, opts ...ImageOption) {
+	if img == nil {
+		n.image = nil
+		return
+	}
+	o := imageOptions{maxDimension: DefaultImageMaxDimension}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	n.image = encodeImage(img, o)
+}
+
+// SetImageFromFile embeds the PNG, JPEG or GIF image in the file at path in
+// the notification, like SetImage.
+func (n *Notification) SetImageFromFile(path string, opts ...ImageOption) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("notify: decoding %s: %w", path, err)
+	} else if img.Bounds().Empty() {
+		return fmt.Errorf("notify: decoding %s: %w", path, ErrEmptyImage)
+	}
+	n.SetImage(img, opts...)
+	return nil
+}
+
+// encodeImage converts img to 8-bit non-premultiplied RGBA, or RGB if it
+// must be opaque, scaled as o says. An image without pixels is encoded
+// with a zero size, rejected when it is sent.
+func encodeImage(img image.Image, o imageOptions) *imageData {
+	b := img.Bounds()
+	if b.Empty() {
+		return &imageData{}
+	}
+	rgba := toNRGBA(img)
+	if w, h := scaledSize(b.Dx(), b.Dy(), o.maxDimension); w != b.Dx() || h != b.Dy() {
+		rgba = scale(rgba, w, h, o.quality)
+	}
+	d := &imageData{
+		Width:         int32(rgba.Rect.Dx()),
+		Height:        int32(rgba.Rect.Dy()),
+		Rowstride:     int32(rgba.Stride),
+		HasAlpha:      true,
+		BitsPerSample: 8,
+		Channels:      4,
+		Data:          rgba.Pix,
+	}
+	if o.forceOpaque {
+		d = d.opaque()
+	}
+	return d
+}
+
+// toNRGBA returns a copy of img as an NRGBA image with its origin at (0, 0),
+// whatever its bounds and color model.
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	switch src := img.(type) {
+	case *image.NRGBA:
+		for y := 0; y < h; y++ {
+			i := src.PixOffset(b.Min.X, b.Min.Y+y)
+			copy(dst.Pix[y*dst.Stride:y*dst.Stride+w*4], src.Pix[i:i+w*4])
+		}
+	case *image.RGBA:
+		for y := 0; y < h; y++ {
+			i := src.PixOffset(b.Min.X, b.Min.Y+y)
+			for x := 0; x < w; x++ {
+				p := src.Pix[i+x*4 : i+x*4+4]
+				c := color.NRGBAModel.Convert(color.RGBA{p[0], p[1], p[2], p[3]}).(color.NRGBA)
+				setNRGBA(dst, x, y, c)
+			}
+		}
+	case *image.Gray:
+		for y := 0; y < h; y++ {
+			i := src.PixOffset(b.Min.X, b.Min.Y+y)
+			for x := 0; x < w; x++ {
+				v := src.Pix[i+x]
+				setNRGBA(dst, x, y, color.NRGBA{v, v, v, 0xff})
+			}
+		}
+	default:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				setNRGBA(dst, x, y, color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA))
+			}
+		}
+	}
+	return dst
+}
+
+// empty returns true if d is the encoding of an image without pixels.
+func (d *imageData) empty() bool {
+	return d != nil && (d.Width == 0 || d.Height == 0)
+}
+
+// setNRGBA sets the pixel at x, y of img, whose origin is at (0, 0), to c,
+// without the interface conversion of Set.
+func setNRGBA(img *image.NRGBA, x, y int, c color.NRGBA) {
+	p := img.Pix[y*img.Stride+x*4 : y*img.Stride+x*4+4]
+	p[0], p[1], p[2], p[3] = c.R, c.G, c.B, c.A
+}
+
+// opaque returns d without its alpha channel.
+func (d *imageData) opaque() *imageData {
+	if !d.HasAlpha {
+		return d
+	}
+	w, h := int(d.Width), int(d.Height)
+	data := make([]byte, 0, w*h*3)
+	for y := 0; y < h; y++ {
+		row := d.Data[y*int(d.Rowstride):]
+		for x := 0; x < w; x++ {
+			data = append(data, row[x*4:x*4+3]...)
+		}
+	}
+	return &imageData{
+		Width:         d.Width,
+		Height:        d.Height,
+		Rowstride:     int32(w * 3),
+		BitsPerSample: 8,
+		Channels:      3,
+		Data:          data,
+	}
+}
+
+// DecodeImage returns the raw image embedded in hints, under any of the
+// names the hint had in the versions of the specification, for notification
+// daemons. It returns nil and no error if there is none. Only 8-bit RGB and
+// RGBA images, the ones SetImage sends, are supported.
+func DecodeImage(hints map[string]dbus.Variant) (image.Image, error) {
+	for _, key := range imageHintKeys {
+		v, ok := hints[key]
+		if !ok {
+			continue
+		}
+		var data imageData
+		if err := dbus.Store([]interface{}{v.Value()}, &data); err != nil {
+			return nil, fmt.Errorf("notify: invalid %s hint: %w", key, err)
+		}
+		img, err := data.decode()
+		if err != nil {
+			return nil, fmt.Errorf("notify: invalid %s hint: %w", key, err)
+		}
+		return img, nil
+	}
+	return nil, nil
+}
+
+// decode converts d to an image.
+func (d *imageData) decode() (image.Image, error) {
+	if d.BitsPerSample != 8 || d.Channels != 3 && d.Channels != 4 || d.HasAlpha != (d.Channels == 4) {
+		return nil, fmt.Errorf("unsupported format: %d channels of %d bits", d.Channels, d.BitsPerSample)
+	}
+	w, h, stride, channels := int(d.Width), int(d.Height), int(d.Rowstride), int(d.Channels)
+	if w <= 0 || h <= 0 || stride < w*channels || len(d.Data) < stride*(h-1)+w*channels {
+		return nil, errors.New("size does not match the data")
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			src := d.Data[y*stride+x*channels:]
+			dst := img.Pix[y*img.Stride+x*4:]
+			copy(dst[:3], src[:3])
+			dst[3] = 0xff
+			if channels == 4 {
+				dst[3] = src[3]
+			}
+		}
+	}
+	return img, nil
+}
+
+// WithImageHintKey sets the name of the hint holding the images embedded
+// with SetImage: "icon_data", "image_data" or "image-data", regardless of
+// the version of the specification the daemon reports, for old daemons
+// that report it wrong or not at all. Image paths are sent with the hint of
+// the same version. The default, "auto", picks the name from the version.
+func WithImageHintKey(key string) Option {
+	return func(nf *Notifier) error {
+		switch key {
+		case "auto":
+			nf.imageSpec = nil
+		case imageHintKey(1, 0):
+			nf.imageSpec = &[2]int{1, 0}
+		case imageHintKey(1, 1):
+			nf.imageSpec = &[2]int{1, 1}
+		case imageHintKey(1, 2):
+			nf.imageSpec = &[2]int{1, 2}
+		default:
+			return fmt.Errorf("notify: invalid image hint key %q", key)
+		}
+		return nil
+	}
+}
+
+// imageHintKeys are the names the raw image hint had, the latest first.
+var imageHintKeys = []string{imageHintKey(1, 2), imageHintKey(1, 1), imageHintKey(1, 0)}
+
+// imageHintKey returns the name of the hint holding raw image data for a
+// daemon implementing version major.minor of the specification. It was
+// renamed twice: "icon_data" before 1.1, "image_data" in 1.1, and
+// "image-data" since 1.2.
+func imageHintKey(major, minor int) string {
+	return specLevel(major, minor).ImageDataHint
+}
+
+// imagePathHintKey returns the name of the hint holding the path of an image
+// for a daemon implementing version major.minor of the specification. It was
+// "image_path" before 1.2, and is "image-path" since.
+func imagePathHintKey(major, minor int) string {
+	return specLevel(major, minor).ImagePathHint
+}
+
+// checkImagePath returns an error if path, a file path or a file:// URI,
+// does not name an existing file. Other URIs and themed icon names are not
+// checked.
+func checkImagePath(path string) error {
+	name := path
+	if strings.HasPrefix(path, "file://") {
+		u, err := url.Parse(path)
+		if err != nil {
+			return fmt.Errorf("notify: invalid image path %q: %w", path, err)
+		}
+		name = u.Path
+	} else if !strings.HasPrefix(path, "/") {
+		return nil
+	}
+
+	if fi, err := os.Stat(name); err != nil {
+		return fmt.Errorf("notify: invalid image path: %w", err)
+	} else if fi.IsDir() {
+		return fmt.Errorf("notify: invalid image path %q: is a directory", path)
+	}
+	return nil
+}
@@ -0,0 +1,381 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestImageHintKeyBySpecVersion(t *testing.T) {
+	tests := []struct {
+		spec         string
+		major, minor int
+		key          string
+	}{
+		{"1.0", 1, 0, "icon_data"},
+		{"1.1", 1, 1, "image_data"},
+		{"1.2", 1, 2, "image-data"},
+		{"garbage", 1, 2, "image-data"},
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(1, 1, color.RGBA{255, 0, 0, 255})
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s := newFakeServer(t)
+			s.setSpecVersion(tt.spec)
+
+			nf := newTestNotifier(t)
+
+			major, minor, err := nf.SpecVersion()
+			if err != nil {
+				t.Fatal(err)
+			} else if major != tt.major || minor != tt.minor {
+				t.Errorf("SpecVersion() = %d.%d, want %d.%d", major, minor, tt.major, tt.minor)
+			}
+
+			n := notify.New("test", "image", "", "", time.Second, notify.NormalUrgency)
+			n.SetImage(img)
+			if _, err := nf.Notify(n); err != nil {
+				t.Fatal(err)
+			}
+			hints := s.last(t).Hints
+			v, ok := hints[tt.key]
+			if !ok {
+				t.Fatalf("no %q hint in %v", tt.key, hints)
+			} else if sig := v.Signature().String(); sig != "(iiibiiay)" {
+				t.Errorf("image hint has signature %s", sig)
+			}
+			if len(hints) != 2 {
+				t.Errorf("unexpected hints %v", hints)
+			}
+		})
+	}
+}
+
+func TestAppIconAndImage(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithAppIcon("mail-client"))
+
+	n := notify.New("mail", "New mail", "", "", time.Second, notify.NormalUrgency)
+	n.SetImage(image.NewGray(image.Rect(0, 0, 4, 4)))
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if got.AppIcon != "mail-client" {
+		t.Errorf("app_icon = %q, want the default of the Notifier", got.AppIcon)
+	}
+	if _, ok := got.Hints["image-data"]; !ok {
+		t.Error("no image-data hint next to the app icon")
+	}
+
+	n.IconPath = "avatar"
+	n.SetImage(nil)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	got = s.last(t)
+	if got.AppIcon != "avatar" {
+		t.Errorf("app_icon = %q, want the icon path of the notification", got.AppIcon)
+	}
+	if _, ok := got.Hints["image-data"]; ok {
+		t.Error("image-data hint still sent after SetImage(nil)")
+	}
+}
+
+func TestImagePath(t *testing.T) {
+	s := newFakeServer(t)
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithLogger(logs.log))
+
+	n := notify.New("player", "Now playing", "", "audio-player", time.Second, notify.NormalUrgency)
+	n.ImagePath = "/music/cover.png"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if v := got.Hints["image-path"].Value(); v != "/music/cover.png" || got.AppIcon != "audio-player" {
+		t.Errorf("image-path hint = %v with app icon %q", v, got.AppIcon)
+	}
+
+	n.SetImage(image.NewGray(image.Rect(0, 0, 1, 1)))
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	got = s.last(t)
+	if _, ok := got.Hints["image-path"]; ok {
+		t.Error("image-path hint sent along with an embedded image")
+	}
+	if _, ok := got.Hints["image-data"]; !ok {
+		t.Error("embedded image not sent")
+	}
+	if logs.len() != 1 {
+		t.Errorf("logged %q, want a warning about the dropped image path", logs.msgs)
+	}
+}
+
+func TestImagePathSpec11(t *testing.T) {
+	s := newFakeServer(t)
+	s.setSpecVersion("1.1")
+	nf := newTestNotifier(t)
+
+	n := notify.New("player", "Now playing", "", "", time.Second, notify.NormalUrgency)
+	n.ImagePath = "file:///music/cover.png"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.last(t).Hints["image_path"]; !ok {
+		t.Errorf("no image_path hint in %v", s.last(t).Hints)
+	}
+}
+
+func TestImagePathCheck(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithImagePathCheck(true))
+
+	n := notify.New("player", "Now playing", "", "", time.Second, notify.NormalUrgency)
+	n.ImagePath = filepath.Join(t.TempDir(), "missing.png")
+	if _, err := nf.Notify(n); err == nil {
+		t.Error("missing image path accepted")
+	}
+
+	n.ImagePath = filepath.Join(t.TempDir(), "cover.png")
+	if err := os.WriteFile(n.ImagePath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.Notify(n); err != nil {
+		t.Error(err)
+	}
+	if len(s.notifications()) != 1 {
+		t.Errorf("sent %d notifications, want 1", len(s.notifications()))
+	}
+}
+
+// uniformImage returns a w×h image of the color c.
+func uniformImage(w, h int, c color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestSetImageDownscales(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	red := color.NRGBA{R: 200, A: 255}
+	tests := []struct {
+		name string
+		w, h int
+		opts []notify.ImageOption
+		size int
+	}{
+		{"default", 1200, 600, nil, 128 * 64 * 4},
+		{"small", 32, 16, nil, 32 * 16 * 4},
+		{"bilinear", 600, 1200, []notify.ImageOption{notify.ImageResampling(notify.ImageBilinear)}, 64 * 128 * 4},
+		{"max", 1200, 600, []notify.ImageOption{notify.ImageMaxDimension(300)}, 300 * 150 * 4},
+		{"unlimited", 200, 100, []notify.ImageOption{notify.ImageMaxDimension(0)}, 200 * 100 * 4},
+		{"opaque", 1200, 600, []notify.ImageOption{notify.ImageForceOpaque()}, 128 * 64 * 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := notify.New("test", "image", "", "", time.Second, notify.NormalUrgency)
+			n.SetImage(uniformImage(tt.w, tt.h, red), tt.opts...)
+			c, err := n.DryRun(nf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.ImageSize(); got != tt.size {
+				t.Errorf("ImageSize() = %d, want %d", got, tt.size)
+			}
+			img, err := notify.DecodeImage(c.Hints)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := color.NRGBAModel.Convert(img.At(3, 3)); got != red {
+				t.Errorf("pixel = %v, want %v", got, red)
+			}
+		})
+	}
+}
+
+// patterned calls set with a different color for each pixel of the w×h
+// rectangle at x0, y0.
+func patterned(x0, y0, w, h int, set func(x, y int, c color.NRGBA)) {
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			set(x, y, color.NRGBA{uint8(x * 37), uint8(y * 59), uint8(x*y + 11), uint8(128 + x%2*127)})
+		}
+	}
+}
+
+func TestSetImageAnyImage(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, 20, 16), image.YCbCrSubsampleRatio420)
+	for i := range ycbcr.Y {
+		ycbcr.Y[i] = uint8(i * 7)
+	}
+	for i := range ycbcr.Cb {
+		ycbcr.Cb[i], ycbcr.Cr[i] = uint8(i*13), uint8(255-i*5)
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, 12, 10))
+	patterned(0, 0, 12, 10, func(x, y int, c color.NRGBA) { rgba.Set(x, y, c) })
+	nrgba := image.NewNRGBA(image.Rect(-4, -4, 8, 8))
+	patterned(-4, -4, 12, 12, nrgba.SetNRGBA)
+	gray := image.NewGray(image.Rect(0, 0, 9, 7))
+	patterned(0, 0, 9, 7, func(x, y int, c color.NRGBA) { gray.Set(x, y, c) })
+	gray16 := image.NewGray16(image.Rect(2, 3, 7, 9))
+	patterned(2, 3, 5, 6, func(x, y int, c color.NRGBA) { gray16.Set(x, y, c) })
+	paletted := image.NewPaletted(image.Rect(1, 1, 4, 4), color.Palette{color.Black, color.White, color.NRGBA{R: 255, A: 128}})
+	for i := range paletted.Pix {
+		paletted.Pix[i] = uint8(i % 3)
+	}
+
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{"YCbCr 4:2:0 offset", ycbcr.SubImage(image.Rect(3, 5, 17, 14))},
+		{"RGBA offset", rgba.SubImage(image.Rect(2, 1, 11, 9))},
+		{"NRGBA negative origin", nrgba},
+		{"Gray offset", gray.SubImage(image.Rect(1, 2, 8, 7))},
+		{"Gray16", gray16},
+		{"Paletted", paletted},
+		{"1×1", rgba.SubImage(image.Rect(5, 5, 6, 6))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := notify.New("test", "image", "", "", time.Second, notify.NormalUrgency)
+			n.SetImage(tt.img, notify.ImageMaxDimension(0))
+			c, err := n.DryRun(nf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := notify.DecodeImage(c.Hints)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := tt.img.Bounds()
+			if got.Bounds() != image.Rect(0, 0, b.Dx(), b.Dy()) {
+				t.Fatalf("bounds = %v, want %v at the origin", got.Bounds(), b)
+			}
+			for y := 0; y < b.Dy(); y++ {
+				for x := 0; x < b.Dx(); x++ {
+					want := color.NRGBAModel.Convert(tt.img.At(b.Min.X+x, b.Min.Y+y))
+					if p := got.At(x, y); p != want {
+						t.Fatalf("pixel %d,%d = %v, want %v", x, y, p, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSetImageEmpty(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	n := notify.New("test", "image", "", "", time.Second, notify.NormalUrgency)
+	n.SetImage(image.NewRGBA(image.Rect(5, 5, 5, 10)))
+	if err := n.Validate(); !errors.Is(err, notify.ErrEmptyImage) {
+		t.Errorf("Validate = %v, want ErrEmptyImage", err)
+	}
+	if _, err := nf.Notify(n); !errors.Is(err, notify.ErrEmptyImage) {
+		t.Errorf("Notify = %v, want ErrEmptyImage", err)
+	}
+	n.SetImage(nil)
+	if _, err := nf.Notify(n); err != nil {
+		t.Errorf("Notify without the image = %v", err)
+	}
+}
+
+func TestOpaqueImagesQuirk(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithQuirks(notify.Quirks{OpaqueImages: true}))
+
+	n := notify.New("test", "image", "", "", time.Second, notify.NormalUrgency)
+	n.SetImage(uniformImage(4, 4, color.NRGBA{G: 100, A: 50}))
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	img, err := notify.DecodeImage(s.last(t).Hints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.At(0, 0); got != (color.NRGBA{G: 100, A: 255}) {
+		t.Errorf("pixel = %v, want it opaque", got)
+	}
+}
+
+func TestSetImageFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, uniformImage(256, 256, color.White)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	n := notify.New("test", "image", "", "", time.Second, notify.NormalUrgency)
+	if err := n.SetImageFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	c, err := n.DryRun(nf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ImageSize(); got != 128*128*4 {
+		t.Errorf("ImageSize() = %d, want a 128×128 image", got)
+	}
+	if err := n.SetImageFromFile(filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Error("SetImageFromFile of a missing file succeeded")
+	}
+}
+
+func TestWithImageHintKey(t *testing.T) {
+	auto := map[string]string{"1.0": "icon_data", "1.1": "image_data", "1.2": "image-data"}
+	for _, key := range []string{"icon_data", "image_data", "image-data", "auto"} {
+		for _, spec := range []string{"1.0", "1.1", "1.2"} {
+			t.Run(key+"/"+spec, func(t *testing.T) {
+				s := newFakeServer(t)
+				s.setSpecVersion(spec)
+				nf := newTestNotifier(t, notify.WithImageHintKey(key))
+
+				n := notify.New("test", "image", "", "", time.Second, notify.NormalUrgency)
+				n.SetImage(image.NewRGBA(image.Rect(0, 0, 1, 1)))
+				c, err := n.DryRun(nf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := key
+				if key == "auto" {
+					want = auto[spec]
+				}
+				if got := c.ImageHintKey(); got != want {
+					t.Errorf("ImageHintKey() = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+
+	if _, err := notify.NewNotifier(notify.WithImageHintKey("image")); err == nil {
+		t.Error("WithImageHintKey accepted an unknown key")
+	}
+}
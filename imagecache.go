@@ -0,0 +1,275 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// DefaultImageCacheSize is the size of the image files kept by
+// WithImageCache when given a size of 0.
+const DefaultImageCacheSize = 8 << 20
+
+// ImageCacheStats are the counters of the image cache, see WithImageCache.
+type ImageCacheStats struct {
+	// Entries is the number of images cached, and Bytes the size of their
+	// files.
+	Entries int
+	Bytes   int64
+	// Hits is the number of sends of a cached image as an image path, and
+	// Misses the number of sends embedding it.
+	Hits   uint64
+	Misses uint64
+	// Evictions is the number of images removed to keep the size of the
+	// cache, the least recently sent first.
+	Evictions uint64
+	// BytesSaved is the size of the image data not sent thanks to the
+	// cache.
+	BytesSaved uint64
+}
+
+// WithImageCache makes the Notifier cache the images embedded with SetImage,
+// like avatars sent again and again, by content. Once an image was sent
+// embedded, it is written to a temporary file, whose path is sent instead
+// the next times: a file:// URI in the image path hint is tiny, while a raw
+// 128×128 image weighs 64 KB on the bus.
+//
+// The image is embedded again if the file was removed, or once for a new
+// daemon. Daemons that do not read image files, see Quirks.NoImageFiles,
+// always get the image embedded. The files are kept up to maxBytes, the
+// least recently sent ones being removed first, or DefaultImageCacheSize if
+// maxBytes is 0, and removed when the Notifier is closed. See
+// Stats.ImageCache for the counters.
+func WithImageCache(maxBytes int64) Option {
+	return func(nf *Notifier) error {
+		if maxBytes < 0 {
+			return errors.New("notify: negative image cache size")
+		}
+		if maxBytes == 0 {
+			maxBytes = DefaultImageCacheSize
+		}
+		nf.images = &imageCache{max: maxBytes, lru: list.New(), byHash: make(map[string]*list.Element)}
+		return nil
+	}
+}
+
+// imageCache holds image files by the hash of their content, the most
+// recently sent first.
+type imageCache struct {
+	mu     sync.Mutex
+	max    int64
+	dir    string
+	lru    *list.List
+	byHash map[string]*list.Element
+	size   int64
+	stats  ImageCacheStats
+	// gen is the generation of the daemon; the images of another one were
+	// not sent embedded to the current daemon.
+	gen uint64
+}
+
+// cachedImage is an image file of an imageCache.
+type cachedImage struct {
+	hash string
+	path string
+	size int64
+	gen  uint64
+}
+
+// imageHash returns the hash of d, sent without its alpha channel if opaque.
+func imageHash(d *imageData, opaque bool) string {
+	h := sha256.New()
+	var head [17]byte
+	binary.LittleEndian.PutUint32(head[0:], uint32(d.Width))
+	binary.LittleEndian.PutUint32(head[4:], uint32(d.Height))
+	binary.LittleEndian.PutUint32(head[8:], uint32(d.Rowstride))
+	binary.LittleEndian.PutUint32(head[12:], uint32(d.Channels))
+	if opaque {
+		head[16] = 1
+	}
+	h.Write(head[:])
+	h.Write(d.Data)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// lookup returns the path of the file of d sent embedded to the current
+// daemon, or "" if there is none.
+func (c *imageCache) lookup(d *imageData, opaque bool) string {
+	hash := imageHash(d, opaque)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.byHash[hash]
+	if !ok {
+		return ""
+	}
+	img := e.Value.(*cachedImage)
+	if _, err := os.Stat(img.path); err != nil {
+		// Embed it again.
+		c.remove(e)
+		return ""
+	}
+	if img.gen != c.gen {
+		return ""
+	}
+	return img.path
+}
+
+// hit records that the file of d was sent in place of its data.
+func (c *imageCache) hit(d *imageData, p string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		if e.Value.(*cachedImage).path == p {
+			c.lru.MoveToFront(e)
+			c.stats.Hits++
+			c.stats.BytesSaved += uint64(len(d.Data))
+			return
+		}
+	}
+}
+
+// store writes d, sent embedded to the current daemon, to its file, and
+// evicts the least recently sent images over the size of c.
+func (c *imageCache) store(d *imageData, opaque bool) error {
+	hash := imageHash(d, opaque)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Misses++
+	if e, ok := c.byHash[hash]; ok {
+		img := e.Value.(*cachedImage)
+		if _, err := os.Stat(img.path); err == nil {
+			img.gen = c.gen
+			c.lru.MoveToFront(e)
+			return nil
+		}
+		c.remove(e)
+	}
+
+	if opaque {
+		d = d.opaque()
+	}
+	img, err := d.decode()
+	if err != nil {
+		return err
+	}
+	if c.dir == "" {
+		dir, err := os.MkdirTemp("", tempDirPrefix+strconv.Itoa(os.Getpid())+"-")
+		if err != nil {
+			return err
+		}
+		c.dir = dir
+	} else if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+	p := filepath.Join(c.dir, hash+".png")
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		os.Remove(p)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(p)
+		return err
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	c.byHash[hash] = c.lru.PushFront(&cachedImage{hash: hash, path: p, size: info.Size(), gen: c.gen})
+	c.size += info.Size()
+	for c.size > c.max && c.lru.Len() > 1 {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+	return nil
+}
+
+// remove removes the image of e and its file. c.mu must be held.
+func (c *imageCache) remove(e *list.Element) {
+	img := c.lru.Remove(e).(*cachedImage)
+	delete(c.byHash, img.hash)
+	c.size -= img.size
+	os.Remove(img.path)
+}
+
+// newDaemon records that the daemon changed, so that the images are sent
+// embedded to it once again.
+func (c *imageCache) newDaemon() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+}
+
+// counters returns the statistics of c.
+func (c *imageCache) counters() ImageCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries, s.Bytes = c.lru.Len(), c.size
+	return s
+}
+
+// removeAll removes the image files and their directory.
+func (c *imageCache) removeAll() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(c.dir)
+	c.dir, c.size = "", 0
+	c.lru.Init()
+	c.byHash = make(map[string]*list.Element)
+	if err != nil {
+		return fmt.Errorf("notify: removing the cached images: %w", err)
+	}
+	return nil
+}
+
+// imageFile returns the path of the cached file to send in place of the
+// image d, or "" to embed it.
+func (nf *Notifier) imageFile(d *imageData, opaque bool) string {
+	if nf.images == nil || !nf.onBus() || nf.daemonQuirks().NoImageFiles {
+		return ""
+	}
+	return nf.images.lookup(d, opaque)
+}
+
+// cacheImage records the send of the image of n with c, unless the hints
+// dropped include it.
+func (nf *Notifier) cacheImage(n *Notification, c Call, dropped []string) {
+	if nf.images == nil || n.image == nil {
+		return
+	}
+	for _, k := range dropped {
+		if imageHints[k] {
+			return
+		}
+	}
+	if c.imageFile != "" {
+		nf.images.hit(n.image, c.imageFile)
+		return
+	}
+	if c.ImageHintKey() == "" || !nf.onBus() || nf.daemonQuirks().NoImageFiles {
+		return
+	}
+	if err := nf.images.store(n.image, c.imageOpaque); err != nil {
+		nf.log(LevelWarn, "caching the image failed, it is sent embedded again", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+// avatar returns a notification with a 16×16 image of the color c.
+func avatar(c color.NRGBA) *notify.Notification {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	n := notify.New("chat", "new message", "", "", 0, notify.NormalUrgency)
+	n.SetImage(img)
+	return n
+}
+
+// sentImage returns the image file of the last notification of s, or ""
+// if its image was embedded.
+func sentImage(t *testing.T, s *fakeServer) string {
+	t.Helper()
+	hints := s.last(t).Hints
+	if v, ok := hints["image-path"]; ok {
+		return strings.TrimPrefix(v.Value().(string), "file://")
+	}
+	if _, ok := hints["image-data"]; !ok {
+		t.Fatalf("no image sent: %v", hints)
+	}
+	return ""
+}
+
+func TestImageCache(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithImageCache(0))
+	red := color.NRGBA{0xff, 0, 0, 0xff}
+
+	send := func(n *notify.Notification) string {
+		t.Helper()
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		return sentImage(t, s)
+	}
+	if p := send(avatar(red)); p != "" {
+		t.Fatalf("first send sent the file %s, want the image embedded", p)
+	}
+	p := send(avatar(red))
+	if p == "" {
+		t.Fatal("second send embedded the image, want its file")
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	} else if got := color.NRGBAModel.Convert(img.At(3, 3)); got != red {
+		t.Errorf("cached image pixel = %v, want %v", got, red)
+	}
+	st := nf.Stats().ImageCache
+	if st.Entries != 1 || st.Hits != 1 || st.Misses != 1 || st.BytesSaved != 16*16*4 || st.Bytes <= 0 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	// A removed file is embedded again.
+	os.Remove(p)
+	if p := send(avatar(red)); p != "" {
+		t.Errorf("send after the file was removed sent %s", p)
+	}
+	if p := send(avatar(red)); p == "" {
+		t.Error("the image is not cached again")
+	}
+
+	// So is the image for a new daemon.
+	nf.InvalidateCaches()
+	if p := send(avatar(red)); p != "" {
+		t.Errorf("send to a new daemon sent %s", p)
+	}
+	if p = send(avatar(red)); p == "" {
+		t.Error("the image is not sent as a file to the new daemon")
+	}
+
+	if err := nf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("cached file after Close: %v", err)
+	}
+}
+
+func TestImageCacheEviction(t *testing.T) {
+	s := newFakeServer(t)
+	// Too small for two images.
+	nf := newTestNotifier(t, notify.WithImageCache(1))
+	red, blue := color.NRGBA{0xff, 0, 0, 0xff}, color.NRGBA{0, 0, 0xff, 0xff}
+
+	for _, c := range []color.NRGBA{red, blue, red} {
+		if _, err := nf.Notify(avatar(c)); err != nil {
+			t.Fatal(err)
+		}
+		if p := sentImage(t, s); p != "" {
+			t.Errorf("send of %v sent %s, want it embedded", c, p)
+		}
+	}
+	if st := nf.Stats().ImageCache; st.Entries != 1 || st.Evictions != 2 || st.Hits != 0 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	if _, err := notify.NewNotifier(notify.WithImageCache(-1)); err == nil {
+		t.Error("WithImageCache(-1) did not fail")
+	}
+}
+
+func TestImageCacheNoImageFiles(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithImageCache(0), notify.WithQuirks(notify.Quirks{NoImageFiles: true}))
+	for i := 0; i < 2; i++ {
+		if _, err := nf.Notify(avatar(color.NRGBA{0, 0xff, 0, 0xff})); err != nil {
+			t.Fatal(err)
+		}
+		if p := sentImage(t, s); p != "" {
+			t.Errorf("send %d sent %s to a daemon without image files", i, p)
+		}
+	}
+}
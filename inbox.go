@@ -0,0 +1,276 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// InboxFile is the name of the file an InboxWriter appends to. The rotated
+// files are named after it, InboxFile.1 being the most recent one.
+const InboxFile = "inbox.ndjson"
+
+// InboxSync is when an InboxWriter flushes its file to disk.
+type InboxSync int
+
+const (
+	// InboxSyncNever leaves flushing to the operating system.
+	InboxSyncNever InboxSync = iota
+	// InboxSyncRotate flushes each file when it is rotated.
+	InboxSyncRotate
+	// InboxSyncEach flushes the file after each record.
+	InboxSyncEach
+)
+
+// InboxWriter is a Transport appending each call to a file in Dir, one JSON
+// record per line, so that what users were told can be read back with
+// ReadInbox. It is meant to mirror the transport of a Notifier:
+//
+//	nf, err := notify.NewNotifier(notify.WithMirrors(notify.NewInboxWriter(dir)))
+//
+// The calls are recorded as sent, after the Redactor and the sensitive
+// policy. Each record is written at once, so that readers never see a
+// record of another one mixed in. InboxWriter assigns its own IDs, and
+// closing notifications does nothing.
+type InboxWriter struct {
+	// Dir is the directory of the files, created if missing.
+	Dir string
+	// MaxSize is the size in bytes after which the file is rotated, or 0
+	// to never rotate it. A record larger than MaxSize gets a file of its
+	// own.
+	MaxSize int64
+	// MaxFiles is the number of rotated files kept; the older ones are
+	// removed.
+	MaxFiles int
+	// Sync is when the file is flushed to disk.
+	Sync InboxSync
+	// Clock tells the time of the records, the real time if nil.
+	Clock Clock
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	lastID atomic.Uint32
+}
+
+// NewInboxWriter returns an InboxWriter appending to the inbox in dir,
+// without rotation.
+func NewInboxWriter(dir string) *InboxWriter {
+	return &InboxWriter{Dir: dir}
+}
+
+func (w *InboxWriter) Notify(ctx context.Context, c Call) (uint32, error) {
+	id := c.ReplacesID
+	if id == 0 {
+		id = w.lastID.Add(1)
+	}
+	now := time.Now()
+	if w.Clock != nil {
+		now = w.Clock.Now()
+	}
+	category, _ := c.Hints["category"].Value().(string)
+	line, err := json.Marshal(jsonRecord{
+		CorrelationID: c.CorrelationID,
+		ID:            id,
+		AppName:       c.AppName,
+		Summary:       c.Summary,
+		Body:          c.Body,
+		Urgency:       c.Urgency,
+		Category:      category,
+		SentAt:        now,
+		UpdatedAt:     now,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("notify: %w", err)
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.write(line); err != nil {
+		return 0, fmt.Errorf("notify: inbox: %w", err)
+	}
+	return id, nil
+}
+
+// write appends line to the file of w, rotating it first if line does not
+// fit. It must be called with w.mu held.
+func (w *InboxWriter) write(line []byte) error {
+	if w.f != nil && w.MaxSize > 0 && w.size > 0 && w.size+int64(len(line)) > w.MaxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	if w.f == nil {
+		if err := os.MkdirAll(w.Dir, 0o700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(filepath.Join(w.Dir, InboxFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.f, w.size = f, info.Size()
+		if w.MaxSize > 0 && w.size > 0 && w.size+int64(len(line)) > w.MaxSize {
+			// Left over by a previous run.
+			return w.write(line)
+		}
+	}
+	n, err := w.f.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if w.Sync == InboxSyncEach {
+		return w.f.Sync()
+	}
+	return nil
+}
+
+// rotate closes the file of w, and shifts it and the rotated files by one,
+// removing the oldest. It must be called with w.mu held.
+func (w *InboxWriter) rotate() error {
+	if w.Sync != InboxSyncNever {
+		w.f.Sync()
+	}
+	err := w.f.Close()
+	w.f = nil
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(w.Dir, InboxFile)
+	if err := os.Remove(path + "." + strconv.Itoa(w.MaxFiles)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := w.MaxFiles - 1; i >= 1; i-- {
+		err := os.Rename(path+"."+strconv.Itoa(i), path+"."+strconv.Itoa(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if w.MaxFiles == 0 {
+		return os.Remove(path)
+	}
+	return os.Rename(path, path+".1")
+}
+
+// CloseNotification does nothing: the records stay.
+func (w *InboxWriter) CloseNotification(id uint32) error {
+	return nil
+}
+
+// Close closes the file of w. Writing again reopens it.
+func (w *InboxWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	if w.Sync != InboxSyncNever {
+		w.f.Sync()
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// ReadInbox returns the records written by an InboxWriter in dir, including
+// its rotated files, the oldest first. Each call is a record, so a
+// notification replaced several times has several records; only the fields
+// of Record that InboxWriter writes are set.
+//
+// The lines that are not valid records, such as the last one of a file
+// written when the program crashed, are skipped.
+func ReadInbox(dir string) ([]Record, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	// The rotated files, by number, the oldest first, then the current one.
+	var rotated []int
+	current := false
+	for _, e := range entries {
+		name := e.Name()
+		if name == InboxFile {
+			current = true
+		} else if suffix, ok := strings.CutPrefix(name, InboxFile+"."); ok {
+			if i, err := strconv.Atoi(suffix); err == nil && i > 0 {
+				rotated = append(rotated, i)
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(rotated)))
+	var names []string
+	for _, i := range rotated {
+		names = append(names, InboxFile+"."+strconv.Itoa(i))
+	}
+	if current {
+		names = append(names, InboxFile)
+	}
+
+	var records []Record
+	for _, name := range names {
+		f, err := os.Open(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			// Rotated away meanwhile.
+			continue
+		} else if err != nil {
+			return records, err
+		}
+		records, err = readInboxFile(f, records)
+		f.Close()
+		if err != nil {
+			return records, err
+		}
+	}
+	return records, nil
+}
+
+// readInboxFile appends the records of f to records.
+func readInboxFile(f *os.File, records []Record) ([]Record, error) {
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var j jsonRecord
+			if json.Unmarshal(line, &j) == nil && !j.SentAt.IsZero() {
+				records = append(records, Record{
+					CorrelationID: j.CorrelationID,
+					ID:            j.ID,
+					AppName:       j.AppName,
+					Summary:       j.Summary,
+					Body:          j.Body,
+					Urgency:       j.Urgency,
+					Category:      j.Category,
+					SentAt:        j.SentAt,
+					UpdatedAt:     j.UpdatedAt,
+				})
+			}
+		}
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+// inboxSend sends summaries through w.
+func inboxSend(t *testing.T, w *notify.InboxWriter, summaries ...string) {
+	t.Helper()
+	for _, s := range summaries {
+		if _, err := w.Notify(context.Background(), notify.Call{AppName: "test", Summary: s}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// inboxSummaries returns the summaries of the records in dir.
+func inboxSummaries(t *testing.T, dir string) []string {
+	t.Helper()
+	records, err := notify.ReadInbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range records {
+		got = append(got, r.Summary)
+	}
+	return got
+}
+
+func TestInboxRotation(t *testing.T) {
+	// Measure a record: all of them have the same size.
+	dir := t.TempDir()
+	w := &notify.InboxWriter{Dir: dir, Clock: newFakeClock()}
+	inboxSend(t, w, "1")
+	info, err := os.Stat(filepath.Join(dir, notify.InboxFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := info.Size()
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		files   []string
+		want    []string
+	}{
+		{"exactly full", 3 * size, []string{notify.InboxFile}, []string{"1", "2", "3"}},
+		{"one byte short", 3*size - 1, []string{notify.InboxFile, notify.InboxFile + ".1"}, []string{"1", "2", "3"}},
+		{"oldest removed", size, []string{notify.InboxFile, notify.InboxFile + ".1", notify.InboxFile + ".2"}, []string{"1", "2", "3"}},
+		{"larger records", size / 2, []string{notify.InboxFile, notify.InboxFile + ".1", notify.InboxFile + ".2"}, []string{"1", "2", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w := &notify.InboxWriter{Dir: dir, MaxSize: tt.maxSize, MaxFiles: 2, Clock: newFakeClock()}
+			inboxSend(t, w, "1", "2", "3")
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			if strings.Join(files, " ") != strings.Join(tt.files, " ") {
+				t.Errorf("files %v, want %v", files, tt.files)
+			}
+			if got := inboxSummaries(t, dir); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("read %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Past MaxFiles, the oldest records are gone.
+	dir = t.TempDir()
+	w = &notify.InboxWriter{Dir: dir, MaxSize: size, MaxFiles: 1, Clock: newFakeClock()}
+	inboxSend(t, w, "1", "2", "3", "4")
+	if got := inboxSummaries(t, dir); strings.Join(got, " ") != "3 4" {
+		t.Errorf("read %v, want [3 4]", got)
+	}
+}
+
+func TestInboxReopen(t *testing.T) {
+	dir := t.TempDir()
+	w := &notify.InboxWriter{Dir: dir, Clock: newFakeClock()}
+	inboxSend(t, w, "1")
+	w.Close()
+	w = &notify.InboxWriter{Dir: dir, Sync: notify.InboxSyncEach, Clock: newFakeClock()}
+	inboxSend(t, w, "2")
+	if got := inboxSummaries(t, dir); strings.Join(got, " ") != "1 2" {
+		t.Errorf("read %v after reopening, want [1 2]", got)
+	}
+}
+
+func TestReadInboxCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	w := &notify.InboxWriter{Dir: dir, Clock: newFakeClock()}
+	inboxSend(t, w, "1")
+	w.Close()
+	f, err := os.OpenFile(filepath.Join(dir, notify.InboxFile), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("not json\n{\"unrelated\": true}\n\n")
+	f.Close()
+	w = &notify.InboxWriter{Dir: dir, Clock: newFakeClock()}
+	inboxSend(t, w, "2")
+	w.Close()
+	// A record cut short by a crash.
+	data, err := os.ReadFile(filepath.Join(dir, notify.InboxFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := strings.LastIndex(string(data[:len(data)-1]), "\n")
+	os.WriteFile(filepath.Join(dir, notify.InboxFile), append(data, data[last+1:len(data)-8]...), 0o600)
+
+	if got := inboxSummaries(t, dir); strings.Join(got, " ") != "1 2" {
+		t.Errorf("read %v, want [1 2]", got)
+	}
+}
+
+func TestInboxRedacted(t *testing.T) {
+	newFakeServer(t)
+	dir := t.TempDir()
+	w := notify.NewInboxWriter(dir)
+	redact := func(summary, body string) (string, string) {
+		return summary, strings.ReplaceAll(body, "hunter2", "***")
+	}
+	nf := newTestNotifier(t, notify.WithMirrors(w), notify.WithRedactor(redact))
+	n := notify.New("test", "Password changed", "new password: hunter2", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	records, err := notify.ReadInbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Body != "new password: ***" || records[0].AppName != "test" {
+		t.Errorf("read %+v, want the redacted notification", records)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, notify.InboxFile))
+	if strings.Contains(string(data), "hunter2") {
+		t.Error("the inbox holds the unredacted body")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultMaxInFlight is the default number of D-Bus calls to the daemon a
+// Notifier waits for at the same time, see WithMaxInFlight.
+const DefaultMaxInFlight = 16
+
+// WithMaxInFlight makes the Notifier wait for the replies to at most max
+// D-Bus calls to the daemon at the same time, instead of
+// DefaultMaxInFlight. Further sends and closes wait for a call to be
+// answered, or for their context to be done, so that hundreds of
+// asynchronous sends do not pile up in the outgoing queue of the
+// connection. The Notifiers of a Core share the limit of the Core.
+//
+// The limit applies to the sends and closes of the Notifier; RawNotify and
+// RawNotifyAsync are not limited.
+func WithMaxInFlight(max int) Option {
+	return func(nf *Notifier) error {
+		if max <= 0 {
+			return fmt.Errorf("notify: invalid maximum of in-flight calls %d", max)
+		}
+		nf.maxInFlight = max
+		return nil
+	}
+}
+
+// inFlight limits the D-Bus calls waiting for a reply at the same time.
+type inFlight struct {
+	mu sync.Mutex
+	// slots holds a value per call in flight, up to its capacity.
+	slots   chan struct{}
+	current int
+	peak    int
+}
+
+// inFlight returns the limit of the calls of the Notifiers sharing the
+// connection of nf.
+func (nf *Notifier) inFlight() *inFlight {
+	if nf.parent != nil {
+		return nf.parent.inFlight()
+	}
+	nf.calls.mu.Lock()
+	defer nf.calls.mu.Unlock()
+	if nf.calls.slots == nil {
+		max := nf.maxInFlight
+		if max == 0 {
+			max = DefaultMaxInFlight
+		}
+		nf.calls.slots = make(chan struct{}, max)
+	}
+	return &nf.calls
+}
+
+// acquire waits for a call to be allowed in flight, or for ctx to be done.
+func (f *inFlight) acquire(ctx context.Context) error {
+	select {
+	case f.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.current++
+	if f.current > f.peak {
+		f.peak = f.current
+	}
+	return nil
+}
+
+// release records that a call acquired was answered.
+func (f *inFlight) release() {
+	f.mu.Lock()
+	f.current--
+	f.mu.Unlock()
+	<-f.slots
+}
+
+// counts returns the number of calls in flight, and the highest so far.
+func (f *inFlight) counts() (current, peak int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.current, f.peak
+}
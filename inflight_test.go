@@ -0,0 +1,61 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestMaxInFlight(t *testing.T) {
+	s := newFakeServer(t)
+	release := s.stall(t)
+	nf := newTestNotifier(t, notify.WithMaxInFlight(3))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := nf.Notify(notify.New("test", fmt.Sprint("call ", i), "", "", 0, notify.NormalUrgency)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	waitFor(t, "the calls in flight", func() bool { return s.received() == 3 })
+	time.Sleep(50 * time.Millisecond)
+	if got := s.received(); got != 3 {
+		t.Errorf("the daemon received %d calls at the same time, want 3", got)
+	}
+	if st := nf.Stats(); st.InFlight != 3 {
+		t.Errorf("Stats.InFlight = %d, want 3", st.InFlight)
+	}
+
+	// Waiting for a slot can be cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := nf.SendContext(ctx, notify.New("test", "cancelled", "", "", 0, notify.NormalUrgency)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendContext while the calls are in flight = %v, want DeadlineExceeded", err)
+	}
+
+	release()
+	wg.Wait()
+	if got := s.received(); got != 10 {
+		t.Errorf("the daemon received %d calls, want 10", got)
+	}
+	if st := nf.Stats(); st.InFlight != 0 || st.PeakInFlight != 3 {
+		t.Errorf("Stats in flight = %d, peak %d, want 0, 3", st.InFlight, st.PeakInFlight)
+	}
+
+	if _, err := notify.NewNotifier(notify.WithMaxInFlight(0)); err == nil {
+		t.Error("WithMaxInFlight(0) accepted")
+	}
+}
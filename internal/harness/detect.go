@@ -0,0 +1,114 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package harness
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// iface is the D-Bus interface of the notification daemons.
+const iface = "org.freedesktop.Notifications"
+
+// Daemon is a notification daemon, as found by Detect.
+type Daemon struct {
+	// Name, Vendor, Version and SpecVersion are the answer of the daemon to
+	// GetServerInformation.
+	Name, Vendor, Version, SpecVersion string
+	// Owner is the unique bus name owning the notification service name.
+	Owner string
+	// Capabilities are the answer of the daemon to GetCapabilities.
+	Capabilities []string
+}
+
+// Has returns true if d has the capability name.
+func (d Daemon) Has(name string) bool {
+	for _, c := range d.Capabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the capabilities of names d does not have.
+func (d Daemon) Missing(names ...string) []string {
+	var missing []string
+	for _, name := range names {
+		if !d.Has(name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// Detect asks the daemon owning the service name dest on conn, with its
+// object at path, who it is and what it can do. It asks the daemon itself,
+// without the caches of the Notifiers, so that what it finds can be
+// compared with them.
+func Detect(ctx context.Context, conn *dbus.Conn, dest string, path dbus.ObjectPath) (Daemon, error) {
+	var d Daemon
+	if err := conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.GetNameOwner", 0, dest).Store(&d.Owner); err != nil {
+		return d, fmt.Errorf("no daemon owns %s: %w", dest, err)
+	}
+	obj := conn.Object(dest, path)
+	if err := obj.CallWithContext(ctx, iface+".GetServerInformation", 0).Store(&d.Name, &d.Vendor, &d.Version, &d.SpecVersion); err != nil {
+		return d, fmt.Errorf("GetServerInformation: %w", err)
+	}
+	if err := obj.CallWithContext(ctx, iface+".GetCapabilities", 0).Store(&d.Capabilities); err != nil {
+		return d, fmt.Errorf("GetCapabilities: %w", err)
+	}
+	sort.Strings(d.Capabilities)
+	return d, nil
+}
+
+// Report is the capability report of a daemon: who it is, its quirks, and
+// the results of the matrix run against it. Quirks are the JSON encoding of
+// the quirk entry of the daemon, in the format of the quirk files, so that
+// the report can back a fix of the quirk table.
+type Report struct {
+	Daemon  Daemon
+	Quirks  string
+	Known   bool
+	Results []Result
+}
+
+// OK returns true if no case failed.
+func (r Report) OK() bool {
+	for _, res := range r.Results {
+		if res.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTo writes r to w as text for people.
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	d := r.Daemon
+	fmt.Fprintf(&b, "daemon: %s %s (%s), specification %s, owner %s\n", d.Name, d.Version, d.Vendor, d.SpecVersion, d.Owner)
+	fmt.Fprintf(&b, "capabilities: %s\n", strings.Join(d.Capabilities, ", "))
+	if r.Known {
+		fmt.Fprintf(&b, "quirks: %s\n", r.Quirks)
+	} else {
+		fmt.Fprintf(&b, "quirks: no entry for %q, the defaults apply\n", d.Name)
+	}
+	WriteResults(&b, r.Results)
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// String returns r as written by WriteTo.
+func (r Report) String() string {
+	var b strings.Builder
+	r.WriteTo(&b)
+	return b.String()
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package harness runs checks against a notification daemon and reports what
+// worked: the steps of the self-test of a Notifier, and the matrix of the
+// integration tests run against the daemon of the session.
+package harness
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of a step or of a case of a matrix.
+type Result struct {
+	Name string
+	// Err is why the step failed, nil if it worked or was skipped.
+	Err error
+	// Skipped is true if the step did not run.
+	Skipped bool
+	// Detail tells what the step found, or why it was skipped.
+	Detail   string
+	Duration time.Duration
+}
+
+// OK returns true if the step ran and worked.
+func (r Result) OK() bool {
+	return r.Err == nil && !r.Skipped
+}
+
+// Status returns "ok", "FAILED" or "skipped".
+func (r Result) Status() string {
+	switch {
+	case r.Err != nil:
+		return "FAILED"
+	case r.Skipped:
+		return "skipped"
+	}
+	return "ok"
+}
+
+// Runner runs steps in turn and records their Results.
+type Runner struct {
+	// Prefix is put before the name of the first step failing in the error
+	// of Err, like "notify: self-test ".
+	Prefix  string
+	Results []Result
+
+	err error
+}
+
+// Step runs fn as the step name unless skip, and returns false if it failed.
+func (r *Runner) Step(name string, skip bool, fn func() (detail string, err error)) bool {
+	res := Result{Name: name, Skipped: skip}
+	if !skip {
+		start := time.Now()
+		res.Detail, res.Err = fn()
+		res.Duration = time.Since(start)
+	}
+	r.Results = append(r.Results, res)
+	if res.Err != nil && r.err == nil {
+		r.err = fmt.Errorf("%s%s: %w", r.Prefix, name, res.Err)
+	}
+	return res.Err == nil
+}
+
+// Skip records the step name as skipped, for reason, which may be empty.
+func (r *Runner) Skip(name, reason string) {
+	r.Results = append(r.Results, Result{Name: name, Skipped: true, Detail: reason})
+}
+
+// Err returns the error of the first step that failed, or nil.
+func (r *Runner) Err() error {
+	return r.err
+}
+
+// Case is a case of a matrix, see Runner.Matrix.
+type Case struct {
+	Name string
+	// Needs are the capabilities the daemon must have for the case to run.
+	Needs []string
+	// Unless, if not empty, is why the case does not apply to the daemon,
+	// like a quirk making its outcome unreliable.
+	Unless string
+	Run    func(ctx context.Context) (detail string, err error)
+}
+
+// Matrix runs the cases that apply to d, and skips the others, telling
+// which capability they need. It stops running cases once ctx is done.
+func (r *Runner) Matrix(ctx context.Context, d Daemon, cases []Case) {
+	for _, c := range cases {
+		if missing := d.Missing(c.Needs...); len(missing) > 0 {
+			r.Skip(c.Name, "needs "+strings.Join(missing, ", "))
+			continue
+		}
+		if c.Unless != "" {
+			r.Skip(c.Name, c.Unless)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			r.Skip(c.Name, err.Error())
+			continue
+		}
+		r.Step(c.Name, false, func() (string, error) { return c.Run(ctx) })
+	}
+}
+
+// WriteResults writes results to w, one line per step with its status,
+// name, duration, and its error or detail.
+func WriteResults(w io.Writer, results []Result) {
+	for _, res := range results {
+		fmt.Fprintf(w, "%-7s %-12s %8s", res.Status(), res.Name, res.Duration.Round(time.Microsecond))
+		if res.Err != nil {
+			fmt.Fprintf(w, "  %v", res.Err)
+		} else if res.Detail != "" {
+			fmt.Fprintf(w, "  %s", res.Detail)
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package harness_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/internal/harness"
+	"github.com/Schnouki/notify/internal/testbus"
+	"github.com/Schnouki/notify/server"
+	"github.com/godbus/dbus/v5"
+)
+
+func TestRunnerSteps(t *testing.T) {
+	r := harness.Runner{Prefix: "check "}
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+	if !r.Step("works", false, func() (string, error) { return "fine", nil }) {
+		t.Error("Step() = false for a step that worked")
+	}
+	if r.Step("fails", false, func() (string, error) { return "", errFirst }) {
+		t.Error("Step() = true for a step that failed")
+	}
+	r.Step("fails too", false, func() (string, error) { return "", errSecond })
+	r.Step("skipped", true, func() (string, error) {
+		t.Error("a skipped step ran")
+		return "", nil
+	})
+	r.Skip("unsupported", "needs actions")
+
+	if err := r.Err(); !errors.Is(err, errFirst) || err.Error() != "check fails: first" {
+		t.Errorf("Err() = %v, want the error of the first step failing", err)
+	}
+	var statuses []string
+	for _, res := range r.Results {
+		statuses = append(statuses, res.Status())
+	}
+	if want := []string{"ok", "FAILED", "FAILED", "skipped", "skipped"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses %v, want %v", statuses, want)
+	}
+	if !r.Results[0].OK() || r.Results[3].OK() {
+		t.Errorf("results %+v", r.Results)
+	}
+
+	var b strings.Builder
+	harness.WriteResults(&b, r.Results)
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "ok      works") || !strings.HasSuffix(lines[0], "  fine") ||
+		!strings.HasSuffix(lines[1], "  first") || !strings.HasSuffix(lines[4], "  needs actions") {
+		t.Errorf("WriteResults() =\n%s", b.String())
+	}
+}
+
+func TestRunnerMatrix(t *testing.T) {
+	d := harness.Daemon{Name: "test", Capabilities: []string{"actions", "body"}}
+	var ran []string
+	run := func(name string) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) {
+			ran = append(ran, name)
+			return "", nil
+		}
+	}
+	var r harness.Runner
+	r.Matrix(context.Background(), d, []harness.Case{
+		{Name: "plain", Run: run("plain")},
+		{Name: "actions", Needs: []string{"actions"}, Run: run("actions")},
+		{Name: "markup", Needs: []string{"body", "body-markup", "body-images"}, Run: run("markup")},
+		{Name: "expire", Unless: "persistent", Run: run("expire")},
+	})
+	if want := []string{"plain", "actions"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if res := r.Results[2]; !res.Skipped || res.Detail != "needs body-markup, body-images" {
+		t.Errorf("markup result %+v, want skipped for the missing capabilities", res)
+	}
+	if res := r.Results[3]; !res.Skipped || res.Detail != "persistent" {
+		t.Errorf("expire result %+v, want skipped for its reason", res)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran = nil
+	r = harness.Runner{}
+	r.Matrix(ctx, d, []harness.Case{{Name: "plain", Run: run("plain")}})
+	if len(ran) != 0 || !r.Results[0].Skipped {
+		t.Errorf("ran %v with a done context, results %+v", ran, r.Results)
+	}
+}
+
+type nopHandler struct{}
+
+func (nopHandler) Notify(*server.Responder, server.ReceivedNotification) error { return nil }
+func (nopHandler) CloseNotification(*server.Responder, uint32) error           { return nil }
+
+func TestDetect(t *testing.T) {
+	addr := testbus.Start(t, filepath.Join(t.TempDir(), "bus"))
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	info := notify.ServerInfo{Name: "dunst", Vendor: "knopwob", Version: "1.9.2", SpecVersion: "1.2"}
+	s, err := server.New(conn, nopHandler{}, server.WithCapabilities("body", "actions"), server.WithServerInfo(info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	client, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	d, err := harness.Detect(context.Background(), client, "org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != info.Name || d.Vendor != info.Vendor || d.Version != info.Version || d.SpecVersion != info.SpecVersion || d.Owner != conn.Names()[0] {
+		t.Errorf("Detect() = %+v, want %+v owned by %s", d, info, conn.Names()[0])
+	}
+	if !reflect.DeepEqual(d.Capabilities, []string{"actions", "body"}) || !d.Has("actions") || d.Has("persistence") {
+		t.Errorf("capabilities %v", d.Capabilities)
+	}
+
+	report := harness.Report{Daemon: d, Results: []harness.Result{{Name: "send", Detail: "ID 1"}}}
+	if text := report.String(); !strings.Contains(text, "daemon: dunst 1.9.2 (knopwob)") || !strings.Contains(text, `no entry for "dunst"`) || !report.OK() {
+		t.Errorf("String() =\n%s", text)
+	}
+
+	if _, err := harness.Detect(context.Background(), client, "org.example.Nobody", "/"); err == nil {
+		t.Error("Detect() found a daemon without an owner")
+	}
+}
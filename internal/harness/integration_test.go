@@ -0,0 +1,249 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build integration
+
+package harness_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/internal/harness"
+	"github.com/godbus/dbus/v5"
+)
+
+// The matrix waits closeWait for the daemon to signal that a notification
+// was closed, and expireWait more than the timeout for it to expire.
+const (
+	closeWait  = 2 * time.Second
+	expireWait = 3 * time.Second
+)
+
+// TestDaemonMatrix runs the matrix against the notification daemon of the
+// session, and logs its capability report. Run it with
+//
+//	NOTIFY_INTEGRATION=1 go test -tags integration -v ./internal/harness
+//
+// Only what the daemon advertises and its quirk entry claims is asserted: a
+// failure means that the capabilities or the quirk table are wrong for it,
+// and the report is the evidence of a fix.
+func TestDaemonMatrix(t *testing.T) {
+	if os.Getenv("NOTIFY_INTEGRATION") != "1" {
+		t.Skip("set NOTIFY_INTEGRATION=1 to run against the notification daemon of the session")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	d, err := harness.Detect(ctx, conn, "org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, known := notify.LookupQuirks(notify.ServerInfo{Name: d.Name, Vendor: d.Vendor, Version: d.Version, SpecVersion: d.SpecVersion})
+	quirks, err := json.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nf, err := notify.NewNotifier(notify.WithAppName("notify-integration"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	var r harness.Runner
+	r.Matrix(ctx, d, matrix(nf, d, q))
+	report := harness.Report{Daemon: d, Quirks: string(quirks), Known: known, Results: r.Results}
+	t.Logf("capability report:\n%s", report)
+	for _, res := range r.Results {
+		if res.Err != nil {
+			t.Errorf("%s: %v", res.Name, res.Err)
+		}
+	}
+}
+
+// matrix returns the cases run against the daemon d, with the quirks q.
+func matrix(nf *notify.Notifier, d harness.Daemon, q notify.Quirks) []harness.Case {
+	persistent := ""
+	if q.Persistence || d.Has("persistence") {
+		persistent = "the daemon keeps notifications until they are dismissed"
+	}
+	return []harness.Case{
+		{Name: "send", Run: func(ctx context.Context) (string, error) {
+			n := newNote("Plain notification", "Sent by the integration tests of notify.")
+			defer nf.CloseNotification(n.Id)
+			if _, err := nf.SendContext(ctx, n); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("ID %d", n.Id), nil
+		}},
+		{Name: "replace", Run: func(ctx context.Context) (string, error) {
+			n := newNote("Replaced notification", "First version.")
+			defer nf.CloseNotification(n.Id)
+			if _, err := nf.SendContext(ctx, n); err != nil {
+				return "", err
+			}
+			first := n.Id
+			n.Body = "Second version."
+			res, err := nf.SendContext(ctx, n)
+			if err != nil {
+				return "", err
+			}
+			if !res.ReusedID {
+				return "", fmt.Errorf("replacing %d gave the new ID %d", first, res.Id)
+			}
+			return fmt.Sprintf("ID %d kept", first), nil
+		}},
+		{Name: "close", Run: func(ctx context.Context) (string, error) {
+			n := newNote("Closed notification", "Closed right away.")
+			closed := onClose(n)
+			if _, err := nf.SendContext(ctx, n); err != nil {
+				return "", err
+			}
+			if err := nf.CloseNotification(n.Id); err != nil {
+				return "", err
+			}
+			return waitClosed(ctx, closed, closeWait, notify.ReasonClosed)
+		}},
+		{Name: "expire", Unless: persistent, Run: func(ctx context.Context) (string, error) {
+			n := newNote("Expiring notification", "Expires after a second.")
+			n.Timeout = notify.Duration(time.Second)
+			closed := onClose(n)
+			defer nf.CloseNotification(n.Id)
+			start := time.Now()
+			if _, err := nf.SendContext(ctx, n); err != nil {
+				return "", err
+			}
+			if _, err := waitClosed(ctx, closed, time.Second+expireWait, notify.ReasonExpired); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("expired after %v", time.Since(start).Round(time.Millisecond)), nil
+		}},
+		{Name: "actions", Needs: []string{"actions"}, Run: func(ctx context.Context) (string, error) {
+			n := newNote("Notification with actions", "Three buttons, and a default action.")
+			n.AddAction(notify.DefaultAction, "Open")
+			for i := 1; i <= 3; i++ {
+				n.AddAction(fmt.Sprint("button-", i), fmt.Sprint("Button ", i))
+			}
+			defer nf.CloseNotification(n.Id)
+			p, err := nf.Preview(n)
+			if err != nil {
+				return "", err
+			}
+			dropped := 0
+			for _, w := range p.Warnings {
+				if w, ok := w.(notify.DroppedActions); ok {
+					dropped = len(w.Keys)
+				}
+			}
+			if want := max(0, 3-q.MaxActions); q.MaxActions > 0 && dropped != want {
+				return "", fmt.Errorf("preview drops %d actions, want %d with at most %d shown", dropped, want, q.MaxActions)
+			}
+			if _, err := nf.SendContext(ctx, n); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d buttons shown", 3-dropped), nil
+		}},
+		{Name: "image", Needs: []string{"icon-static"}, Run: func(ctx context.Context) (string, error) {
+			n := newNote("Notification with an image", "A green square.")
+			img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+			draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{0x2e, 0x7d, 0x32, 0xff}}, image.Point{}, draw.Src)
+			n.SetImage(img)
+			defer nf.CloseNotification(n.Id)
+			p, err := nf.Preview(n)
+			if err != nil {
+				return "", err
+			}
+			if _, err := notify.DecodeImage(p.Call.Hints); err != nil {
+				return "", fmt.Errorf("no image sent: %w", err)
+			}
+			if _, err := nf.SendContext(ctx, n); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d bytes sent", p.WireSize), nil
+		}},
+		{Name: "progress", Run: func(ctx context.Context) (string, error) {
+			n := newNote("Progress notification", "Copying files…")
+			defer nf.CloseNotification(n.Id)
+			for _, v := range []int32{0, 50, 100} {
+				n.AddHints(notify.ValueHint(v))
+				res, err := nf.SendContext(ctx, n)
+				if err != nil {
+					return "", err
+				}
+				if res.Replaced && !res.ReusedID {
+					return "", fmt.Errorf("the update to %d%% gave the new ID %d", v, res.Id)
+				}
+			}
+			return "updated to 100%", nil
+		}},
+		{Name: "markup", Needs: []string{"body-markup"}, Run: func(ctx context.Context) (string, error) {
+			n := newNote("Notification with markup", "<b>Bold</b>, <i>italic</i> and <u>underlined</u>.")
+			defer nf.CloseNotification(n.Id)
+			p, err := nf.Preview(n)
+			if err != nil {
+				return "", err
+			}
+			for _, w := range p.Warnings {
+				if _, ok := w.(notify.StrippedMarkup); ok {
+					return "", errors.New("the markup is stripped although the daemon has body-markup")
+				}
+			}
+			if _, err := nf.SendContext(ctx, n); err != nil {
+				return "", err
+			}
+			return "", nil
+		}},
+	}
+}
+
+// newNote returns a transient notification of the matrix.
+func newNote(summary, body string) *notify.Notification {
+	n := notify.New("", summary, body, "dialog-information", 10*time.Second, notify.NormalUrgency)
+	n.AddHints(notify.TransientHint(true))
+	return n
+}
+
+// onClose returns the channel getting the reason n is closed with.
+func onClose(n *notify.Notification) <-chan notify.CloseReason {
+	closed := make(chan notify.CloseReason, 1)
+	n.OnClose = func(reason notify.CloseReason) {
+		select {
+		case closed <- reason:
+		default:
+		}
+	}
+	return closed
+}
+
+// waitClosed waits up to wait for the reason of closed to be want.
+func waitClosed(ctx context.Context, closed <-chan notify.CloseReason, wait time.Duration, want notify.CloseReason) (string, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case reason := <-closed:
+		if reason != want {
+			return "", fmt.Errorf("closed with the reason %v, want %v", reason, want)
+		}
+		return "closed: " + reason.String(), nil
+	case <-timer.C:
+		return "", fmt.Errorf("the daemon did not signal that the notification was closed within %v", wait)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package testbus starts private D-Bus session buses for the tests, so that
+// they never talk to the real notification daemon of the user running them.
+package testbus
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// busConfig is a minimal session bus configuration without any service
+// activation, so that nothing but the fake servers of the tests can own the
+// notification service name.
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>%s</listen>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// Run starts a private session bus, makes it the session bus of the process,
+// and runs the tests. It stores the address of the bus in addr, or the empty
+// string if no bus could be started, in which case the tests run without a
+// session bus. It returns the exit code for os.Exit.
+func Run(m *testing.M, addr *string) int {
+	dir, err := os.MkdirTemp("", "notify-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cmd, a, err := start(dir, "unix:dir="+dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "not running D-Bus tests:", err)
+		os.Unsetenv("DBUS_SESSION_BUS_ADDRESS")
+	} else {
+		defer cmd.Process.Kill()
+		*addr = a
+		os.Setenv("DBUS_SESSION_BUS_ADDRESS", a)
+	}
+	return m.Run()
+}
+
+// Start starts another private bus listening on the socket at path, for the
+// tests needing several buses, and returns its address. The bus is stopped
+// at the end of the test, which is skipped if the bus cannot be started.
+func Start(t testing.TB, path string) string {
+	t.Helper()
+	dir := t.TempDir()
+	cmd, addr, err := start(dir, "unix:path="+path)
+	if err != nil {
+		t.Skip("no private D-Bus bus available:", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return addr
+}
+
+// start starts a bus listening on listen, with its configuration in dir.
+func start(dir, listen string) (*exec.Cmd, string, error) {
+	config := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(config, []byte(fmt.Sprintf(busConfig, listen)), 0o644); err != nil {
+		return nil, "", err
+	}
+
+	cmd := exec.Command("dbus-daemon", "--config-file="+config, "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, "", err
+	}
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, "", err
+	}
+	return cmd, strings.TrimSpace(line), nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// InterfaceInfo describes a D-Bus interface of the object of the daemon, as
+// reported by its introspection data.
+type InterfaceInfo struct {
+	// Name is the name of the interface, for example
+	// "org.freedesktop.Notifications".
+	Name string
+	// Methods and Signals are the names of its methods and signals.
+	Methods []string
+	Signals []string
+}
+
+// HasMethod reports whether the interface has the method name.
+func (i InterfaceInfo) HasMethod(name string) bool {
+	for _, m := range i.Methods {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSignal reports whether the interface has the signal name.
+func (i InterfaceInfo) HasSignal(name string) bool {
+	for _, s := range i.Signals {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// introspection caches the result of ProbeInterfaces.
+type introspection struct {
+	ifaces []InterfaceInfo
+	err    error
+}
+
+// ProbeInterfaces returns the interfaces of the object of the daemon, with
+// their methods and signals, as reported by its
+// org.freedesktop.DBus.Introspectable interface. It lets callers check for
+// the extensions of a daemon, such as the command interface of dunst,
+// without guessing from its name and version.
+//
+// The introspection data is parsed leniently: the interfaces found before
+// malformed data are kept, and empty data means no interfaces. The result,
+// or the error of a daemon that cannot be introspected, is cached like the
+// capabilities, see InvalidateCaches.
+func (nf *Notifier) ProbeInterfaces(ctx context.Context) ([]InterfaceInfo, error) {
+	if nf.core != nil {
+		return nf.core.root.ProbeInterfaces(ctx)
+	}
+	nf.mu.Lock()
+	cached, gen := nf.ifaces, nf.probe.gen
+	nf.mu.Unlock()
+	if cached != nil {
+		return cached.ifaces, cached.err
+	}
+
+	conn, err := nf.connection()
+	if err != nil {
+		return nil, err
+	}
+	var data string
+	err = nf.object(conn).CallWithContext(ctx, "org.freedesktop.DBus.Introspectable.Introspect", nf.callFlags()).Store(&data)
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		// The daemon answered: it cannot be introspected.
+		err = fmt.Errorf("notify: introspecting the daemon: %w", err)
+	} else if err != nil {
+		return nil, fmt.Errorf("notify: introspecting the daemon: %w", err)
+	}
+	nf.watchOwner(conn)
+	result := &introspection{err: err}
+	if err == nil {
+		var perr error
+		result.ifaces, perr = parseIntrospection(data)
+		if perr != nil {
+			nf.log(LevelWarn, "malformed introspection data from the daemon", perr)
+		}
+	}
+
+	nf.mu.Lock()
+	if gen == nf.probe.gen {
+		nf.ifaces = result
+	}
+	nf.mu.Unlock()
+	return result.ifaces, result.err
+}
+
+// parseIntrospection returns the interfaces of the root node of the
+// introspection data. If the data is malformed, it returns the interfaces
+// found before the error, along with it.
+func parseIntrospection(data string) ([]InterfaceInfo, error) {
+	d := xml.NewDecoder(strings.NewReader(data))
+	d.Strict = false
+	var (
+		ifaces []InterfaceInfo
+		// depth is that of the current element, 1 for the root node.
+		depth int
+		cur   *InterfaceInfo
+	)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if cur != nil {
+				ifaces = append(ifaces, *cur)
+			}
+			return ifaces, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 2 && t.Name.Local == "interface":
+				cur = &InterfaceInfo{Name: xmlAttr(t, "name")}
+			case depth == 3 && cur != nil && t.Name.Local == "method":
+				cur.Methods = append(cur.Methods, xmlAttr(t, "name"))
+			case depth == 3 && cur != nil && t.Name.Local == "signal":
+				cur.Signals = append(cur.Signals, xmlAttr(t, "name"))
+			}
+		case xml.EndElement:
+			if depth == 2 && cur != nil {
+				ifaces = append(ifaces, *cur)
+				cur = nil
+			}
+			depth--
+		}
+	}
+	if cur != nil {
+		ifaces = append(ifaces, *cur)
+	}
+	return ifaces, nil
+}
+
+// xmlAttr returns the value of the attribute name of e.
+func xmlAttr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// findInterface returns the interface name among ifaces.
+func findInterface(ifaces []InterfaceInfo, name string) (InterfaceInfo, bool) {
+	for _, i := range ifaces {
+		if i.Name == name {
+			return i, true
+		}
+	}
+	return InterfaceInfo{}, false
+}
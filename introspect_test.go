@@ -0,0 +1,149 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeIntrospectable answers the introspection of the fake daemon with data,
+// counting the calls.
+type fakeIntrospectable struct {
+	mu    sync.Mutex
+	data  string
+	calls int
+}
+
+func (f *fakeIntrospectable) Introspect() (string, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return f.data, nil
+}
+
+func (f *fakeIntrospectable) set(data string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data = data
+}
+
+func (f *fakeIntrospectable) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// introspectable makes the fake server answer introspection with data.
+func introspectable(t *testing.T, s *fakeServer, data string) *fakeIntrospectable {
+	t.Helper()
+	f := &fakeIntrospectable{data: data}
+	if err := s.conn.Export(f, s.path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+const dunstIntrospection = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Object Introspection 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/introspect.dtd">
+<node>
+  <interface name="org.freedesktop.Notifications">
+    <method name="Notify"><arg direction="in" type="s"/></method>
+    <method name="CloseNotification"/>
+    <signal name="ActionInvoked"><arg type="u"/><arg type="s"/></signal>
+  </interface>
+  <interface name="org.dunstproject.cmd0">
+    <method name="NotificationListDisplayed"/>
+  </interface>
+  <node name="child"><interface name="org.example.Child"/></node>
+</node>`
+
+func TestProbeInterfaces(t *testing.T) {
+	s := newFakeServer(t)
+	f := introspectable(t, s, dunstIntrospection)
+	if err := s.conn.Export(fakeDunst{}, s.path, "org.dunstproject.cmd0"); err != nil {
+		t.Fatal(err)
+	}
+	nf := newTestNotifier(t)
+
+	ifaces, err := nf.ProbeInterfaces(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 2 || ifaces[0].Name != "org.freedesktop.Notifications" || ifaces[1].Name != "org.dunstproject.cmd0" {
+		t.Fatalf("ProbeInterfaces() = %+v", ifaces)
+	}
+	if n := ifaces[0]; !n.HasMethod("CloseNotification") || !n.HasSignal("ActionInvoked") || n.HasMethod("ActionInvoked") {
+		t.Errorf("notifications interface %+v", n)
+	}
+
+	// The daemon is not named dunst, but has its interface.
+	if _, err := nf.Displayed(); err != nil {
+		t.Errorf("Displayed() = %v", err)
+	}
+	if _, err := nf.ProbeInterfaces(context.Background()); err != nil || f.count() != 1 {
+		t.Errorf("introspected %d times, %v", f.count(), err)
+	}
+
+	f.set(`<node><interface name="org.freedesktop.Notifications"/></node>`)
+	nf.InvalidateCaches()
+	if ifaces, _ := nf.ProbeInterfaces(context.Background()); len(ifaces) != 1 || f.count() != 2 {
+		t.Errorf("ProbeInterfaces() = %+v after InvalidateCaches", ifaces)
+	}
+	if _, err := nf.Displayed(); !errors.Is(err, notify.ErrUnsupported) {
+		t.Errorf("Displayed() = %v without the dunst interface", err)
+	}
+}
+
+func TestProbeInterfacesTolerant(t *testing.T) {
+	s := newFakeServer(t)
+	s.setServerName("dunst")
+	f := introspectable(t, s, "")
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithLogger(logs.log))
+
+	if ifaces, err := nf.ProbeInterfaces(context.Background()); ifaces != nil || err != nil {
+		t.Errorf("ProbeInterfaces() = %+v, %v for empty data", ifaces, err)
+	}
+	// Without introspection data, Displayed goes by the quirks of dunst,
+	// and this one has no command interface.
+	if _, err := nf.Displayed(); !errors.Is(err, notify.ErrUnsupported) {
+		t.Errorf("Displayed() = %v", err)
+	}
+
+	f.set(`<node><interface name="org.freedesktop.Notifications"><method name="Notify"/></interface><interface name="org.dunstproject.cmd0"`)
+	nf.InvalidateCaches()
+	ifaces, err := nf.ProbeInterfaces(context.Background())
+	if err != nil || len(ifaces) != 1 || !ifaces[0].HasMethod("Notify") {
+		t.Errorf("ProbeInterfaces() = %+v, %v for malformed data", ifaces, err)
+	}
+	if !logs.logged("malformed introspection data") {
+		t.Errorf("malformed data not logged: %q", logs.msgs)
+	}
+
+	for _, data := range []string{"not xml at all", "<node><method name=", "</node>", "<node><interface><method/></interface></node>"} {
+		f.set(data)
+		nf.InvalidateCaches()
+		if _, err := nf.ProbeInterfaces(context.Background()); err != nil {
+			t.Errorf("ProbeInterfaces() = %v for %q", err, data)
+		}
+	}
+}
+
+func TestProbeInterfacesUnsupported(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	if _, err := nf.ProbeInterfaces(context.Background()); err == nil {
+		t.Error("a daemon without introspection was introspected")
+	}
+	if _, err := nf.Displayed(); !errors.Is(err, notify.ErrUnsupported) {
+		t.Errorf("Displayed() = %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package journal implements a notify.Transport recording notifications in
+// the systemd journal, using its native protocol.
+//
+// It can replace the D-Bus transport, or mirror it:
+//
+//	nf, err := notify.NewNotifier(notify.WithMirrors(journal.New("myapp")))
+//
+// so that journalctl -t myapp shows the notifications that were sent.
+package journal
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Schnouki/notify"
+)
+
+// DefaultSocket is the native socket of journald.
+const DefaultSocket = "/run/systemd/journal/socket"
+
+// ErrUnavailable is returned by Ping when the journal socket is missing.
+var ErrUnavailable = errors.New("journal: journald socket unavailable")
+
+// Syslog priorities of the urgencies.
+const (
+	priorityCrit   = 2
+	priorityNotice = 5
+	priorityInfo   = 6
+)
+
+// Transport records the calls of a Notifier in the journal, with these
+// fields:
+//
+//	MESSAGE            the summary
+//	PRIORITY           crit, notice or info, from the urgency of the call
+//	SYSLOG_IDENTIFIER  the identifier, or the app name if it is empty
+//	NOTIFY_BODY        the body, if any
+//	NOTIFY_APP         the app name
+//	NOTIFY_CORRELATION the correlation ID of the notification, if any
+//
+// If the journal is not running, notifications are silently dropped; use
+// Ping to find out. Transport assigns its own IDs, and closing
+// notifications does nothing.
+type Transport struct {
+	// Identifier is the syslog identifier of the entries.
+	Identifier string
+	// Socket is the path of the journal socket, DefaultSocket if empty.
+	Socket string
+
+	lastID atomic.Uint32
+}
+
+// New returns a Transport recording notifications with the syslog
+// identifier id.
+func New(id string) *Transport {
+	return &Transport{Identifier: id}
+}
+
+func (t *Transport) socket() string {
+	if t.Socket == "" {
+		return DefaultSocket
+	}
+	return t.Socket
+}
+
+// Ping returns an error wrapping ErrUnavailable if the journal socket does
+// not exist.
+func (t *Transport) Ping() error {
+	if _, err := os.Stat(t.socket()); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// Notify records c in the journal.
+func (t *Transport) Notify(ctx context.Context, c notify.Call) (uint32, error) {
+	id := c.ReplacesID
+	if id == 0 {
+		id = t.lastID.Add(1)
+	}
+	if t.Ping() != nil {
+		return id, nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: t.socket(), Net: "unixgram"})
+	if err != nil {
+		return 0, fmt.Errorf("journal: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+	if _, err := conn.Write(t.entry(c)); err != nil {
+		return 0, fmt.Errorf("journal: %w", err)
+	}
+	return id, nil
+}
+
+// CloseNotification does nothing: journal entries stay.
+func (t *Transport) CloseNotification(id uint32) error {
+	return nil
+}
+
+// entry returns the journal entry for c, in the native format.
+func (t *Transport) entry(c notify.Call) []byte {
+	ident := t.Identifier
+	if ident == "" {
+		ident = c.AppName
+	}
+
+	var buf bytes.Buffer
+	writeField(&buf, "MESSAGE", c.Summary)
+	writeField(&buf, "PRIORITY", strconv.Itoa(priority(c)))
+	if ident != "" {
+		writeField(&buf, "SYSLOG_IDENTIFIER", ident)
+	}
+	if c.Body != "" {
+		writeField(&buf, "NOTIFY_BODY", c.Body)
+	}
+	writeField(&buf, "NOTIFY_APP", c.AppName)
+	if c.CorrelationID != "" {
+		writeField(&buf, "NOTIFY_CORRELATION", c.CorrelationID)
+	}
+	return buf.Bytes()
+}
+
+// priority returns the syslog priority of the urgency of c: c.Urgency, not
+// the byte of its urgency hint, which the Notifier may have mapped to another
+// one for the daemon. Calls without an urgency hint are notices.
+func priority(c notify.Call) int {
+	if _, ok := c.Hints["urgency"]; !ok {
+		return priorityNotice
+	}
+	switch c.Urgency {
+	case notify.LowUrgency:
+		return priorityInfo
+	case notify.CriticalUrgency:
+		return priorityCrit
+	default:
+		return priorityNotice
+	}
+}
+
+// writeField writes the field name=value to buf. Values with newlines are
+// written with their length, as the protocol requires.
+func writeField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name + "=" + value + "\n")
+		return
+	}
+	buf.WriteString(name + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
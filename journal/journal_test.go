@@ -0,0 +1,139 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package journal_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/journal"
+)
+
+// listen returns a fake journal socket.
+func listen(t *testing.T) (string, *net.UnixConn) {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip("no unix datagram sockets:", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return path, conn
+}
+
+// parseEntry parses an entry in the native journal format.
+func parseEntry(t *testing.T, b []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(b) > 0 {
+		i := bytes.IndexAny(b, "=\n")
+		if i < 0 {
+			t.Fatalf("truncated entry %q", b)
+		}
+		name := string(b[:i])
+		if b[i] == '=' {
+			j := bytes.IndexByte(b, '\n')
+			fields[name] = string(b[i+1 : j])
+			b = b[j+1:]
+			continue
+		}
+		b = b[i+1:]
+		n := binary.LittleEndian.Uint64(b)
+		fields[name] = string(b[8 : 8+n])
+		b = b[8+n+1:]
+	}
+	return fields
+}
+
+func TestTransport(t *testing.T) {
+	path, conn := listen(t)
+	jt := journal.New("myapp")
+	jt.Socket = path
+	if err := jt.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	nf, err := notify.NewNotifier(notify.WithTransport(jt), notify.WithAppName("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	n := notify.New("", "disk full", "only 1%\nleft", "", 0, notify.CriticalUrgency)
+	n.CorrelationID = "job-42"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Id == 0 {
+		t.Error("no ID assigned")
+	}
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	size, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := parseEntry(t, buf[:size])
+	want := map[string]string{
+		"MESSAGE":            "disk full",
+		"PRIORITY":           "2",
+		"SYSLOG_IDENTIFIER":  "myapp",
+		"NOTIFY_BODY":        "only 1%\nleft",
+		"NOTIFY_APP":         "app",
+		"NOTIFY_CORRELATION": "job-42",
+	}
+	if len(got) != len(want) {
+		t.Errorf("entry = %q, want %q", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestTransportUnavailable(t *testing.T) {
+	jt := journal.New("myapp")
+	jt.Socket = filepath.Join(t.TempDir(), "missing")
+	if err := jt.Ping(); !errors.Is(err, journal.ErrUnavailable) {
+		t.Errorf("Ping() = %v, want ErrUnavailable", err)
+	}
+	id, err := jt.Notify(context.Background(), notify.Call{Summary: "dropped"})
+	if err != nil || id == 0 {
+		t.Errorf("Notify() = %d, %v, want an ID and no error", id, err)
+	}
+}
+
+// TestTransportUrgencyMap checks that the priority comes from the urgency, not
+// from the byte it is mapped to for the daemon.
+func TestTransportUrgencyMap(t *testing.T) {
+	path, conn := listen(t)
+	jt := journal.New("myapp")
+	jt.Socket = path
+	nf, err := notify.NewNotifier(notify.WithTransport(jt), notify.WithUrgencyMap(
+		map[notify.NotificationUrgency]byte{notify.CriticalUrgency: 0, notify.LowUrgency: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	if _, err := nf.Notify(notify.New("", "disk full", "", "", 0, notify.CriticalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	size, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parseEntry(t, buf[:size])["PRIORITY"]; got != "2" {
+		t.Errorf("PRIORITY = %q, want 2", got)
+	}
+}
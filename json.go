@@ -0,0 +1,254 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var urgencyNames = [...]string{
+	LowUrgency:      "low",
+	NormalUrgency:   "normal",
+	CriticalUrgency: "critical",
+}
+
+// String returns "low", "normal" or "critical".
+func (u NotificationUrgency) String() string {
+	if int(u) < len(urgencyNames) {
+		return urgencyNames[u]
+	}
+	return fmt.Sprintf("NotificationUrgency(%d)", byte(u))
+}
+
+// MarshalText encodes u as its name, see String.
+func (u NotificationUrgency) MarshalText() ([]byte, error) {
+	if int(u) >= len(urgencyNames) {
+		return nil, fmt.Errorf("notify: invalid urgency %d", byte(u))
+	}
+	return []byte(urgencyNames[u]), nil
+}
+
+// UnmarshalText decodes an urgency encoded by MarshalText.
+func (u *NotificationUrgency) UnmarshalText(text []byte) error {
+	for i, name := range urgencyNames {
+		if string(text) == name {
+			*u = NotificationUrgency(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("notify: invalid urgency %q", text)
+}
+
+// Duration is a time.Duration encoded as text like "5s", for the timeouts of
+// notifications in configuration files.
+type Duration time.Duration
+
+// DefaultTimeout is the timeout letting the daemon choose how long a
+// notification is shown, sent as the -1 ms of the specification.
+const DefaultTimeout = Duration(-time.Millisecond)
+
+// String returns d like time.Duration does, or "default" for
+// DefaultTimeout.
+func (d Duration) String() string {
+	if d == DefaultTimeout {
+		return "default"
+	}
+	return time.Duration(d).String()
+}
+
+// MarshalText encodes d as its String.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a duration like "5s" or "0", as time.ParseDuration
+// does, or "default" for DefaultTimeout.
+func (d *Duration) UnmarshalText(text []byte) error {
+	if string(text) == "default" {
+		*d = DefaultTimeout
+		return nil
+	}
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("notify: invalid timeout: %w", err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// jsonNotification is the JSON encoding of a Notification.
+type jsonNotification struct {
+	Name                  string              `json:"name,omitempty"`
+	Summary               string              `json:"summary"`
+	Body                  string              `json:"body,omitempty"`
+	IconPath              string              `json:"icon,omitempty"`
+	ImagePath             string              `json:"image_path,omitempty"`
+	Timeout               Duration            `json:"timeout,omitempty"`
+	Urgency               NotificationUrgency `json:"urgency"`
+	Id                    uint32              `json:"id,omitempty"`
+	Tag                   string              `json:"tag,omitempty"`
+	CorrelationID         string              `json:"correlation_id,omitempty"`
+	Sensitive             bool                `json:"sensitive,omitempty"`
+	Actions               []jsonAction        `json:"actions,omitempty"`
+	Persistent            bool                `json:"persistent,omitempty"`
+	CloseOnAction         bool                `json:"close_on_action,omitempty"`
+	AllowCriticalExpiry   bool                `json:"allow_critical_expiry,omitempty"`
+	AllowUrgencyDowngrade bool                `json:"allow_urgency_downgrade,omitempty"`
+	Localized             bool                `json:"localized,omitempty"`
+}
+
+type jsonAction struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// MarshalJSON encodes the fields of n that are not callbacks, with the
+// timeout as a Duration like "5s" and the urgency as its name. Hints
+// and embedded images are not encoded.
+func (n *Notification) MarshalJSON() ([]byte, error) {
+	j := jsonNotification{
+		Name:                  n.Name,
+		Summary:               n.Summary,
+		Body:                  n.Body,
+		IconPath:              n.IconPath,
+		ImagePath:             n.ImagePath,
+		Timeout:               n.Timeout,
+		Urgency:               n.baseUrgency(),
+		Id:                    n.Id,
+		Tag:                   n.Tag,
+		CorrelationID:         n.CorrelationID,
+		Sensitive:             n.Sensitive,
+		Persistent:            n.Persistent,
+		CloseOnAction:         n.CloseOnAction,
+		AllowCriticalExpiry:   n.AllowCriticalExpiry,
+		AllowUrgencyDowngrade: n.AllowUrgencyDowngrade,
+		Localized:             n.Localized,
+	}
+	for _, a := range n.Actions {
+		j.Actions = append(j.Actions, jsonAction{a.Key, a.Label})
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a notification encoded by MarshalJSON. A missing
+// urgency is NormalUrgency.
+func (n *Notification) UnmarshalJSON(data []byte) error {
+	j := jsonNotification{Urgency: NormalUrgency}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	var actions []Action
+	for _, a := range j.Actions {
+		actions = append(actions, Action{a.Key, a.Label})
+	}
+	*n = Notification{
+		Name:                  j.Name,
+		Summary:               j.Summary,
+		Body:                  j.Body,
+		IconPath:              j.IconPath,
+		ImagePath:             j.ImagePath,
+		Timeout:               j.Timeout,
+		Urgency:               j.Urgency,
+		Id:                    j.Id,
+		Tag:                   j.Tag,
+		CorrelationID:         j.CorrelationID,
+		Sensitive:             j.Sensitive,
+		Actions:               actions,
+		Persistent:            j.Persistent,
+		CloseOnAction:         j.CloseOnAction,
+		AllowCriticalExpiry:   j.AllowCriticalExpiry,
+		AllowUrgencyDowngrade: j.AllowUrgencyDowngrade,
+		Localized:             j.Localized,
+
+		urgencySet: true,
+	}
+	return nil
+}
+
+// MarshalText encodes k as its name, see String.
+func (k EventKind) MarshalText() ([]byte, error) {
+	if k < EventSent || k > EventSlow {
+		return nil, fmt.Errorf("notify: invalid event kind %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes an event kind encoded by MarshalText.
+func (k *EventKind) UnmarshalText(text []byte) error {
+	for kind := EventSent; kind <= EventSlow; kind++ {
+		if string(text) == kind.String() {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("notify: invalid event kind %q", text)
+}
+
+// jsonEvent is the JSON encoding of an Event.
+type jsonEvent struct {
+	Kind            EventKind   `json:"kind"`
+	Time            time.Time   `json:"time"`
+	ID              uint32      `json:"id,omitempty"`
+	CorrelationID   string      `json:"correlation_id,omitempty"`
+	Tag             string      `json:"tag,omitempty"`
+	Key             string      `json:"key,omitempty"`
+	ActivationToken string      `json:"activation_token,omitempty"`
+	Reason          CloseReason `json:"reason,omitempty"`
+	Err             string      `json:"error,omitempty"`
+	Delay           Duration    `json:"delay,omitempty"`
+	ReplacesID      uint32      `json:"replaces_id,omitempty"`
+	ReusedID        bool        `json:"reused_id,omitempty"`
+}
+
+// MarshalJSON encodes e with its kind as its name, the delay as a Duration
+// like "5s" and the error as its message.
+func (e Event) MarshalJSON() ([]byte, error) {
+	j := jsonEvent{
+		Kind:            e.Kind,
+		Time:            e.Time,
+		ID:              e.ID,
+		CorrelationID:   e.CorrelationID,
+		Tag:             e.Tag,
+		Key:             e.Key,
+		ActivationToken: e.ActivationToken,
+		Reason:          e.Reason,
+		Delay:           Duration(e.Delay),
+		ReplacesID:      e.ReplacesID,
+		ReusedID:        e.ReusedID,
+	}
+	if e.Err != nil {
+		j.Err = e.Err.Error()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes an event encoded by MarshalJSON. The error, if any,
+// only keeps its message.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var j jsonEvent
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*e = Event{
+		Kind:            j.Kind,
+		Time:            j.Time,
+		ID:              j.ID,
+		CorrelationID:   j.CorrelationID,
+		Tag:             j.Tag,
+		Key:             j.Key,
+		ActivationToken: j.ActivationToken,
+		Reason:          j.Reason,
+		Delay:           time.Duration(j.Delay),
+		ReplacesID:      j.ReplacesID,
+		ReusedID:        j.ReusedID,
+	}
+	if j.Err != "" {
+		e.Err = errors.New(j.Err)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestNotificationJSON(t *testing.T) {
+	n := notify.New("app", "summary", "body", "icon", 1500*time.Millisecond, notify.CriticalUrgency)
+	n.Tag = "build"
+	n.AddAction(notify.DefaultAction, "Open")
+	n.Persistent = true
+
+	data, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"name":"app","summary":"summary","body":"body","icon":"icon","timeout":"1.5s","urgency":"critical","tag":"build","actions":[{"key":"default","label":"Open"}],"persistent":true}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var got notify.Notification
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != n.Name || got.Summary != n.Summary || got.Body != n.Body || got.IconPath != n.IconPath ||
+		got.Timeout != n.Timeout || got.Urgency != n.Urgency || got.Tag != n.Tag ||
+		!reflect.DeepEqual(got.Actions, n.Actions) || !got.Persistent {
+		t.Errorf("round trip gave %+v, want %+v", got, n)
+	}
+
+	if err := json.Unmarshal([]byte(`{"summary":"s"}`), &got); err != nil || got.Urgency != notify.NormalUrgency {
+		t.Errorf("default urgency = %v, %v", got.Urgency, err)
+	}
+	if err := json.Unmarshal([]byte(`{"summary":"s","timeout":"soon"}`), &got); err == nil {
+		t.Error("invalid timeout accepted")
+	}
+}
+
+func TestEventJSON(t *testing.T) {
+	e := notify.Event{
+		Kind:  notify.EventFailed,
+		Time:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		ID:    7,
+		Tag:   "build",
+		Err:   errors.New("no daemon"),
+		Delay: 2 * time.Second,
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"kind":"failed","time":"2024-05-01T12:00:00Z","id":7,"tag":"build","error":"no daemon","delay":"2s"}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var got notify.Event
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Err == nil || got.Err.Error() != "no daemon" {
+		t.Errorf("decoded error %v", got.Err)
+	}
+	got.Err, e.Err = nil, nil
+	if !reflect.DeepEqual(got, e) {
+		t.Errorf("round trip gave %+v, want %+v", got, e)
+	}
+	if err := json.Unmarshal([]byte(`{"kind":"exploded"}`), &got); err == nil {
+		t.Error("invalid kind accepted")
+	}
+}
+
+func TestDurationText(t *testing.T) {
+	tests := []struct {
+		text string
+		d    notify.Duration
+		want string
+	}{
+		{"5s", notify.Duration(5 * time.Second), "5s"},
+		{"1m30s", notify.Duration(90 * time.Second), "1m30s"},
+		{"0", 0, "0s"},
+		{"default", notify.DefaultTimeout, "default"},
+	}
+	for _, tt := range tests {
+		var d notify.Duration
+		if err := d.UnmarshalText([]byte(tt.text)); err != nil || d != tt.d {
+			t.Errorf("UnmarshalText(%q) = %v, %v, want %v", tt.text, d, err, tt.d)
+		}
+		if got, _ := tt.d.MarshalText(); string(got) != tt.want {
+			t.Errorf("MarshalText(%v) = %q, want %q", time.Duration(tt.d), got, tt.want)
+		}
+	}
+	var d notify.Duration
+	if err := d.UnmarshalText([]byte("5")); err == nil {
+		t.Error("duration without unit accepted")
+	}
+}
+
+// TestPresetsJSON checks that a file of notification presets written by hand
+// decodes, and encodes back to the same presets.
+func TestPresetsJSON(t *testing.T) {
+	const file = `{
+		"backup": {"summary": "Backup done", "timeout": "5s", "urgency": "low"},
+		"disk": {"summary": "Disk full", "timeout": "0", "urgency": "critical"},
+		"mail": {"summary": "New mail", "timeout": "default"}
+	}`
+	var presets map[string]*notify.Notification
+	if err := json.Unmarshal([]byte(file), &presets); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct {
+		timeout time.Duration
+		urgency notify.NotificationUrgency
+	}{
+		"backup": {5 * time.Second, notify.LowUrgency},
+		"disk":   {0, notify.CriticalUrgency},
+		"mail":   {time.Duration(notify.DefaultTimeout), notify.NormalUrgency},
+	}
+	for name, w := range want {
+		n := presets[name]
+		if n == nil {
+			t.Fatalf("missing preset %q", name)
+		}
+		if n.TimeoutDuration() != w.timeout || n.Urgency != w.urgency {
+			t.Errorf("%s: timeout %v, urgency %v, want %v, %v", name, n.TimeoutDuration(), n.Urgency, w.timeout, w.urgency)
+		}
+	}
+
+	data, err := json.Marshal(presets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again map[string]*notify.Notification
+	if err := json.Unmarshal(data, &again); err != nil {
+		t.Fatal(err)
+	}
+	for name, n := range presets {
+		if got := again[name]; got.Timeout != n.Timeout || got.Urgency != n.Urgency || got.Summary != n.Summary {
+			t.Errorf("%s: round trip gave %+v, want %+v", name, got, n)
+		}
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	n := &notify.Notification{Summary: "s", Timeout: notify.DefaultTimeout}
+	if err := n.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	newFakeServer(t)
+	c, err := n.DryRun(newTestNotifier(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ExpireTimeout != -1 {
+		t.Errorf("ExpireTimeout = %d, want -1", c.ExpireTimeout)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     notify.Notification
+		valid bool
+	}{
+		{"ok", notify.Notification{Summary: "s", Actions: []notify.Action{{"a", "A"}, {"b", "B"}}}, true},
+		{"empty summary", notify.Notification{Body: "b"}, false},
+		{"invalid UTF-8", notify.Notification{Summary: "s", Body: "\xff"}, false},
+		{"negative timeout", notify.Notification{Summary: "s", Timeout: notify.Duration(-time.Second)}, false},
+		{"bad urgency", notify.Notification{Summary: "s", Urgency: 7}, false},
+		{"duplicate action", notify.Notification{Summary: "s", Actions: []notify.Action{{"a", "A"}, {"a", "B"}}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.n.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateAllProblems(t *testing.T) {
+	n := notify.Notification{
+		Body:    "\xff",
+		Timeout: notify.Duration(-time.Second),
+		Actions: []notify.Action{{"a", "A"}, {"a", "B"}},
+	}
+	n.SetHint("urgency", "high")
+	n.SetHint("x-callback", func() {})
+	n.SetHint("x-vendor", int32(1))
+
+	err := n.Validate()
+	var invalid *notify.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Validate() = %v, want a *ValidationError", err)
+	}
+	want := []struct {
+		field string
+		err   error
+	}{
+		{"summary", notify.ErrEmptySummary},
+		{"body", notify.ErrInvalidUTF8},
+		{"timeout", notify.ErrNegativeTimeout},
+		{"actions[1]", notify.ErrDuplicateAction},
+		{"hints[urgency]", notify.ErrHintType},
+		{"hints[x-callback]", notify.ErrHintType},
+	}
+	if len(invalid.Errs) != len(want) {
+		t.Fatalf("Validate() found %d problems, want %d:\n%v", len(invalid.Errs), len(want), err)
+	}
+	for i, w := range want {
+		var fe *notify.FieldError
+		if !errors.As(invalid.Errs[i], &fe) || fe.Field != w.field || !errors.Is(fe, w.err) {
+			t.Errorf("problem %d = %v, want %s: %v", i, invalid.Errs[i], w.field, w.err)
+		}
+	}
+	if !errors.Is(err, notify.ErrNegativeTimeout) {
+		t.Error("errors.Is does not find the problems")
+	}
+	if lines := strings.Count(err.Error(), "\n"); lines != len(want) {
+		t.Errorf("Error() has %d lines of problems:\n%v", lines, err)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// This file holds the API of the first versions of the package: the implicit
+// notification and its package-level functions, and the methods of
+// Notification sending through the default Notifier. They all go through
+// the default Notifier, so they get the transport, quirks and adaptations of
+// Notifiers, and behave as they always did. legacy_test.go checks that.
+//
+// They stay as long as the module path does. Once the Notifier API is
+// stable, they will be marked Deprecated, pointing to their replacements:
+// NewNotifier and Notifier.Notify for SendMsg and Notification.Send,
+// Notification.Tag or Id for the Replace functions, and New or
+// WithAppName and WithAppIcon for the implicit notification. They will only
+// be removed with a new major version.
+
+// note acts as the default notification, which allows you to set default
+// parameters and then send messages without creating any Notifications.
+var note = Notification{
+	Timeout: Duration(3 * time.Second),
+	Urgency: NormalUrgency,
+
+	urgencySet: true,
+}
+
+// Init sets the defaults for the implicit notification.
+func Init(name, icon string, timeout time.Duration, urgency NotificationUrgency) {
+	note.Name = name
+	note.IconPath = icon
+	note.Timeout = Duration(timeout)
+	note.Urgency = urgency
+}
+
+// Name returns the name for the implicit notification.
+func Name() string { return note.Name }
+
+// SetName sets the name for the implicit notification.
+func SetName(name string) { note.Name = name }
+
+// IconPath returns the icon path for the implicit notification.
+func IconPath() string { return note.IconPath }
+
+// SetIconPath sets the icon path for the implicit notification.
+func SetIconPath(path string) { note.IconPath = path }
+
+// Timeout returns the timeout for the implicit notification.
+func Timeout() time.Duration { return note.TimeoutDuration() }
+
+// SetTimeout sets the timeout for the implicit notification.
+func SetTimeout(dur time.Duration) { note.Timeout = Duration(dur) }
+
+// Urgency returns the urgency level for the implicit notification.
+// It can be either LowUrgency, NormalUrgency, or CriticalUrgency.
+func Urgency() NotificationUrgency { return note.Urgency }
+
+// SetUrgency sets the urgency level for the implicit notification.
+// It can be either LowUrgency, NormalUrgency, or CriticalUrgency.
+func SetUrgency(urgency NotificationUrgency) { note.Urgency = urgency }
+
+// SendMsg sends the summary and the body as a notification, returning a unique
+// notification ID and an error, possibly nil. It takes all other values from
+// the implicit notification object.
+func SendMsg(summary, body string) (id uint32, err error) {
+	return SendUrgentMsg(summary, body, note.Urgency)
+}
+
+// SendUrgentMsg sends the summary and the body as a notification with the
+// urgency of urgency, and returns a unique notification ID and an error,
+// possibly nil. Otherwise it is like SendMsg.
+func SendUrgentMsg(summary, body string, urgency NotificationUrgency) (id uint32, err error) {
+	return sendImplicit(context.Background(), Default(), 0, summary, body, urgency)
+}
+
+// ReplaceMsg replaces the already existing notification with the ID id with
+// summary and body, returning the new ID and an error if it fails. It takes
+// all other values from the implicit notification object.
+//
+// In particular, if the notification it is replacing had other properties,
+// such as another urgency, these are also replaced by the defaults in the
+// implicit notification!
+func ReplaceMsg(id uint32, summary, body string) (newID uint32, err error) {
+	return ReplaceUrgentMsg(id, summary, body, note.Urgency)
+}
+
+// ReplaceUrgentMsg replaces the already existing notification with the ID id
+// with summary and body and urgency, returning the new ID and an error if it
+// fails. It takes all other values from the implicit notification object.
+//
+// The urgency is always sent: the zero NotificationUrgency is LowUrgency, not
+// the urgency the notification had. To change only the texts of a
+// notification, use Notification.ReplaceKeeping; WithStrictUrgency on the
+// default Notifier warns about the replacements lowering the urgency.
+func ReplaceUrgentMsg(id uint32, summary, body string, urgency NotificationUrgency) (newID uint32, err error) {
+	return sendImplicit(context.Background(), Default(), id, summary, body, urgency)
+}
+
+// sendImplicit sends summary and body with nf like Notifier.SendContext,
+// with the other values from the implicit notification object, replacing
+// the notification id if it is not 0.
+func sendImplicit(ctx context.Context, nf *Notifier, id uint32, summary, body string, urgency NotificationUrgency) (uint32, error) {
+	n := New(note.Name, summary, body, note.IconPath, 0, urgency)
+	n.Id, n.Timeout = id, note.Timeout
+	res, err := nf.notify(ctx, n, false)
+	return res.Id, err
+}
+
+// implicitCall returns the call of nf sending summary and body with the
+// other values from the implicit notification object, for QuickSend.
+func implicitCall(nf *Notifier, id uint32, summary, body string, urgency NotificationUrgency) Call {
+	summary, body = nf.redact(summary, body)
+	return Call{
+		AppName:       nf.appNameFor(note.Name),
+		ReplacesID:    id,
+		AppIcon:       note.IconPath,
+		Summary:       summary,
+		Body:          body,
+		Hints:         nf.urgencyHint(urgency),
+		ExpireTimeout: note.timeoutInMS(),
+		Urgency:       urgency,
+	}
+}
+
+// ServiceAvailable returns true if notifications via DBus are available.
+//
+// First, it initiates a connection via DBus to find out whether DBus is
+// available, then it contacts the notification service to find out if it is
+// available. If one or the other is not available, it returns false.
+//
+// Before using notify, it is a good idea (though not necessary) to test
+// if this service is available. If it's not available, this does not
+// tell you why though. Maybe another day.
+func ServiceAvailable() bool {
+	return Default().Available()
+}
+
+// Send sends the notification n as it is, and returns an err, possibly nil.
+// Since n is a copy, the ID assigned by the daemon is lost; use SendR to get
+// it.
+func (n Notification) Send() (err error) {
+	_, err = Default().Notify(&n)
+	return err
+}
+
+// ReplaceMsg is identical to notify.ReplaceMsg, except that the rest of the
+// values come from n.
+func (n Notification) ReplaceMsg(summary, body string) (err error) {
+	n.Summary, n.Body = summary, body
+	return n.Send()
+}
+
+// ReplaceUrgentMsg is identical to notify.ReplaceUrgentMsg, except that the
+// rest of the values come from n. Use ReplaceKeeping to keep the urgency of
+// n.
+func (n Notification) ReplaceUrgentMsg(summary, body string, urgency NotificationUrgency) (err error) {
+	n.Summary, n.Body = summary, body
+	n.SetUrgency(urgency)
+	return n.Send()
+}
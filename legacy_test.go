@@ -0,0 +1,153 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+// The tests of this file only use the API of the first versions of the
+// package, see legacy.go, so that it keeps working as it did.
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestServiceAvailable(t *testing.T) {
+	newFakeServer(t)
+	if !notify.ServiceAvailable() {
+		t.Fatal("ServiceAvailable() = false with a running daemon")
+	}
+}
+
+func TestSendMsg(t *testing.T) {
+	s := newFakeServer(t)
+	notify.Init("test", "icon.png", 2*time.Second, notify.CriticalUrgency)
+	defer notify.Init("", "", 3*time.Second, notify.NormalUrgency)
+
+	id, err := notify.SendMsg("summary", "body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if got.ID != id || got.AppName != "test" || got.AppIcon != "icon.png" ||
+		got.Summary != "summary" || got.Body != "body" || got.ExpireTimeout != 2000 {
+		t.Errorf("unexpected notification %+v", got)
+	}
+	if u := got.Hints["urgency"].Value(); u != byte(notify.CriticalUrgency) {
+		t.Errorf("urgency hint = %v, want %v", u, byte(notify.CriticalUrgency))
+	}
+
+	newID, err := notify.ReplaceMsg(id, "replaced", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.ReplacesID != id || newID != id {
+		t.Errorf("ReplaceMsg sent replaces_id %d and got %d, want %d", got.ReplacesID, newID, id)
+	}
+}
+
+func TestImplicitNotification(t *testing.T) {
+	defer notify.Init("", "", 3*time.Second, notify.NormalUrgency)
+
+	if notify.Timeout() != 3*time.Second || notify.Urgency() != notify.NormalUrgency {
+		t.Errorf("defaults are a timeout of %v and urgency %v", notify.Timeout(), notify.Urgency())
+	}
+	notify.SetName("legacy")
+	notify.SetIconPath("legacy.png")
+	notify.SetTimeout(5 * time.Second)
+	notify.SetUrgency(notify.LowUrgency)
+	if notify.Name() != "legacy" || notify.IconPath() != "legacy.png" ||
+		notify.Timeout() != 5*time.Second || notify.Urgency() != notify.LowUrgency {
+		t.Errorf("implicit notification is %q, %q, %v, %v",
+			notify.Name(), notify.IconPath(), notify.Timeout(), notify.Urgency())
+	}
+
+	s := newFakeServer(t)
+	id, err := notify.SendUrgentMsg("urgent", "", notify.CriticalUrgency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if got.AppName != "legacy" || got.AppIcon != "legacy.png" || got.ExpireTimeout != 5000 {
+		t.Errorf("unexpected notification %+v", got)
+	}
+	if u := got.Hints["urgency"].Value(); u != byte(notify.CriticalUrgency) {
+		t.Errorf("urgency hint = %v, want %v", u, byte(notify.CriticalUrgency))
+	}
+	if notify.Urgency() != notify.LowUrgency {
+		t.Error("SendUrgentMsg changed the implicit urgency")
+	}
+
+	if _, err := notify.ReplaceUrgentMsg(id, "calmer", "", notify.LowUrgency); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.ReplacesID != id || got.Summary != "calmer" || got.Hints["urgency"].Value() != byte(notify.LowUrgency) {
+		t.Errorf("ReplaceUrgentMsg sent %+v", got)
+	}
+}
+
+func TestNotificationSend(t *testing.T) {
+	s := newFakeServer(t)
+	n := notify.New("legacy", "summary", "body", "icon.png", time.Second, notify.NormalUrgency)
+	if err := n.Send(); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if got.AppName != "legacy" || got.Summary != "summary" || got.Body != "body" ||
+		got.AppIcon != "icon.png" || got.ExpireTimeout != 1000 {
+		t.Errorf("unexpected notification %+v", got)
+	}
+	// Send has a value receiver: the ID is not recorded.
+	if n.Id != 0 {
+		t.Errorf("Send set the ID to %d", n.Id)
+	}
+
+	if err := n.ReplaceMsg("new summary", "new body"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.Summary != "new summary" || got.Body != "new body" || got.AppIcon != "icon.png" {
+		t.Errorf("ReplaceMsg sent %+v", got)
+	}
+	if err := n.ReplaceUrgentMsg("critical", "", notify.CriticalUrgency); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.Summary != "critical" || got.Hints["urgency"].Value() != byte(notify.CriticalUrgency) {
+		t.Errorf("ReplaceUrgentMsg sent %+v", got)
+	}
+	if n.Summary != "summary" || n.Body != "body" || n.Urgency != notify.NormalUrgency {
+		t.Errorf("the replace methods changed the notification to %+v", n)
+	}
+	if got := len(s.notifications()); got != 3 {
+		t.Errorf("the daemon received %d notifications, want 3", got)
+	}
+}
+
+// TestNotificationDocExample runs the example of the documentation of
+// Notification.
+func TestNotificationDocExample(t *testing.T) {
+	s := newFakeServer(t)
+
+	critical := notify.New("prog", "", "", "critical-icon.png", time.Duration(0), notify.CriticalUrgency)
+	boring := notify.New("prog", "", "", "low-icon.png", 1*time.Second, notify.LowUrgency)
+	if err := boring.ReplaceMsg("Nothing is happening... boring!", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := critical.ReplaceMsg("Your computer is on fire!", "Here is what you should do:\n ..."); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := s.notifications()
+	if len(sent) != 2 {
+		t.Fatalf("the daemon received %d notifications, want 2", len(sent))
+	}
+	if b := sent[0]; b.AppIcon != "low-icon.png" || b.Summary != "Nothing is happening... boring!" ||
+		b.ExpireTimeout != 1000 || b.Hints["urgency"].Value() != byte(notify.LowUrgency) {
+		t.Errorf("boring notification %+v", b)
+	}
+	if c := sent[1]; c.AppIcon != "critical-icon.png" || c.Summary != "Your computer is on fire!" ||
+		c.ExpireTimeout != 0 || c.Hints["urgency"].Value() != byte(notify.CriticalUrgency) {
+		t.Errorf("critical notification %+v", c)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package libnotify mimics the C API of libnotify on top of notify.Notifier,
+// so that porting a C application is mechanical:
+//
+//	notify_init("app")                       libnotify.Init("app")
+//	notify_notification_new(s, b, icon)      libnotify.NotificationNew(s, b, icon)
+//	notify_notification_set_timeout(n, ms)   n.SetTimeout(ms)
+//	notify_notification_set_urgency(n, u)    n.SetUrgency(u)
+//	notify_notification_set_hint(n, k, v)    n.SetHint(k, v)
+//	notify_notification_add_action(n, ...)   n.AddAction(action, label, callback)
+//	notify_notification_show(n, &error)      n.Show()
+//	notify_notification_close(n, &error)     n.Close()
+//	g_signal_connect(n, "closed", ...)       n.Closed = callback
+//	notify_uninit()                          libnotify.Uninit()
+//
+// As with libnotify, showing a notification again updates it in place. No
+// main loop is needed: the callbacks are called from the goroutines of the
+// Notifier, so they must not block.
+package libnotify
+
+import (
+	"errors"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// ErrNotInitted is returned by Show and Close before Init, or after Uninit.
+var ErrNotInitted = errors.New("libnotify: not initted")
+
+// The timeouts of SetTimeout, as NOTIFY_EXPIRES_DEFAULT and
+// NOTIFY_EXPIRES_NEVER.
+const (
+	ExpiresDefault = -1
+	ExpiresNever   = 0
+)
+
+// The urgencies of SetUrgency, as NOTIFY_URGENCY_LOW, NOTIFY_URGENCY_NORMAL
+// and NOTIFY_URGENCY_CRITICAL.
+const (
+	UrgencyLow      = notify.LowUrgency
+	UrgencyNormal   = notify.NormalUrgency
+	UrgencyCritical = notify.CriticalUrgency
+)
+
+var (
+	mu       sync.Mutex
+	notifier *notify.Notifier
+	appName  string
+)
+
+// Init creates the Notifier of the package, sending name for the
+// notifications that do not set their own, with the options opts. Like
+// notify_init, calling it again once initted does nothing.
+func Init(name string, opts ...notify.Option) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if notifier != nil {
+		return nil
+	}
+	nf, err := notify.NewNotifier(append([]notify.Option{notify.WithAppName(name)}, opts...)...)
+	if err != nil {
+		return err
+	}
+	notifier, appName = nf, name
+	return nil
+}
+
+// Uninit closes the Notifier of the package. Init can be called again
+// afterwards.
+func Uninit() error {
+	mu.Lock()
+	nf := notifier
+	notifier, appName = nil, ""
+	mu.Unlock()
+	if nf == nil {
+		return nil
+	}
+	return nf.Close()
+}
+
+// IsInitted returns true between Init and Uninit.
+func IsInitted() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return notifier != nil
+}
+
+// AppName returns the name given to Init.
+func AppName() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return appName
+}
+
+// Notifier returns the Notifier of the package, or nil before Init, to mix
+// this package with the rest of the notify API.
+func Notifier() *notify.Notifier {
+	mu.Lock()
+	defer mu.Unlock()
+	return notifier
+}
+
+// ServerCaps returns the capabilities of the notification daemon.
+func ServerCaps() ([]string, error) {
+	nf := Notifier()
+	if nf == nil {
+		return nil, ErrNotInitted
+	}
+	return nf.Capabilities()
+}
+
+// ActionCallback is called with the notification and the key of the action
+// invoked by the user.
+type ActionCallback func(n *Notification, action string)
+
+// Notification is a notification shown and updated with Show, which can be
+// used by a single goroutine.
+type Notification struct {
+	// Closed is called when the notification is closed, as the "closed"
+	// signal of libnotify. It is optional and can be nil, and must be set
+	// before Show.
+	Closed func(n *Notification)
+
+	n *notify.Notification
+	// hints are the keys of the hints set, for ClearHints.
+	hints map[string]bool
+
+	mu        sync.Mutex
+	callbacks map[string]ActionCallback
+	reason    notify.CloseReason
+}
+
+// NotificationNew returns a new notification, with the default timeout of
+// the daemon.
+func NotificationNew(summary, body, icon string) *Notification {
+	n := &Notification{
+		n:         notify.New("", summary, body, icon, 0, notify.NormalUrgency),
+		hints:     make(map[string]bool),
+		callbacks: make(map[string]ActionCallback),
+	}
+	n.n.Timeout = notify.DefaultTimeout
+	// The daemon closes the notification once an action is invoked, as it
+	// does for libnotify, which does not set the "resident" hint.
+	n.n.CloseOnAction = true
+	n.n.OnAction = n.onAction
+	n.n.OnClose = n.onClose
+	return n
+}
+
+// Update changes the texts and the icon of n, which are shown by the next
+// Show.
+func (n *Notification) Update(summary, body, icon string) {
+	n.n.Summary, n.n.Body, n.n.IconPath = summary, body, icon
+}
+
+// SetAppName sets the application name of n, in place of the one given to
+// Init.
+func (n *Notification) SetAppName(name string) {
+	n.n.Name = name
+}
+
+// SetTimeout sets the timeout of n in milliseconds, or ExpiresDefault or
+// ExpiresNever.
+func (n *Notification) SetTimeout(ms int) {
+	if ms < 0 {
+		n.n.Timeout = notify.DefaultTimeout
+		return
+	}
+	n.n.Timeout = notify.Duration(time.Duration(ms) * time.Millisecond)
+}
+
+// SetUrgency sets the urgency of n.
+func (n *Notification) SetUrgency(u notify.NotificationUrgency) {
+	n.n.SetUrgency(u)
+}
+
+// SetCategory sets the category of n, such as "email.arrived".
+func (n *Notification) SetCategory(category string) {
+	n.SetHint("category", category)
+}
+
+// SetHint sets the hint key to value, encoded as Notification.SetHint
+// does. Like with libnotify, a nil value removes the hint.
+func (n *Notification) SetHint(key string, value interface{}) {
+	n.n.SetHint(key, value)
+	if value == nil {
+		delete(n.hints, key)
+	} else {
+		n.hints[key] = true
+	}
+}
+
+// ClearHints removes the hints set with SetHint and SetCategory, and resets
+// the urgency to UrgencyNormal.
+func (n *Notification) ClearHints() {
+	for k := range n.hints {
+		n.n.SetHint(k, nil)
+	}
+	n.hints = make(map[string]bool)
+	n.n.SetUrgency(notify.NormalUrgency)
+}
+
+// SetImage embeds img in n, as notify_notification_set_image_from_pixbuf.
+func (n *Notification) SetImage(img image.Image) {
+	n.n.SetImage(img)
+}
+
+// AddAction adds the action with the key action and label, calling callback
+// when the user invokes it. The key "default" is the action of clicking the
+// notification.
+func (n *Notification) AddAction(action, label string, callback ActionCallback) {
+	n.n.AddAction(action, label)
+	n.mu.Lock()
+	n.callbacks[action] = callback
+	n.mu.Unlock()
+}
+
+// ClearActions removes the actions of n.
+func (n *Notification) ClearActions() {
+	n.n.SetActions()
+	n.mu.Lock()
+	n.callbacks = make(map[string]ActionCallback)
+	n.mu.Unlock()
+}
+
+// Show shows n, or updates it in place if it was already shown. After
+// Close, it is shown anew.
+func (n *Notification) Show() error {
+	nf := Notifier()
+	if nf == nil {
+		return ErrNotInitted
+	}
+	_, err := nf.Notify(n.n)
+	return err
+}
+
+// Close closes n if it is shown.
+func (n *Notification) Close() error {
+	nf := Notifier()
+	if nf == nil {
+		return ErrNotInitted
+	}
+	return nf.Dismiss(n.n)
+}
+
+// ID returns the ID of n given by the daemon, or 0 before Show.
+func (n *Notification) ID() uint32 {
+	return n.n.Id
+}
+
+// ClosedReason returns why n was last closed, or 0 if it never was, where
+// libnotify returns -1.
+func (n *Notification) ClosedReason() notify.CloseReason {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.reason
+}
+
+// onAction calls the callback of the action key.
+func (n *Notification) onAction(key string) {
+	n.mu.Lock()
+	cb := n.callbacks[key]
+	n.mu.Unlock()
+	if cb != nil {
+		cb(n, key)
+	}
+}
+
+// onClose records the reason and calls Closed.
+func (n *Notification) onClose(reason notify.CloseReason) {
+	n.mu.Lock()
+	n.reason = reason
+	n.mu.Unlock()
+	if n.Closed != nil {
+		n.Closed(n)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package libnotify_test
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/internal/testbus"
+	"github.com/Schnouki/notify/libnotify"
+	"github.com/Schnouki/notify/server"
+	"github.com/godbus/dbus/v5"
+)
+
+var busAddress string
+
+func TestMain(m *testing.M) {
+	os.Exit(testbus.Run(m, &busAddress))
+}
+
+// daemon is a server.Handler recording notifications.
+type daemon struct {
+	mu       sync.Mutex
+	received []server.ReceivedNotification
+	closed   []uint32
+}
+
+func (d *daemon) Notify(r *server.Responder, n server.ReceivedNotification) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.received = append(d.received, n)
+	return nil
+}
+
+func (d *daemon) CloseNotification(r *server.Responder, id uint32) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = append(d.closed, id)
+	return nil
+}
+
+func (d *daemon) last(t *testing.T) server.ReceivedNotification {
+	t.Helper()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.received) == 0 {
+		t.Fatal("no notification received")
+	}
+	return d.received[len(d.received)-1]
+}
+
+// serve starts a notification daemon handled by d on the private bus, and
+// inits the package.
+func serve(t *testing.T, d *daemon) *server.Server {
+	t.Helper()
+	if busAddress == "" {
+		t.Skip("no private D-Bus session bus available")
+	}
+	conn, err := dbus.Connect(busAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s, err := server.New(conn, d, server.WithCapabilities("body", "actions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	if err := libnotify.Init("porting"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { libnotify.Uninit() })
+	return s
+}
+
+// wait waits for ch to be closed.
+func wait(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestShowUpdatesInPlace(t *testing.T) {
+	var d daemon
+	serve(t, &d)
+
+	n := libnotify.NotificationNew("Downloading", "0%", "network-receive")
+	if err := n.Show(); err != nil {
+		t.Fatal(err)
+	}
+	first := d.last(t)
+	if first.AppName != "porting" || first.Summary != "Downloading" || first.AppIcon != "network-receive" ||
+		first.ExpireTimeout != -1 || first.ReplacesID != 0 {
+		t.Errorf("received %+v", first)
+	}
+	if n.ID() != first.ID {
+		t.Errorf("ID() = %d, want %d", n.ID(), first.ID)
+	}
+
+	n.Update("Downloaded", "100%", "network-receive")
+	if err := n.Show(); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.last(t); got.ReplacesID != first.ID || got.ID != first.ID || got.Body != "100%" {
+		t.Errorf("second Show() sent %+v, want it to replace %d", got, first.ID)
+	}
+}
+
+func TestHints(t *testing.T) {
+	var d daemon
+	serve(t, &d)
+
+	n := libnotify.NotificationNew("Battery low", "5% left", "battery-caution")
+	n.SetTimeout(libnotify.ExpiresNever)
+	n.SetUrgency(libnotify.UrgencyLow)
+	n.SetCategory("device")
+	n.SetHint("x-level", int32(5))
+	n.SetAppName("power")
+	if err := n.Show(); err != nil {
+		t.Fatal(err)
+	}
+	got := d.last(t)
+	if got.AppName != "power" || got.ExpireTimeout != 0 {
+		t.Errorf("received %+v", got)
+	}
+	want := map[string]interface{}{
+		"urgency":  byte(notify.LowUrgency),
+		"category": "device",
+		"x-level":  int32(5),
+	}
+	for k, v := range want {
+		if h, ok := got.Hints[k]; !ok || h.Value() != v {
+			t.Errorf("hint %s = %v, want %v", k, h, v)
+		}
+	}
+
+	n.ClearHints()
+	n.SetTimeout(1500)
+	if err := n.Show(); err != nil {
+		t.Fatal(err)
+	}
+	got = d.last(t)
+	if _, ok := got.Hints["category"]; ok || got.ExpireTimeout != 1500 {
+		t.Errorf("after ClearHints received %+v", got)
+	}
+	if v := got.Hints["urgency"].Value(); v != byte(notify.NormalUrgency) {
+		t.Errorf("urgency after ClearHints = %v", v)
+	}
+}
+
+func TestActionAndClosed(t *testing.T) {
+	var d daemon
+	s := serve(t, &d)
+
+	n := libnotify.NotificationNew("New mail", "From: someone", "")
+	invoked := make(chan struct{})
+	n.AddAction("read", "Read", func(got *libnotify.Notification, action string) {
+		if got != n || action != "read" {
+			t.Errorf("callback called with %p %q", got, action)
+		}
+		close(invoked)
+	})
+	closed := make(chan struct{})
+	n.Closed = func(*libnotify.Notification) { close(closed) }
+	if err := n.Show(); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.last(t); len(got.Actions) != 1 || got.Actions[0].Key != "read" {
+		t.Errorf("actions = %v", got.Actions)
+	}
+
+	s.Responder().ActionInvoked(n.ID(), "read")
+	wait(t, invoked, "the action callback")
+	// As for libnotify, the notification is closed after the action.
+	wait(t, closed, "the closed callback")
+	if r := n.ClosedReason(); r != notify.ReasonClosed {
+		t.Errorf("ClosedReason() = %v, want closed", r)
+	}
+}
+
+func TestClose(t *testing.T) {
+	var d daemon
+	serve(t, &d)
+
+	n := libnotify.NotificationNew("Recording", "", "media-record")
+	if n.ClosedReason() != 0 {
+		t.Errorf("ClosedReason() before Show = %v", n.ClosedReason())
+	}
+	closed := make(chan struct{})
+	n.Closed = func(*libnotify.Notification) { close(closed) }
+	if err := n.Show(); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, closed, "the closed callback")
+	if r := n.ClosedReason(); r != notify.ReasonClosed {
+		t.Errorf("ClosedReason() = %v, want closed", r)
+	}
+	d.mu.Lock()
+	if len(d.closed) != 1 || d.closed[0] != n.ID() {
+		t.Errorf("the daemon closed %v, want %d", d.closed, n.ID())
+	}
+	d.mu.Unlock()
+}
+
+func TestInit(t *testing.T) {
+	if busAddress == "" {
+		t.Skip("no private D-Bus session bus available")
+	}
+	n := libnotify.NotificationNew("too early", "", "")
+	if err := n.Show(); !errors.Is(err, libnotify.ErrNotInitted) {
+		t.Errorf("Show() before Init = %v, want ErrNotInitted", err)
+	}
+
+	if err := libnotify.Init("first"); err != nil {
+		t.Fatal(err)
+	}
+	// Like notify_init, a second Init keeps the first one.
+	if err := libnotify.Init("second"); err != nil {
+		t.Fatal(err)
+	}
+	if !libnotify.IsInitted() || libnotify.AppName() != "first" {
+		t.Errorf("after Init: IsInitted() = %v, AppName() = %q", libnotify.IsInitted(), libnotify.AppName())
+	}
+	if err := libnotify.Uninit(); err != nil {
+		t.Fatal(err)
+	}
+	if libnotify.IsInitted() || libnotify.Notifier() != nil {
+		t.Error("still initted after Uninit")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import "sync"
+
+// notificationLock serializes the sends and closes of one *Notification,
+// see lockNotification.
+type notificationLock struct {
+	mu sync.Mutex
+	// refs counts the goroutines holding or waiting for mu.
+	refs int
+}
+
+// lockNotification waits until no other send or close of n runs on nf,
+// and returns the function letting the next one run.
+func (nf *Notifier) lockNotification(n *Notification) (unlock func()) {
+	nf.mu.Lock()
+	l, ok := nf.notificationLocks[n]
+	if !ok {
+		if nf.notificationLocks == nil {
+			nf.notificationLocks = make(map[*Notification]*notificationLock)
+		}
+		l = &notificationLock{}
+		nf.notificationLocks[n] = l
+	}
+	l.refs++
+	nf.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		nf.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(nf.notificationLocks, n)
+		}
+		nf.mu.Unlock()
+	}
+}
+
+// Dismiss closes the notification n, sent by nf.
+//
+// Unlike CloseNotification, Dismiss follows the lifecycle of n: if a send
+// of n is in flight, Dismiss waits for its ID and then closes it, and
+// closing n again, or closing it before it was sent, does nothing and
+// returns nil. Sending n after Dismiss shows it anew, with a new ID, rather
+// than replacing the closed one.
+func (nf *Notifier) Dismiss(n *Notification) error {
+	defer nf.lockNotification(n)()
+	if n.closed || n.Id == 0 {
+		return nil
+	}
+	if err := nf.CloseNotification(n.Id); err != nil {
+		return err
+	}
+	n.closed = true
+	return nil
+}
+
+// Close closes the notification n sent with SendR or the default Notifier,
+// see Notifier.Dismiss.
+func (n *Notification) Close() error {
+	return Default().Dismiss(n)
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestDismissTwice(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	n := notify.New("test", "once", "", "", 0, notify.NormalUrgency)
+
+	// Before it is sent, there is nothing to close.
+	if err := nf.Dismiss(n); err != nil || len(s.closedIDs()) != 0 {
+		t.Errorf("Dismiss() = %v before sending, closed %v", err, s.closedIDs())
+	}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := nf.Dismiss(n); err != nil {
+			t.Fatalf("Dismiss() #%d = %v", i+1, err)
+		}
+	}
+	if got := s.closedIDs(); len(got) != 1 || got[0] != n.Id {
+		t.Errorf("closed %v, want only %d", got, n.Id)
+	}
+}
+
+func TestSendAfterDismiss(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	n := notify.New("test", "again", "", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	first := n.Id
+	if err := nf.Dismiss(n); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.ReplacesID != 0 || n.Id == first {
+		t.Errorf("sent again replacing %d, with ID %d, want a new notification", got.ReplacesID, n.Id)
+	}
+	// The new lifecycle can be closed again.
+	if err := nf.Dismiss(n); err != nil || len(s.closedIDs()) != 2 {
+		t.Errorf("Dismiss() = %v, closed %v", err, s.closedIDs())
+	}
+}
+
+func TestDismissDuringSend(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	n := notify.New("test", "in flight", "", "", 0, notify.NormalUrgency)
+
+	release := s.stall(t)
+	sent := make(chan error, 1)
+	go func() {
+		_, err := nf.Notify(n)
+		sent <- err
+	}()
+	waitFor(t, "the send", func() bool { return s.received() == 1 })
+	dismissed := make(chan error, 1)
+	go func() { dismissed <- nf.Dismiss(n) }()
+
+	select {
+	case err := <-dismissed:
+		t.Fatalf("Dismiss() = %v before the daemon assigned an ID", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	if err := <-sent; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-dismissed; err != nil {
+		t.Fatal(err)
+	}
+	if got := s.closedIDs(); len(got) != 1 || got[0] != s.last(t).ID {
+		t.Errorf("closed %v, want the ID of the send %d", got, s.last(t).ID)
+	}
+}
+
+func TestConcurrentSends(t *testing.T) {
+	s := newFakeServer(t)
+	s.setReplyDelay(10 * time.Millisecond)
+	nf := newTestNotifier(t)
+	n := notify.New("test", "twice", "", "", 0, notify.NormalUrgency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := nf.Notify(n); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// The second send waits for the ID of the first one, and replaces it.
+	got := s.notifications()
+	if len(got) != 2 || got[0].ReplacesID != 0 || got[1].ReplacesID != got[0].ID {
+		t.Errorf("sent %+v, want the second send replacing the first", got)
+	}
+}
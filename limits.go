@@ -0,0 +1,116 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// specHints are the hints of the specification that are not images or
+// positions. The other hints are vendor-specific.
+var specHints = map[string]bool{
+	"action-icons":   true,
+	"category":       true,
+	"desktop-entry":  true,
+	"resident":       true,
+	"sound-file":     true,
+	"sound-name":     true,
+	"suppress-sound": true,
+	"transient":      true,
+	"urgency":        true,
+}
+
+// imageHints are the hints holding the image of a notification, in all the
+// versions of the specification.
+var imageHints = map[string]bool{
+	"image-data": true, "image_data": true, "icon_data": true,
+	"image-path": true, "image_path": true,
+}
+
+// WithDropHintsOnLimits makes the Notifier send a notification again, once,
+// without its optional hints when the daemon rejects it with a
+// LimitsExceeded error, like xfce4-notifyd does for notifications with
+// many hints. The optional hints are, in the order they are reported in
+// SendResult.DroppedHints: the vendor-specific hints (all those not in the
+// specification, including the stack tags), the image hints, then the "x"
+// and "y" positioning hints. The urgency, category and the other hints of
+// the specification are kept.
+func WithDropHintsOnLimits(enabled bool) Option {
+	return func(nf *Notifier) error {
+		nf.dropHintsOnLimits = enabled
+		return nil
+	}
+}
+
+// limitsExceeded returns true if err is a LimitsExceeded error of the
+// daemon or of the bus.
+func limitsExceeded(err error) bool {
+	var dbusErr dbus.Error
+	return errors.As(err, &dbusErr) && strings.HasSuffix(dbusErr.Name, ".LimitsExceeded")
+}
+
+// optionalHint returns the rank of the hint key in the order optional hints
+// are dropped, or 0 if it must be kept.
+func optionalHint(key string) int {
+	switch {
+	case key == "x" || key == "y":
+		return 3
+	case imageHints[key]:
+		return 2
+	case !specHints[key]:
+		return 1
+	}
+	return 0
+}
+
+// slimCall returns c without its optional hints, and the keys of the hints
+// dropped, in the order they are dropped.
+func slimCall(c Call) (Call, []string) {
+	hints := make(map[string]dbus.Variant, len(c.Hints))
+	var dropped []string
+	for k, v := range c.Hints {
+		if optionalHint(k) != 0 {
+			dropped = append(dropped, k)
+		} else {
+			hints[k] = v
+		}
+	}
+	sort.Slice(dropped, func(i, j int) bool {
+		ri, rj := optionalHint(dropped[i]), optionalHint(dropped[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return dropped[i] < dropped[j]
+	})
+	c.Hints = hints
+	return c, dropped
+}
+
+// sendWithinLimits sends c, and again without its optional hints if the
+// daemon rejects it for exceeding its limits and nf allows it. It returns
+// the extra out-arguments of the reply and the hints dropped by the second
+// send.
+func (nf *Notifier) sendWithinLimits(ctx context.Context, c Call) (uint32, []interface{}, []string, error) {
+	id, extra, err := nf.sendExtra(ctx, c)
+	if err == nil || !nf.dropHintsOnLimits || !limitsExceeded(err) {
+		return id, extra, nil, err
+	}
+	slim, dropped := slimCall(c)
+	if len(dropped) == 0 {
+		return id, extra, nil, err
+	}
+	nf.log(LevelWarn, fmt.Sprintf("daemon limits exceeded, sending again without the hints %s", strings.Join(dropped, ", ")), err)
+	id, extra, err = nf.sendExtra(ctx, slim)
+	if err != nil {
+		return id, nil, nil, err
+	}
+	return id, extra, dropped, nil
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestDropHintsOnLimits(t *testing.T) {
+	s := newFakeServer(t)
+	s.setMaxHints(3)
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithDropHintsOnLimits(true), notify.WithLogger(logs.log))
+
+	n := notify.New("test", "many hints", "", "", 0, notify.CriticalUrgency)
+	n.AddHints(notify.CategoryHint("transfer.complete"), notify.XYHint{X: 10, Y: 20})
+	n.SetHint("x-vendor-color", "red")
+	n.SetImage(image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	res, err := nf.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"x-vendor-color", "image-data", "x", "y"}
+	if !reflect.DeepEqual(res.DroppedHints, want) {
+		t.Errorf("DroppedHints = %q, want %q", res.DroppedHints, want)
+	}
+	sent := s.last(t)
+	if len(sent.Hints) != 2 || sent.Hints["urgency"].Value() != byte(notify.CriticalUrgency) || sent.Hints["category"].Value() != "transfer.complete" {
+		t.Errorf("retry sent the hints %v, want only urgency and category", sent.Hints)
+	}
+	if !logs.logged("daemon limits exceeded") {
+		t.Error("the dropped hints were not logged")
+	}
+}
+
+func TestDropHintsOnLimitsDisabled(t *testing.T) {
+	s := newFakeServer(t)
+	s.setMaxHints(1)
+	nf := newTestNotifier(t)
+
+	n := notify.New("test", "many hints", "", "", 0, notify.NormalUrgency)
+	n.SetHint("x-vendor-color", "red")
+	if _, err := nf.Notify(n); err == nil {
+		t.Error("Notify succeeded without WithDropHintsOnLimits")
+	}
+	if got := s.received(); got != 1 {
+		t.Errorf("server received %d calls, want no retry", got)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Localizer translates the texts shown to the user. Sprintf returns the
+// text of the message key, formatted with args like fmt.Sprintf formats the
+// English text of the key, see the Msg constants. Keys it does not know are
+// those of Notifications with Localized set.
+type Localizer interface {
+	Sprintf(key string, args ...any) string
+}
+
+// The keys of the texts the package shows to the user, with their English
+// text and arguments.
+const (
+	// MsgMore is "and %d more", the summary of the rollup of a Group, with
+	// the number of notifications it stands for.
+	MsgMore = "notify.more"
+	// MsgAck is "OK", the label of the AckAction of the Persistent
+	// notifications.
+	MsgAck = "notify.ack"
+	// MsgOpen is "Open" and MsgCopyPath "Copy path", the labels of the
+	// actions of NotifyFile.
+	MsgOpen     = "notify.open"
+	MsgCopyPath = "notify.copy-path"
+	// MsgNextPage is "Next", the label of the NextPageAction of
+	// SendPaginated.
+	MsgNextPage = "notify.next-page"
+	// MsgReply is "Reply", the label of the reply of Prompt, and MsgEnter
+	// "Enter", that of its action on daemons without inline replies.
+	MsgReply = "notify.reply"
+	MsgEnter = "notify.enter"
+	// MsgAlways is "Always %s", the label of the actions of AskRemember
+	// remembering the choice, with the choice.
+	MsgAlways = "notify.always"
+	// MsgCancel is "Cancel" and MsgOpenFolder "Open folder", the labels of
+	// the actions of a Transfer.
+	MsgCancel     = "notify.cancel"
+	MsgOpenFolder = "notify.open-folder"
+	// MsgTransferProgress is "%s of %s", the body of a Transfer with the
+	// bytes done and the total, and MsgTransferSpeed "%s (%s/s)" that body
+	// followed by the speed.
+	MsgTransferProgress = "notify.transfer-progress"
+	MsgTransferSpeed    = "notify.transfer-speed"
+	// MsgTransferFailed is "%s failed" and MsgTransferComplete "%s
+	// complete", the summaries of a Transfer once done, with its title.
+	MsgTransferFailed   = "notify.transfer-failed"
+	MsgTransferComplete = "notify.transfer-complete"
+	// MsgCatchUp is "While you were away", the summary of the catch-up
+	// notification of ResumeCollapse, and MsgCatchUpItem "%[2]s (%[1]d):
+	// %[3]s" its lines, with the count, the category or application, and
+	// the last summary. MsgShowAll is "Show all", the label of its action.
+	MsgCatchUp     = "notify.catch-up"
+	MsgCatchUpItem = "notify.catch-up-item"
+	MsgShowAll     = "notify.show-all"
+	// MsgMoreActions is "More…", the label of the MoreActionsAction.
+	MsgMoreActions = "notify.more-actions"
+)
+
+// englishMessages are the English texts of the keys.
+var englishMessages = map[string]string{
+	MsgMore:             "and %d more",
+	MsgAck:              "OK",
+	MsgOpen:             "Open",
+	MsgCopyPath:         "Copy path",
+	MsgNextPage:         "Next",
+	MsgReply:            "Reply",
+	MsgEnter:            "Enter",
+	MsgAlways:           "Always %s",
+	MsgCancel:           "Cancel",
+	MsgOpenFolder:       "Open folder",
+	MsgTransferProgress: "%s of %s",
+	MsgTransferSpeed:    "%s (%s/s)",
+	MsgTransferFailed:   "%s failed",
+	MsgTransferComplete: "%s complete",
+	MsgCatchUp:          "While you were away",
+	MsgCatchUpItem:      "%[2]s (%[1]d): %[3]s",
+	MsgShowAll:          "Show all",
+	MsgMoreActions:      "More…",
+}
+
+// EnglishLocalizer is the Localizer used by default: it returns the English
+// texts of the keys, and the other keys as is, formatted with args if any.
+type EnglishLocalizer struct{}
+
+// Sprintf returns the English text of key formatted with args.
+func (EnglishLocalizer) Sprintf(key string, args ...any) string {
+	format, ok := englishMessages[key]
+	if !ok {
+		format = key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// WithLocalizer makes the Notifier get the texts it shows to the user from
+// l, instead of EnglishLocalizer: the labels of the actions it adds, and
+// the summaries and bodies it writes, see the Msg constants. The
+// notifications with Localized set have their texts translated by l too.
+func WithLocalizer(l Localizer) Option {
+	return func(nf *Notifier) error {
+		if l == nil {
+			return errors.New("notify: nil localizer")
+		}
+		nf.localizer = l
+		return nil
+	}
+}
+
+// localize returns the text of key, formatted with args.
+func (nf *Notifier) localize(key string, args ...any) string {
+	if nf.localizer == nil {
+		return EnglishLocalizer{}.Sprintf(key, args...)
+	}
+	return nf.localizer.Sprintf(key, args...)
+}
+
+// localizeActions returns actions with their labels translated.
+func (nf *Notifier) localizeActions(actions []Action) []Action {
+	out := make([]Action, len(actions))
+	for i, a := range actions {
+		out[i] = Action{a.Key, nf.localize(a.Label)}
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// fakeLocalizer translates key to "fr(key args)", recording the keys.
+type fakeLocalizer struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+func (l *fakeLocalizer) Sprintf(key string, args ...any) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.keys == nil {
+		l.keys = make(map[string]bool)
+	}
+	l.keys[key] = true
+	return strings.TrimSpace(fmt.Sprintln(append([]any{"fr(" + key}, args...)...)) + ")"
+}
+
+func (l *fakeLocalizer) used(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.keys[key]
+}
+
+// sendUntilCancelled runs f in the background until it sent a notification,
+// then cancels its context and waits for it to return.
+func sendUntilCancelled(t *testing.T, nf *notify.Notifier, f func(ctx context.Context)) {
+	t.Helper()
+	events := nf.Events()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(ctx)
+	}()
+	waitEvent(t, events, notify.EventSent)
+	cancel()
+	select {
+	case <-done:
+	case <-waitTimeout():
+		t.Fatal("the call did not return once cancelled")
+	}
+}
+
+func TestLocalizer(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	var l fakeLocalizer
+	store, err := notify.NewIDStore(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := []notify.Option{notify.WithLocalizer(&l), notify.WithClock(clock), notify.WithIDStore(store)}
+	nf := newTestNotifier(t, opts...)
+
+	g := nf.Group("mail")
+	for i := 0; i < 3; i++ {
+		g.Add(notify.New("mail", fmt.Sprint("mail ", i), "", "", 0, notify.NormalUrgency))
+	}
+	if err := g.Flush(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Summary; got != "fr(notify.more 2)" {
+		t.Errorf("rollup summary %q", got)
+	}
+
+	persistent := notify.New("test", "see me", "", "", 0, notify.NormalUrgency)
+	persistent.Persistent = true
+	if _, err := nf.Notify(persistent); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Actions; got[1] != "fr(notify.ack)" {
+		t.Errorf("ack action %q", got)
+	}
+	nf.CloseNotification(persistent.Id)
+
+	if _, err := nf.NotifyFile("Saved", filepath.Join(t.TempDir(), "notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Actions; got[1] != "fr(notify.open)" || got[3] != "fr(notify.copy-path)" {
+		t.Errorf("file actions %q", got)
+	}
+
+	sendUntilCancelled(t, nf, func(ctx context.Context) {
+		nf.SendPaginated(ctx, notify.New("test", "pages", "", "", 0, notify.NormalUrgency), []string{"one", "two"}, time.Minute)
+	})
+	if got := s.last(t).Actions; got[1] != "fr(notify.next-page)" {
+		t.Errorf("next page action %q", got)
+	}
+	sendUntilCancelled(t, nf, func(ctx context.Context) { nf.Prompt(ctx, "Rename", "") })
+	if got := s.last(t).Actions; got[1] != "fr(notify.enter)" {
+		t.Errorf("enter action %q", got)
+	}
+	sendUntilCancelled(t, nf, func(ctx context.Context) { nf.AskRemember(ctx, "overwrite", "Overwrite?", "Yes") })
+	if got := s.last(t).Actions; got[3] != "fr(notify.always Yes)" {
+		t.Errorf("always action %q", got)
+	}
+
+	tr := notify.NewTransfer(nf, "Download", 2<<20)
+	clock.Advance(time.Second)
+	if err := tr.Progress(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.Actions[1] != "fr(notify.cancel)" || got.Body != "fr(notify.transfer-speed fr(notify.transfer-progress 1.0 MiB 2.0 MiB) 1.0 MiB)" {
+		t.Errorf("transfer sent as %q, %q", got.Actions, got.Body)
+	}
+	if err := tr.Complete("/tmp/file"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.Summary != "fr(notify.transfer-complete Download)" || got.Actions[1] != "fr(notify.open-folder)" {
+		t.Errorf("complete transfer sent as %q, %q", got.Summary, got.Actions)
+	}
+	if err := notify.NewTransfer(nf, "Upload", 0).Fail(fmt.Errorf("boom")); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Summary; got != "fr(notify.transfer-failed Upload)" {
+		t.Errorf("failed transfer summary %q", got)
+	}
+
+	s.setCapabilities("body", "actions", "inline-reply")
+	nf = newTestNotifier(t, opts...)
+	sendUntilCancelled(t, nf, func(ctx context.Context) { nf.Prompt(ctx, "Rename", "") })
+	if got := s.last(t).Actions; got[1] != "fr(notify.reply)" {
+		t.Errorf("reply action %q", got)
+	}
+
+	for _, key := range []string{
+		notify.MsgMore, notify.MsgAck, notify.MsgOpen, notify.MsgCopyPath,
+		notify.MsgNextPage, notify.MsgReply, notify.MsgEnter, notify.MsgAlways,
+		notify.MsgCancel, notify.MsgOpenFolder, notify.MsgTransferProgress,
+		notify.MsgTransferSpeed, notify.MsgTransferFailed, notify.MsgTransferComplete,
+	} {
+		if !l.used(key) {
+			t.Errorf("%s was not localized", key)
+		}
+	}
+}
+
+func TestLocalizedNotification(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithLocalizer(&fakeLocalizer{}))
+
+	template := notify.New("test", "app.backup-done", "app.backup-body", "", 0, notify.NormalUrgency)
+	template.Localized = true
+	template.AddAction("show", "app.show")
+	n := *template
+	if _, err := nf.Notify(&n); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if got.Summary != "fr(app.backup-done)" || got.Body != "fr(app.backup-body)" || got.Actions[1] != "fr(app.show)" {
+		t.Errorf("sent %q, %q, %q", got.Summary, got.Body, got.Actions)
+	}
+	if template.Summary != "app.backup-done" {
+		t.Errorf("the template changed to %q", template.Summary)
+	}
+
+	// Without a Localizer, the keys are sent as is, and the texts of the
+	// package are in English.
+	if got := (notify.EnglishLocalizer{}).Sprintf(notify.MsgMore, 3); got != "and 3 more" {
+		t.Errorf("EnglishLocalizer.Sprintf(MsgMore, 3) = %q", got)
+	}
+	nf = newTestNotifier(t)
+	n = *template
+	if _, err := nf.Notify(&n); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.Summary != "app.backup-done" || got.Actions[1] != "app.show" {
+		t.Errorf("sent %q, %q without a Localizer", got.Summary, got.Actions)
+	}
+	if _, err := notify.NewNotifier(notify.WithLocalizer(nil)); err == nil {
+		t.Error("nil localizer accepted")
+	}
+}
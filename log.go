@@ -0,0 +1,150 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Level is the severity of a message passed to a Logger.
+type Level int
+
+const (
+	LevelDebug Level = iota // LevelDebug is for details only useful when debugging.
+	LevelInfo               // LevelInfo is for decisions taken by the package.
+	LevelWarn               // LevelWarn is for problems that were worked around.
+	LevelError              // LevelError is for failures that lost something.
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Logger receives the problems of the work a Notifier does in the
+// background, such as dispatching signals, where there is no caller to
+// return an error to. err may be nil.
+type Logger func(level Level, msg string, err error)
+
+// SlogLogger returns a Logger writing to l, with err as the "err" attribute.
+func SlogLogger(l *slog.Logger) Logger {
+	return func(level Level, msg string, err error) {
+		var args []any
+		if err != nil {
+			args = append(args, "err", err)
+		}
+		l.Log(context.Background(), level.slogLevel(), msg, args...)
+	}
+}
+
+// slogLevel returns the slog equivalent of l.
+func (l Level) slogLevel() slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+// WithLogger sets the logger of the Notifier, see SetLogger.
+func WithLogger(l Logger) Option {
+	return func(nf *Notifier) error {
+		nf.logf = l
+		return nil
+	}
+}
+
+// SetLogger sets the logger receiving the background problems of nf. By
+// default, they are dropped silently. A nil l restores the default.
+func (nf *Notifier) SetLogger(l Logger) {
+	nf.mu.Lock()
+	nf.logf = l
+	pending := nf.pendingLogs
+	if l != nil {
+		nf.pendingLogs = nil
+	}
+	nf.mu.Unlock()
+	if l != nil {
+		for _, p := range pending {
+			l(p.level, p.msg, p.err)
+		}
+	}
+}
+
+// SetLogger sets the logger used by the package-level functions, see
+// Notifier.SetLogger.
+func SetLogger(l Logger) {
+	Default().SetLogger(l)
+}
+
+// log passes msg and err to the logger of nf, if there is one.
+func (nf *Notifier) log(level Level, msg string, err error) {
+	nf.mu.Lock()
+	l := nf.logf
+	nf.mu.Unlock()
+	if l != nil {
+		l(level, msg, err)
+	}
+}
+
+// pendingLog is a message kept until nf has a logger, see logLater.
+type pendingLog struct {
+	level Level
+	msg   string
+	err   error
+}
+
+// logLater passes msg and err to the logger of nf, or to the first one set
+// if there is none yet, for the problems found before a program can set
+// one, like those of the environment.
+func (nf *Notifier) logLater(level Level, msg string, err error) {
+	nf.mu.Lock()
+	l := nf.logf
+	if l == nil {
+		nf.pendingLogs = append(nf.pendingLogs, pendingLog{level, msg, err})
+	}
+	nf.mu.Unlock()
+	if l != nil {
+		l(level, msg, err)
+	}
+}
+
+// callback calls fn for the notification id, recovering from any panic so
+// that it cannot take the dispatcher down with it. The panic is reported to
+// the logger and as an EventFailed. Nothing is called once nf is shutting
+// down.
+func (nf *Notifier) callback(what string, id uint32, fn func()) {
+	nf.mu.Lock()
+	stopped := nf.shutdown
+	nf.mu.Unlock()
+	if stopped {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("%s callback panicked: %v", what, r)
+			nf.log(LevelError, what+" callback panicked", err)
+			nf.emit(Event{Kind: EventFailed, ID: id, Err: err})
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// LogTransport delivers calls by writing them to W, one line each:
+//
+//	[critical] app: summary: "body"
+//
+// with the body quoted, and left out if empty. It assigns its own IDs, and
+// closing notifications does nothing.
+type LogTransport struct {
+	W io.Writer
+
+	mu     sync.Mutex
+	lastID atomic.Uint32
+}
+
+// NewLogTransport returns a LogTransport writing to w.
+func NewLogTransport(w io.Writer) *LogTransport {
+	return &LogTransport{W: w}
+}
+
+func (t *LogTransport) Notify(ctx context.Context, c Call) (uint32, error) {
+	line := fmt.Sprintf("[%v] %s: %s", c.Urgency, c.AppName, c.Summary)
+	if c.Body != "" {
+		line += ": " + strconv.Quote(c.Body)
+	}
+	t.mu.Lock()
+	_, err := io.WriteString(t.W, line+"\n")
+	t.mu.Unlock()
+	if err != nil {
+		return 0, fmt.Errorf("notify: %w", err)
+	}
+	if c.ReplacesID != 0 {
+		return c.ReplacesID, nil
+	}
+	return t.lastID.Add(1), nil
+}
+
+// CloseNotification does nothing: log lines stay.
+func (t *LogTransport) CloseNotification(id uint32) error {
+	return nil
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Schnouki/notify/internal/testbus"
+)
+
+// busAddress is the address of the private bus started by TestMain, or the
+// empty string if no bus could be started.
+var busAddress string
+
+// TestMain starts a private session bus for the tests, so that they never
+// talk to the real notification daemon of the user running them.
+func TestMain(m *testing.M) {
+	os.Exit(testbus.Run(m, &busAddress))
+}
+
+// requireBus skips the test if TestMain could not start a private bus.
+func requireBus(t testing.TB) {
+	t.Helper()
+	if busAddress == "" {
+		t.Skip("no private D-Bus session bus available")
+	}
+}
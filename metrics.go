@@ -0,0 +1,128 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"time"
+)
+
+// The upper bounds of the buckets of the histograms of the asynchronous
+// operations, see WithQueueMetrics.
+var (
+	LatencyBuckets = []time.Duration{
+		time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond,
+		100 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second,
+	}
+	DepthBuckets = []int{1, 2, 4, 8, 16, 32, 64, 128, 256}
+)
+
+// Histogram counts values in fixed buckets.
+type Histogram[T int | time.Duration] struct {
+	// Bounds are the upper bounds of the buckets, in increasing order.
+	Bounds []T
+	// Counts are the numbers of values in each bucket: Counts[i] counts
+	// the values up to Bounds[i] and above the previous bound, and the
+	// last one, past the bounds, those above the last bound.
+	Counts []uint64
+	// Total is the number of values, and Max the highest one.
+	Total uint64
+	Max   T
+}
+
+// newHistogram returns an empty histogram with bounds.
+func newHistogram[T int | time.Duration](bounds []T) Histogram[T] {
+	return Histogram[T]{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+}
+
+// add counts v.
+func (h *Histogram[T]) add(v T) {
+	i := 0
+	for i < len(h.Bounds) && v > h.Bounds[i] {
+		i++
+	}
+	h.Counts[i]++
+	if h.Total == 0 || v > h.Max {
+		h.Max = v
+	}
+	h.Total++
+}
+
+// clone returns a copy of h not sharing its counts.
+func (h Histogram[T]) clone() Histogram[T] {
+	h.Counts = append([]uint64(nil), h.Counts...)
+	return h
+}
+
+// queueMetrics measures the asynchronous operations of a Notifier.
+type queueMetrics struct {
+	// slow is the latency above which an EventSlow is emitted, never if 0.
+	slow    time.Duration
+	latency Histogram[time.Duration]
+	depth   Histogram[int]
+}
+
+// newQueueMetrics returns empty metrics emitting EventSlow above slow.
+func newQueueMetrics(slow time.Duration) *queueMetrics {
+	return &queueMetrics{
+		slow:    slow,
+		latency: newHistogram(LatencyBuckets),
+		depth:   newHistogram(DepthBuckets),
+	}
+}
+
+// WithQueueMetrics makes the Notifier measure its asynchronous operations:
+// the latency of each one, from SendAsync or ReplaceAsync to the reply of
+// the daemon, and the depth of the queue when each one is queued. They are
+// counted in the Latency and QueueDepth histograms of the Stats, with the
+// bounds of LatencyBuckets and DepthBuckets.
+// If slow is not 0, the operations taking longer also emit an EventSlow
+// with their latency. Times are taken on the clock of the Notifier.
+//
+// Without this option, nothing is measured.
+func WithQueueMetrics(slow time.Duration) Option {
+	return func(nf *Notifier) error {
+		if slow < 0 {
+			return fmt.Errorf("notify: invalid slow operation threshold %v", slow)
+		}
+		nf.metrics = newQueueMetrics(slow)
+		return nil
+	}
+}
+
+// viewMetrics returns the metrics of a view of nf, which has its own queue.
+// It must be called with nf.mu held.
+func (nf *Notifier) viewMetrics() *queueMetrics {
+	if nf.metrics == nil {
+		return nil
+	}
+	return newQueueMetrics(nf.metrics.slow)
+}
+
+// queuedOp records the depth of the queue once op was queued, and when. It
+// must be called with nf.mu held.
+func (nf *Notifier) queuedOp(op *asyncOp) {
+	if nf.metrics == nil {
+		return
+	}
+	op.queuedAt = nf.clock.Now()
+	nf.metrics.depth.add(nf.queued)
+}
+
+// doneOp records the latency of op, once executed, and emits an EventSlow
+// if it was too slow.
+func (nf *Notifier) doneOp(op *asyncOp) {
+	if nf.metrics == nil {
+		return
+	}
+	latency := nf.clock.Now().Sub(op.queuedAt)
+	nf.mu.Lock()
+	nf.metrics.latency.add(latency)
+	slow := nf.metrics.slow
+	nf.mu.Unlock()
+	if slow > 0 && latency > slow {
+		nf.emit(Event{Kind: EventSlow, ID: op.n.Id, Tag: op.n.Tag, CorrelationID: op.n.CorrelationID, Delay: latency})
+	}
+}
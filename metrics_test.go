@@ -0,0 +1,80 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestQueueMetrics(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithQueueMetrics(time.Second))
+	events := nf.Events()
+
+	release := s.stall(t)
+	var done []<-chan error
+	for i := 0; i < 5; i++ {
+		done = append(done, nf.SendAsync(notify.New("test", fmt.Sprint("op ", i), "", "", 0, notify.NormalUrgency)))
+	}
+	waitFor(t, "the queued sends", func() bool { return s.received() == 5 })
+	clock.Advance(2 * time.Second)
+	release()
+	for _, c := range done {
+		if err := <-c; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := nf.Stats()
+	// The queue was 1 to 5 deep, in the buckets up to 1, 2, 4 and 8.
+	if got := st.QueueDepth; fmt.Sprint(got.Counts) != "[1 1 2 1 0 0 0 0 0 0]" || got.Max != 5 || got.Total != 5 {
+		t.Errorf("QueueDepth = %+v", got)
+	}
+	// All took 2s, in the bucket up to 5s.
+	if got := st.Latency; fmt.Sprint(got.Counts) != "[0 0 0 0 0 0 0 5 0]" || got.Max != 2*time.Second {
+		t.Errorf("Latency = %+v", got)
+	}
+	for i := 0; i < 5; i++ {
+		if e := waitEvent(t, events, notify.EventSlow); e.Delay != 2*time.Second || e.ID == 0 {
+			t.Errorf("EventSlow = %+v", e)
+		}
+	}
+
+	// The Stats are copies.
+	st.Latency.Counts[0] = 42
+	if nf.Stats().Latency.Counts[0] != 0 {
+		t.Error("the Stats share the histogram")
+	}
+	if _, err := notify.NewNotifier(notify.WithQueueMetrics(-time.Second)); err == nil {
+		t.Error("negative threshold accepted")
+	}
+}
+
+func TestQueueMetricsReplyDelay(t *testing.T) {
+	s := newFakeServer(t)
+	s.setReplyDelay(20 * time.Millisecond)
+	nf := newTestNotifier(t, notify.WithQueueMetrics(0))
+	if err := <-nf.SendAsync(notify.New("test", "slow daemon", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	// The latency includes the reply delay: nothing in the buckets up to
+	// 10ms.
+	if got := nf.Stats().Latency; got.Total != 1 || got.Counts[0]+got.Counts[1]+got.Counts[2] != 0 || got.Max < 20*time.Millisecond {
+		t.Errorf("Latency = %+v", got)
+	}
+
+	nf = newTestNotifier(t)
+	if err := <-nf.SendAsync(notify.New("test", "unmeasured", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if st := nf.Stats(); st.Latency.Total != 0 || st.QueueDepth.Counts != nil {
+		t.Errorf("Stats() = %+v without WithQueueMetrics", st)
+	}
+}
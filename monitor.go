@@ -0,0 +1,41 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// SetMonitor asks for n to be shown on the monitor spec: its index for
+// daemons like dunst, or the name of its output, such as "DP-1", for daemons
+// like mako whose configuration matches on an "output" hint. The hint is
+// chosen when n is sent, from the quirks of the daemon; nothing is sent for
+// daemons with no known way to select the monitor. An empty spec removes
+// the request.
+func (n *Notification) SetMonitor(spec string) {
+	n.monitor = spec
+}
+
+// monitorHint adds the hint selecting the monitor spec of the daemon to
+// hints.
+func (nf *Notifier) monitorHint(hints map[string]dbus.Variant, spec string) {
+	q := nf.daemonQuirks()
+	switch {
+	case q.MonitorHint == "":
+		return
+	case q.MonitorByIndex:
+		index, err := strconv.ParseInt(spec, 10, 32)
+		if err != nil {
+			nf.log(LevelWarn, fmt.Sprintf("the daemon selects monitors by index, not by %q", spec), err)
+			return
+		}
+		hints[q.MonitorHint] = dbus.MakeVariant(int32(index))
+	default:
+		hints[q.MonitorHint] = dbus.MakeVariant(spec)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestSetMonitor(t *testing.T) {
+	tests := []struct {
+		daemon, spec string
+		key, sig     string
+	}{
+		{"dunst", "1", "monitor", "i"},
+		{"dunst", "DP-1", "", ""},
+		{"mako", "DP-1", "output", "s"},
+		{"fake", "1", "", ""},
+	}
+	for _, tt := range tests {
+		s := newFakeServer(t)
+		s.setServerName(tt.daemon)
+		nf := newTestNotifier(t)
+
+		n := notify.New("test", "there", "", "", time.Second, notify.NormalUrgency)
+		n.SetMonitor(tt.spec)
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		hints := s.last(t).Hints
+		for _, key := range []string{"monitor", "output"} {
+			v, ok := hints[key]
+			switch {
+			case key != tt.key && ok:
+				t.Errorf("%s, %q: unexpected %q hint", tt.daemon, tt.spec, key)
+			case key == tt.key && !ok:
+				t.Errorf("%s, %q: no %q hint", tt.daemon, tt.spec, key)
+			case key == tt.key && v.Signature().String() != tt.sig:
+				t.Errorf("%s, %q: %q hint has signature %s, want %s", tt.daemon, tt.spec, key, v.Signature(), tt.sig)
+			}
+		}
+		nf.Close()
+		s.conn.Close()
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// MultiTransport is a Transport delivering every call to a primary transport
+// and to mirrors, such as a log of the notifications. The IDs are those of
+// the primary: mirrors get the calls with the ReplacesID of the primary, and
+// are closed with its IDs.
+//
+// The mirrors get every call, even when the primary fails, so that they
+// still record notifications when no daemon is running. Errors of the
+// mirrors are passed to OnMirrorError if it is set, and otherwise ignored.
+//
+// The MultiTransport has string IDs, see StringIDTransport, if its primary
+// has. The mirrors then get the notifications by their string IDs if they
+// have some too, and with the Ids of the Notifier otherwise.
+type MultiTransport struct {
+	Primary Transport
+	Mirrors []Transport
+
+	// OnMirrorError is called with the errors of the mirrors.
+	OnMirrorError func(mirror Transport, err error)
+}
+
+// NewMultiTransport returns a MultiTransport delivering the calls to primary
+// and to mirrors.
+func NewMultiTransport(primary Transport, mirrors ...Transport) *MultiTransport {
+	return &MultiTransport{Primary: primary, Mirrors: mirrors}
+}
+
+func (t *MultiTransport) Notify(ctx context.Context, c Call) (uint32, error) {
+	var (
+		id  uint32
+		err = ErrNoTransport
+	)
+	if t.Primary != nil {
+		id, err = t.Primary.Notify(ctx, c)
+	}
+	for _, m := range t.Mirrors {
+		if _, merr := m.Notify(ctx, c); merr != nil {
+			t.mirrorError(m, merr)
+		}
+	}
+	return id, err
+}
+
+func (t *MultiTransport) CloseNotification(id uint32) error {
+	err := ErrNoTransport
+	if t.Primary != nil {
+		err = t.Primary.CloseNotification(id)
+	}
+	for _, m := range t.Mirrors {
+		if merr := m.CloseNotification(id); merr != nil {
+			t.mirrorError(m, merr)
+		}
+	}
+	return err
+}
+
+// NotifyID delivers c as the notification id to the primary and to the
+// mirrors.
+func (t *MultiTransport) NotifyID(ctx context.Context, id string, c Call) error {
+	err := ErrNoTransport
+	if t.Primary != nil {
+		err = notifyID(ctx, t.Primary, id, c)
+	}
+	for _, m := range t.Mirrors {
+		if merr := notifyID(ctx, m, id, c); merr != nil {
+			t.mirrorError(m, merr)
+		}
+	}
+	return err
+}
+
+// CloseID closes the notification id with the primary and the mirrors.
+func (t *MultiTransport) CloseID(id string) error {
+	err := ErrNoTransport
+	if t.Primary != nil {
+		err = closeID(t.Primary, id)
+	}
+	for _, m := range t.Mirrors {
+		if merr := closeID(m, id); merr != nil {
+			t.mirrorError(m, merr)
+		}
+	}
+	return err
+}
+
+// hasStringIDs returns true if the primary of t has string IDs.
+func (t *MultiTransport) hasStringIDs() bool {
+	_, ok := stringIDOf(t.Primary)
+	return ok
+}
+
+func (t *MultiTransport) mirrorError(m Transport, err error) {
+	if t.OnMirrorError != nil {
+		t.OnMirrorError(m, err)
+	}
+}
+
+// WithMirrors makes the Notifier also deliver its calls to mirrors, keeping
+// its own transport, D-Bus or the one given to WithTransport, as the primary
+// one. Errors of the mirrors are logged as warnings.
+func WithMirrors(mirrors ...Transport) Option {
+	return func(nf *Notifier) error {
+		for _, m := range mirrors {
+			if m == nil {
+				return errors.New("notify: nil transport")
+			}
+		}
+		nf.mirrors = append(nf.mirrors, mirrors...)
+		return nil
+	}
+}
+
+// mirror wraps the transport of nf in a MultiTransport if it has mirrors.
+func (nf *Notifier) mirror() {
+	if len(nf.mirrors) == 0 {
+		return
+	}
+	mt := NewMultiTransport(nf.transport, nf.mirrors...)
+	mt.OnMirrorError = func(_ Transport, err error) {
+		nf.log(LevelWarn, "mirroring a notification failed", err)
+	}
+	nf.transport = mt
+}
@@ -7,7 +7,7 @@ package notify
 import (
 	"time"
 
-	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/v5"
 )
 
 // NotificationUrgency can be either LowUrgency, NormalUrgency, and CriticalUrgency.
@@ -21,9 +21,28 @@ const (
 	CriticalUrgency                            // CriticalUrgency is for errors or severe events.
 )
 
+// urgencyVariants holds the urgency hint values, made once.
+var urgencyVariants = [...]dbus.Variant{
+	LowUrgency:      dbus.MakeVariant(byte(LowUrgency)),
+	NormalUrgency:   dbus.MakeVariant(byte(NormalUrgency)),
+	CriticalUrgency: dbus.MakeVariant(byte(CriticalUrgency)),
+}
+
+// urgencyHints holds the hints of the notifications with only an urgency,
+// made once and shared by every call: they must never be written to.
+var urgencyHints = [...]map[string]dbus.Variant{
+	LowUrgency:      {"urgency": urgencyVariants[LowUrgency]},
+	NormalUrgency:   {"urgency": urgencyVariants[NormalUrgency]},
+	CriticalUrgency: {"urgency": urgencyVariants[CriticalUrgency]},
+}
+
 // asHint returns the NotificationUrgency in the type that the DBus
-// specification requires.
+// specification requires. The map of the known urgencies is shared, and
+// must not be modified.
 func (u NotificationUrgency) asHint() map[string]dbus.Variant {
+	if int(u) < len(urgencyHints) {
+		return urgencyHints[u]
+	}
 	return map[string]dbus.Variant{"urgency": dbus.MakeVariant(byte(u))}
 }
 
@@ -36,60 +55,162 @@ func (u NotificationUrgency) asHint() map[string]dbus.Variant {
 //	func main() {
 //		critical := notify.New("prog", "", "", "critical-icon.png", time.Duration(0), notify.CriticalUrgency)
 //		boring := notify.New("prog", "", "", "low-icon.png", 1 * time.Second, notify.LowUrgency)
-//		boring.ReplaceMsg("Nothing is happening... boring!", "")
-//		critical.ReplaceMsg("Your computer is on fire!", "Here is what you should do:\n ...")
+//		boring.Fire("Nothing is happening... boring!", "")
+//		critical.Fire("Your computer is on fire!", "Here is what you should do:\n ...")
 //	}
 //
+// Fire sends a copy of the template, so the templates can be shared by
+// goroutines. Sending a Notification itself, with SendR or Notifier.Notify,
+// updates its ID and must not be done concurrently.
+//
+// A Notification literal can be sent as well as one made with New: the zero
+// value of each field is documented with it.
+//
 type Notification struct {
 	// Name represents the application name sending the notification.  This is
-	// optional and can be the empty string "".
+	// optional and can be the empty string "", in which case the name set
+	// with WithAppName or the name of the executable is sent.
 	Name string
-	// Summary represents the subject of the notification.
+	// Summary represents the subject of the notification. It is required:
+	// a notification without a summary fails Validate.
 	Summary string
 	// Body represents the main body with extra details. Some notification
 	// daemons ignore the body; it is optional and can be the empty string "".
 	Body string
 
-	// IconPath is a path to an icon that should be used for the notification.
-	// Some notification daemons ignore the icon path; it is optional and can
-	// be the empty string "".
+	// IconPath is the icon identifying the application sending the
+	// notification, either a themed icon name or a path. Some notification
+	// daemons ignore the icon path; it is optional and can be the empty
+	// string "", in which case the app icon of the Notifier is used. An image
+	// showing the content of the notification is set with SetImage instead.
 	IconPath string
+	// ImagePath is a path or a file:// URI to an image showing the content
+	// of the notification, like album art. It is optional and can be the
+	// empty string ""; an image embedded with SetImage takes precedence.
+	ImagePath string
 	// Timeout is the requested timeout for the notification. Some notification
-	// daemons override the requested timeout. A value of 0 is a request that
-	// it not timeout at all.
-	Timeout time.Duration
+	// daemons override the requested timeout. A value of 0, the zero value, is
+	// a request that it not timeout at all, and DefaultTimeout lets the daemon
+	// choose.
+	Timeout Duration
 	// Urgency determines the urgency of the notification, which can be one of
-	// LowUrgency, NormalUrgency, and CriticalUrgency.
+	// LowUrgency, NormalUrgency, and CriticalUrgency. The zero value of a
+	// Notification literal is sent as NormalUrgency: use New or SetUrgency
+	// to send LowUrgency.
 	Urgency NotificationUrgency
 
-	// Id is the ID of the notification. It is 0 initially, and will be
-	// updated when calling Send or one of the Replace methods.
+	// Id is the ID of the notification. It is 0 initially, for a new
+	// notification, and will be updated when calling Send or one of the
+	// Replace methods. Sent on several buses, it is the ID on the last one,
+	// see Notifier.NotificationID.
 	Id uint32
+	// Tag identifies notifications that replace each other: a notification
+	// without an ID replaces the last one sent with the same tag by the same
+	// Notifier. It is optional and can be the empty string "".
+	Tag string
+	// CorrelationID identifies the notification in events and history
+	// records, unlike its ID, which daemons reuse. If it is empty, Notify
+	// sets it to a random UUID; set it to trace notifications with your
+	// own IDs.
+	CorrelationID string
+	// Sensitive marks the body as private: it is hidden according to the
+	// sensitive policy of the Notifier, see WithSensitivePolicy. It is false
+	// by default.
+	Sensitive bool
+
+	// Actions are the actions shown with the notification, in order. It is
+	// optional and can be nil, for a notification without actions.
+	Actions []Action
+	// OnAction is called with the key of the action when the user invokes
+	// one of the actions. It is optional and can be nil.
+	OnAction func(key string)
+	// OnClose is called with the reason when the notification is closed.
+	// It is optional and can be nil.
+	OnClose func(reason CloseReason)
+	// OnReply is called with the text typed by the user in reply to the
+	// notification, on daemons with the "inline-reply" capability; see
+	// AddReply. It is optional and can be nil.
+	OnReply func(text string)
+	// OnReplaced is called with the ID of the new notification when another
+	// Notification with the same Tag replaces this one, so that resources
+	// tied to it can be released. It is optional and can be nil.
+	OnReplaced func(byID uint32)
+	// Persistent asks for the notification to stay around until the user
+	// saw it, rather than as long as the daemon keeps it. Daemons with the "persistence" capability keep notifications
+	// by themselves; with others, the Notifier posts it again until it is
+	// acknowledged, see Notifier.Notify.
+	Persistent bool
+	// CloseOnAction gives the same behavior with all daemons after an
+	// action is invoked: if true, the notification is closed, and if false,
+	// the zero value, it stays visible (by setting the "resident" hint).
+	CloseOnAction bool
+	// AllowCriticalExpiry keeps the timeout of a critical notification
+	// with WithCriticalNoExpiry, for the rare critical notifications meant
+	// to expire. It is false by default.
+	AllowCriticalExpiry bool
+	// AllowUrgencyDowngrade acknowledges that n lowers the urgency of the
+	// notification it replaces, see WithStrictUrgency. It is false by
+	// default.
+	AllowUrgencyDowngrade bool
+	// Localized marks Summary, Body and the labels of Actions as message
+	// keys, translated by the Localizer of the Notifier when sent (see
+	// WithLocalizer), so that templates can hold keys instead of texts. It
+	// is false by default: the texts are sent as they are.
+	Localized bool
+
+	// urgencySet is true if Urgency was set with New or SetUrgency, so
+	// that a LowUrgency is not the zero value, see baseUrgency.
+	urgencySet bool
+	// image is the embedded image, see SetImage.
+	image *imageData
+	// icon is the content of the icon set with SetIconFromFS, and iconExt
+	// the extension of its name.
+	icon    []byte
+	iconExt string
+	// monitor is the monitor to show the notification on, see SetMonitor.
+	monitor string
+	// windowID is the X11 window of the notification, see SetWindowID.
+	windowID uint32
+	// hints holds the hints set with SetHint, and hintsGen their version.
+	hints    map[string]interface{}
+	hintsGen uint64
+	// cache holds what was computed for the last send.
+	cache sendCache
+	// activationToken is the token of the last action invoked, see
+	// ActivationToken.
+	activationToken string
+	// closed is true once the notification was closed with Dismiss, until
+	// it is sent again.
+	closed bool
+	// bus is the bus n was last sent on, and busIDs its IDs on the buses
+	// it was sent on, see Notifier.NotificationID.
+	bus    string
+	busIDs map[string]uint32
 }
 
 // New returns a pointer to a new Notification.
 func New(name, summary, body, icon string, timeout time.Duration, urgency NotificationUrgency) *Notification {
-	return &Notification{name, summary, body, icon, timeout, urgency, 0}
-}
+	return &Notification{
+		Name:     name,
+		Summary:  summary,
+		Body:     body,
+		IconPath: icon,
+		Timeout:  Duration(timeout),
+		Urgency:  urgency,
 
-// Send sends the notification n as it is, and returns an err, possibly nil.
-func (n Notification) Send() (err error) {
-	n.Id, err = notify(n.Name, n.Summary, n.Body, n.IconPath, n.Id, nil, n.Urgency.asHint(), n.timeoutInMS())
-	return err
+		urgencySet: true,
+	}
 }
 
-// ReplaceMsg is identical to notify.ReplaceMsg, except that the rest of the
-// values come from n.
-func (n Notification) ReplaceMsg(summary, body string) (err error) {
-	n.Summary, n.Body = summary, body
-	return n.Send()
+// TimeoutDuration returns Timeout as a time.Duration.
+func (n *Notification) TimeoutDuration() time.Duration {
+	return time.Duration(n.Timeout)
 }
 
-// ReplaceUrgentMsg is identical to notify.ReplaceUrgentMsg, except that the
-// rest of the values come from n.
-func (n Notification) ReplaceUrgentMsg(summary, body string, urgency NotificationUrgency) (err error) {
-	n.Summary, n.Body, n.Urgency = summary, body, urgency
-	return n.Send()
+// SendR sends the notification n as it is, updates n.Id, and returns the
+// result.
+func (n *Notification) SendR() (SendResult, error) {
+	return Default().Notify(n)
 }
 
 // timeoutInMS returns Timeout in milliseconds.
@@ -97,5 +218,5 @@ func (n Notification) ReplaceUrgentMsg(summary, body string, urgency Notificatio
 // The specification specifies that the timeout is the number of milliseconds
 // that the notification should be displayed.
 func (n Notification) timeoutInMS() int32 {
-	return int32(n.Timeout / time.Millisecond)
+	return int32(time.Duration(n.Timeout) / time.Millisecond)
 }
@@ -0,0 +1,890 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Notifier sends notifications over a D-Bus connection that it owns.
+//
+// The package-level functions use a default Notifier that shares the
+// process-wide session bus connection. Create your own Notifier via
+// NewNotifier if you need to connect in an unusual environment, such as a
+// nested session or a user service started without DBUS_SESSION_BUS_ADDRESS.
+//
+// A Notifier sends asynchronous notifications and calls callbacks in the
+// background: defer Shutdown in main so that they are not cut short when the
+// program exits.
+type Notifier struct {
+	config
+
+	mu   sync.Mutex
+	conn *dbus.Conn
+	// parent is the Notifier whose connection nf uses, if nf is a view
+	// created by As or belongs to a Core.
+	parent *Notifier
+	// core is the Core nf belongs to, if any, and fanout the Core whose
+	// connection nf owns.
+	core   *Core
+	fanout *Core
+
+	// quirksAuto is true if the quirks were picked by AutoConfigure, and are
+	// dropped with the other caches.
+	quirksAuto bool
+
+	// info caches the server information, see ServerInfo.
+	info *ServerInfo
+	// caps caches the capabilities of the daemon, see Capabilities, and
+	// feats the features parsed from them.
+	caps  []string
+	feats *Features
+	// ifaces caches the interfaces of the daemon, see ProbeInterfaces.
+	ifaces *introspection
+	// probe holds the state of the probes filling these caches, see
+	// Snapshot.
+	probe probeState
+
+	// temps holds the temporary files written for the notifications of nf
+	// and the Notifiers sharing its connection, see tempFiles.
+	temps tempFiles
+
+	// groups holds the notification groups by name, see Group.
+	groups map[string]*Group
+	// scopes holds the scopes of nf that are not nested, see Scope. The
+	// notifications sent through scopes are in scoped, and the IDs of those
+	// still open in scopedIDs.
+	scopes    map[*Scope]struct{}
+	scoped    map[*Notification]*Scope
+	scopedIDs map[uint32]*Notification
+	// coordinators holds the nodes of nf in groups of processes, see
+	// Coordinate.
+	coordinators map[*Coordinator]struct{}
+
+	// signals receives the signals of the daemon once nf listens to them.
+	signals chan *dbus.Signal
+	// stopDispatch stops dispatching the signals when closed, and
+	// dispatchDone is closed once they are no longer dispatched.
+	stopDispatch chan struct{}
+	dispatchDone chan struct{}
+	// reconnect is what is left to do since the connection was lost, see
+	// connectionLost.
+	reconnect reconnectState
+	// tracked holds the notifications receiving signals, by ID.
+	tracked map[uint32]trackedNotification
+	// activationTokens holds the activation tokens received for the next
+	// ActionInvoked signal, by ID.
+	activationTokens map[uint32]string
+	// closing holds the pending closes of notifications with
+	// CloseOnAction, by ID.
+	closing map[uint32]Timer
+	// settling holds the closes held back for the settle window, by ID.
+	settling map[uint32]settlingClose
+	// sentUrgency holds the urgencies of the notifications sent, by ID, see
+	// WithStrictUrgency.
+	sentUrgency map[uint32]NotificationUrgency
+	// reposts holds the persistent notifications being posted again, by ID.
+	reposts map[uint32]*repost
+	// trackOrder bounds what is remembered of the notifications sent, see
+	// WithTrackingLimits.
+	trackOrder trackOrder
+
+	// pendingLogs are the messages waiting for a logger, see logLater.
+	pendingLogs []pendingLog
+	// events receives the events of nf once Events is called.
+	events chan Event
+
+	// pool runs the callbacks for a Pool execution, and callbacksRunning
+	// counts those queued or running on other goroutines.
+	pool             callbackPool
+	callbacksRunning sync.WaitGroup
+
+	// lanes holds the pending asynchronous operations, by notification or
+	// by tag, see SendAsync.
+	lanes map[any]*lane
+	// lanesRunning counts the lanes being executed.
+	lanesRunning sync.WaitGroup
+	// queued is the number of operations in the lanes, pending or being
+	// executed, at most queueCap, and seq numbers them in order. When the
+	// queue is full, queuePolicy applies, and queueFreed, if not nil, is
+	// closed once an operation completes. See WithQueue.
+	queued     int
+	seq        uint64
+	queueFreed chan struct{}
+	// metrics measures the asynchronous operations, see WithQueueMetrics.
+	metrics *queueMetrics
+	// notificationLocks serializes the sends and closes of each
+	// notification, see Dismiss.
+	notificationLocks map[*Notification]*notificationLock
+	// replaceWatches holds the waits of Ask and SendAndWait, by the ID of
+	// the notification waited for.
+	replaceWatches map[uint32][]*replaceWatch
+	// actionPages holds the pages shown past the first one, see
+	// WithActionOverflow.
+	actionPages map[*Notification]*actionPage
+	// stats holds the counters of nf, see Stats.
+	stats Stats
+	// deferred holds the notifications held back while nf is paused, see
+	// Pause.
+	deferred []*Notification
+	// catchUp holds the notifications summarized at the end of the quiet
+	// hours, see QuietHours.Collapse.
+	catchUp catchUpState
+	// shutdown is set by Shutdown, after which no operations are queued
+	// and no callbacks are called, and stopped is closed.
+	shutdown bool
+	stopped  chan struct{}
+	// openIDs are the notifications nf sent that are still open, see
+	// WithCloseOnShutdown.
+	openIDs map[uint32]struct{}
+	// errorReports holds the error handler, shared with the views, see
+	// SetErrorHandler.
+	errorReports *errorReports
+	// stringIDs holds the IDs sent to a StringIDTransport.
+	stringIDs stringIDs
+	// tags holds the last notification sent with each tag.
+	tags map[string]tagged
+	// screenReader is whether a screen reader is running, as last found
+	// out, see WithScreenReaderTimeout.
+	screenReader screenReader
+	// calls limits the calls in flight to maxInFlight. The Notifiers with a
+	// parent use the limit of the parent.
+	calls inFlight
+	// session holds the notifications held back until a graphical session
+	// is active, see WithSessionWait.
+	session sessionWait
+	// probed holds the last answers about the session, for the
+	// DeliveryContext of the sends.
+	probed probedSession
+	// paced holds the times of the sends in the current window, in order,
+	// see WithAdaptivePacing.
+	paced []time.Time
+	// debugTrace is where the calls and signals are written, if not nil,
+	// see EnableDebugTrace.
+	debugTrace atomic.Pointer[debugTrace]
+	// resumed holds the notifications posted again by HandleResume, whose
+	// expiry is ignored, and wall the timers it re-evaluates.
+	resumed map[uint32]struct{}
+	wall    wallTimers
+	// history keeps the records of the notifications sent, see
+	// WithHistory.
+	history *history
+	// lastSent holds the hash of the last call sent for each ID, see
+	// WithSkipUnchanged.
+	lastSent map[uint32]uint64
+
+	// scheduled holds the pending scheduled sends, see SendAt.
+	scheduled map[*Scheduled]struct{}
+
+	// lastOwner is the unique bus name of the daemon that received the last
+	// notification.
+	lastOwner string
+}
+
+// config is the configuration of a Notifier set by its options, which its
+// views share.
+type config struct {
+	// address is the bus address to connect to instead of the session bus.
+	address string
+	// connOpts are passed to godbus when connecting. If there are none and
+	// no address is set, the shared session bus connection is used instead.
+	connOpts []dbus.ConnOption
+
+	// destination and path identify the notification daemon on the bus.
+	destination string
+	path        dbus.ObjectPath
+
+	// appName is the default app_name, see WithAppName. It is only used if
+	// appNameSet is true.
+	appName    string
+	appNameSet bool
+	// appIcon is the default app_icon, see WithAppIcon.
+	appIcon string
+	// categoryIcons are the icons of the categories, CategoryIcons if nil,
+	// sent if categoryIconsOn is true, see WithCategoryIcons.
+	categoryIcons   map[string]string
+	categoryIconsOn bool
+	// looseCategories sends the categories not following the conventions,
+	// see WithCategoryCheck.
+	looseCategories bool
+	// checkImagePath enables checking ImagePath, see WithImagePathCheck.
+	checkImagePath bool
+	// imageSpec is the version of the specification whose image hints are
+	// sent, or nil to use the one of the daemon, see WithImageHintKey.
+	imageSpec *[2]int
+	// senderPID sends the "sender-pid" hint, see WithSenderPID.
+	senderPID bool
+	// images caches the embedded images sent, shared with the views of nf,
+	// see WithImageCache.
+	images *imageCache
+	// repostInterval is how often persistent notifications are posted
+	// again, see WithRepostInterval.
+	repostInterval time.Duration
+	// defaultHints are sent with every notification, see WithDefaultHints.
+	defaultHints map[string]dbus.Variant
+	// healthProbe makes HealthCheck send a probe, see WithHealthProbe.
+	healthProbe bool
+	// redactor filters the text of notifications, see WithRedactor.
+	redactor Redactor
+	// dedup drops duplicate notifications, see WithDedup.
+	dedup *dedup
+	// quirks are the quirks of the daemon to work around, see WithQuirks.
+	// nil means none.
+	quirks *Quirks
+	// snapshotTTL is how long the caches are kept, forever if 0, see
+	// WithSnapshotTTL.
+	snapshotTTL time.Duration
+
+	// settleWindow is how long closes are held back for the actions and
+	// replies signalled after them, see WithSettleWindow.
+	settleWindow time.Duration
+	// strictUrgency warns about the replacements lowering the urgency of
+	// the notifications, see WithStrictUrgency.
+	strictUrgency bool
+	// trackMax and trackTTL bound what is remembered of the notifications
+	// sent, see WithTrackingLimits.
+	trackMax int
+	trackTTL time.Duration
+
+	// logf receives the problems of the background work, see SetLogger.
+	logf Logger
+
+	// execution is how the callbacks are run, see WithCallbackExecution.
+	execution Execution
+
+	// queueCap and queuePolicy bound the asynchronous operations, see
+	// WithQueue.
+	queueCap    int
+	queuePolicy QueuePolicy
+	// actionOverflow is what to do with the actions the daemon does not
+	// show, see WithActionOverflow.
+	actionOverflow ActionOverflow
+	// closeOnShutdown makes Shutdown close the notifications that are
+	// still open, see WithCloseOnShutdown.
+	closeOnShutdown bool
+	// shutdownGrace bounds the Shutdown of HandleSignals, see
+	// WithShutdownGrace.
+	shutdownGrace time.Duration
+	// sensitivePolicy decides when the body of sensitive notifications is
+	// replaced by sensitivePlaceholder, see WithSensitivePolicy, and
+	// lockDetector tells whether the session is locked.
+	sensitivePolicy      SensitivePolicy
+	sensitivePlaceholder string
+	lockDetector         func() (bool, error)
+	// minTimeout is the minimum timeout of the notifications, raised to
+	// screenReaderMin while a screen reader is running, as told by
+	// screenReaderDetector. See WithMinimumTimeout and
+	// WithScreenReaderTimeout.
+	minTimeout           time.Duration
+	screenReaderMin      time.Duration
+	screenReaderDetector func() (bool, error)
+	// criticalNoExpiry sends the critical notifications as never expiring,
+	// see WithCriticalNoExpiry.
+	criticalNoExpiry bool
+	// localizer translates the texts shown to the user, see WithLocalizer.
+	localizer Localizer
+	// maxInFlight limits the calls in flight, see WithMaxInFlight.
+	maxInFlight int
+	// soundPlayer plays the sounds the daemon does not, see
+	// WithSoundFallback.
+	soundPlayer SoundPlayer
+	// execRunner and execEnv spawn the processes of nf, see WithExecRunner
+	// and WithExecEnv.
+	execRunner ExecRunner
+	execEnv    map[string]string
+	// waitSession holds back notifications until a graphical session is
+	// active, as reported by logind on logindConn, see WithSessionWait.
+	waitSession bool
+	logindConn  *dbus.Conn
+	// controlPolicy sanitizes the bidi control and zero-width characters,
+	// see WithControlSanitizer.
+	controlPolicy ControlPolicy
+	// fieldPolicies holds the rules of the name, summary and body, see
+	// WithFieldPolicies.
+	fieldPolicies map[string]FieldPolicy
+	// sizeLimits caps the actions and hints of the notifications, see
+	// WithSizeLimits.
+	sizeLimits SizeLimits
+	// preserveNewlines turns the newlines of bodies into line breaks for
+	// markup daemons, see WithPreserveNewlines.
+	preserveNewlines bool
+	// quiet holds back notifications during quiet hours, see
+	// WithQuietHours.
+	quiet *QuietHours
+	// pace spaces the notifications, see WithAdaptivePacing.
+	pace *Pace
+	// traceHook is called at each phase of the notifications, see
+	// WithTraceHook.
+	traceHook TraceHook
+	// resumeWatch and resumeCollapse are set by WithResumeHandling.
+	resumeWatch, resumeCollapse bool
+	// noAutoStart keeps the calls of nf from starting the daemon, see
+	// WithAutoActivation.
+	noAutoStart bool
+	// urgencyHints are the urgency hints of the urgencies mapped by
+	// WithUrgencyMap; the others use the shared ones of asHint.
+	urgencyHints [CriticalUrgency + 1]map[string]dbus.Variant
+	// dropHintsOnLimits enables sending again without the optional hints,
+	// see WithDropHintsOnLimits.
+	dropHintsOnLimits bool
+	// idStore records the IDs of the tags for other processes, see
+	// WithIDStore.
+	idStore *IDStore
+	// skipUnchanged makes nf skip unchanged sends, see WithSkipUnchanged.
+	skipUnchanged bool
+	// transport delivers the calls of nf, see WithTransport. It is nil on
+	// platforms without D-Bus, unless set by WithTransport.
+	transport Transport
+	// mirrors also get the calls of nf, see WithMirrors.
+	mirrors []Transport
+
+	// clock tells the time, see WithClock.
+	clock Clock
+	// schedulePolicy applies to scheduled sends with the same tag.
+	schedulePolicy SchedulePolicy
+}
+
+// Option configures a Notifier, see NewNotifier.
+type Option func(*Notifier) error
+
+// WithConnOptions passes opts to godbus when the Notifier connects to the
+// session bus. Setting any connection option gives the Notifier a private
+// connection instead of the shared one.
+func WithConnOptions(opts ...dbus.ConnOption) Option {
+	return func(nf *Notifier) error {
+		nf.connOpts = append(nf.connOpts, opts...)
+		return nil
+	}
+}
+
+// WithAuth restricts the authentication methods used when connecting to the
+// session bus, for example to dbus.AuthExternal when the default detection
+// does not work in your environment.
+func WithAuth(methods ...dbus.Auth) Option {
+	return WithConnOptions(dbus.WithAuth(methods...))
+}
+
+// WithBusAddress connects the Notifier to the bus at addr, such as
+// "unix:path=/run/kiosk/bus", instead of the session bus.
+func WithBusAddress(addr string) Option {
+	return func(nf *Notifier) error {
+		if addr == "" {
+			return errors.New("notify: empty bus address")
+		}
+		nf.address = addr
+		return nil
+	}
+}
+
+// WithDestination makes the Notifier talk to the notification daemon
+// registered under the well-known name at objectPath, instead of
+// org.freedesktop.Notifications at /org/freedesktop/Notifications. Signals
+// are only accepted from that destination too.
+func WithDestination(name string, objectPath dbus.ObjectPath) Option {
+	return func(nf *Notifier) error {
+		if name == "" {
+			return errors.New("notify: empty destination name")
+		} else if !objectPath.IsValid() {
+			return fmt.Errorf("notify: invalid object path %q", objectPath)
+		}
+		nf.destination, nf.path = name, objectPath
+		return nil
+	}
+}
+
+// WithAppName sets the application name sent for the notifications that
+// leave Name empty, instead of the name of the executable. An empty name
+// makes the Notifier send an empty name, which some daemons show as
+// "Unknown application".
+func WithAppName(name string) Option {
+	return func(nf *Notifier) error {
+		nf.appName, nf.appNameSet = name, true
+		return nil
+	}
+}
+
+// derivedAppName is the application name used when neither the notification
+// nor the Notifier set one.
+var derivedAppName = func() string {
+	if len(os.Args) == 0 || os.Args[0] == "" {
+		return ""
+	}
+	return filepath.Base(os.Args[0])
+}()
+
+// appNameFor returns the application name to send for a notification named
+// name.
+func (nf *Notifier) appNameFor(name string) string {
+	switch {
+	case name != "":
+		return name
+	case nf.appNameSet:
+		return nf.appName
+	default:
+		return derivedAppName
+	}
+}
+
+// WithAppIcon sets the icon identifying the application, a themed icon name
+// or a path, sent for the notifications that leave IconPath empty. Content
+// images are set on each notification with SetImage instead.
+func WithAppIcon(icon string) Option {
+	return func(nf *Notifier) error {
+		nf.appIcon = icon
+		return nil
+	}
+}
+
+// WithImagePathCheck makes the Notifier check that the ImagePath of each
+// notification names an existing file before sending it, instead of letting
+// the daemon show a broken image.
+func WithImagePathCheck(check bool) Option {
+	return func(nf *Notifier) error {
+		nf.checkImagePath = check
+		return nil
+	}
+}
+
+// NewNotifier returns a new Notifier configured by opts, or an error if
+// one of the options is invalid, or the file of quirk rules named by
+// NOTIFY_QUIRKS_FILE is. The connection to the bus is only established when
+// it is first needed.
+func NewNotifier(opts ...Option) (*Notifier, error) {
+	if envQuirksErr != nil {
+		return nil, envQuirksErr
+	}
+	return newNotifier(opts...)
+}
+
+// newNotifier is NewNotifier, without the checks of the environment.
+func newNotifier(opts ...Option) (*Notifier, error) {
+	nf := baseNotifier()
+	for _, opt := range opts {
+		if err := opt(nf); err != nil {
+			return nil, err
+		}
+	}
+	if nf.transport == nil && newBusTransport != nil {
+		nf.transport = newBusTransport(nf)
+	}
+	nf.transport = nf.bindBus(nf.transport)
+	nf.mirror()
+	if nf.resumeWatch {
+		nf.watchSleep()
+	}
+	return nf, nil
+}
+
+// baseNotifier returns a Notifier with the defaults, before its options
+// apply.
+func baseNotifier() *Notifier {
+	return &Notifier{
+		config: config{
+			destination:    dbusDestination,
+			path:           dbusObjectPath,
+			repostInterval: DefaultRepostInterval,
+			settleWindow:   DefaultSettleWindow,
+			queueCap:       DefaultQueueCapacity,
+			clock:          realClock{},
+		},
+		stopped:      make(chan struct{}),
+		errorReports: new(errorReports),
+	}
+}
+
+// private returns true if nf has its own connection instead of the shared
+// session bus connection.
+func (nf *Notifier) private() bool {
+	return nf.address != "" || len(nf.connOpts) > 0
+}
+
+// object returns the notification daemon object on conn.
+func (nf *Notifier) object(conn *dbus.Conn) dbus.BusObject {
+	return conn.Object(nf.destination, nf.path)
+}
+
+// connection returns the D-Bus connection of nf, connecting if necessary.
+func (nf *Notifier) connection() (*dbus.Conn, error) {
+	if nf.parent != nil {
+		conn, err := nf.parent.connection()
+		if err != nil {
+			return nil, err
+		}
+		nf.mu.Lock()
+		nf.conn = conn
+		nf.mu.Unlock()
+		return conn, nil
+	}
+
+	nf.mu.Lock()
+	if nf.conn != nil && !nf.conn.Connected() {
+		nf.connectionLost()
+	}
+	if nf.conn == nil {
+		conn, err := nf.dial()
+		if err != nil {
+			nf.mu.Unlock()
+			return nil, err
+		}
+		nf.conn = conn
+	}
+	conn := nf.conn
+	done, err := nf.restoreListener()
+	nf.mu.Unlock()
+
+	if err != nil {
+		nf.log(LevelWarn, "listening to the signals of the daemon again failed", err)
+	}
+	if done {
+		nf.reconnected()
+	}
+	return conn, nil
+}
+
+// Close closes the connection of nf if it is a private one. The shared
+// session bus connection is left open, as other code may be using it. The
+// connection of a Core is closed with its last Notifier, see Core. The
+// scopes and coordinators of nf are closed first, see Scope and Coordinate.
+func (nf *Notifier) Close() error {
+	nf.closeCoordinators()
+	err := nf.closeScopes()
+	if cerr := nf.close(); err == nil {
+		err = cerr
+	}
+	// The workers may be waiting for nf.mu, so they are stopped without it.
+	nf.pool.stop()
+	if nf.parent == nil {
+		if terr := nf.temps.removeAll(); err == nil {
+			err = terr
+		}
+		if nf.images != nil {
+			if ierr := nf.images.removeAll(); err == nil {
+				err = ierr
+			}
+		}
+	}
+	if nf.core != nil {
+		if cerr := nf.core.release(nf); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (nf *Notifier) close() error {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+
+	nf.closeEvents()
+	nf.stopReposts()
+	nf.stopSchedules()
+	nf.stopCatchUp()
+	nf.deferred = nil
+	nf.stats.Buffered = 0
+	nf.stopSessionWait()
+	if nf.conn == nil {
+		return nil
+	}
+	nf.stopListening()
+	if !nf.private() || nf.parent != nil {
+		nf.conn = nil
+		return nil
+	}
+	err := nf.conn.Close()
+	nf.conn = nil
+	return err
+}
+
+// Available returns true if notifications via D-Bus are available to nf.
+// See ServiceAvailable for details. With WithAutoActivation(false), it does
+// not start the daemon, and returns false if it is not running.
+func (nf *Notifier) Available() bool {
+	conn, err := nf.connection()
+	if err != nil {
+		return false
+	}
+
+	call := nf.object(conn).Call(dbusInterface+".GetCapabilities", nf.callFlags())
+	return call.Err == nil
+}
+
+// Notify sends the notification n via nf and updates n.Id with the ID the
+// notification daemon assigned to it. If n has callbacks, they are called
+// when the daemon signals that an action was invoked or that it was closed;
+// if the transport of nf does not deliver signals, see Transport, Notify
+// returns ErrUnsupported instead.
+//
+// If n has a Tag and no ID, it replaces the last notification sent by nf
+// with the same tag.
+//
+// If n is Persistent and the daemon does not keep notifications around by
+// itself, n is posted again every repost interval until it is acknowledged
+// with the AckAction or closed with CloseNotification. The reposts use a copy
+// of n taken by Notify.
+//
+// If nf drops duplicates, see WithDedup, n may not be sent at all. If nf is
+// paused, see Pause, n is sent on Resume. During quiet hours, see
+// WithQuietHours, n is sent later or dropped. If nf skips unchanged sends, see
+// WithSkipUnchanged, n may not be sent again.
+func (nf *Notifier) Notify(n *Notification) (SendResult, error) {
+	return nf.notify(context.Background(), n, false)
+}
+
+// NotifyForce is like Notify, but sends n even if it did not change since
+// it was last sent, see WithSkipUnchanged.
+func (nf *Notifier) NotifyForce(n *Notification) (SendResult, error) {
+	return nf.notify(context.Background(), n, true)
+}
+
+// SendContext is like Notify, but the call is delivered with ctx, which is
+// also passed to the trace hook, see WithTraceHook. If n has no
+// CorrelationID, it gets the one of ctx, see ContextWithCorrelationID.
+func (nf *Notifier) SendContext(ctx context.Context, n *Notification) (SendResult, error) {
+	return nf.notify(ctx, n, false)
+}
+
+func (nf *Notifier) notify(ctx context.Context, n *Notification, force bool) (SendResult, error) {
+	res, err := func() (SendResult, error) {
+		defer nf.lockNotification(n)()
+		return nf.notifyLocked(ctx, n, force)
+	}()
+	nf.reportError(n, err)
+	return res, err
+}
+
+// notifyLocked is notify, with the lock of n held, see lockNotification.
+func (nf *Notifier) notifyLocked(ctx context.Context, n *Notification, force bool) (SendResult, error) {
+	if n.closed {
+		// A new lifecycle: the closed notification is not replaced.
+		n.Id, n.closed = 0, false
+	}
+	if nf.transport != nil && (n.OnAction != nil || n.OnClose != nil || n.OnReply != nil) && !nf.signalSupport() {
+		return SendResult{}, errNoSignals
+	}
+	if id, ok := CorrelationIDFromContext(ctx); ok && n.CorrelationID == "" {
+		n.CorrelationID = id
+	}
+	correlate(n)
+	if nf.deferSend(n) {
+		return SendResult{Deferred: true}, nil
+	}
+	if nf.holdForSession(n) {
+		return SendResult{Deferred: true}, nil
+	}
+	if res, held, err := nf.holdQuiet(n); held {
+		return res, err
+	}
+	if nf.duplicate(n) {
+		return SendResult{Deduplicated: true}, nil
+	}
+	res, err := nf.deliver(ctx, n, force)
+	if err != nil || res.Skipped {
+		return res, err
+	}
+	nf.shown(n)
+	if nf.needsRepost(n) {
+		nf.startRepost(n)
+	}
+	return res, nil
+}
+
+// deliver sends n and registers it for its signals. Unless force is true,
+// n is not sent again if it did not change, see WithSkipUnchanged.
+func (nf *Notifier) deliver(ctx context.Context, n *Notification, force bool) (SendResult, error) {
+	if record := nf.storedTag(n); record != nil {
+		defer record()
+	}
+	c, err := nf.prepare(n)
+	if err != nil {
+		return SendResult{}, err
+	}
+	hash, skip := nf.unchanged(c)
+	if skip && !force {
+		nf.mu.Lock()
+		nf.stats.Skipped++
+		nf.mu.Unlock()
+		n.Id = c.ReplacesID
+		nf.recordScoped(n, 0, n.Id)
+		return SendResult{Id: n.Id, Replaced: true, Skipped: true}, nf.track(ctx, n, c)
+	}
+	if err := nf.paceSend(ctx, n); err != nil {
+		return SendResult{}, err
+	}
+	lowered := nf.lowersUrgency(c, n.AllowUrgencyDowngrade)
+	nf.trace(ctx, PhaseBefore, &c, nil)
+	nf.traceCall(&c)
+	id, extra, dropped, err := nf.sendWithinLimits(ctx, c)
+	nf.traceReply(id, c.CorrelationID, err)
+	nf.trace(ctx, PhaseAfterReply, &c, err)
+	if err != nil {
+		return SendResult{}, err
+	}
+	nf.setID(n, id)
+	if n.icon != nil {
+		nf.tempFiles().use(c.AppIcon, id)
+	}
+	nf.cacheImage(n, c, dropped)
+	nf.recordHash(id, hash)
+	nf.recordUrgency(id, c)
+	nf.recordScoped(n, c.ReplacesID, id)
+	nf.recordOpen(c.ReplacesID, id)
+	dc := nf.deliveryContext()
+	nf.recordSent(n, c, dc)
+	if c.ReplacesID != 0 {
+		nf.replaced(c.ReplacesID)
+	}
+	res := SendResult{Id: id, Extra: extra, Replaced: c.ReplacesID != 0, DroppedHints: dropped, Sanitized: c.Sanitized, UrgencyLowered: lowered, Context: dc}
+	if res.Replaced {
+		res.ReusedID = id == c.ReplacesID
+		if !res.ReusedID {
+			nf.log(LevelDebug, fmt.Sprintf("the daemon gave notification %d the new ID %d instead of replacing it", c.ReplacesID, id), nil)
+		}
+	}
+	if nf.dropsCriticalTimeout(n) {
+		res.CriticalNoExpiry = true
+		nf.log(LevelInfo, fmt.Sprintf("sent critical notification %d as never expiring instead of with the timeout %v", id, n.TimeoutDuration()), nil)
+	}
+	if len(c.Sanitized) > 0 {
+		nf.log(LevelInfo, fmt.Sprintf("sanitized notification %d: %v", id, c.Sanitized), nil)
+	}
+	if nf.onBus() {
+		res.ServerChanged = nf.ownerChanged()
+	}
+	if n.Tag != "" {
+		nf.setTagged(n)
+	}
+	err = nf.track(ctx, n, c)
+	nf.limitTracking(n)
+	nf.playSound(c)
+	nf.emit(Event{Kind: EventSent, ID: n.Id, CorrelationID: n.CorrelationID, ReplacesID: c.ReplacesID, ReusedID: res.ReusedID})
+	return res, err
+}
+
+// SendResult describes a notification that was sent.
+type SendResult struct {
+	// Id is the ID the daemon assigned to the notification.
+	Id uint32
+	// Extra holds the out-arguments some daemons reply with after the ID,
+	// which are otherwise ignored. It is nil for the daemons following the
+	// specification.
+	Extra []interface{}
+	// Replaced is true if the notification was sent to replace another one.
+	Replaced bool
+	// ReusedID is true if the daemon replaced the other notification, keeping
+	// its ID. Daemons that no longer know the ID to replace, because that
+	// notification was closed, may show a new one with a new ID instead:
+	// Id, and the ID of the notification, are then the new one.
+	ReusedID bool
+	// ServerChanged is true if the daemon is not the one the previous
+	// notification of the Notifier was sent to, for example because it was
+	// restarted. IDs from the previous daemon are no longer valid.
+	ServerChanged bool
+	// Deduplicated is true if the notification was not sent because it was
+	// already shown, see WithDedup. The other fields are then zero.
+	Deduplicated bool
+	// Deferred is true if the notification was held back because the
+	// Notifier is paused, see Pause. The other fields are then zero.
+	Deferred bool
+	// Skipped is true if the notification was not sent again because it
+	// did not change, see WithSkipUnchanged.
+	Skipped bool
+	// Quiet is true if the notification was held back by quiet hours, see
+	// WithQuietHours. The other fields are then zero.
+	Quiet bool
+	// DroppedHints are the keys of the hints the notification was sent
+	// without because the daemon rejected them, see WithDropHintsOnLimits.
+	DroppedHints []string
+	// Sanitized are the characters removed or escaped from the summary and
+	// body, see WithControlSanitizer.
+	Sanitized []SanitizedChar
+	// CriticalNoExpiry is true if the notification was critical and sent as
+	// never expiring instead of with its timeout, see WithCriticalNoExpiry.
+	CriticalNoExpiry bool
+	// UrgencyLowered is true if the notification replaced one sent with a
+	// higher urgency, see WithStrictUrgency.
+	UrgencyLowered bool
+	// Context is what was known of the desktop of the user when the
+	// notification was sent.
+	Context DeliveryContext
+}
+
+// heldBack returns true if the send did not show the notification, see
+// ErrNotShown.
+func (r SendResult) heldBack() bool {
+	return r.Quiet || r.Deferred || r.Skipped || r.Deduplicated
+}
+
+// ownerChanged records the daemon that received the last notification, and
+// returns true if it differs from the previous one.
+func (nf *Notifier) ownerChanged() bool {
+	owner := nf.daemonOwner()
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	changed := nf.lastOwner != "" && owner != nf.lastOwner
+	nf.lastOwner = owner
+	return changed
+}
+
+// tagged is the last notification sent with a tag.
+type tagged struct {
+	id uint32
+	n  *Notification
+}
+
+// taggedID returns the ID of the last notification sent with tag, or 0.
+func (nf *Notifier) taggedID(tag string) uint32 {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	return nf.tags[tag].id
+}
+
+// setTagged records n as the last notification sent with its tag. If it
+// replaced another Notification, the OnReplaced callback of that one is
+// called.
+func (nf *Notifier) setTagged(n *Notification) {
+	nf.mu.Lock()
+	if nf.tags == nil {
+		nf.tags = make(map[string]tagged)
+	}
+	old := nf.tags[n.Tag].n
+	nf.tags[n.Tag] = tagged{n.Id, n}
+	nf.mu.Unlock()
+
+	if old != nil && old != n && old.OnReplaced != nil {
+		nf.callback("OnReplaced", old.Id, func() { old.OnReplaced(n.Id) })
+	}
+}
+
+// CloseNotification asks the daemon to close the notification with the ID
+// id, if it is still shown.
+func (nf *Notifier) CloseNotification(id uint32) error {
+	if nf.transport == nil {
+		return ErrNoTransport
+	}
+	nf.stopRepost(id)
+	nf.mu.Lock()
+	nf.forgetSent(id)
+	nf.mu.Unlock()
+	nf.traceClose(id)
+	var err error
+	closed := false
+	if t, ok := nf.stringIDTransport(); ok {
+		closed, err = nf.closeStringID(t, id)
+	}
+	if !closed {
+		err = nf.transport.CloseNotification(id)
+	}
+	nf.traceReply(id, "", err)
+	return err
+}
@@ -0,0 +1,316 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/godbus/dbus/v5"
+)
+
+func TestNotifierPrivateConnection(t *testing.T) {
+	s := newFakeServer(t)
+	u, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nf, err := notify.NewNotifier(notify.WithAuth(dbus.AuthExternal(u.Uid)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+	if !nf.Available() {
+		t.Fatal("Available() = false with a running daemon")
+	}
+
+	n := notify.New("private", "summary", "", "", time.Second, notify.LowUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); n.Id == 0 || got.ID != n.Id || got.AppName != "private" {
+		t.Errorf("sent %+v, notification has id %d", got, n.Id)
+	}
+}
+
+func TestNotifierCustomDestination(t *testing.T) {
+	s := newFakeServerAt(t, "org.example.Notifications", "/org/example/Notifications")
+
+	nf, err := notify.NewNotifier(
+		notify.WithBusAddress(busAddress),
+		notify.WithDestination("org.example.Notifications", "/org/example/Notifications"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	if notify.ServiceAvailable() {
+		t.Error("ServiceAvailable() = true without a daemon at the default destination")
+	}
+	if _, err := nf.Notify(notify.New("kiosk", "summary", "", "", time.Second, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.AppName != "kiosk" {
+		t.Errorf("unexpected notification %+v", got)
+	}
+}
+
+func TestWithDestinationInvalid(t *testing.T) {
+	if _, err := notify.NewNotifier(notify.WithDestination("org.example.Notifications", "not/a/path")); err == nil {
+		t.Error("invalid object path accepted")
+	}
+	if _, err := notify.NewNotifier(notify.WithDestination("", "/org/example")); err == nil {
+		t.Error("empty destination name accepted")
+	}
+	if _, err := notify.NewNotifier(notify.WithBusAddress("")); err == nil {
+		t.Error("empty bus address accepted")
+	}
+}
+
+func TestSendResult(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	n := notify.New("test", "first", "", "", time.Second, notify.NormalUrgency)
+	res, err := nf.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Id == 0 || res.Id != n.Id || res.Replaced || res.ServerChanged {
+		t.Errorf("first send returned %+v", res)
+	}
+
+	res, err = nf.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Id != n.Id || !res.Replaced || res.ServerChanged {
+		t.Errorf("replacing send returned %+v", res)
+	}
+
+	s.conn.Close()
+	newFakeServer(t)
+	res, err = nf.Notify(notify.New("test", "after restart", "", "", time.Second, notify.NormalUrgency))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Replaced || !res.ServerChanged {
+		t.Errorf("send to a restarted daemon returned %+v", res)
+	}
+}
+
+// TestReusedID checks the result of replacing a closed notification with
+// daemons that reuse its ID and with daemons that assign a new one.
+func TestReusedID(t *testing.T) {
+	for _, fresh := range []bool{false, true} {
+		t.Run(fmt.Sprint("fresh=", fresh), func(t *testing.T) {
+			s := newFakeServer(t)
+			s.setFreshIDs(fresh)
+			nf := newTestNotifier(t)
+			events := nf.Events()
+
+			n := notify.New("test", "first", "", "", time.Second, notify.NormalUrgency)
+			n.OnClose = func(notify.CloseReason) {}
+			if _, err := nf.Notify(n); err != nil {
+				t.Fatal(err)
+			}
+			first := n.Id
+			if e := waitEvent(t, events, notify.EventSent); e.ReplacesID != 0 || e.ReusedID {
+				t.Errorf("first EventSent = %+v", e)
+			}
+
+			// A shown notification keeps its ID with both daemons.
+			res, err := nf.Notify(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.Replaced || !res.ReusedID || res.Id != first {
+				t.Errorf("replacing a shown notification returned %+v", res)
+			}
+			waitEvent(t, events, notify.EventSent)
+
+			s.emitClosed(first, uint32(notify.ReasonDismissed))
+			waitEvent(t, events, notify.EventClosed)
+			res, err = nf.Notify(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.Replaced || res.ReusedID != !fresh || res.Id != n.Id || s.last(t).ID != n.Id {
+				t.Errorf("replacing a closed notification returned %+v, notification ID %d", res, n.Id)
+			}
+			if fresh && n.Id == first {
+				t.Errorf("the notification kept the ID %d the daemon did not reuse", first)
+			}
+			e := waitEvent(t, events, notify.EventSent)
+			if e.ID != n.Id || e.ReplacesID != first || e.ReusedID != !fresh {
+				t.Errorf("EventSent = %+v", e)
+			}
+		})
+	}
+}
+
+func TestAppName(t *testing.T) {
+	requireBus(t)
+	derived := filepath.Base(os.Args[0])
+	tests := []struct {
+		name string
+		opts []notify.Option
+		want string
+	}{
+		{"explicit", nil, "explicit"},
+		{"explicit", []notify.Option{notify.WithAppName("kiosk")}, "explicit"},
+		{"", []notify.Option{notify.WithAppName("kiosk")}, "kiosk"},
+		{"", nil, derived},
+		{"", []notify.Option{notify.WithAppName("")}, ""},
+	}
+	for _, tt := range tests {
+		nf := newTestNotifier(t, tt.opts...)
+		n := notify.New(tt.name, "summary", "", "", time.Second, notify.NormalUrgency)
+		c, err := n.DryRun(nf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.AppName != tt.want {
+			t.Errorf("Name %q with %d options: sent app name %q, want %q", tt.name, len(tt.opts), c.AppName, tt.want)
+		}
+		if n.Name != tt.name {
+			t.Errorf("Name changed to %q", n.Name)
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	first := notify.New("test", "first", "", "", 0, notify.NormalUrgency)
+	first.Tag = "status"
+	if _, err := nf.Notify(first); err != nil {
+		t.Fatal(err)
+	}
+
+	n := notify.New("test", "summary", "body", "icon", 1500*time.Millisecond, notify.LowUrgency)
+	n.Tag = "status"
+	n.AddAction("ok", "OK")
+	c, err := n.DryRun(nf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.notifications()) != 1 {
+		t.Fatal("DryRun sent a notification")
+	}
+	if c.AppName != "test" || c.ReplacesID != first.Id || c.AppIcon != "icon" || c.Summary != "summary" ||
+		c.Body != "body" || c.ExpireTimeout != 1500 || len(c.Actions) != 2 || c.Hints["urgency"].Value() != byte(0) {
+		t.Errorf("unexpected call %+v", c)
+	}
+
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if got.ReplacesID != c.ReplacesID || got.Summary != c.Summary || len(got.Hints) != len(c.Hints) {
+		t.Errorf("sent %+v, DryRun returned %+v", got, c)
+	}
+}
+
+func TestRawNotifyAsync(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	const n = 20
+	done := make(chan *dbus.Call, n)
+	for i := 0; i < n; i++ {
+		go nf.RawNotifyAsync(context.Background(), notify.Call{AppName: "raw", Summary: "async"}, done)
+	}
+	ids := make(map[uint32]bool)
+	for i := 0; i < n; i++ {
+		select {
+		case call := <-done:
+			id, err := notify.NotifyReply(call)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids[id] = true
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the replies")
+		}
+	}
+	if len(ids) != n || len(s.notifications()) != n {
+		t.Errorf("got %d distinct IDs for %d notifications received", len(ids), len(s.notifications()))
+	}
+
+	id, err := nf.RawNotify(context.Background(), notify.Call{AppName: "raw", Summary: "sync", ReplacesID: 1})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != 1 || s.last(t).Summary != "sync" {
+		t.Errorf("RawNotify returned %d and sent %+v", id, s.last(t))
+	}
+}
+
+func TestHintsOrderOnTheWire(t *testing.T) {
+	s := newFakeServer(t)
+	var mu sync.Mutex
+	var bodies [][]byte
+	capture := dbus.WithOutgoingInterceptor(func(msg *dbus.Message) {
+		if member, _ := msg.Headers[dbus.FieldMember].Value().(string); member != "Notify" {
+			return
+		}
+		// The header fields are encoded in a random order: only compare
+		// the bodies, which end the messages.
+		var buf bytes.Buffer
+		if err := msg.EncodeTo(&buf, binary.LittleEndian); err != nil {
+			t.Error(err)
+			return
+		}
+		size := binary.LittleEndian.Uint32(buf.Bytes()[4:])
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, buf.Bytes()[buf.Len()-int(size):])
+	})
+	nf := newTestNotifier(t, notify.WithConnOptions(capture))
+
+	n := notify.New("test", "ordered", "", "", 0, notify.NormalUrgency)
+	for i := 0; i < 16; i++ {
+		n.SetHint(fmt.Sprintf("x-hint-%02d", i), int32(i))
+	}
+	c, err := n.DryRun(nf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorted := c.SortedHints()
+	if len(sorted) != len(c.Hints) || !sort.SliceIsSorted(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key }) {
+		t.Errorf("SortedHints() = %v", sorted)
+	}
+
+	for i := 0; i < 2; i++ {
+		n.Id = 0
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 {
+		t.Fatalf("captured %d Notify calls, want 2", len(bodies))
+	}
+	if !bytes.Equal(bodies[0], bodies[1]) {
+		t.Errorf("identical sends differ on the wire:\n%x\n%x", bodies[0], bodies[1])
+	}
+	if got := s.last(t); got.Signature.String() != "susssasa{sv}i" || len(got.Hints) != len(c.Hints) || got.Hints["x-hint-07"].Value() != int32(7) {
+		t.Errorf("the daemon got %+v", got)
+	}
+}
@@ -27,6 +27,10 @@
 //
 // Alternatively, you can create your own Notification template, via New.
 //
+// These functions send through a default Notifier, and keep working as they
+// always did. Programs needing more, like callbacks, a transport or options,
+// create their own Notifier with NewNotifier.
+//
 // The notify package has been developed according to
 // https://developer.gnome.org/notification-spec, although there is a lot of
 // functionality missing.
@@ -43,80 +47,3 @@
 // I have tried to implement this in the Go philosophy, please let me know if
 // I can improve it somehow.
 package notify
-
-import (
-	"time"
-)
-
-// note acts as the default notification, which allows you to set default
-// parameters and then send messages without creating any Notifications.
-var note = Notification{
-	Timeout: 3 * time.Second,
-	Urgency: NormalUrgency,
-}
-
-// Init sets the defaults for the implicit notification.
-func Init(name, icon string, timeout time.Duration, urgency NotificationUrgency) {
-	note.Name = name
-	note.IconPath = icon
-	note.Timeout = timeout
-	note.Urgency = urgency
-}
-
-// Name returns the name for the implicit notification.
-func Name() string { return note.Name }
-
-// SetName sets the name for the implicit notification.
-func SetName(name string) { note.Name = name }
-
-// IconPath returns the icon path for the implicit notification.
-func IconPath() string { return note.IconPath }
-
-// SetIconPath sets the icon path for the implicit notification.
-func SetIconPath(path string) { note.IconPath = path }
-
-// Timeout returns the timeout for the implicit notification.
-func Timeout() time.Duration { return note.Timeout }
-
-// SetTimeout sets the timeout for the implicit notification.
-func SetTimeout(dur time.Duration) { note.Timeout = dur }
-
-// Urgency returns the urgency level for the implicit notification.
-// It can be either LowUrgency, NormalUrgency, or CriticalUrgency.
-func Urgency() NotificationUrgency { return note.Urgency }
-
-// SetUrgency sets the urgency level for the implicit notification.
-// It can be either LowUrgency, NormalUrgency, or CriticalUrgency.
-func SetUrgency(urgency NotificationUrgency) { note.Urgency = urgency }
-
-// SendMsg sends the summary and the body as a notification, returning a unique
-// notification ID and an error, possibly nil. It takes all other values from
-// the implicit notification object.
-func SendMsg(summary, body string) (id uint32, err error) {
-	return SendUrgentMsg(summary, body, note.Urgency)
-}
-
-// SendUrgentMsg sends the summary and the body as a notification with the
-// urgency of urgency, and returns a unique notification ID and an error,
-// possibly nil. Otherwise it is like SendMsg.
-func SendUrgentMsg(summary, body string, urgency NotificationUrgency) (id uint32, err error) {
-	return notify(note.Name, summary, body, note.IconPath, 0, nil, urgency.asHint(), note.timeoutInMS())
-}
-
-// ReplaceMsg replaces the already existing notification with the ID id with
-// summary and body, returning the new ID and an error if it fails. It takes
-// all other values from the implicit notification object.
-//
-// In particular, if the notification it is replacing had other properties,
-// such as another urgency, these are also replaced by the defaults in the
-// implicit notification!
-func ReplaceMsg(id uint32, summary, body string) (newID uint32, err error) {
-	return ReplaceUrgentMsg(id, summary, body, note.Urgency)
-}
-
-// ReplaceUrgentMsg replaces the already existing notification with the ID id
-// with summary and body and urgency, returning the new ID and an error if it
-// fails. It takes all other values from the implicit notification object.
-func ReplaceUrgentMsg(id uint32, summary, body string, urgency NotificationUrgency) (newID uint32, err error) {
-	return notify(note.Name, summary, body, note.IconPath, id, nil, urgency.asHint(), note.timeoutInMS())
-}
@@ -2,11 +2,14 @@
 // Use of this source code is governed by an MIT license
 // that can be found in the LICENSE file.
 
-package notify
+package notify_test
 
 import (
-	"github.com/goulash/notify"
+	"context"
+	"strings"
 	"time"
+
+	"github.com/Schnouki/notify"
 )
 
 // This is a simple example for how to use the notify package.
@@ -15,7 +18,26 @@ func Example() {
 
 	notify.SendMsg("Starting up the Simple Server", "")
 	time.Sleep(3 * time.Second)
-	id, _ = notify.SendUrgentMsg("Oops, made a big mistake!", "", notify.CriticalUrgency)
+	id, _ := notify.SendUrgentMsg("Oops, made a big mistake!", "", notify.CriticalUrgency)
 	time.Sleep(1 * time.Second)
 	notify.ReplaceMsg(id, "Ha! Fixed that, thank goodness!", "")
 }
+
+func ExampleNotifier_SendPaginated() {
+	trace := strings.Repeat("goroutine 1 [running]:\nmain.main()\n", 20)
+
+	lines := strings.Split(trace, "\n")
+	var pages []string
+	for len(lines) > 10 {
+		pages = append(pages, strings.Join(lines[:10], "\n"))
+		lines = lines[10:]
+	}
+	pages = append(pages, strings.Join(lines, "\n"))
+
+	nf, err := notify.NewNotifier()
+	if err != nil {
+		return
+	}
+	n := notify.New("myapp", "Crash report", "", "", 0, notify.CriticalUrgency)
+	nf.SendPaginated(context.Background(), n, pages, 5*time.Second)
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package notifytest provides helpers for testing code using notify.
+package notifytest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// Clock is a notify.Clock whose time only moves with Advance, so that the
+// time-based features of a Notifier can be tested without sleeping:
+//
+//	clock := notifytest.NewClock(time.Time{})
+//	nf, err := notify.NewNotifier(notify.WithClock(clock))
+//	...
+//	clock.Advance(time.Minute)
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+// timer is a timer of a Clock. Timers with a function call it, the others
+// send the time on their channel.
+type timer struct {
+	c      *Clock
+	at     time.Time
+	f      func()
+	ch     chan time.Time
+	active bool
+}
+
+// NewClock returns a Clock showing start, or 2013-01-01 12:00 UTC if start
+// is the zero time.
+func NewClock(start time.Time) *Clock {
+	if start.IsZero() {
+		start = time.Date(2013, 1, 1, 12, 0, 0, 0, time.UTC)
+	}
+	return &Clock{now: start}
+}
+
+// Now returns the time of c.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a timer sending the time on its channel once c is
+// advanced by d.
+func (c *Clock) NewTimer(d time.Duration) notify.Timer {
+	return c.add(d, nil, make(chan time.Time, 1))
+}
+
+// After returns a channel receiving the time once c is advanced by d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// AfterFunc returns a timer calling f once c is advanced by d. Unlike with
+// the real clock, f is called by Advance, before it returns.
+func (c *Clock) AfterFunc(d time.Duration, f func()) notify.Timer {
+	return c.add(d, f, nil)
+}
+
+func (c *Clock) add(d time.Duration, f func(), ch chan time.Time) *timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &timer{c: c, at: c.now.Add(d), f: f, ch: ch, active: true}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Timers returns the number of timers that did not fire and were not
+// stopped, to wait for code running in other goroutines to start its
+// timers before calling Advance.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, t := range c.timers {
+		if t.active {
+			n++
+		}
+	}
+	return n
+}
+
+// Advance moves the time forward by d, firing the timers due in the
+// meantime in order.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		var next *timer
+		for _, t := range c.timers {
+			if t.active && !t.at.After(end) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		next.active = false
+		if next.at.After(c.now) {
+			c.now = next.at
+		}
+		c.prune()
+		if next.f == nil {
+			select {
+			case next.ch <- c.now:
+			default:
+			}
+			continue
+		}
+		c.mu.Unlock()
+		next.f()
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+// Suspend moves the time forward by d without firing any timer, as when
+// the system is suspended: the timers, which measure their durations on a
+// monotonic clock, fire d later than they would have. See
+// Notifier.HandleResume.
+func (c *Clock) Suspend(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.timers {
+		if t.active {
+			t.at = t.at.Add(d)
+		}
+	}
+}
+
+// prune forgets the timers that are no longer active. It must be called
+// with c.mu held.
+func (c *Clock) prune() {
+	active := c.timers[:0]
+	for _, t := range c.timers {
+		if t.active {
+			active = append(active, t)
+		}
+	}
+	for i := len(active); i < len(c.timers); i++ {
+		c.timers[i] = nil
+	}
+	c.timers = active
+}
+
+func (t *timer) C() <-chan time.Time { return t.ch }
+
+func (t *timer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	active := t.active
+	t.active = false
+	return active
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	active := t.active
+	t.at = t.c.now.Add(d)
+	t.active = true
+	for _, other := range t.c.timers {
+		if other == t {
+			return active
+		}
+	}
+	t.c.timers = append(t.c.timers, t)
+	return active
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notifytest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify/notifytest"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 2, 2, 0, 0, 0, 0, time.UTC)
+	c := notifytest.NewClock(start)
+
+	var order []string
+	c.AfterFunc(2*time.Second, func() { order = append(order, "func") })
+	timer := c.NewTimer(time.Second)
+	after := c.After(3 * time.Second)
+	stopped := c.AfterFunc(time.Second, func() { order = append(order, "stopped") })
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("Stop does not report whether the timer was active")
+	}
+	if n := c.Timers(); n != 3 {
+		t.Errorf("Timers() = %d, want 3", n)
+	}
+
+	c.Advance(1500 * time.Millisecond)
+	select {
+	case at := <-timer.C():
+		if want := start.Add(time.Second); !at.Equal(want) {
+			t.Errorf("timer fired at %v, want %v", at, want)
+		}
+	default:
+		t.Error("timer did not fire")
+	}
+	if len(order) != 0 {
+		t.Errorf("called %q too early", order)
+	}
+	if got := c.Now(); !got.Equal(start.Add(1500 * time.Millisecond)) {
+		t.Errorf("Now() = %v", got)
+	}
+
+	if timer.Reset(time.Second) {
+		t.Error("Reset of a fired timer returned true")
+	}
+	c.Advance(2 * time.Second)
+	if len(order) != 1 || order[0] != "func" {
+		t.Errorf("called %q, want [func]", order)
+	}
+	for _, ch := range []<-chan time.Time{timer.C(), after} {
+		select {
+		case <-ch:
+		default:
+			t.Error("timer did not fire")
+		}
+	}
+	if n := c.Timers(); n != 0 {
+		t.Errorf("Timers() = %d after all fired", n)
+	}
+}
+
+func TestClockSuspend(t *testing.T) {
+	start := time.Date(2020, 2, 2, 0, 0, 0, 0, time.UTC)
+	c := notifytest.NewClock(start)
+	fired := false
+	c.AfterFunc(time.Minute, func() { fired = true })
+
+	c.Suspend(time.Hour)
+	if fired {
+		t.Error("the timer fired during the suspend")
+	}
+	if want := start.Add(time.Hour); !c.Now().Equal(want) {
+		t.Errorf("Now() = %v, want %v", c.Now(), want)
+	}
+	c.Advance(59 * time.Second)
+	if fired {
+		t.Error("the timer fired before its monotonic duration")
+	}
+	c.Advance(time.Second)
+	if !fired {
+		t.Error("the timer did not fire a minute of running time after it started")
+	}
+}
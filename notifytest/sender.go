@@ -0,0 +1,91 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notifytest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Schnouki/notify"
+)
+
+// NopSender is a notify.Sender that sends nothing: its notifications all get
+// the ID 1, and closing them does nothing.
+type NopSender struct{}
+
+// SendContext sets the ID of n to 1 unless ctx is done.
+func (NopSender) SendContext(ctx context.Context, n *notify.Notification) (notify.SendResult, error) {
+	if err := ctx.Err(); err != nil {
+		return notify.SendResult{}, err
+	}
+	n.Id = 1
+	return notify.SendResult{Id: 1}, nil
+}
+
+// CloseNotification does nothing.
+func (NopSender) CloseNotification(id uint32) error { return nil }
+
+// RecordingSender is a notify.Sender recording the notifications sent and
+// closed, so that tests can check them:
+//
+//	var s notifytest.RecordingSender
+//	app := newApp(&s)
+//	...
+//	if sent := s.Sent(); len(sent) != 1 { ... }
+//
+// Like a daemon, it gives the notifications IDs from 1 up, and those
+// replacing another keep its ID. The zero value is ready to use.
+type RecordingSender struct {
+	mu     sync.Mutex
+	lastID uint32
+	sent   []notify.Notification
+	closed []uint32
+}
+
+// SendContext records a copy of n, and sets its ID, unless ctx is done.
+func (s *RecordingSender) SendContext(ctx context.Context, n *notify.Notification) (notify.SendResult, error) {
+	if err := ctx.Err(); err != nil {
+		return notify.SendResult{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := notify.SendResult{Id: n.Id, Replaced: n.Id != 0, ReusedID: n.Id != 0}
+	if n.Id == 0 {
+		s.lastID++
+		res.Id = s.lastID
+	}
+	n.Id = res.Id
+	s.sent = append(s.sent, *n)
+	return res, nil
+}
+
+// CloseNotification records that id was closed.
+func (s *RecordingSender) CloseNotification(id uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = append(s.closed, id)
+	return nil
+}
+
+// Sent returns copies of the notifications sent, in order.
+func (s *RecordingSender) Sent() []notify.Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]notify.Notification(nil), s.sent...)
+}
+
+// Closed returns the IDs of the notifications closed, in order.
+func (s *RecordingSender) Closed() []uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uint32(nil), s.closed...)
+}
+
+// Reset forgets the notifications sent and closed.
+func (s *RecordingSender) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent, s.closed = nil, nil
+}
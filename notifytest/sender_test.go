@@ -0,0 +1,64 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notifytest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/notifytest"
+)
+
+func TestRecordingSender(t *testing.T) {
+	var s notifytest.RecordingSender
+	var sender notify.Sender = &s
+	ctx := context.Background()
+
+	n := notify.New("test", "first", "", "", 0, notify.NormalUrgency)
+	if res, err := sender.SendContext(ctx, n); err != nil || res.Id != 1 || res.Replaced || n.Id != 1 {
+		t.Fatalf("SendContext = %+v, %v, ID %d", res, err, n.Id)
+	}
+	n.Summary = "replaced"
+	if res, err := sender.SendContext(ctx, n); err != nil || res.Id != 1 || !res.Replaced || !res.ReusedID {
+		t.Fatalf("SendContext(replacing) = %+v, %v", res, err)
+	}
+	other := notify.New("test", "second", "", "", 0, notify.NormalUrgency)
+	if res, _ := sender.SendContext(ctx, other); res.Id != 2 {
+		t.Errorf("second notification got ID %d, want 2", res.Id)
+	}
+	if err := sender.CloseNotification(2); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := s.Sent()
+	if len(sent) != 3 || sent[0].Summary != "first" || sent[1].Summary != "replaced" || sent[2].Summary != "second" {
+		t.Errorf("Sent() = %+v", sent)
+	}
+	if closed := s.Closed(); len(closed) != 1 || closed[0] != 2 {
+		t.Errorf("Closed() = %v, want [2]", closed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := sender.SendContext(cancelled, other); err != context.Canceled {
+		t.Errorf("SendContext with a cancelled context = %v", err)
+	}
+	s.Reset()
+	if len(s.Sent()) != 0 || len(s.Closed()) != 0 {
+		t.Error("Reset kept the records")
+	}
+}
+
+func TestNopSender(t *testing.T) {
+	var sender notify.Sender = notifytest.NopSender{}
+	n := notify.New("test", "nothing", "", "", 0, notify.NormalUrgency)
+	if res, err := sender.SendContext(context.Background(), n); err != nil || res.Id != 1 || n.Id != 1 {
+		t.Errorf("SendContext = %+v, %v", res, err)
+	}
+	if err := sender.CloseNotification(1); err != nil {
+		t.Error(err)
+	}
+}
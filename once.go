@@ -0,0 +1,91 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errNoIDStore is returned by OncePer, AskRemember and the like without an
+// ID store.
+var errNoIDStore = errors.New("notify: no ID store, see WithIDStore")
+
+// keyPath returns the file of key in the store, named prefix followed by a
+// hash of key, so that keys of any length make valid file names.
+func (s *IDStore) keyPath(prefix, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, prefix+hex.EncodeToString(sum[:]))
+}
+
+// oncePath returns the file recording when key was last sent by OncePer.
+func (s *IDStore) oncePath(key string) string {
+	return s.keyPath("once-", key)
+}
+
+// OncePer sends n, unless a notification was sent with key within d, by any
+// process using the same ID store as nf (see WithIDStore). It is meant for
+// the helpers started with the session, which should not repeat the same
+// notification on every login.
+//
+// When n is shown, the time is recorded for key; a notification held back,
+// for example during quiet hours, is not recorded. The file of
+// key is locked from the check to the record, whatever the locking of the
+// store, so that only one of several processes calling OncePer at the same
+// time sends n. Skipping n is not an error.
+func (nf *Notifier) OncePer(key string, d time.Duration, n *Notification) error {
+	if nf.idStore == nil {
+		return errNoIDStore
+	}
+	f, err := os.OpenFile(nf.idStore.oncePath(key), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	// Closing the file releases the lock.
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+
+	var buf [32]byte
+	size, _ := f.ReadAt(buf[:], 0)
+	now := nf.clock.Now()
+	if v, err := strconv.ParseInt(strings.TrimSpace(string(buf[:size])), 10, 64); err == nil {
+		if last := time.Unix(0, v); now.Sub(last) < d {
+			nf.log(LevelDebug, fmt.Sprintf("skipped notification %q, sent at %v", key, last), nil)
+			return nil
+		}
+	}
+
+	if res, err := nf.Notify(n); err != nil || res.heldBack() {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.FormatInt(now.UnixNano(), 10)+"\n"), 0); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	return nil
+}
+
+// ClearOnce forgets when a notification was last sent with key by OncePer,
+// so that the next OncePer with key sends it.
+func (nf *Notifier) ClearOnce(key string) error {
+	if nf.idStore == nil {
+		return errNoIDStore
+	}
+	if err := os.Remove(nf.idStore.oncePath(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("notify: %w", err)
+	}
+	return nil
+}
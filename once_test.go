@@ -0,0 +1,113 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestOncePer(t *testing.T) {
+	s := newFakeServer(t)
+	dir := t.TempDir()
+	clock := newFakeClock()
+	store, err := notify.NewIDStore(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nf := newTestNotifier(t, notify.WithIDStore(store), notify.WithClock(clock))
+	backup := func() *notify.Notification {
+		return notify.New("test", "backup is 3 days old", "", "", 0, notify.NormalUrgency)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := nf.OncePer("backup", 24*time.Hour, backup()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(s.notifications()); got != 1 {
+		t.Fatalf("sent %d notifications, want 1", got)
+	}
+
+	clock.Advance(25 * time.Hour)
+	if err := nf.OncePer("backup", 24*time.Hour, backup()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(s.notifications()); got != 2 {
+		t.Fatalf("sent %d notifications after a day, want 2", got)
+	}
+
+	if err := nf.ClearOnce("backup"); err != nil {
+		t.Fatal(err)
+	}
+	if err := nf.OncePer("backup", 24*time.Hour, backup()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(s.notifications()); got != 3 {
+		t.Errorf("sent %d notifications after ClearOnce, want 3", got)
+	}
+	if err := nf.ClearOnce("never sent"); err != nil {
+		t.Error(err)
+	}
+
+	if err := newTestNotifier(t).OncePer("backup", time.Hour, backup()); err == nil {
+		t.Error("OncePer without an ID store succeeded")
+	}
+}
+
+func TestOncePerHeldBack(t *testing.T) {
+	s := newFakeServer(t)
+	dir := t.TempDir()
+	welcome := func() *notify.Notification {
+		return notify.New("test", "welcome", "", "", 0, notify.NormalUrgency)
+	}
+	// The key is too long for a file name of its own.
+	key := strings.Repeat("login ", 100)
+
+	paused := storeNotifier(t, dir)
+	paused.Pause()
+	if err := paused.OncePer(key, time.Hour, welcome()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(s.notifications()); got != 0 {
+		t.Fatalf("sent %d notifications while paused", got)
+	}
+	for i := 0; i < 2; i++ {
+		if err := storeNotifier(t, dir).OncePer(key, time.Hour, welcome()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(s.notifications()); got != 1 {
+		t.Errorf("sent %d notifications, want the one held back sent once", got)
+	}
+}
+
+// TestOncePerConcurrent checks that only one of the processes logging in at
+// the same time sends the notification.
+func TestOncePerConcurrent(t *testing.T) {
+	s := newFakeServer(t)
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		nf := storeNotifier(t, dir)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := notify.New("test", "welcome", "", "", 0, notify.NormalUrgency)
+			if err := nf.OncePer("login", time.Hour, n); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := len(s.notifications()); got != 1 {
+		t.Errorf("sent %d notifications, want 1", got)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MoreActionsAction is the key of the action showing the next actions of a
+// notification with more actions than the daemon shows, see
+// ActionOverflowPaginate.
+const MoreActionsAction = "more-actions"
+
+// ErrTooManyActions is returned, wrapped in a FieldError, for notifications
+// with more actions than the daemon shows, see ActionOverflowError.
+var ErrTooManyActions = errors.New("more actions than the daemon shows")
+
+// ActionOverflow is what a Notifier does with the notifications that have
+// more actions than the daemon shows, see Quirks.MaxActions. The
+// DefaultAction is not shown as a button, and does not count.
+type ActionOverflow int
+
+const (
+	// ActionOverflowSend sends all the actions, and logs a warning: the
+	// daemon drops the ones it cannot show.
+	ActionOverflowSend ActionOverflow = iota
+	// ActionOverflowError fails the send with ErrTooManyActions.
+	ActionOverflowError
+	// ActionOverflowPaginate shows the actions a page at a time: each page
+	// but the last one has the next actions that fit, and a "More…" action,
+	// MoreActionsAction, sending the notification again with the following
+	// ones. It needs at least two actions shown, and signals.
+	ActionOverflowPaginate
+)
+
+// WithActionOverflow sets what the Notifier does with the notifications
+// that have more actions than the daemon shows. The default is
+// ActionOverflowSend.
+func WithActionOverflow(o ActionOverflow) Option {
+	return func(nf *Notifier) error {
+		if o < ActionOverflowSend || o > ActionOverflowPaginate {
+			return fmt.Errorf("notify: invalid action overflow %d", o)
+		}
+		nf.actionOverflow = o
+		return nil
+	}
+}
+
+// actionPage is the page of actions shown by a notification, see
+// ActionOverflowPaginate.
+type actionPage struct {
+	page int
+	// turningFrom is the ID of the notification whose MoreActionsAction
+	// was invoked, until the daemon closes it or the next page replaces it.
+	turningFrom uint32
+}
+
+// pageActions returns the actions to send out of the actions of n, that
+// the daemon may not all show.
+func (nf *Notifier) pageActions(n *Notification, actions []Action) ([]Action, error) {
+	buttons := 0
+	for _, a := range actions {
+		if a.Key != DefaultAction {
+			buttons++
+		}
+	}
+	if buttons == 0 {
+		return actions, nil
+	}
+	max := nf.daemonQuirks().MaxActions
+	paginate := nf.actionOverflow == ActionOverflowPaginate && max >= 2 && nf.onBus()
+	if max <= 0 || buttons <= max {
+		if paginate {
+			nf.mu.Lock()
+			delete(nf.actionPages, n)
+			nf.mu.Unlock()
+		}
+		return actions, nil
+	}
+	if nf.actionOverflow == ActionOverflowError {
+		return nil, fmt.Errorf("notify: %w", &FieldError{"actions", fmt.Errorf("%w: %d, the daemon shows %d", ErrTooManyActions, buttons, max)})
+	}
+	if !paginate {
+		// The daemon drops the buttons past max, see hiddenButtons.
+		nf.log(LevelWarn, fmt.Sprintf("the notification daemon only shows %d of the %d actions of %q", max, buttons, n.Summary), nil)
+		return actions, nil
+	}
+
+	nf.mu.Lock()
+	start := 0
+	if p := nf.actionPages[n]; p != nil {
+		start = p.page * (max - 1)
+	}
+	nf.mu.Unlock()
+	if start >= buttons {
+		// The actions changed since the last page.
+		start = 0
+	}
+	last := buttons-start <= max
+	paged := make([]Action, 0, max+1)
+	i := 0
+	for _, a := range actions {
+		if a.Key == DefaultAction {
+			paged = append(paged, a)
+			continue
+		}
+		if i >= start && (last || i < start+max-1) {
+			paged = append(paged, a)
+		}
+		i++
+	}
+	if !last {
+		paged = append(paged, Action{MoreActionsAction, nf.localize(MsgMoreActions)})
+	}
+	return paged, nil
+}
+
+// hiddenButtons returns the keys of the buttons of the action pairs of a
+// call that a daemon showing max of them drops.
+func hiddenButtons(actions []string, max int) []string {
+	if max <= 0 {
+		return nil
+	}
+	var hidden []string
+	shown := 0
+	for i := 0; i+1 < len(actions); i += 2 {
+		if actions[i] == DefaultAction {
+			continue
+		}
+		if shown++; shown > max {
+			hidden = append(hidden, actions[i])
+		}
+	}
+	return hidden
+}
+
+// pagesActions returns true if n, sent with c, shows its actions a page at
+// a time, and so must get the signals of the daemon.
+func (nf *Notifier) pagesActions(n *Notification, c Call) bool {
+	if (trackedNotification{call: c}).hasAction(MoreActionsAction) {
+		return true
+	}
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	return nf.actionPages[n] != nil
+}
+
+// turnActionPage starts showing the next page of actions of n, whose
+// MoreActionsAction was invoked on the notification id, and returns true,
+// or returns false if n does not page its actions. It must be called with
+// nf.mu held.
+func (nf *Notifier) turnActionPage(n *Notification, id uint32) bool {
+	if nf.actionOverflow != ActionOverflowPaginate {
+		return false
+	}
+	p := nf.actionPages[n]
+	if p == nil {
+		if nf.actionPages == nil {
+			nf.actionPages = make(map[*Notification]*actionPage)
+		}
+		p = &actionPage{}
+		nf.actionPages[n] = p
+	}
+	p.page++
+	p.turningFrom = id
+	go nf.showActionPage(n, id)
+	return true
+}
+
+// showActionPage sends n again with its current page of actions, replacing
+// the notification from.
+func (nf *Notifier) showActionPage(n *Notification, from uint32) {
+	res, err := nf.Patch(n, func(*Notification) {})
+	if err != nil {
+		nf.log(LevelWarn, fmt.Sprintf("showing the next actions of notification %d failed", from), err)
+	}
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if p := nf.actionPages[n]; p != nil && (err != nil || res.Id == from) && p.turningFrom == from {
+		// The daemon will not close from for the page turn.
+		p.turningFrom = 0
+	}
+}
+
+// actionPageClosed forgets the page of actions of n, closed with the ID id,
+// and returns true if it was closed for turning the page: the next page
+// replaces it. It must be called with nf.mu held.
+func (nf *Notifier) actionPageClosed(n *Notification, id uint32) bool {
+	p := nf.actionPages[n]
+	if p == nil {
+		return false
+	}
+	if p.turningFrom == id {
+		p.turningFrom = 0
+		return true
+	}
+	delete(nf.actionPages, n)
+	return false
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+// deviceNotification returns a notification with five device actions and
+// a default one.
+func deviceNotification() *notify.Notification {
+	n := notify.New("test", "Pick a device", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Settings")
+	for _, d := range []string{"hdmi", "usb", "jack", "bt", "spdif"} {
+		n.AddAction(d, d)
+	}
+	return n
+}
+
+func TestActionOverflowError(t *testing.T) {
+	s := newFakeServer(t)
+	s.setServerName("gnome-shell")
+	nf := newTestNotifier(t, notify.WithActionOverflow(notify.ActionOverflowError))
+	_, err := nf.Notify(deviceNotification())
+	var fe *notify.FieldError
+	if !errors.Is(err, notify.ErrTooManyActions) || !errors.As(err, &fe) || fe.Field != "actions" {
+		t.Errorf("Notify() = %v, want ErrTooManyActions", err)
+	}
+	if s.received() != 0 {
+		t.Errorf("server received %d notifications", s.received())
+	}
+
+	// Three actions, besides the default one, fit.
+	n := deviceNotification()
+	n.Actions = n.Actions[:4]
+	if _, err := nf.Notify(n); err != nil {
+		t.Errorf("Notify() = %v with three actions", err)
+	}
+}
+
+func TestActionOverflowSend(t *testing.T) {
+	s := newFakeServer(t)
+	s.setServerName("gnome-shell")
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithLogger(logs.log))
+	if _, err := nf.Notify(deviceNotification()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(s.last(t).Actions); got != 12 {
+		t.Errorf("sent %d action strings, want all 12", got)
+	}
+	if !logs.logged("only shows 3 of the 5 actions") {
+		t.Errorf("no warning about the dropped actions")
+	}
+}
+
+func TestActionOverflowPaginate(t *testing.T) {
+	s := newFakeServer(t)
+	s.setServerName("gnome-shell")
+	// GNOME Shell closes notifications when an action is invoked.
+	s.setFreshIDs(true)
+	nf := newTestNotifier(t, notify.WithActionOverflow(notify.ActionOverflowPaginate))
+	events := nf.Events()
+	n := deviceNotification()
+	var (
+		mu      sync.Mutex
+		chosen  []string
+		reasons []notify.CloseReason
+	)
+	n.OnAction = func(key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		chosen = append(chosen, key)
+	}
+	n.OnClose = func(reason notify.CloseReason) {
+		mu.Lock()
+		defer mu.Unlock()
+		reasons = append(reasons, reason)
+	}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, notify.EventSent)
+	first := s.last(t)
+	want := []string{"default", "Settings", "hdmi", "hdmi", "usb", "usb", notify.MoreActionsAction, "More…"}
+	if !reflect.DeepEqual(first.Actions, want) {
+		t.Errorf("first page %q, want %q", first.Actions, want)
+	}
+
+	// The page turn, and the close the daemon makes of it, are not seen by
+	// the callbacks.
+	release := s.stall(t)
+	s.emitAction(first.ID, notify.MoreActionsAction)
+	waitFor(t, "the second page", func() bool { return s.received() == 2 })
+	s.emitClosed(first.ID, uint32(notify.ReasonDismissed))
+	release()
+	// The signals of the second page are only delivered once it is
+	// tracked, when it is sent.
+	if e := waitEvent(t, events, notify.EventSent); e.ReplacesID != first.ID {
+		t.Fatalf("sent %+v, want the second page", e)
+	}
+	second := s.last(t)
+	want = []string{"default", "Settings", "jack", "jack", "bt", "bt", "spdif", "spdif"}
+	if !reflect.DeepEqual(second.Actions, want) || second.ReplacesID != first.ID {
+		t.Errorf("second page %q replacing %d, want %q replacing %d", second.Actions, second.ReplacesID, want, first.ID)
+	}
+
+	s.emitAction(second.ID, "bt")
+	s.emitClosed(second.ID, uint32(notify.ReasonClosed))
+	waitFor(t, "the callbacks", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(reasons) == 1
+	})
+	mu.Lock()
+	if !reflect.DeepEqual(chosen, []string{"bt"}) || reasons[0] != notify.ReasonClosed {
+		t.Errorf("OnAction got %q, OnClose %v", chosen, reasons)
+	}
+	mu.Unlock()
+
+	// Once closed, the notification starts again from the first page.
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Actions; !reflect.DeepEqual(got, first.Actions) {
+		t.Errorf("sent again with %q, want the first page", got)
+	}
+}
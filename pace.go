@@ -0,0 +1,130 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// ErrRateLimited wraps the errors of the daemons refusing a notification
+// because the application sent too many of them.
+var ErrRateLimited = errors.New("notify: rate limited by the notification daemon")
+
+// Pace is a rate of notifications: at most Limit of them in any Window. It
+// is encoded in JSON as {"limit": 10, "window": "10s"}.
+type Pace struct {
+	Limit  int
+	Window time.Duration
+}
+
+// jsonPace is the JSON encoding of a Pace.
+type jsonPace struct {
+	Limit  int      `json:"limit"`
+	Window Duration `json:"window"`
+}
+
+func (p Pace) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonPace{p.Limit, Duration(p.Window)})
+}
+
+func (p *Pace) UnmarshalJSON(data []byte) error {
+	var j jsonPace
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*p = Pace{j.Limit, time.Duration(j.Window)}
+	return nil
+}
+
+// valid returns true if p limits the rate.
+func (p Pace) valid() bool {
+	return p.Limit > 0 && p.Window > 0
+}
+
+// WithAdaptivePacing makes the Notifier space its notifications so that the
+// daemon never gets more of them in a window than it accepts: the Pace in
+// its Quirks, or else def. When a send has to wait, it sleeps on the clock
+// of the Notifier, or until the context of SendContext is done, and emits
+// an EventPaced with the delay. Critical notifications are never held
+// back, and do not count.
+func WithAdaptivePacing(def Pace) Option {
+	return func(nf *Notifier) error {
+		if !def.valid() {
+			return fmt.Errorf("notify: invalid pace of %d per %v", def.Limit, def.Window)
+		}
+		nf.pace = &def
+		return nil
+	}
+}
+
+// daemonPace returns the pace of the daemon, see WithAdaptivePacing.
+func (nf *Notifier) daemonPace() Pace {
+	if q := nf.daemonQuirks(); q.Pace.valid() {
+		return q.Pace
+	}
+	return *nf.pace
+}
+
+// paceSend waits until n can be sent without exceeding the pace of the
+// daemon, if nf paces its notifications. The send is given the first free
+// slot right away, so that concurrent sends are spread in order.
+func (nf *Notifier) paceSend(ctx context.Context, n *Notification) error {
+	if nf.pace == nil || n.Urgency == CriticalUrgency {
+		return nil
+	}
+	p := nf.daemonPace()
+
+	nf.mu.Lock()
+	now := nf.clock.Now()
+	sends := nf.paced
+	for len(sends) > 0 && now.Sub(sends[0]) >= p.Window {
+		sends = sends[1:]
+	}
+	at := now
+	if len(sends) >= p.Limit {
+		if free := sends[len(sends)-p.Limit].Add(p.Window); free.After(at) {
+			at = free
+		}
+	}
+	nf.paced = append(sends, at)
+	delay := at.Sub(now)
+	if delay > 0 {
+		nf.stats.Paced++
+	}
+	nf.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	nf.emit(Event{Kind: EventPaced, Tag: n.Tag, CorrelationID: n.CorrelationID, Delay: delay})
+	select {
+	case <-nf.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimited wraps err with ErrRateLimited if the daemon refused the
+// notification for its rate.
+func rateLimited(err error) error {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return err
+	}
+	name := dbusErr.Name[strings.LastIndexByte(dbusErr.Name, '.')+1:]
+	switch name {
+	case "RateLimited", "RateLimitExceeded", "TooManyNotifications":
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	}
+	return err
+}
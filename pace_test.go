@@ -0,0 +1,91 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestAdaptivePacing(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	start := clock.Now()
+	nf := newTestNotifier(t,
+		notify.WithClock(clock),
+		notify.WithQuirks(notify.Quirks{Pace: notify.Pace{Limit: 10, Window: time.Second}}),
+		notify.WithAdaptivePacing(notify.Pace{Limit: 100, Window: time.Minute}))
+	events := nf.Events()
+
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 50; i++ {
+			if _, err := nf.Notify(notify.New("test", fmt.Sprint(i), "", "", 0, notify.NormalUrgency)); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	var sent []time.Duration
+	paced := 0
+	for finished := false; !finished || len(events) > 0; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			finished = true
+		case e := <-events:
+			switch e.Kind {
+			case notify.EventSent:
+				sent = append(sent, e.Time.Sub(start))
+			case notify.EventPaced:
+				paced++
+			}
+		case <-time.After(time.Millisecond):
+			if clock.Timers() > 0 {
+				clock.Advance(100 * time.Millisecond)
+			}
+		case <-waitTimeout():
+			t.Fatalf("only %d notifications sent", len(sent))
+		}
+	}
+
+	if len(sent) != 50 || s.received() != 50 {
+		t.Fatalf("%d sent and %d received, want 50", len(sent), s.received())
+	}
+	for i := 10; i < len(sent); i++ {
+		if d := sent[i] - sent[i-10]; d < time.Second {
+			t.Errorf("sends %d and %d are %v apart, want at least 1s", i-10, i, d)
+		}
+	}
+	if last := sent[len(sent)-1]; last != 4*time.Second {
+		t.Errorf("last send at %v, want 4s", last)
+	}
+	if paced != 4 || nf.Stats().Paced != 4 {
+		t.Errorf("%d EventPaced and %d paced sends, want 4", paced, nf.Stats().Paced)
+	}
+
+	// The window is full again, but critical notifications go through.
+	if _, err := nf.Notify(notify.New("test", "urgent", "", "", 0, notify.CriticalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if s.received() != 51 {
+		t.Error("the critical notification was held back")
+	}
+}
+
+func TestAdaptivePacingInvalid(t *testing.T) {
+	for _, p := range []notify.Pace{{}, {Limit: 1}, {Window: time.Second}, {Limit: -1, Window: time.Second}} {
+		if _, err := notify.NewNotifier(notify.WithAdaptivePacing(p)); err == nil {
+			t.Errorf("pace %+v accepted", p)
+		}
+	}
+}
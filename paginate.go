@@ -0,0 +1,117 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// NextPageAction is the key of the action showing the next page of a
+// notification sent with SendPaginated.
+const NextPageAction = "next-page"
+
+// SendPaginated shows n with each of pages as its body in turn, for content
+// too long for one notification, like a stack trace. A page is replaced by
+// the next one after interval, or when the user invokes the "Next" action;
+// if interval is 0, only the action turns pages. The last page is left shown
+// with the timeout of n, and SendPaginated returns once it is.
+//
+// If n is closed, for example dismissed by the user, the sequence stops and
+// ErrDismissed is returned.
+// If ctx is done first, n is closed and the error of ctx is returned. The
+// OnAction and OnClose callbacks of n are still called, and are restored
+// when SendPaginated returns.
+//
+// For example, to show a stack trace ten lines at a time:
+//
+//	lines := strings.Split(trace, "\n")
+//	var pages []string
+//	for len(lines) > 10 {
+//		pages = append(pages, strings.Join(lines[:10], "\n"))
+//		lines = lines[10:]
+//	}
+//	pages = append(pages, strings.Join(lines, "\n"))
+//	n := notify.New("myapp", "Crash report", "", "", 0, notify.CriticalUrgency)
+//	err := nf.SendPaginated(ctx, n, pages, 5*time.Second)
+func (nf *Notifier) SendPaginated(ctx context.Context, n *Notification, pages []string, interval time.Duration) error {
+	if len(pages) == 0 {
+		return errors.New("notify: no pages to send")
+	}
+
+	next := make(chan struct{}, 1)
+	closed := make(chan struct{}, 1)
+	onAction, onClose := n.OnAction, n.OnClose
+	n.OnAction = func(key string) {
+		if key == NextPageAction {
+			select {
+			case next <- struct{}{}:
+			default:
+			}
+			return
+		}
+		if onAction != nil {
+			onAction(key)
+		}
+	}
+	n.OnClose = func(reason CloseReason) {
+		if onClose != nil {
+			onClose(reason)
+		}
+		select {
+		case closed <- struct{}{}:
+		default:
+		}
+	}
+	defer nf.restoreCallbacks(n, onAction, onClose)
+
+	actions := n.Actions
+	for i, page := range pages {
+		last := i == len(pages)-1
+		n.Body = page
+		n.Actions = actions
+		if !last {
+			n.Actions = append(actions[:len(actions):len(actions)], Action{NextPageAction, nf.localize(MsgNextPage)})
+		}
+		if _, err := nf.SendContext(ctx, n); err != nil {
+			n.Actions = actions
+			return err
+		}
+		if last {
+			return nil
+		}
+
+		if err := nf.waitPage(ctx, n, interval, next, closed); err != nil {
+			n.Actions = actions
+			return err
+		}
+	}
+	return nil
+}
+
+// waitPage waits until the current page of n must be replaced by the next
+// one, and returns an error if the sequence must stop instead.
+func (nf *Notifier) waitPage(ctx context.Context, n *Notification, interval time.Duration, next, closed <-chan struct{}) error {
+	var timeout <-chan time.Time
+	if interval > 0 {
+		t := nf.clock.NewTimer(interval)
+		defer t.Stop()
+		timeout = t.C()
+	}
+	select {
+	case <-next:
+		return nil
+	case <-timeout:
+		return nil
+	case <-closed:
+		return ErrDismissed
+	case <-ctx.Done():
+		nf.CloseNotification(n.Id)
+		return ctx.Err()
+	case <-nf.stopped:
+		return errWaitShutdown
+	}
+}
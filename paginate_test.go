@@ -0,0 +1,80 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// paginate starts SendPaginated in the background and returns its result.
+func paginate(nf *notify.Notifier, n *notify.Notification, pages []string, interval time.Duration) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- nf.SendPaginated(context.Background(), n, pages, interval) }()
+	return done
+}
+
+func TestSendPaginated(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+	events := nf.Events()
+
+	n := notify.New("test", "trace", "", "", 0, notify.NormalUrgency)
+	onAction := func(string) {}
+	n.OnAction = onAction
+	done := paginate(nf, n, []string{"one", "two", "three"}, time.Minute)
+
+	waitEvent(t, events, notify.EventSent)
+	if first := s.last(t); first.Body != "one" || len(first.Actions) != 2 || first.Actions[0] != notify.NextPageAction {
+		t.Errorf("first page sent as %+v", first)
+	}
+	waitFor(t, "page timer", func() bool { return clock.Timers() == 1 })
+	clock.Advance(time.Minute)
+	waitEvent(t, events, notify.EventSent)
+
+	s.emitAction(s.last(t).ID, notify.NextPageAction)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	sent := s.notifications()
+	if len(sent) != 3 {
+		t.Fatalf("sent %d pages, want 3", len(sent))
+	}
+	last := sent[2]
+	if last.Body != "three" || len(last.Actions) != 0 || last.ReplacesID != sent[0].ID {
+		t.Errorf("last page sent as %+v", last)
+	}
+	if !sameFunc(n.OnAction, onAction) || n.OnClose != nil {
+		t.Error("SendPaginated did not restore the callbacks of the notification")
+	}
+}
+
+func TestSendPaginatedDismissed(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	events := nf.Events()
+
+	closed := make(chan notify.CloseReason, 1)
+	n := notify.New("test", "trace", "", "", 0, notify.NormalUrgency)
+	n.OnClose = func(reason notify.CloseReason) { closed <- reason }
+	done := paginate(nf, n, []string{"one", "two"}, 0)
+
+	waitEvent(t, events, notify.EventSent)
+	s.emitClosed(s.last(t).ID, uint32(notify.ReasonDismissed))
+	if err := <-done; !errors.Is(err, notify.ErrDismissed) {
+		t.Errorf("SendPaginated() = %v, want ErrDismissed", err)
+	}
+	if reason := <-closed; reason != notify.ReasonDismissed {
+		t.Errorf("OnClose got %v", reason)
+	}
+	if got := s.received(); got != 1 {
+		t.Errorf("sent %d pages after the dismissal", got-1)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// Patch applies fn to n and sends n again, replacing the notification
+// shown with the rest of it unchanged. No other send or close of n runs on
+// nf between fn and the send, so fn sees n as it was last sent.
+//
+// Like Notify, Patch shows n anew if it was closed, and does not send it if
+// fn changed nothing and nf skips unchanged notifications, see
+// WithSkipUnchanged.
+func (nf *Notifier) Patch(n *Notification, fn func(n *Notification)) (SendResult, error) {
+	if fn == nil {
+		return SendResult{}, errors.New("notify: nil patch")
+	}
+	res, err := func() (SendResult, error) {
+		defer nf.lockNotification(n)()
+		fn(n)
+		return nf.notifyLocked(context.Background(), n, false)
+	}()
+	nf.reportError(n, err)
+	return res, err
+}
+
+// Patch calls Notifier.Patch on the default Notifier.
+func (n *Notification) Patch(fn func(n *Notification)) error {
+	_, err := Default().Patch(n, fn)
+	return err
+}
+
+// ReplaceBody replaces the body of n, sent with SendR or the default
+// Notifier, keeping its summary and everything else, see Patch.
+func (n *Notification) ReplaceBody(body string) error {
+	return n.Patch(func(n *Notification) { n.Body = body })
+}
+
+// ReplaceSummary replaces the summary of n, sent with SendR or the default
+// Notifier, keeping its body and everything else, see Patch.
+func (n *Notification) ReplaceSummary(summary string) error {
+	return n.Patch(func(n *Notification) { n.Summary = summary })
+}
+
+// ReplaceKeeping replaces the summary and body of n, sent with SendR or the
+// default Notifier, keeping its urgency and everything else, see Patch.
+func (n *Notification) ReplaceKeeping(summary, body string) error {
+	return n.Patch(func(n *Notification) { n.Summary, n.Body = summary, body })
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestReplaceBody(t *testing.T) {
+	s := newFakeServer(t)
+	n := notify.New("test", "Copying files", "0%", "edit-copy", 0, notify.LowUrgency)
+	n.AddAction("cancel", "Cancel")
+	n.SetHint("x-test-count", int32(3))
+	if _, err := n.SendR(); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.ReplaceBody("50%"); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.ReplaceSummary("Copying photos"); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := s.notifications()
+	if len(sent) != 3 {
+		t.Fatalf("sent %d notifications, want 3", len(sent))
+	}
+	first, body, summary := sent[0], sent[1], sent[2]
+	if body.ReplacesID != first.ID || summary.ReplacesID != first.ID {
+		t.Errorf("replaced %d and %d, want %d", body.ReplacesID, summary.ReplacesID, first.ID)
+	}
+	if body.Body != "50%" || summary.Summary != "Copying photos" || summary.Body != "50%" {
+		t.Errorf("sent %+v then %+v", body, summary)
+	}
+	// Everything else is sent as it was.
+	same := func(a, b sentNotification) bool {
+		a.ReplacesID, a.ID = 0, 0
+		b.ReplacesID, b.ID = 0, 0
+		return reflect.DeepEqual(a, b)
+	}
+	first.Body = "50%"
+	if !same(first, body) {
+		t.Errorf("ReplaceBody sent %+v after %+v", body, first)
+	}
+	body.Summary = "Copying photos"
+	if !same(body, summary) {
+		t.Errorf("ReplaceSummary sent %+v after %+v", summary, body)
+	}
+}
+
+func TestPatchUnchanged(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithSkipUnchanged(true))
+	n := notify.New("test", "Downloading", "10%", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	res, err := nf.Patch(n, func(n *notify.Notification) { n.Body = "10%" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Skipped || s.received() != 1 {
+		t.Errorf("Patch() = %+v, server received %d, want it skipped", res, s.received())
+	}
+	if _, err := nf.Patch(n, nil); err == nil {
+		t.Error("Patch accepted a nil function")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+// Pause holds back the notifications of nf until Resume is called, like a
+// do not disturb mode of the application. Critical notifications are still
+// sent right away.
+//
+// Held back notifications are not sent: Notify returns a SendResult with
+// Deferred set, and emits an EventDeferred. This includes the notifications
+// sent by SendAsync and SendAt, but not the reposts of the notifications
+// that are already shown.
+func (nf *Notifier) Pause() {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	nf.stats.Paused = true
+}
+
+// Resume stops holding back notifications. If flush is true, the held back
+// notifications are sent in order, only the last one of those with the same
+// Tag, or the same content if they have no tag; the result is the join of
+// the errors of the sends. Otherwise they are discarded. See ResumeWith for
+// a single catch-up notification instead.
+func (nf *Notifier) Resume(flush bool) error {
+	if flush {
+		return nf.ResumeWith(ResumeFlushAll)
+	}
+	return nf.ResumeWith(ResumeDrop)
+}
+
+// deferSend holds back n if nf is paused, and returns true if it did. The
+// send uses a copy of n taken now.
+func (nf *Notifier) deferSend(n *Notification) bool {
+	if n.Urgency == CriticalUrgency {
+		return false
+	}
+	nf.mu.Lock()
+	if !nf.stats.Paused {
+		nf.mu.Unlock()
+		return false
+	}
+	cp := *n
+	cp.cache = sendCache{}
+	nf.deferred = append(nf.deferred, &cp)
+	nf.stats.Buffered = len(nf.deferred)
+	nf.stats.Deferred++
+	nf.mu.Unlock()
+
+	nf.emit(Event{Kind: EventDeferred, Tag: n.Tag, CorrelationID: n.CorrelationID})
+	return true
+}
+
+// collapse returns the notifications of ns to send on Resume: for each tag,
+// or each content if there is no tag, the last one, in the place of the
+// first one.
+func collapse(nf *Notifier, ns []*Notification) []*Notification {
+	index := make(map[string]int)
+	var out []*Notification
+	for _, n := range ns {
+		key := "content:" + nf.contentHash(n)
+		if n.Tag != "" {
+			key = "tag:" + n.Tag
+		}
+		if i, ok := index[key]; ok {
+			out[i] = n
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, n)
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestPauseResumeFlush(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	events := nf.Events()
+
+	nf.Pause()
+	for _, summary := range []string{"build 1", "mail", "build 2"} {
+		n := notify.New("test", summary, "", "", 0, notify.NormalUrgency)
+		if summary != "mail" {
+			n.Tag = "build"
+		}
+		res, err := nf.Notify(n)
+		if err != nil {
+			t.Fatal(err)
+		} else if !res.Deferred || n.Id != 0 {
+			t.Errorf("Notify(%q) = %+v while paused", summary, res)
+		}
+		waitEvent(t, events, notify.EventDeferred)
+	}
+	dup := notify.New("test", "mail", "", "", 0, notify.NormalUrgency)
+	nf.Notify(dup)
+	urgent := notify.New("test", "urgent", "", "", 0, notify.CriticalUrgency)
+	if res, err := nf.Notify(urgent); err != nil || res.Deferred {
+		t.Errorf("critical Notify = %+v, %v while paused", res, err)
+	}
+	if got := s.received(); got != 1 {
+		t.Fatalf("server received %d notifications while paused, want only the critical one", got)
+	}
+	if st := nf.Stats(); !st.Paused || st.Buffered != 4 || st.Deferred != 4 {
+		t.Errorf("Stats() = %+v while paused", st)
+	}
+
+	if err := nf.Resume(true); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range s.notifications()[1:] {
+		got = append(got, n.Summary)
+	}
+	if len(got) != 2 || got[0] != "build 2" || got[1] != "mail" {
+		t.Errorf("flushed %q, want [build 2 mail]", got)
+	}
+	if st := nf.Stats(); st.Paused || st.Buffered != 0 {
+		t.Errorf("Stats() = %+v after Resume", st)
+	}
+}
+
+func TestPauseResumeDiscard(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	nf.Pause()
+	if err := <-nf.SendAsync(notify.New("test", "async", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if err := nf.Resume(false); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.received(); got != 0 {
+		t.Errorf("server received %d notifications, want the held back one discarded", got)
+	}
+
+	if _, err := nf.Notify(notify.New("test", "after", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.received(); got != 1 {
+		t.Errorf("server received %d notifications after Resume, want 1", got)
+	}
+}
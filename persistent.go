@@ -0,0 +1,151 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AckAction is the key of the action added to persistent notifications on
+// daemons without the "persistence" capability. Invoking it acknowledges the
+// notification, which stops it from being posted again.
+const AckAction = "ack"
+
+// DefaultRepostInterval is how often persistent notifications are posted
+// again by default, see WithRepostInterval.
+const DefaultRepostInterval = time.Minute
+
+// repost is a persistent notification being posted again until it is
+// acknowledged.
+type repost struct {
+	// n is a copy of the notification, private to the repost loop.
+	n    *Notification
+	stop chan struct{}
+	// owner is the unique bus name of the daemon the notification was first
+	// sent to.
+	owner string
+}
+
+// WithRepostInterval sets how often persistent notifications are posted again
+// on daemons without the "persistence" capability.
+func WithRepostInterval(d time.Duration) Option {
+	return func(nf *Notifier) error {
+		if d <= 0 {
+			return fmt.Errorf("notify: invalid repost interval %v", d)
+		}
+		nf.repostInterval = d
+		return nil
+	}
+}
+
+// needsRepost returns true if n is persistent, but the daemon does not keep
+// notifications around by itself.
+func (nf *Notifier) needsRepost(n *Notification) bool {
+	if !nf.onBus() || nf.quirks != nil && nf.quirks.Persistence {
+		return false
+	}
+	if !n.Persistent {
+		return false
+	}
+	f, err := nf.features()
+	return err != nil || !f.Persistence
+}
+
+// startRepost starts posting n again until it is acknowledged, replacing any
+// previous repost loop for its ID.
+func (nf *Notifier) startRepost(n *Notification) {
+	r := &repost{stop: make(chan struct{}), owner: nf.daemonOwner()}
+	cp := *n
+	r.n = &cp
+
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if old, ok := nf.reposts[n.Id]; ok {
+		close(old.stop)
+	}
+	if nf.reposts == nil {
+		nf.reposts = make(map[uint32]*repost)
+	}
+	nf.reposts[n.Id] = r
+	go nf.repostLoop(r, nf.repostInterval)
+}
+
+// stopRepost stops posting the notification id again.
+func (nf *Notifier) stopRepost(id uint32) {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if r, ok := nf.reposts[id]; ok {
+		close(r.stop)
+		delete(nf.reposts, id)
+	}
+}
+
+// stopReposts stops all the repost loops. It must be called with nf.mu held.
+func (nf *Notifier) stopReposts() {
+	for id, r := range nf.reposts {
+		close(r.stop)
+		delete(nf.reposts, id)
+	}
+}
+
+func (nf *Notifier) repostLoop(r *repost, interval time.Duration) {
+	t := nf.clock.NewTimer(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C():
+		}
+		t.Reset(interval)
+
+		id := r.n.Id
+		if owner := nf.daemonOwner(); owner != r.owner {
+			nf.log(LevelInfo, fmt.Sprintf("daemon changed, no longer posting notification %d again", id), nil)
+			nf.stopRepost(id)
+			return
+		}
+		if _, err := nf.deliver(context.Background(), r.n, true); err != nil {
+			nf.log(LevelWarn, fmt.Sprintf("posting notification %d again failed", id), err)
+			nf.reportError(r.n, err)
+			continue
+		}
+
+		if r.n.Id != id {
+			nf.mu.Lock()
+			if nf.reposts[id] == r {
+				delete(nf.reposts, id)
+				nf.reposts[r.n.Id] = r
+			}
+			nf.mu.Unlock()
+		}
+	}
+}
+
+// daemonOwner returns the unique bus name currently owning the destination
+// of nf, or the empty string if it cannot be found.
+func (nf *Notifier) daemonOwner() string {
+	conn, err := nf.connection()
+	if err != nil {
+		return ""
+	}
+	var owner string
+	if conn.BusObject().Call("org.freedesktop.DBus.GetNameOwner", 0, nf.destination).Store(&owner) != nil {
+		return ""
+	}
+	return owner
+}
+
+// hasAction returns true if actions contain an action with key.
+func hasAction(actions []Action, key string) bool {
+	for _, a := range actions {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
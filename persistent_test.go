@@ -0,0 +1,121 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/notifytest"
+)
+
+const repostInterval = time.Minute
+
+// newRepostNotifier returns a Notifier posting persistent notifications
+// again every repostInterval of clock.
+func newRepostNotifier(t *testing.T, clock *notifytest.Clock) *notify.Notifier {
+	return newTestNotifier(t, notify.WithClock(clock), notify.WithRepostInterval(repostInterval))
+}
+
+// repost advances clock to the next repost, once the repost loop waits for
+// it.
+func repost(t *testing.T, clock *notifytest.Clock) {
+	t.Helper()
+	waitFor(t, "repost timer", func() bool { return clock.Timers() == 1 })
+	clock.Advance(repostInterval)
+}
+
+func TestPersistentRepost(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newRepostNotifier(t, clock)
+
+	n := notify.New("test", "see me", "", "", time.Second, notify.NormalUrgency)
+	n.Persistent = true
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	first := s.last(t)
+	if len(first.Actions) != 2 || first.Actions[0] != notify.AckAction {
+		t.Errorf("persistent notification sent with actions %q", first.Actions)
+	}
+
+	for i := 2; i <= 3; i++ {
+		repost(t, clock)
+		waitFor(t, "repost", func() bool { return len(s.notifications()) == i })
+	}
+	for _, sn := range s.notifications()[1:] {
+		if sn.ReplacesID != n.Id {
+			t.Errorf("repost replaces %d, want %d", sn.ReplacesID, n.Id)
+		}
+	}
+
+	s.emitAction(n.Id, notify.AckAction)
+	waitFor(t, "repost loop to stop", func() bool { return clock.Timers() == 0 })
+	clock.Advance(repostInterval)
+	if got := len(s.notifications()); got != 3 {
+		t.Errorf("still posting after acknowledgement: %d notifications", got)
+	}
+}
+
+func TestPersistentCloseStopsRepost(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newRepostNotifier(t, clock)
+
+	n := notify.New("test", "see me", "", "", time.Second, notify.NormalUrgency)
+	n.Persistent = true
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if err := nf.CloseNotification(n.Id); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "repost loop to stop", func() bool { return clock.Timers() == 0 })
+	clock.Advance(repostInterval)
+	if got := len(s.notifications()); got != 1 {
+		t.Errorf("posted %d times after Close, want once", got)
+	}
+}
+
+func TestPersistentWithDaemonPersistence(t *testing.T) {
+	s := newFakeServer(t)
+	s.setCapabilities("body", "actions", "persistence")
+	clock := newFakeClock()
+	nf := newRepostNotifier(t, clock)
+
+	n := notify.New("test", "see me", "", "", time.Second, notify.NormalUrgency)
+	n.Persistent = true
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if clock.Timers() != 0 {
+		t.Error("repost timer started for a daemon with persistence")
+	}
+	if sent := s.notifications(); len(sent) != 1 || len(sent[0].Actions) != 0 {
+		t.Errorf("sent %+v to a daemon with persistence", sent)
+	}
+}
+
+func TestPersistentStopsOnDaemonChange(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithRepostInterval(repostInterval), notify.WithLogger(logs.log))
+
+	n := notify.New("test", "see me", "", "", time.Second, notify.NormalUrgency)
+	n.Persistent = true
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	s.conn.Close()
+	restarted := newFakeServer(t)
+	repost(t, clock)
+	waitFor(t, "repost loop to stop", func() bool { return logs.logged("no longer posting") })
+	if got := len(restarted.notifications()); got != 0 {
+		t.Errorf("posted %d times to the new daemon", got)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	portalDestination = "org.freedesktop.portal.Desktop"
+	portalObjectPath  = "/org/freedesktop/portal/desktop"
+	portalInterface   = "org.freedesktop.portal.Notification"
+)
+
+// PortalTransport delivers calls to the notification portal of
+// xdg-desktop-portal, for sandboxed applications that cannot reach the
+// notification daemon. The portal IDs are strings chosen by the caller, so
+// it is a StringIDTransport: a Notifier sends the Tag of a notification as
+// its portal ID. The calls without a notification get IDs assigned by the
+// transport, sent as "notify-ID". The actions of notifications are sent as
+// buttons, but the portal does not report them to this transport.
+type PortalTransport struct {
+	// Conn is the connection to the session bus, the shared one if nil.
+	Conn *dbus.Conn
+
+	lastID atomic.Uint32
+}
+
+func (t *PortalTransport) connection() (*dbus.Conn, error) {
+	if t.Conn != nil {
+		return t.Conn, nil
+	}
+	return dbus.SessionBus()
+}
+
+func (t *PortalTransport) Notify(ctx context.Context, c Call) (uint32, error) {
+	id := c.ReplacesID
+	if id == 0 {
+		id = t.lastID.Add(1)
+	}
+	if err := t.NotifyID(ctx, portalID(id), c); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (t *PortalTransport) CloseNotification(id uint32) error {
+	return t.CloseID(portalID(id))
+}
+
+// NotifyID adds the notification id to the portal, replacing the one with
+// the same id.
+func (t *PortalTransport) NotifyID(ctx context.Context, id string, c Call) error {
+	conn, err := t.connection()
+	if err != nil {
+		return err
+	}
+	obj := conn.Object(portalDestination, portalObjectPath)
+	if err := obj.CallWithContext(ctx, portalInterface+".AddNotification", 0, id, portalNotification(c)).Err; err != nil {
+		return fmt.Errorf("notify: portal: %w", err)
+	}
+	return nil
+}
+
+// CloseID removes the notification id from the portal.
+func (t *PortalTransport) CloseID(id string) error {
+	conn, err := t.connection()
+	if err != nil {
+		return err
+	}
+	obj := conn.Object(portalDestination, portalObjectPath)
+	return obj.Call(portalInterface+".RemoveNotification", 0, id).Err
+}
+
+// portalID returns the portal ID of the notification id.
+func portalID(id uint32) string {
+	return "notify-" + strconv.FormatUint(uint64(id), 10)
+}
+
+// portalNotification returns the notification of the portal for c.
+func portalNotification(c Call) map[string]dbus.Variant {
+	priority := "normal"
+	switch c.Urgency {
+	case LowUrgency:
+		priority = "low"
+	case CriticalUrgency:
+		priority = "urgent"
+	}
+	n := map[string]dbus.Variant{
+		"title":    dbus.MakeVariant(c.Summary),
+		"body":     dbus.MakeVariant(c.Body),
+		"priority": dbus.MakeVariant(priority),
+	}
+	var buttons []map[string]dbus.Variant
+	for i := 0; i+1 < len(c.Actions); i += 2 {
+		if c.Actions[i] == DefaultAction {
+			n["default-action"] = dbus.MakeVariant(DefaultAction)
+			continue
+		}
+		buttons = append(buttons, map[string]dbus.Variant{
+			"label":  dbus.MakeVariant(c.Actions[i+1]),
+			"action": dbus.MakeVariant(c.Actions[i]),
+		})
+	}
+	if len(buttons) > 0 {
+		n["buttons"] = dbus.MakeVariant(buttons)
+	}
+	return n
+}
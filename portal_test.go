@@ -0,0 +1,253 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/godbus/dbus/v5"
+)
+
+// fakePortal is exported on the private bus as the notification portal.
+type fakePortal struct {
+	mu      sync.Mutex
+	added   []portalAdd
+	removed []string
+}
+
+type portalAdd struct {
+	id    string
+	title string
+}
+
+func (p *fakePortal) AddNotification(id string, n map[string]dbus.Variant) *dbus.Error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	title, _ := n["title"].Value().(string)
+	p.added = append(p.added, portalAdd{id, title})
+	return nil
+}
+
+func (p *fakePortal) RemoveNotification(id string) *dbus.Error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removed = append(p.removed, id)
+	return nil
+}
+
+func (p *fakePortal) last(t *testing.T) portalAdd {
+	t.Helper()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.added) == 0 {
+		t.Fatal("the portal received no notification")
+	}
+	return p.added[len(p.added)-1]
+}
+
+// newPortalNotifier starts a fake portal on the private bus, and returns it
+// with a Notifier sending to it.
+func newPortalNotifier(t *testing.T, opts ...notify.Option) (*fakePortal, *notify.Notifier) {
+	t.Helper()
+	p, tr := newPortal(t)
+	nf, err := notify.NewNotifier(append([]notify.Option{notify.WithTransport(tr)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nf.Close() })
+	return p, nf
+}
+
+// newPortal starts a fake portal on the private bus, and returns it with a
+// transport sending to it.
+func newPortal(t *testing.T) (*fakePortal, *notify.PortalTransport) {
+	t.Helper()
+	requireBus(t)
+	var conns [2]*dbus.Conn
+	for i := range conns {
+		conn, err := dbus.Connect(busAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conns[i] = conn
+	}
+	p := new(fakePortal)
+	if err := conns[0].Export(p, "/org/freedesktop/portal/desktop", "org.freedesktop.portal.Notification"); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := conns[0].RequestName("org.freedesktop.portal.Desktop", dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatal(err)
+	} else if reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatal("the fake portal could not own its name")
+	}
+	return p, &notify.PortalTransport{Conn: conns[1]}
+}
+
+func fnvHash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+func TestPortalTagIsID(t *testing.T) {
+	p, nf := newPortalNotifier(t)
+	n := notify.New("ci", "Build started", "", "", 0, notify.NormalUrgency)
+	n.Tag = "build"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.last(t); got.id != "build" || got.title != "Build started" {
+		t.Errorf("portal received %+v", got)
+	}
+	if n.Id != fnvHash("build") {
+		t.Errorf("Id = %d, want the hash %d of the tag", n.Id, fnvHash("build"))
+	}
+
+	// Another notification with the tag reuses the portal ID.
+	again := notify.New("ci", "Build done", "", "", 0, notify.NormalUrgency)
+	again.Tag = "build"
+	res, err := nf.Notify(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.last(t); got.id != "build" || got.title != "Build done" {
+		t.Errorf("portal received %+v", got)
+	}
+	if again.Id != n.Id || !res.Replaced || !res.ReusedID {
+		t.Errorf("Id = %d, result %+v, want the Id %d reused", again.Id, res, n.Id)
+	}
+}
+
+func TestPortalReplaceAndClose(t *testing.T) {
+	p, nf := newPortalNotifier(t)
+	n := notify.New("backup", "Backing up", "", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	first := p.last(t)
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(first.id) {
+		t.Errorf("portal ID %q of a notification without a tag is not a UUID", first.id)
+	}
+	if n.Id != fnvHash(first.id) {
+		t.Errorf("Id = %d, want the hash of %q", n.Id, first.id)
+	}
+
+	n.Summary = "Backed up"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.last(t); got.id != first.id || got.title != "Backed up" {
+		t.Errorf("replacing sent %+v, want the ID %q", got, first.id)
+	}
+
+	other := notify.New("backup", "Other", "", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(other); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.last(t); got.id == first.id {
+		t.Errorf("another notification got the ID %q too", got.id)
+	}
+
+	if err := nf.CloseNotification(n.Id); err != nil {
+		t.Fatal(err)
+	}
+	tagged := notify.New("backup", "Tagged", "", "", 0, notify.NormalUrgency)
+	tagged.Tag = "status"
+	if _, err := nf.Notify(tagged); err != nil {
+		t.Fatal(err)
+	}
+	if err := nf.Dismiss(tagged); err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.removed) != 2 || p.removed[0] != first.id || p.removed[1] != "status" {
+		t.Errorf("portal removed %q, want %q and status", p.removed, first.id)
+	}
+}
+
+func TestPortalMirrored(t *testing.T) {
+	var mirror recordingTransport
+	p, nf := newPortalNotifier(t, notify.WithMirrors(&mirror))
+	n := notify.New("ci", "Build started", "", "", 0, notify.NormalUrgency)
+	n.Tag = "build"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.last(t); got.id != "build" || n.Id != fnvHash("build") {
+		t.Errorf("portal received %+v, Id %d, want the tag as the ID", got, n.Id)
+	}
+	if err := nf.CloseNotification(n.Id); err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	removed := p.removed
+	p.mu.Unlock()
+	if len(removed) != 1 || removed[0] != "build" {
+		t.Errorf("portal removed %q", removed)
+	}
+	if len(mirror.calls) != 1 || len(mirror.closed) != 1 || mirror.closed[0] != n.Id {
+		t.Errorf("mirror got %+v, closed %v", mirror.calls, mirror.closed)
+	}
+}
+
+// downPortal is a StringIDTransport failing every call.
+type downPortal struct{ failingTransport }
+
+func (t downPortal) NotifyID(ctx context.Context, id string, c notify.Call) error { return t.err }
+func (t downPortal) CloseID(id string) error                                      { return t.err }
+
+func TestPortalFallback(t *testing.T) {
+	p, portal := newPortal(t)
+	var logged recordingTransport
+	nf, err := notify.NewNotifier(notify.WithTransport(notify.NewFallbackTransport(portal, &logged)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	n := notify.New("ci", "Build started", "", "", 0, notify.NormalUrgency)
+	n.Tag = "build"
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.last(t); got.id != "build" || n.Id != fnvHash("build") {
+		t.Errorf("portal received %+v, Id %d, want the tag as the ID", got, n.Id)
+	}
+	if err := nf.CloseNotification(n.Id); err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	removed := p.removed
+	p.mu.Unlock()
+	if len(removed) != 1 || removed[0] != "build" || len(logged.calls) != 0 {
+		t.Errorf("portal removed %q, the log got %+v", removed, logged.calls)
+	}
+
+	// Without a portal, the next transport gets the notification as one of
+	// its own.
+	nf, err = notify.NewNotifier(notify.WithTransport(notify.NewFallbackTransport(downPortal{failingTransport{errors.New("down")}}, &logged)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if len(logged.calls) != 1 || logged.calls[0].ReplacesID != 0 {
+		t.Fatalf("the log got %+v, want a new notification", logged.calls)
+	}
+	if err := nf.CloseNotification(n.Id); err != nil || len(logged.closed) != 1 || logged.closed[0] != 1 {
+		t.Errorf("close: %v, the log closed %v, want its own ID", err, logged.closed)
+	}
+}
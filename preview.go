@@ -0,0 +1,150 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Preview is what sending a notification would do, see Notifier.Preview.
+type Preview struct {
+	// Call is the call that would be made, after the notification was
+	// localized, redacted, sanitized and adapted to the daemon.
+	Call Call
+	// Warnings are the ways the notification the user sees would differ
+	// from it.
+	Warnings []PreviewWarning
+	// WireSize is an estimate of the size in bytes of the D-Bus message of
+	// Call, to compare with the limits of the bus.
+	WireSize int
+}
+
+// PreviewWarning is a warning of a Preview: a TruncatedBody,
+// StrippedMarkup, DroppedActions, DroppedHints or EscapedCharacters.
+type PreviewWarning interface {
+	String() string
+	previewWarning()
+}
+
+// TruncatedBody warns that the daemon shows the first To of the From
+// characters of the body, see Quirks.MaxBodyLength.
+type TruncatedBody struct {
+	From, To int
+}
+
+// StrippedMarkup warns that the markup of the body is not shown: the daemon
+// does not have the "body-markup" capability, or the images were replaced
+// by their alternative text as it does not have the "body-images" one.
+type StrippedMarkup struct{}
+
+// DroppedActions warns that the actions Keys are not shown: the daemon does
+// not have the "actions" capability, or shows fewer actions, see
+// Quirks.MaxActions.
+type DroppedActions struct {
+	Keys []string
+}
+
+// DroppedHints warns that the daemon ignores the hints Keys, as it does not
+// have their capability.
+type DroppedHints struct {
+	Keys []string
+}
+
+// EscapedCharacters warns that Count characters were removed or escaped
+// from the summary and body, see WithControlSanitizer.
+type EscapedCharacters struct {
+	Count int
+}
+
+func (w TruncatedBody) String() string {
+	return fmt.Sprintf("body truncated from %d to %d characters", w.From, w.To)
+}
+
+func (StrippedMarkup) String() string { return "body markup stripped" }
+
+func (w DroppedActions) String() string {
+	return "actions dropped: " + strings.Join(w.Keys, ", ")
+}
+
+func (w DroppedHints) String() string {
+	return "hints dropped: " + strings.Join(w.Keys, ", ")
+}
+
+func (w EscapedCharacters) String() string {
+	return fmt.Sprintf("%d characters removed or escaped", w.Count)
+}
+
+func (TruncatedBody) previewWarning()     {}
+func (StrippedMarkup) previewWarning()    {}
+func (DroppedActions) previewWarning()    {}
+func (DroppedHints) previewWarning()      {}
+func (EscapedCharacters) previewWarning() {}
+
+// Preview returns the call that nf would make to send n, like DryRun, and
+// what the user would not see of it, without sending anything. It is meant
+// for user interfaces checking notifications as they are written.
+//
+// Preview may still talk to the daemon to find out its capabilities and
+// quirks; if it cannot, or with a Transport other than D-Bus, only the
+// sanitizer and the quirks set with WithQuirks are taken into account.
+func (nf *Notifier) Preview(n *Notification) (Preview, error) {
+	c, err := nf.prepare(n)
+	if err != nil {
+		return Preview{}, err
+	}
+	p := Preview{Call: c, WireSize: wireSize(c)}
+	q := nf.daemonQuirks()
+	var f *Features
+	if nf.onBus() {
+		// Without the capabilities, only the other warnings are given.
+		f, _ = nf.features()
+	}
+
+	if length := utf8.RuneCountInString(c.Body); q.MaxBodyLength > 0 && length > q.MaxBodyLength {
+		p.Warnings = append(p.Warnings, TruncatedBody{From: length, To: q.MaxBodyLength})
+	}
+	if c.strippedImages || f != nil && !f.BodyMarkup && markupTag.MatchString(c.Body) {
+		p.Warnings = append(p.Warnings, StrippedMarkup{})
+	}
+
+	var actions []string
+	if f != nil && !f.Actions {
+		for i := 0; i+1 < len(c.Actions); i += 2 {
+			actions = append(actions, c.Actions[i])
+		}
+	} else {
+		actions = hiddenButtons(c.Actions, q.MaxActions)
+	}
+	if len(actions) > 0 {
+		p.Warnings = append(p.Warnings, DroppedActions{Keys: actions})
+	}
+
+	var hints []string
+	if f != nil {
+		if _, ok := c.Hints["action-icons"]; ok && !f.ActionIcons {
+			hints = append(hints, "action-icons")
+		}
+		// The sound hints are played by the sound fallback, if any.
+		if callSound(c) != "" && !f.Sound && nf.soundPlayer == nil {
+			for _, key := range []string{"sound-file", "sound-name"} {
+				if _, ok := c.Hints[key]; ok {
+					hints = append(hints, key)
+				}
+			}
+		}
+	}
+	if len(hints) > 0 {
+		sort.Strings(hints)
+		p.Warnings = append(p.Warnings, DroppedHints{Keys: hints})
+	}
+
+	if len(c.Sanitized) > 0 {
+		p.Warnings = append(p.Warnings, EscapedCharacters{Count: len(c.Sanitized)})
+	}
+	return p, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestPreview(t *testing.T) {
+	s := newFakeServer(t)
+	s.setCapabilities("body")
+	nf := newTestNotifier(t, notify.WithQuirks(notify.Quirks{MaxBodyLength: 10}), notify.WithControlSanitizer(notify.EscapeControls))
+
+	scenarios := []struct {
+		name string
+		n    func() *notify.Notification
+		want notify.PreviewWarning
+	}{
+		{"truncated body", func() *notify.Notification {
+			return notify.New("", "long", "twenty characters!!!", "", 0, notify.NormalUrgency)
+		}, notify.TruncatedBody{From: 20, To: 10}},
+		{"markup", func() *notify.Notification {
+			return notify.New("", "markup", "<b>hi</b>", "", 0, notify.NormalUrgency)
+		}, notify.StrippedMarkup{}},
+		{"image", func() *notify.Notification {
+			return notify.New("", "image", `<img src="a.png" alt="a"/>`, "", 0, notify.NormalUrgency)
+		}, notify.StrippedMarkup{}},
+		{"actions", func() *notify.Notification {
+			n := notify.New("", "actions", "", "", 0, notify.NormalUrgency)
+			n.AddAction(notify.DefaultAction, "Open")
+			n.AddAction("reply", "Reply")
+			return n
+		}, notify.DroppedActions{Keys: []string{notify.DefaultAction, "reply"}}},
+		{"hints", func() *notify.Notification {
+			n := notify.New("", "hints", "", "", 0, notify.NormalUrgency)
+			n.AddHints(notify.ActionIconsHint(true), notify.SoundNameHint("message-new-instant"))
+			return n
+		}, notify.DroppedHints{Keys: []string{"action-icons", "sound-name"}}},
+		{"escaped", func() *notify.Notification {
+			return notify.New("", "invoice_\u202egpj.exe", "", "", 0, notify.NormalUrgency)
+		}, notify.EscapedCharacters{Count: 1}},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			n := sc.n()
+			p, err := nf.Preview(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []notify.PreviewWarning{sc.want}; !reflect.DeepEqual(p.Warnings, want) {
+				t.Errorf("Preview() warnings = %v, want %v", p.Warnings, want)
+			}
+			c, err := n.DryRun(nf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Summary != p.Call.Summary || c.Body != p.Call.Body {
+				t.Errorf("Preview() call = %+v, want the call of DryRun %+v", p.Call, c)
+			}
+		})
+	}
+	if sent := s.notifications(); len(sent) != 0 {
+		t.Errorf("Preview sent %d notifications", len(sent))
+	}
+}
+
+func TestPreviewMaxActions(t *testing.T) {
+	newFakeServer(t)
+	n := notify.New("", "actions", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	n.AddAction("reply", "Reply")
+	n.AddAction("archive", "Archive")
+	n.AddAction("delete", "Delete")
+
+	nf := newTestNotifier(t, notify.WithQuirks(notify.Quirks{MaxActions: 2}))
+	p, err := nf.Preview(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []notify.PreviewWarning{notify.DroppedActions{Keys: []string{"delete"}}}; !reflect.DeepEqual(p.Warnings, want) {
+		t.Errorf("Preview() warnings = %v, want %v", p.Warnings, want)
+	}
+
+	// The pages show all the actions.
+	paged := newTestNotifier(t, notify.WithQuirks(notify.Quirks{MaxActions: 2}), notify.WithActionOverflow(notify.ActionOverflowPaginate))
+	if p, err := paged.Preview(n); err != nil {
+		t.Fatal(err)
+	} else if len(p.Warnings) != 0 {
+		t.Errorf("Preview() with pages warnings = %v, want none", p.Warnings)
+	}
+}
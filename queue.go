@@ -0,0 +1,93 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultQueueCapacity is the default number of asynchronous operations
+// that may be pending, see WithQueue.
+const DefaultQueueCapacity = 1024
+
+// QueuePolicy is what happens to an asynchronous operation queued while the
+// queue is full, see WithQueue.
+type QueuePolicy int
+
+const (
+	// QueueDropOldest drops the oldest pending operation to make room, so
+	// that the newest information wins. It is the default.
+	QueueDropOldest QueuePolicy = iota
+	// QueueDropNewest drops the new operation.
+	QueueDropNewest
+	// QueueBlock waits for room in the queue.
+	QueueBlock
+	// QueueError fails the new operation with ErrQueueFull.
+	QueueError
+)
+
+var (
+	// ErrDropped is the result of asynchronous operations dropped because
+	// the queue was full.
+	ErrDropped = errors.New("notify: dropped from the full queue")
+	// ErrQueueFull is the result of asynchronous operations queued while
+	// the queue is full, with QueueError.
+	ErrQueueFull = errors.New("notify: queue full")
+)
+
+// WithQueue sets how many asynchronous operations may be queued, including
+// the ones being executed, and what to do with new ones when the queue is
+// full. QueueDropOldest can only drop operations that did not start: if
+// there are none, the new operation is dropped. Dropped operations are
+// reported with EventDropped events and in the Stats.
+func WithQueue(capacity int, policy QueuePolicy) Option {
+	return func(nf *Notifier) error {
+		if capacity < 1 {
+			return fmt.Errorf("notify: invalid queue capacity %d", capacity)
+		}
+		if policy < QueueDropOldest || policy > QueueError {
+			return fmt.Errorf("notify: invalid queue policy %d", policy)
+		}
+		nf.queueCap, nf.queuePolicy = capacity, policy
+		return nil
+	}
+}
+
+// dropOldest removes the oldest operation that did not start and returns
+// it, or returns nil if there is none. It must be called with nf.mu held.
+func (nf *Notifier) dropOldest() *asyncOp {
+	var oldest *lane
+	for _, l := range nf.lanes {
+		if len(l.ops) > 0 && (oldest == nil || l.ops[0].seq < oldest.ops[0].seq) {
+			oldest = l
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+	op := oldest.ops[0]
+	oldest.ops = oldest.ops[1:]
+	nf.queued--
+	nf.stats.Dropped++
+	return op
+}
+
+// dequeued tells the operations waiting for room in the queue that one was
+// completed. It must be called with nf.mu held.
+func (nf *Notifier) dequeued() {
+	if nf.queueFreed != nil {
+		close(nf.queueFreed)
+		nf.queueFreed = nil
+	}
+}
+
+// dropped completes op, dropped from the queue.
+func (nf *Notifier) dropped(op *asyncOp) {
+	e := Event{Kind: EventDropped, Tag: op.n.Tag, CorrelationID: op.n.CorrelationID, Err: ErrDropped}
+	op.done <- ErrDropped
+	close(op.done)
+	nf.emit(e)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// fillQueue stalls s and fills the queue of nf, of capacity 2, with a send
+// being executed and a pending one, both with the same tag.
+func fillQueue(t *testing.T, s *fakeServer, nf *notify.Notifier) (release func(), running, pending <-chan error) {
+	t.Helper()
+	release = s.stall(t)
+	a := notify.New("test", "a", "", "", 0, notify.NormalUrgency)
+	a.Tag = "queue"
+	running = nf.SendAsync(a)
+	waitFor(t, "first send to reach the server", func() bool { return s.received() == 1 })
+	b := notify.New("test", "b", "", "", 0, notify.NormalUrgency)
+	b.Tag = "queue"
+	pending = nf.SendAsync(b)
+	if q := nf.Stats().Queued; q != 2 {
+		t.Fatalf("Stats().Queued = %d, want 2", q)
+	}
+	return release, running, pending
+}
+
+func queueNotification(summary string) *notify.Notification {
+	n := notify.New("test", summary, "", "", 0, notify.NormalUrgency)
+	n.Tag = "queue"
+	return n
+}
+
+func TestQueueDropOldest(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithQueue(2, notify.QueueDropOldest))
+	events := nf.Events()
+	release, running, pending := fillQueue(t, s, nf)
+
+	newest := nf.SendAsync(queueNotification("c"))
+	if err := <-pending; !errors.Is(err, notify.ErrDropped) {
+		t.Errorf("oldest pending send returned %v, want ErrDropped", err)
+	}
+	release()
+	for _, ch := range []<-chan error{running, newest} {
+		if err := <-ch; err != nil {
+			t.Error(err)
+		}
+	}
+	if got := s.last(t).Summary; got != "c" {
+		t.Errorf("last notification shown is %q, want the newest", got)
+	}
+	if st := nf.Stats(); st.Dropped != 1 || st.Queued != 0 {
+		t.Errorf("Stats() = %+v", st)
+	}
+	waitEvent(t, events, notify.EventDropped)
+}
+
+func TestQueueDropNewest(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithQueue(2, notify.QueueDropNewest))
+	events := nf.Events()
+	release, running, pending := fillQueue(t, s, nf)
+
+	if err := <-nf.SendAsync(queueNotification("c")); !errors.Is(err, notify.ErrDropped) {
+		t.Errorf("new send returned %v, want ErrDropped", err)
+	}
+	waitEvent(t, events, notify.EventDropped)
+	release()
+	for _, ch := range []<-chan error{running, pending} {
+		if err := <-ch; err != nil {
+			t.Error(err)
+		}
+	}
+	if got := s.last(t).Summary; got != "b" {
+		t.Errorf("last notification shown is %q", got)
+	}
+	if st := nf.Stats(); st.Dropped != 1 {
+		t.Errorf("Stats() = %+v", st)
+	}
+}
+
+func TestQueueError(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithQueue(2, notify.QueueError))
+	release, running, pending := fillQueue(t, s, nf)
+
+	if err := <-nf.SendAsync(queueNotification("c")); !errors.Is(err, notify.ErrQueueFull) {
+		t.Errorf("new send returned %v, want ErrQueueFull", err)
+	}
+	release()
+	<-running
+	<-pending
+	if st := nf.Stats(); st.Dropped != 0 {
+		t.Errorf("Stats() = %+v, want nothing dropped", st)
+	}
+}
+
+func TestQueueBlock(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithQueue(2, notify.QueueBlock))
+	release, running, pending := fillQueue(t, s, nf)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := <-nf.SendAsyncContext(ctx, queueNotification("timeout")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("blocked send returned %v, want DeadlineExceeded", err)
+	}
+
+	blocked := make(chan (<-chan error), 1)
+	go func() { blocked <- nf.SendAsync(queueNotification("c")) }()
+	select {
+	case <-blocked:
+		t.Fatal("SendAsync did not block on a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	for _, ch := range []<-chan error{running, pending, <-blocked} {
+		if err := <-ch; err != nil {
+			t.Error(err)
+		}
+	}
+	if got := s.last(t).Summary; got != "c" {
+		t.Errorf("last notification shown is %q", got)
+	}
+}
+
+// waitEvent waits for an event of the given kind on events.
+func waitEvent(t *testing.T, events <-chan notify.Event, kind notify.EventKind) notify.Event {
+	t.Helper()
+	for {
+		select {
+		case e := <-events:
+			if e.Kind == kind {
+				return e
+			}
+		case <-waitTimeout():
+			t.Fatalf("no %v event", kind)
+		}
+	}
+}
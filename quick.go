@@ -0,0 +1,39 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// quickTimeout bounds the whole exchange of QuickSend with the bus.
+const quickTimeout = 25 * time.Second
+
+// errQuickUnsupported is returned by quickSend when the bus cannot be
+// reached without the full D-Bus client: QuickSend falls back to it.
+var errQuickUnsupported = errors.New("notify: quick send not supported for this bus")
+
+// QuickSend sends summary and body as a notification with urgency, like
+// SendUrgentMsg, but over a short-lived connection of its own instead of
+// the shared one of the default Notifier.
+//
+// It is meant for tiny programs sending a single notification and exiting,
+// whose run time is dominated by setting up the D-Bus client: QuickSend
+// writes the authentication, the Hello and the Notify call at once,
+// reads the reply and disconnects, without any goroutines or signal
+// handling. It only knows the EXTERNAL authentication over unix sockets;
+// with other buses, it sends through the default Notifier.
+func QuickSend(summary, body string, urgency NotificationUrgency) error {
+	nf := Default()
+	c := implicitCall(nf, 0, summary, body, urgency)
+	err := quickSend(nf, os.Getenv("DBUS_SESSION_BUS_ADDRESS"), c)
+	if errors.Is(err, errQuickUnsupported) {
+		_, err = nf.send(context.Background(), c)
+	}
+	return err
+}
@@ -0,0 +1,13 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build !(linux || freebsd || netbsd || openbsd || dragonfly)
+
+package notify
+
+// quickSend is not supported on the platforms without D-Bus: QuickSend
+// goes through the default Notifier, which fails.
+func quickSend(nf *Notifier, addr string, c Call) error {
+	return errQuickUnsupported
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/godbus/dbus/v5"
+)
+
+func TestQuickSend(t *testing.T) {
+	s := newFakeServer(t)
+	if err := notify.QuickSend("Brightness", "70%", notify.CriticalUrgency); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if got.Summary != "Brightness" || got.Body != "70%" || got.ReplacesID != 0 || got.Member != "Notify" {
+		t.Errorf("QuickSend sent %+v", got)
+	}
+	if u := got.Hints["urgency"].Value(); u != byte(notify.CriticalUrgency) {
+		t.Errorf("urgency hint %v", u)
+	}
+	// The call is the same as the one of SendUrgentMsg, over another
+	// connection.
+	if _, err := notify.SendUrgentMsg("Brightness", "70%", notify.CriticalUrgency); err != nil {
+		t.Fatal(err)
+	}
+	sent := s.notifications()
+	if a, b := sent[0], sent[1]; a.AppName != b.AppName || a.ExpireTimeout != b.ExpireTimeout || a.Signature != b.Signature || a.Sender == b.Sender {
+		t.Errorf("QuickSend sent %+v, SendUrgentMsg %+v", a, b)
+	}
+}
+
+func TestQuickSendNoDaemon(t *testing.T) {
+	requireBus(t)
+	err := notify.QuickSend("nobody", "listens", notify.NormalUrgency)
+	var dbusErr dbus.Error
+	if !errors.Is(err, notify.ErrNoDaemon) || !errors.As(err, &dbusErr) {
+		t.Errorf("QuickSend() = %v without a daemon", err)
+	}
+}
+
+func TestQuickSendExtraReply(t *testing.T) {
+	s := newFakeServer(t)
+	s.setReplyShape(t, tokenReplyDaemon{fakeDaemon{s}})
+	if err := notify.QuickSend("Volume", "40%", notify.NormalUrgency); err != nil {
+		t.Errorf("QuickSend() = %v with a token after the ID", err)
+	}
+}
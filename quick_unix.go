@@ -0,0 +1,163 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build linux || freebsd || netbsd || openbsd || dragonfly
+
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// quickSend makes the Notify call c on the bus at addr over a connection
+// of its own, following the defaults of nf.
+func quickSend(nf *Notifier, addr string, c Call) error {
+	path, ok := quickSocket(addr)
+	if !ok {
+		return errQuickUnsupported
+	}
+	conn, err := net.DialTimeout("unix", path, quickTimeout)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(quickTimeout))
+
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := conn.Write([]byte("\x00AUTH EXTERNAL " + uid + "\r\n")); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	} else if !strings.HasPrefix(line, "OK ") {
+		// The bus wants another mechanism.
+		return errQuickUnsupported
+	}
+
+	// The bus handles the messages in order, so the Notify call does not
+	// wait for the reply to Hello.
+	var out bytes.Buffer
+	out.WriteString("BEGIN\r\n")
+	hello := &dbus.Message{
+		Type: dbus.TypeMethodCall,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldDestination: dbus.MakeVariant("org.freedesktop.DBus"),
+			dbus.FieldPath:        dbus.MakeVariant(dbus.ObjectPath("/org/freedesktop/DBus")),
+			dbus.FieldInterface:   dbus.MakeVariant("org.freedesktop.DBus"),
+			dbus.FieldMember:      dbus.MakeVariant("Hello"),
+		},
+	}
+	notify := notifyMessage(nf.destination, nf.path, nf.callFlags(), c)
+	for serial, msg := range []*dbus.Message{hello, notify} {
+		if err := encodeQuick(&out, msg, uint32(serial+1)); err != nil {
+			return fmt.Errorf("notify: %w", err)
+		}
+	}
+	if _, err := out.WriteTo(conn); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+
+	// Only the reply to Notify is decoded: the reply to Hello comes first,
+	// and the signals, such as NameAcquired, are skipped.
+	helloReplied := false
+	for {
+		skip, err := skipQuick(r, &helloReplied)
+		if err != nil {
+			return fmt.Errorf("notify: %w", err)
+		} else if skip {
+			continue
+		}
+		msg, err := dbus.DecodeMessage(r)
+		if err != nil {
+			return fmt.Errorf("notify: %w", err)
+		}
+		if serial, _ := msg.Headers[dbus.FieldReplySerial].Value().(uint32); serial != 2 {
+			continue
+		}
+		if msg.Type == dbus.TypeError {
+			name, _ := msg.Headers[dbus.FieldErrorName].Value().(string)
+			return rateLimited(noDaemon(dbus.Error{Name: name, Body: msg.Body}))
+		}
+		if _, _, ok := replyID(msg.Body); !ok {
+			return errUnrecognizedResponse
+		}
+		return nil
+	}
+}
+
+// skipQuick discards the next message of r if it is a signal, or the first
+// method reply, which answers Hello, and returns true if it did. Decoding
+// them would cost more than the rest of QuickSend.
+func skipQuick(r *bufio.Reader, helloReplied *bool) (bool, error) {
+	fixed, err := r.Peek(16)
+	if err != nil {
+		return false, err
+	}
+	switch typ := dbus.Type(fixed[1]); {
+	case typ == dbus.TypeSignal:
+	case typ == dbus.TypeMethodReply && !*helloReplied:
+		*helloReplied = true
+	default:
+		return false, nil
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if fixed[0] == 'B' {
+		order = binary.BigEndian
+	}
+	fields := int(order.Uint32(fixed[12:]))
+	size := 16 + (fields+7)&^7 + int(order.Uint32(fixed[4:]))
+	_, err = r.Discard(size)
+	return true, err
+}
+
+// encodeQuick appends msg to out with the given serial. The serial of a
+// dbus.Message is only set by a dbus.Conn, so it is written at its offset
+// in the fixed header.
+func encodeQuick(out *bytes.Buffer, msg *dbus.Message, serial uint32) error {
+	start := out.Len()
+	if err := msg.EncodeTo(out, binary.LittleEndian); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(out.Bytes()[start+8:], serial)
+	return nil
+}
+
+// quickSocket returns the path of the first unix socket of the bus address
+// addr, with a leading @ for abstract sockets.
+func quickSocket(addr string) (string, bool) {
+	for _, entry := range strings.Split(addr, ";") {
+		params, ok := strings.CutPrefix(entry, "unix:")
+		if !ok {
+			continue
+		}
+		for _, kv := range strings.Split(params, ",") {
+			k, v, _ := strings.Cut(kv, "=")
+			v, err := url.PathUnescape(v)
+			if err != nil {
+				continue
+			}
+			switch k {
+			case "path":
+				return v, true
+			case "abstract":
+				return "@" + v, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrQuietHours is the error of the EventDropped events of the
+// notifications dropped during quiet hours, see QuietHours.Drop.
+var ErrQuietHours = errors.New("notify: notification dropped during quiet hours")
+
+// QuietWindow is a daily period of quiet hours, from Start to End. Both are
+// times of day, as durations since midnight: {22 * time.Hour, 7 * time.Hour}
+// is from 22:00 to 07:00 the next day.
+type QuietWindow struct {
+	Start, End time.Duration
+}
+
+// QuietHours holds back notifications during some hours of the day, see
+// WithQuietHours.
+type QuietHours struct {
+	// Windows are the periods of quiet hours. They may overlap.
+	Windows []QuietWindow
+	// Location is the time zone of the windows, time.Local if nil. The
+	// windows follow the wall clock across daylight saving time changes.
+	Location *time.Location
+	// Allow are the urgencies of the notifications still sent during quiet
+	// hours. Critical notifications are always sent.
+	Allow []NotificationUrgency
+	// Classes, if not empty, limits the quiet hours to the notifications
+	// whose "category" hint is in one of these classes, see InClass: the
+	// others are still sent. QuietHours{Windows: w, Classes: []string{"im"}}
+	// holds back the instant messages but the critical ones.
+	Classes []string
+	// Drop drops the notifications held back. Otherwise they are sent at
+	// the end of the quiet hours, with SendAt.
+	Drop bool
+	// Collapse sends a single catch-up notification summarizing the
+	// notifications held back at the end of the quiet hours, instead of
+	// each of them, see ResumeCollapse. It is ignored with Drop.
+	Collapse bool
+}
+
+// WithQuietHours makes the Notifier hold back notifications during the
+// quiet hours q: Notify returns a SendResult with Quiet set, and the
+// notification is sent at the end of the quiet hours or dropped. Reposts of
+// the notifications already shown are not held back.
+func WithQuietHours(q QuietHours) Option {
+	return func(nf *Notifier) error {
+		if len(q.Windows) == 0 {
+			return errors.New("notify: quiet hours without windows")
+		}
+		for _, w := range q.Windows {
+			if w.Start < 0 || w.Start >= 24*time.Hour || w.End < 0 || w.End >= 24*time.Hour || w.Start == w.End {
+				return fmt.Errorf("notify: invalid quiet window %v-%v", w.Start, w.End)
+			}
+		}
+		q.Windows = append([]QuietWindow(nil), q.Windows...)
+		q.Allow = append([]NotificationUrgency(nil), q.Allow...)
+		q.Classes = append([]string(nil), q.Classes...)
+		nf.quiet = &q
+		return nil
+	}
+}
+
+// InQuietHours returns true if nf is in quiet hours, and when they end.
+func (nf *Notifier) InQuietHours() (until time.Time, ok bool) {
+	if nf.quiet == nil {
+		return time.Time{}, false
+	}
+	return nf.quiet.until(nf.clock.Now())
+}
+
+// location returns the time zone of the windows of q.
+func (q *QuietHours) location() *time.Location {
+	if q.Location == nil {
+		return time.Local
+	}
+	return q.Location
+}
+
+// allows returns true if notifications of urgency u are sent during q.
+func (q *QuietHours) allows(u NotificationUrgency) bool {
+	if u == CriticalUrgency {
+		return true
+	}
+	for _, a := range q.Allow {
+		if a == u {
+			return true
+		}
+	}
+	return false
+}
+
+// covers returns true if the quiet hours of q apply to n, see Classes.
+func (q *QuietHours) covers(n *Notification) bool {
+	if len(q.Classes) == 0 {
+		return true
+	}
+	category := n.category()
+	for _, class := range q.Classes {
+		if InClass(category, class) {
+			return true
+		}
+	}
+	return false
+}
+
+// until returns true if now is in the quiet hours of q, and when they end,
+// following windows that start when the previous one ends.
+func (q *QuietHours) until(now time.Time) (time.Time, bool) {
+	loc := q.location()
+	var end time.Time
+	t := now
+	for i := 0; i <= len(q.Windows); i++ {
+		extended := false
+		y, m, d := t.In(loc).Date()
+		// A window covering t started the same day, or the day before if it
+		// spans midnight.
+		for day := d - 1; day <= d; day++ {
+			for _, w := range q.Windows {
+				start := wallClock(y, m, day, w.Start, loc)
+				stop := wallClock(y, m, day, w.End, loc)
+				if w.End <= w.Start {
+					stop = wallClock(y, m, day+1, w.End, loc)
+				}
+				if !t.Before(start) && t.Before(stop) && stop.After(end) {
+					end = stop
+					extended = true
+				}
+			}
+		}
+		if !extended {
+			break
+		}
+		t = end
+	}
+	return end, !end.IsZero()
+}
+
+// wallClock returns the time of day offset of the day y-m-d in loc. Times
+// skipped by daylight saving time changes are normalized by time.Date.
+func wallClock(y int, m time.Month, d int, offset time.Duration, loc *time.Location) time.Time {
+	h, mins, s := int(offset/time.Hour), int(offset/time.Minute%60), int(offset/time.Second%60)
+	return time.Date(y, m, d, h, mins, s, 0, loc)
+}
+
+// holdQuiet holds back n if nf is in quiet hours, and returns true if it
+// did.
+func (nf *Notifier) holdQuiet(n *Notification) (SendResult, bool, error) {
+	q := nf.quiet
+	if q == nil || q.allows(n.urgency()) || !q.covers(n) {
+		return SendResult{}, false, nil
+	}
+	until, ok := q.until(nf.clock.Now())
+	if !ok {
+		return SendResult{}, false, nil
+	}
+	if q.Drop {
+		nf.mu.Lock()
+		nf.stats.Quieted++
+		nf.mu.Unlock()
+		nf.emit(Event{Kind: EventDropped, Tag: n.Tag, CorrelationID: n.CorrelationID, Err: ErrQuietHours})
+		return SendResult{Quiet: true}, true, nil
+	}
+	if q.Collapse {
+		nf.holdCatchUp(n, until)
+	} else if _, err := nf.SendAt(until, n); err != nil {
+		return SendResult{}, true, err
+	}
+	nf.mu.Lock()
+	nf.stats.Quieted++
+	nf.mu.Unlock()
+	nf.emit(Event{Kind: EventDeferred, Tag: n.Tag, CorrelationID: n.CorrelationID})
+	return SendResult{Quiet: true}, true, nil
+}
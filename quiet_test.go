@@ -0,0 +1,145 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/notifytest"
+)
+
+func paris(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestQuietHoursWindows(t *testing.T) {
+	loc := paris(t)
+	q := notify.QuietHours{
+		Windows: []notify.QuietWindow{
+			{22 * time.Hour, 7 * time.Hour},
+			{7 * time.Hour, 7*time.Hour + 30*time.Minute},
+			{13 * time.Hour, 14 * time.Hour},
+		},
+		Location: loc,
+	}
+	tests := []struct {
+		now, until time.Time
+	}{
+		{time.Date(2013, 1, 1, 21, 59, 0, 0, loc), time.Time{}},
+		{time.Date(2013, 1, 1, 22, 0, 0, 0, loc), time.Date(2013, 1, 2, 7, 30, 0, 0, loc)},
+		{time.Date(2013, 1, 2, 3, 0, 0, 0, loc), time.Date(2013, 1, 2, 7, 30, 0, 0, loc)},
+		{time.Date(2013, 1, 2, 7, 30, 0, 0, loc), time.Time{}},
+		{time.Date(2013, 1, 2, 13, 15, 0, 0, loc), time.Date(2013, 1, 2, 14, 0, 0, 0, loc)},
+		// Daylight saving time starts at 02:00 on 2013-03-31 and ends at
+		// 03:00 on 2013-10-27: the windows still end at 07:30 wall time.
+		{time.Date(2013, 3, 30, 23, 0, 0, 0, loc), time.Date(2013, 3, 31, 7, 30, 0, 0, loc)},
+		{time.Date(2013, 10, 26, 23, 0, 0, 0, loc), time.Date(2013, 10, 27, 7, 30, 0, 0, loc)},
+		{time.Date(2013, 10, 26, 21, 0, 0, 0, time.UTC), time.Date(2013, 10, 27, 7, 30, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		nf, err := notify.NewNotifier(notify.WithQuietHours(q), notify.WithClock(notifytest.NewClock(tt.now)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		until, ok := nf.InQuietHours()
+		if ok != !tt.until.IsZero() || !until.Equal(tt.until) {
+			t.Errorf("at %v: InQuietHours() = %v, %v, want until %v", tt.now, until, ok, tt.until)
+		}
+		nf.Close()
+	}
+}
+
+func TestQuietHoursInvalid(t *testing.T) {
+	for _, q := range []notify.QuietHours{
+		{},
+		{Windows: []notify.QuietWindow{{time.Hour, time.Hour}}},
+		{Windows: []notify.QuietWindow{{22 * time.Hour, 24 * time.Hour}}},
+	} {
+		if _, err := notify.NewNotifier(notify.WithQuietHours(q)); err == nil {
+			t.Errorf("WithQuietHours(%+v) succeeded", q)
+		}
+	}
+}
+
+func TestQuietHoursDefer(t *testing.T) {
+	loc := paris(t)
+	s := newFakeServer(t)
+	clock := notifytest.NewClock(time.Date(2013, 1, 1, 23, 0, 0, 0, loc))
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithQuietHours(notify.QuietHours{
+		Windows:  []notify.QuietWindow{{22 * time.Hour, 7 * time.Hour}},
+		Location: loc,
+	}))
+	events := nf.Events()
+
+	res, err := nf.Notify(notify.New("test", "backup done", "", "", 0, notify.NormalUrgency))
+	if err != nil || !res.Quiet {
+		t.Fatalf("Notify() = %+v, %v, want it held back", res, err)
+	}
+	waitEvent(t, events, notify.EventDeferred)
+	if _, err := nf.Notify(notify.New("test", "disk failing", "", "", 0, notify.CriticalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	hinted := notify.New("test", "battery low", "", "", 0, notify.NormalUrgency)
+	hinted.AddHints(notify.UrgencyHint(notify.CriticalUrgency))
+	if res, err := nf.Notify(hinted); err != nil || res.Quiet {
+		t.Fatalf("Notify() with the urgency hint = %+v, %v, want it sent", res, err)
+	}
+	if got := summaries(s); len(got) != 2 || got[0] != "disk failing" || got[1] != "battery low" {
+		t.Fatalf("sent %q, want only the critical notifications", got)
+	}
+	morning := time.Date(2013, 1, 2, 7, 0, 0, 0, loc)
+	if p := nf.PendingScheduled(); len(p) != 1 || !p[0].At.Equal(morning) {
+		t.Errorf("pending %+v, want the notification scheduled at %v", p, morning)
+	}
+	if st := nf.Stats(); !st.Quiet || st.Quieted != 1 {
+		t.Errorf("Stats() = %+v", st)
+	}
+
+	clock.Advance(8 * time.Hour)
+	waitFor(t, "the deferred notification", func() bool { return len(summaries(s)) == 3 })
+	if got := summaries(s); got[2] != "backup done" {
+		t.Errorf("sent %q after the quiet hours", got)
+	}
+	if _, ok := nf.InQuietHours(); ok {
+		t.Error("still in quiet hours")
+	}
+}
+
+func TestQuietHoursDrop(t *testing.T) {
+	s := newFakeServer(t)
+	clock := notifytest.NewClock(time.Date(2013, 1, 1, 23, 0, 0, 0, time.UTC))
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithQuietHours(notify.QuietHours{
+		Windows:  []notify.QuietWindow{{22 * time.Hour, 7 * time.Hour}},
+		Location: time.UTC,
+		Allow:    []notify.NotificationUrgency{notify.NormalUrgency},
+		Drop:     true,
+	}))
+	events := nf.Events()
+
+	if res, err := nf.Notify(notify.New("test", "noise", "", "", 0, notify.LowUrgency)); err != nil || !res.Quiet {
+		t.Fatalf("Notify() = %+v, %v, want it dropped", res, err)
+	}
+	if e := waitEvent(t, events, notify.EventDropped); !errors.Is(e.Err, notify.ErrQuietHours) {
+		t.Errorf("EventDropped.Err = %v", e.Err)
+	}
+	if _, err := nf.Notify(notify.New("test", "allowed", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if p := nf.PendingScheduled(); len(p) != 0 {
+		t.Errorf("pending %+v, want the notification dropped", p)
+	}
+	if got := summaries(s); len(got) != 1 || got[0] != "allowed" {
+		t.Errorf("sent %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import "time"
+
+// Quirks describes how a notification daemon departs from the specification
+// or from what most daemons do. Its JSON encoding is the one of the files
+// read by LoadQuirks.
+type Quirks struct {
+	// Persistence is true if the daemon keeps notifications until they are
+	// dismissed, although it does not advertise the "persistence"
+	// capability. Persistent notifications are then not posted again.
+	Persistence bool `json:"persistence,omitempty"`
+	// StackTagHints are the hints carrying the Tag of notifications. If
+	// empty, both x-dunst-stack-tag and x-canonical-private-synchronous are
+	// sent.
+	StackTagHints []string `json:"stack_tag_hints,omitempty"`
+	// MonitorHint is the hint selecting the monitor of the notifications
+	// set with SetMonitor, or empty if the daemon has none.
+	MonitorHint string `json:"monitor_hint,omitempty"`
+	// MonitorByIndex is true if the daemon expects MonitorHint to be the
+	// index of the monitor, as an int32, rather than the name of its output.
+	MonitorByIndex bool `json:"monitor_by_index,omitempty"`
+	// Displayed is how to list the notifications shown by the daemon, see
+	// Notifier.Displayed: "dunst" for the dunst interface, or empty if the
+	// daemon cannot tell.
+	Displayed string `json:"displayed,omitempty"`
+	// OpaqueImages is true if the daemon mishandles the alpha channel of
+	// embedded images, which are then sent without it.
+	OpaqueImages bool `json:"opaque_images,omitempty"`
+	// FileURLsHint is the hint listing the URLs of the files a notification
+	// is about, see NotifyFile, or empty if the daemon has none.
+	FileURLsHint string `json:"file_urls_hint,omitempty"`
+	// WindowIDHints are the hints carrying the X11 window of notifications
+	// set with SetWindowID. If empty, "window-id" is sent.
+	WindowIDHints []string `json:"window_id_hints,omitempty"`
+	// Pace is the rate of notifications of an application above which the
+	// daemon drops or refuses them, see WithAdaptivePacing.
+	Pace Pace `json:"pace,omitempty"`
+	// MaxActions is the number of actions the daemon shows, besides the
+	// DefaultAction, or 0 if it shows them all; see WithActionOverflow.
+	MaxActions int `json:"max_actions,omitempty"`
+	// MaxBodyLength is the number of characters of the body the daemon
+	// shows, or 0 if it shows them all; see Notifier.Preview.
+	MaxBodyLength int `json:"max_body_length,omitempty"`
+	// NoImageFiles is true if the daemon does not show the image files of
+	// the image path hint, so that WithImageCache embeds the images.
+	NoImageFiles bool `json:"no_image_files,omitempty"`
+}
+
+// defaultStackTagHints are the stacking hints sent when the daemon is not
+// known to need specific ones.
+var defaultStackTagHints = []string{"x-dunst-stack-tag", "x-canonical-private-synchronous"}
+
+// quirkTable holds the quirks of known daemons, by the name they report in
+// GetServerInformation. The rules loaded by LoadQuirks take precedence.
+var quirkTable = map[string]Quirks{
+	"dunst":       {StackTagHints: []string{"x-dunst-stack-tag"}, MonitorHint: "monitor", MonitorByIndex: true, Displayed: "dunst"},
+	"mako":        {MonitorHint: "output"},
+	"notify-osd":  {StackTagHints: []string{"x-canonical-private-synchronous"}},
+	"gnome-shell": {Persistence: true, Pace: Pace{Limit: 10, Window: 10 * time.Second}, MaxActions: 3},
+	"Plasma":      {Persistence: true, FileURLsHint: "x-kde-urls", WindowIDHints: []string{"window-id", "x-kde-window-id"}},
+}
+
+// desktopDaemons holds the daemon of the desktops shipping their own, by
+// XDG_CURRENT_DESKTOP entry. It is used when the daemon cannot be asked.
+var desktopDaemons = map[string]string{
+	"GNOME": "gnome-shell",
+	"KDE":   "Plasma",
+	"Unity": "notify-osd",
+}
+
+// WithQuirks makes the Notifier work around the quirks q of the daemon,
+// instead of the ones AutoConfigure would pick.
+func WithQuirks(q Quirks) Option {
+	return func(nf *Notifier) error {
+		nf.quirks = &q
+		return nil
+	}
+}
+
+// daemonQuirks returns the quirks set with WithQuirks or picked by
+// AutoConfigure, or else the ones of the daemon in the quirk table.
+func (nf *Notifier) daemonQuirks() Quirks {
+	if nf.quirks != nil {
+		return *nf.quirks
+	}
+	info, err := nf.ServerInfo()
+	if err != nil {
+		return Quirks{}
+	}
+	q, _ := LookupQuirks(info)
+	return q
+}
+
+// stackTagHints returns the hints carrying the Tag of notifications.
+func (nf *Notifier) stackTagHints() []string {
+	if nf.quirks != nil && len(nf.quirks.StackTagHints) > 0 {
+		return nf.quirks.StackTagHints
+	}
+	return defaultStackTagHints
+}
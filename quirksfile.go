@@ -0,0 +1,333 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EnvQuirksFile is the environment variable naming a file of quirk rules,
+// loaded with LoadQuirks when the package is initialized.
+const EnvQuirksFile = "NOTIFY_QUIRKS_FILE"
+
+// QuirksRule gives Quirks to the daemons it matches, by the ServerInfo they
+// report. Files of rules, see LoadQuirks, hold a JSON array of them:
+//
+//	[{"name": "dunst", "versions": ">=1.9 <2", "quirks": {"displayed": "dunst"}}]
+type QuirksRule struct {
+	// Name is the name of the daemons matched.
+	Name string `json:"name"`
+	// Vendor, if not empty, is the vendor of the daemons matched.
+	Vendor string `json:"vendor,omitempty"`
+	// Versions, if not empty, is the range of the versions matched: one or
+	// more space-separated constraints like ">=1.9", "<2" or "=1.9.2",
+	// which are all met. Versions are compared by their numeric components,
+	// with the missing ones as 0, and a pre-release like "2.0-rc1" comes
+	// before its release. A daemon version without numbers matches no range.
+	Versions string `json:"versions,omitempty"`
+	// Quirks are the quirks of the daemons matched. Those the rule sets
+	// replace those of the rules with a lower precedence, and of the
+	// built-in table; the others are kept.
+	Quirks Quirks `json:"quirks"`
+
+	// constraints are the parsed Versions.
+	constraints []versionConstraint
+	// set holds the JSON names of the quirks the rule sets.
+	set map[string]bool
+}
+
+// merge returns q with the quirks r sets replaced. It takes q by value, so
+// that the lookups matching no rule do not allocate.
+func (r *QuirksRule) merge(q Quirks) Quirks {
+	dst, src := reflect.ValueOf(&q).Elem(), reflect.ValueOf(&r.Quirks).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		name, _, _ := strings.Cut(dst.Type().Field(i).Tag.Get("json"), ",")
+		if r.set[name] {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return q
+}
+
+// specificity returns how many of the fields of ServerInfo r matches.
+func (r *QuirksRule) specificity() int {
+	n := 1
+	if r.Vendor != "" {
+		n++
+	}
+	if len(r.constraints) > 0 {
+		n++
+	}
+	return n
+}
+
+// matches returns true if r matches the daemon of info.
+func (r *QuirksRule) matches(info ServerInfo) bool {
+	if r.Name != info.Name || r.Vendor != "" && r.Vendor != info.Vendor {
+		return false
+	}
+	if len(r.constraints) == 0 {
+		return true
+	}
+	v, ok := parseVersion(daemonVersion(info.Version))
+	if !ok {
+		return false
+	}
+	for _, c := range r.constraints {
+		if !c.allows(v) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	// userQuirksMu guards userQuirks, the rules loaded by LoadQuirks,
+	// ordered by decreasing precedence.
+	userQuirksMu sync.RWMutex
+	userQuirks   []QuirksRule
+)
+
+// envQuirksErr is the error of loading the file of EnvQuirksFile, returned
+// by NewNotifier and NewCore.
+var envQuirksErr = loadEnvQuirks()
+
+// loadEnvQuirks loads the file named by EnvQuirksFile, if any.
+func loadEnvQuirks() error {
+	path := os.Getenv(EnvQuirksFile)
+	if path == "" {
+		return nil
+	}
+	if err := LoadQuirksFile(path); err != nil {
+		return fmt.Errorf("%w (from %s)", err, EnvQuirksFile)
+	}
+	return nil
+}
+
+// LoadQuirks reads a JSON array of QuirksRules from r, and makes the
+// Notifiers use them over the built-in quirk table, see QuirksRule. It
+// replaces the rules loaded before. Nothing is replaced if r cannot be read
+// or holds an invalid rule.
+//
+// For a daemon, the rules loaded take precedence over the built-in ones;
+// among them, those matching more of the name, vendor and versions take
+// precedence, then the last ones in r. A rule only replaces the quirks it
+// sets, so that a rule fixing one quirk of dunst keeps the built-in others.
+// Quirks set with WithQuirks or picked
+// by AutoConfigure take precedence over all rules.
+func LoadQuirks(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var rules []QuirksRule
+	if err := dec.Decode(&rules); err != nil {
+		return fmt.Errorf("notify: invalid quirks: %w", err)
+	}
+	// The quirks a rule sets are the keys of its object.
+	var sets []struct {
+		Quirks map[string]json.RawMessage `json:"quirks"`
+	}
+	if err := json.Unmarshal(data, &sets); err != nil {
+		return fmt.Errorf("notify: invalid quirks: %w", err)
+	}
+	for i := range rules {
+		r := &rules[i]
+		r.set = make(map[string]bool, len(sets[i].Quirks))
+		for name := range sets[i].Quirks {
+			r.set[name] = true
+		}
+		if r.Name == "" {
+			return fmt.Errorf("notify: invalid quirks: rule %d has no name", i)
+		}
+		cs, err := parseVersionRange(r.Versions)
+		if err != nil {
+			return fmt.Errorf("notify: invalid quirks: rule %d (%s): %w", i, r.Name, err)
+		}
+		r.constraints = cs
+	}
+	// Reverse first, so that the stable sort keeps the last rules first
+	// among those as specific.
+	for i, j := 0, len(rules)-1; i < j; i, j = i+1, j-1 {
+		rules[i], rules[j] = rules[j], rules[i]
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].specificity() > rules[j].specificity()
+	})
+
+	userQuirksMu.Lock()
+	defer userQuirksMu.Unlock()
+	userQuirks = rules
+	return nil
+}
+
+// LoadQuirksFile is like LoadQuirks, reading the file at path.
+func LoadQuirksFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	defer f.Close()
+	if err := LoadQuirks(f); err != nil {
+		return fmt.Errorf("%w in %s", err, path)
+	}
+	return nil
+}
+
+// DumpQuirks writes the effective quirk rules to w, in the format read by
+// LoadQuirks: the rules loaded, then the built-in ones, from the highest
+// precedence to the lowest.
+func DumpQuirks(w io.Writer) error {
+	userQuirksMu.RLock()
+	rules := append([]QuirksRule(nil), userQuirks...)
+	userQuirksMu.RUnlock()
+
+	names := make([]string, 0, len(quirkTable))
+	for name := range quirkTable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rules = append(rules, QuirksRule{Name: name, Quirks: quirkTable[name]})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(rules)
+}
+
+// LookupQuirks returns the quirks of the daemon of info: those of the
+// built-in table, with the ones set by the rules loaded matching it, and
+// false if neither has an entry for it. Quirks set with WithQuirks or
+// picked by AutoConfigure take precedence over them.
+func LookupQuirks(info ServerInfo) (Quirks, bool) {
+	userQuirksMu.RLock()
+	defer userQuirksMu.RUnlock()
+	q, ok := quirkTable[info.Name]
+	for i := len(userQuirks) - 1; i >= 0; i-- {
+		if userQuirks[i].matches(info) {
+			q = userQuirks[i].merge(q)
+			ok = true
+		}
+	}
+	return q, ok
+}
+
+// version is a parsed version: its numeric components, and its pre-release
+// suffix, if any.
+type version struct {
+	nums []int
+	pre  string
+}
+
+// daemonVersion returns the version number in s, a version as reported by
+// a daemon, like "1.9.2 (2023-04-20)" or "v44.2".
+func daemonVersion(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t("); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimPrefix(s, "v")
+}
+
+// parseVersion parses a version like "1.9", "1.9.2" or "2.0-rc1".
+func parseVersion(s string) (version, bool) {
+	var v version
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		s, v.pre = s[:i], s[i+1:]
+	}
+	if s == "" {
+		return version{}, false
+	}
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.nums = append(v.nums, n)
+	}
+	return v, true
+}
+
+// compare returns -1, 0 or 1 if v is before, the same as or after w.
+func (v version) compare(w version) int {
+	for i := 0; i < len(v.nums) || i < len(w.nums); i++ {
+		var a, b int
+		if i < len(v.nums) {
+			a = v.nums[i]
+		}
+		if i < len(w.nums) {
+			b = w.nums[i]
+		}
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.pre == w.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case w.pre == "":
+		return -1
+	}
+	return strings.Compare(v.pre, w.pre)
+}
+
+// versionConstraint is a constraint of a version range, like ">=1.9".
+type versionConstraint struct {
+	op string
+	v  version
+}
+
+// allows returns true if v meets c.
+func (c versionConstraint) allows(v version) bool {
+	cmp := v.compare(c.v)
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return cmp == 0
+}
+
+// parseVersionRange parses the space-separated constraints of s.
+func parseVersionRange(s string) ([]versionConstraint, error) {
+	var cs []versionConstraint
+	for _, f := range strings.Fields(s) {
+		op := "="
+		for _, o := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(f, o) {
+				op, f = o, f[len(o):]
+				break
+			}
+		}
+		v, ok := parseVersion(f)
+		if !ok {
+			return nil, errors.New("invalid version range " + strconv.Quote(s))
+		}
+		cs = append(cs, versionConstraint{op, v})
+	}
+	return cs, nil
+}
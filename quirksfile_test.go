@@ -0,0 +1,184 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+// loadQuirks loads the rules of src, and the built-in quirks only once the
+// test is over.
+func loadQuirks(t *testing.T, src string) {
+	t.Helper()
+	t.Cleanup(func() { notify.LoadQuirks(strings.NewReader("[]")) })
+	if err := notify.LoadQuirks(strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// monitorHint returns the hint the daemon gets to select a monitor, which
+// tells the quirks picked for it.
+func monitorHint(t *testing.T, nf *notify.Notifier) string {
+	t.Helper()
+	nf.InvalidateCaches()
+	n := notify.New("test", "where", "", "", 0, notify.NormalUrgency)
+	n.SetMonitor("1")
+	c, err := n.DryRun(nf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range c.Hints {
+		if v.Value() == "1" || v.Value() == int32(1) {
+			return k
+		}
+	}
+	return ""
+}
+
+func TestQuirksRulePrecedence(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	loadQuirks(t, `[
+		{"name": "inhouse", "quirks": {"monitor_hint": "any"}},
+		{"name": "inhouse", "quirks": {"monitor_hint": "later"}},
+		{"name": "inhouse", "vendor": "ACME", "quirks": {"monitor_hint": "vendor"}},
+		{"name": "inhouse", "vendor": "ACME", "versions": ">=2", "quirks": {"monitor_hint": "vendor-v2"}},
+		{"name": "inhouse", "versions": ">=2", "quirks": {"monitor_hint": "v2"}},
+		{"name": "mako", "quirks": {"monitor_hint": "user-mako"}}
+	]`)
+
+	for _, tt := range []struct {
+		name, vendor, version string
+		want                  string
+	}{
+		{"inhouse", "other", "1.0", "later"},
+		{"inhouse", "ACME", "1.0", "vendor"},
+		{"inhouse", "other", "2.1", "v2"},
+		{"inhouse", "ACME", "2.1", "vendor-v2"},
+		{"mako", "emersion", "1.8", "user-mako"},
+		{"dunst", "knopwob", "1.9.2", "monitor"},
+		{"unknown", "", "", ""},
+	} {
+		s.setServerInfo(tt.name, tt.vendor, tt.version)
+		if got := monitorHint(t, nf); got != tt.want {
+			t.Errorf("%s %s %s: monitor hint %q, want %q", tt.name, tt.vendor, tt.version, got, tt.want)
+		}
+	}
+
+	// WithQuirks takes precedence over all rules.
+	s.setServerInfo("inhouse", "ACME", "2.1")
+	if got := monitorHint(t, newTestNotifier(t, notify.WithQuirks(notify.Quirks{MonitorHint: "mine"}))); got != "mine" {
+		t.Errorf("WithQuirks: monitor hint %q, want mine", got)
+	}
+}
+
+func TestQuirksRulesMerged(t *testing.T) {
+	loadQuirks(t, `[
+		{"name": "dunst", "quirks": {"monitor_hint": "output", "monitor_by_index": false}},
+		{"name": "dunst", "versions": ">=2", "quirks": {"max_actions": 2}}
+	]`)
+	q, ok := notify.LookupQuirks(notify.ServerInfo{Name: "dunst", Version: "2.0"})
+	want := notify.Quirks{StackTagHints: []string{"x-dunst-stack-tag"}, MonitorHint: "output", Displayed: "dunst", MaxActions: 2}
+	if !ok || !reflect.DeepEqual(q, want) {
+		t.Errorf("LookupQuirks() = %+v, %v, want %+v", q, ok, want)
+	}
+	q, ok = notify.LookupQuirks(notify.ServerInfo{Name: "dunst", Version: "1.9"})
+	if want.MaxActions = 0; !ok || !reflect.DeepEqual(q, want) {
+		t.Errorf("LookupQuirks() for 1.9 = %+v, %v, want %+v", q, ok, want)
+	}
+}
+
+func TestQuirksVersionRanges(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	for _, tt := range []struct {
+		versions, version string
+		match             bool
+	}{
+		{">=1.9 <2", "1.9", true},
+		{">=1.9 <2", "1.9.0", true},
+		{">=1.9 <2", "1.10.3", true},
+		{">=1.9 <2", "1.8.99", false},
+		{">=1.9 <2", "2", false},
+		{">=1.9 <2", "2.0-rc1", true},
+		{">=2", "2.0-rc1", false},
+		{"=1.9.2", "1.9.2 (2023-04-20)", true},
+		{"1.9.2", "v1.9.2", true},
+		{">1.9", "1.9.0", false},
+		{"<=44", "44.0", true},
+		{">=1", "unknown", false},
+		{">=1", "", false},
+	} {
+		loadQuirks(t, `[{"name": "inhouse", "versions": "`+tt.versions+`", "quirks": {"monitor_hint": "matched"}}]`)
+		s.setServerInfo("inhouse", "", tt.version)
+		if got := monitorHint(t, nf) == "matched"; got != tt.match {
+			t.Errorf("%q matching %q = %v, want %v", tt.versions, tt.version, got, tt.match)
+		}
+	}
+}
+
+func TestLoadQuirksErrors(t *testing.T) {
+	loadQuirks(t, `[{"name": "inhouse", "quirks": {"monitor_hint": "kept"}}]`)
+	for _, src := range []string{
+		`{"name": "inhouse"}`,
+		`[{"quirks": {}}]`,
+		`[{"name": "inhouse", "versions": ">=one"}]`,
+		`[{"name": "inhouse", "versions": "~1.2"}]`,
+		`[{"name": "inhouse", "quirks": {"monitor_hnit": "typo"}}]`,
+		`[{"name": "inhouse"`,
+	} {
+		if err := notify.LoadQuirks(strings.NewReader(src)); err == nil {
+			t.Errorf("LoadQuirks(%s) succeeded", src)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := notify.DumpQuirks(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"kept"`) {
+		t.Error("a failed LoadQuirks replaced the rules")
+	}
+
+	path := filepath.Join(t.TempDir(), "quirks.json")
+	os.WriteFile(path, []byte(`[{"name": "inhouse", "versions": "bad"}]`), 0o600)
+	if err := notify.LoadQuirksFile(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("LoadQuirksFile = %v, want an error naming the file", err)
+	}
+}
+
+func TestDumpQuirks(t *testing.T) {
+	loadQuirks(t, `[{"name": "inhouse", "vendor": "ACME", "versions": ">=2", "quirks": {"pace": {"limit": 5, "window": "1s"}}}]`)
+	var buf bytes.Buffer
+	if err := notify.DumpQuirks(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var rules []notify.QuirksRule
+	if err := json.Unmarshal(buf.Bytes(), &rules); err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) < 2 || rules[0].Name != "inhouse" || rules[0].Quirks.Pace.Limit != 5 {
+		t.Fatalf("dumped rules start with %+v", rules[0])
+	}
+	names := map[string]bool{}
+	for _, r := range rules {
+		names[r.Name] = true
+	}
+	if !names["dunst"] || !names["gnome-shell"] {
+		t.Errorf("built-in rules missing from %s", buf.String())
+	}
+	// The dump loads back.
+	if err := notify.LoadQuirks(&buf); err != nil {
+		t.Errorf("loading the dump: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+// reconnectState is what is left to do once the connection of a Notifier
+// was lost: connect again, and listen to the signals again if it did, as
+// the match rules were lost with the connection.
+type reconnectState struct {
+	pending bool
+	listen  bool
+}
+
+// connectionLost forgets the connection of nf, which was lost, and the
+// listener on it. nf.mu must be held.
+func (nf *Notifier) connectionLost() {
+	nf.reconnect.pending = true
+	if nf.signals != nil {
+		nf.reconnect.listen = true
+		nf.signals = nil
+		nf.stopDispatch = nil
+		nf.dispatchDone = nil
+	}
+	nf.conn = nil
+}
+
+// restoreListener listens to the signals on the new connection of nf if it
+// listened on the lost one, and returns true once this reconnection is
+// done. nf.mu must be held.
+func (nf *Notifier) restoreListener() (bool, error) {
+	if !nf.reconnect.pending {
+		return false, nil
+	}
+	if nf.reconnect.listen {
+		if err := nf.installListener(nf.conn); err != nil {
+			return false, err
+		}
+	}
+	nf.reconnect = reconnectState{}
+	return true, nil
+}
+
+// reconnected tells nf and the Notifiers listening through it that the
+// connection was lost and connected again, so that the signals sent in
+// between were missed. The daemon may have changed too, so the caches are
+// dropped.
+func (nf *Notifier) reconnected() {
+	nf.log(LevelInfo, "connected to the bus again, signals sent in between were missed", nil)
+	nf.InvalidateCaches()
+	nf.emit(Event{Kind: EventReconnected})
+	if nf.fanout != nil {
+		for _, sub := range nf.fanout.listeners() {
+			sub.emit(Event{Kind: EventReconnected})
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+// busProxy forwards the connections to its socket to the test bus, and
+// kills them with drop, like a bus restart would.
+type busProxy struct {
+	addr string
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newBusProxy(t *testing.T) *busProxy {
+	t.Helper()
+	requireBus(t)
+	_, path, ok := strings.Cut(busAddress, "path=")
+	if !ok {
+		t.Skipf("cannot proxy the bus at %s", busAddress)
+	}
+	path, _, _ = strings.Cut(path, ",")
+	sock := filepath.Join(t.TempDir(), "bus")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	p := &busProxy{addr: "unix:path=" + sock}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			bus, err := net.Dial("unix", path)
+			if err != nil {
+				c.Close()
+				continue
+			}
+			p.mu.Lock()
+			p.conns = append(p.conns, c, bus)
+			p.mu.Unlock()
+			go io.Copy(bus, c)
+			go io.Copy(c, bus)
+		}
+	}()
+	t.Cleanup(p.drop)
+	return p
+}
+
+// drop closes the connections through p.
+func (p *busProxy) drop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.conns {
+		c.Close()
+	}
+	p.conns = nil
+}
+
+func TestReconnectKeepsCallbacks(t *testing.T) {
+	s := newFakeServer(t)
+	p := newBusProxy(t)
+	nf := newTestNotifier(t, notify.WithBusAddress(p.addr))
+	events := nf.Events()
+
+	invoked := make(chan string, 1)
+	n := notify.New("test", "still listening", "", "", 0, notify.NormalUrgency)
+	n.AddAction("open", "Open")
+	n.OnAction = func(key string) { invoked <- key }
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, notify.EventSent)
+
+	p.drop()
+	waitEvent(t, events, notify.EventReconnected)
+
+	s.emitAction(n.Id, "open")
+	select {
+	case key := <-invoked:
+		if key != "open" {
+			t.Errorf("OnAction called with %q", key)
+		}
+	case <-waitTimeout():
+		t.Fatal("the action after the reconnection did not reach its callback")
+	}
+
+	// Sends use the new connection.
+	if _, err := nf.Notify(notify.New("test", "after", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Errorf("Notify after the reconnection: %v", err)
+	}
+	if got := s.received(); got != 2 {
+		t.Errorf("the daemon received %d notifications, want 2", got)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redactor returns the summary and body of a notification with the text
+// that must not be shown removed.
+type Redactor func(summary, body string) (string, string)
+
+// WithRedactor makes the Notifier pass the summary and body of each
+// notification through r before they leave the process: r applies to what is
+// sent to the daemon or to another Transport, and to the notifications
+// mentioned in log messages. The raw call API, such as RawNotify, is not
+// affected.
+func WithRedactor(r Redactor) Option {
+	return func(nf *Notifier) error {
+		nf.redactor = r
+		return nil
+	}
+}
+
+// redact returns summary and body redacted by the Redactor of nf, if any.
+func (nf *Notifier) redact(summary, body string) (string, string) {
+	if nf.redactor == nil {
+		return summary, body
+	}
+	return nf.redactor(summary, body)
+}
+
+// NewRegexpRedactor returns a Redactor replacing the text matched by any of
+// patterns with replacement. Where matches of several patterns overlap,
+// the whole overlapping text is replaced once, so that no part of it is
+// left.
+func NewRegexpRedactor(patterns []string, replacement string) (Redactor, error) {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = re
+	}
+	redact := func(s string) string {
+		var spans [][]int
+		for _, re := range res {
+			spans = append(spans, re.FindAllStringIndex(s, -1)...)
+		}
+		if len(spans) == 0 {
+			return s
+		}
+		sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+		var b strings.Builder
+		last := 0
+		for i := 0; i < len(spans); {
+			start, end := spans[i][0], spans[i][1]
+			for i++; i < len(spans) && spans[i][0] <= end; i++ {
+				end = max(end, spans[i][1])
+			}
+			if start == end {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(replacement)
+			last = end
+		}
+		b.WriteString(s[last:])
+		return b.String()
+	}
+	return func(summary, body string) (string, string) {
+		return redact(summary), redact(body)
+	}, nil
+}
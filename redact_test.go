@@ -0,0 +1,96 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestRegexpRedactor(t *testing.T) {
+	redact, err := notify.NewRegexpRedactor([]string{
+		`secret-token`,
+		`token-\d+`,
+		`[a-z]+@example\.com`,
+		`example`,
+	}, "█")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ in, want string }{
+		{"nothing to hide", "nothing to hide"},
+		{"use secret-token-1234 now", "use █ now"},
+		{"token-1 and token-2", "█ and █"},
+		{"mail bob@example.com", "mail █"},
+		{"example.org", "█.org"},
+		{"secret-tokensecret-token", "█"},
+	}
+	for _, tt := range tests {
+		if summary, body := redact(tt.in, tt.in); summary != tt.want || body != tt.want {
+			t.Errorf("redact(%q) = %q, %q, want %q", tt.in, summary, body, tt.want)
+		}
+	}
+
+	if _, err := notify.NewRegexpRedactor([]string{"("}, ""); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
+
+func TestWithRedactor(t *testing.T) {
+	redact, err := notify.NewRegexpRedactor([]string{`[a-z]+@example\.com`}, "[email]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithRedactor(redact))
+	n := notify.New("test", "Mail from bob@example.com", "Reply to alice@example.com", "", time.Second, notify.NormalUrgency)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.Summary != "Mail from [email]" || got.Body != "Reply to [email]" {
+		t.Errorf("sent %q, %q", got.Summary, got.Body)
+	}
+	if n.Summary != "Mail from bob@example.com" {
+		t.Errorf("Summary changed to %q", n.Summary)
+	}
+
+	var rt recordingTransport
+	other, err := notify.NewNotifier(notify.WithTransport(&rt), notify.WithRedactor(redact))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := rt.calls[0]; got.Summary != "Mail from [email]" || got.Body != "Reply to [email]" {
+		t.Errorf("delivered %q, %q to the transport", got.Summary, got.Body)
+	}
+}
+
+func TestWithRedactorBeforeFieldPolicies(t *testing.T) {
+	redact, err := notify.NewRegexpRedactor([]string{`[a-z]+@example\.com`}, "[email]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newFakeServer(t)
+	truncate := notify.FieldPolicy{MaxRunes: 20, OnTooLong: notify.PolicyTruncate}
+	nf := newTestNotifier(t, notify.WithRedactor(redact), notify.WithFieldPolicies(map[string]notify.FieldPolicy{"body": truncate}))
+
+	// Truncated first, the address would be cut short of what the
+	// Redactor matches.
+	n := notify.New("test", "New mail", "Reply to alice@example.com", "", time.Second, notify.NormalUrgency)
+	if err := nf.Validate(n); err != nil {
+		t.Errorf("Validate() = %v, want the redacted body within the policy", err)
+	}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Body; got != "Reply to [email]" {
+		t.Errorf("sent the body %q", got)
+	}
+}
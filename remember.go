@@ -0,0 +1,103 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The prefixes of the action keys of AskRemember, followed by the index of
+// the choice, so that the keys never depend on the text of the choices.
+const (
+	chooseActionPrefix = "notify-choose-"
+	alwaysActionPrefix = "notify-always-"
+)
+
+// rememberPath returns the file holding the choice remembered for key by
+// AskRemember.
+func (s *IDStore) rememberPath(key string) string {
+	return s.keyPath("remember-", key)
+}
+
+// AskRemember asks summary with the choices as actions, each with an
+// "Always" variant, and returns the choice made. If the user picks an
+// Always variant, the choice is recorded for key in the ID store of nf (see
+// WithIDStore), and the next calls with key return it right away, with
+// remembered true, until Forget is called. A remembered answer that is no
+// longer one of the choices is ignored.
+//
+// Otherwise it is like SendAndWait: a closed notification returns
+// ErrDismissed, and a done ctx its error.
+func (nf *Notifier) AskRemember(ctx context.Context, key, summary string, choices ...string) (choice string, remembered bool, err error) {
+	if nf.idStore == nil {
+		return "", false, errNoIDStore
+	}
+	if len(choices) == 0 {
+		return "", false, errors.New("notify: no choices")
+	}
+	for i, c := range choices {
+		if c == "" {
+			return "", false, fmt.Errorf("notify: empty choice %d", i)
+		}
+		for _, prev := range choices[:i] {
+			if c == prev {
+				return "", false, fmt.Errorf("notify: duplicate choice %q", c)
+			}
+		}
+	}
+
+	path := nf.idStore.rememberPath(key)
+	if b, err := os.ReadFile(path); err == nil {
+		for _, c := range choices {
+			if c == string(b) {
+				return c, true, nil
+			}
+		}
+		nf.log(LevelDebug, fmt.Sprintf("ignored the answer remembered for %q, no longer a choice", key), nil)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", false, fmt.Errorf("notify: %w", err)
+	}
+
+	n := New("", summary, "", "", 0, NormalUrgency)
+	for i, c := range choices {
+		n.AddAction(chooseActionPrefix+strconv.Itoa(i), c)
+	}
+	for i, c := range choices {
+		n.AddAction(alwaysActionPrefix+strconv.Itoa(i), nf.localize(MsgAlways, c))
+	}
+	answer, err := nf.SendAndWait(ctx, n)
+	if err != nil {
+		return "", false, err
+	}
+
+	always := strings.HasPrefix(answer, alwaysActionPrefix)
+	i, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(answer, alwaysActionPrefix), chooseActionPrefix))
+	if err != nil || i < 0 || i >= len(choices) {
+		return "", false, fmt.Errorf("notify: unexpected answer %q", answer)
+	}
+	if always {
+		if err := writeFileAtomic(path, []byte(choices[i])); err != nil {
+			return choices[i], false, fmt.Errorf("notify: %w", err)
+		}
+	}
+	return choices[i], false, nil
+}
+
+// Forget forgets the choice remembered for key by AskRemember, so that the
+// next AskRemember with key asks again.
+func (nf *Notifier) Forget(key string) error {
+	if nf.idStore == nil {
+		return errNoIDStore
+	}
+	if err := os.Remove(nf.idStore.rememberPath(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("notify: %w", err)
+	}
+	return nil
+}
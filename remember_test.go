@@ -0,0 +1,99 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestAskRemember(t *testing.T) {
+	s := newFakeServer(t)
+	store, err := notify.NewIDStore(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nf := newTestNotifier(t, notify.WithIDStore(store))
+	events := nf.Events()
+
+	type result struct {
+		choice     string
+		remembered bool
+		err        error
+	}
+	// answer asks, invokes the action key, and returns the result.
+	answer := func(key string) result {
+		t.Helper()
+		results := make(chan result, 1)
+		go func() {
+			c, r, err := nf.AskRemember(context.Background(), "overwrite", "Overwrite the file?", "Yes", "No")
+			results <- result{c, r, err}
+		}()
+		waitEvent(t, events, notify.EventSent)
+		s.emitAction(s.last(t).ID, key)
+		select {
+		case r := <-results:
+			return r
+		case <-waitTimeout():
+			t.Fatal("AskRemember did not return")
+		}
+		return result{}
+	}
+
+	if r := answer("notify-choose-0"); r != (result{"Yes", false, nil}) {
+		t.Fatalf("first answer = %+v", r)
+	}
+	want := []string{
+		"notify-choose-0", "Yes", "notify-choose-1", "No",
+		"notify-always-0", "Always Yes", "notify-always-1", "Always No",
+	}
+	if got := s.last(t).Actions; !reflect.DeepEqual(got, want) {
+		t.Errorf("actions = %q, want %q", got, want)
+	}
+
+	if r := answer("notify-always-1"); r != (result{"No", false, nil}) {
+		t.Fatalf("always answer = %+v", r)
+	}
+	sent := s.received()
+	c, remembered, err := nf.AskRemember(context.Background(), "overwrite", "Overwrite the file?", "Yes", "No")
+	if c != "No" || !remembered || err != nil {
+		t.Errorf("remembered answer = %q, %v, %v", c, remembered, err)
+	}
+	if s.received() != sent {
+		t.Error("the remembered answer was asked again")
+	}
+
+	if err := nf.Forget("overwrite"); err != nil {
+		t.Fatal(err)
+	}
+	if r := answer("notify-choose-0"); r != (result{"Yes", false, nil}) {
+		t.Errorf("answer after Forget = %+v", r)
+	}
+	if err := nf.Forget("never asked"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAskRememberInvalid(t *testing.T) {
+	store, err := notify.NewIDStore(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nf, err := notify.NewNotifier(notify.WithIDStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, choices := range [][]string{nil, {""}, {"Yes", "Yes"}} {
+		if _, _, err := nf.AskRemember(context.Background(), "key", "Sure?", choices...); err == nil {
+			t.Errorf("choices %q accepted", choices)
+		}
+	}
+	if _, _, err := newTestNotifier(t).AskRemember(context.Background(), "key", "Sure?", "Yes"); err == nil {
+		t.Error("AskRemember without an ID store succeeded")
+	}
+}
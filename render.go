@@ -0,0 +1,85 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Render returns the text of v for a notification: the empty string for
+// nil, including nil pointers, maps, slices and functions held in an
+// interface, the Error method of errors, the String method of fmt.Stringers,
+// and the %v format of everything else.
+//
+// A nil pointer in an error or fmt.Stringer interface is not nil itself, but
+// calling its method would usually panic; Render returns the empty string
+// instead.
+func Render(v any) string {
+	if isNil(v) {
+		return ""
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// isNil returns true if v is nil or holds a nil value.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// renderArgs returns args with the nil values replaced by the empty string,
+// like Render. fmt formats the others, calling the methods of errors and
+// fmt.Stringers itself, so that verbs like %q apply to their text.
+func renderArgs(args []any) []any {
+	rendered := make([]any, len(args))
+	for i, arg := range args {
+		if isNil(arg) {
+			rendered[i] = ""
+		} else {
+			rendered[i] = arg
+		}
+	}
+	return rendered
+}
+
+// NotifyValue sends a notification with summary and the Render text of
+// body, see Render, and the timeout of the implicit notification.
+func (nf *Notifier) NotifyValue(summary string, body any) (SendResult, error) {
+	return nf.Notify(New("", summary, Render(body), "", note.TimeoutDuration(), NormalUrgency))
+}
+
+// Notifyf sends a notification with summary and a body formatted like
+// fmt.Sprintf, like NotifyValue. The nil values of args, including the nil
+// pointers in an error or fmt.Stringer, are formatted as the empty string.
+func (nf *Notifier) Notifyf(summary, format string, args ...any) (SendResult, error) {
+	return nf.Notify(New("", summary, fmt.Sprintf(format, renderArgs(args)...), "", note.TimeoutDuration(), NormalUrgency))
+}
+
+// NotifyValue sends summary and the Render text of body as a notification,
+// like SendMsg.
+func NotifyValue(summary string, body any) (id uint32, err error) {
+	return SendMsg(summary, Render(body))
+}
+
+// Notifyf sends summary and a body formatted like Notifier.Notifyf as a
+// notification, like SendMsg.
+func Notifyf(summary, format string, args ...any) (id uint32, err error) {
+	return SendMsg(summary, fmt.Sprintf(format, renderArgs(args)...))
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+type diskError struct{ disk string }
+
+func (e *diskError) Error() string { return "disk " + e.disk + " failing" }
+
+type progress struct{ done, total int }
+
+func (p progress) String() string { return fmt.Sprintf("%d/%d", p.done, p.total) }
+
+type priority int
+
+func (p priority) String() string { return [...]string{"low", "normal", "high"}[p] }
+
+func TestRender(t *testing.T) {
+	var nilErr *diskError
+	var nilStringer *progress
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"nil", nil, ""},
+		{"string", "hello", "hello"},
+		{"error", errors.New("boom"), "boom"},
+		{"pointer error", &diskError{"sda"}, "disk sda failing"},
+		{"nil pointer error", error(nilErr), ""},
+		{"stringer", progress{1, 3}, "1/3"},
+		{"nil pointer stringer", fmt.Stringer(nilStringer), ""},
+		{"duration", 90 * time.Second, "1m30s"},
+		{"int", 42, "42"},
+		{"nil slice", []string(nil), ""},
+		{"slice", []int{1, 2}, "[1 2]"},
+	}
+	for _, tt := range tests {
+		if got := notify.Render(tt.v); got != tt.want {
+			t.Errorf("%s: Render(%#v) = %q, want %q", tt.name, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestNotifyf(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	var nilErr *diskError
+	if _, err := nf.Notifyf("backup", "%d files, error: %v, %s done", 3, error(nilErr), progress{2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.last(t).Body, "3 files, error: , 2/3 done"; got != want {
+		t.Errorf("body %q, want %q", got, want)
+	}
+	// fmt formats errors and fmt.Stringers with the verbs as usual.
+	if _, err := nf.Notifyf("backup", "%q failed, priority %s (%d)", &diskError{"sdb"}, priority(2), priority(2)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.last(t).Body, `"disk sdb failing" failed, priority high (2)`; got != want {
+		t.Errorf("body %q, want %q", got, want)
+	}
+	if _, err := nf.NotifyValue("backup", &diskError{"sdb"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Body; got != "disk sdb failing" {
+		t.Errorf("body %q", got)
+	}
+	if _, err := nf.NotifyValue("backup", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Body; got != "" {
+		t.Errorf("body %q, want it empty", got)
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// InlineReplyAction is the key of the action that lets the user type a reply
+// in the notification, on daemons with the "inline-reply" capability. The
+// reply is passed to OnReply.
+const InlineReplyAction = "inline-reply"
+
+// EnterAction is the key of the action shown by Prompt on daemons without
+// inline replies.
+const EnterAction = "enter"
+
+var (
+	// ErrInputUnsupported is returned by Prompt when the user chose to enter
+	// the text, but the daemon cannot take it. Ask for it in a dialog or on
+	// the terminal instead.
+	ErrInputUnsupported = errors.New("notify: the notification daemon does not support text input")
+	// ErrInsecure is returned by PromptSecret.
+	ErrInsecure = errors.New("notify: notification daemons echo the text typed in notifications")
+	// ErrDismissed is returned by Prompt when the notification is closed
+	// without an answer.
+	ErrDismissed = errors.New("notify: notification dismissed")
+)
+
+// AddReply adds the InlineReplyAction to n, with the label of the button
+// sending the reply and the placeholder text of the empty text field.
+// Daemons without the "inline-reply" capability show it as a plain action.
+func (n *Notification) AddReply(label, placeholder string) {
+	n.AddAction(InlineReplyAction, label)
+	if placeholder != "" {
+		n.SetHint("x-kde-reply-placeholder-text", placeholder)
+	}
+}
+
+// Prompt shows a notification asking for some text, and returns the reply of
+// the user. If the daemon does not support inline replies, the notification
+// has an "Enter" action instead, for which Prompt returns
+// ErrInputUnsupported: the caller should then ask for the text some other
+// way.
+//
+// Prompt returns ErrDismissed if the notification is closed without an
+// answer, or the error of ctx if it is done first, closing the notification.
+func (nf *Notifier) Prompt(ctx context.Context, summary, placeholder string) (string, error) {
+	type answer struct {
+		text string
+		err  error
+	}
+	answers := make(chan answer, 1)
+	reply := func(text string, err error) {
+		select {
+		case answers <- answer{text, err}:
+		default:
+		}
+	}
+
+	n := New("", summary, "", "", 0, NormalUrgency)
+	if f, err := nf.features(); err == nil && f.Has("inline-reply") {
+		n.AddReply(nf.localize(MsgReply), placeholder)
+		n.OnReply = func(text string) { reply(text, nil) }
+	} else {
+		n.Body = placeholder
+		n.AddAction(EnterAction, nf.localize(MsgEnter))
+		n.OnAction = func(string) { reply("", ErrInputUnsupported) }
+	}
+	n.OnClose = func(CloseReason) { reply("", ErrDismissed) }
+	if _, err := nf.SendContext(ctx, n); err != nil {
+		return "", err
+	}
+
+	select {
+	case a := <-answers:
+		if errors.Is(a.err, ErrDismissed) {
+			return "", a.err
+		}
+		nf.CloseNotification(n.Id)
+		return a.text, a.err
+	case <-ctx.Done():
+		nf.CloseNotification(n.Id)
+		return "", ctx.Err()
+	case <-nf.stopped:
+		return "", errWaitShutdown
+	}
+}
+
+// Choice is the answer to a notification sent by Ask.
+type Choice struct {
+	// Key is the key of the action chosen.
+	Key string
+	// Auto is true if Key is the default choice, selected because the user
+	// did not answer in time, see WithDefaultChoice.
+	Auto bool
+}
+
+// WaitOption configures Ask and SendAndWait.
+type WaitOption func(*waitOptions)
+
+type waitOptions struct {
+	defaultKey string
+	after      time.Duration
+	maxWait    time.Duration
+	noResponse NoResponse
+}
+
+// WithDefaultChoice makes Ask and SendAndWait choose the action key if the
+// user does not answer within after: the notification is then closed. A
+// notification expired by the daemon is answered with key too.
+func WithDefaultChoice(key string, after time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.defaultKey = key
+		o.after = after
+	}
+}
+
+// Ask sends n and waits until the user invokes one of its actions,
+// returning its key, or until it is closed, returning ErrDismissed. It wraps
+// the OnAction and OnClose callbacks of n, which are still called, and
+// restores them when it returns.
+//
+// If n is replaced while Ask waits, by another send of n or of its ID, Ask
+// returns ErrReplaced. If n is not shown, for example during quiet hours,
+// Ask returns ErrNotShown. See WithMaxWait to stop waiting before ctx is
+// done.
+//
+// If ctx is done first, Ask closes n and returns the error of ctx. If the
+// transport of nf does not deliver signals, see Transport, Ask returns
+// ErrUnsupported without sending n.
+func (nf *Notifier) Ask(ctx context.Context, n *Notification, opts ...WaitOption) (Choice, error) {
+	o, err := waitOpts(n, opts)
+	if err != nil {
+		return Choice{}, err
+	}
+	if !nf.signalSupport() {
+		return Choice{}, errNoSignals
+	}
+	return nf.ask(ctx, n, o)
+}
+
+// waitOpts returns the options opts for a wait for n.
+func waitOpts(n *Notification, opts []WaitOption) (waitOptions, error) {
+	var o waitOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.defaultKey != "" && !hasAction(n.Actions, o.defaultKey) {
+		return o, fmt.Errorf("notify: default choice %q is not an action of the notification", o.defaultKey)
+	}
+	if o.maxWait < 0 {
+		return o, fmt.Errorf("notify: invalid maximum wait %v", o.maxWait)
+	}
+	if o.noResponse != NoResponseError && o.noResponse != NoResponseExpired {
+		return o, fmt.Errorf("notify: invalid no response policy %d", o.noResponse)
+	}
+	return o, nil
+}
+
+func (nf *Notifier) ask(ctx context.Context, n *Notification, o waitOptions) (Choice, error) {
+	type answer struct {
+		choice Choice
+		err    error
+		// close is true if the notification is still shown.
+		close bool
+	}
+	answers := make(chan answer, 1)
+	reply := func(c Choice, err error) {
+		select {
+		case answers <- answer{c, err, false}:
+		default:
+		}
+	}
+
+	onAction, onClose := n.OnAction, n.OnClose
+	n.OnAction = func(key string) {
+		if onAction != nil {
+			onAction(key)
+		}
+		reply(Choice{Key: key}, nil)
+	}
+	n.OnClose = func(reason CloseReason) {
+		if onClose != nil {
+			onClose(reason)
+		}
+		if reason == ReasonExpired && o.defaultKey != "" {
+			reply(Choice{Key: o.defaultKey, Auto: true}, nil)
+		}
+		reply(Choice{}, ErrDismissed)
+	}
+	defer nf.restoreCallbacks(n, onAction, onClose)
+	if res, err := nf.SendContext(ctx, n); err != nil {
+		return Choice{}, err
+	} else if res.heldBack() {
+		return Choice{}, ErrNotShown
+	}
+	defer nf.watchReplace(n.Id, func() { reply(Choice{}, ErrReplaced) })()
+	timeout := func(c Choice, err error) func() {
+		return func() {
+			select {
+			case answers <- answer{c, err, true}:
+			default:
+			}
+		}
+	}
+	if o.defaultKey != "" {
+		timer := nf.afterWall(o.after, timeout(Choice{Key: o.defaultKey, Auto: true}, nil))
+		defer timer.Stop()
+	}
+	if o.maxWait > 0 {
+		timer := nf.afterWall(o.maxWait, timeout(o.timedOut()))
+		defer timer.Stop()
+	}
+
+	select {
+	case a := <-answers:
+		if a.close {
+			nf.CloseNotification(n.Id)
+		}
+		return a.choice, a.err
+	case <-ctx.Done():
+		nf.CloseNotification(n.Id)
+		return Choice{}, ctx.Err()
+	case <-nf.stopped:
+		return Choice{}, errWaitShutdown
+	}
+}
+
+// restoreCallbacks sets the OnAction and OnClose callbacks of n back to
+// onAction and onClose, once a wait wrapping them is over.
+func (nf *Notifier) restoreCallbacks(n *Notification, onAction func(string), onClose func(CloseReason)) {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	n.OnAction, n.OnClose = onAction, onClose
+}
+
+// SendAndWait is like Ask, but only returns the key of the action chosen.
+//
+// If the transport of nf does not deliver signals, see Transport, the user
+// cannot answer: SendAndWait sends n and returns the default choice, see
+// WithDefaultChoice, once it is due, or ErrDismissed once the timeout of n
+// expires, or what WithMaxWait tells if its wait is shorter. Without any of
+// them, it returns ErrUnsupported without sending n.
+func (nf *Notifier) SendAndWait(ctx context.Context, n *Notification, opts ...WaitOption) (key string, err error) {
+	o, err := waitOpts(n, opts)
+	if err != nil {
+		return "", err
+	}
+	if nf.signalSupport() {
+		c, err := nf.ask(ctx, n, o)
+		return c.Key, err
+	}
+
+	var expired error
+	wait, closeAfter := o.after, false
+	if o.defaultKey == "" {
+		wait, expired = n.TimeoutDuration(), ErrDismissed
+	}
+	if o.maxWait > 0 && (wait <= 0 || o.maxWait < wait) {
+		c, err := o.timedOut()
+		wait, expired, closeAfter = o.maxWait, err, true
+		o.defaultKey = c.Key
+	}
+	if wait <= 0 {
+		return "", errNoSignals
+	}
+	if res, err := nf.SendContext(ctx, n); err != nil {
+		return "", err
+	} else if res.heldBack() {
+		return "", ErrNotShown
+	}
+	replaced := make(chan struct{})
+	defer nf.watchReplace(n.Id, func() { close(replaced) })()
+	timer := nf.clock.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		if closeAfter {
+			nf.CloseNotification(n.Id)
+		}
+		return o.defaultKey, expired
+	case <-replaced:
+		return "", ErrReplaced
+	case <-ctx.Done():
+		nf.CloseNotification(n.Id)
+		return "", ctx.Err()
+	case <-nf.stopped:
+		return "", errWaitShutdown
+	}
+}
+
+// PromptSecret always returns ErrInsecure: notification daemons show the
+// text typed in notifications, and may keep it in their history, so they
+// must not be used to ask for passwords or other secrets.
+func (nf *Notifier) PromptSecret(ctx context.Context, summary, placeholder string) (string, error) {
+	return "", ErrInsecure
+}
+
+// Prompt calls Notifier.Prompt on the default Notifier.
+func Prompt(ctx context.Context, summary, placeholder string) (string, error) {
+	return Default().Prompt(ctx, summary, placeholder)
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/notifytest"
+)
+
+type promptResult struct {
+	text string
+	err  error
+}
+
+// prompt runs Prompt in the background, and returns the notification it
+// showed, once it receives signals, and the channel receiving its result.
+func prompt(t *testing.T, ctx context.Context, s *fakeServer, nf *notify.Notifier) (sentNotification, <-chan promptResult) {
+	t.Helper()
+	events := nf.Events()
+	results := make(chan promptResult, 1)
+	go func() {
+		text, err := nf.Prompt(ctx, "Rename file", "New name")
+		results <- promptResult{text, err}
+	}()
+	for e := range events {
+		if e.Kind == notify.EventSent {
+			break
+		}
+	}
+	return s.last(t), results
+}
+
+func TestPromptInlineReply(t *testing.T) {
+	s := newFakeServer(t)
+	s.setCapabilities("body", "actions", "inline-reply")
+	nf := newTestNotifier(t)
+
+	sent, results := prompt(t, context.Background(), s, nf)
+	if len(sent.Actions) != 2 || sent.Actions[0] != notify.InlineReplyAction {
+		t.Errorf("actions %q", sent.Actions)
+	}
+	if v := sent.Hints["x-kde-reply-placeholder-text"].Value(); v != "New name" {
+		t.Errorf("placeholder %v", v)
+	}
+
+	s.emitReplied(sent.ID, "report.pdf")
+	r := <-results
+	if r.err != nil || r.text != "report.pdf" {
+		t.Errorf("Prompt returned %q, %v", r.text, r.err)
+	}
+	waitFor(t, "close", func() bool { return len(s.closedIDs()) == 1 })
+}
+
+func TestPromptFallback(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	sent, results := prompt(t, context.Background(), s, nf)
+	if len(sent.Actions) != 2 || sent.Actions[0] != notify.EnterAction {
+		t.Errorf("actions %q", sent.Actions)
+	}
+	s.emitAction(sent.ID, notify.EnterAction)
+	if r := <-results; !errors.Is(r.err, notify.ErrInputUnsupported) {
+		t.Errorf("Prompt returned %q, %v", r.text, r.err)
+	}
+}
+
+func TestPromptDismissedAndCancelled(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	sent, results := prompt(t, context.Background(), s, nf)
+	s.emitClosed(sent.ID, uint32(notify.ReasonDismissed))
+	if r := <-results; !errors.Is(r.err, notify.ErrDismissed) {
+		t.Errorf("dismissed Prompt returned %q, %v", r.text, r.err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	results2 := make(chan promptResult, 1)
+	go func() {
+		text, err := nf.Prompt(ctx, "Rename file", "New name")
+		results2 <- promptResult{text, err}
+	}()
+	if r := <-results2; !errors.Is(r.err, context.DeadlineExceeded) {
+		t.Errorf("cancelled Prompt returned %q, %v", r.text, r.err)
+	}
+	if got := s.closedIDs(); len(got) != 1 || got[0] != s.last(t).ID {
+		t.Errorf("closed %v after the cancelled prompt", got)
+	}
+}
+
+func TestPromptSecret(t *testing.T) {
+	nf, err := notify.NewNotifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.PromptSecret(context.Background(), "Password", ""); !errors.Is(err, notify.ErrInsecure) {
+		t.Errorf("PromptSecret returned %v", err)
+	}
+}
+
+type askResult struct {
+	choice notify.Choice
+	err    error
+}
+
+// ask runs Ask with a default choice of "later" after two minutes in the
+// background, and returns the notification it showed, once its timer is
+// started, and the channel receiving its result.
+func ask(t *testing.T, s *fakeServer, nf *notify.Notifier, clock *notifytest.Clock) (sentNotification, <-chan askResult) {
+	t.Helper()
+	results := make(chan askResult, 1)
+	go func() {
+		n := notify.New("test", "Updates installed", "", "", 0, notify.NormalUrgency)
+		n.AddAction("now", "Reboot now")
+		n.AddAction("later", "Later")
+		c, err := nf.Ask(context.Background(), n, notify.WithDefaultChoice("later", 2*time.Minute))
+		results <- askResult{c, err}
+	}()
+	waitFor(t, "the default choice timer", func() bool { return clock.Timers() == 1 })
+	return s.last(t), results
+}
+
+func TestAskDefaultChoice(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer func(s *fakeServer, id uint32, clock *notifytest.Clock)
+		want   notify.Choice
+		closed bool
+	}{
+		{"user first", func(s *fakeServer, id uint32, clock *notifytest.Clock) {
+			s.emitAction(id, "now")
+		}, notify.Choice{Key: "now"}, false},
+		{"timer first", func(s *fakeServer, id uint32, clock *notifytest.Clock) {
+			clock.Advance(2 * time.Minute)
+		}, notify.Choice{Key: "later", Auto: true}, true},
+		{"daemon expiry first", func(s *fakeServer, id uint32, clock *notifytest.Clock) {
+			s.emitClosed(id, uint32(notify.ReasonExpired))
+		}, notify.Choice{Key: "later", Auto: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			clock := newFakeClock()
+			nf := newTestNotifier(t, notify.WithClock(clock))
+
+			sent, results := ask(t, s, nf, clock)
+			tt.answer(s, sent.ID, clock)
+			var r askResult
+			select {
+			case r = <-results:
+			case <-waitTimeout():
+				t.Fatal("Ask did not return")
+			}
+			if r.err != nil || r.choice != tt.want {
+				t.Errorf("Ask() = %+v, %v, want %+v", r.choice, r.err, tt.want)
+			}
+			if tt.closed {
+				waitFor(t, "close", func() bool { return len(s.closedIDs()) == 1 })
+			}
+			if n := clock.Timers(); n != 0 {
+				t.Errorf("%d timers still running", n)
+			}
+		})
+	}
+}
+
+func TestAskDefaultChoiceNotAnAction(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	n := notify.New("test", "Reboot?", "", "", 0, notify.NormalUrgency)
+	n.AddAction("now", "Reboot now")
+	if _, err := nf.Ask(context.Background(), n, notify.WithDefaultChoice("later", time.Minute)); err == nil {
+		t.Error("Ask accepted a default choice that is not an action")
+	}
+	if got := s.received(); got != 0 {
+		t.Errorf("server received %d notifications", got)
+	}
+}
+
+func TestAskNotShown(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	nf.Pause()
+	n := notify.New("test", "Deploy?", "", "", 0, notify.NormalUrgency)
+	n.AddAction("yes", "Deploy")
+	if _, err := nf.Ask(context.Background(), n); !errors.Is(err, notify.ErrNotShown) {
+		t.Errorf("Ask() while paused = %v, want %v", err, notify.ErrNotShown)
+	}
+
+	var rt recordingTransport
+	other := newRecordingNotifier(t, &rt, notify.WithDedup(filepath.Join(t.TempDir(), "shown.json"), time.Minute))
+	for i := 0; i < 2; i++ {
+		n := notify.New("test", "Deploy?", "", "", 0, notify.NormalUrgency)
+		n.AddAction("yes", "Deploy")
+		_, err := other.SendAndWait(context.Background(), n, notify.WithDefaultChoice("yes", time.Millisecond))
+		if want := []error{nil, notify.ErrNotShown}[i]; !errors.Is(err, want) {
+			t.Errorf("SendAndWait() %d = %v, want %v", i, err, want)
+		}
+	}
+}
+
+// sameFunc returns true if the functions a and b are the same.
+func sameFunc(a, b any) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func TestAskRestoresCallbacks(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	events := nf.Events()
+
+	var keys []string
+	onAction := func(key string) { keys = append(keys, key) }
+	onClose := func(notify.CloseReason) {}
+	n := notify.New("test", "Deploy?", "", "", 0, notify.NormalUrgency)
+	n.AddAction("yes", "Deploy")
+	n.OnAction, n.OnClose = onAction, onClose
+	results := make(chan error, 1)
+	go func() {
+		_, err := nf.Ask(context.Background(), n)
+		results <- err
+	}()
+	waitEvent(t, events, notify.EventSent)
+	s.emitAction(s.last(t).ID, "yes")
+	select {
+	case err := <-results:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-waitTimeout():
+		t.Fatal("Ask did not return")
+	}
+	if !sameFunc(n.OnAction, onAction) || !sameFunc(n.OnClose, onClose) {
+		t.Error("Ask did not restore the callbacks of the notification")
+	}
+	if len(keys) != 1 || keys[0] != "yes" {
+		t.Errorf("OnAction got %v", keys)
+	}
+
+	nf.Pause()
+	if _, err := nf.Ask(context.Background(), n); !errors.Is(err, notify.ErrNotShown) {
+		t.Fatalf("Ask() while paused = %v", err)
+	}
+	if !sameFunc(n.OnAction, onAction) || !sameFunc(n.OnClose, onClose) {
+		t.Error("Ask did not restore the callbacks of a notification not shown")
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// resumeGrace is how long after a resume the daemon may still signal that a
+// notification re-posted by HandleResume expired.
+const resumeGrace = 5 * time.Second
+
+// wallTime returns t without its monotonic clock reading, so that durations
+// computed from it follow the wall clock, which goes on while the system is
+// suspended.
+func wallTime(t time.Time) time.Time {
+	return t.Round(0)
+}
+
+// wallTimer is a timer of a Notifier due at a wall clock time: scheduled
+// sends, the end of quiet hours, the default choice of Ask. The timers of a
+// Clock may not run while the system is suspended, so HandleResume fires or
+// resets the wall timers against the wall clock.
+type wallTimer struct {
+	nf *Notifier
+	at time.Time
+	f  func()
+	t  Timer
+}
+
+// wallTimers are the pending wall timers of a Notifier.
+type wallTimers struct {
+	mu  sync.Mutex
+	set map[*wallTimer]struct{}
+}
+
+// afterWall returns a wall timer calling f after d.
+func (nf *Notifier) afterWall(d time.Duration, f func()) *wallTimer {
+	w := &wallTimer{nf: nf, at: wallTime(nf.clock.Now()).Add(d), f: f}
+	nf.wall.mu.Lock()
+	defer nf.wall.mu.Unlock()
+	if nf.wall.set == nil {
+		nf.wall.set = make(map[*wallTimer]struct{})
+	}
+	nf.wall.set[w] = struct{}{}
+	w.t = nf.clock.AfterFunc(d, w.fire)
+	return w
+}
+
+func (w *wallTimer) fire() {
+	w.nf.wall.mu.Lock()
+	delete(w.nf.wall.set, w)
+	w.nf.wall.mu.Unlock()
+	w.f()
+}
+
+// Stop prevents w from firing, and returns false if it already fired or was
+// stopped.
+func (w *wallTimer) Stop() bool {
+	w.nf.wall.mu.Lock()
+	defer w.nf.wall.mu.Unlock()
+	delete(w.nf.wall.set, w)
+	return w.t.Stop()
+}
+
+// WithResumeHandling makes the Notifier watch logind for the system
+// resuming from suspend, and then call HandleResume. If collapse is true,
+// the scheduled sends that became due while the system was suspended are
+// summarized by a single catch-up notification, like ResumeCollapse does,
+// instead of being sent one by one.
+func WithResumeHandling(collapse bool) Option {
+	return func(nf *Notifier) error {
+		nf.resumeWatch, nf.resumeCollapse = true, collapse
+		return nil
+	}
+}
+
+// watchSleep watches the PrepareForSleep signal of logind, until nf is
+// closed.
+func (nf *Notifier) watchSleep() {
+	conn, err := nf.logind()
+	if err != nil {
+		nf.log(LevelWarn, "connecting to logind failed, not handling the resumes from suspend", err)
+		return
+	}
+	match := []dbus.MatchOption{
+		dbus.WithMatchSender(logindName),
+		dbus.WithMatchObjectPath(logindPath),
+		dbus.WithMatchInterface(logindManager),
+		dbus.WithMatchMember("PrepareForSleep"),
+	}
+	if err := conn.AddMatchSignal(match...); err != nil {
+		nf.log(LevelWarn, "watching logind failed, not handling the resumes from suspend", err)
+		return
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	go func() {
+		defer func() {
+			conn.RemoveSignal(signals)
+			conn.RemoveMatchSignal(match...)
+		}()
+		for {
+			select {
+			case <-nf.stopped:
+				return
+			case sig, ok := <-signals:
+				if !ok {
+					return
+				}
+				if sig.Name != logindManager+".PrepareForSleep" || len(sig.Body) == 0 {
+					continue
+				}
+				if start, ok := sig.Body[0].(bool); ok && !start {
+					nf.HandleResume()
+				}
+			}
+		}
+	}()
+}
+
+// HandleResume re-evaluates the timers of nf against the wall clock, as
+// they may not have run while the system was suspended. WithResumeHandling
+// calls it when logind signals a resume; programs finding out by other
+// means may call it themselves.
+//
+// The scheduled sends that are due are sent right away, see
+// WithResumeHandling to collapse them, and so are the ends of quiet hours
+// and the default choices of Ask and SendAndWait; the other timers are
+// reset to fire at their wall clock time. The notifications with callbacks
+// whose timeout ran out while the system was suspended, and that the daemon
+// did not close, are posted again with their whole timeout, as the user did
+// not see them; the daemon signalling right after the resume that they
+// expired is ignored.
+func (nf *Notifier) HandleResume() {
+	now := wallTime(nf.clock.Now())
+
+	nf.mu.Lock()
+	var due []*Scheduled
+	for s := range nf.scheduled {
+		if !wallTime(s.at).After(now) && s.timer.Stop() {
+			delete(nf.scheduled, s)
+			due = append(due, s)
+		}
+	}
+	var unseen []*Notification
+	for id, t := range nf.tracked {
+		if t.n.Timeout > 0 && !t.sent.Add(t.n.TimeoutDuration()).After(now) && nf.reposts[id] == nil {
+			unseen = append(unseen, t.n)
+			if nf.resumed == nil {
+				nf.resumed = make(map[uint32]struct{})
+			}
+			nf.resumed[id] = struct{}{}
+		}
+	}
+	collapse := nf.resumeCollapse
+	nf.mu.Unlock()
+
+	nf.wall.mu.Lock()
+	var fire []*wallTimer
+	for w := range nf.wall.set {
+		if !w.t.Stop() {
+			continue
+		}
+		if d := w.at.Sub(now); d > 0 {
+			w.t.Reset(d)
+			continue
+		}
+		delete(nf.wall.set, w)
+		fire = append(fire, w)
+	}
+	nf.wall.mu.Unlock()
+
+	nf.log(LevelInfo, fmt.Sprintf("resumed from suspend: %d scheduled sends and %d timers due, %d notifications to post again", len(due), len(fire), len(unseen)), nil)
+	sort.Slice(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+	if collapse && len(due) > 1 {
+		held := make([]*Notification, len(due))
+		for i, s := range due {
+			held[i] = s.n
+		}
+		if err := nf.sendCatchUp(held); err != nil {
+			nf.log(LevelWarn, "catch-up notification of the scheduled sends failed", err)
+		}
+	} else {
+		for _, s := range due {
+			s.send()
+		}
+	}
+	sort.Slice(fire, func(i, j int) bool { return fire[i].at.Before(fire[j].at) })
+	for _, w := range fire {
+		w.f()
+	}
+	for _, n := range unseen {
+		if _, err := nf.deliver(context.Background(), n, true); err != nil {
+			nf.log(LevelWarn, fmt.Sprintf("posting notification %d again after the resume failed", n.Id), err)
+			nf.reportError(n, err)
+		}
+	}
+	if len(unseen) > 0 {
+		nf.clock.AfterFunc(resumeGrace, func() {
+			nf.mu.Lock()
+			nf.resumed = nil
+			nf.mu.Unlock()
+		})
+	}
+}
+
+// expiredUnseen returns true if the daemon signalled that the notification
+// id expired while HandleResume posts it again. It must be called with nf.mu
+// held.
+func (nf *Notifier) expiredUnseen(id uint32, reason CloseReason) bool {
+	if _, ok := nf.resumed[id]; !ok || reason != ReasonExpired {
+		return false
+	}
+	delete(nf.resumed, id)
+	return true
+}
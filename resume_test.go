@@ -0,0 +1,180 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestHandleResumeScheduled(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+
+	for _, sc := range []struct {
+		summary string
+		after   time.Duration
+	}{{"in an hour", time.Hour}, {"in three hours", 3 * time.Hour}} {
+		if _, err := nf.SendAfter(sc.after, notify.New("test", sc.summary, "", "", 0, notify.NormalUrgency)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two hours pass while suspended: the timers did not run.
+	clock.Suspend(2 * time.Hour)
+	if got := summaries(s); len(got) != 0 {
+		t.Fatalf("sent %q during the suspend", got)
+	}
+
+	nf.HandleResume()
+	if got := summaries(s); !reflect.DeepEqual(got, []string{"in an hour"}) {
+		t.Fatalf("sent %q on resume, want the overdue notification", got)
+	}
+	// The other one is still due at three hours of wall clock time, not
+	// three hours of running time.
+	clock.Advance(time.Hour - time.Second)
+	if got := summaries(s); len(got) != 1 {
+		t.Fatalf("sent %q before the wall clock time", got)
+	}
+	clock.Advance(time.Second)
+	if got := summaries(s); len(got) != 2 || got[1] != "in three hours" {
+		t.Errorf("sent %q, want the second notification at its time", got)
+	}
+	if p := nf.PendingScheduled(); len(p) != 0 {
+		t.Errorf("pending %+v", p)
+	}
+}
+
+func TestResumeHandlingCollapse(t *testing.T) {
+	s := newFakeServer(t)
+	l := newFakeLogind(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithLogindConn(l.conn), notify.WithResumeHandling(true))
+
+	for i, summary := range []string{"backup done", "disk checked", "update ready"} {
+		n := notify.New("test", summary, "", "", 0, notify.NormalUrgency)
+		if _, err := nf.SendAfter(time.Duration(i+1)*time.Minute, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := nf.SendAfter(time.Hour, notify.New("test", "later", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	clock.Suspend(10 * time.Minute)
+	l.emitPrepareForSleep(true)
+	l.emitPrepareForSleep(false)
+	waitFor(t, "the catch-up notification", func() bool { return len(summaries(s)) == 1 })
+	if n := s.last(t); n.Summary == "backup done" || len(n.Actions) != 2 || n.Actions[0] != notify.DefaultAction {
+		t.Errorf("sent %+v, want a catch-up notification", n)
+	}
+	if p := nf.PendingScheduled(); len(p) != 1 || p[0].Summary != "later" {
+		t.Errorf("pending %+v, want only the notification due later", p)
+	}
+}
+
+func TestResumeHandlingCoreAndViews(t *testing.T) {
+	s := newFakeServer(t)
+	l := newFakeLogind(t)
+	clock := newFakeClock()
+	core, err := notify.NewCore(notify.WithBusAddress(busAddress), notify.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer core.Close()
+	nf, err := core.Notifier("backup", notify.WithLogindConn(l.conn), notify.WithResumeHandling(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+	view := nf.As("sync")
+	defer view.Close()
+
+	for _, sender := range []*notify.Notifier{nf, view} {
+		if _, err := sender.SendAfter(time.Minute, notify.New("", "due", "", "", 0, notify.NormalUrgency)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Suspend(10 * time.Minute)
+	l.emitPrepareForSleep(true)
+	l.emitPrepareForSleep(false)
+	waitFor(t, "the scheduled sends", func() bool { return len(summaries(s)) == 2 })
+	apps := map[string]bool{}
+	for _, n := range s.notifications() {
+		apps[n.AppName] = true
+	}
+	if !apps["backup"] || !apps["sync"] {
+		t.Errorf("sent by %v, want both the Notifier and its view", apps)
+	}
+}
+
+func TestHandleResumeUnseen(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	// The closes are delivered as they come, not after the settle window.
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithSettleWindow(0))
+
+	var cb callbacks
+	n := notify.New("test", "meeting soon", "", "", 10*time.Second, notify.NormalUrgency)
+	cb.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	shown := notify.New("test", "never expires", "", "", 0, notify.NormalUrgency)
+	cb.attach(shown)
+	if _, err := nf.Notify(shown); err != nil {
+		t.Fatal(err)
+	}
+	id := n.Id
+
+	clock.Suspend(time.Hour)
+	nf.HandleResume()
+	if got := summaries(s); !reflect.DeepEqual(got, []string{"meeting soon", "never expires", "meeting soon"}) {
+		t.Fatalf("sent %q, want the expired notification posted again", got)
+	}
+	if last := s.last(t); last.ReplacesID != id || last.ExpireTimeout != 10000 {
+		t.Errorf("posted again as %+v, want it replacing %d with its timeout", last, id)
+	}
+
+	// The daemon catching up with the expiry of the first post is ignored,
+	// the next close is not.
+	s.emitClosed(id, uint32(notify.ReasonExpired))
+	s.emitClosed(n.Id, uint32(notify.ReasonDismissed))
+	waitFor(t, "the close", func() bool { return len(cb.closed()) == 1 })
+	if got := cb.closed(); got[0] != notify.ReasonDismissed {
+		t.Errorf("OnClose called with %v, want only ReasonDismissed", got)
+	}
+}
+
+func TestHandleResumeDefaultChoice(t *testing.T) {
+	newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+
+	done := make(chan notify.Choice, 1)
+	go func() {
+		n := notify.New("test", "reboot?", "", "", 0, notify.NormalUrgency)
+		n.AddAction("yes", "Yes")
+		c, err := nf.Ask(context.Background(), n, notify.WithDefaultChoice("yes", time.Minute))
+		if err != nil {
+			t.Error(err)
+		}
+		done <- c
+	}()
+	waitFor(t, "the countdown", func() bool { return clock.Timers() > 0 })
+	clock.Suspend(time.Hour)
+	nf.HandleResume()
+	select {
+	case c := <-done:
+		if c.Key != "yes" || !c.Auto {
+			t.Errorf("Ask() = %+v, want the default choice", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the countdown did not end on resume")
+	}
+}
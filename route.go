@@ -0,0 +1,196 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// maxRoutes is the number of notifications whose route an UrgencyRouter
+// remembers before forgetting the oldest.
+const maxRoutes = 4096
+
+// UrgencyRouter is a Transport delivering each call to the transport of its
+// urgency, for example the low urgency ones to a log only:
+//
+//	router, err := notify.RouteByUrgency(map[notify.NotificationUrgency]notify.Transport{
+//		notify.LowUrgency:      logTransport,
+//		notify.CriticalUrgency: notify.NewMultiTransport(desktop, ntfy),
+//	}, desktop)
+//
+// RouteClass routes the calls of a category class, such as "im", before
+// their urgency.
+//
+// The transports assign IDs of their own, which may collide, so the router
+// gives the notifications IDs of its own too. A call replacing a
+// notification, and closing it, go to the transport the notification was
+// sent to, whatever its urgency now. The router remembers the last 4096
+// notifications: a call replacing an older one is sent as a new
+// notification.
+//
+// The router does not deliver signals, as the IDs of the daemon are not
+// those of the router.
+type UrgencyRouter struct {
+	routes   map[NotificationUrgency]Transport
+	fallback Transport
+	classes  []classRoute
+
+	table routeTable
+}
+
+// classRoute is the transport of the calls in a category class, see
+// RouteClass.
+type classRoute struct {
+	class string
+	t     Transport
+}
+
+// route is the transport a notification was sent to, and the ID that
+// transport gave it.
+type route struct {
+	t  Transport
+	id uint32
+}
+
+// routeTable gives IDs of their own to the notifications a Transport sends
+// to other transports, and remembers the routes of the last maxRoutes.
+type routeTable struct {
+	mu     sync.Mutex
+	lastID uint32
+	sent   map[uint32]route
+	// order holds the IDs of sent, the oldest first.
+	order []uint32
+}
+
+// lookup returns the route of the notification id.
+func (rt *routeTable) lookup(id uint32) (route, bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r, ok := rt.sent[id]
+	return r, ok
+}
+
+// add records r as the route of the notification id, and returns id, or a
+// new ID if id is not known.
+func (rt *routeTable) add(id uint32, r route) uint32 {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if _, ok := rt.sent[id]; !ok {
+		rt.lastID++
+		id = rt.lastID
+	}
+	rt.put(id, r)
+	return id
+}
+
+// set records r as the route of the notification id, an ID chosen by the
+// caller.
+func (rt *routeTable) set(id uint32, r route) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.put(id, r)
+}
+
+// put records r as the route of id. It must be called with rt.mu held.
+func (rt *routeTable) put(id uint32, r route) {
+	if _, ok := rt.sent[id]; !ok {
+		if rt.sent == nil {
+			rt.sent = make(map[uint32]route)
+		}
+		rt.order = append(rt.order, id)
+		if len(rt.order) > maxRoutes {
+			delete(rt.sent, rt.order[0])
+			rt.order = rt.order[1:]
+		}
+	}
+	rt.sent[id] = r
+}
+
+// remove forgets the notification id, and returns its route.
+func (rt *routeTable) remove(id uint32) (route, bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r, ok := rt.sent[id]
+	delete(rt.sent, id)
+	return r, ok
+}
+
+// RouteByUrgency returns an UrgencyRouter delivering the calls to the
+// transport of their urgency in routes, or to fallback for the urgencies
+// missing from routes. It fails if an urgency has neither.
+func RouteByUrgency(routes map[NotificationUrgency]Transport, fallback Transport) (*UrgencyRouter, error) {
+	r := &UrgencyRouter{routes: make(map[NotificationUrgency]Transport, len(routes)), fallback: fallback}
+	for u, t := range routes {
+		if t == nil {
+			return nil, fmt.Errorf("notify: nil transport for the urgency %v", u)
+		}
+		r.routes[u] = t
+	}
+	if fallback == nil {
+		for _, u := range []NotificationUrgency{LowUrgency, NormalUrgency, CriticalUrgency} {
+			if r.routes[u] == nil {
+				return nil, fmt.Errorf("notify: no transport for the urgency %v, and no fallback", u)
+			}
+		}
+	}
+	return r, nil
+}
+
+// RouteClass makes r deliver the calls whose "category" hint is in class,
+// see InClass, to t, whatever their urgency. The classes are tried in the
+// order they were added, before the urgencies. RouteClass must be called
+// before r delivers calls.
+func (r *UrgencyRouter) RouteClass(class string, t Transport) error {
+	if t == nil {
+		return fmt.Errorf("notify: nil transport for the class %q", class)
+	}
+	r.classes = append(r.classes, classRoute{class, t})
+	return nil
+}
+
+// transport returns the transport of c.
+func (r *UrgencyRouter) transport(c Call) Transport {
+	if len(r.classes) > 0 {
+		category, _ := c.Hints["category"].Value().(string)
+		for _, cr := range r.classes {
+			if category != "" && InClass(category, cr.class) {
+				return cr.t
+			}
+		}
+	}
+	if t, ok := r.routes[c.Urgency]; ok {
+		return t
+	}
+	return r.fallback
+}
+
+func (r *UrgencyRouter) Notify(ctx context.Context, c Call) (uint32, error) {
+	id := c.ReplacesID
+	prev, replacing := r.table.lookup(id)
+	t := r.transport(c)
+	c.ReplacesID = 0
+	if replacing {
+		t, c.ReplacesID = prev.t, prev.id
+	}
+	if t == nil {
+		return 0, errors.New("notify: no transport for the urgency " + c.Urgency.String())
+	}
+	inner, err := t.Notify(ctx, c)
+	if err != nil {
+		return 0, err
+	}
+	return r.table.add(id, route{t, inner}), nil
+}
+
+func (r *UrgencyRouter) CloseNotification(id uint32) error {
+	rt, ok := r.table.remove(id)
+	if !ok {
+		return nil
+	}
+	return rt.t.CloseNotification(rt.id)
+}
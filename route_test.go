@@ -0,0 +1,94 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestRouteByUrgency(t *testing.T) {
+	var logs, desktop, ntfy recordingTransport
+	router, err := notify.RouteByUrgency(map[notify.NotificationUrgency]notify.Transport{
+		notify.LowUrgency:      &logs,
+		notify.CriticalUrgency: notify.NewMultiTransport(&desktop, &ntfy),
+	}, &desktop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nf, err := notify.NewNotifier(notify.WithTransport(router), notify.WithBusAddress("unix:path=/nonexistent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	send := func(n *notify.Notification) {
+		t.Helper()
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	low := notify.New("test", "low", "", "", time.Second, notify.LowUrgency)
+	normal := notify.New("test", "normal", "", "", time.Second, notify.NormalUrgency)
+	critical := notify.New("test", "critical", "", "", time.Second, notify.CriticalUrgency)
+	send(low)
+	send(normal)
+	send(critical)
+	if len(logs.calls) != 1 || logs.calls[0].Summary != "low" {
+		t.Errorf("log transport got %+v", logs.calls)
+	}
+	if len(desktop.calls) != 2 || desktop.calls[0].Summary != "normal" || desktop.calls[1].Summary != "critical" {
+		t.Errorf("desktop transport got %+v", desktop.calls)
+	}
+	if len(ntfy.calls) != 1 || ntfy.calls[0].Summary != "critical" {
+		t.Errorf("ntfy transport got %+v", ntfy.calls)
+	}
+	// The transports gave the IDs 1, 1 and 2: the router does not.
+	if low.Id == normal.Id || normal.Id == critical.Id || low.Id == critical.Id {
+		t.Errorf("IDs %d, %d and %d collide", low.Id, normal.Id, critical.Id)
+	}
+
+	// Replacing follows the first route, with the ID of its transport,
+	// even if the urgency changed.
+	id := low.Id
+	low.Summary, low.Urgency = "low, now critical", notify.CriticalUrgency
+	send(low)
+	if low.Id != id {
+		t.Errorf("replacing gave the ID %d, want %d", low.Id, id)
+	}
+	if len(logs.calls) != 2 || logs.calls[1].Summary != "low, now critical" || logs.calls[1].ReplacesID != 1 {
+		t.Errorf("log transport got %+v", logs.calls)
+	}
+	if len(desktop.calls) != 2 || len(ntfy.calls) != 1 {
+		t.Errorf("a replace went to another route: desktop %d calls, ntfy %d", len(desktop.calls), len(ntfy.calls))
+	}
+
+	if err := nf.CloseNotification(critical.Id); err != nil {
+		t.Fatal(err)
+	}
+	if len(desktop.closed) != 1 || desktop.closed[0] != 2 || len(ntfy.closed) != 1 || len(logs.closed) != 0 {
+		t.Errorf("closed %v on desktop, %v on ntfy and %v on the log", desktop.closed, ntfy.closed, logs.closed)
+	}
+}
+
+func TestRouteByUrgencyInvalid(t *testing.T) {
+	var rt recordingTransport
+	if _, err := notify.RouteByUrgency(map[notify.NotificationUrgency]notify.Transport{
+		notify.LowUrgency:    &rt,
+		notify.NormalUrgency: &rt,
+	}, nil); err == nil {
+		t.Error("RouteByUrgency without the critical urgency nor a fallback succeeded")
+	}
+	if _, err := notify.RouteByUrgency(map[notify.NotificationUrgency]notify.Transport{
+		notify.LowUrgency: nil,
+	}, &rt); err == nil {
+		t.Error("RouteByUrgency with a nil transport succeeded")
+	}
+	if _, err := notify.RouteByUrgency(nil, &rt); err != nil {
+		t.Errorf("RouteByUrgency with only a fallback = %v", err)
+	}
+}
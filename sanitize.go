@@ -0,0 +1,99 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ControlPolicy decides what happens to the bidi control and zero-width
+// characters of summaries and bodies, see WithControlSanitizer.
+type ControlPolicy int
+
+const (
+	// KeepControls sends the characters as they are.
+	KeepControls ControlPolicy = iota
+	// StripControls removes them.
+	StripControls
+	// EscapeControls replaces them by their code point, like "[U+202E]",
+	// so that they show.
+	EscapeControls
+)
+
+// SanitizedChar is a character removed or escaped by the sanitizer, see
+// WithControlSanitizer.
+type SanitizedChar struct {
+	// Field is "summary" or "body".
+	Field string
+	// Char is the character.
+	Char rune
+	// Offset is the byte offset of Char in the original text.
+	Offset int
+}
+
+func (c SanitizedChar) String() string {
+	return fmt.Sprintf("%s: U+%04X at %d", c.Field, c.Char, c.Offset)
+}
+
+// WithControlSanitizer makes the Notifier strip or escape, following p, the
+// characters that can make a summary or body show something else than it
+// says, like the right-to-left override of "invoice_gpj.exe": the bidi
+// embeddings, overrides and isolates (U+202A to U+202E and U+2066 to U+2069)
+// and the zero-width space, word joiner and byte order mark.
+//
+// Right-to-left text, and the zero-width joiner and non-joiner that emoji
+// sequences and some scripts need, are left alone. The characters found are
+// reported in SendResult.Sanitized and logged. The default is KeepControls.
+func WithControlSanitizer(p ControlPolicy) Option {
+	return func(nf *Notifier) error {
+		if p < KeepControls || p > EscapeControls {
+			return fmt.Errorf("notify: invalid control policy %d", p)
+		}
+		nf.controlPolicy = p
+		return nil
+	}
+}
+
+// isSpoofingControl returns true if r is one of the characters handled by
+// WithControlSanitizer.
+func isSpoofingControl(r rune) bool {
+	switch {
+	case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return r == '\u200b' || r == '\u2060' || r == '\ufeff'
+}
+
+// sanitizeControls returns s with the characters handled by
+// WithControlSanitizer stripped or escaped following p, and appends them to
+// found.
+func sanitizeControls(field, s string, p ControlPolicy, found []SanitizedChar) (string, []SanitizedChar) {
+	if p == KeepControls || strings.IndexFunc(s, isSpoofingControl) < 0 {
+		return s, found
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i, r := range s {
+		if !isSpoofingControl(r) {
+			b.WriteRune(r)
+			continue
+		}
+		found = append(found, SanitizedChar{field, r, i})
+		if p == EscapeControls {
+			fmt.Fprintf(&b, "[U+%04X]", r)
+		}
+	}
+	return b.String(), found
+}
+
+// sanitize returns the summary and body with the characters handled by
+// WithControlSanitizer stripped or escaped, and those characters.
+func (nf *Notifier) sanitize(summary, body string) (string, string, []SanitizedChar) {
+	var found []SanitizedChar
+	summary, found = sanitizeControls("summary", summary, nf.controlPolicy, found)
+	body, found = sanitizeControls("body", body, nf.controlPolicy, found)
+	return summary, body, found
+}
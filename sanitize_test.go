@@ -0,0 +1,87 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestControlSanitizer(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        notify.ControlPolicy
+		summary, body string
+		wantSummary   string
+		wantBody      string
+		sanitized     []notify.SanitizedChar
+	}{
+		{"override stripped", notify.StripControls,
+			"invoice_\u202egpj.exe", "", "invoice_gpj.exe", "",
+			[]notify.SanitizedChar{{"summary", '\u202e', 8}}},
+		{"override escaped", notify.EscapeControls,
+			"invoice_\u202egpj.exe", "", "invoice_[U+202E]gpj.exe", "", []notify.SanitizedChar{{"summary", '\u202e', 8}}},
+		{"kept by default", notify.KeepControls,
+			"invoice_\u202egpj.exe", "", "invoice_\u202egpj.exe", "", nil},
+		{"isolates and zero-width in body", notify.StripControls,
+			"from \u2067דנה\u2069", "pay\u200bpal\ufeff", "from דנה", "paypal",
+			[]notify.SanitizedChar{
+				{"summary", '\u2067', 5}, {"summary", '\u2069', 14},
+				{"body", '\u200b', 3}, {"body", '\ufeff', 9},
+			}},
+		{"Hebrew untouched", notify.StripControls,
+			"שלום עולם", "הודעה חדשה", "שלום עולם", "הודעה חדשה", nil},
+		{"Arabic untouched", notify.StripControls,
+			"مرحبا بالعالم", "رسالة جديدة", "مرحبا بالعالم", "رسالة جديدة", nil},
+		{"mixed direction untouched", notify.StripControls,
+			"Dana: שלום, build 42 passed", "ملف report.pdf جاهز", "Dana: שלום, build 42 passed", "ملف report.pdf جاهز", nil},
+		{"joiners untouched", notify.StripControls,
+			"family \U0001F468\u200d\U0001F469\u200d\U0001F467", "می\u200cخواهم",
+			"family \U0001F468\u200d\U0001F469\u200d\U0001F467", "می\u200cخواهم", nil},
+	}
+	newFakeServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nf := newTestNotifier(t, notify.WithControlSanitizer(tt.policy))
+			c, err := notify.New("test", tt.summary, tt.body, "", 0, notify.NormalUrgency).DryRun(nf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Summary != tt.wantSummary || c.Body != tt.wantBody {
+				t.Errorf("sent %q, %q, want %q, %q", c.Summary, c.Body, tt.wantSummary, tt.wantBody)
+			}
+			if !reflect.DeepEqual(c.Sanitized, tt.sanitized) {
+				t.Errorf("Sanitized = %v, want %v", c.Sanitized, tt.sanitized)
+			}
+		})
+	}
+}
+
+func TestControlSanitizerReport(t *testing.T) {
+	s := newFakeServer(t)
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithControlSanitizer(notify.StripControls))
+	nf.SetLogger(logs.log)
+
+	res, err := nf.Notify(notify.New("test", "invoice_\u202egpj.exe", "", "", 0, notify.NormalUrgency))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []notify.SanitizedChar{{"summary", '\u202e', 8}}; !reflect.DeepEqual(res.Sanitized, want) {
+		t.Errorf("SendResult.Sanitized = %v, want %v", res.Sanitized, want)
+	}
+	if got := s.last(t).Summary; got != "invoice_gpj.exe" {
+		t.Errorf("daemon got %q", got)
+	}
+	if !logs.logged("U+202E") {
+		t.Error("the sanitized character was not logged")
+	}
+
+	if _, err := notify.NewNotifier(notify.WithControlSanitizer(notify.ControlPolicy(7))); err == nil {
+		t.Error("invalid control policy accepted")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import "image"
+
+// DefaultImageMaxDimension is the largest width and height of the images
+// embedded by SetImage, unless ImageMaxDimension says otherwise.
+const DefaultImageMaxDimension = 128
+
+// ImageQuality is how images are resampled when they are downscaled.
+type ImageQuality int
+
+const (
+	// ImageNearest picks the nearest pixel: fast, but blocky.
+	ImageNearest ImageQuality = iota
+	// ImageBilinear interpolates between the four nearest pixels.
+	ImageBilinear
+)
+
+// imageOptions holds the settings of SetImage.
+type imageOptions struct {
+	maxDimension int
+	quality      ImageQuality
+	forceOpaque  bool
+}
+
+// ImageOption configures how SetImage embeds an image.
+type ImageOption func(*imageOptions)
+
+// ImageMaxDimension downscales images to at most limit pixels on each side,
+// keeping their aspect ratio. If limit is 0 or less, images are embedded at
+// their size.
+func ImageMaxDimension(limit int) ImageOption {
+	return func(o *imageOptions) { o.maxDimension = limit }
+}
+
+// ImageResampling sets how images are downscaled. The default is
+// ImageNearest.
+func ImageResampling(q ImageQuality) ImageOption {
+	return func(o *imageOptions) { o.quality = q }
+}
+
+// ImageForceOpaque drops the alpha channel of images, for daemons that
+// mishandle it. It is done for those with the OpaqueImages quirk anyway.
+func ImageForceOpaque() ImageOption {
+	return func(o *imageOptions) { o.forceOpaque = true }
+}
+
+// scaledSize returns the size of a w×h image scaled down to fit in a
+// limit×limit square, or w and h if it fits or limit is 0 or less.
+func scaledSize(w, h, limit int) (int, int) {
+	if limit <= 0 || w <= limit && h <= limit {
+		return w, h
+	}
+	if w >= h {
+		return limit, max(1, h*limit/w)
+	}
+	return max(1, w*limit/h), limit
+}
+
+// scale returns src resized to w×h.
+func scale(src *image.NRGBA, w, h int, q ImageQuality) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			d := dst.Pix[y*dst.Stride+x*4 : y*dst.Stride+x*4+4]
+			if q == ImageNearest {
+				sx, sy := (2*x+1)*sw/(2*w), (2*y+1)*sh/(2*h)
+				copy(d, src.Pix[sy*src.Stride+sx*4:])
+				continue
+			}
+
+			// Sample at the center of the destination pixel, in 8-bit
+			// fixed point.
+			fx := ((2*x+1)*sw*256/(2*w) - 128)
+			fy := ((2*y+1)*sh*256/(2*h) - 128)
+			x0, y0 := clamp(fx>>8, sw-1), clamp(fy>>8, sh-1)
+			x1, y1 := clamp(x0+1, sw-1), clamp(y0+1, sh-1)
+			ax, ay := clamp(fx-x0<<8, 256), clamp(fy-y0<<8, 256)
+			p00 := src.Pix[y0*src.Stride+x0*4:]
+			p10 := src.Pix[y0*src.Stride+x1*4:]
+			p01 := src.Pix[y1*src.Stride+x0*4:]
+			p11 := src.Pix[y1*src.Stride+x1*4:]
+			for c := 0; c < 4; c++ {
+				top := int(p00[c])*(256-ax) + int(p10[c])*ax
+				bottom := int(p01[c])*(256-ax) + int(p11[c])*ax
+				d[c] = byte((top*(256-ay) + bottom*ay + 1<<15) >> 16)
+			}
+		}
+	}
+	return dst
+}
+
+// clamp returns v limited to [0, hi].
+func clamp(v, hi int) int {
+	return min(max(v, 0), hi)
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SchedulePolicy decides what happens when a notification is scheduled
+// while another one with the same Tag is still pending.
+type SchedulePolicy int
+
+const (
+	// ScheduleReplace cancels the pending send in favor of the new one.
+	ScheduleReplace SchedulePolicy = iota
+	// ScheduleKeep keeps the pending send and drops the new one.
+	ScheduleKeep
+	// ScheduleError keeps the pending send and returns ErrAlreadyScheduled.
+	ScheduleError
+)
+
+// ErrAlreadyScheduled is returned by SendAt and SendAfter with the
+// ScheduleError policy, when a send with the same Tag is pending.
+var ErrAlreadyScheduled = errors.New("notify: a notification with this tag is already scheduled")
+
+// WithSchedulePolicy sets what SendAt and SendAfter do with a notification
+// whose Tag is already scheduled. The default is ScheduleReplace.
+func WithSchedulePolicy(p SchedulePolicy) Option {
+	return func(nf *Notifier) error {
+		if p < ScheduleReplace || p > ScheduleError {
+			return fmt.Errorf("notify: invalid schedule policy %d", p)
+		}
+		nf.schedulePolicy = p
+		return nil
+	}
+}
+
+// Scheduled is a notification that will be sent later, see SendAt.
+type Scheduled struct {
+	nf    *Notifier
+	n     *Notification
+	at    time.Time
+	timer *wallTimer
+	// scope is the scope sending n, if any, see Scope.SendAt.
+	scope *Scope
+}
+
+// ScheduledInfo describes a pending scheduled send.
+type ScheduledInfo struct {
+	Tag     string
+	Summary string
+	At      time.Time
+}
+
+// SendAt sends n at the time at, or right away if at is in the past. The
+// send uses a copy of n taken by SendAt, so n.Id is not updated.
+//
+// If n has a Tag and a send with the same Tag is pending, the schedule
+// policy of nf decides which one is kept. With ScheduleKeep, the pending
+// send is returned.
+func (nf *Notifier) SendAt(at time.Time, n *Notification) (*Scheduled, error) {
+	cp := *n
+	s := &Scheduled{nf: nf, n: &cp, at: at}
+
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if n.Tag != "" {
+		for old := range nf.scheduled {
+			if old.n.Tag != n.Tag {
+				continue
+			}
+			switch nf.schedulePolicy {
+			case ScheduleKeep:
+				return old, nil
+			case ScheduleError:
+				return nil, ErrAlreadyScheduled
+			}
+			old.timer.Stop()
+			delete(nf.scheduled, old)
+		}
+	}
+	if nf.scheduled == nil {
+		nf.scheduled = make(map[*Scheduled]struct{})
+	}
+	nf.scheduled[s] = struct{}{}
+	s.timer = nf.afterWall(at.Sub(nf.clock.Now()), s.fire)
+	return s, nil
+}
+
+// SendAfter sends n after d, see SendAt.
+func (nf *Notifier) SendAfter(d time.Duration, n *Notification) (*Scheduled, error) {
+	return nf.SendAt(nf.clock.Now().Add(d), n)
+}
+
+// Cancel cancels the send, and returns false if it already happened or was
+// cancelled.
+func (s *Scheduled) Cancel() bool {
+	s.nf.mu.Lock()
+	defer s.nf.mu.Unlock()
+	if _, ok := s.nf.scheduled[s]; !ok {
+		return false
+	}
+	delete(s.nf.scheduled, s)
+	s.timer.Stop()
+	return true
+}
+
+// fire sends the notification if the send is still pending.
+func (s *Scheduled) fire() {
+	s.nf.mu.Lock()
+	_, ok := s.nf.scheduled[s]
+	delete(s.nf.scheduled, s)
+	s.nf.mu.Unlock()
+
+	if ok {
+		s.send()
+	}
+}
+
+// send sends the notification of s.
+func (s *Scheduled) send() {
+	notify := s.nf.Notify
+	if s.scope != nil {
+		notify = s.scope.Notify
+	}
+	if _, err := notify(s.n); err != nil {
+		summary, _ := s.nf.redact(s.n.Summary, "")
+		s.nf.log(LevelWarn, fmt.Sprintf("scheduled notification %q failed", summary), err)
+	}
+}
+
+// PendingScheduled returns the pending scheduled sends of nf, the earliest
+// first.
+func (nf *Notifier) PendingScheduled() []ScheduledInfo {
+	nf.mu.Lock()
+	infos := make([]ScheduledInfo, 0, len(nf.scheduled))
+	for s := range nf.scheduled {
+		infos = append(infos, ScheduledInfo{Tag: s.n.Tag, Summary: s.n.Summary, At: s.at})
+	}
+	nf.mu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].At.Before(infos[j].At) })
+	return infos
+}
+
+// stopSchedules cancels the pending scheduled sends. It must be called with
+// nf.mu held.
+func (nf *Notifier) stopSchedules() {
+	for s := range nf.scheduled {
+		s.timer.Stop()
+		delete(nf.scheduled, s)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func batteryLow(summary string) *notify.Notification {
+	n := notify.New("test", summary, "", "", time.Second, notify.CriticalUrgency)
+	n.Tag = "battery"
+	return n
+}
+
+func summaries(s *fakeServer) []string {
+	var got []string
+	for _, n := range s.notifications() {
+		got = append(got, n.Summary)
+	}
+	return got
+}
+
+func TestSendAfter(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+
+	if _, err := nf.SendAfter(time.Minute, notify.New("test", "later", "", "", time.Second, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	cancelled, err := nf.SendAfter(time.Minute, notify.New("test", "never", "", "", time.Second, notify.NormalUrgency))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cancelled.Cancel() || cancelled.Cancel() {
+		t.Error("Cancel did not report the pending send once")
+	}
+
+	clock.Advance(59 * time.Second)
+	if got := summaries(s); len(got) != 0 {
+		t.Fatalf("sent %q early", got)
+	}
+	clock.Advance(time.Second)
+	if got := summaries(s); len(got) != 1 || got[0] != "later" {
+		t.Fatalf("sent %q", got)
+	}
+	if p := nf.PendingScheduled(); len(p) != 0 {
+		t.Errorf("still pending: %+v", p)
+	}
+}
+
+func TestSchedulePolicies(t *testing.T) {
+	tests := []struct {
+		policy  notify.SchedulePolicy
+		wantErr error
+		want    string
+	}{
+		{notify.ScheduleReplace, nil, "second"},
+		{notify.ScheduleKeep, nil, "first"},
+		{notify.ScheduleError, notify.ErrAlreadyScheduled, "first"},
+	}
+	for _, tt := range tests {
+		s := newFakeServer(t)
+		clock := newFakeClock()
+		nf := newTestNotifier(t, notify.WithClock(clock), notify.WithSchedulePolicy(tt.policy))
+
+		first, err := nf.SendAfter(time.Minute, batteryLow("first"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := nf.SendAfter(2*time.Minute, batteryLow("second"))
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("policy %d: second SendAfter returned %v, want %v", tt.policy, err, tt.wantErr)
+		}
+		if tt.policy == notify.ScheduleKeep && second != first {
+			t.Errorf("policy %d: the pending send was not returned", tt.policy)
+		}
+
+		pending := nf.PendingScheduled()
+		if len(pending) != 1 || pending[0].Summary != tt.want || pending[0].Tag != "battery" {
+			t.Errorf("policy %d: pending %+v", tt.policy, pending)
+		}
+
+		clock.Advance(time.Hour)
+		if got := summaries(s); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("policy %d: sent %q, want %q", tt.policy, got, tt.want)
+		}
+		nf.Close()
+		s.conn.Close()
+	}
+}
+
+func TestPendingScheduledOrder(t *testing.T) {
+	requireBus(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+
+	start := clock.Now()
+	for _, d := range []time.Duration{3 * time.Minute, time.Minute, 2 * time.Minute} {
+		if _, err := nf.SendAfter(d, notify.New("test", d.String(), "", "", 0, notify.NormalUrgency)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending := nf.PendingScheduled()
+	for i, p := range pending {
+		if want := start.Add(time.Duration(i+1) * time.Minute); !p.At.Equal(want) {
+			t.Errorf("pending[%d] at %v, want %v", i, p.At, want)
+		}
+	}
+
+	nf.Close()
+	if p := nf.PendingScheduled(); len(p) != 0 {
+		t.Errorf("Close left %d scheduled sends", len(p))
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrScopeClosed is returned by the sends of a closed Scope.
+var ErrScopeClosed = errors.New("notify: scope closed")
+
+// Scope is a lifetime for notifications: closing it closes the
+// notifications sent through it that are still shown, and cancels its
+// pending scheduled sends and the sends using its Context. Create one with
+// Notifier.Scope, and close it on every return path:
+//
+//	scope := notifier.Scope()
+//	defer scope.Close()
+//	scope.Notify(notify.New("backup", "Backing up…", "", "", 0, notify.NormalUrgency))
+//
+// Scopes nest, see Scope.Scope, and closing the Notifier closes its scopes.
+type Scope struct {
+	nf     *Notifier
+	parent *Scope
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// The fields below are guarded by nf.mu.
+	closed bool
+	// open holds the notifications sent through the scope and not closed
+	// yet, with their ID.
+	open      map[*Notification]uint32
+	scheduled map[*Scheduled]struct{}
+	children  map[*Scope]struct{}
+}
+
+// Scope returns a new scope sending with nf.
+func (nf *Notifier) Scope() *Scope {
+	s := newScope(nf, nil)
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if nf.scopes == nil {
+		nf.scopes = make(map[*Scope]struct{})
+	}
+	nf.scopes[s] = struct{}{}
+	return s
+}
+
+func newScope(nf *Notifier, parent *Scope) *Scope {
+	ctx := context.Background()
+	if parent != nil {
+		ctx = parent.ctx
+	}
+	s := &Scope{nf: nf, parent: parent, open: make(map[*Notification]uint32)}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	return s
+}
+
+// Scope returns a new scope nested in s: it is closed by s.Close, before
+// the notifications of s. If s is closed, so is the new scope.
+func (s *Scope) Scope() *Scope {
+	child := newScope(s.nf, s)
+	s.nf.mu.Lock()
+	defer s.nf.mu.Unlock()
+	if s.closed {
+		child.closed = true
+		return child
+	}
+	if s.children == nil {
+		s.children = make(map[*Scope]struct{})
+	}
+	s.children[child] = struct{}{}
+	return child
+}
+
+// Context returns a context done once s is closed, for the sends that take
+// one, like SendPaginated.
+func (s *Scope) Context() context.Context {
+	return s.ctx
+}
+
+// Notify sends n like Notifier.Notify, and closes it with s.
+func (s *Scope) Notify(n *Notification) (SendResult, error) {
+	return s.SendContext(context.Background(), n)
+}
+
+// SendContext sends n like Notifier.SendContext, and closes it with s. The
+// send is cancelled if s is closed meanwhile.
+func (s *Scope) SendContext(ctx context.Context, n *Notification) (SendResult, error) {
+	if err := s.join(n); err != nil {
+		return SendResult{}, err
+	}
+	ctx, stop := mergeContext(ctx, s.ctx)
+	defer stop()
+	return s.nf.SendContext(ctx, n)
+}
+
+// SendPaginated shows the pages of n like Notifier.SendPaginated, until s
+// is closed.
+func (s *Scope) SendPaginated(ctx context.Context, n *Notification, pages []string, interval time.Duration) error {
+	if err := s.join(n); err != nil {
+		return err
+	}
+	ctx, stop := mergeContext(ctx, s.ctx)
+	defer stop()
+	return s.nf.SendPaginated(ctx, n, pages, interval)
+}
+
+// SendAt sends n at the time at like Notifier.SendAt, unless s is closed
+// first. The notification sent is closed with s.
+func (s *Scope) SendAt(at time.Time, n *Notification) (*Scheduled, error) {
+	if err := s.join(nil); err != nil {
+		return nil, err
+	}
+	sch, err := s.nf.SendAt(at, n)
+	if err != nil {
+		return nil, err
+	}
+	nf := s.nf
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if _, pending := nf.scheduled[sch]; !pending || sch.scope != nil {
+		// Sent already, or kept by ScheduleKeep from another scope.
+		return sch, nil
+	}
+	if s.closed {
+		sch.timer.Stop()
+		delete(nf.scheduled, sch)
+		return nil, ErrScopeClosed
+	}
+	sch.scope = s
+	if s.scheduled == nil {
+		s.scheduled = make(map[*Scheduled]struct{})
+	}
+	s.scheduled[sch] = struct{}{}
+	return sch, nil
+}
+
+// SendAfter sends n after d, see SendAt.
+func (s *Scope) SendAfter(d time.Duration, n *Notification) (*Scheduled, error) {
+	return s.SendAt(s.nf.clock.Now().Add(d), n)
+}
+
+// join makes s close n, if not nil, once sent, or returns ErrScopeClosed.
+func (s *Scope) join(n *Notification) error {
+	nf := s.nf
+	// The closes by the user are forgotten, so listen to the signals to
+	// find out.
+	nf.listenAlways()
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if s.closed {
+		return ErrScopeClosed
+	}
+	if n != nil {
+		if nf.scoped == nil {
+			nf.scoped = make(map[*Notification]*Scope)
+		}
+		nf.scoped[n] = s
+	}
+	return nil
+}
+
+// Close closes the nested scopes, cancels the pending scheduled sends of s,
+// and closes the notifications sent through s that were not closed yet,
+// for example by the user. It returns the first error, and closing s again
+// does nothing.
+func (s *Scope) Close() error {
+	nf := s.nf
+	nf.mu.Lock()
+	if s.closed {
+		nf.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	if s.parent != nil {
+		delete(s.parent.children, s)
+	} else {
+		delete(nf.scopes, s)
+	}
+	children := make([]*Scope, 0, len(s.children))
+	for child := range s.children {
+		children = append(children, child)
+	}
+	for sch := range s.scheduled {
+		if _, pending := nf.scheduled[sch]; pending {
+			sch.timer.Stop()
+			delete(nf.scheduled, sch)
+		}
+	}
+	s.scheduled = nil
+	nf.mu.Unlock()
+	s.cancel()
+
+	var err error
+	for _, child := range children {
+		if cerr := child.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	nf.mu.Lock()
+	open := make([]*Notification, 0, len(s.open))
+	for n, id := range s.open {
+		open = append(open, n)
+		if nf.scopedIDs[id] == n {
+			delete(nf.scopedIDs, id)
+		}
+	}
+	for n, owner := range nf.scoped {
+		if owner == s {
+			delete(nf.scoped, n)
+		}
+	}
+	s.open = nil
+	nf.mu.Unlock()
+	for _, n := range open {
+		if derr := nf.Dismiss(n); err == nil {
+			err = derr
+		}
+	}
+	return err
+}
+
+// recordScoped records that n, sent replacing the notification replaced,
+// got the ID id, if it was sent through a scope.
+func (nf *Notifier) recordScoped(n *Notification, replaced, id uint32) {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	s, ok := nf.scoped[n]
+	if !ok || s.closed {
+		return
+	}
+	if replaced != 0 && replaced != id && nf.scopedIDs[replaced] == n {
+		delete(nf.scopedIDs, replaced)
+	}
+	if nf.scopedIDs == nil {
+		nf.scopedIDs = make(map[uint32]*Notification)
+	}
+	nf.scopedIDs[id] = n
+	s.open[n] = id
+}
+
+// leaveScope forgets the notification id, closed, from its scope. It must be
+// called with nf.mu held.
+func (nf *Notifier) leaveScope(id uint32) {
+	n, ok := nf.scopedIDs[id]
+	if !ok {
+		return
+	}
+	delete(nf.scopedIDs, id)
+	if s := nf.scoped[n]; s != nil && s.open[n] == id {
+		delete(s.open, n)
+	}
+}
+
+// closeScopes closes the scopes of nf, and returns the first error.
+func (nf *Notifier) closeScopes() error {
+	nf.mu.Lock()
+	scopes := make([]*Scope, 0, len(nf.scopes))
+	for s := range nf.scopes {
+		scopes = append(scopes, s)
+	}
+	nf.mu.Unlock()
+	var err error
+	for _, s := range scopes {
+		if serr := s.Close(); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// mergeContext returns a context done when ctx or scope is, and the
+// function releasing it.
+func mergeContext(ctx, scope context.Context) (context.Context, func()) {
+	merged, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(scope, cancel)
+	return merged, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// sendScoped sends a new notification called summary through s.
+func sendScoped(t *testing.T, s *notify.Scope, summary string) *notify.Notification {
+	t.Helper()
+	n := notify.New("", summary, "", "", 0, notify.NormalUrgency)
+	if _, err := s.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// sortedIDs returns ids sorted.
+func sortedIDs(ids ...uint32) []uint32 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func TestScopeClose(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	scope := nf.Scope()
+	a := sendScoped(t, scope, "a")
+	b := sendScoped(t, scope, "b")
+	other := notify.New("", "other", "", "", 0, notify.NormalUrgency)
+	if _, err := nf.Notify(other); err != nil {
+		t.Fatal(err)
+	}
+	// Replacing a keeps a single notification to close.
+	a.Summary = "a again"
+	if _, err := scope.Notify(a); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scope.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sortedIDs(s.closedIDs()...), sortedIDs(a.Id, b.Id); !reflect.DeepEqual(got, want) {
+		t.Errorf("closed %v, want %v", got, want)
+	}
+	select {
+	case <-scope.Context().Done():
+	default:
+		t.Error("the context of the closed scope is not done")
+	}
+
+	if err := scope.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	if got := len(s.closedIDs()); got != 2 {
+		t.Errorf("second Close() closed %d more notifications", got-2)
+	}
+	if _, err := scope.Notify(notify.New("", "late", "", "", 0, notify.NormalUrgency)); !errors.Is(err, notify.ErrScopeClosed) {
+		t.Errorf("Notify() after Close() = %v, want ErrScopeClosed", err)
+	}
+}
+
+func TestScopeUserDismissed(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	scope := nf.Scope()
+	dismissed := notify.New("", "dismissed", "", "", 0, notify.NormalUrgency)
+	closed := make(chan struct{})
+	dismissed.OnClose = func(notify.CloseReason) { close(closed) }
+	if _, err := scope.Notify(dismissed); err != nil {
+		t.Fatal(err)
+	}
+	shown := sendScoped(t, scope, "shown")
+	s.emitClosed(dismissed.Id, uint32(notify.ReasonDismissed))
+	select {
+	case <-closed:
+	case <-waitTimeout():
+		t.Fatal("OnClose was not called")
+	}
+
+	if err := scope.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.closedIDs(), []uint32{shown.Id}; !reflect.DeepEqual(got, want) {
+		t.Errorf("closed %v, want only the notification still shown %v", got, want)
+	}
+}
+
+func TestScopeNested(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	outer := nf.Scope()
+	inner := outer.Scope()
+	first := sendScoped(t, inner, "inner")
+	if err := inner.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.closedIDs(), []uint32{first.Id}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("the inner scope closed %v, want %v", got, want)
+	}
+
+	again := outer.Scope()
+	second := sendScoped(t, again, "inner again")
+	third := sendScoped(t, outer, "outer")
+	if err := outer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The nested scope is closed first.
+	if got, want := s.closedIDs(), []uint32{first.Id, second.Id, third.Id}; !reflect.DeepEqual(got, want) {
+		t.Errorf("closed %v, want %v", got, want)
+	}
+	if _, err := again.Notify(notify.New("", "late", "", "", 0, notify.NormalUrgency)); !errors.Is(err, notify.ErrScopeClosed) {
+		t.Errorf("Notify() in a closed nested scope = %v, want ErrScopeClosed", err)
+	}
+	if _, err := outer.Scope().Notify(notify.New("", "late", "", "", 0, notify.NormalUrgency)); !errors.Is(err, notify.ErrScopeClosed) {
+		t.Errorf("Notify() in a scope nested in a closed one = %v, want ErrScopeClosed", err)
+	}
+}
+
+func TestScopeScheduled(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+
+	scope := nf.Scope()
+	if _, err := scope.SendAfter(time.Minute, notify.New("", "early", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scope.SendAfter(time.Hour, notify.New("", "late", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	waitFor(t, "the scheduled send", func() bool { return len(s.notifications()) == 1 })
+	early := s.last(t)
+
+	if err := scope.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := nf.PendingScheduled(); len(got) != 0 {
+		t.Errorf("PendingScheduled() after Close() = %v", got)
+	}
+	if got, want := s.closedIDs(), []uint32{early.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("closed %v, want the scheduled notification sent %v", got, want)
+	}
+	clock.Advance(time.Hour)
+	if got := summaries(s); !reflect.DeepEqual(got, []string{"early"}) {
+		t.Errorf("sent %v, want the cancelled send dropped", got)
+	}
+}
+
+func TestNotifierCloseClosesScopes(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	outer := nf.Scope()
+	a := sendScoped(t, outer, "a")
+	b := sendScoped(t, outer.Scope(), "b")
+	if err := nf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sortedIDs(s.closedIDs()...), sortedIDs(a.Id, b.Id); !reflect.DeepEqual(got, want) {
+		t.Errorf("closed %v, want %v", got, want)
+	}
+	if err := outer.Close(); err != nil {
+		t.Errorf("Close() of a scope of a closed Notifier = %v", err)
+	}
+}
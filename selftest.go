@@ -0,0 +1,269 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"time"
+
+	"github.com/Schnouki/notify/internal/harness"
+)
+
+// The self-test waits selfTestWait for the user to act on its notification
+// if interactive, and selfTestSignalWait for the daemon to signal that it
+// was closed otherwise.
+const (
+	selfTestWait       = 15 * time.Second
+	selfTestSignalWait = 2 * time.Second
+)
+
+// SelfTestStep is a step of SelfTest: "connection", "daemon",
+// "capabilities", "notification", "close" or "signals".
+type SelfTestStep struct {
+	Name string
+	// Err is why the step failed, nil if it worked or was skipped.
+	Err error
+	// Skipped is true if the step did not apply, for example the bus steps
+	// with another Transport, or could not run as an earlier step failed.
+	Skipped bool
+	// Detail tells what the step found, such as the ID of the notification.
+	Detail   string
+	Duration time.Duration
+}
+
+// OK returns true if the step ran and worked.
+func (s SelfTestStep) OK() bool {
+	return s.Err == nil && !s.Skipped
+}
+
+// SelfTestReport is the report of SelfTest.
+type SelfTestReport struct {
+	// Server is the daemon, and Owner the unique bus name owning the
+	// notification service name.
+	Server ServerInfo
+	Owner  string
+	// Capabilities are the capabilities of the daemon, and Unsupported the
+	// capabilities of the specification it does not have.
+	Capabilities []string
+	Unsupported  []string
+	Steps        []SelfTestStep
+	// ID is the ID of the test notification, 0 if it was not sent.
+	ID uint32
+	// Action is the key of the action the user invoked, if any, and
+	// CloseReason why the notification was closed, if the daemon said so.
+	Action      string
+	CloseReason CloseReason
+	Duration    time.Duration
+}
+
+// OK returns true if no step failed.
+func (r SelfTestReport) OK() bool {
+	for _, s := range r.Steps {
+		if s.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Step returns the step called name, and false if r has none.
+func (r SelfTestReport) Step(name string) (SelfTestStep, bool) {
+	for _, s := range r.Steps {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return SelfTestStep{}, false
+}
+
+// String returns r as text for people, one line per step.
+func (r SelfTestReport) String() string {
+	var b strings.Builder
+	if r.Server.Name != "" {
+		fmt.Fprintf(&b, "daemon: %s %s (%s), specification %s, owner %s\n", r.Server.Name, r.Server.Version, r.Server.Vendor, r.Server.SpecVersion, r.Owner)
+	}
+	if r.Capabilities != nil {
+		fmt.Fprintf(&b, "capabilities: %s\n", strings.Join(r.Capabilities, ", "))
+	}
+	if len(r.Unsupported) > 0 {
+		fmt.Fprintf(&b, "unsupported: %s\n", strings.Join(r.Unsupported, ", "))
+	}
+	results := make([]harness.Result, len(r.Steps))
+	for i, s := range r.Steps {
+		results[i] = harness.Result(s)
+	}
+	harness.WriteResults(&b, results)
+	fmt.Fprintf(&b, "total %v\n", r.Duration.Round(time.Microsecond))
+	return b.String()
+}
+
+// SelfTest checks the whole notification path, for bug reports: the bus
+// connection, the daemon and its capabilities, then sends a visible test
+// notification, with an action and an image if the daemon shows them, and
+// closes it. If interactive, SelfTest waits up to 15 seconds for the user to
+// invoke the action or dismiss the notification before closing it;
+// otherwise it closes it right away and waits briefly for the daemon to
+// signal it. With a Transport other than D-Bus, the notification is only
+// sent and closed.
+//
+// The report tells what worked and what did not, even if SelfTest fails. The
+// error is that of the first step that failed.
+func (nf *Notifier) SelfTest(ctx context.Context, interactive bool) (r SelfTestReport, err error) {
+	start := time.Now()
+	runner := harness.Runner{Prefix: "notify: self-test "}
+	defer func() {
+		r.Steps = make([]SelfTestStep, len(runner.Results))
+		for i, res := range runner.Results {
+			r.Steps[i] = SelfTestStep(res)
+		}
+		r.Duration = time.Since(start)
+	}()
+	step := runner.Step
+	if nf.transport == nil {
+		step("connection", false, func() (string, error) { return "", ErrNoTransport })
+		return r, runner.Err()
+	}
+
+	bus := nf.onBus()
+	var f Features
+	ok := step("connection", !bus, func() (string, error) {
+		conn, err := nf.connection()
+		if err != nil {
+			return "", err
+		} else if !conn.Connected() {
+			return "", errors.New("connection closed")
+		}
+		return "", nil
+	})
+	ok = step("daemon", !bus || !ok, func() (string, error) {
+		conn, err := nf.connection()
+		if err != nil {
+			return "", err
+		}
+		err = conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.GetNameOwner", 0, nf.destination).Store(&r.Owner)
+		if err != nil {
+			return "", fmt.Errorf("no daemon owns %s: %w", nf.destination, err)
+		}
+		if r.Server, err = nf.ServerInfo(); err != nil {
+			return "", err
+		}
+		return r.Server.Name, nil
+	}) && ok
+	ok = step("capabilities", !bus || !ok, func() (string, error) {
+		var err error
+		if f, err = nf.Features(); err != nil {
+			return "", err
+		}
+		r.Capabilities = f.Capabilities()
+		for _, name := range featureNames {
+			if !f.Has(name) {
+				r.Unsupported = append(r.Unsupported, name)
+			}
+		}
+		return fmt.Sprintf("%d capabilities", len(r.Capabilities)), nil
+	}) && ok
+	if !ok {
+		step("notification", true, nil)
+		step("close", true, nil)
+		step("signals", true, nil)
+		return r, runner.Err()
+	}
+
+	actions := make(chan string, 1)
+	closed := make(chan CloseReason, 1)
+	n := New("", "Notification self-test", "If you can see this, notifications work.", "dialog-information", selfTestWait, NormalUrgency)
+	n.AddHints(TransientHint(true))
+	signals := nf.signalSupport()
+	if signals {
+		n.OnClose = func(reason CloseReason) {
+			select {
+			case closed <- reason:
+			default:
+			}
+		}
+	}
+	var with []string
+	if signals && f.Actions {
+		n.AddAction(DefaultAction, "It works")
+		n.OnAction = func(key string) {
+			select {
+			case actions <- key:
+			default:
+			}
+		}
+		with = append(with, "an action")
+	}
+	if bus && (f.IconStatic || f.IconMulti) {
+		n.SetImage(selfTestImage())
+		with = append(with, "an image")
+	}
+	sent := step("notification", false, func() (string, error) {
+		if _, err := nf.SendContext(ctx, n); err != nil {
+			return "", err
+		}
+		r.ID = n.Id
+		detail := fmt.Sprintf("ID %d", n.Id)
+		if len(with) > 0 {
+			detail += ", with " + strings.Join(with, " and ")
+		}
+		return detail, nil
+	})
+	if !sent {
+		step("close", true, nil)
+		step("signals", true, nil)
+		return r, runner.Err()
+	}
+
+	closeStep := func() {
+		step("close", false, func() (string, error) {
+			return "", nf.CloseNotification(n.Id)
+		})
+	}
+	waitSignals := func(wait time.Duration) {
+		step("signals", !signals, func() (string, error) {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			for {
+				select {
+				case r.Action = <-actions:
+					if !interactive {
+						continue
+					}
+					return fmt.Sprintf("action %q invoked", r.Action), nil
+				case r.CloseReason = <-closed:
+					return "notification closed: " + r.CloseReason.String(), nil
+				case <-timer.C:
+					if interactive {
+						return "no answer from the user", nil
+					}
+					return "", errors.New("the daemon did not signal that the notification was closed")
+				case <-ctx.Done():
+					return "", ctx.Err()
+				}
+			}
+		})
+	}
+	if interactive {
+		waitSignals(selfTestWait)
+		closeStep()
+	} else {
+		closeStep()
+		waitSignals(selfTestSignalWait)
+	}
+	return r, runner.Err()
+}
+
+// selfTestImage returns the image of the self-test notification.
+func selfTestImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{0x2e, 0x7d, 0x32, 0xff}}, image.Point{}, draw.Src)
+	return img
+}
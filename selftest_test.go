@@ -0,0 +1,135 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func stepNames(r notify.SelfTestReport) []string {
+	var names []string
+	for _, s := range r.Steps {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestSelfTest(t *testing.T) {
+	s := newFakeServer(t)
+	s.setCapabilities("body", "actions", "icon-static")
+	nf := newTestNotifier(t)
+
+	r, err := nf.SelfTest(context.Background(), false)
+	if err != nil {
+		t.Fatalf("SelfTest() = %v\n%s", err, r)
+	}
+	want := []string{"connection", "daemon", "capabilities", "notification", "close", "signals"}
+	if got := stepNames(r); !reflect.DeepEqual(got, want) {
+		t.Errorf("steps %v, want %v", got, want)
+	}
+	for _, step := range r.Steps {
+		if !step.OK() {
+			t.Errorf("step %+v did not work", step)
+		}
+	}
+	if !r.OK() || r.Server.Name != "fake" || r.Server.SpecVersion != "1.2" || !strings.HasPrefix(r.Owner, ":") {
+		t.Errorf("report %+v", r)
+	}
+	if !reflect.DeepEqual(r.Capabilities, []string{"actions", "body", "icon-static"}) {
+		t.Errorf("capabilities %v", r.Capabilities)
+	}
+	if !reflect.DeepEqual(r.Unsupported, []string{"action-icons", "body-hyperlinks", "body-images", "body-markup", "icon-multi", "persistence", "sound"}) {
+		t.Errorf("unsupported %v", r.Unsupported)
+	}
+	if r.ID == 0 || r.CloseReason != notify.ReasonClosed || r.Duration <= 0 {
+		t.Errorf("report %+v, want the notification sent and closed", r)
+	}
+
+	sent := s.last(t)
+	if sent.ID != r.ID || !reflect.DeepEqual(sent.Actions, []string{notify.DefaultAction, "It works"}) {
+		t.Errorf("the daemon got %+v", sent)
+	}
+	if _, ok := sent.Hints["image-data"]; !ok {
+		t.Errorf("the test notification has no image: hints %v", sent.Hints)
+	}
+	if sent.Hints["transient"].Value() != true {
+		t.Errorf("the test notification is not transient: hints %v", sent.Hints)
+	}
+	if ids := s.closedIDs(); len(ids) != 1 || ids[0] != r.ID {
+		t.Errorf("closed %v, want %d", ids, r.ID)
+	}
+	if text := r.String(); !strings.Contains(text, "daemon: fake 1.0 (notify)") || !strings.Contains(text, "with an action and an image") {
+		t.Errorf("String() =\n%s", text)
+	}
+}
+
+func TestSelfTestWithoutImages(t *testing.T) {
+	s := newFakeServer(t)
+	s.setCapabilities("body")
+	nf := newTestNotifier(t)
+
+	r, err := nf.SelfTest(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := s.last(t)
+	if len(sent.Actions) != 0 || sent.Hints["image-data"].Value() != nil {
+		t.Errorf("the daemon without actions nor images got %+v", sent)
+	}
+	if step, _ := r.Step("notification"); strings.Contains(step.Detail, "with") {
+		t.Errorf("notification step %+v", step)
+	}
+}
+
+func TestSelfTestNoDaemon(t *testing.T) {
+	requireBus(t)
+	nf := newTestNotifier(t)
+
+	r, err := nf.SelfTest(context.Background(), false)
+	if err == nil || r.OK() {
+		t.Fatalf("SelfTest() without a daemon = %v\n%s", err, r)
+	}
+	if step, _ := r.Step("connection"); !step.OK() {
+		t.Errorf("connection step %+v", step)
+	}
+	if step, _ := r.Step("daemon"); step.Err == nil {
+		t.Errorf("daemon step %+v, want it failed", step)
+	}
+	for _, name := range []string{"capabilities", "notification", "close", "signals"} {
+		if step, _ := r.Step(name); !step.Skipped {
+			t.Errorf("step %+v, want it skipped", step)
+		}
+	}
+	if r.ID != 0 {
+		t.Errorf("sent notification %d", r.ID)
+	}
+}
+
+func TestSelfTestTransport(t *testing.T) {
+	var rt recordingTransport
+	nf, err := notify.NewNotifier(notify.WithTransport(&rt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	r, err := nf.SelfTest(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"connection", "daemon", "capabilities", "signals"} {
+		if step, _ := r.Step(name); !step.Skipped {
+			t.Errorf("step %+v, want it skipped", step)
+		}
+	}
+	if len(rt.calls) != 1 || len(rt.closed) != 1 || rt.closed[0] != r.ID {
+		t.Errorf("transport got %d calls and closed %v", len(rt.calls), rt.closed)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import "context"
+
+// Sender sends and closes notifications. *Notifier implements it; code
+// holding a Sender instead can be tested with the senders of the notifytest
+// package.
+type Sender interface {
+	// SendContext sends n, see Notifier.SendContext.
+	SendContext(ctx context.Context, n *Notification) (SendResult, error)
+	// CloseNotification closes the notification id, see
+	// Notifier.CloseNotification.
+	CloseNotification(id uint32) error
+}
+
+var _ Sender = (*Notifier)(nil)
+
+// ErrorNotification returns the notification that NotifyError sends about
+// err, so that it can be sent with any Sender.
+func ErrorNotification(summary string, err error) *Notification {
+	icon, urgency := mapError(err)
+	return New("", summary, errorBody(err), icon, note.TimeoutDuration(), urgency)
+}
+
+// NotifyError sends a notification about err with the given summary, see
+// the NotifyError function.
+func (nf *Notifier) NotifyError(summary string, err error) error {
+	if err == nil {
+		return nil
+	}
+	_, serr := nf.Notify(ErrorNotification(summary, err))
+	return serr
+}
+
+// NotifyFile sends a notification about the file at path, see the
+// NotifyFile function.
+func (nf *Notifier) NotifyFile(summary, path string, opts ...FileOption) (*Notification, error) {
+	return NotifyFile(nf, summary, path, opts...)
+}
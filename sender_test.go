@@ -0,0 +1,52 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/notifytest"
+)
+
+// reportFailure is application code holding a Sender.
+func reportFailure(s notify.Sender, err error) error {
+	_, serr := s.SendContext(context.Background(), notify.ErrorNotification("Sync failed", err))
+	return serr
+}
+
+func TestSenderErrorNotification(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	var rec notifytest.RecordingSender
+
+	boom := errors.New("boom")
+	for _, sender := range []notify.Sender{nf, &rec} {
+		if err := reportFailure(sender, boom); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := nf.NotifyError("Sync failed", boom); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := s.notifications()
+	if len(sent) != 2 {
+		t.Fatalf("the daemon received %d notifications, want 2", len(sent))
+	}
+	recorded := rec.Sent()
+	if len(recorded) != 1 {
+		t.Fatalf("recorded %d notifications, want 1", len(recorded))
+	}
+	r := recorded[0]
+	for i, got := range sent {
+		if got.Summary != r.Summary || got.Body != r.Body || got.AppIcon != r.IconPath || got.Hints["urgency"].Value() != byte(r.Urgency) {
+			t.Errorf("notification %d is %q, %q, %q, %v, recorded %q, %q, %q, %v", i,
+				got.Summary, got.Body, got.AppIcon, got.Hints["urgency"].Value(), r.Summary, r.Body, r.IconPath, r.Urgency)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SensitivePolicy decides when the body of Sensitive notifications is
+// hidden, see WithSensitivePolicy.
+type SensitivePolicy int
+
+const (
+	// AlwaysShow sends the body of sensitive notifications as it is.
+	AlwaysShow SensitivePolicy = iota
+	// HideBodyWhenLocked hides the body while the session is locked, see
+	// Notifier.SessionLocked.
+	HideBodyWhenLocked
+	// AlwaysHideBody always hides the body.
+	AlwaysHideBody
+)
+
+// WithSensitivePolicy sets when the body of the notifications with
+// Sensitive set is replaced by placeholder, which may be empty. The
+// default is AlwaysShow.
+func WithSensitivePolicy(p SensitivePolicy, placeholder string) Option {
+	return func(nf *Notifier) error {
+		if p < AlwaysShow || p > AlwaysHideBody {
+			return fmt.Errorf("notify: invalid sensitive policy %d", p)
+		}
+		nf.sensitivePolicy = p
+		nf.sensitivePlaceholder = placeholder
+		return nil
+	}
+}
+
+// WithLockDetector makes the Notifier call locked to find out whether the
+// session is locked, instead of asking the screensaver of the session, see
+// SessionLocked.
+func WithLockDetector(locked func() (bool, error)) Option {
+	return func(nf *Notifier) error {
+		if locked == nil {
+			return errors.New("notify: nil lock detector")
+		}
+		nf.lockDetector = locked
+		return nil
+	}
+}
+
+// SessionLocked returns true if the session is locked, as reported by the
+// org.freedesktop.ScreenSaver service of the session bus, or the detector
+// set by WithLockDetector.
+func (nf *Notifier) SessionLocked() (bool, error) {
+	if nf.lockDetector != nil {
+		return nf.lockDetector()
+	}
+	conn, err := nf.connection()
+	if err != nil {
+		return false, err
+	}
+	var active bool
+	call := conn.Object("org.freedesktop.ScreenSaver", "/org/freedesktop/ScreenSaver").Call("org.freedesktop.ScreenSaver.GetActive", 0)
+	if call.Err != nil {
+		return false, call.Err
+	} else if call.Store(&active) != nil {
+		return false, errUnrecognizedResponse
+	}
+	nf.sessionProbed(active)
+	return active, nil
+}
+
+// hideBody returns true if the body of n must be hidden. If the lock state
+// cannot be found out, the session is assumed to be locked.
+func (nf *Notifier) hideBody(n *Notification) bool {
+	if !n.Sensitive {
+		return false
+	}
+	switch nf.sensitivePolicy {
+	case AlwaysHideBody:
+		return true
+	case HideBodyWhenLocked:
+		locked, err := nf.SessionLocked()
+		if err != nil {
+			nf.log(LevelWarn, "finding out whether the session is locked failed, hiding the body", err)
+			return true
+		}
+		return locked
+	}
+	return false
+}
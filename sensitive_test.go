@@ -0,0 +1,109 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/Schnouki/notify"
+)
+
+func chatMessage() *notify.Notification {
+	n := notify.New("chat", "New message from Alice", "the launch codes are 1234", "", 0, notify.NormalUrgency)
+	n.Sensitive = true
+	return n
+}
+
+func TestSensitivePolicies(t *testing.T) {
+	const secret, hidden = "the launch codes are 1234", "Open the app to read it"
+	tests := []struct {
+		policy notify.SensitivePolicy
+		locked bool
+		err    error
+		want   string
+	}{
+		{notify.AlwaysShow, false, nil, secret},
+		{notify.AlwaysShow, true, nil, secret},
+		{notify.HideBodyWhenLocked, false, nil, secret},
+		{notify.HideBodyWhenLocked, true, nil, hidden},
+		{notify.HideBodyWhenLocked, false, errors.New("no screensaver"), hidden},
+		{notify.AlwaysHideBody, false, nil, hidden},
+		{notify.AlwaysHideBody, true, nil, hidden},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("policy %d locked %v error %v", tt.policy, tt.locked, tt.err), func(t *testing.T) {
+			s := newFakeServer(t)
+			nf := newTestNotifier(t,
+				notify.WithSensitivePolicy(tt.policy, hidden),
+				notify.WithLockDetector(func() (bool, error) { return tt.locked, tt.err }))
+
+			if _, err := nf.Notify(chatMessage()); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.last(t).Body; got != tt.want {
+				t.Errorf("sent body %q, want %q", got, tt.want)
+			}
+			plain := notify.New("chat", "Update available", "version 2", "", 0, notify.NormalUrgency)
+			if _, err := nf.Notify(plain); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.last(t).Body; got != "version 2" {
+				t.Errorf("sent the body of a notification that is not sensitive as %q", got)
+			}
+		})
+	}
+}
+
+func TestSensitiveHistory(t *testing.T) {
+	tests := []struct {
+		name   string
+		option func(int) notify.Option
+		want   string
+	}{
+		{"untrusted", notify.WithHistory, "(hidden)"},
+		{"trusted", notify.WithTrustedHistory, "the launch codes are 1234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newFakeServer(t)
+			nf := newTestNotifier(t, tt.option(10), notify.WithSensitivePolicy(notify.AlwaysShow, "(hidden)"))
+			if _, err := nf.Notify(chatMessage()); err != nil {
+				t.Fatal(err)
+			}
+			if h := nf.History(); len(h) != 1 || h[0].Body != tt.want {
+				t.Errorf("history %+v, want the body recorded as %q", h, tt.want)
+			}
+		})
+	}
+}
+
+// fakeScreenSaver exports the screensaver interface.
+type fakeScreenSaver bool
+
+func (s fakeScreenSaver) GetActive() (bool, *dbus.Error) {
+	return bool(s), nil
+}
+
+func TestSessionLocked(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	if _, err := nf.SessionLocked(); err == nil {
+		t.Error("SessionLocked succeeded without a screensaver")
+	}
+
+	if err := s.conn.Export(fakeScreenSaver(true), "/org/freedesktop/ScreenSaver", "org.freedesktop.ScreenSaver"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.conn.RequestName("org.freedesktop.ScreenSaver", dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatal(err)
+	}
+	if locked, err := nf.SessionLocked(); err != nil || !locked {
+		t.Errorf("SessionLocked() = %v, %v, want true", locked, err)
+	}
+}
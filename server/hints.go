@@ -0,0 +1,54 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package server
+
+import (
+	"image"
+
+	"github.com/Schnouki/notify"
+)
+
+// Urgency returns the urgency of n, and false if it has no valid "urgency"
+// hint, in which case the specification says it is notify.NormalUrgency.
+func (n ReceivedNotification) Urgency() (notify.NotificationUrgency, bool) {
+	var h notify.UrgencyHint
+	if !h.DecodeHint(n.Hints) {
+		return notify.NormalUrgency, false
+	}
+	return notify.NotificationUrgency(h), true
+}
+
+// Progress returns the "value" hint of n, a percentage.
+func (n ReceivedNotification) Progress() (int, bool) {
+	var h notify.ValueHint
+	ok := h.DecodeHint(n.Hints)
+	return int(h), ok
+}
+
+// Category returns the "category" hint of n.
+func (n ReceivedNotification) Category() (string, bool) {
+	var h notify.CategoryHint
+	ok := h.DecodeHint(n.Hints)
+	return string(h), ok
+}
+
+// Image returns the raw image embedded in n, or nil if there is none. See
+// notify.DecodeImage.
+func (n ReceivedNotification) Image() (image.Image, error) {
+	return notify.DecodeImage(n.Hints)
+}
+
+// HintAs returns the value of the hint key of n, and false if it is missing
+// or not of type T. Use the D-Bus types: byte for "y", int32 for "i", and so
+// on. Structs are received as []interface{}.
+func HintAs[T any](n ReceivedNotification, key string) (T, bool) {
+	v, ok := n.Hints[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	t, ok := v.Value().(T)
+	return t, ok
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package server_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/server"
+)
+
+func TestReceivedHints(t *testing.T) {
+	var rec recorder
+	_, nf := serve(t, &rec)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 3))
+	img.Set(1, 2, color.NRGBA{R: 10, G: 20, B: 30, A: 40})
+	n := notify.New("test", "hints", "", "", 0, notify.CriticalUrgency)
+	n.AddHints(notify.ValueHint(42), notify.CategoryHint("transfer.complete"))
+	n.SetHint("x-vendor", uint32(7))
+	n.SetImage(img)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	got := rec.notifications()[0]
+
+	if u, ok := got.Urgency(); !ok || u != notify.CriticalUrgency {
+		t.Errorf("Urgency() = %v, %v", u, ok)
+	}
+	if p, ok := got.Progress(); !ok || p != 42 {
+		t.Errorf("Progress() = %v, %v", p, ok)
+	}
+	if c, ok := got.Category(); !ok || c != "transfer.complete" {
+		t.Errorf("Category() = %q, %v", c, ok)
+	}
+	if v, ok := server.HintAs[uint32](got, "x-vendor"); !ok || v != 7 {
+		t.Errorf("HintAs[uint32] = %v, %v", v, ok)
+	}
+	if _, ok := server.HintAs[string](got, "x-vendor"); ok {
+		t.Error("HintAs[string] of a uint32 hint succeeded")
+	}
+
+	decoded, err := got.Image()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Bounds() != img.Bounds() {
+		t.Fatalf("Image() has bounds %v, want %v", decoded.Bounds(), img.Bounds())
+	}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			if g, w := decoded.At(x, y), img.At(x, y); g != w {
+				t.Errorf("pixel (%d, %d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+func TestReceivedHintsMissing(t *testing.T) {
+	var n server.ReceivedNotification
+	if u, ok := n.Urgency(); ok || u != notify.NormalUrgency {
+		t.Errorf("Urgency() = %v, %v without hints", u, ok)
+	}
+	if _, ok := n.Progress(); ok {
+		t.Error("Progress() succeeded without hints")
+	}
+	if img, err := n.Image(); img != nil || err != nil {
+		t.Errorf("Image() = %v, %v without hints", img, err)
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package server implements the daemon side of the Freedesktop
+// Notifications Specification, for custom notification daemons and for
+// tests.
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Schnouki/notify"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusName       = "org.freedesktop.Notifications"
+	dbusObjectPath = "/org/freedesktop/Notifications"
+	dbusInterface  = "org.freedesktop.Notifications"
+)
+
+// ReceivedNotification is a notification sent to a Server.
+type ReceivedNotification struct {
+	// ID is the ID the Server assigned to the notification: the one it
+	// replaces, or a new one.
+	ID uint32
+	// Sender is the unique bus name of the client.
+	Sender string
+
+	AppName       string
+	ReplacesID    uint32
+	AppIcon       string
+	Summary       string
+	Body          string
+	Actions       []notify.Action
+	Hints         map[string]dbus.Variant
+	ExpireTimeout int32
+}
+
+// Handler shows the notifications received by a Server. Its methods may be
+// called concurrently.
+type Handler interface {
+	// Notify shows n, replacing the notification with the same ID if it is
+	// shown. If it fails, the error is returned to the client.
+	Notify(r *Responder, n ReceivedNotification) error
+	// CloseNotification hides the notification id, at the request of a
+	// client. If it succeeds, the Server signals that the notification was
+	// closed with notify.ReasonClosed.
+	CloseNotification(r *Responder, id uint32) error
+}
+
+// Server is a notification daemon exported on a D-Bus connection.
+type Server struct {
+	conn    *dbus.Conn
+	handler Handler
+	name    string
+	caps    []string
+	info    notify.ServerInfo
+
+	responder Responder
+
+	mu     sync.Mutex
+	lastID uint32
+	// open holds the IDs of the notifications shown.
+	open map[uint32]bool
+}
+
+// Option configures a Server, see New.
+type Option func(*Server) error
+
+// WithName makes the Server own name instead of org.freedesktop.Notifications.
+func WithName(name string) Option {
+	return func(s *Server) error {
+		if name == "" {
+			return errors.New("server: empty bus name")
+		}
+		s.name = name
+		return nil
+	}
+}
+
+// WithCapabilities sets the capabilities the Server advertises. The default
+// is "body" and "actions".
+func WithCapabilities(caps ...string) Option {
+	return func(s *Server) error {
+		s.caps = append([]string(nil), caps...)
+		return nil
+	}
+}
+
+// WithServerInfo sets the server information the Server reports.
+func WithServerInfo(info notify.ServerInfo) Option {
+	return func(s *Server) error {
+		s.info = info
+		return nil
+	}
+}
+
+// New exports a notification daemon handled by h on conn, and requests its
+// bus name. It fails if another daemon owns the name.
+func New(conn *dbus.Conn, h Handler, opts ...Option) (*Server, error) {
+	s := &Server{
+		conn:    conn,
+		handler: h,
+		name:    dbusName,
+		caps:    []string{"body", "actions"},
+		info:    notify.ServerInfo{Name: "notify-server", Vendor: "notify", Version: "1.0", SpecVersion: "1.2"},
+		open:    make(map[uint32]bool),
+	}
+	s.responder.s = s
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := conn.Export(daemon{s}, dbusObjectPath, dbusInterface); err != nil {
+		return nil, err
+	}
+	reply, err := conn.RequestName(s.name, dbus.NameFlagDoNotQueue)
+	if err == nil && reply != dbus.RequestNameReplyPrimaryOwner {
+		err = fmt.Errorf("server: %s is already owned", s.name)
+	}
+	if err != nil {
+		conn.Export(nil, dbusObjectPath, dbusInterface)
+		return nil, err
+	}
+	return s, nil
+}
+
+// Responder returns the Responder signalling the events of the notifications
+// of s.
+func (s *Server) Responder() *Responder {
+	return &s.responder
+}
+
+// Close releases the bus name of s and stops serving. The connection is left
+// open.
+func (s *Server) Close() error {
+	_, err := s.conn.ReleaseName(s.name)
+	if uerr := s.conn.Export(nil, dbusObjectPath, dbusInterface); err == nil {
+		err = uerr
+	}
+	return err
+}
+
+// Responder signals the events of the notifications of a Server to their
+// clients.
+type Responder struct {
+	s *Server
+}
+
+// CloseExpired signals that the notification id expired.
+func (r *Responder) CloseExpired(id uint32) error {
+	return r.close(id, notify.ReasonExpired)
+}
+
+// CloseDismissed signals that the user dismissed the notification id.
+func (r *Responder) CloseDismissed(id uint32) error {
+	return r.close(id, notify.ReasonDismissed)
+}
+
+// CloseRequested signals that the notification id was closed by a call to
+// CloseNotification.
+func (r *Responder) CloseRequested(id uint32) error {
+	return r.close(id, notify.ReasonClosed)
+}
+
+// CloseOther signals that the notification id was closed for another reason.
+func (r *Responder) CloseOther(id uint32) error {
+	return r.close(id, notify.ReasonUndefined)
+}
+
+// ActivationToken signals the XDG activation token of the next action
+// invoked on the notification id, before ActionInvoked.
+func (r *Responder) ActivationToken(id uint32, token string) error {
+	return r.s.conn.Emit(dbusObjectPath, dbusInterface+".ActivationToken", id, token)
+}
+
+// ActionInvoked signals that the user invoked the action key of the
+// notification id.
+func (r *Responder) ActionInvoked(id uint32, key string) error {
+	return r.s.conn.Emit(dbusObjectPath, dbusInterface+".ActionInvoked", id, key)
+}
+
+func (r *Responder) close(id uint32, reason notify.CloseReason) error {
+	r.s.mu.Lock()
+	delete(r.s.open, id)
+	r.s.mu.Unlock()
+	return r.s.conn.Emit(dbusObjectPath, dbusInterface+".NotificationClosed", id, uint32(reason))
+}
+
+// daemon holds the methods exported on the bus.
+type daemon struct {
+	s *Server
+}
+
+func (d daemon) Notify(msg dbus.Message, appName string, replacesID uint32, appIcon, summary, body string, actions []string, hints map[string]dbus.Variant, expireTimeout int32) (uint32, *dbus.Error) {
+	// godbus loses the signature of structs in variants when storing the
+	// arguments, so take the hints straight from the message.
+	if raw, ok := msg.Body[6].(map[string]dbus.Variant); ok {
+		hints = raw
+	}
+	sender, _ := msg.Headers[dbus.FieldSender].Value().(string)
+
+	s := d.s
+	s.mu.Lock()
+	id := replacesID
+	if !s.open[id] {
+		s.lastID++
+		id = s.lastID
+	}
+	s.mu.Unlock()
+
+	n := ReceivedNotification{
+		ID:            id,
+		Sender:        sender,
+		AppName:       appName,
+		ReplacesID:    replacesID,
+		AppIcon:       appIcon,
+		Summary:       summary,
+		Body:          body,
+		Actions:       parseActions(actions),
+		Hints:         hints,
+		ExpireTimeout: expireTimeout,
+	}
+	if err := s.handler.Notify(&s.responder, n); err != nil {
+		return 0, dbus.MakeFailedError(err)
+	}
+
+	s.mu.Lock()
+	s.open[id] = true
+	s.mu.Unlock()
+	return id, nil
+}
+
+// CloseNotification fails for notifications that are not shown, as required
+// by the specification.
+func (d daemon) CloseNotification(id uint32) *dbus.Error {
+	s := d.s
+	s.mu.Lock()
+	open := s.open[id]
+	s.mu.Unlock()
+	if !open {
+		return dbus.MakeFailedError(fmt.Errorf("notification %d is not shown", id))
+	}
+
+	if err := s.handler.CloseNotification(&s.responder, id); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	if err := s.responder.CloseRequested(id); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (d daemon) GetCapabilities() ([]string, *dbus.Error) {
+	return append([]string(nil), d.s.caps...), nil
+}
+
+func (d daemon) GetServerInformation() (string, string, string, string, *dbus.Error) {
+	info := d.s.info
+	return info.Name, info.Vendor, info.Version, info.SpecVersion, nil
+}
+
+// parseActions returns the actions from the flat key, label list of the
+// specification.
+func parseActions(array []string) []notify.Action {
+	if len(array) < 2 {
+		return nil
+	}
+	actions := make([]notify.Action, 0, len(array)/2)
+	for i := 0; i+1 < len(array); i += 2 {
+		actions = append(actions, notify.Action{Key: array[i], Label: array[i+1]})
+	}
+	return actions
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package server_test
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+	"github.com/Schnouki/notify/internal/testbus"
+	"github.com/Schnouki/notify/server"
+	"github.com/godbus/dbus/v5"
+)
+
+var busAddress string
+
+func TestMain(m *testing.M) {
+	os.Exit(testbus.Run(m, &busAddress))
+}
+
+// recorder is a Handler recording what it is asked to do.
+type recorder struct {
+	mu       sync.Mutex
+	received []server.ReceivedNotification
+	closed   []uint32
+}
+
+func (r *recorder) Notify(_ *server.Responder, n server.ReceivedNotification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.received = append(r.received, n)
+	return nil
+}
+
+func (r *recorder) notifications() []server.ReceivedNotification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]server.ReceivedNotification(nil), r.received...)
+}
+
+func (r *recorder) closedIDs() []uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]uint32(nil), r.closed...)
+}
+
+func (r *recorder) CloseNotification(_ *server.Responder, id uint32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = append(r.closed, id)
+	return nil
+}
+
+// serve starts a Server handled by h, and returns a Notifier talking to it.
+func serve(t *testing.T, h server.Handler) (*server.Server, *notify.Notifier) {
+	t.Helper()
+	if busAddress == "" {
+		t.Skip("no private D-Bus session bus available")
+	}
+	conn, err := dbus.Connect(busAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s, err := server.New(conn, h, server.WithName("org.example.Notifications"), server.WithCapabilities("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	nf, err := notify.NewNotifier(notify.WithBusAddress(busAddress),
+		notify.WithDestination("org.example.Notifications", "/org/freedesktop/Notifications"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nf.Close() })
+	return s, nf
+}
+
+func TestNotifyAndReplace(t *testing.T) {
+	var rec recorder
+	_, nf := serve(t, &rec)
+
+	n := notify.New("test", "summary", "body", "icon", time.Second, notify.CriticalUrgency)
+	n.AddAction("ok", "OK")
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	id := n.Id
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Id != id {
+		t.Errorf("replace got ID %d, want %d", n.Id, id)
+	}
+
+	received := rec.notifications()
+	if len(received) != 2 {
+		t.Fatalf("received %d notifications", len(received))
+	}
+	got := received[0]
+	if got.ID != id || got.AppName != "test" || got.Summary != "summary" || got.ExpireTimeout != 1000 ||
+		len(got.Actions) != 1 || got.Actions[0] != (notify.Action{Key: "ok", Label: "OK"}) || got.Sender == "" {
+		t.Errorf("received %+v", got)
+	}
+	if v := got.Hints["urgency"].Value(); v != byte(notify.CriticalUrgency) {
+		t.Errorf("urgency hint %v", v)
+	}
+
+	caps, err := nf.Capabilities()
+	if err != nil || len(caps) != 1 || caps[0] != "body" {
+		t.Errorf("capabilities %q, %v", caps, err)
+	}
+}
+
+func TestCloseReasons(t *testing.T) {
+	var rec recorder
+	s, nf := serve(t, &rec)
+	r := s.Responder()
+
+	tests := []struct {
+		close func(id uint32) error
+		want  notify.CloseReason
+	}{
+		{r.CloseExpired, notify.ReasonExpired},
+		{r.CloseDismissed, notify.ReasonDismissed},
+		{r.CloseRequested, notify.ReasonClosed},
+		{r.CloseOther, notify.ReasonUndefined},
+		{nf.CloseNotification, notify.ReasonClosed},
+	}
+	for _, tt := range tests {
+		reasons := make(chan notify.CloseReason, 1)
+		n := notify.New("test", "closing", "", "", 0, notify.NormalUrgency)
+		n.OnClose = func(reason notify.CloseReason) { reasons <- reason }
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		if err := tt.close(n.Id); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got := <-reasons:
+			if got != tt.want {
+				t.Errorf("closed with %v, want %v", got, tt.want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no close signal for %v", tt.want)
+		}
+	}
+
+	closed := rec.closedIDs()
+	if len(closed) != 1 {
+		t.Fatalf("handler closed %v, want the notification closed by the client", closed)
+	}
+	if err := nf.CloseNotification(closed[0]); err == nil {
+		t.Error("closing a closed notification succeeded")
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// The logind service, on the system bus.
+const (
+	logindName    = "org.freedesktop.login1"
+	logindPath    = "/org/freedesktop/login1"
+	logindManager = "org.freedesktop.login1.Manager"
+	logindSession = "org.freedesktop.login1.Session"
+)
+
+// WithLogindConn makes the Notifier ask logind on conn instead of the system
+// bus, see GraphicalSessionActive.
+func WithLogindConn(conn *dbus.Conn) Option {
+	return func(nf *Notifier) error {
+		if conn == nil {
+			return errors.New("notify: nil logind connection")
+		}
+		nf.logindConn = conn
+		return nil
+	}
+}
+
+// WithSessionWait makes the Notifier hold back notifications until a
+// graphical session of the user is active, see GraphicalSessionActive. This
+// is for the services started before the user logs in, which have a session
+// bus but no desktop to show notifications yet.
+//
+// Held back notifications are not sent: Notify returns a SendResult with
+// Deferred set, and emits an EventDeferred. They are sent in order as soon
+// as logind signals that a graphical session became active. Critical
+// notifications are still sent right away, and once a graphical session was
+// active, nothing is held back anymore. If logind cannot be asked,
+// notifications are sent right away too.
+func WithSessionWait() Option {
+	return func(nf *Notifier) error {
+		nf.waitSession = true
+		return nil
+	}
+}
+
+// GraphicalSessionActive returns true if the user running the program has an
+// active graphical session, an X11, Wayland or Mir one, as reported by
+// logind.
+func (nf *Notifier) GraphicalSessionActive() (bool, error) {
+	conn, err := nf.logind()
+	if err != nil {
+		return false, err
+	}
+	var sessions []struct {
+		ID   string
+		UID  uint32
+		User string
+		Seat string
+		Path dbus.ObjectPath
+	}
+	if err := conn.Object(logindName, logindPath).Call(logindManager+".ListSessions", 0).Store(&sessions); err != nil {
+		return false, fmt.Errorf("notify: listing the logind sessions: %w", err)
+	}
+	uid := uint32(os.Getuid())
+	for _, s := range sessions {
+		if s.UID != uid {
+			continue
+		}
+		obj := conn.Object(logindName, s.Path)
+		typ, err := obj.GetProperty(logindSession + ".Type")
+		if err != nil {
+			return false, fmt.Errorf("notify: logind session %s: %w", s.ID, err)
+		}
+		active, err := obj.GetProperty(logindSession + ".Active")
+		if err != nil {
+			return false, fmt.Errorf("notify: logind session %s: %w", s.ID, err)
+		}
+		t, _ := typ.Value().(string)
+		a, _ := active.Value().(bool)
+		if a && graphicalSession(t) {
+			nf.graphicalProbed(true)
+			return true, nil
+		}
+	}
+	nf.graphicalProbed(false)
+	return false, nil
+}
+
+// graphicalSession returns true if the logind session type t is a graphical
+// one.
+func graphicalSession(t string) bool {
+	switch t {
+	case "x11", "wayland", "mir":
+		return true
+	}
+	return false
+}
+
+// logind returns the connection to the bus of logind.
+func (nf *Notifier) logind() (*dbus.Conn, error) {
+	if nf.logindConn != nil {
+		return nf.logindConn, nil
+	}
+	return dbus.SystemBus()
+}
+
+// sessionWait holds the notifications held back until a graphical session
+// is active, see WithSessionWait.
+type sessionWait struct {
+	// ready is true once a graphical session was active.
+	ready bool
+	held  []*Notification
+	// stop stops watching the signals of logind, nil if not watching.
+	stop chan struct{}
+}
+
+// holdForSession holds back n if no graphical session was active yet, and
+// returns true if it did. The send uses a copy of n taken now.
+func (nf *Notifier) holdForSession(n *Notification) bool {
+	if !nf.waitSession || n.Urgency == CriticalUrgency {
+		return false
+	}
+	nf.mu.Lock()
+	ready, watching := nf.session.ready, nf.session.stop != nil
+	nf.mu.Unlock()
+	if ready {
+		return false
+	}
+	if !watching {
+		active, err := nf.GraphicalSessionActive()
+		if err != nil {
+			nf.log(LevelWarn, "finding out whether a graphical session is active failed, not waiting for one", err)
+		}
+		if err != nil || active {
+			nf.mu.Lock()
+			nf.session.ready = true
+			nf.mu.Unlock()
+			return false
+		}
+	}
+
+	nf.mu.Lock()
+	if nf.session.ready {
+		nf.mu.Unlock()
+		return false
+	}
+	if nf.session.stop == nil {
+		stop := make(chan struct{})
+		if err := nf.watchSession(stop); err != nil {
+			nf.session.ready = true
+			nf.mu.Unlock()
+			nf.log(LevelWarn, "watching the logind sessions failed, not waiting for one", err)
+			return false
+		}
+		nf.session.stop = stop
+	}
+	cp := *n
+	cp.cache = sendCache{}
+	nf.session.held = append(nf.session.held, &cp)
+	nf.stats.Deferred++
+	nf.mu.Unlock()
+
+	nf.emit(Event{Kind: EventDeferred, Tag: n.Tag, CorrelationID: n.CorrelationID})
+	return true
+}
+
+// watchSession watches the signals of logind until stop is closed or a
+// graphical session is active, and then sends the held back notifications.
+func (nf *Notifier) watchSession(stop chan struct{}) error {
+	conn, err := nf.logind()
+	if err != nil {
+		return err
+	}
+	match := []dbus.MatchOption{
+		dbus.WithMatchSender(logindName),
+		dbus.WithMatchPathNamespace(logindPath),
+	}
+	if err := conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	go func() {
+		defer func() {
+			conn.RemoveSignal(signals)
+			conn.RemoveMatchSignal(match...)
+		}()
+		// The session may have become active before the match was added.
+		active := func() bool {
+			ok, err := nf.GraphicalSessionActive()
+			if err != nil {
+				nf.log(LevelWarn, "finding out whether a graphical session is active failed", err)
+			}
+			return ok
+		}
+		if active() {
+			nf.sessionReady(stop)
+			return
+		}
+		for {
+			select {
+			case <-stop:
+				return
+			case sig, ok := <-signals:
+				if !ok {
+					return
+				}
+				if logindSignal(sig) && active() {
+					nf.sessionReady(stop)
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// logindSignal returns true if sig may tell that a session became active:
+// the change of the properties of a session, or a new session.
+func logindSignal(sig *dbus.Signal) bool {
+	if !strings.HasPrefix(string(sig.Path), logindPath) {
+		return false
+	}
+	switch sig.Name {
+	case "org.freedesktop.DBus.Properties.PropertiesChanged", logindManager + ".SessionNew":
+		return true
+	}
+	return false
+}
+
+// sessionReady stops holding back notifications and sends those held back,
+// unless nf stopped watching with stop.
+func (nf *Notifier) sessionReady(stop chan struct{}) {
+	nf.mu.Lock()
+	if nf.session.stop != stop {
+		nf.mu.Unlock()
+		return
+	}
+	held := nf.session.held
+	nf.session = sessionWait{ready: true}
+	nf.mu.Unlock()
+
+	nf.log(LevelInfo, fmt.Sprintf("a graphical session is active, sending %d held back notifications", len(held)), nil)
+	for _, n := range held {
+		if _, err := nf.Notify(n); err != nil {
+			summary, _ := nf.redact(n.Summary, "")
+			nf.log(LevelWarn, fmt.Sprintf("sending the held back notification %q failed", summary), err)
+		}
+	}
+}
+
+// stopSessionWait stops watching the signals of logind and drops the held
+// back notifications. nf.mu must be held.
+func (nf *Notifier) stopSessionWait() {
+	if nf.session.stop != nil {
+		close(nf.session.stop)
+	}
+	nf.session = sessionWait{ready: nf.session.ready}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+
+	"github.com/Schnouki/notify"
+)
+
+// fakeLogind is a logind stub on the test bus, listing the sessions added
+// with addSession.
+type fakeLogind struct {
+	conn *dbus.Conn
+
+	mu       sync.Mutex
+	sessions []logindSession
+	props    map[string]*prop.Properties
+}
+
+type logindSession struct {
+	ID   string
+	UID  uint32
+	User string
+	Seat string
+	Path dbus.ObjectPath
+}
+
+func newFakeLogind(t *testing.T) *fakeLogind {
+	t.Helper()
+	requireBus(t)
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	l := &fakeLogind{conn: conn, props: make(map[string]*prop.Properties)}
+	if err := conn.Export(logindManager{l}, "/org/freedesktop/login1", "org.freedesktop.login1.Manager"); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := conn.RequestName("org.freedesktop.login1", dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatal(err)
+	} else if reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatal("fake logind could not own org.freedesktop.login1")
+	}
+	return l
+}
+
+// addSession adds the session id of the user running the tests.
+func (l *fakeLogind) addSession(t *testing.T, id, typ string, active bool) {
+	t.Helper()
+	path := dbus.ObjectPath("/org/freedesktop/login1/session/_3" + id)
+	p, err := prop.Export(l.conn, path, prop.Map{
+		"org.freedesktop.login1.Session": {
+			"Type":   {Value: typ, Emit: prop.EmitTrue},
+			"Active": {Value: active, Emit: prop.EmitTrue},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sessions = append(l.sessions, logindSession{id, uint32(os.Getuid()), "user", "seat0", path})
+	l.props[id] = p
+}
+
+// activate makes the session id active, emitting PropertiesChanged.
+func (l *fakeLogind) activate(id string) {
+	l.setActive(id, true)
+}
+
+// setActive sets whether the session id is active, emitting
+// PropertiesChanged.
+func (l *fakeLogind) setActive(id string, active bool) {
+	l.mu.Lock()
+	p := l.props[id]
+	l.mu.Unlock()
+	p.SetMust("org.freedesktop.login1.Session", "Active", active)
+}
+
+type logindManager struct{ l *fakeLogind }
+
+func (m logindManager) ListSessions() ([]logindSession, *dbus.Error) {
+	m.l.mu.Lock()
+	defer m.l.mu.Unlock()
+	return append([]logindSession(nil), m.l.sessions...), nil
+}
+
+func TestGraphicalSessionActive(t *testing.T) {
+	l := newFakeLogind(t)
+	nf := newTestNotifier(t, notify.WithLogindConn(l.conn))
+
+	check := func(want bool) {
+		t.Helper()
+		if active, err := nf.GraphicalSessionActive(); err != nil || active != want {
+			t.Errorf("GraphicalSessionActive = %v, %v, want %v, nil", active, err, want)
+		}
+	}
+	check(false)
+	l.addSession(t, "1", "tty", true)
+	check(false)
+	l.addSession(t, "2", "wayland", false)
+	check(false)
+	l.activate("2")
+	check(true)
+}
+
+func TestGraphicalSessionActiveWithoutLogind(t *testing.T) {
+	requireBus(t)
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	nf := newTestNotifier(t, notify.WithLogindConn(conn))
+	if _, err := nf.GraphicalSessionActive(); err == nil {
+		t.Error("GraphicalSessionActive succeeded without logind")
+	}
+
+	// Without logind, nothing is held back.
+	s := newFakeServer(t)
+	nf = newTestNotifier(t, notify.WithLogindConn(conn), notify.WithSessionWait())
+	n := notify.New("test", "not waiting", "", "", 0, notify.NormalUrgency)
+	if res, err := nf.Notify(n); err != nil || res.Deferred {
+		t.Fatalf("Notify = %+v, %v", res, err)
+	}
+	if got := s.received(); got != 1 {
+		t.Errorf("the daemon received %d notifications, want 1", got)
+	}
+}
+
+func TestSessionWait(t *testing.T) {
+	s := newFakeServer(t)
+	l := newFakeLogind(t)
+	l.addSession(t, "1", "x11", false)
+	nf := newTestNotifier(t, notify.WithLogindConn(l.conn), notify.WithSessionWait())
+	events := nf.Events()
+
+	for i := 0; i < 3; i++ {
+		n := notify.New("test", fmt.Sprint("held ", i), "", "", 0, notify.NormalUrgency)
+		if res, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		} else if !res.Deferred || n.Id != 0 {
+			t.Fatalf("notification %d was not held back: %+v", i, res)
+		}
+		waitEvent(t, events, notify.EventDeferred)
+	}
+	critical := notify.New("test", "critical", "", "", 0, notify.CriticalUrgency)
+	if res, err := nf.Notify(critical); err != nil || res.Deferred {
+		t.Fatalf("Notify(critical) = %+v, %v", res, err)
+	}
+	if got := s.received(); got != 1 {
+		t.Fatalf("the daemon received %d notifications before login, want 1", got)
+	}
+
+	l.activate("1")
+	waitFor(t, "the held back notifications", func() bool { return s.received() == 4 })
+	for i, sent := range s.notifications()[1:] {
+		if want := fmt.Sprint("held ", i); sent.Summary != want {
+			t.Errorf("notification %d is %q, want %q", i, sent.Summary, want)
+		}
+	}
+
+	// Once a graphical session was active, nothing is held back.
+	n := notify.New("test", "after login", "", "", 0, notify.NormalUrgency)
+	if res, err := nf.Notify(n); err != nil || res.Deferred {
+		t.Errorf("Notify after login = %+v, %v", res, err)
+	}
+	if st := nf.Stats(); st.Deferred != 3 {
+		t.Errorf("Stats.Deferred = %d, want 3", st.Deferred)
+	}
+}
+
+// emitPrepareForSleep emits the PrepareForSleep signal of logind, with start
+// false for a resume.
+func (l *fakeLogind) emitPrepareForSleep(start bool) {
+	l.conn.Emit("/org/freedesktop/login1", "org.freedesktop.login1.Manager.PrepareForSleep", start)
+}
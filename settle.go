@@ -0,0 +1,94 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultSettleWindow is how long the close of a notification with an
+// OnAction or OnReply callback is held back by default, see
+// WithSettleWindow.
+const DefaultSettleWindow = 100 * time.Millisecond
+
+// settlingClose is a close held back for the settling window.
+type settlingClose struct {
+	reason CloseReason
+	timer  Timer
+}
+
+// WithSettleWindow sets how long the close of a notification with an
+// OnAction or OnReply callback is held back, as some daemons signal it just
+// before the action or the inline reply that closed the notification. If the
+// action or reply comes within d, its callback is called first, then the
+// OnClose callback; otherwise the close is delivered after d. Only the closes
+// that may come from the user, ReasonDismissed and ReasonUndefined, are held,
+// and only until an action or reply was signalled. A d of 0 delivers the
+// closes as they come. The default is DefaultSettleWindow.
+func WithSettleWindow(d time.Duration) Option {
+	return func(nf *Notifier) error {
+		if d < 0 {
+			return errors.New("notify: negative settle window")
+		}
+		nf.settleWindow = d
+		return nil
+	}
+}
+
+// holdClose holds back the close of id with reason for the settling window,
+// and returns false if it must be delivered now. Only the closes by the user
+// are held, until an action or reply was signalled. It must be called with
+// nf.mu held.
+func (nf *Notifier) holdClose(id uint32, reason CloseReason) bool {
+	if nf.settleWindow <= 0 || reason != ReasonDismissed && reason != ReasonUndefined {
+		return false
+	}
+	t, ok := nf.tracked[id]
+	if !ok || t.answered || t.n.OnAction == nil && t.n.OnReply == nil {
+		return false
+	}
+	if _, held := nf.settling[id]; held {
+		// The first close holds.
+		return true
+	}
+	if nf.settling == nil {
+		nf.settling = make(map[uint32]settlingClose)
+	}
+	nf.settling[id] = settlingClose{reason, nf.clock.AfterFunc(nf.settleWindow, func() { nf.releaseClose(id) })}
+	return true
+}
+
+// answered records that an action or reply was signalled for id, so that its
+// close is not held back anymore. It must be called with nf.mu held.
+func (nf *Notifier) answered(id uint32) {
+	if t, ok := nf.tracked[id]; ok && !t.answered {
+		t.answered = true
+		nf.tracked[id] = t
+	}
+}
+
+// releaseClose delivers the close of id held back by holdClose, if any.
+func (nf *Notifier) releaseClose(id uint32) {
+	nf.mu.Lock()
+	s, ok := nf.settling[id]
+	if ok {
+		s.timer.Stop()
+		delete(nf.settling, id)
+	}
+	nf.mu.Unlock()
+	if ok {
+		nf.closed(id, s.reason)
+	}
+}
+
+// stopSettling drops the closes held back. It must be called with nf.mu
+// held.
+func (nf *Notifier) stopSettling() {
+	for _, s := range nf.settling {
+		s.timer.Stop()
+	}
+	nf.settling = nil
+}
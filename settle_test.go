@@ -0,0 +1,139 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// settleLog records the callbacks of a notification, in order.
+type settleLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (l *settleLog) add(s string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, s)
+}
+
+func (l *settleLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.calls...)
+}
+
+func (l *settleLog) attach(n *notify.Notification) {
+	n.OnAction = func(key string) { l.add("action " + key) }
+	n.OnReply = func(text string) { l.add("reply " + text) }
+	n.OnClose = func(reason notify.CloseReason) { l.add("close " + reason.String()) }
+}
+
+func TestSettleWindow(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		emit func(s *fakeServer, id uint32)
+		want []string
+	}{
+		{"reply then close", func(s *fakeServer, id uint32) {
+			s.emitReplied(id, "on my way")
+			s.emitClosed(id, uint32(notify.ReasonDismissed))
+		}, []string{"reply on my way", "close dismissed"}},
+		{"close then reply", func(s *fakeServer, id uint32) {
+			s.emitClosed(id, uint32(notify.ReasonDismissed))
+			s.emitReplied(id, "on my way")
+		}, []string{"reply on my way", "close dismissed"}},
+		{"action then close", func(s *fakeServer, id uint32) {
+			s.emitAction(id, "inline-reply")
+			s.emitClosed(id, uint32(notify.ReasonUndefined))
+		}, []string{"action inline-reply", "close undefined"}},
+		{"close then action", func(s *fakeServer, id uint32) {
+			s.emitClosed(id, uint32(notify.ReasonUndefined))
+			s.emitAction(id, "inline-reply")
+		}, []string{"action inline-reply", "close undefined"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			clock := newFakeClock()
+			nf := newTestNotifier(t, notify.WithClock(clock))
+
+			var log settleLog
+			n := notify.New("test", "Message from Bob", "", "", 0, notify.NormalUrgency)
+			n.AddAction("inline-reply", "Reply")
+			log.attach(n)
+			if _, err := nf.Notify(n); err != nil {
+				t.Fatal(err)
+			}
+			// The fake clock does not move: the second signal comes within
+			// the window.
+			tt.emit(s, n.Id)
+			waitFor(t, "the callbacks", func() bool { return len(log.get()) == 2 })
+			if got := log.get(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("callbacks %q, want %q", got, tt.want)
+			}
+			if n := clock.Timers(); n != 0 {
+				t.Errorf("%d timers still running", n)
+			}
+		})
+	}
+}
+
+func TestSettleWindowTimeout(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithSettleWindow(time.Second))
+
+	var log settleLog
+	n := notify.New("test", "Message from Bob", "", "", 0, notify.NormalUrgency)
+	log.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	s.emitClosed(n.Id, uint32(notify.ReasonDismissed))
+	waitFor(t, "the held close", func() bool { return clock.Timers() == 1 })
+	clock.Advance(time.Second - time.Millisecond)
+	if got := log.get(); len(got) != 0 {
+		t.Fatalf("callbacks %q within the window", got)
+	}
+	clock.Advance(time.Millisecond)
+	waitFor(t, "the close", func() bool { return len(log.get()) == 1 })
+
+	// A reply after the window is that of a closed notification.
+	s.emitReplied(n.Id, "too late")
+	s.emitClosed(n.Id, uint32(notify.ReasonDismissed))
+	time.Sleep(20 * time.Millisecond)
+	if got := log.get(); !reflect.DeepEqual(got, []string{"close dismissed"}) {
+		t.Errorf("callbacks %q", got)
+	}
+}
+
+func TestSettleWindowOff(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithClock(newFakeClock()), notify.WithSettleWindow(0))
+
+	var log settleLog
+	n := notify.New("test", "Message from Bob", "", "", 0, notify.NormalUrgency)
+	log.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	s.emitClosed(n.Id, uint32(notify.ReasonDismissed))
+	s.emitReplied(n.Id, "lost")
+	waitFor(t, "the close", func() bool { return len(log.get()) == 1 })
+	time.Sleep(20 * time.Millisecond)
+	if got := log.get(); !reflect.DeepEqual(got, []string{"close dismissed"}) {
+		t.Errorf("callbacks %q, want the close only", got)
+	}
+
+	if _, err := notify.NewNotifier(notify.WithSettleWindow(-time.Second)); err == nil {
+		t.Error("NewNotifier() with a negative settle window succeeded")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrShutdown is returned for the asynchronous operations queued on a
+// Notifier that is shutting down.
+var ErrShutdown = errors.New("notify: notifier is shut down")
+
+// errWaitShutdown is returned by the calls waiting for the user when the
+// Notifier shuts down. It matches both ErrShutdown and context.Canceled.
+var errWaitShutdown = fmt.Errorf("%w: %w", ErrShutdown, context.Canceled)
+
+// Shutdown sends the notifications queued with SendAsync and ReplaceAsync,
+// stops listening to signals, waits for the callbacks that are running, and
+// closes nf like Close. Operations queued afterwards fail with ErrShutdown.
+//
+// With WithCloseOnShutdown, the notifications still open are closed once the
+// queued ones are sent.
+//
+// No callbacks are called once Shutdown is called: the signals received
+// in the meantime are dropped, and calls waiting for the user, like
+// SendAndWait and Prompt, return an error matching both ErrShutdown and
+// context.Canceled right away.
+//
+// Programs that exit right after notifying should defer Shutdown in main, so
+// that pending notifications are not lost when the process exits.
+//
+// If ctx is done first, Shutdown closes nf without waiting any longer and
+// returns the error of ctx.
+func (nf *Notifier) Shutdown(ctx context.Context) error {
+	nf.mu.Lock()
+	if !nf.shutdown {
+		nf.shutdown = true
+		close(nf.stopped)
+	}
+	nf.mu.Unlock()
+
+	lanesDone := make(chan struct{})
+	go func() {
+		nf.lanesRunning.Wait()
+		close(lanesDone)
+	}()
+	err := wait(ctx, lanesDone)
+	if err == nil {
+		nf.closeOpen()
+	}
+
+	nf.mu.Lock()
+	dispatchDone := nf.dispatchDone
+	if nf.conn != nil {
+		nf.stopListening()
+	}
+	nf.mu.Unlock()
+	if err == nil && dispatchDone != nil {
+		err = wait(ctx, dispatchDone)
+	}
+	if err == nil {
+		callbacksDone := make(chan struct{})
+		go func() {
+			nf.callbacksRunning.Wait()
+			close(callbacksDone)
+		}()
+		err = wait(ctx, callbacksDone)
+	}
+
+	if cerr := nf.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// wait waits until done is closed or ctx is done.
+func wait(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestShutdownFlushesAsyncSends(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	const count = 50
+	for i := 0; i < count; i++ {
+		nf.SendAsync(notify.New("test", fmt.Sprint(i), "", "", time.Second, notify.NormalUrgency))
+	}
+	if err := nf.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(s.notifications()); got != count {
+		t.Errorf("%d notifications delivered before Shutdown returned, want %d", got, count)
+	}
+
+	err := <-nf.SendAsync(notify.New("test", "late", "", "", time.Second, notify.NormalUrgency))
+	if !errors.Is(err, notify.ErrShutdown) {
+		t.Errorf("SendAsync after Shutdown returned %v", err)
+	}
+}
+
+func TestShutdownWaitsForCallbacks(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	var started, finished atomic.Bool
+	n := notify.New("test", "slow", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	n.OnAction = func(string) {
+		started.Store(true)
+		time.Sleep(100 * time.Millisecond)
+		finished.Store(true)
+	}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	s.emitAction(n.Id, notify.DefaultAction)
+	waitFor(t, "callback", started.Load)
+
+	if err := nf.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !finished.Load() {
+		t.Error("Shutdown returned before the callback finished")
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	var started atomic.Bool
+	n := notify.New("test", "stuck", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	n.OnAction = func(string) {
+		started.Store(true)
+		<-release
+	}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	s.emitAction(n.Id, notify.DefaultAction)
+	waitFor(t, "callback", started.Load)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := nf.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown returned %v with a stuck callback", err)
+	}
+}
+
+func TestShutdownClickBefore(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	var calls atomic.Int32
+	n := notify.New("test", "click", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	n.OnAction = func(string) { calls.Add(1) }
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+
+	s.emitAction(n.Id, notify.DefaultAction)
+	if err := nf.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The click was either handled before Shutdown returned, or dropped.
+	before := calls.Load()
+	time.Sleep(50 * time.Millisecond)
+	if after := calls.Load(); after != before || after > 1 {
+		t.Errorf("OnAction called %d times after Shutdown returned, %d before", after-before, before)
+	}
+}
+
+func TestShutdownClickAfter(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	var calls atomic.Int32
+	n := notify.New("test", "click", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	n.OnAction = func(string) { calls.Add(1) }
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if err := nf.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	s.emitAction(n.Id, notify.DefaultAction)
+	time.Sleep(50 * time.Millisecond)
+	if got := calls.Load(); got != 0 {
+		t.Errorf("OnAction called %d times for a click after Shutdown", got)
+	}
+}
+
+func TestShutdownInterruptsSendAndWait(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	errs := make(chan error, 1)
+	go func() {
+		n := notify.New("test", "waiting", "", "", 0, notify.NormalUrgency)
+		n.AddAction("ok", "OK")
+		_, err := nf.SendAndWait(context.Background(), n)
+		errs <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := nf.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, notify.ErrShutdown) {
+			t.Errorf("SendAndWait returned %v", err)
+		}
+	case <-waitTimeout():
+		t.Fatal("SendAndWait still waiting after Shutdown")
+	}
+}
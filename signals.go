@@ -0,0 +1,488 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// CloseReason is the reason a notification was closed, as given by the
+// daemon in the NotificationClosed signal.
+type CloseReason uint32
+
+const (
+	ReasonExpired   CloseReason = iota + 1 // ReasonExpired means the notification timed out.
+	ReasonDismissed                        // ReasonDismissed means the user dismissed the notification.
+	ReasonClosed                           // ReasonClosed means CloseNotification was called.
+	ReasonUndefined                        // ReasonUndefined is for everything else.
+)
+
+// String returns "expired", "dismissed", "closed" or "undefined".
+func (r CloseReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonDismissed:
+		return "dismissed"
+	case ReasonClosed:
+		return "closed"
+	case ReasonUndefined:
+		return "undefined"
+	}
+	return fmt.Sprintf("CloseReason(%d)", uint32(r))
+}
+
+// closeOnActionDelay is how long to wait after an action was invoked on a
+// notification with CloseOnAction before closing it. Most daemons that close
+// notifications by themselves do so right away, and closing a notification
+// that is already gone is an error for some of them.
+const closeOnActionDelay = 50 * time.Millisecond
+
+// trackedNotification is a notification receiving signals, with the call it
+// was last sent with, and the context of that send for the trace hook.
+type trackedNotification struct {
+	n    *Notification
+	call Call
+	ctx  context.Context
+	// sent is the wall clock time of the send, and shown the time, with its
+	// monotonic clock reading, since which the notification is shown.
+	sent  time.Time
+	shown time.Time
+	// answered is true once an action or a reply was signalled, see
+	// holdClose.
+	answered bool
+}
+
+// hasAction returns true if key is one of the actions t was sent with.
+func (t trackedNotification) hasAction(key string) bool {
+	for i := 0; i < len(t.call.Actions); i += 2 {
+		if t.call.Actions[i] == key {
+			return true
+		}
+	}
+	return false
+}
+
+// track registers n, sent with c in ctx, to receive the signals for its ID,
+// starting to listen for signals if necessary.
+func (nf *Notifier) track(ctx context.Context, n *Notification, c Call) error {
+	if !nf.onBus() || n.OnAction == nil && n.OnClose == nil && n.OnReply == nil && !n.CloseOnAction && !n.Persistent && nf.traceHook == nil && !nf.pagesActions(n, c) {
+		nf.untrack(n.Id)
+		return nil
+	}
+	conn, err := nf.connection()
+	if err != nil {
+		return err
+	}
+	if err := nf.listen(conn); err != nil {
+		return err
+	}
+
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if nf.tracked == nil {
+		nf.tracked = make(map[uint32]trackedNotification)
+	}
+	now := nf.clock.Now()
+	shown := now
+	if old, ok := nf.tracked[n.Id]; ok && old.n == n {
+		// Replaced in place, it is still shown.
+		shown = old.shown
+	}
+	nf.tracked[n.Id] = trackedNotification{n: n, call: c, ctx: ctx, sent: wallTime(now), shown: shown}
+	return nil
+}
+
+// untrack stops delivering signals for id.
+func (nf *Notifier) untrack(id uint32) {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	delete(nf.tracked, id)
+	if t, ok := nf.closing[id]; ok {
+		t.Stop()
+		delete(nf.closing, id)
+	}
+}
+
+// untrackAll stops delivering signals for all the notifications of nf, when
+// the daemon that showed them is gone: the IDs of its notifications mean
+// nothing to the next one, which may assign them to others.
+func (nf *Notifier) untrackAll() {
+	nf.mu.Lock()
+	dropped := len(nf.tracked)
+	for id, t := range nf.closing {
+		t.Stop()
+		delete(nf.closing, id)
+	}
+	nf.stopSettling()
+	nf.tracked = nil
+	nf.activationTokens = nil
+	nf.actionPages = nil
+	nf.trackOrder = trackOrder{}
+	nf.mu.Unlock()
+
+	if dropped > 0 {
+		nf.log(LevelInfo, fmt.Sprintf("notification daemon gone, dropping the callbacks of %d notifications", dropped), nil)
+	}
+}
+
+// listen subscribes to the signals of the notification daemon, once. The
+// Notifiers of a Core get them from the Core.
+func (nf *Notifier) listen(conn *dbus.Conn) error {
+	if nf.core != nil {
+		return nf.core.subscribe(conn, nf)
+	}
+
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if nf.signals != nil {
+		return nil
+	}
+	return nf.installListener(conn)
+}
+
+// installListener adds the match rules of nf to conn, and dispatches the
+// signals received on it. nf.mu must be held.
+func (nf *Notifier) installListener(conn *dbus.Conn) error {
+	if err := conn.AddMatchSignal(nf.matchOptions()...); err != nil {
+		return err
+	}
+	if err := conn.AddMatchSignal(nf.ownerMatchOptions()...); err != nil {
+		conn.RemoveMatchSignal(nf.matchOptions()...)
+		return err
+	}
+	ch := make(chan *dbus.Signal, 16)
+	conn.Signal(ch)
+	nf.signals = ch
+	nf.stopDispatch = make(chan struct{})
+	nf.dispatchDone = make(chan struct{})
+	go nf.dispatch(conn, ch, nf.stopDispatch, nf.dispatchDone)
+	return nil
+}
+
+// listenAlways starts listening to the signals of the daemon if nf is on the
+// bus, even if no notification has callbacks.
+func (nf *Notifier) listenAlways() {
+	if !nf.onBus() {
+		return
+	}
+	conn, err := nf.connection()
+	if err == nil {
+		err = nf.listen(conn)
+	}
+	if err != nil {
+		nf.log(LevelWarn, "listening to the signals of the daemon failed", err)
+	}
+}
+
+// stopListening unsubscribes from the signals of the notification daemon.
+// It must be called with nf.mu held, before the connection is closed.
+func (nf *Notifier) stopListening() {
+	if nf.core != nil {
+		nf.core.unsubscribe(nf)
+	} else if nf.signals != nil {
+		nf.conn.RemoveMatchSignal(nf.matchOptions()...)
+		nf.conn.RemoveMatchSignal(nf.ownerMatchOptions()...)
+		nf.conn.RemoveSignal(nf.signals)
+		close(nf.stopDispatch)
+		nf.signals = nil
+		nf.stopDispatch = nil
+		nf.dispatchDone = nil
+		nf.reconnect = reconnectState{}
+	} else {
+		return
+	}
+	for id, t := range nf.closing {
+		t.Stop()
+		delete(nf.closing, id)
+	}
+	nf.stopSettling()
+	nf.tracked = nil
+	nf.activationTokens = nil
+}
+
+// matchOptions returns the match rule for the signals of the daemon.
+func (nf *Notifier) matchOptions() []dbus.MatchOption {
+	return []dbus.MatchOption{
+		dbus.WithMatchSender(nf.destination),
+		dbus.WithMatchObjectPath(nf.path),
+		dbus.WithMatchInterface(dbusInterface),
+	}
+}
+
+// ownerMatchOptions returns the match rule for the changes of the owner of
+// the bus name of the daemon.
+func (nf *Notifier) ownerMatchOptions() []dbus.MatchOption {
+	return []dbus.MatchOption{
+		dbus.WithMatchSender("org.freedesktop.DBus"),
+		dbus.WithMatchInterface("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, nf.destination),
+	}
+}
+
+// dispatch delivers the signals received on ch from conn until stop is
+// closed, then closes done. The signals are delivered to the Notifiers of
+// the core of nf too, if it is one. If conn closes ch because it was lost,
+// dispatch connects again, see connection.
+func (nf *Notifier) dispatch(conn *dbus.Conn, ch chan *dbus.Signal, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	for {
+		select {
+		case sig, ok := <-ch:
+			if !ok {
+				nf.mu.Lock()
+				lost := nf.signals == ch
+				nf.mu.Unlock()
+				if !lost {
+					return
+				}
+				<-conn.Context().Done()
+				nf.log(LevelWarn, "lost the connection to the bus, connecting again", nil)
+				if _, err := nf.connection(); err != nil {
+					nf.log(LevelWarn, "connecting to the bus again failed", err)
+				}
+				return
+			}
+			nf.deliverSignal(sig)
+			if nf.fanout != nil {
+				for _, sub := range nf.fanout.listeners() {
+					sub.deliverSignal(sig)
+				}
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// deliverSignal delivers sig to nf, recovering from any panic, for example
+// of the logger, so that the dispatcher goes on with the next signals.
+func (nf *Notifier) deliverSignal(sig *dbus.Signal) {
+	defer func() {
+		if r := recover(); r != nil {
+			nf.log(LevelError, "handling a "+sig.Name+" signal panicked", fmt.Errorf("%v", r))
+		}
+	}()
+	nf.handleSignal(sig)
+}
+
+// handleSignal delivers sig to nf. Malformed signals are logged and
+// dropped; arguments past the ones of the specification are ignored.
+func (nf *Notifier) handleSignal(sig *dbus.Signal) {
+	nf.traceSignal(sig)
+	if sig.Name == "org.freedesktop.DBus.NameOwnerChanged" {
+		if len(sig.Body) > 0 && sig.Body[0] == nf.destination {
+			nf.log(LevelInfo, "notification daemon changed, dropping the cached capabilities", nil)
+			nf.InvalidateCaches()
+			if len(sig.Body) > 1 && sig.Body[1] != "" {
+				nf.untrackAll()
+			}
+		}
+		return
+	}
+	if sig.Path != nf.path {
+		return
+	}
+
+	switch sig.Name {
+	case dbusInterface + ".ActionInvoked":
+		id, key, ok := signalArgs[string](sig)
+		if !ok {
+			nf.log(LevelWarn, "dropped malformed ActionInvoked signal", fmt.Errorf("arguments %v", sig.Body))
+			return
+		}
+		nf.actionInvoked(id, key)
+	case dbusInterface + ".NotificationClosed":
+		id, reason, ok := signalArgs[uint32](sig)
+		if !ok {
+			nf.log(LevelWarn, "dropped malformed NotificationClosed signal", fmt.Errorf("arguments %v", sig.Body))
+			return
+		}
+		nf.notificationClosed(id, CloseReason(reason))
+	case dbusInterface + ".NotificationReplied":
+		id, text, ok := signalArgs[string](sig)
+		if !ok {
+			nf.log(LevelWarn, "dropped malformed NotificationReplied signal", fmt.Errorf("arguments %v", sig.Body))
+			return
+		}
+		nf.notificationReplied(id, text)
+	case dbusInterface + ".ActivationToken":
+		id, token, ok := signalArgs[string](sig)
+		if !ok {
+			nf.log(LevelWarn, "dropped malformed ActivationToken signal", fmt.Errorf("arguments %v", sig.Body))
+			return
+		}
+		nf.activationTokenReceived(id, token)
+	}
+}
+
+// signalArgs returns the arguments of a signal made of a notification ID
+// and a value of type T.
+func signalArgs[T any](sig *dbus.Signal) (id uint32, v T, ok bool) {
+	if len(sig.Body) < 2 {
+		return 0, v, false
+	}
+	id, ok = sig.Body[0].(uint32)
+	if !ok {
+		return 0, v, false
+	}
+	v, ok = sig.Body[1].(T)
+	return id, v, ok
+}
+
+func (nf *Notifier) actionInvoked(id uint32, key string) {
+	nf.mu.Lock()
+	t, ok := nf.tracked[id]
+	if ok && !t.hasAction(key) {
+		nf.mu.Unlock()
+		nf.log(LevelWarn, fmt.Sprintf("ignored action %q invoked on notification %d, which does not have it", key, id), nil)
+		return
+	}
+	n := t.n
+	if ok && key == MoreActionsAction && nf.turnActionPage(n, id) {
+		nf.mu.Unlock()
+		return
+	}
+	nf.answered(id)
+	token := nf.activationTokens[id]
+	delete(nf.activationTokens, id)
+	corr := nf.recordSignal(id, func(r *Record) {
+		r.Action, r.ActionAt = key, nf.clock.Now()
+	})
+	// The callback is read under the lock, which a wait restoring the
+	// callbacks of n holds too.
+	var onAction func(string)
+	if ok {
+		corr, onAction = n.CorrelationID, n.OnAction
+	}
+	if ok && n.CloseOnAction {
+		if nf.closing == nil {
+			nf.closing = make(map[uint32]Timer)
+		}
+		if _, pending := nf.closing[id]; !pending {
+			nf.closing[id] = nf.clock.AfterFunc(closeOnActionDelay, func() { nf.closeAfterAction(id) })
+		}
+	}
+	nf.mu.Unlock()
+
+	if key == AckAction {
+		nf.stopRepost(id)
+	}
+	nf.emit(Event{Kind: EventAction, ID: id, Key: key, CorrelationID: corr, ActivationToken: token})
+	if ok {
+		n.activationToken = token
+		nf.trace(t.ctx, PhaseSignalAction, &t.call, nil)
+	}
+	if onAction != nil {
+		nf.runCallback("OnAction", id, func() { onAction(key) })
+	}
+	nf.releaseClose(id)
+}
+
+// closeAfterAction closes id, unless the daemon closed it in the meantime.
+func (nf *Notifier) closeAfterAction(id uint32) {
+	nf.mu.Lock()
+	_, pending := nf.closing[id]
+	delete(nf.closing, id)
+	nf.mu.Unlock()
+
+	if !pending {
+		return
+	}
+	if err := nf.CloseNotification(id); err != nil {
+		nf.log(LevelWarn, fmt.Sprintf("closing notification %d after its action failed", id), err)
+	}
+}
+
+func (nf *Notifier) notificationReplied(id uint32, text string) {
+	nf.mu.Lock()
+	t, ok := nf.tracked[id]
+	nf.answered(id)
+	corr := nf.recordSignal(id, func(r *Record) {})
+	nf.mu.Unlock()
+	n := t.n
+	if ok {
+		corr = n.CorrelationID
+	}
+
+	nf.emit(Event{Kind: EventReplied, ID: id, CorrelationID: corr})
+	if ok && n.OnReply != nil {
+		nf.runCallback("OnReply", id, func() { n.OnReply(text) })
+	}
+	nf.releaseClose(id)
+}
+
+func (nf *Notifier) notificationClosed(id uint32, reason CloseReason) {
+	if reason < ReasonExpired || reason > ReasonUndefined {
+		reason = ReasonUndefined
+	}
+
+	nf.mu.Lock()
+	if nf.expiredUnseen(id, reason) {
+		nf.mu.Unlock()
+		nf.log(LevelDebug, fmt.Sprintf("ignored the expiry of notification %d, posted again after the resume", id), nil)
+		return
+	}
+	if nf.holdClose(id, reason) {
+		nf.mu.Unlock()
+		return
+	}
+	nf.mu.Unlock()
+	nf.closed(id, reason)
+}
+
+// closed delivers the close of id for reason.
+func (nf *Notifier) closed(id uint32, reason CloseReason) {
+	nf.mu.Lock()
+	t, ok := nf.tracked[id]
+	nf.forgetSent(id)
+	nf.forgetTracked(id)
+	nf.leaveScope(id)
+	delete(nf.openIDs, id)
+	delete(nf.activationTokens, id)
+	if ok && nf.actionPageClosed(t.n, id) {
+		// The next page of actions replaces it.
+		nf.mu.Unlock()
+		nf.untrack(id)
+		nf.tempFiles().release(id)
+		return
+	}
+	now := nf.clock.Now()
+	var displayed time.Duration
+	known := false
+	corr := nf.recordSignal(id, func(r *Record) {
+		r.ClosedAt, r.Reason = now, reason
+		r.DisplayedFor, r.DisplayedKnown = max(now.Sub(r.shownAt), 0), true
+		displayed, known = r.DisplayedFor, true
+		delete(nf.history.open, id)
+	})
+	n := t.n
+	var onClose func(CloseReason)
+	if ok {
+		onClose = n.OnClose
+	}
+	nf.mu.Unlock()
+	if ok {
+		corr = n.CorrelationID
+		if !known {
+			displayed, known = max(now.Sub(t.shown), 0), true
+		}
+	}
+	nf.untrack(id)
+	nf.tempFiles().release(id)
+
+	nf.emit(Event{Kind: EventClosed, ID: id, Reason: reason, CorrelationID: corr, DisplayedFor: displayed, DisplayedKnown: known})
+	if ok {
+		nf.trace(t.ctx, PhaseSignalClosed, &t.call, nil)
+	}
+	if onClose != nil {
+		nf.runCallback("OnClose", id, func() { onClose(reason) })
+	}
+}
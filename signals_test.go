@@ -0,0 +1,430 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// callbacks records the callbacks of a notification.
+type callbacks struct {
+	mu      sync.Mutex
+	actions []string
+	reasons []notify.CloseReason
+}
+
+func (c *callbacks) attach(n *notify.Notification) {
+	n.OnAction = func(key string) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.actions = append(c.actions, key)
+	}
+	n.OnClose = func(reason notify.CloseReason) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.reasons = append(c.reasons, reason)
+	}
+}
+
+func (c *callbacks) closed() []notify.CloseReason {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]notify.CloseReason(nil), c.reasons...)
+}
+
+func (c *callbacks) invoked() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.actions...)
+}
+
+func TestCloseOnAction(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+
+	var cb callbacks
+	n := notify.New("test", "close me", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	n.CloseOnAction = true
+	cb.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if _, ok := got.Hints["resident"]; ok {
+		t.Error("resident hint set with CloseOnAction")
+	}
+	if len(got.Actions) != 2 || got.Actions[0] != notify.DefaultAction || got.Actions[1] != "Open" {
+		t.Errorf("unexpected actions %q", got.Actions)
+	}
+
+	s.emitAction(n.Id, notify.DefaultAction)
+	waitFor(t, "close timer", func() bool { return clock.Timers() == 1 })
+	clock.Advance(time.Second)
+	waitFor(t, "close after action", func() bool { return len(cb.closed()) == 1 })
+	if ids := s.closedIDs(); len(ids) != 1 || ids[0] != n.Id {
+		t.Errorf("CloseNotification called with %v, want [%d]", ids, n.Id)
+	}
+	if keys := cb.invoked(); len(keys) != 1 || keys[0] != notify.DefaultAction {
+		t.Errorf("OnAction called with %q", keys)
+	}
+	if r := cb.closed()[0]; r != notify.ReasonClosed {
+		t.Errorf("OnClose called with %v, want ReasonClosed", r)
+	}
+}
+
+func TestCloseOnActionDaemonClosesFirst(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+
+	var cb callbacks
+	n := notify.New("test", "close me", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	n.CloseOnAction = true
+	cb.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+
+	s.emitAction(n.Id, notify.DefaultAction)
+	s.emitClosed(n.Id, uint32(notify.ReasonDismissed))
+	waitFor(t, "close signal", func() bool { return len(cb.closed()) == 1 })
+	if n := clock.Timers(); n != 0 {
+		t.Errorf("%d close timers still pending", n)
+	}
+	clock.Advance(time.Second)
+
+	if ids := s.closedIDs(); len(ids) != 0 {
+		t.Errorf("CloseNotification called with %v after the daemon closed it", ids)
+	}
+	if r := cb.closed(); len(r) != 1 || r[0] != notify.ReasonDismissed {
+		t.Errorf("OnClose called with %v, want [ReasonDismissed]", r)
+	}
+}
+
+func TestResidentWithoutCloseOnAction(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	n := notify.New("test", "stay", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := s.last(t).Hints["resident"]; !ok || v.Value() != true {
+		t.Errorf("resident hint = %v, want true", v)
+	}
+}
+
+// logRecorder records the messages passed to a Logger.
+type logRecorder struct {
+	mu   sync.Mutex
+	msgs []string
+	errs []error
+}
+
+func (r *logRecorder) log(level notify.Level, msg string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, level.String()+": "+msg)
+	r.errs = append(r.errs, err)
+}
+
+func (r *logRecorder) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.msgs)
+}
+
+// logged returns true if a message containing substr was logged.
+func (r *logRecorder) logged(substr string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range r.msgs {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCallbackPanicIsLogged(t *testing.T) {
+	s := newFakeServer(t)
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithLogger(logs.log))
+
+	n := notify.New("test", "panic", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Boom")
+	n.OnAction = func(string) { panic("boom") }
+	var cb callbacks
+	m := notify.New("test", "healthy", "", "", 0, notify.NormalUrgency)
+	m.AddAction(notify.DefaultAction, "Open")
+	cb.attach(m)
+	for _, n := range []*notify.Notification{n, m} {
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.emitAction(n.Id, notify.DefaultAction)
+	s.emitAction(m.Id, notify.DefaultAction)
+	waitFor(t, "healthy callback", func() bool { return len(cb.invoked()) == 1 })
+
+	if logs.len() != 1 {
+		t.Fatalf("logged %q, want the panic only", logs.msgs)
+	}
+	if logs.msgs[0] != "error: OnAction callback panicked" || logs.errs[0] == nil {
+		t.Errorf("logged %q (%v)", logs.msgs[0], logs.errs[0])
+	}
+}
+
+func TestMalformedSignalIsLogged(t *testing.T) {
+	s := newFakeServer(t)
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithLogger(logs.log))
+
+	n := notify.New("test", "malformed", "", "", 0, notify.NormalUrgency)
+	n.OnClose = func(notify.CloseReason) {}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	s.conn.Emit(s.path, "org.freedesktop.Notifications.NotificationClosed", n.Id, "expired")
+	waitFor(t, "log message", func() bool { return logs.len() == 1 })
+	if logs.msgs[0] != "warn: dropped malformed NotificationClosed signal" {
+		t.Errorf("logged %q", logs.msgs[0])
+	}
+}
+
+func TestCallbackPanicIsAFailedEvent(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	events := nf.Events()
+
+	n := notify.New("test", "panic", "", "", 0, notify.NormalUrgency)
+	n.OnClose = func(notify.CloseReason) { panic("boom") }
+	var cb callbacks
+	m := notify.New("test", "healthy", "", "", 0, notify.NormalUrgency)
+	cb.attach(m)
+	for _, n := range []*notify.Notification{n, m} {
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.emitClosed(n.Id, uint32(notify.ReasonExpired))
+	s.emitClosed(m.Id, uint32(notify.ReasonExpired))
+	waitFor(t, "healthy callback", func() bool { return len(cb.closed()) == 1 })
+
+	var kinds []notify.EventKind
+	var failed notify.Event
+	timeout := time.After(time.Second)
+	for len(kinds) < 5 {
+		select {
+		case e := <-events:
+			kinds = append(kinds, e.Kind)
+			if e.Kind == notify.EventFailed {
+				failed = e
+			}
+		case <-timeout:
+			t.Fatalf("only received events %v", kinds)
+		}
+	}
+	want := []notify.EventKind{notify.EventSent, notify.EventSent, notify.EventClosed, notify.EventFailed, notify.EventClosed}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("received events %v, want %v", kinds, want)
+		}
+	}
+	if failed.ID != n.Id || failed.Err == nil {
+		t.Errorf("unexpected failed event %+v", failed)
+	}
+}
+
+func TestUnknownCloseReason(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	reasons := make(chan notify.CloseReason, 1)
+	n := notify.New("test", "closing", "", "", 0, notify.NormalUrgency)
+	n.OnClose = func(reason notify.CloseReason) { reasons <- reason }
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	s.emitClosed(n.Id, 42)
+	select {
+	case got := <-reasons:
+		if got != notify.ReasonUndefined {
+			t.Errorf("unknown reason parsed as %v, want ReasonUndefined", got)
+		}
+	case <-waitTimeout():
+		t.Fatal("no close signal")
+	}
+}
+
+// TestActionRouting checks that actions are routed by notification and key,
+// not by key only.
+func TestActionRouting(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	var first, second callbacks
+	a := notify.New("test", "first", "", "", 0, notify.NormalUrgency)
+	a.AddAction("open", "Open")
+	first.attach(a)
+	b := notify.New("test", "second", "", "", 0, notify.NormalUrgency)
+	b.AddAction("open", "Open")
+	second.attach(b)
+	for _, n := range []*notify.Notification{a, b} {
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.emitAction(a.Id, "open")
+	s.emitAction(b.Id, "open")
+	s.emitAction(a.Id, "open")
+	waitFor(t, "interleaved actions", func() bool {
+		return len(first.invoked()) == 2 && len(second.invoked()) == 1
+	})
+
+	// A click on a notification closed before it is dispatched is dropped.
+	s.emitClosed(a.Id, uint32(notify.ReasonDismissed))
+	s.emitAction(a.Id, "open")
+	s.emitAction(b.Id, "open")
+	waitFor(t, "action on the survivor", func() bool { return len(second.invoked()) == 2 })
+	if got := first.invoked(); len(got) != 2 {
+		t.Errorf("closed notification got actions %q", got)
+	}
+}
+
+// TestActionRoutingAfterRestart checks that the callbacks of the notifications
+// of a daemon that is gone are not called for the notifications of the next
+// one, which reuses their IDs.
+func TestActionRoutingAfterRestart(t *testing.T) {
+	s := newFakeServer(t)
+	var logs logRecorder
+	nf := newTestNotifier(t, notify.WithLogger(logs.log))
+
+	var stale callbacks
+	n := notify.New("test", "before", "", "", 0, notify.NormalUrgency)
+	n.AddAction("open", "Open")
+	stale.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+
+	events := nf.Events()
+
+	s.conn.Close()
+	s = newFakeServer(t)
+	waitFor(t, "callbacks dropped", func() bool { return logs.logged("dropping the callbacks of 1 notifications") })
+
+	// Another application gets the ID of n from the new daemon.
+	m := notify.New("other", "after", "", "", 0, notify.NormalUrgency)
+	m.AddAction("open", "Open")
+	if _, err := newTestNotifier(t).Notify(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Id != n.Id {
+		t.Fatalf("daemon assigned %d, want the reused %d", m.Id, n.Id)
+	}
+	s.emitAction(m.Id, "open")
+	waitEvent(t, events, notify.EventAction)
+	if got := stale.invoked(); len(got) != 0 {
+		t.Errorf("stale callbacks got actions %q", got)
+	}
+}
+
+func TestMalformedSignals(t *testing.T) {
+	const iface = "org.freedesktop.Notifications."
+	tests := []struct {
+		name   string
+		member string
+		args   func(id uint32) []interface{}
+		// dropped is true if the signal is logged and dropped; otherwise
+		// it must reach the callbacks of the notification.
+		dropped bool
+	}{
+		{"unknown reason", "NotificationClosed", func(id uint32) []interface{} { return []interface{}{id, uint32(7)} }, false},
+		{"zero reason", "NotificationClosed", func(id uint32) []interface{} { return []interface{}{id, uint32(0)} }, false},
+		{"extra arguments", "ActionInvoked", func(id uint32) []interface{} { return []interface{}{id, "open", "x-extra", int32(1)} }, false},
+		{"no arguments", "ActionInvoked", func(id uint32) []interface{} { return nil }, true},
+		{"missing key", "ActionInvoked", func(id uint32) []interface{} { return []interface{}{id} }, true},
+		{"signed ID", "ActionInvoked", func(id uint32) []interface{} { return []interface{}{int32(id), "open"} }, true},
+		{"string reason", "NotificationClosed", func(id uint32) []interface{} { return []interface{}{id, "dismissed"} }, true},
+		{"numeric reply", "NotificationReplied", func(id uint32) []interface{} { return []interface{}{id, uint32(3)} }, true},
+		{"empty token", "ActivationToken", func(id uint32) []interface{} { return []interface{}{id} }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			var logs logRecorder
+			nf := newTestNotifier(t, notify.WithLogger(logs.log))
+			var cb callbacks
+			n := notify.New("test", "malformed", "", "", 0, notify.NormalUrgency)
+			n.AddAction("open", "Open")
+			cb.attach(n)
+			n.OnReply = func(string) { t.Error("OnReply called") }
+			if _, err := nf.Notify(n); err != nil {
+				t.Fatal(err)
+			}
+
+			s.conn.Emit(s.path, iface+tt.member, tt.args(n.Id)...)
+			if tt.dropped {
+				waitFor(t, "log message", func() bool { return logs.logged("dropped malformed " + tt.member) })
+				// The dispatcher goes on.
+				s.emitAction(n.Id, "open")
+				waitFor(t, "the next action", func() bool { return len(cb.invoked()) == 1 })
+				if got := cb.closed(); len(got) != 0 {
+					t.Errorf("OnClose got %v", got)
+				}
+				return
+			}
+			waitFor(t, "the callbacks", func() bool { return len(cb.invoked())+len(cb.closed()) == 1 })
+			if got := cb.closed(); len(got) == 1 && got[0] != notify.ReasonUndefined {
+				t.Errorf("OnClose got %v, want ReasonUndefined", got[0])
+			}
+			if got := cb.invoked(); len(got) == 1 && got[0] != "open" {
+				t.Errorf("OnAction got %q", got[0])
+			}
+			if logs.len() != 0 {
+				t.Errorf("logged %q", logs.msgs)
+			}
+		})
+	}
+}
+
+func TestSignalPanicIsRecovered(t *testing.T) {
+	s := newFakeServer(t)
+	var logs logRecorder
+	logger := func(level notify.Level, msg string, err error) {
+		if strings.HasPrefix(msg, "dropped malformed") {
+			panic("boom")
+		}
+		logs.log(level, msg, err)
+	}
+	nf := newTestNotifier(t, notify.WithLogger(logger))
+	var cb callbacks
+	n := notify.New("test", "panic", "", "", 0, notify.NormalUrgency)
+	n.AddAction("open", "Open")
+	cb.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+
+	s.conn.Emit(s.path, "org.freedesktop.Notifications.ActionInvoked", n.Id)
+	s.emitAction(n.Id, "open")
+	waitFor(t, "the next action", func() bool { return len(cb.invoked()) == 1 })
+	if !logs.logged("handling a org.freedesktop.Notifications.ActionInvoked signal panicked") {
+		t.Errorf("logged %q", logs.msgs)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// SizeLimits caps the size of the notifications, so that a caller cannot
+// bloat the state kept for each notification, or the message sent, until
+// the bus or the daemon disconnects the Notifier. See WithSizeLimits.
+type SizeLimits struct {
+	// MaxActions is the maximum number of actions of a notification.
+	MaxActions int
+	// MaxLabelRunes is the maximum length of an action label in runes.
+	MaxLabelRunes int
+	// MaxHintsBytes is the maximum size of the hints, image included, as
+	// estimated for the D-Bus message.
+	MaxHintsBytes int
+}
+
+// DefaultSizeLimits are the limits of Validate and of the Notifiers without
+// WithSizeLimits. They are far above what any daemon shows: the hints may
+// hold an image of a thousand pixels square, and the session bus refuses
+// messages of 32 MiB.
+var DefaultSizeLimits = SizeLimits{
+	MaxActions:    64,
+	MaxLabelRunes: 256,
+	MaxHintsBytes: 16 << 20,
+}
+
+// WithSizeLimits replaces the DefaultSizeLimits of the notifications sent by
+// the Notifier, and checked by Notifier.Validate. The fields left at 0 keep
+// their default. A notification above a limit fails to send with a
+// *ValidationError, with ErrActionLimit, ErrTooLong or ErrHintsTooLarge.
+func WithSizeLimits(limits SizeLimits) Option {
+	return func(nf *Notifier) error {
+		if limits.MaxActions < 0 || limits.MaxLabelRunes < 0 || limits.MaxHintsBytes < 0 {
+			return fmt.Errorf("notify: negative size limits %+v", limits)
+		}
+		nf.sizeLimits = limits
+		return nil
+	}
+}
+
+// withDefaults returns l with the fields left at 0 set to their default.
+func (l SizeLimits) withDefaults() SizeLimits {
+	if l.MaxActions == 0 {
+		l.MaxActions = DefaultSizeLimits.MaxActions
+	}
+	if l.MaxLabelRunes == 0 {
+		l.MaxLabelRunes = DefaultSizeLimits.MaxLabelRunes
+	}
+	if l.MaxHintsBytes == 0 {
+		l.MaxHintsBytes = DefaultSizeLimits.MaxHintsBytes
+	}
+	return l
+}
+
+// check returns the FieldErrors of the limits n is above.
+func (l SizeLimits) check(n *Notification) []error {
+	l = l.withDefaults()
+	var errs []error
+	if len(n.Actions) > l.MaxActions {
+		errs = append(errs, &FieldError{"actions", fmt.Errorf("%w: %d, at most %d", ErrActionLimit, len(n.Actions), l.MaxActions)})
+	}
+	for i, a := range n.Actions {
+		if runes := utf8.RuneCountInString(a.Label); runes > l.MaxLabelRunes {
+			errs = append(errs, &FieldError{fmt.Sprintf("actions[%d]", i), fmt.Errorf("%w: label of %d runes, at most %d", ErrTooLong, runes, l.MaxLabelRunes)})
+		}
+	}
+	if size := notificationHintsSize(n); size > l.MaxHintsBytes {
+		errs = append(errs, &FieldError{"hints", fmt.Errorf("%w: about %d bytes, at most %d", ErrHintsTooLarge, size, l.MaxHintsBytes)})
+	}
+	return errs
+}
+
+// notificationHintsSize estimates the size of the hints set with SetHint
+// and of the image of n on the wire. The hints that cannot be sent are left
+// to checkHints.
+func notificationHintsSize(n *Notification) int {
+	// The hints are a dictionary: its length, then its entries, each
+	// aligned to 8 bytes.
+	var e wireSizer
+	e.fixed(4)
+	e.align(8)
+	for k, v := range n.hints {
+		if k == "image-data" && n.image != nil {
+			continue
+		}
+		if variant, err := hintValue(v); err == nil {
+			e.entry(k, variant.Signature().String(), reflect.ValueOf(variant.Value()))
+		}
+	}
+	if n.image != nil {
+		e.entry("image-data", imageDataSignature, reflect.ValueOf(n.image).Elem())
+	}
+	return e.n
+}
+
+// wireHeaderSize is about the size of the header of a Notify call on the
+// session bus: the path, interface, member, destination, sender and body
+// signature.
+const wireHeaderSize = 160
+
+// wireSize estimates the size of the D-Bus message of the call c.
+func wireSize(c Call) int {
+	e := wireSizer{n: wireHeaderSize}
+	for _, v := range []interface{}{c.AppName, c.ReplacesID, c.AppIcon, c.Summary, c.Body, c.Actions, c.Hints, c.ExpireTimeout} {
+		e.value(reflect.ValueOf(v))
+	}
+	return e.n
+}
+
+var (
+	variantType   = reflect.TypeOf(dbus.Variant{})
+	signatureType = reflect.TypeOf(dbus.Signature{})
+)
+
+// wireSizer adds up the size of values marshalled as D-Bus does, with their
+// alignment padding, in n.
+type wireSizer struct {
+	n int
+}
+
+// align pads n to a multiple of size.
+func (e *wireSizer) align(size int) {
+	e.n = (e.n + size - 1) / size * size
+}
+
+// fixed adds a fixed-size value of size bytes.
+func (e *wireSizer) fixed(size int) {
+	e.align(size)
+	e.n += size
+}
+
+// entry adds the entry of a dictionary of variants with key and the value
+// v of the signature sig.
+func (e *wireSizer) entry(key, sig string, v reflect.Value) {
+	e.align(8)
+	e.fixed(4)
+	e.n += len(key) + 1
+	e.n += len(sig) + 2
+	e.value(v)
+}
+
+// value adds v, of a type godbus can marshal.
+func (e *wireSizer) value(v reflect.Value) {
+	if !v.IsValid() {
+		return
+	}
+	switch v.Type() {
+	case variantType:
+		variant := v.Interface().(dbus.Variant)
+		e.n += len(variant.Signature().String()) + 2
+		e.value(reflect.ValueOf(variant.Value()))
+		return
+	case signatureType:
+		e.n += len(v.Interface().(dbus.Signature).String()) + 2
+		return
+	}
+	switch v.Kind() {
+	case reflect.Uint8:
+		e.n++
+	case reflect.Int16, reflect.Uint16:
+		e.fixed(2)
+	case reflect.Bool, reflect.Int32, reflect.Uint32:
+		e.fixed(4)
+	case reflect.Int, reflect.Uint, reflect.Int64, reflect.Uint64, reflect.Float64:
+		e.fixed(8)
+	case reflect.String:
+		e.fixed(4)
+		e.n += v.Len() + 1
+	case reflect.Slice, reflect.Array:
+		e.fixed(4)
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.n += v.Len()
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			e.value(v.Index(i))
+		}
+	case reflect.Map:
+		e.fixed(4)
+		e.align(8)
+		// The order of the entries only changes the padding a little.
+		iter := v.MapRange()
+		for iter.Next() {
+			e.align(8)
+			e.value(iter.Key())
+			e.value(iter.Value())
+		}
+	case reflect.Struct:
+		e.align(8)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				e.value(v.Field(i))
+			}
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			e.value(v.Elem())
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestSizeLimits(t *testing.T) {
+	s := newFakeServer(t)
+	limits := notify.SizeLimits{MaxActions: 3, MaxLabelRunes: 5, MaxHintsBytes: 1000}
+	nf := newTestNotifier(t, notify.WithSizeLimits(limits))
+
+	withActions := func(count int) func(*notify.Notification) {
+		return func(n *notify.Notification) {
+			for i := 0; i < count; i++ {
+				n.AddAction(fmt.Sprint("key", i), "Label")
+			}
+		}
+	}
+	withLabel := func(label string) func(*notify.Notification) {
+		return func(n *notify.Notification) { n.AddAction("key", label) }
+	}
+	// The blob hint takes 28 bytes besides its bytes: the length of the
+	// map and its padding, the key, the signature of the variant and the
+	// length of the array.
+	withBlob := func(size int) func(*notify.Notification) {
+		return func(n *notify.Notification) { n.SetHint("x-blob", make([]byte, size)) }
+	}
+	tests := []struct {
+		name  string
+		set   func(*notify.Notification)
+		field string
+		err   error
+	}{
+		{"actions at the limit", withActions(3), "", nil},
+		{"actions over the limit", withActions(4), "actions", notify.ErrActionLimit},
+		{"label at the limit", withLabel("ééééé"), "", nil},
+		{"label over the limit", withLabel("éééééé"), "actions[0]", notify.ErrTooLong},
+		{"hints at the limit", withBlob(1000 - 28), "", nil},
+		{"hints over the limit", withBlob(1000 - 27), "hints", notify.ErrHintsTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := notify.New("app", "summary", "", "", 0, notify.NormalUrgency)
+			tt.set(n)
+			verr := nf.Validate(n)
+			_, err := nf.Notify(n)
+			if tt.err == nil {
+				if verr != nil || err != nil {
+					t.Fatalf("Validate() = %v, Notify() = %v, want no errors", verr, err)
+				}
+				return
+			}
+			for _, err := range []error{verr, err} {
+				var fe *notify.FieldError
+				if !errors.Is(err, tt.err) || !errors.As(err, &fe) || fe.Field != tt.field {
+					t.Errorf("error = %v, want a %s error for %s", err, tt.err, tt.field)
+				}
+			}
+		})
+	}
+	if got := s.received(); got != 3 {
+		t.Errorf("server received %d notifications, want only the 3 within the limits", got)
+	}
+}
+
+func TestSizeLimitsDefaults(t *testing.T) {
+	n := notify.New("app", "summary", "", "", 0, notify.NormalUrgency)
+	for i := 0; i < notify.DefaultSizeLimits.MaxActions; i++ {
+		n.AddAction(fmt.Sprint("key", i), strings.Repeat("a", notify.DefaultSizeLimits.MaxLabelRunes))
+	}
+	if err := n.Validate(); err != nil {
+		t.Fatalf("Validate() = %v at the default limits", err)
+	}
+	n.AddAction("one-more", "Label")
+	if err := n.Validate(); !errors.Is(err, notify.ErrActionLimit) {
+		t.Errorf("Validate() = %v, want %v", err, notify.ErrActionLimit)
+	}
+
+	// The fields left at 0 keep their default.
+	nf := newTestNotifier(t, notify.WithSizeLimits(notify.SizeLimits{MaxLabelRunes: 1000}))
+	n = notify.New("app", "summary", "", "", 0, notify.NormalUrgency)
+	n.AddAction("key", strings.Repeat("a", 1000))
+	if err := nf.Validate(n); err != nil {
+		t.Errorf("Validate() = %v, want the label within the raised limit", err)
+	}
+	if err := n.Validate(); !errors.Is(err, notify.ErrTooLong) {
+		t.Errorf("Notification.Validate() = %v, want the default limit", err)
+	}
+
+	if _, err := notify.NewNotifier(notify.WithSizeLimits(notify.SizeLimits{MaxActions: -1})); err == nil {
+		t.Error("NewNotifier accepted a negative limit")
+	}
+}
+
+func TestPreviewWireSize(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	n := notify.New("app", "Build finished", strings.Repeat("All the tests passed. ", 20), "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	n.AddAction("rerun", "Run again")
+	n.AddHints(notify.CategoryHint("transfer.complete"), notify.ValueHint(42))
+	n.SetHint("x-vendor-color", "red")
+	n.SetImage(image.NewRGBA(image.Rect(0, 0, 32, 32)))
+
+	p, err := nf.Preview(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	got := s.lastSize(t)
+	if diff := p.WireSize - got; diff < -got/10 || diff > got/10 {
+		t.Errorf("Preview().WireSize = %d, want about the %d bytes of the message", p.WireSize, got)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// WithSkipUnchanged makes the Notifier skip replacing a notification with
+// exactly the same call it was last sent with, like a progress notification
+// updated faster than its text changes. The skipped send succeeds, with
+// Skipped set in its SendResult; use NotifyForce to send anyway.
+//
+// The call is compared after everything the Notifier adds or adapts, so a
+// notification is sent again if the daemon changed. Notifications closed by
+// the daemon are always sent again.
+func WithSkipUnchanged(skip bool) Option {
+	return func(nf *Notifier) error {
+		nf.skipUnchanged = skip
+		return nil
+	}
+}
+
+// unchanged returns the hash of c, and true if c replaces a notification
+// last sent with the same call.
+func (nf *Notifier) unchanged(c Call) (hash uint64, ok bool) {
+	if !nf.skipUnchanged {
+		return 0, false
+	}
+	hash = callHash(c)
+	if c.ReplacesID == 0 {
+		return hash, false
+	}
+
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	last, sent := nf.lastSent[c.ReplacesID]
+	return hash, sent && last == hash
+}
+
+// recordHash records that id was last sent with the call of the given hash.
+func (nf *Notifier) recordHash(id uint32, hash uint64) {
+	if !nf.skipUnchanged {
+		return
+	}
+	// Notifications closed by the daemon must be sent again, so listen to
+	// the signals to find out.
+	nf.listenAlways()
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	if nf.lastSent == nil {
+		nf.lastSent = make(map[uint32]uint64)
+	}
+	nf.lastSent[id] = hash
+}
+
+// forgetSent forgets the last call sent for id, so that it is sent again.
+// It must be called with nf.mu held.
+func (nf *Notifier) forgetSent(id uint32) {
+	delete(nf.lastSent, id)
+	delete(nf.sentUrgency, id)
+}
+
+// callHash returns a hash of everything c shows, except the ID it replaces.
+func callHash(c Call) uint64 {
+	h := fnv.New64a()
+	for _, s := range []string{c.AppName, c.AppIcon, c.Summary, c.Body} {
+		fmt.Fprintf(h, "%d:%s", len(s), s)
+	}
+	fmt.Fprintf(h, "%d:%q", len(c.Actions), c.Actions)
+	keys := make([]string, 0, len(c.Hints))
+	for k := range c.Hints {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := c.Hints[k]
+		fmt.Fprintf(h, "%q=%s:%v;", k, v.Signature(), v.Value())
+	}
+	fmt.Fprintf(h, "%d", c.ExpireTimeout)
+	return h.Sum64()
+}
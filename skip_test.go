@@ -0,0 +1,60 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestSkipUnchanged(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithSkipUnchanged(true))
+
+	n := notify.New("test", "copying", "50%", "", 0, notify.NormalUrgency)
+	n.Tag = "progress"
+	send := func(body string, force bool) notify.SendResult {
+		t.Helper()
+		n.Body = body
+		send := nf.Notify
+		if force {
+			send = nf.NotifyForce
+		}
+		res, err := send(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	send("50%", false)
+	id := n.Id
+	if res := send("50%", false); !res.Skipped || res.Id != id {
+		t.Errorf("unchanged send returned %+v", res)
+	}
+	if got := s.received(); got != 1 {
+		t.Errorf("server received %d notifications, want the unchanged one skipped", got)
+	}
+	if res := send("51%", false); res.Skipped {
+		t.Errorf("changed send returned %+v", res)
+	}
+	if res := send("51%", true); res.Skipped {
+		t.Errorf("forced send returned %+v", res)
+	}
+	if got := s.received(); got != 3 {
+		t.Errorf("server received %d notifications, want 3", got)
+	}
+	if st := nf.Stats(); st.Skipped != 1 {
+		t.Errorf("Stats().Skipped = %d, want 1", st.Skipped)
+	}
+
+	events := nf.Events()
+	s.emitClosed(id, uint32(notify.ReasonDismissed))
+	waitEvent(t, events, notify.EventClosed)
+	if res := send("51%", false); res.Skipped {
+		t.Errorf("send after the notification was closed returned %+v", res)
+	}
+}
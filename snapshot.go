@@ -0,0 +1,226 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EnvSnapshot is what a Notifier knows about the notification daemon, see
+// Snapshot.
+type EnvSnapshot struct {
+	// Info is the server information of the daemon, and SpecMajor and
+	// SpecMinor the version of the specification it follows, see
+	// SpecVersion.
+	Info                 ServerInfo
+	SpecMajor, SpecMinor int
+	// Capabilities are the capabilities of the daemon, and Features the
+	// same parsed.
+	Capabilities []string
+	Features     Features
+	// Quirks are the quirks worked around, nil if none.
+	Quirks *Quirks
+	// Owner is the unique bus name of the daemon, empty if it is unknown.
+	Owner string
+	// Time is when the daemon was asked.
+	Time time.Time
+}
+
+// WithSnapshotTTL makes the Notifier ask the daemon again for its server
+// information and capabilities once they are older than ttl. By default they
+// are kept until another daemon takes over, see InvalidateCaches.
+func WithSnapshotTTL(ttl time.Duration) Option {
+	return func(nf *Notifier) error {
+		if ttl < 0 {
+			return fmt.Errorf("notify: negative snapshot TTL %v", ttl)
+		}
+		nf.snapshotTTL = ttl
+		return nil
+	}
+}
+
+// Snapshot returns what nf knows about the notification daemon, asking it
+// what is not cached yet: the server information, the capabilities and the
+// owner of the bus name are asked at the same time, and concurrent callers
+// wait for the same answers. ServerInfo, Capabilities and the adaptations to
+// the daemon use the same cache, so it is a cheap way to log the whole
+// environment once.
+//
+// If some of the questions fail, the snapshot holds the answers to the
+// others, and the error joins the failures.
+func (nf *Notifier) Snapshot(ctx context.Context) (EnvSnapshot, error) {
+	if nf.core != nil {
+		return nf.core.root.Snapshot(ctx)
+	}
+	p := nf.probeDaemon(ctx, true, true)
+
+	var s EnvSnapshot
+	if p.info != nil {
+		s.Info = *p.info
+		s.SpecMajor, s.SpecMinor = parseSpecVersion(p.info.SpecVersion)
+	}
+	if p.caps != nil {
+		s.Capabilities = append([]string(nil), p.caps...)
+		s.Features = parseFeatures(p.caps)
+	}
+	nf.mu.Lock()
+	if nf.quirks != nil {
+		q := *nf.quirks
+		s.Quirks = &q
+	}
+	s.Owner = nf.probe.owner
+	s.Time = nf.probe.taken
+	nf.mu.Unlock()
+	return s, errors.Join(p.infoErr, p.capsErr)
+}
+
+// probeState holds the probes of the daemon, see probeDaemon.
+type probeState struct {
+	// running is closed once the running probes are done, nil if none is
+	// running.
+	running chan struct{}
+	// taken is when the cached answers were asked, and owner is the owner
+	// of the bus name then.
+	taken time.Time
+	owner string
+	// gen counts the invalidations of the caches, so that the answers of
+	// the probes running during one are dropped.
+	gen uint64
+}
+
+// probeResult holds the answers of the daemon, cached or not.
+type probeResult struct {
+	info    *ServerInfo
+	caps    []string
+	infoErr error
+	capsErr error
+}
+
+// probeDaemon returns the server information and the capabilities of the
+// daemon, asking it for everything that is not cached if the server
+// information is wanted and not cached, or the capabilities are.
+func (nf *Notifier) probeDaemon(ctx context.Context, wantInfo, wantCaps bool) probeResult {
+	for {
+		nf.mu.Lock()
+		nf.expireSnapshot()
+		p := probeResult{info: nf.info, caps: nf.caps}
+		if (!wantInfo || p.info != nil) && (!wantCaps || p.caps != nil) {
+			nf.mu.Unlock()
+			return p
+		}
+		if running := nf.probe.running; running != nil {
+			nf.mu.Unlock()
+			select {
+			case <-running:
+				continue
+			case <-ctx.Done():
+				return probeResult{infoErr: ctx.Err(), capsErr: ctx.Err()}
+			}
+		}
+		done := make(chan struct{})
+		nf.probe.running = done
+		gen := nf.probe.gen
+		nf.mu.Unlock()
+
+		p, owner := nf.runProbes(ctx, p)
+
+		nf.mu.Lock()
+		nf.probe.running = nil
+		if gen == nf.probe.gen && (p.infoErr == nil || p.capsErr == nil) {
+			nf.info, nf.caps = p.info, p.caps
+			if p.capsErr == nil {
+				nf.feats = nil
+			}
+			if nf.probe.taken.IsZero() {
+				nf.probe.taken = nf.clock.Now()
+			}
+			nf.probe.owner = owner
+		}
+		nf.mu.Unlock()
+		close(done)
+		return p
+	}
+}
+
+// runProbes asks the daemon at the same time for what p misses, and for the
+// owner of its bus name.
+func (nf *Notifier) runProbes(ctx context.Context, p probeResult) (probeResult, string) {
+	needInfo, needCaps := p.info == nil, p.caps == nil
+	conn, err := nf.connection()
+	if err != nil {
+		if needInfo {
+			p.infoErr = err
+		}
+		if needCaps {
+			p.capsErr = err
+		}
+		return p, ""
+	}
+
+	var wg sync.WaitGroup
+	if needInfo {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var info ServerInfo
+			call := nf.object(conn).CallWithContext(ctx, dbusInterface+".GetServerInformation", nf.callFlags())
+			if call.Err != nil {
+				p.infoErr = call.Err
+			} else if call.Store(&info.Name, &info.Vendor, &info.Version, &info.SpecVersion) != nil {
+				p.infoErr = errUnrecognizedResponse
+			} else {
+				p.info = &info
+			}
+		}()
+	}
+	if needCaps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var caps []string
+			call := nf.object(conn).CallWithContext(ctx, dbusInterface+".GetCapabilities", nf.callFlags())
+			if call.Err != nil {
+				p.capsErr = call.Err
+			} else if call.Store(&caps) != nil {
+				p.capsErr = errUnrecognizedResponse
+			} else if caps == nil {
+				p.caps = []string{}
+			} else {
+				p.caps = caps
+			}
+		}()
+	}
+	var owner string
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.GetNameOwner", 0, nf.destination).Store(&owner) != nil {
+			owner = ""
+		}
+	}()
+	wg.Wait()
+
+	if p.infoErr == nil || p.capsErr == nil {
+		nf.watchOwner(conn)
+	}
+	return p, owner
+}
+
+// expireSnapshot drops the cached server information and capabilities if
+// they are older than the snapshot TTL. nf.mu must be held.
+func (nf *Notifier) expireSnapshot() {
+	if nf.snapshotTTL == 0 || nf.probe.taken.IsZero() || nf.clock.Now().Sub(nf.probe.taken) < nf.snapshotTTL {
+		return
+	}
+	nf.info = nil
+	nf.caps = nil
+	nf.feats = nil
+	nf.ifaces = nil
+	nf.probe.taken = time.Time{}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestSnapshot(t *testing.T) {
+	s := newFakeServer(t)
+	s.setServerInfo("fake", "tests", "1.0")
+	s.setSpecVersion("1.1")
+	s.setCapabilities("body", "actions")
+	nf := newTestNotifier(t)
+
+	snap, err := nf.Snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Info.Name != "fake" || snap.Info.Vendor != "tests" || snap.SpecMajor != 1 || snap.SpecMinor != 1 {
+		t.Errorf("Snapshot info = %+v, spec %d.%d", snap.Info, snap.SpecMajor, snap.SpecMinor)
+	}
+	if !reflect.DeepEqual(snap.Capabilities, []string{"body", "actions"}) || !snap.Features.Actions || snap.Features.BodyMarkup {
+		t.Errorf("Snapshot capabilities = %q, features %+v", snap.Capabilities, snap.Features)
+	}
+	if snap.Owner == "" || snap.Time.IsZero() {
+		t.Errorf("Snapshot owner %q, time %v", snap.Owner, snap.Time)
+	}
+	if got := s.probeCount(); got != 2 {
+		t.Errorf("the daemon was asked %d times, want 2", got)
+	}
+}
+
+// TestSnapshotOneBurst checks that many sends at once, which all need the
+// capabilities, ask the daemon only once.
+func TestSnapshotOneBurst(t *testing.T) {
+	s := newFakeServer(t)
+	s.setCapabilities("body", "body-markup")
+	nf := newTestNotifier(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := notify.New("test", "burst", "<b>body</b>", "", 0, notify.NormalUrgency)
+			if _, err := nf.Notify(n); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := nf.ServerInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.probeCount(); got != 2 {
+		t.Errorf("the daemon was asked %d times for 50 sends, want 2", got)
+	}
+}
+
+func TestSnapshotTTL(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithSnapshotTTL(time.Minute))
+
+	if _, err := nf.Snapshot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(30 * time.Second)
+	if _, err := nf.Capabilities(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.probeCount(); got != 2 {
+		t.Fatalf("the daemon was asked %d times before the TTL, want 2", got)
+	}
+
+	s.setCapabilities("persistence")
+	clock.Advance(time.Minute)
+	caps, err := nf.Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(caps, []string{"persistence"}) {
+		t.Errorf("Capabilities after the TTL = %q", caps)
+	}
+	if got := s.probeCount(); got != 4 {
+		t.Errorf("the daemon was asked %d times after the TTL, want 4", got)
+	}
+
+	nf.InvalidateCaches()
+	if _, err := nf.Snapshot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.probeCount(); got != 6 {
+		t.Errorf("the daemon was asked %d times after InvalidateCaches, want 6", got)
+	}
+}
+
+func TestSnapshotTTLInvalid(t *testing.T) {
+	if _, err := notify.NewNotifier(notify.WithSnapshotTTL(-time.Second)); err == nil {
+		t.Error("a negative snapshot TTL was accepted")
+	}
+}
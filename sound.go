@@ -0,0 +1,130 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNoSoundPlayer is returned by ExecSoundPlayer when none of the players
+// it tries is installed.
+var ErrNoSoundPlayer = errors.New("notify: no sound player found")
+
+// soundTimeout is how long a sound played by the Notifier may take.
+const soundTimeout = 30 * time.Second
+
+// SoundPlayer plays sounds for the daemons that do not, see
+// WithSoundFallback. nameOrPath is the value of the "sound-file" hint, a
+// path, or of the "sound-name" hint, a themed sound name like
+// "message-new-instant".
+type SoundPlayer interface {
+	Play(ctx context.Context, nameOrPath string) error
+}
+
+// ExecSoundPlayer plays sounds by running canberra-gtk-play, or paplay or
+// aplay for the paths if it is not installed. For a Notifier, they are run
+// by its ExecRunner with its environment, see WithExecRunner and
+// WithExecEnv.
+type ExecSoundPlayer struct{}
+
+// Play plays the sound nameOrPath with the first player installed.
+func (ExecSoundPlayer) Play(ctx context.Context, nameOrPath string) error {
+	isPath := strings.ContainsRune(nameOrPath, '/')
+	players := [][]string{{"canberra-gtk-play", "-i", nameOrPath}}
+	if isPath {
+		players = [][]string{
+			{"canberra-gtk-play", "-f", nameOrPath},
+			{"paplay", nameOrPath},
+			{"aplay", "-q", nameOrPath},
+		}
+	}
+	e := execFrom(ctx)
+	for _, p := range players {
+		if _, err := e.lookPath(p[0]); err != nil {
+			continue
+		}
+		var out bytes.Buffer
+		cmd := e.command(ctx, p[0], p[1:]...)
+		cmd.Stdout, cmd.Stderr = &out, &out
+		if err := e.run(cmd); err != nil {
+			if msg := strings.TrimSpace(out.String()); msg != "" {
+				return fmt.Errorf("notify: %s: %w: %s", p[0], err, msg)
+			}
+			return fmt.Errorf("notify: %s: %w", p[0], err)
+		}
+		return nil
+	}
+	return ErrNoSoundPlayer
+}
+
+// WithSoundFallback makes the Notifier play the sound of the notifications
+// with p when the daemon does not play sounds, as it does not advertise the
+// "sound" capability. Pass ExecSoundPlayer{} to use the players of the
+// system.
+//
+// The sound is the one of the "sound-file" hint, or else of the
+// "sound-name" hint. It is played in the background once the notification
+// is sent, and failures are logged. Nothing is played for notifications
+// with the "suppress-sound" hint, nor while nf is paused or in quiet hours.
+func WithSoundFallback(p SoundPlayer) Option {
+	return func(nf *Notifier) error {
+		if p == nil {
+			return errors.New("notify: nil sound player")
+		}
+		nf.soundPlayer = p
+		return nil
+	}
+}
+
+// callSound returns the sound of c, or "" if it has none or suppresses
+// sounds.
+func callSound(c Call) string {
+	var file SoundFileHint
+	var name SoundNameHint
+	var suppress SuppressSoundHint
+	if suppress.DecodeHint(c.Hints) && bool(suppress) {
+		return ""
+	}
+	if file.DecodeHint(c.Hints) && file != "" {
+		return string(file)
+	} else if name.DecodeHint(c.Hints) && name != "" {
+		return string(name)
+	}
+	return ""
+}
+
+// playSound plays the sound of c in the background if the daemon does not.
+func (nf *Notifier) playSound(c Call) {
+	if nf.soundPlayer == nil {
+		return
+	}
+	sound := callSound(c)
+	if sound == "" {
+		return
+	}
+	if f, err := nf.features(); err == nil && f.Sound {
+		return
+	}
+	nf.mu.Lock()
+	paused := nf.stats.Paused
+	nf.mu.Unlock()
+	if _, quiet := nf.InQuietHours(); paused || quiet {
+		nf.log(LevelDebug, fmt.Sprintf("not playing the sound %q while notifications are held back", sound), nil)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), soundTimeout)
+		defer cancel()
+		if err := nf.soundPlayer.Play(nf.execContext(ctx), sound); err != nil {
+			nf.log(LevelWarn, fmt.Sprintf("playing the sound %q failed", sound), err)
+		}
+	}()
+}
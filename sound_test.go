@@ -0,0 +1,130 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// stubPlayer records the sounds it is asked to play, and plays them until
+// release is closed, if it is not nil, returning err.
+type stubPlayer struct {
+	played  chan string
+	release chan struct{}
+	err     error
+}
+
+func newStubPlayer() *stubPlayer {
+	return &stubPlayer{played: make(chan string, 16)}
+}
+
+func (p *stubPlayer) Play(ctx context.Context, nameOrPath string) error {
+	p.played <- nameOrPath
+	if p.release != nil {
+		<-p.release
+	}
+	return p.err
+}
+
+// next returns the next sound played.
+func (p *stubPlayer) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case sound := <-p.played:
+		return sound
+	case <-waitTimeout():
+		t.Fatal("no sound played")
+		return ""
+	}
+}
+
+func sendSound(t *testing.T, nf *notify.Notifier, urgency notify.NotificationUrgency, hints ...notify.Hint) {
+	t.Helper()
+	n := notify.New("test", "sound", "", "", 0, urgency)
+	n.AddHints(hints...)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSoundFallback(t *testing.T) {
+	newFakeServer(t)
+	p := newStubPlayer()
+	nf := newTestNotifier(t, notify.WithSoundFallback(p))
+
+	// The sounds that must not be played are followed by one that must, so
+	// that the next one played tells whether they were.
+	sendSound(t, nf, notify.NormalUrgency)
+	sendSound(t, nf, notify.NormalUrgency, notify.SoundNameHint("bell"), notify.SuppressSoundHint(true))
+	sendSound(t, nf, notify.NormalUrgency, notify.SoundNameHint("message-new-instant"))
+	if got := p.next(t); got != "message-new-instant" {
+		t.Errorf("played %q, want the sound name", got)
+	}
+	sendSound(t, nf, notify.NormalUrgency, notify.SoundNameHint("bell"), notify.SoundFileHint("/usr/share/sounds/alert.oga"))
+	if got := p.next(t); got != "/usr/share/sounds/alert.oga" {
+		t.Errorf("played %q, want the sound file", got)
+	}
+
+	// Critical notifications are still sent while paused, but silently.
+	nf.Pause()
+	sendSound(t, nf, notify.CriticalUrgency, notify.SoundNameHint("paused"))
+	nf.Resume(false)
+	sendSound(t, nf, notify.CriticalUrgency, notify.SoundNameHint("resumed"))
+	if got := p.next(t); got != "resumed" {
+		t.Errorf("played %q, want nothing while paused", got)
+	}
+}
+
+func TestSoundFallbackDaemonPlays(t *testing.T) {
+	s := newFakeServer(t)
+	s.setCapabilities("body", "sound")
+	p := newStubPlayer()
+	nf := newTestNotifier(t, notify.WithSoundFallback(p))
+
+	sendSound(t, nf, notify.NormalUrgency, notify.SoundNameHint("bell"))
+	select {
+	case sound := <-p.played:
+		t.Errorf("played %q although the daemon plays sounds", sound)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSoundFallbackInBackground(t *testing.T) {
+	newFakeServer(t)
+	logs := &logRecorder{}
+	p := newStubPlayer()
+	p.release = make(chan struct{})
+	p.err = errors.New("no audio device")
+	nf := newTestNotifier(t, notify.WithSoundFallback(p), notify.WithLogger(logs.log))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n := notify.New("test", "sound", "", "", 0, notify.NormalUrgency)
+		n.AddHints(notify.SoundNameHint("slow"))
+		if _, err := nf.Notify(n); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-waitTimeout():
+		t.Fatal("Notify waited for the sound")
+	}
+	p.next(t)
+	close(p.release)
+	waitFor(t, "the playback error", func() bool { return logs.logged(`playing the sound "slow" failed`) })
+}
+
+func TestWithSoundFallbackNil(t *testing.T) {
+	if _, err := notify.NewNotifier(notify.WithSoundFallback(nil)); err == nil {
+		t.Error("a nil sound player was accepted")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// SpecLevel is what a revision of the specification changed, as
+// implemented by the notification daemon, see Notifier.SpecLevel.
+type SpecLevel struct {
+	// Major and Minor are the revision, such as 1.2.
+	Major, Minor int
+	// ImageDataHint and ImagePathHint are the hints holding an embedded
+	// image and the path of an image, which were renamed.
+	ImageDataHint string
+	ImagePathHint string
+	// HasActivationToken is true if the daemon signals an activation token
+	// before the actions, see OnClick. In a Wayland session, window IDs are
+	// then not sent.
+	HasActivationToken bool
+	// WantsInt64SenderPid is true if the daemon reads the "sender-pid"
+	// hint as an int64 rather than a uint32, see WithSenderPID.
+	WantsInt64SenderPid bool
+	// StrictSoundHints is true if the daemon only plays "sound-file" hints
+	// holding an absolute path or a file:// URI, so relative paths are made
+	// absolute.
+	StrictSoundHints bool
+}
+
+// specLevels are the revisions of the specification, the oldest first. 1.3
+// is still in progress.
+var specLevels = []SpecLevel{
+	{Major: 1, Minor: 0, ImageDataHint: "icon_data", ImagePathHint: "image_path"},
+	{Major: 1, Minor: 1, ImageDataHint: "image_data", ImagePathHint: "image_path"},
+	{Major: 1, Minor: 2, ImageDataHint: "image-data", ImagePathHint: "image-path", HasActivationToken: true},
+	{Major: 1, Minor: 3, ImageDataHint: "image-data", ImagePathHint: "image-path", HasActivationToken: true,
+		WantsInt64SenderPid: true, StrictSoundHints: true},
+}
+
+// specDaemons holds the daemons implementing a revision newer than the one
+// they report, by the name they report, from their major version since on.
+var specDaemons = map[string]struct {
+	since        int
+	major, minor int
+}{
+	"gnome-shell": {since: 46, major: 1, minor: 3},
+	"Plasma":      {since: 6, major: 1, minor: 3},
+}
+
+// specLevel returns the latest revision at most major.minor, or the oldest
+// one.
+func specLevel(major, minor int) SpecLevel {
+	level := specLevels[0]
+	for _, l := range specLevels[1:] {
+		if specAtLeast(major, minor, l.Major, l.Minor) {
+			level = l
+		}
+	}
+	return level
+}
+
+// serverSpecLevel returns the revision implemented by the daemon info.
+func serverSpecLevel(info ServerInfo) SpecLevel {
+	major, minor := parseSpecVersion(info.SpecVersion)
+	if d, ok := specDaemons[info.Name]; ok && specAtLeast(d.major, d.minor, major, minor) {
+		v, _, _ := strings.Cut(info.Version, ".")
+		if n, err := strconv.Atoi(v); err == nil && n >= d.since {
+			major, minor = d.major, d.minor
+		}
+	}
+	return specLevel(major, minor)
+}
+
+// SpecLevel returns the revision of the specification implemented by the
+// notification daemon: the one of SpecVersion, or a newer one for the
+// daemons known to implement it already. If the daemon cannot be asked, the
+// latest released revision is returned with the error.
+func (nf *Notifier) SpecLevel() (SpecLevel, error) {
+	info, err := nf.ServerInfo()
+	if err != nil {
+		return specLevel(latestSpecMajor, latestSpecMinor), err
+	}
+	return serverSpecLevel(info), nil
+}
+
+// WithSenderPID makes the Notifier send the process ID in the "sender-pid"
+// hint, which some daemons use to find the application, as the type the
+// daemon expects, see SpecLevel.WantsInt64SenderPid.
+func WithSenderPID() Option {
+	return func(nf *Notifier) error {
+		nf.senderPID = true
+		return nil
+	}
+}
+
+// senderPIDHint returns the "sender-pid" hint for a daemon implementing
+// level.
+func senderPIDHint(level SpecLevel) dbus.Variant {
+	if level.WantsInt64SenderPid {
+		return dbus.MakeVariant(int64(os.Getpid()))
+	}
+	return dbus.MakeVariant(uint32(os.Getpid()))
+}
+
+// absSoundFile makes the "sound-file" hint of hints an absolute path, if it
+// is a relative one, for the daemons with StrictSoundHints.
+func (nf *Notifier) absSoundFile(hints map[string]dbus.Variant) {
+	v, ok := hints["sound-file"]
+	if !ok {
+		return
+	}
+	path, ok := v.Value().(string)
+	if !ok || path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "file://") {
+		return
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		nf.log(LevelWarn, "the daemon only plays absolute sound files, dropping the sound file "+strconv.Quote(path), err)
+		delete(hints, "sound-file")
+		return
+	}
+	hints["sound-file"] = dbus.MakeVariant(abs)
+}
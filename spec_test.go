@@ -0,0 +1,122 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestSpecLevel(t *testing.T) {
+	s := newFakeServer(t)
+	tests := []struct {
+		fixture      string
+		major, minor int
+		tokens       bool
+		int64PID     bool
+		strictSound  bool
+	}{
+		{"spec-1.1", 1, 1, false, false, false},
+		{"spec-1.2", 1, 2, true, false, false},
+		{"spec-1.3", 1, 3, true, true, true},
+		{"gnome-45", 1, 2, true, false, false},
+		{"gnome-46", 1, 3, true, true, true},
+		{"plasma-5", 1, 2, true, false, false},
+		{"plasma-6", 1, 3, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			s.impersonate(t, tt.fixture)
+			l, err := newTestNotifier(t).SpecLevel()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Major != tt.major || l.Minor != tt.minor || l.HasActivationToken != tt.tokens ||
+				l.WantsInt64SenderPid != tt.int64PID || l.StrictSoundHints != tt.strictSound {
+				t.Errorf("SpecLevel() = %+v", l)
+			}
+		})
+	}
+}
+
+func TestSenderPID(t *testing.T) {
+	s := newFakeServer(t)
+	pid := os.Getpid()
+	tests := []struct {
+		fixture string
+		want    interface{}
+	}{
+		{"spec-1.2", uint32(pid)},
+		{"gnome-46", int64(pid)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			s.impersonate(t, tt.fixture)
+			n := notify.New("test", "hello", "", "", 0, notify.NormalUrgency)
+			c, err := n.DryRun(newTestNotifier(t, notify.WithSenderPID()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := c.Hints["sender-pid"].Value(); v != tt.want {
+				t.Errorf("sender-pid = %T %v, want %T %v", v, v, tt.want, tt.want)
+			}
+
+			c, err = n.DryRun(newTestNotifier(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v, ok := c.Hints["sender-pid"]; ok {
+				t.Errorf("sender-pid sent without WithSenderPID: %v", v)
+			}
+		})
+	}
+}
+
+func TestStrictSoundHints(t *testing.T) {
+	s := newFakeServer(t)
+	abs, err := filepath.Abs("sounds/done.oga")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		fixture, file, want string
+	}{
+		{"spec-1.2", "sounds/done.oga", "sounds/done.oga"},
+		{"plasma-6", "sounds/done.oga", abs},
+		{"plasma-6", "file:///usr/share/sounds/done.oga", "file:///usr/share/sounds/done.oga"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture+" "+tt.file, func(t *testing.T) {
+			s.impersonate(t, tt.fixture)
+			n := notify.New("test", "done", "", "", 0, notify.NormalUrgency)
+			n.AddHints(notify.SoundFileHint(tt.file))
+			c, err := n.DryRun(newTestNotifier(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.Hints["sound-file"].Value(); got != tt.want {
+				t.Errorf("sound-file = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaylandWindowWithoutTokens(t *testing.T) {
+	s := newFakeServer(t)
+	s.impersonate(t, "spec-1.1")
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	n := notify.New("test", "build done", "", "", 0, notify.NormalUrgency)
+	n.SetWindowID(7)
+	c, err := n.DryRun(newTestNotifier(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Hints["window-id"].Value(); v != uint32(7) {
+		t.Errorf("window-id = %v, want it sent to a daemon without activation tokens", v)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import "time"
+
+// Stats are counters of what a Notifier did, see Notifier.Stats.
+type Stats struct {
+	// Queued is the number of asynchronous operations queued or being
+	// executed.
+	Queued int
+	// Dropped is the number of asynchronous operations dropped because the
+	// queue was full.
+	Dropped uint64
+
+	// Paused is true if notifications are held back, see Pause.
+	Paused bool
+	// Buffered is the number of notifications held back until Resume.
+	Buffered int
+	// Deferred is the number of notifications that were held back.
+	Deferred uint64
+	// Quiet is true if nf is in quiet hours, see WithQuietHours, and
+	// Quieted is the number of notifications they held back.
+	Quiet   bool
+	Quieted uint64
+	// Skipped is the number of sends skipped because the notification did
+	// not change, see WithSkipUnchanged.
+	Skipped uint64
+	// Paced is the number of sends that waited to keep the pace of the
+	// daemon, see WithAdaptivePacing.
+	Paced uint64
+	// Tracked is the number of notifications getting the signals of the
+	// daemon, and Evicted the number of notifications forgotten before the
+	// daemon closed them, see WithTrackingLimits.
+	Tracked int
+	Evicted uint64
+	// InFlight is the number of D-Bus calls waiting for a reply, and
+	// PeakInFlight the highest it was, see WithMaxInFlight. The Notifiers
+	// of a Core share them.
+	InFlight     int
+	PeakInFlight int
+	// Latency counts the latencies of the asynchronous operations, and
+	// QueueDepth the depths of the queue they were queued at, see
+	// WithQueueMetrics. QueueDepth.Max is the deepest the queue was. Both
+	// are empty without WithQueueMetrics.
+	Latency    Histogram[time.Duration]
+	QueueDepth Histogram[int]
+	// ImageCache counts what the image cache saved, see WithImageCache. It
+	// is empty without it.
+	ImageCache ImageCacheStats
+}
+
+// Stats returns the counters of nf.
+func (nf *Notifier) Stats() Stats {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	s := nf.stats
+	s.Queued = nf.queued
+	s.Tracked = len(nf.tracked)
+	s.InFlight, s.PeakInFlight = nf.inFlight().counts()
+	if nf.metrics != nil {
+		s.Latency = nf.metrics.latency.clone()
+		s.QueueDepth = nf.metrics.depth.clone()
+	}
+	if nf.quiet != nil {
+		_, s.Quiet = nf.quiet.until(nf.clock.Now())
+	}
+	if nf.images != nil {
+		s.ImageCache = nf.images.counters()
+	}
+	return s
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// StringIDTransport is implemented by the transports whose notifications
+// have string IDs chosen by the caller instead of IDs assigned by the
+// daemon, like PortalTransport. A Notifier with such a transport, given to
+// WithTransport, uses the Tag of a notification as its ID, or a random UUID
+// for a notification without a Tag, kept as long as it is replaced. The Id
+// of the notification is then the 32-bit FNV-1a hash of its string ID, or 1
+// if the hash is 0, so that a Tag gives the same Id in every process.
+//
+// Sending a notification again calls NotifyID with the same ID, and closing
+// it calls CloseID. The Notify and CloseNotification methods of the
+// Transport are only used for the calls without a notification, like those
+// of RawNotify. FallbackTransport and MultiTransport have string IDs when
+// their members have.
+type StringIDTransport interface {
+	Transport
+	// NotifyID delivers c as the notification id, replacing the one with
+	// the same id if any.
+	NotifyID(ctx context.Context, id string, c Call) error
+	// CloseID closes the notification id.
+	CloseID(id string) error
+}
+
+// maxStringIDs bounds the string IDs a Notifier remembers to replace and
+// close the notifications by their Id.
+const maxStringIDs = 4096
+
+// stringIDs holds the string IDs sent by a Notifier, by the Id of their
+// notification.
+type stringIDs struct {
+	ids   map[uint32]string
+	order []uint32
+}
+
+// stringIDHash returns the Id of the notification with the string ID id.
+func stringIDHash(id string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	if sum := h.Sum32(); sum != 0 {
+		return sum
+	}
+	return 1
+}
+
+// stringIDMembers is implemented by the transports having string IDs only
+// when their members have, like FallbackTransport.
+type stringIDMembers interface {
+	hasStringIDs() bool
+}
+
+// stringIDOf returns t if it has string IDs.
+func stringIDOf(t Transport) (StringIDTransport, bool) {
+	st, ok := t.(StringIDTransport)
+	if m, isChain := t.(stringIDMembers); isChain && !m.hasStringIDs() {
+		return nil, false
+	}
+	return st, ok
+}
+
+// notifyID delivers c as the notification id to t: by its string ID if t
+// has string IDs, and with the Id of the notification otherwise.
+func notifyID(ctx context.Context, t Transport, id string, c Call) error {
+	if st, ok := stringIDOf(t); ok {
+		return st.NotifyID(ctx, id, c)
+	}
+	_, err := t.Notify(ctx, c)
+	return err
+}
+
+// closeID closes the notification id with t, by its Id if t has no string
+// IDs.
+func closeID(t Transport, id string) error {
+	if st, ok := stringIDOf(t); ok {
+		return st.CloseID(id)
+	}
+	return t.CloseNotification(stringIDHash(id))
+}
+
+// stringIDTransport returns the transport of nf if it has string IDs.
+func (nf *Notifier) stringIDTransport() (StringIDTransport, bool) {
+	return stringIDOf(nf.transport)
+}
+
+// stringID returns the string ID to send n with, replacing the notification
+// replaces, or "" if the transport of nf has none.
+func (nf *Notifier) stringID(n *Notification, replaces uint32) string {
+	if _, ok := nf.stringIDTransport(); !ok {
+		return ""
+	}
+	if n.Tag != "" {
+		return n.Tag
+	}
+	if id, ok := nf.knownStringID(replaces); ok {
+		return id
+	}
+	return newCorrelationID()
+}
+
+// knownStringID returns the string ID of the notification id.
+func (nf *Notifier) knownStringID(id uint32) (string, bool) {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	sid, ok := nf.stringIDs.ids[id]
+	return sid, ok
+}
+
+// sendStringID delivers c with its string ID, and returns the Id of the
+// notification.
+func (nf *Notifier) sendStringID(ctx context.Context, t StringIDTransport, c Call) (uint32, error) {
+	if err := t.NotifyID(ctx, c.stringID, c); err != nil {
+		return 0, err
+	}
+	id := stringIDHash(c.stringID)
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+	s := &nf.stringIDs
+	if _, ok := s.ids[id]; !ok {
+		if s.ids == nil {
+			s.ids = make(map[uint32]string)
+		}
+		s.order = append(s.order, id)
+		if len(s.order) > maxStringIDs {
+			delete(s.ids, s.order[0])
+			s.order = s.order[1:]
+		}
+	}
+	s.ids[id] = c.stringID
+	return id, nil
+}
+
+// closeStringID closes the notification id by its string ID, and returns
+// false if id has none known.
+func (nf *Notifier) closeStringID(t StringIDTransport, id uint32) (bool, error) {
+	sid, ok := nf.knownStringID(id)
+	if !ok {
+		return false, nil
+	}
+	return true, t.CloseID(sid)
+}
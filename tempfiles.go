@@ -0,0 +1,173 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tempDirPrefix prefixes the name of the directories of the temporary files,
+// followed by the ID of the process and a random suffix.
+const tempDirPrefix = "notify-"
+
+// staleTempAge is the age of the directories of the temporary files of other
+// processes after which they are removed, as those of crashed processes.
+const staleTempAge = 24 * time.Hour
+
+// SetIconFromFS makes the icon of the notification the file name of fsys,
+// such as an icon embedded in the program. As daemons need a path, the icon
+// is written to a temporary file when the notification is sent, in place of
+// IconPath. Notifications with the same icon share the file, which is
+// removed when they are all closed, or when the Notifier is closed.
+func (n *Notification) SetIconFromFS(fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	n.icon, n.iconExt = data, path.Ext(name)
+	return nil
+}
+
+// tempFiles holds the temporary files written for the notifications, by
+// content, in a directory of the process under os.TempDir.
+type tempFiles struct {
+	mu    sync.Mutex
+	dir   string
+	files map[string]*tempFile
+}
+
+// tempFile is a temporary file, and the IDs of the notifications showing it.
+type tempFile struct {
+	path string
+	ids  map[uint32]bool
+}
+
+// tempFiles returns the temporary files of the Notifiers sharing the
+// connection of nf.
+func (nf *Notifier) tempFiles() *tempFiles {
+	if nf.parent != nil {
+		return &nf.parent.temps
+	}
+	return &nf.temps
+}
+
+// write returns the path of a temporary file holding data, with the
+// extension ext, writing it unless a file with the same content exists.
+func (t *tempFiles) write(data []byte, ext string) (string, error) {
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:16]) + ext
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if f, ok := t.files[key]; ok {
+		if _, err := os.Stat(f.path); err == nil {
+			return f.path, nil
+		}
+	}
+	if t.dir == "" {
+		sweepStaleTemps(os.TempDir())
+		dir, err := os.MkdirTemp("", tempDirPrefix+strconv.Itoa(os.Getpid())+"-")
+		if err != nil {
+			return "", err
+		}
+		t.dir = dir
+	} else if err := os.MkdirAll(t.dir, 0o700); err != nil {
+		return "", err
+	}
+	p := filepath.Join(t.dir, key)
+	if err := os.WriteFile(p, data, 0o600); err != nil {
+		return "", err
+	}
+	if t.files == nil {
+		t.files = make(map[string]*tempFile)
+	}
+	t.files[key] = &tempFile{path: p, ids: make(map[uint32]bool)}
+	return p, nil
+}
+
+// use records that the notification id shows the file at p, and no longer
+// the one it showed before.
+func (t *tempFiles) use(p string, id uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, f := range t.files {
+		if f.path == p {
+			f.ids[id] = true
+		} else {
+			t.drop(key, f, id)
+		}
+	}
+}
+
+// release records that the notification id was closed, removing the files
+// that no notification shows anymore.
+func (t *tempFiles) release(id uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, f := range t.files {
+		t.drop(key, f, id)
+	}
+}
+
+// drop records that the notification id no longer shows f, removing f if
+// it was the last one. t.mu must be held.
+func (t *tempFiles) drop(key string, f *tempFile, id uint32) {
+	if !f.ids[id] {
+		return
+	}
+	delete(f.ids, id)
+	if len(f.ids) == 0 {
+		os.Remove(f.path)
+		delete(t.files, key)
+	}
+}
+
+// removeAll removes all the temporary files and their directory.
+func (t *tempFiles) removeAll() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(t.dir)
+	t.dir, t.files = "", nil
+	if err != nil {
+		return fmt.Errorf("notify: removing the temporary files: %w", err)
+	}
+	return nil
+}
+
+// sweepStaleTemps removes the directories of temporary files and cached
+// images in dir older than staleTempAge, left by processes that crashed.
+// Those of the processes still running are kept, however old.
+func sweepStaleTemps(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), tempDirPrefix) {
+			continue
+		}
+		s, _, ok := strings.Cut(strings.TrimPrefix(e.Name(), tempDirPrefix), "-")
+		pid, err := strconv.Atoi(s)
+		if !ok || err != nil || pid == os.Getpid() || processAlive(pid) {
+			continue
+		}
+		if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > staleTempAge {
+			os.RemoveAll(filepath.Join(dir, e.Name()))
+		}
+	}
+}
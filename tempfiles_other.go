@@ -0,0 +1,13 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build !unix
+
+package notify
+
+// processAlive returns false: without a way to tell, the directories of the
+// other processes are swept by their age only.
+func processAlive(pid int) bool {
+	return false
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+var iconFS = fstest.MapFS{
+	"icons/app.png":   {Data: []byte("app icon")},
+	"icons/other.png": {Data: []byte("other icon")},
+	"icons/copy.png":  {Data: []byte("app icon")},
+}
+
+// sendIcon sends a notification with the icon name of iconFS, and returns
+// it with the icon path the daemon received.
+func sendIcon(t *testing.T, s *fakeServer, nf *notify.Notifier, name string) (*notify.Notification, string) {
+	t.Helper()
+	n := notify.New("test", name, "", "", 0, notify.NormalUrgency)
+	if err := n.SetIconFromFS(iconFS, name); err != nil {
+		t.Fatal(err)
+	}
+	n.OnClose = func(notify.CloseReason) {}
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	return n, s.last(t).AppIcon
+}
+
+func TestSetIconFromFS(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	a, pathA := sendIcon(t, s, nf, "icons/app.png")
+	b, pathB := sendIcon(t, s, nf, "icons/copy.png")
+	c, pathC := sendIcon(t, s, nf, "icons/other.png")
+	if pathA != pathB {
+		t.Errorf("the same icon was written twice, to %s and %s", pathA, pathB)
+	}
+	if pathA == pathC {
+		t.Errorf("different icons share %s", pathA)
+	}
+	if data, err := os.ReadFile(pathA); err != nil || string(data) != "app icon" {
+		t.Errorf("ReadFile(%s) = %q, %v", pathA, data, err)
+	}
+	if filepath.Ext(pathA) != ".png" {
+		t.Errorf("icon path %s has not the extension of the icon", pathA)
+	}
+
+	// The files are removed once no notification shows them.
+	s.emitClosed(a.Id, uint32(notify.ReasonDismissed))
+	s.emitClosed(c.Id, uint32(notify.ReasonDismissed))
+	waitFor(t, "the removal of the other icon", func() bool {
+		_, err := os.Stat(pathC)
+		return os.IsNotExist(err)
+	})
+	if _, err := os.Stat(pathA); err != nil {
+		t.Errorf("icon still shown by a notification: %v", err)
+	}
+	s.emitClosed(b.Id, uint32(notify.ReasonDismissed))
+	waitFor(t, "the removal of the app icon", func() bool {
+		_, err := os.Stat(pathA)
+		return os.IsNotExist(err)
+	})
+
+	if err := (&notify.Notification{}).SetIconFromFS(iconFS, "missing.png"); err == nil {
+		t.Error("SetIconFromFS succeeded with a missing file")
+	}
+}
+
+func TestTempFilesShutdown(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	_, path := sendIcon(t, s, nf, "icons/app.png")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := nf.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("%d entries left in the temporary directory after Shutdown", len(entries))
+	}
+}
+
+func TestTempFilesStaleSweep(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	// The directory of a process that exited, and those of a running one.
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skipf("no process to exit: %v", err)
+	}
+	stale := filepath.Join(tmp, fmt.Sprintf("notify-%d-stale", exited.Process.Pid))
+	running := filepath.Join(tmp, fmt.Sprintf("notify-%d-running", os.Getppid()))
+	recent := filepath.Join(tmp, "notify-2-recent")
+	unrelated := filepath.Join(tmp, "notify-test-old")
+	for _, dir := range []string{stale, running, recent, unrelated} {
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, dir := range []string{stale, running, unrelated} {
+		os.Chtimes(dir, old, old)
+	}
+
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	sendIcon(t, s, nf, "icons/app.png")
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale directory not swept: %v", err)
+	}
+	for _, dir := range []string{running, recent, unrelated} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s was swept: %v", dir, err)
+		}
+	}
+}
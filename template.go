@@ -0,0 +1,51 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+// Fire sends a copy of template with the given summary and body, as a new
+// notification, and returns the copy. template itself is only read, so one
+// template can be fired from many goroutines at once, as long as none of
+// them changes it meanwhile:
+//
+//	critical := notify.New("prog", "", "", "critical-icon.png", 0, notify.CriticalUrgency)
+//	go critical.Fire("Disk full", "/home has no space left")
+//	go critical.Fire("Your computer is on fire!", "Here is what you should do:\n ...")
+//
+// The copy starts without an ID or a correlation ID, so it is shown as a
+// new notification, unless template has a Tag: each copy then replaces the
+// previous one. Its actions and hints are copied; it shares the callbacks
+// and the image of template, which a send never changes.
+func (nf *Notifier) Fire(template *Notification, summary, body string) (*Notification, error) {
+	n := template.clone()
+	n.Summary, n.Body = summary, body
+	if _, err := nf.Notify(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Fire calls Notifier.Fire on the default Notifier, with n as the template.
+func (n *Notification) Fire(summary, body string) (*Notification, error) {
+	return Default().Fire(n, summary, body)
+}
+
+// clone returns a copy of n that shares nothing written by a send, without
+// the state of the sends of n.
+func (n *Notification) clone() *Notification {
+	cp := *n
+	cp.Id = 0
+	cp.CorrelationID = ""
+	cp.Actions = append([]Action(nil), n.Actions...)
+	if n.hints != nil {
+		cp.hints = make(map[string]interface{}, len(n.hints))
+		for k, v := range n.hints {
+			cp.hints[k] = v
+		}
+	}
+	cp.cache = sendCache{}
+	cp.activationToken = ""
+	cp.closed = false
+	return &cp
+}
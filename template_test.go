@@ -0,0 +1,70 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestFire(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	template := notify.New("test", "template", "", "icon", 0, notify.CriticalUrgency)
+	template.AddAction("ack", "Acknowledge")
+	template.SetHint("category", "device.error")
+
+	const goroutines = 20
+	fired := make([]*notify.Notification, goroutines)
+	var wg sync.WaitGroup
+	for i := range fired {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n, err := nf.Fire(template, fmt.Sprintf("summary %d", i), fmt.Sprintf("body %d", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			fired[i] = n
+		}(i)
+	}
+	wg.Wait()
+
+	if template.Id != 0 || template.Summary != "template" || template.CorrelationID != "" || len(template.Actions) != 1 {
+		t.Errorf("template changed: %+v", template)
+	}
+	ids := make(map[uint32]bool)
+	for i, n := range fired {
+		if n == nil {
+			continue
+		}
+		if n == template || n.Id == 0 || ids[n.Id] || n.Summary != fmt.Sprintf("summary %d", i) || n.Body != fmt.Sprintf("body %d", i) {
+			t.Errorf("fired notification %d = %+v", i, n)
+		}
+		ids[n.Id] = true
+		if n.Hint("category") != "device.error" || len(n.Actions) != 1 {
+			t.Errorf("fired notification %d lost the actions or hints of the template: %+v", i, n)
+		}
+	}
+	if got := len(s.notifications()); got != goroutines {
+		t.Errorf("the daemon got %d notifications, want %d", got, goroutines)
+	}
+	for _, c := range s.notifications() {
+		if c.ReplacesID != 0 {
+			t.Errorf("fired notification replaced %d", c.ReplacesID)
+		}
+	}
+
+	// Changing a fired notification leaves the template alone.
+	fired[0].AddAction("more", "More")
+	fired[0].SetHint("category", "other")
+	if len(template.Actions) != 1 || template.Hint("category") != "device.error" {
+		t.Errorf("template shares its actions or hints: %+v", template)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+)
+
+// Phase is a step in the life of a notification reported to a TraceHook.
+type Phase int
+
+const (
+	PhaseBefore       Phase = iota // PhaseBefore is right before the call is delivered.
+	PhaseAfterReply                // PhaseAfterReply is once the transport replied, with its error if any.
+	PhaseSignalClosed              // PhaseSignalClosed is when the daemon signals that the notification was closed.
+	PhaseSignalAction              // PhaseSignalAction is when the daemon signals that an action was invoked.
+)
+
+// String returns the name of the phase.
+func (p Phase) String() string {
+	switch p {
+	case PhaseBefore:
+		return "before"
+	case PhaseAfterReply:
+		return "after-reply"
+	case PhaseSignalClosed:
+		return "signal-closed"
+	case PhaseSignalAction:
+		return "signal-action"
+	}
+	return fmt.Sprintf("Phase(%d)", int(p))
+}
+
+// TraceHook is called at each Phase of the notifications sent by a Notifier,
+// for tracing. ctx is the context given to SendContext, or
+// context.Background() for Notify, so that hooks can get their span from
+// it; for the signals, it is the context of the last send of the
+// notification, which may be done by then. call is the call sent, which
+// must not be modified.
+type TraceHook func(ctx context.Context, phase Phase, call *Call, err error)
+
+// WithTraceHook makes the Notifier call hook at each Phase of its
+// notifications. The signal phases are only reported over D-Bus, for the
+// notifications sent since hook was set.
+func WithTraceHook(hook TraceHook) Option {
+	return func(nf *Notifier) error {
+		nf.traceHook = hook
+		return nil
+	}
+}
+
+// trace calls the trace hook of nf, if any.
+func (nf *Notifier) trace(ctx context.Context, phase Phase, c *Call, err error) {
+	if nf.traceHook == nil {
+		return
+	}
+	nf.callback("TraceHook", 0, func() { nf.traceHook(ctx, phase, c, err) })
+}
+
+// correlationKey is the context key of the correlation ID.
+type correlationKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx holding the correlation ID
+// id. SendContext gives it to the notifications sent with the returned
+// context that have no CorrelationID.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID held by ctx, see
+// ContextWithCorrelationID.
+func CorrelationIDFromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(correlationKey{}).(string)
+	return id, ok
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+type traceKey struct{}
+
+// traceRecorder records the calls of a trace hook.
+type traceRecorder struct {
+	mu     sync.Mutex
+	phases []notify.Phase
+	values []interface{}
+	errs   []error
+}
+
+func (r *traceRecorder) hook(ctx context.Context, phase notify.Phase, c *notify.Call, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.phases = append(r.phases, phase)
+	r.values = append(r.values, ctx.Value(traceKey{}))
+	r.errs = append(r.errs, err)
+}
+
+func (r *traceRecorder) recorded() []notify.Phase {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]notify.Phase(nil), r.phases...)
+}
+
+func TestTraceHook(t *testing.T) {
+	s := newFakeServer(t)
+	var r traceRecorder
+	nf := newTestNotifier(t, notify.WithTraceHook(r.hook))
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "span")
+	n := notify.New("test", "traced", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	res, err := nf.SendContext(ctx, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.emitAction(res.Id, notify.DefaultAction)
+	s.emitClosed(res.Id, uint32(notify.ReasonDismissed))
+
+	want := []notify.Phase{notify.PhaseBefore, notify.PhaseAfterReply, notify.PhaseSignalAction, notify.PhaseSignalClosed}
+	waitFor(t, "the signal phases", func() bool { return len(r.recorded()) == len(want) })
+	if got := r.recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("phases = %v, want %v", got, want)
+	}
+	for i, v := range r.values {
+		if v != "span" {
+			t.Errorf("phase %v: context value = %v, want span", r.phases[i], v)
+		}
+	}
+}
+
+func TestTraceHookError(t *testing.T) {
+	var r traceRecorder
+	fail := errors.New("down")
+	nf, err := notify.NewNotifier(notify.WithTransport(failingTransport{fail}), notify.WithTraceHook(r.hook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.Notify(notify.New("test", "lost", "", "", 0, notify.NormalUrgency)); !errors.Is(err, fail) {
+		t.Fatalf("Notify = %v, want %v", err, fail)
+	}
+	want := []notify.Phase{notify.PhaseBefore, notify.PhaseAfterReply}
+	if got := r.recorded(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("phases = %v, want %v", got, want)
+	}
+	if r.errs[0] != nil || !errors.Is(r.errs[1], fail) {
+		t.Errorf("errors = %v, want [<nil> %v]", r.errs, fail)
+	}
+}
+
+func TestContextCorrelationID(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	ctx := notify.ContextWithCorrelationID(context.Background(), "req-42")
+	if id, ok := notify.CorrelationIDFromContext(ctx); !ok || id != "req-42" {
+		t.Fatalf("CorrelationIDFromContext = %q, %v", id, ok)
+	}
+	n := notify.New("test", "correlated", "", "", 0, notify.NormalUrgency)
+	if _, err := nf.SendContext(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if n.CorrelationID != "req-42" {
+		t.Errorf("CorrelationID = %q, want req-42", n.CorrelationID)
+	}
+
+	n = notify.New("test", "own ID", "", "", 0, notify.NormalUrgency)
+	n.CorrelationID = "mine"
+	if _, err := nf.SendContext(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if n.CorrelationID != "mine" {
+		t.Errorf("CorrelationID = %q, want mine", n.CorrelationID)
+	}
+}
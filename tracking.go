@@ -0,0 +1,150 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"container/list"
+	"fmt"
+	"time"
+)
+
+// WithTrackingLimits bounds what nf remembers of the notifications it sent:
+// their callbacks, tags and last calls, normally forgotten when the daemon
+// signals that they were closed. Some daemons never do, so a program
+// sending many notifications would otherwise remember them all.
+//
+// A notification is forgotten ttl after its timeout, or ttl after it was
+// last sent if it never expires or lets the daemon choose. The notifications
+// are checked on the sends, at most once per ttl, so they may be kept up to
+// twice as long. When more than max notifications are remembered, the one
+// sent the longest ago is forgotten. Notifications being posted again, see
+// Persistent, are never forgotten. A zero ttl or max means no such limit,
+// which is the default.
+//
+// A forgotten notification is no longer updated by the signals of the
+// daemon: its OnClose callback is called with ReasonUndefined, so that the
+// resources tied to it can be released. The forgotten notifications are
+// counted in Stats.Evicted.
+func WithTrackingLimits(max int, ttl time.Duration) Option {
+	return func(nf *Notifier) error {
+		if max < 0 || ttl < 0 {
+			return fmt.Errorf("notify: invalid tracking limits %d, %v", max, ttl)
+		}
+		nf.trackMax, nf.trackTTL = max, ttl
+		return nil
+	}
+}
+
+// trackOrder holds the notifications remembered by a Notifier with
+// tracking limits, the one sent the longest ago first.
+type trackOrder struct {
+	order *list.List
+	byID  map[uint32]*list.Element
+	// swept is when the expired notifications were last forgotten.
+	swept time.Time
+}
+
+// trackEntry is a notification in a trackOrder.
+type trackEntry struct {
+	id      uint32
+	tag     string
+	expires time.Time
+}
+
+// limitTracking records that n was just sent, and forgets the notifications
+// past the tracking limits of nf.
+func (nf *Notifier) limitTracking(n *Notification) {
+	if nf.trackMax == 0 && nf.trackTTL == 0 {
+		return
+	}
+	nf.mu.Lock()
+	now := nf.clock.Now()
+	o := &nf.trackOrder
+	if o.order == nil {
+		o.order = list.New()
+		o.byID = make(map[uint32]*list.Element)
+	}
+	if e, ok := o.byID[n.Id]; ok {
+		o.order.Remove(e)
+	}
+	expires := now.Add(nf.trackTTL)
+	if n.Timeout > 0 {
+		expires = expires.Add(n.TimeoutDuration())
+	}
+	o.byID[n.Id] = o.order.PushBack(&trackEntry{n.Id, n.Tag, expires})
+
+	var gone []evicted
+	if nf.trackTTL > 0 && now.Sub(o.swept) >= nf.trackTTL {
+		o.swept = now
+		for e := o.order.Front(); e != nil; {
+			next := e.Next()
+			if te := e.Value.(*trackEntry); now.After(te.expires) && nf.reposts[te.id] == nil {
+				gone = append(gone, nf.evict(e))
+			}
+			e = next
+		}
+	}
+	if nf.trackMax > 0 {
+		for e := o.order.Front(); e != nil && o.order.Len() > nf.trackMax; {
+			next := e.Next()
+			if nf.reposts[e.Value.(*trackEntry).id] == nil {
+				gone = append(gone, nf.evict(e))
+			}
+			e = next
+		}
+	}
+	nf.mu.Unlock()
+
+	for _, e := range gone {
+		nf.tempFiles().release(e.id)
+		// A notification sent again with another ID is still shown.
+		if n := e.n; n != nil && n.Id == e.id && n.OnClose != nil {
+			nf.runCallback("OnClose", e.id, func() { n.OnClose(ReasonUndefined) })
+		}
+	}
+	if len(gone) > 0 {
+		nf.log(LevelDebug, fmt.Sprintf("forgot %d notifications past the tracking limits", len(gone)), nil)
+	}
+}
+
+// evicted is a notification forgotten by limitTracking, with n nil if it
+// did not get signals.
+type evicted struct {
+	id uint32
+	n  *Notification
+}
+
+// evict forgets the notification of e. It must be called with nf.mu held.
+func (nf *Notifier) evict(e *list.Element) evicted {
+	te := e.Value.(*trackEntry)
+	nf.trackOrder.order.Remove(e)
+	delete(nf.trackOrder.byID, te.id)
+	t := nf.tracked[te.id]
+	delete(nf.tracked, te.id)
+	if timer, ok := nf.closing[te.id]; ok {
+		timer.Stop()
+		delete(nf.closing, te.id)
+	}
+	nf.forgetSent(te.id)
+	delete(nf.activationTokens, te.id)
+	if te.tag != "" && nf.tags[te.tag].id == te.id {
+		delete(nf.tags, te.tag)
+	}
+	if t.n != nil {
+		delete(nf.actionPages, t.n)
+	}
+	nf.stats.Evicted++
+	return evicted{te.id, t.n}
+}
+
+// forgetTracked removes id from the notifications remembered under the
+// tracking limits, once the daemon closed it. It must be called with nf.mu
+// held.
+func (nf *Notifier) forgetTracked(id uint32) {
+	if e, ok := nf.trackOrder.byID[id]; ok {
+		nf.trackOrder.order.Remove(e)
+		delete(nf.trackOrder.byID, id)
+	}
+}
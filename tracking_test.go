@@ -0,0 +1,129 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestTrackingLimitsMax(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithTrackingLimits(3, 0))
+
+	var cb callbacks
+	var sent []*notify.Notification
+	for i := 0; i < 10; i++ {
+		n := notify.New("test", fmt.Sprintf("notification %d", i), "", "", 0, notify.NormalUrgency)
+		n.Tag = fmt.Sprintf("tag %d", i)
+		cb.attach(n)
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		sent = append(sent, n)
+		if st := nf.Stats(); st.Tracked > 3 {
+			t.Fatalf("after %d sends, Stats().Tracked = %d, want at most 3", i+1, st.Tracked)
+		}
+	}
+	if st := nf.Stats(); st.Tracked != 3 || st.Evicted != 7 {
+		t.Errorf("Stats() = %+v, want 3 tracked and 7 evicted", st)
+	}
+	reasons := cb.closed()
+	if len(reasons) != 7 {
+		t.Fatalf("OnClose called %d times, want 7", len(reasons))
+	}
+	for _, r := range reasons {
+		if r != notify.ReasonUndefined {
+			t.Errorf("OnClose called with %v, want ReasonUndefined", r)
+		}
+	}
+
+	// A notification closed by the daemon is no longer counted against the
+	// limit.
+	s.emitClosed(sent[9].Id, uint32(notify.ReasonDismissed))
+	waitFor(t, "close", func() bool { return len(cb.closed()) == 8 })
+	if _, err := nf.Notify(notify.New("test", "one more", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if st := nf.Stats(); st.Evicted != 7 {
+		t.Errorf("Stats().Evicted = %d after a close, want 7", st.Evicted)
+	}
+
+	// The tag of an evicted notification no longer replaces it.
+	n := notify.New("test", "tagged", "", "", 0, notify.NormalUrgency)
+	n.Tag = "tag 0"
+	if res, err := nf.Notify(n); err != nil || res.Replaced {
+		t.Errorf("Notify with the tag of an evicted notification = %+v, %v, want a new notification", res, err)
+	}
+}
+
+func TestTrackingLimitsTTL(t *testing.T) {
+	newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithTrackingLimits(0, time.Minute))
+
+	var cb callbacks
+	send := func(timeout time.Duration) {
+		t.Helper()
+		n := notify.New("test", "ttl", "", "", timeout, notify.NormalUrgency)
+		cb.attach(n)
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(when string, tracked int, evicted uint64) {
+		t.Helper()
+		if st := nf.Stats(); st.Tracked != tracked || st.Evicted != evicted {
+			t.Errorf("%s: Stats() has %d tracked and %d evicted, want %d and %d", when, st.Tracked, st.Evicted, tracked, evicted)
+		}
+	}
+
+	send(5 * time.Second) // expires at 1m05s
+	clock.Advance(30 * time.Second)
+	send(0) // never expires, forgotten at 1m30s
+	check("at 30s", 2, 0)
+	clock.Advance(40 * time.Second)
+	send(0)
+	check("at 1m10s", 2, 1)
+	clock.Advance(time.Minute)
+	send(0)
+	check("at 2m10s", 2, 2)
+	if reasons := cb.closed(); len(reasons) != 2 || reasons[0] != notify.ReasonUndefined {
+		t.Errorf("OnClose called with %v, want ReasonUndefined twice", reasons)
+	}
+}
+
+func TestTrackingLimitsSteadyState(t *testing.T) {
+	newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock), notify.WithTrackingLimits(50, time.Minute))
+
+	for i := 0; i < 500; i++ {
+		n := notify.New("test", "steady", "", "", time.Second, notify.LowUrgency)
+		n.OnClose = func(notify.CloseReason) {}
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Second)
+		if st := nf.Stats(); st.Tracked > 50 {
+			t.Fatalf("after %d sends, Stats().Tracked = %d, want at most 50", i+1, st.Tracked)
+		}
+	}
+	if st := nf.Stats(); st.Evicted != 450 {
+		t.Errorf("Stats().Evicted = %d, want 450", st.Evicted)
+	}
+}
+
+func TestTrackingLimitsInvalid(t *testing.T) {
+	if _, err := notify.NewNotifier(notify.WithTrackingLimits(-1, 0)); err == nil {
+		t.Error("WithTrackingLimits(-1, 0) succeeded")
+	}
+	if _, err := notify.NewNotifier(notify.WithTrackingLimits(0, -time.Second)); err == nil {
+		t.Error("WithTrackingLimits(0, -1s) succeeded")
+	}
+}
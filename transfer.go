@@ -0,0 +1,166 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Keys of the actions of transfer notifications.
+const (
+	CancelAction     = "cancel"
+	OpenFolderAction = "open-folder"
+)
+
+// Transfer is a notification showing the progress of a download, an upload,
+// or any other transfer of a known number of bytes. Create one with
+// NewTransfer.
+//
+// While in progress, the notification has a Cancel action that cancels the
+// context of the transfer. Once complete, it is replaced with one showing
+// the file and an "Open folder" action.
+type Transfer struct {
+	// Open is called with the folder of the file to open when the
+	// "Open folder" action is invoked. It defaults to running xdg-open.
+	Open func(dir string) error
+
+	nf      *Notifier
+	title   string
+	total   int64
+	started time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu sync.Mutex
+	n  *Notification
+}
+
+// NewTransfer returns a new Transfer of total bytes called title, sent via
+// notifier. If total is not known, use 0. Nothing is shown until the first
+// call to Progress.
+func NewTransfer(notifier *Notifier, title string, total int64) *Transfer {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Transfer{
+		Open:    notifier.xdgOpen,
+		nf:      notifier,
+		title:   title,
+		total:   total,
+		started: notifier.clock.Now(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	t.n = &Notification{
+		Summary:  title,
+		IconPath: "folder-download",
+		Urgency:  NormalUrgency,
+		Actions:  []Action{{CancelAction, notifier.localize(MsgCancel)}},
+		OnAction: t.onAction,
+	}
+	return t
+}
+
+// Context returns a context that is canceled when the user cancels the
+// transfer.
+func (t *Transfer) Context() context.Context { return t.ctx }
+
+// Notification returns the notification of the transfer, to customize it
+// before the first call to Progress.
+func (t *Transfer) Notification() *Notification { return t.n }
+
+// Progress shows that done bytes were transferred so far, with the
+// transfer speed.
+func (t *Transfer) Progress(done int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	body := formatBytes(done)
+	if t.total > 0 {
+		percent := done * 100 / t.total
+		if percent > 100 {
+			percent = 100
+		}
+		t.n.SetHint("value", int32(percent))
+		body = t.nf.localize(MsgTransferProgress, body, formatBytes(t.total))
+	}
+	if elapsed := t.nf.clock.Now().Sub(t.started).Seconds(); elapsed > 0 {
+		body = t.nf.localize(MsgTransferSpeed, body, formatBytes(int64(float64(done)/elapsed)))
+	}
+	t.n.Body = body
+	_, err := t.nf.Notify(t.n)
+	return err
+}
+
+// Fail replaces the notification with one showing err.
+func (t *Transfer) Fail(err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.n.Summary = t.nf.localize(MsgTransferFailed, t.title)
+	t.n.Body = err.Error()
+	t.n.IconPath = "dialog-error"
+	t.n.Urgency = CriticalUrgency
+	t.n.Actions = nil
+	t.n.SetHint("value", nil)
+	_, err = t.nf.Notify(t.n)
+	return err
+}
+
+// Complete replaces the notification with one showing the file at path and
+// an "Open folder" action.
+func (t *Transfer) Complete(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.n.Summary = t.nf.localize(MsgTransferComplete, t.title)
+	t.n.Body = filepath.Base(path)
+	t.n.IconPath = "emblem-default"
+	t.n.Actions = []Action{{OpenFolderAction, t.nf.localize(MsgOpenFolder)}}
+	t.n.CloseOnAction = true
+	t.n.SetHint("value", nil)
+	t.n.SetHint("x-kde-urls", []string{"file://" + path})
+	dir := filepath.Dir(path)
+	t.n.OnAction = func(key string) {
+		if key == OpenFolderAction && t.Open != nil {
+			if err := t.Open(dir); err != nil {
+				t.nf.log(LevelWarn, "opening "+dir+" failed", err)
+			}
+		}
+	}
+	_, err := t.nf.Notify(t.n)
+	return err
+}
+
+// onAction handles the actions of the notification while in progress.
+func (t *Transfer) onAction(key string) {
+	if key != CancelAction {
+		return
+	}
+	t.cancel()
+	t.mu.Lock()
+	id := t.n.Id
+	t.mu.Unlock()
+	if err := t.nf.CloseNotification(id); err != nil {
+		t.nf.log(LevelWarn, "closing canceled transfer failed", err)
+	}
+}
+
+// formatBytes formats n bytes with a binary unit prefix.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
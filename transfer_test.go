@@ -0,0 +1,96 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestTransferCancel(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	tr := notify.NewTransfer(nf, "Downloading movie.mkv", 4<<20)
+	if err := tr.Progress(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Progress(3 << 20); err != nil {
+		t.Fatal(err)
+	}
+	sent := s.notifications()
+	if len(sent) != 2 || sent[1].ReplacesID != sent[0].ID {
+		t.Fatalf("progress did not replace the notification: %+v", sent)
+	}
+	if v := sent[1].Hints["value"].Value(); v != int32(75) {
+		t.Errorf("value hint = %v, want 75", v)
+	}
+	if sent[1].Actions[0] != notify.CancelAction {
+		t.Errorf("no cancel action in %q", sent[1].Actions)
+	}
+
+	s.emitAction(sent[0].ID, notify.CancelAction)
+	select {
+	case <-tr.Context().Done():
+	case <-waitTimeout():
+		t.Fatal("context not canceled")
+	}
+	waitFor(t, "close", func() bool { return len(s.closedIDs()) == 1 })
+}
+
+func TestTransferComplete(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	opened := make(chan string, 1)
+	tr := notify.NewTransfer(nf, "Downloading movie.mkv", 0)
+	tr.Open = func(dir string) error {
+		opened <- dir
+		return nil
+	}
+	if err := tr.Progress(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Complete("/home/me/Downloads/movie.mkv"); err != nil {
+		t.Fatal(err)
+	}
+	done := s.last(t)
+	if done.Summary != "Downloading movie.mkv complete" || done.AppIcon != "emblem-default" ||
+		len(done.Actions) != 2 || done.Actions[0] != notify.OpenFolderAction {
+		t.Errorf("unexpected completion %+v", done)
+	}
+	if _, ok := done.Hints["value"]; ok {
+		t.Error("progress hint still set after completion")
+	}
+
+	s.emitAction(done.ID, notify.OpenFolderAction)
+	select {
+	case dir := <-opened:
+		if dir != "/home/me/Downloads" {
+			t.Errorf("opened %q", dir)
+		}
+	case <-waitTimeout():
+		t.Fatal("folder not opened")
+	}
+}
+
+func TestTransferFail(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+
+	tr := notify.NewTransfer(nf, "Uploading", 100)
+	if err := tr.Progress(10); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Fail(errors.New("connection reset")); err != nil {
+		t.Fatal(err)
+	}
+	failed := s.last(t)
+	if failed.Body != "connection reset" || len(failed.Actions) != 0 || failed.Hints["urgency"].Value() != byte(notify.CriticalUrgency) {
+		t.Errorf("unexpected failure %+v", failed)
+	}
+}
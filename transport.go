@@ -0,0 +1,124 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoTransport is returned when sending on a platform without D-Bus, by a
+// Notifier that was not given a Transport.
+var ErrNoTransport = errors.New("notify: no notification transport on this platform")
+
+// Transport delivers the calls of a Notifier. The default transport sends
+// them to the notification daemon over D-Bus; use WithTransport to deliver
+// them some other way. The transports whose notifications have string IDs
+// chosen by the caller implement StringIDTransport.
+//
+// Signals are only received with transports reporting SignalSupport, like
+// the D-Bus transport, possibly mirrored with WithMirrors. With other
+// transports, notifications with OnAction, OnClose or OnReply callbacks are
+// rejected with ErrUnsupported, Ask returns ErrUnsupported, SendAndWait only
+// waits for the timeout of the notification, and persistent notifications
+// are not posted again.
+type Transport interface {
+	// Notify delivers c and returns the ID of the notification.
+	Notify(ctx context.Context, c Call) (id uint32, err error)
+	// CloseNotification closes the notification id.
+	CloseNotification(id uint32) error
+}
+
+// SignalSupporter is implemented by the transports that can report whether
+// the signals of the daemon, like ActionInvoked, reach the Notifier.
+type SignalSupporter interface {
+	SignalSupport() bool
+}
+
+// errNoSignals is returned for what requires the signals of the daemon on a
+// transport without them.
+var errNoSignals = fmt.Errorf("%w: the transport does not deliver signals", ErrUnsupported)
+
+// WithTransport makes the Notifier deliver its calls via t instead of D-Bus.
+func WithTransport(t Transport) Option {
+	return func(nf *Notifier) error {
+		if t == nil {
+			return errors.New("notify: nil transport")
+		}
+		nf.transport = t
+		return nil
+	}
+}
+
+// onBus returns true if nf delivers its calls over D-Bus, and so receives
+// signals.
+func (nf *Notifier) onBus() bool {
+	t := nf.transport
+	if mt, ok := t.(*MultiTransport); ok {
+		t = mt.Primary
+	}
+	_, ok := t.(busTransport)
+	return ok
+}
+
+// signalSupport returns true if the transport of nf delivers the signals of
+// the daemon. A MultiTransport does if its primary transport does.
+func (nf *Notifier) signalSupport() bool {
+	t := nf.transport
+	if mt, ok := t.(*MultiTransport); ok {
+		t = mt.Primary
+	}
+	s, ok := t.(SignalSupporter)
+	return ok && s.SignalSupport()
+}
+
+// extraReplier is implemented by the transports reporting the extra
+// out-arguments of the Notify replies, see SendResult.Extra.
+type extraReplier interface {
+	notifyExtra(ctx context.Context, c Call) (uint32, []interface{}, error)
+}
+
+// busTransport delivers the calls of a Notifier over its D-Bus connection.
+type busTransport struct {
+	nf *Notifier
+}
+
+// SignalSupport returns true: the signals come over the connection.
+func (t busTransport) SignalSupport() bool { return true }
+
+func (t busTransport) Notify(ctx context.Context, c Call) (uint32, error) {
+	id, _, err := t.notifyExtra(ctx, c)
+	return id, err
+}
+
+func (t busTransport) notifyExtra(ctx context.Context, c Call) (uint32, []interface{}, error) {
+	if t.nf == nil {
+		return 0, nil, ErrNoTransport
+	}
+	calls := t.nf.inFlight()
+	if err := calls.acquire(ctx); err != nil {
+		return 0, nil, err
+	}
+	defer calls.release()
+	id, extra, err := t.nf.rawNotifyExtra(ctx, c)
+	return id, extra, rateLimited(noDaemon(err))
+}
+
+func (t busTransport) CloseNotification(id uint32) error {
+	if t.nf == nil {
+		return ErrNoTransport
+	}
+	conn, err := t.nf.connection()
+	if err != nil {
+		return err
+	}
+	calls := t.nf.inFlight()
+	if err := calls.acquire(context.Background()); err != nil {
+		return err
+	}
+	defer calls.release()
+	return t.nf.object(conn).Call(dbusInterface+".CloseNotification", t.nf.callFlags(), id).Err
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// recordingTransport is a Transport recording the calls it delivers.
+type recordingTransport struct {
+	mu     sync.Mutex
+	calls  []notify.Call
+	closed []uint32
+}
+
+func (t *recordingTransport) Notify(ctx context.Context, c notify.Call) (uint32, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = append(t.calls, c)
+	if c.ReplacesID != 0 {
+		return c.ReplacesID, nil
+	}
+	return uint32(len(t.calls)), nil
+}
+
+func (t *recordingTransport) CloseNotification(id uint32) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = append(t.closed, id)
+	return nil
+}
+
+func TestWithTransport(t *testing.T) {
+	var rt recordingTransport
+	nf, err := notify.NewNotifier(notify.WithTransport(&rt), notify.WithBusAddress("unix:path=/nonexistent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	n := notify.New("test", "summary", "body", "", time.Second, notify.CriticalUrgency)
+	res, err := nf.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Id != 1 || n.Id != 1 || res.ServerChanged {
+		t.Errorf("Notify returned %+v", res)
+	}
+	if len(rt.calls) != 1 || rt.calls[0].Summary != "summary" || rt.calls[0].Hints["urgency"].Value() != byte(notify.CriticalUrgency) {
+		t.Errorf("delivered %+v", rt.calls)
+	}
+
+	if err := nf.CloseNotification(n.Id); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.closed) != 1 || rt.closed[0] != n.Id {
+		t.Errorf("closed %v", rt.closed)
+	}
+}
+
+func TestWithMirrors(t *testing.T) {
+	s := newFakeServer(t)
+	var mirror recordingTransport
+	nf := newTestNotifier(t, notify.WithMirrors(&mirror))
+
+	var cb callbacks
+	n := notify.New("test", "mirrored", "", "", 0, notify.NormalUrgency)
+	n.AddAction(notify.DefaultAction, "Open")
+	cb.attach(n)
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if len(mirror.calls) != 1 || mirror.calls[0].Summary != "mirrored" {
+		t.Errorf("mirror got %+v", mirror.calls)
+	}
+
+	// Signals still come from the daemon.
+	s.emitAction(n.Id, notify.DefaultAction)
+	waitFor(t, "action", func() bool { return len(cb.invoked()) == 1 })
+
+	if err := nf.CloseNotification(n.Id); err != nil {
+		t.Fatal(err)
+	}
+	if len(mirror.closed) != 1 || mirror.closed[0] != n.Id {
+		t.Errorf("mirror closed %v, want [%d]", mirror.closed, n.Id)
+	}
+}
+
+func TestMirrorsWithoutDaemon(t *testing.T) {
+	var mirror recordingTransport
+	nf, err := notify.NewNotifier(notify.WithBusAddress("unix:path=/nonexistent"), notify.WithMirrors(&mirror))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	if _, err := nf.Notify(notify.New("test", "no daemon", "", "", 0, notify.NormalUrgency)); err == nil {
+		t.Error("Notify succeeded without a daemon")
+	}
+	if len(mirror.calls) != 1 {
+		t.Errorf("mirror got %d calls, want 1", len(mirror.calls))
+	}
+}
+
+func TestTransportWithoutSignals(t *testing.T) {
+	var rt recordingTransport
+	clock := newFakeClock()
+	nf, err := notify.NewNotifier(notify.WithTransport(&rt), notify.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nf.Close()
+
+	n := notify.New("test", "Reboot?", "", "", time.Minute, notify.NormalUrgency)
+	n.AddAction("now", "Reboot now")
+	n.AddAction("later", "Later")
+	n.OnAction = func(string) {}
+	if _, err := nf.Notify(n); !errors.Is(err, notify.ErrUnsupported) {
+		t.Errorf("Notify with OnAction = %v, want ErrUnsupported", err)
+	}
+	n.OnAction = nil
+	if _, err := nf.Ask(context.Background(), n); !errors.Is(err, notify.ErrUnsupported) {
+		t.Errorf("Ask = %v, want ErrUnsupported", err)
+	}
+	if len(rt.calls) != 0 {
+		t.Fatalf("delivered %d calls, want none", len(rt.calls))
+	}
+
+	tests := []struct {
+		opts    []notify.WaitOption
+		advance time.Duration
+		key     string
+		err     error
+	}{
+		{nil, time.Minute, "", notify.ErrDismissed},
+		{[]notify.WaitOption{notify.WithDefaultChoice("later", 10*time.Second)}, 10 * time.Second, "later", nil},
+	}
+	for _, tt := range tests {
+		type result struct {
+			key string
+			err error
+		}
+		results := make(chan result, 1)
+		go func() {
+			key, err := nf.SendAndWait(context.Background(), n, tt.opts...)
+			results <- result{key, err}
+		}()
+		waitFor(t, "the timer", func() bool { return clock.Timers() == 1 })
+		clock.Advance(tt.advance)
+		select {
+		case r := <-results:
+			if r.key != tt.key || !errors.Is(r.err, tt.err) {
+				t.Errorf("SendAndWait() = %q, %v, want %q, %v", r.key, r.err, tt.key, tt.err)
+			}
+		case <-waitTimeout():
+			t.Fatal("SendAndWait did not return")
+		}
+	}
+
+	n.Timeout = 0
+	if _, err := nf.SendAndWait(context.Background(), n); !errors.Is(err, notify.ErrUnsupported) {
+		t.Errorf("SendAndWait without a timeout = %v, want ErrUnsupported", err)
+	}
+}
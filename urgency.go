@@ -0,0 +1,102 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// UrgencyMapFlag changes how WithUrgencyMap validates its map.
+type UrgencyMapFlag int
+
+const (
+	// AllowNonStandard lets WithUrgencyMap map urgencies to bytes other
+	// than the 0, 1 and 2 of the specification.
+	AllowNonStandard UrgencyMapFlag = 1 << iota
+)
+
+// WithUrgencyMap sets the byte sent in the urgency hint for the urgencies
+// in m, for daemons that do not read them as the specification says. The
+// urgencies missing from m keep the byte of the specification.
+//
+// The map applies to every urgency hint sent by the Notifier, including
+// the ones set with UrgencyHint and those of the package-level functions.
+// Call.Urgency still holds the urgency before it was mapped, which is what
+// transports like the journal use.
+//
+// Bytes other than 0, 1 and 2 are rejected, unless flags has
+// AllowNonStandard.
+func WithUrgencyMap(m map[NotificationUrgency]byte, flags ...UrgencyMapFlag) Option {
+	var allow UrgencyMapFlag
+	for _, f := range flags {
+		allow |= f
+	}
+	return func(nf *Notifier) error {
+		var hints [CriticalUrgency + 1]map[string]dbus.Variant
+		for u, b := range m {
+			if u > CriticalUrgency {
+				return fmt.Errorf("notify: urgency map: unknown urgency %d", byte(u))
+			}
+			if b > byte(CriticalUrgency) && allow&AllowNonStandard == 0 {
+				return fmt.Errorf("notify: urgency map: %v mapped to %d, outside 0-2", u, b)
+			}
+			hints[u] = map[string]dbus.Variant{"urgency": dbus.MakeVariant(b)}
+		}
+		nf.urgencyHints = hints
+		return nil
+	}
+}
+
+// urgencyHint returns the hints holding only the urgency hint of u, as nf
+// sends it. The map is shared, and must not be modified.
+func (nf *Notifier) urgencyHint(u NotificationUrgency) map[string]dbus.Variant {
+	if int(u) < len(nf.urgencyHints) && nf.urgencyHints[u] != nil {
+		return nf.urgencyHints[u]
+	}
+	return u.asHint()
+}
+
+// mapUrgencyHint replaces the urgency hint set by the custom hints in hints
+// with the one nf sends for it.
+func (nf *Notifier) mapUrgencyHint(hints map[string]dbus.Variant, custom map[string]interface{}) {
+	if _, ok := custom["urgency"]; !ok {
+		return
+	}
+	if b, ok := hints["urgency"].Value().(byte); ok && int(b) < len(nf.urgencyHints) {
+		if mapped := nf.urgencyHints[b]; mapped != nil {
+			hints["urgency"] = mapped["urgency"]
+		}
+	}
+}
+
+// urgency returns the urgency n is sent with: the one of its UrgencyHint,
+// if it has a valid one, or its Urgency, see baseUrgency.
+func (n *Notification) urgency() NotificationUrgency {
+	v := n.hints["urgency"]
+	if variant, ok := v.(dbus.Variant); ok {
+		v = variant.Value()
+	}
+	if b, ok := v.(byte); ok && b <= byte(CriticalUrgency) {
+		return NotificationUrgency(b)
+	}
+	return n.baseUrgency()
+}
+
+// baseUrgency returns Urgency, or NormalUrgency if it is the zero value of
+// a Notification literal, which was not set with New or SetUrgency.
+func (n *Notification) baseUrgency() NotificationUrgency {
+	if n.Urgency == LowUrgency && !n.urgencySet {
+		return NormalUrgency
+	}
+	return n.Urgency
+}
+
+// SetUrgency sets Urgency to u. It is the way to send a Notification literal
+// with LowUrgency, the zero value of Urgency otherwise sent as NormalUrgency.
+func (n *Notification) SetUrgency(u NotificationUrgency) {
+	n.Urgency, n.urgencySet = u, true
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestUrgencyMap(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithUrgencyMap(map[notify.NotificationUrgency]byte{
+		notify.LowUrgency:      2,
+		notify.CriticalUrgency: 0,
+	}))
+
+	for _, c := range []struct {
+		urgency notify.NotificationUrgency
+		want    byte
+	}{
+		{notify.LowUrgency, 2},
+		{notify.NormalUrgency, 1},
+		{notify.CriticalUrgency, 0},
+	} {
+		n := notify.New("test", c.urgency.String(), "", "", time.Second, c.urgency)
+		if _, err := nf.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.last(t).Hints["urgency"].Value(); got != c.want {
+			t.Errorf("%v: urgency hint = %v, want %d", c.urgency, got, c.want)
+		}
+		call, err := n.DryRun(nf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if call.Urgency != c.urgency {
+			t.Errorf("%v: Call.Urgency = %v", c.urgency, call.Urgency)
+		}
+	}
+
+	// The typed hint is mapped too.
+	n := notify.New("test", "typed", "", "", time.Second, notify.NormalUrgency)
+	n.AddHints(notify.UrgencyHint(notify.CriticalUrgency))
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Hints["urgency"].Value(); got != byte(0) {
+		t.Errorf("typed hint = %v, want 0", got)
+	}
+}
+
+func TestUrgencyMapNonStandard(t *testing.T) {
+	m := map[notify.NotificationUrgency]byte{notify.CriticalUrgency: 7}
+	if _, err := notify.NewNotifier(notify.WithUrgencyMap(m)); err == nil {
+		t.Error("byte 7 accepted without AllowNonStandard")
+	}
+	if _, err := notify.NewNotifier(notify.WithUrgencyMap(map[notify.NotificationUrgency]byte{3: 1})); err == nil {
+		t.Error("unknown urgency accepted")
+	}
+
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithUrgencyMap(m, notify.AllowNonStandard))
+	if _, err := nf.Notify(notify.New("test", "odd", "", "", time.Second, notify.CriticalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t).Hints["urgency"].Value(); got != byte(7) {
+		t.Errorf("urgency hint = %v, want 7", got)
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build !(linux || freebsd || netbsd || openbsd || dragonfly)
+
+package notify
+
+// UserRuntimeDir is where the runtime directories of the users are on the
+// platforms with D-Bus.
+const UserRuntimeDir = "/run/user"
+
+// ListUserBuses fails: there are no session buses on this platform.
+func ListUserBuses() ([]UserBus, error) {
+	return nil, ErrNoTransport
+}
+
+// ListUserBusesIn fails like ListUserBuses.
+func ListUserBusesIn(dir string) ([]UserBus, error) {
+	return nil, ErrNoTransport
+}
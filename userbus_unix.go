@@ -0,0 +1,55 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+//go:build linux || freebsd || netbsd || openbsd || dragonfly
+
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+)
+
+// UserRuntimeDir is where the runtime directories of the users are, each
+// holding the socket of the session bus of its user, see ListUserBuses.
+const UserRuntimeDir = "/run/user"
+
+// ListUserBuses returns the session buses of the users under
+// UserRuntimeDir, by UID; see ListUserBusesIn.
+func ListUserBuses() ([]UserBus, error) {
+	return ListUserBusesIn(UserRuntimeDir)
+}
+
+// ListUserBusesIn returns the session buses found as dir/*/bus, by UID: the
+// sockets, owned by the user of the bus. Connecting to the bus of another
+// user usually needs the privileges of that user.
+func ListUserBusesIn(dir string) ([]UserBus, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "bus"))
+	if err != nil {
+		return nil, err
+	}
+	var buses []UserBus
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil || fi.Mode()&os.ModeSocket == 0 {
+			continue
+		}
+		st, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			continue
+		}
+		buses = append(buses, UserBus{UID: st.Uid, Path: path, Address: "unix:path=" + path})
+	}
+	if len(buses) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(buses, func(i, j int) bool {
+		return buses[i].UID < buses[j].UID || buses[i].UID == buses[j].UID && buses[i].Path < buses[j].Path
+	})
+	return buses, nil
+}
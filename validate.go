@@ -0,0 +1,194 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// The problems reported by Validate, wrapped in FieldErrors. ErrTooLong is
+// reported for the action labels above the SizeLimits, and for the field
+// policies of a Notifier, see WithFieldPolicies, as ErrPatternMismatch.
+var (
+	ErrEmptySummary    = errors.New("empty summary")
+	ErrInvalidUTF8     = errors.New("invalid UTF-8")
+	ErrNegativeTimeout = errors.New("negative timeout")
+	ErrInvalidUrgency  = errors.New("invalid urgency")
+	ErrDuplicateAction = errors.New("duplicate action key")
+	ErrEmptyActionKey  = errors.New("empty action key")
+	ErrOddActions      = errors.New("action key without a label")
+	ErrHintType        = errors.New("invalid hint type")
+	ErrEmptyImage      = errors.New("image without pixels")
+	ErrInvalidCategory = errors.New("invalid category")
+	ErrTooLong         = errors.New("too long")
+	ErrPatternMismatch = errors.New("does not match")
+	ErrActionLimit     = errors.New("above the action limit")
+	ErrHintsTooLarge   = errors.New("hints too large")
+)
+
+// FieldError is a problem with one field of a notification.
+type FieldError struct {
+	// Field names the field, like "summary", "actions[1]" or
+	// "hints[urgency]".
+	Field string
+	// Err is the problem, one of the errors above, possibly wrapped with
+	// details.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError holds all the problems found by Validate, as FieldErrors.
+// Use errors.Is and errors.As to look for a specific one.
+type ValidationError struct {
+	Errs []error
+}
+
+// Error lists the problems, one per line.
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("notify: invalid notification:")
+	for _, err := range e.Errs {
+		b.WriteString("\n\t" + err.Error())
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Errs
+}
+
+// hintSignatures holds the D-Bus signatures of the hints with a known type,
+// taken from the hint types so that they cannot disagree.
+var hintSignatures = func() map[string]dbus.Signature {
+	hints := make(map[string]dbus.Variant)
+	for _, h := range []Hint{
+		UrgencyHint(0), CategoryHint(""), DesktopEntryHint(""), ImagePathHint(""),
+		SoundFileHint(""), SoundNameHint(""), SuppressSoundHint(false), TransientHint(false),
+		ResidentHint(false), ActionIconsHint(false), ValueHint(0), XYHint{},
+	} {
+		h.EncodeHint(hints)
+	}
+	sigs := make(map[string]dbus.Signature, len(hints))
+	for k, v := range hints {
+		sigs[k] = v.Signature()
+	}
+	return sigs
+}()
+
+// Validate returns a *ValidationError listing everything that prevents n
+// from being sent as it is: no summary, invalid UTF-8 text, a negative
+// timeout, an unknown urgency, actions with an empty key or several with the
+// same key, hints that cannot be sent or have the wrong type for their key,
+// a "category" hint that is not valid, see CheckCategory, an image
+// without pixels, or actions and hints above the DefaultSizeLimits.
+func (n *Notification) Validate() error {
+	return n.validate(DefaultSizeLimits)
+}
+
+// validate is Validate with the size limits.
+func (n *Notification) validate(limits SizeLimits) error {
+	var errs []error
+	add := func(field string, err error) {
+		errs = append(errs, &FieldError{field, err})
+	}
+
+	if n.Summary == "" {
+		add("summary", ErrEmptySummary)
+	}
+	for _, f := range []struct{ name, s string }{
+		{"name", n.Name},
+		{"summary", n.Summary},
+		{"body", n.Body},
+		{"tag", n.Tag},
+	} {
+		if !utf8.ValidString(f.s) {
+			add(f.name, ErrInvalidUTF8)
+		}
+	}
+	if n.Timeout < 0 && n.Timeout != DefaultTimeout {
+		add("timeout", fmt.Errorf("%w %v", ErrNegativeTimeout, n.Timeout))
+	}
+	if n.Urgency > CriticalUrgency {
+		add("urgency", fmt.Errorf("%w %d", ErrInvalidUrgency, byte(n.Urgency)))
+	}
+	errs = append(errs, checkActions(n.Actions)...)
+	errs = append(errs, checkHints(n.hints)...)
+	if err := checkCategoryHint(n); err != nil {
+		errs = append(errs, err)
+	}
+	if n.image.empty() {
+		add("image", ErrEmptyImage)
+	}
+	errs = append(errs, limits.check(n)...)
+
+	if len(errs) > 0 {
+		return &ValidationError{errs}
+	}
+	return nil
+}
+
+// checkActions returns the FieldErrors of the actions: invalid UTF-8, empty
+// or duplicate keys.
+func checkActions(actions []Action) []error {
+	var errs []error
+	keys := make(map[string]bool, len(actions))
+	for i, a := range actions {
+		field := fmt.Sprintf("actions[%d]", i)
+		if !utf8.ValidString(a.Key) || !utf8.ValidString(a.Label) {
+			errs = append(errs, &FieldError{field, ErrInvalidUTF8})
+		}
+		if a.Key == "" {
+			errs = append(errs, &FieldError{field, ErrEmptyActionKey})
+		} else if keys[a.Key] {
+			errs = append(errs, &FieldError{field, fmt.Errorf("%w %q", ErrDuplicateAction, a.Key)})
+		}
+		keys[a.Key] = true
+	}
+	return errs
+}
+
+// checkHints returns the FieldErrors of the hints, in the order of their
+// keys, see checkHint.
+func checkHints[V any](hints map[string]V) []error {
+	keys := make([]string, 0, len(hints))
+	for k := range hints {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, k := range keys {
+		if err := checkHint(k, hints[k]); err != nil {
+			errs = append(errs, &FieldError{"hints[" + k + "]", err})
+		}
+	}
+	return errs
+}
+
+// checkHint returns an error if the hint key cannot be sent with the value
+// v, converted as by SetHint, or has not the type it requires.
+func checkHint(key string, v interface{}) error {
+	variant, err := hintValue(v)
+	if err != nil {
+		return err
+	}
+	sig := variant.Signature()
+	if want, ok := hintSignatures[key]; ok && sig != want {
+		return fmt.Errorf("%w: %s, want %s", ErrHintType, sig, want)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// As returns a view of nf sending notifications on behalf of another
+// application: they get appName as their app name, unless they have a Name,
+// and appName without ".desktop" as their desktop entry, so that desktops
+// grouping notifications by application attribute them to the right one.
+//
+// A view shares the connection, transport and configuration of nf, but has
+// its own tags, callbacks and events. Closing a view does not close the
+// connection of nf. The view of a Notifier of a Core belongs to the Core
+// too. The view of a Notifier handling the resumes from suspend handles
+// them for its own scheduled sends and timers, until it is closed.
+func (nf *Notifier) As(appName string) *Notifier {
+	v := nf.view(appName)
+	if v.core != nil {
+		v.core.mu.Lock()
+		v.core.members[v] = struct{}{}
+		v.core.mu.Unlock()
+	}
+	if v.resumeWatch {
+		v.watchSleep()
+	}
+	return v
+}
+
+func (nf *Notifier) view(appName string) *Notifier {
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+
+	hints := make(map[string]dbus.Variant, len(nf.defaultHints)+1)
+	for k, v := range nf.defaultHints {
+		hints[k] = v
+	}
+	hints["desktop-entry"] = dbus.MakeVariant(strings.TrimSuffix(appName, ".desktop"))
+
+	parent := nf
+	if nf.parent != nil {
+		parent = nf.parent
+	}
+	cfg := nf.config
+	cfg.appName, cfg.appNameSet = appName, true
+	cfg.defaultHints = hints
+	return &Notifier{
+		config:       cfg,
+		parent:       parent,
+		core:         nf.core,
+		info:         nf.info,
+		caps:         nf.caps,
+		feats:        nf.feats,
+		ifaces:       nf.ifaces,
+		stopped:      make(chan struct{}),
+		errorReports: nf.errorReports,
+		metrics:      nf.viewMetrics(),
+	}
+}
+
+// As returns a view of the default Notifier sending notifications on behalf
+// of appName, see Notifier.As.
+func As(appName string) *Notifier {
+	return Default().As(appName)
+}
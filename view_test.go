@@ -0,0 +1,61 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestAs(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t, notify.WithAppName("bridge"))
+	mail := nf.As("org.example.Mail.desktop")
+	chat := nf.As("chat")
+
+	var mailCB, chatCB callbacks
+	m := notify.New("", "new mail", "", "", 0, notify.NormalUrgency)
+	m.AddAction(notify.DefaultAction, "Open")
+	mailCB.attach(m)
+	if _, err := mail.Notify(m); err != nil {
+		t.Fatal(err)
+	}
+	got := s.last(t)
+	if got.AppName != "org.example.Mail.desktop" || got.Hints["desktop-entry"].Value() != "org.example.Mail" {
+		t.Errorf("mail view sent app name %q and desktop entry %v", got.AppName, got.Hints["desktop-entry"])
+	}
+
+	c := notify.New("", "new message", "", "", 0, notify.NormalUrgency)
+	c.AddAction(notify.DefaultAction, "Open")
+	chatCB.attach(c)
+	if _, err := chat.Notify(c); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.AppName != "chat" || got.Hints["desktop-entry"].Value() != "chat" {
+		t.Errorf("chat view sent app name %q and desktop entry %v", got.AppName, got.Hints["desktop-entry"])
+	}
+
+	if _, err := nf.Notify(notify.New("", "from the bridge", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.last(t); got.AppName != "bridge" || got.Hints["desktop-entry"].Value() != nil {
+		t.Errorf("parent sent app name %q and desktop entry %v", got.AppName, got.Hints["desktop-entry"])
+	}
+
+	s.emitAction(c.Id, notify.DefaultAction)
+	s.emitAction(m.Id, notify.DefaultAction)
+	waitFor(t, "actions", func() bool { return len(mailCB.invoked()) == 1 && len(chatCB.invoked()) == 1 })
+
+	if err := chat.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mail.Notify(notify.New("", "still there", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Errorf("closing a view broke the others: %v", err)
+	}
+	if _, err := nf.Notify(notify.New("", "still there", "", "", 0, notify.NormalUrgency)); err != nil {
+		t.Errorf("closing a view broke its parent: %v", err)
+	}
+}
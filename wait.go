@@ -0,0 +1,105 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNoResponse is returned by Ask and SendAndWait when neither an
+	// action nor a close arrives within the maximum wait, see WithMaxWait.
+	ErrNoResponse = errors.New("notify: no response to the notification")
+	// ErrReplaced is returned by Ask and SendAndWait when the notification
+	// they wait for is replaced by another send.
+	ErrReplaced = errors.New("notify: notification replaced")
+	// ErrNotShown is returned by Ask and SendAndWait when the notification
+	// is not shown, so that there is nothing to wait for: held back by
+	// quiet hours or while the Notifier is paused, or not sent as a
+	// duplicate or unchanged.
+	ErrNotShown = errors.New("notify: notification not shown")
+)
+
+// NoResponse is what Ask and SendAndWait do once the maximum wait is over,
+// see WithMaxWait.
+type NoResponse int
+
+const (
+	// NoResponseError returns ErrNoResponse.
+	NoResponseError NoResponse = iota
+	// NoResponseExpired handles the notification as expired by the daemon:
+	// the default choice is returned if there is one, ErrDismissed
+	// otherwise.
+	NoResponseExpired
+)
+
+// WithMaxWait makes Ask and SendAndWait stop waiting max after sending the
+// notification, whatever the context, and close it. Some daemons never
+// signal the close of expired notifications, which would otherwise be
+// waited for until the context is done. policy tells what to return then.
+func WithMaxWait(max time.Duration, policy NoResponse) WaitOption {
+	return func(o *waitOptions) {
+		o.maxWait = max
+		o.noResponse = policy
+	}
+}
+
+// timedOut returns the answer of a wait with o once its maximum wait is
+// over.
+func (o waitOptions) timedOut() (Choice, error) {
+	switch {
+	case o.noResponse == NoResponseError:
+		return Choice{}, ErrNoResponse
+	case o.defaultKey != "":
+		return Choice{Key: o.defaultKey, Auto: true}, nil
+	}
+	return Choice{}, ErrDismissed
+}
+
+// replaceWatch is a wait for a notification, resolved by its replacement.
+type replaceWatch struct {
+	replaced func()
+}
+
+// watchReplace makes nf call replaced when the notification id is replaced,
+// until stop is called.
+func (nf *Notifier) watchReplace(id uint32, replaced func()) (stop func()) {
+	w := &replaceWatch{replaced}
+	nf.mu.Lock()
+	if nf.replaceWatches == nil {
+		nf.replaceWatches = make(map[uint32][]*replaceWatch)
+	}
+	nf.replaceWatches[id] = append(nf.replaceWatches[id], w)
+	nf.mu.Unlock()
+	return func() {
+		nf.mu.Lock()
+		defer nf.mu.Unlock()
+		ws := nf.replaceWatches[id]
+		for i := range ws {
+			if ws[i] == w {
+				ws = append(ws[:i], ws[i+1:]...)
+				break
+			}
+		}
+		if len(ws) == 0 {
+			delete(nf.replaceWatches, id)
+		} else {
+			nf.replaceWatches[id] = ws
+		}
+	}
+}
+
+// replaced resolves the waits for the notification id, which was just
+// replaced.
+func (nf *Notifier) replaced(id uint32) {
+	nf.mu.Lock()
+	ws := nf.replaceWatches[id]
+	delete(nf.replaceWatches, id)
+	nf.mu.Unlock()
+	for _, w := range ws {
+		w.replaced()
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestMaxWait(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []notify.WaitOption
+		want   string
+		err    error
+		timers int
+	}{
+		{"no response", []notify.WaitOption{
+			notify.WithMaxWait(time.Minute, notify.NoResponseError),
+		}, "", notify.ErrNoResponse, 1},
+		{"expired", []notify.WaitOption{
+			notify.WithMaxWait(time.Minute, notify.NoResponseExpired),
+		}, "", notify.ErrDismissed, 1},
+		{"expired with default", []notify.WaitOption{
+			notify.WithDefaultChoice("later", time.Hour),
+			notify.WithMaxWait(time.Minute, notify.NoResponseExpired),
+		}, "later", nil, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			clock := newFakeClock()
+			nf := newTestNotifier(t, notify.WithClock(clock))
+			n := notify.New("test", "Reboot?", "", "", 0, notify.NormalUrgency)
+			n.AddAction("later", "Later")
+
+			type result struct {
+				key string
+				err error
+			}
+			results := make(chan result, 1)
+			go func() {
+				// The context never ends: only the maximum wait does.
+				key, err := nf.SendAndWait(context.Background(), n, tt.opts...)
+				results <- result{key, err}
+			}()
+			waitFor(t, "the timers", func() bool { return clock.Timers() == tt.timers })
+			clock.Advance(time.Minute)
+			var r result
+			select {
+			case r = <-results:
+			case <-waitTimeout():
+				t.Fatal("SendAndWait did not return")
+			}
+			if r.key != tt.want || !errors.Is(r.err, tt.err) {
+				t.Errorf("SendAndWait() = %q, %v, want %q, %v", r.key, r.err, tt.want, tt.err)
+			}
+			waitFor(t, "close", func() bool { return len(s.closedIDs()) == 1 })
+			if n := clock.Timers(); n != 0 {
+				t.Errorf("%d timers still running", n)
+			}
+		})
+	}
+}
+
+func TestWaitReplaced(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+	n := notify.New("test", "Downloading", "", "", 0, notify.NormalUrgency)
+	n.AddAction("cancel", "Cancel")
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := nf.Ask(context.Background(), n, notify.WithMaxWait(time.Hour, notify.NoResponseError))
+		errs <- err
+	}()
+	waitFor(t, "the maximum wait timer", func() bool { return clock.Timers() == 1 })
+	if err := <-nf.ReplaceAsync(n, "Downloaded", ""); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, notify.ErrReplaced) {
+			t.Errorf("Ask() = %v, want ErrReplaced", err)
+		}
+	case <-waitTimeout():
+		t.Fatal("Ask did not return")
+	}
+	// The replacement is left shown.
+	if got := s.closedIDs(); len(got) != 0 {
+		t.Errorf("closed %v", got)
+	}
+}
+
+func TestMaxWaitInvalid(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	n := notify.New("test", "Reboot?", "", "", 0, notify.NormalUrgency)
+	for _, opt := range []notify.WaitOption{
+		notify.WithMaxWait(-time.Second, notify.NoResponseError),
+		notify.WithMaxWait(time.Second, notify.NoResponse(7)),
+	} {
+		if _, err := nf.Ask(context.Background(), n, opt); err == nil {
+			t.Error("Ask accepted an invalid maximum wait")
+		}
+	}
+	if got := s.received(); got != 0 {
+		t.Errorf("server received %d notifications", got)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// RawSignal is a signal of the notifications interface, see WatchAll.
+type RawSignal struct {
+	// Sender is the unique bus name of the daemon that sent the signal.
+	Sender string
+	// Path is the object path of the daemon.
+	Path dbus.ObjectPath
+	// Member is the name of the signal, like "ActionInvoked".
+	Member string
+	// ID is the ID of the notification concerned, if the signal has one.
+	ID uint32
+	// Key is the key of the invoked action, for ActionInvoked.
+	Key string
+	// Reason is the reason of the close, for NotificationClosed.
+	Reason CloseReason
+	// Body holds all the arguments of the signal, as received.
+	Body []interface{}
+}
+
+// WatchAll returns a channel receiving every signal of the notifications
+// interface that the bus lets nf see, including those about notifications
+// sent by other processes, until ctx is done. The channel is then closed.
+// It is independent of the callbacks of the notifications of nf, which are
+// still called.
+//
+// Keep reading from the channel: signals are not dropped, so a slow reader
+// holds back the following ones.
+//
+// Signals can reveal what other applications show and what the user does
+// with it, including the text of inline replies in Body: treat them as
+// private data. The bus only shows the signals broadcast by the daemon;
+// those some daemons send to the client of the notification alone are not
+// received.
+func (nf *Notifier) WatchAll(ctx context.Context) (<-chan RawSignal, error) {
+	conn, err := nf.connection()
+	if err != nil {
+		return nil, err
+	}
+	rule := []dbus.MatchOption{dbus.WithMatchInterface(dbusInterface)}
+	if err := conn.AddMatchSignal(rule...); err != nil {
+		return nil, err
+	}
+	ch := make(chan *dbus.Signal, 16)
+	conn.Signal(ch)
+
+	out := make(chan RawSignal)
+	go func() {
+		defer close(out)
+		defer conn.RemoveSignal(ch)
+		defer conn.RemoveMatchSignal(rule...)
+		for {
+			var sig *dbus.Signal
+			select {
+			case sig = <-ch:
+			case <-ctx.Done():
+				return
+			}
+			member, ok := strings.CutPrefix(sig.Name, dbusInterface+".")
+			if !ok {
+				continue
+			}
+			select {
+			case out <- rawSignal(sig, member):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// rawSignal decodes sig, the signal member of the notifications interface.
+func rawSignal(sig *dbus.Signal, member string) RawSignal {
+	raw := RawSignal{Sender: sig.Sender, Path: sig.Path, Member: member, Body: sig.Body}
+	if len(sig.Body) > 0 {
+		raw.ID, _ = sig.Body[0].(uint32)
+	}
+	switch member {
+	case "ActionInvoked":
+		if _, key, ok := signalArgs[string](sig); ok {
+			raw.Key = key
+		}
+	case "NotificationClosed":
+		if _, reason, ok := signalArgs[uint32](sig); ok {
+			raw.Reason = CloseReason(reason)
+		}
+	}
+	return raw
+}
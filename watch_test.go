@@ -0,0 +1,57 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestWatchAll(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals, err := nf.WatchAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Notification 42 was sent by someone else.
+	s.emitAction(42, "open")
+	s.emitClosed(42, uint32(notify.ReasonDismissed))
+
+	want := []notify.RawSignal{
+		{Sender: s.conn.Names()[0], Path: s.path, Member: "ActionInvoked", ID: 42, Key: "open"},
+		{Sender: s.conn.Names()[0], Path: s.path, Member: "NotificationClosed", ID: 42, Reason: notify.ReasonDismissed},
+	}
+	for _, w := range want {
+		select {
+		case got := <-signals:
+			if len(got.Body) != 2 {
+				t.Errorf("%s signal has arguments %v", got.Member, got.Body)
+			}
+			got.Body = nil
+			if !reflect.DeepEqual(got, w) {
+				t.Errorf("received %+v, want %+v", got, w)
+			}
+		case <-waitTimeout():
+			t.Fatalf("no %s signal", w.Member)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-signals:
+		if ok {
+			t.Error("received a signal after the context was cancelled")
+		}
+	case <-waitTimeout():
+		t.Fatal("channel not closed after the context was cancelled")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// defaultWindowIDHints are the hints carrying the window of notifications
+// when the daemon is not known to need specific ones.
+var defaultWindowIDHints = []string{"window-id"}
+
+// SetWindowID makes n belong to the X11 window id, so that daemons that
+// support it can flag the window in the task bar or focus it when n is
+// clicked. 0 removes the window.
+//
+// XIDs mean nothing on Wayland: in a Wayland session the window is not
+// sent to the daemons sending activation tokens, and the window is raised
+// with the activation token of the click instead, see OnClick and
+// SpecLevel.HasActivationToken.
+func (n *Notification) SetWindowID(id uint32) {
+	n.windowID = id
+}
+
+// OnClick makes clicking n call activate with the activation token of the
+// click, if the daemon sent one, so that the application can raise its
+// window: give the token to the toolkit, or to a new process with
+// SetActivationEnv. It adds the DefaultAction with label, replacing any
+// previous one, and wraps OnAction, which is still called.
+func (n *Notification) OnClick(label string, activate func(token string)) {
+	n.RemoveAction(DefaultAction)
+	n.Actions = append([]Action{{DefaultAction, label}}, n.Actions...)
+	onAction := n.OnAction
+	n.OnAction = func(key string) {
+		if key == DefaultAction {
+			activate(n.ActivationToken())
+		}
+		if onAction != nil {
+			onAction(key)
+		}
+	}
+}
+
+// waylandSession returns true if the session is a Wayland one.
+func waylandSession() bool {
+	return os.Getenv("WAYLAND_DISPLAY") != "" || os.Getenv("XDG_SESSION_TYPE") == "wayland"
+}
+
+// windowIDHints adds the hints carrying the window id to hints, for a
+// daemon implementing level, logging which ones and why, so that DryRun
+// shows it.
+func (nf *Notifier) windowIDHints(hints map[string]dbus.Variant, id uint32, level SpecLevel) {
+	keys, why := defaultWindowIDHints, "default"
+	if waylandSession() {
+		if level.HasActivationToken {
+			nf.log(LevelDebug, fmt.Sprintf("not sending window %d: Wayland session, clicks raise the window with their activation token", id), nil)
+			return
+		}
+		// The daemon may still raise XWayland windows.
+		why = "Wayland session, but the daemon sends no activation tokens"
+	}
+	if q := nf.daemonQuirks(); len(q.WindowIDHints) > 0 {
+		keys, why = q.WindowIDHints, "quirks of the daemon"
+	}
+	v := dbus.MakeVariant(id)
+	for _, k := range keys {
+		hints[k] = v
+	}
+	nf.log(LevelDebug, fmt.Sprintf("sending window %d as %q (%s)", id, keys, why), nil)
+}
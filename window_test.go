@@ -0,0 +1,99 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+func TestWindowIDHints(t *testing.T) {
+	newFakeServer(t)
+	tests := []struct {
+		name    string
+		wayland string
+		quirks  *notify.Quirks
+		want    []string
+		logged  string
+	}{
+		{"X11", "", nil, []string{"window-id"}, "default"},
+		{"X11 with quirks", "", &notify.Quirks{WindowIDHints: []string{"window-id", "x-kde-window-id"}}, []string{"window-id", "x-kde-window-id"}, "quirks"},
+		{"Wayland", "wayland-0", nil, nil, "Wayland"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WAYLAND_DISPLAY", tt.wayland)
+			t.Setenv("XDG_SESSION_TYPE", "")
+			var opts []notify.Option
+			if tt.quirks != nil {
+				opts = append(opts, notify.WithQuirks(*tt.quirks))
+			}
+			var logs logRecorder
+			nf := newTestNotifier(t, opts...)
+			nf.SetLogger(logs.log)
+
+			n := notify.New("test", "build done", "", "", 0, notify.NormalUrgency)
+			n.SetWindowID(0x3a00007)
+			c, err := n.DryRun(nf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range []string{"window-id", "x-kde-window-id"} {
+				v, sent := c.Hints[k]
+				want := false
+				for _, w := range tt.want {
+					want = want || w == k
+				}
+				if sent != want || sent && v.Value() != uint32(0x3a00007) {
+					t.Errorf("hint %s = %v (sent %v), want sent %v", k, v, sent, want)
+				}
+			}
+			if !logs.logged(tt.logged) {
+				t.Errorf("the choice of hints was not logged with %q", tt.logged)
+			}
+		})
+	}
+}
+
+func TestOnClick(t *testing.T) {
+	s := newFakeServer(t)
+	nf := newTestNotifier(t)
+	events := nf.Events()
+
+	tokens := make(chan string, 1)
+	keys := make(chan string, 1)
+	n := notify.New("test", "build done", "", "", 0, notify.NormalUrgency)
+	n.AddAction("log", "Show log")
+	n.OnAction = func(key string) { keys <- key }
+	n.OnClick("Open", func(token string) { tokens <- token })
+	if _, err := nf.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, notify.EventSent)
+	if got := s.last(t).Actions; len(got) != 4 || got[0] != notify.DefaultAction || got[1] != "Open" {
+		t.Errorf("actions = %q", got)
+	}
+
+	s.emitActivationToken(n.Id, "token")
+	s.emitAction(n.Id, notify.DefaultAction)
+	select {
+	case got := <-tokens:
+		if got != "token" {
+			t.Errorf("activate got %q, want token", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("activate not called")
+	}
+	select {
+	case key := <-keys:
+		if key != notify.DefaultAction {
+			t.Errorf("OnAction got %q", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnAction not called")
+	}
+}
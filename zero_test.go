@@ -0,0 +1,153 @@
+// Copyright (c) 2013, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Schnouki/notify"
+)
+
+// checkZeroSent checks that the last notification of s is the one of a
+// Notification literal with only a summary.
+func checkZeroSent(t *testing.T, s *fakeServer, want notify.NotificationUrgency) {
+	t.Helper()
+	sent := s.last(t)
+	if got := sent.Hints["urgency"].Value(); got != byte(want) {
+		t.Errorf("urgency = %v, want %d", got, want)
+	}
+	if derived := filepath.Base(os.Args[0]); sent.AppName != derived {
+		t.Errorf("app name = %q, want %q", sent.AppName, derived)
+	}
+	if sent.ExpireTimeout != 0 {
+		t.Errorf("expire timeout = %d, want 0", sent.ExpireTimeout)
+	}
+}
+
+func TestZeroNotificationSendPaths(t *testing.T) {
+	s := newFakeServer(t)
+	clock := newFakeClock()
+	nf := newTestNotifier(t, notify.WithClock(clock))
+
+	paths := []struct {
+		name string
+		send func(n *notify.Notification) error
+	}{
+		{"Notify", func(n *notify.Notification) error {
+			_, err := nf.Notify(n)
+			return err
+		}},
+		{"SendContext", func(n *notify.Notification) error {
+			_, err := nf.SendContext(context.Background(), n)
+			return err
+		}},
+		{"Fire", func(n *notify.Notification) error {
+			_, err := nf.Fire(n, n.Summary, "")
+			return err
+		}},
+		{"Scope", func(n *notify.Notification) error {
+			_, err := nf.Scope().Notify(n)
+			return err
+		}},
+		{"Patch", func(n *notify.Notification) error {
+			_, err := nf.Patch(n, func(n *notify.Notification) { n.Body = "patched" })
+			return err
+		}},
+		{"SendAfter", func(n *notify.Notification) error {
+			sent := len(s.notifications())
+			if _, err := nf.SendAfter(time.Minute, n); err != nil {
+				return err
+			}
+			clock.Advance(time.Minute)
+			waitFor(t, "the scheduled send", func() bool { return len(s.notifications()) > sent })
+			return nil
+		}},
+	}
+	for _, p := range paths {
+		t.Run(p.name, func(t *testing.T) {
+			if err := p.send(&notify.Notification{Summary: p.name}); err != nil {
+				t.Fatal(err)
+			}
+			checkZeroSent(t, s, notify.NormalUrgency)
+
+			low := &notify.Notification{Summary: p.name + " low"}
+			low.SetUrgency(notify.LowUrgency)
+			if err := p.send(low); err != nil {
+				t.Fatal(err)
+			}
+			checkZeroSent(t, s, notify.LowUrgency)
+		})
+	}
+}
+
+func TestZeroNotificationDryRun(t *testing.T) {
+	newFakeServer(t)
+	nf := newTestNotifier(t)
+	tests := []struct {
+		name string
+		n    *notify.Notification
+		want notify.NotificationUrgency
+	}{
+		{"literal", &notify.Notification{Summary: "hi"}, notify.NormalUrgency},
+		{"literal critical", &notify.Notification{Summary: "hi", Urgency: notify.CriticalUrgency}, notify.CriticalUrgency},
+		{"New low", notify.New("", "hi", "", "", 0, notify.LowUrgency), notify.LowUrgency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urgency := tt.n.Urgency
+			c, err := tt.n.DryRun(nf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Urgency != tt.want || c.Hints["urgency"].Value() != byte(tt.want) {
+				t.Errorf("urgency = %v, hint %v, want %v", c.Urgency, c.Hints["urgency"].Value(), tt.want)
+			}
+			if tt.n.Urgency != urgency {
+				t.Errorf("DryRun changed Urgency to %v", tt.n.Urgency)
+			}
+		})
+	}
+
+	// A low urgency hint still wins.
+	n := &notify.Notification{Summary: "hint"}
+	n.AddHints(notify.UrgencyHint(notify.LowUrgency))
+	c, err := n.DryRun(nf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Urgency != notify.LowUrgency {
+		t.Errorf("urgency with a low urgency hint = %v", c.Urgency)
+	}
+}
+
+func TestZeroNotificationJSON(t *testing.T) {
+	data, err := json.Marshal(&notify.Notification{Summary: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n notify.Notification
+	if err := json.Unmarshal(data, &n); err != nil {
+		t.Fatal(err)
+	} else if n.Urgency != notify.NormalUrgency {
+		t.Errorf("decoded urgency = %v, want normal", n.Urgency)
+	}
+
+	// A decoded low urgency is kept.
+	if err := json.Unmarshal([]byte(`{"summary":"hi","urgency":"low"}`), &n); err != nil {
+		t.Fatal(err)
+	}
+	newFakeServer(t)
+	c, err := n.DryRun(newTestNotifier(t))
+	if err != nil {
+		t.Fatal(err)
+	} else if c.Urgency != notify.LowUrgency {
+		t.Errorf("urgency of a decoded low notification = %v", c.Urgency)
+	}
+}